@@ -13,6 +13,7 @@ import (
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/x/internal/loader"
 	"github.com/go-gost/x/internal/matcher"
+	xnet "github.com/go-gost/x/internal/net"
 )
 
 type options struct {
@@ -110,6 +111,7 @@ func (p *localAdmission) Admit(ctx context.Context, addr string, opts ...admissi
 	if host, _, _ := net.SplitHostPort(addr); host != "" {
 		addr = host
 	}
+	addr = xnet.NormalizeHost(addr)
 
 	matched := p.matched(addr)
 
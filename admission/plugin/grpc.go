@@ -3,17 +3,20 @@ package admission
 import (
 	"context"
 	"io"
+	"net"
 
 	"github.com/go-gost/core/admission"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/plugin/admission/proto"
 	"github.com/go-gost/x/internal/plugin"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type grpcPlugin struct {
 	conn   grpc.ClientConnInterface
 	client proto.AdmissionClient
+	cache  *plugin.Cache
 	log    logger.Logger
 }
 
@@ -34,8 +37,9 @@ func NewGRPCPlugin(name string, addr string, opts ...plugin.Option) admission.Ad
 	}
 
 	p := &grpcPlugin{
-		conn: conn,
-		log:  log,
+		conn:  conn,
+		cache: plugin.NewCache(options.CacheTTL),
+		log:   log,
 	}
 	if conn != nil {
 		p.client = proto.NewAdmissionClient(conn)
@@ -48,14 +52,25 @@ func (p *grpcPlugin) Admit(ctx context.Context, addr string, opts ...admission.O
 		return false
 	}
 
+	// the client port is ignored, so the decision is shared by the connections of the client.
+	key := addr
+	if host, _, _ := net.SplitHostPort(addr); host != "" {
+		key = host
+	}
+	if v, ok := p.cache.Get(key); ok {
+		return v.(bool)
+	}
+
+	var header metadata.MD
 	r, err := p.client.Admit(ctx,
 		&proto.AdmissionRequest{
 			Addr: addr,
-		})
+		}, grpc.Header(&header))
 	if err != nil {
 		p.log.Error(err)
 		return false
 	}
+	p.cache.Set(key, r.Ok, plugin.HeaderTTL(header))
 	return r.Ok
 }
 
@@ -25,6 +25,7 @@ type options struct {
 	accessLog  bool
 	pathPrefix string
 	auther     auth.Authenticator
+	tenants    []tenant
 }
 
 type Option func(*options)
@@ -47,6 +48,16 @@ func AutherOption(auther auth.Authenticator) Option {
 	}
 }
 
+// TenantOption adds the tenant of the namespace authenticated by the auther,
+// the tenant only sees and manages the objects in its namespace.
+func TenantOption(namespace string, auther auth.Authenticator) Option {
+	return func(o *options) {
+		if namespace != "" && auther != nil {
+			o.tenants = append(o.tenants, tenant{namespace: namespace, auther: auther})
+		}
+	}
+}
+
 type server struct {
 	s      *http.Server
 	ln     net.Listener
@@ -67,6 +78,8 @@ func NewService(addr string, opts ...Option) (service.Service, error) {
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
+	// the names of the namespaced objects contain the escaped separator.
+	r.UseRawPath = true
 	r.Use(
 		cors.New((cors.Config{
 			AllowAllOrigins:     true,
@@ -88,9 +101,23 @@ func NewService(addr string, opts ...Option) (service.Service, error) {
 	router.StaticFS("/docs", http.FS(swaggerDoc))
 
 	config := router.Group("/config")
-	config.Use(mwBasicAuth(options.auther))
+	config.Use(mwBasicAuth(options.auther, options.tenants), mwNamespace())
 	registerConfig(config)
 
+	tunnels := router.Group("/tunnels")
+	tunnels.Use(mwBasicAuth(options.auther, options.tenants), mwNamespace())
+	tunnels.GET("", getTunnelList)
+
+	stats := router.Group("/stats")
+	stats.Use(mwBasicAuth(options.auther, options.tenants), mwNamespace())
+	stats.GET("/history", getStatsHistory)
+
+	diag := router.Group("/diag")
+	diag.Use(mwBasicAuth(options.auther, options.tenants), mwNamespace())
+	diag.GET("/trace", traceChain)
+	diag.GET("/selftest", getSelfTestList)
+	diag.POST("/selftest", runSelfTest)
+
 	return &server{
 		s: &http.Server{
 			Handler: r,
@@ -128,6 +155,11 @@ func registerConfig(config *gin.RouterGroup) {
 	config.POST("/services", createService)
 	config.PUT("/services/:service", updateService)
 	config.DELETE("/services/:service", deleteService)
+	config.GET("/services/:service/effective", getEffectiveService)
+	config.PUT("/services/:service/chain", swapServiceChain)
+	config.PUT("/services/:service/schedule", overrideServiceSchedule)
+	config.PUT("/services/:service/maintenance", enableServiceMaintenance)
+	config.DELETE("/services/:service/maintenance", disableServiceMaintenance)
 
 	config.POST("/chains", createChain)
 	config.PUT("/chains/:chain", updateChain)
@@ -137,6 +169,10 @@ func registerConfig(config *gin.RouterGroup) {
 	config.PUT("/hops/:hop", updateHop)
 	config.DELETE("/hops/:hop", deleteHop)
 
+	config.POST("/templates", createTemplate)
+	config.PUT("/templates/:template", updateTemplate)
+	config.DELETE("/templates/:template", deleteTemplate)
+
 	config.POST("/authers", createAuther)
 	config.PUT("/authers/:auther", updateAuther)
 	config.DELETE("/authers/:auther", deleteAuther)
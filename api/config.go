@@ -57,6 +57,17 @@ func getConfig(ctx *gin.Context) {
 					CreateTime: status.CreateTime().Unix(),
 					State:      string(status.State()),
 				}
+				if addr := s.Addr(); addr != nil {
+					svc.Status.Addr = addr.String()
+				}
+				if res := status.Resources(); res != nil {
+					svc.Status.Resources = &config.ServiceResources{
+						Goroutines: res.Goroutines,
+						FDs:        res.FDs,
+						Memory:     res.Memory,
+						Bandwidth:  res.Bandwidth,
+					}
+				}
 				if st := status.Stats(); st != nil {
 					svc.Status.Stats = &config.ServiceStats{
 						TotalConns:   st.Get(stats.KindTotalConns),
@@ -65,6 +76,12 @@ func getConfig(ctx *gin.Context) {
 						InputBytes:   st.Get(stats.KindInputBytes),
 						OutputBytes:  st.Get(stats.KindOutputBytes),
 					}
+					for kind, n := range status.Errors() {
+						if svc.Status.Stats.Errors == nil {
+							svc.Status.Stats.Errors = make(map[string]uint64)
+						}
+						svc.Status.Stats.Errors[string(kind)] = n
+					}
 				}
 				for _, ev := range status.Events() {
 					if !ev.Time.IsZero() {
@@ -80,6 +97,9 @@ func getConfig(ctx *gin.Context) {
 	})
 	var resp getConfigResponse
 	resp.Config = config.Global()
+	if ns := namespaceFromContext(ctx); ns != "" {
+		resp.Config = filterConfig(resp.Config, ns)
+	}
 
 	buf := &bytes.Buffer{}
 	switch req.Format {
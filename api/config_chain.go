@@ -41,7 +41,7 @@ func createChain(ctx *gin.Context) {
 		return
 	}
 
-	v, err := parser.ParseChain(&req.Data, logger.Default())
+	v, err := parser.ParseChain(&req.Data, logger.Default(), config.Global().Templates...)
 	if err != nil {
 		writeError(ctx, ErrCreate)
 		return
@@ -100,7 +100,7 @@ func updateChain(ctx *gin.Context) {
 
 	req.Data.Name = req.Chain
 
-	v, err := parser.ParseChain(&req.Data, logger.Default())
+	v, err := parser.ParseChain(&req.Data, logger.Default(), config.Global().Templates...)
 	if err != nil {
 		writeError(ctx, ErrCreate)
 		return
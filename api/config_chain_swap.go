@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/registry"
+)
+
+type chainSwapper interface {
+	SwapChain(name string, c chain.Chainer, grace time.Duration) error
+}
+
+// chainSwap swaps the chain of the service.
+type chainSwap struct {
+	// the name of the new chain.
+	Chain string `json:"chain"`
+	// the grace period in seconds for the connections through the old chain to finish before they are closed,
+	// the connections are kept until they finish if it is 0.
+	Grace int `json:"grace,omitempty"`
+}
+
+// swagger:parameters swapServiceChainRequest
+type swapServiceChainRequest struct {
+	// in: path
+	// required: true
+	Service string `uri:"service" json:"service"`
+	// in: body
+	Data chainSwap `json:"data"`
+}
+
+// successful operation.
+// swagger:response swapServiceChainResponse
+type swapServiceChainResponse struct {
+	Data Response
+}
+
+func swapServiceChain(ctx *gin.Context) {
+	// swagger:route PUT /config/services/{service}/chain Service swapServiceChainRequest
+	//
+	// Swap the chain of the service atomically, the new connections go through the new chain
+	// while the connections through the old chain drain in the grace period.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: swapServiceChainResponse
+
+	var req swapServiceChainRequest
+	ctx.ShouldBindUri(&req)
+	ctx.ShouldBindJSON(&req.Data)
+
+	if req.Data.Chain == "" || req.Data.Grace < 0 {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+	if !registry.ChainRegistry().IsRegistered(req.Data.Chain) {
+		writeError(ctx, ErrNotFound)
+		return
+	}
+
+	svc := registry.ServiceRegistry().Get(req.Service)
+	if svc == nil {
+		writeError(ctx, ErrNotFound)
+		return
+	}
+	swapper, ok := svc.(chainSwapper)
+	if !ok {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+	if err := swapper.SwapChain(req.Data.Chain,
+		registry.ChainRegistry().Get(req.Data.Chain),
+		time.Duration(req.Data.Grace)*time.Second); err != nil {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+
+	config.OnUpdate(func(c *config.Config) error {
+		for _, s := range c.Services {
+			if s == nil || s.Name != req.Service || s.Handler == nil {
+				continue
+			}
+			s.Handler.Chain = req.Data.Chain
+			s.Handler.ChainGroup = nil
+			break
+		}
+		return nil
+	})
+
+	ctx.JSON(http.StatusOK, Response{
+		Msg: "OK",
+	})
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/x/config"
+	mdx "github.com/go-gost/x/metadata"
+)
+
+// swagger:parameters getEffectiveServiceRequest
+type getEffectiveServiceRequest struct {
+	// in: path
+	// required: true
+	Service string `uri:"service" json:"service"`
+}
+
+// successful operation.
+// swagger:response getEffectiveServiceResponse
+type getEffectiveServiceResponse struct {
+	// in: body
+	Service effectiveService
+}
+
+// effectiveService is the config the service actually runs with,
+// the defaults are applied, the metadata are coerced and the referenced objects are expanded.
+// For the tenants, the objects shared by the namespaces are not expanded.
+type effectiveService struct {
+	Name       string                    `json:"name"`
+	Addr       string                    `json:"addr,omitempty"`
+	Listener   *effectiveListener        `json:"listener"`
+	Handler    *effectiveHandler         `json:"handler"`
+	Forwarder  *config.ForwarderConfig   `json:"forwarder,omitempty"`
+	Egress     *config.EgressConfig      `json:"egress,omitempty"`
+	Admissions []*config.AdmissionConfig `json:"admissions,omitempty"`
+	Bypasses   []*config.BypassConfig    `json:"bypasses,omitempty"`
+	Resolver   *config.ResolverConfig    `json:"resolver,omitempty"`
+	Hosts      *config.HostsConfig       `json:"hosts,omitempty"`
+	Limiter    *config.LimiterConfig     `json:"limiter,omitempty"`
+	CLimiter   *config.LimiterConfig     `json:"climiter,omitempty"`
+	RLimiter   *config.LimiterConfig     `json:"rlimiter,omitempty"`
+	Loggers    []*config.LoggerConfig    `json:"loggers,omitempty"`
+	Observer   *config.ObserverConfig    `json:"observer,omitempty"`
+	Recorders  []*config.RecorderConfig  `json:"recorders,omitempty"`
+	Metadata   map[string]any            `json:"metadata,omitempty"`
+	DependsOn  []string                  `json:"dependsOn,omitempty"`
+}
+
+type effectiveListener struct {
+	Type string `json:"type"`
+	// the chains of the chain or the chain group.
+	Chains  []*config.ChainConfig  `json:"chains,omitempty"`
+	Authers []*config.AutherConfig `json:"authers,omitempty"`
+	Auth    *config.AuthConfig     `json:"auth,omitempty"`
+	TLS     *config.TLSConfig      `json:"tls,omitempty"`
+	// the metadata with the defaults of the listener applied.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+type effectiveHandler struct {
+	Type    string `json:"type"`
+	Retries int    `json:"retries,omitempty"`
+	// the chains of the chain or the chain group.
+	Chains      []*config.ChainConfig      `json:"chains,omitempty"`
+	Authers     []*config.AutherConfig     `json:"authers,omitempty"`
+	Auth        *config.AuthConfig         `json:"auth,omitempty"`
+	TLS         *config.TLSConfig          `json:"tls,omitempty"`
+	Limiter     *config.LimiterConfig      `json:"limiter,omitempty"`
+	Observer    *config.ObserverConfig     `json:"observer,omitempty"`
+	Middlewares []*config.MiddlewareConfig `json:"middlewares,omitempty"`
+	// the metadata with the defaults of the handler applied.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+func getEffectiveService(ctx *gin.Context) {
+	// swagger:route GET /config/services/{service}/effective Service getEffectiveServiceRequest
+	//
+	// Get the effective config of the service, with the defaults applied, the metadata coerced and the referenced objects expanded.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: getEffectiveServiceResponse
+
+	var req getEffectiveServiceRequest
+	ctx.ShouldBindUri(&req)
+
+	cfg := config.Global()
+	var svc *config.ServiceConfig
+	for _, v := range cfg.Services {
+		if v != nil && v.Name == req.Service {
+			svc = v
+			break
+		}
+	}
+	if svc == nil {
+		writeError(ctx, ErrNotFound)
+		return
+	}
+	if ns := namespaceFromContext(ctx); ns != "" {
+		// the shared objects (e.g. the recorders and the plugins) carry the credentials,
+		// only the objects in the namespace of the tenant are expanded.
+		cfg = filterConfig(cfg, ns)
+	}
+
+	ctx.JSON(http.StatusOK, newEffectiveService(cfg, svc))
+}
+
+func newEffectiveService(cfg *config.Config, svc *config.ServiceConfig) *effectiveService {
+	es := &effectiveService{
+		Name:       svc.Name,
+		Addr:       svc.Addr,
+		Forwarder:  svc.Forwarder,
+		Egress:     svc.Egress,
+		Admissions: findAll(cfg.Admissions, admissionName, svc.Admission, svc.Admissions...),
+		Bypasses:   findAll(cfg.Bypasses, bypassName, svc.Bypass, svc.Bypasses...),
+		Resolver:   find(cfg.Resolvers, resolverName, svc.Resolver),
+		Hosts:      find(cfg.Hosts, hostsName, svc.Hosts),
+		Limiter:    find(cfg.Limiters, limiterName, svc.Limiter),
+		CLimiter:   find(cfg.CLimiters, limiterName, svc.CLimiter),
+		RLimiter:   find(cfg.RLimiters, limiterName, svc.RLimiter),
+		Loggers:    findAll(cfg.Loggers, loggerName, svc.Logger, svc.Loggers...),
+		Observer:   find(cfg.Observers, observerName, svc.Observer),
+		Metadata:   svc.Metadata,
+		DependsOn:  svc.DependsOn,
+	}
+	for _, v := range svc.Recorders {
+		if v == nil {
+			continue
+		}
+		if rec := find(cfg.Recorders, recorderName, v.Name); rec != nil {
+			es.Recorders = append(es.Recorders, rec)
+		}
+	}
+
+	// the defaults of the service parser.
+	listener := svc.Listener
+	if listener == nil {
+		listener = &config.ListenerConfig{Type: "tcp"}
+	}
+	es.Listener = &effectiveListener{
+		Type:     listener.Type,
+		Chains:   findChains(cfg, listener.Chain, listener.ChainGroup),
+		Authers:  findAll(cfg.Authers, autherName, listener.Auther, listener.Authers...),
+		Auth:     listener.Auth,
+		TLS:      listener.TLS,
+		Metadata: mdx.Effective(mdx.KindListener, listener.Type, listener.Metadata),
+	}
+
+	handler := svc.Handler
+	if handler == nil {
+		handler = &config.HandlerConfig{Type: "auto"}
+	}
+	es.Handler = &effectiveHandler{
+		Type:        handler.Type,
+		Retries:     handler.Retries,
+		Chains:      findChains(cfg, handler.Chain, handler.ChainGroup),
+		Authers:     findAll(cfg.Authers, autherName, handler.Auther, handler.Authers...),
+		Auth:        handler.Auth,
+		TLS:         handler.TLS,
+		Limiter:     find(cfg.Limiters, limiterName, handler.Limiter),
+		Observer:    find(cfg.Observers, observerName, handler.Observer),
+		Middlewares: handler.Middlewares,
+		Metadata:    mdx.Effective(mdx.KindHandler, handler.Type, handler.Metadata),
+	}
+
+	return es
+}
+
+func findChains(cfg *config.Config, name string, group *config.ChainGroupConfig) []*config.ChainConfig {
+	var names []string
+	if group != nil {
+		names = group.Chains
+	}
+	return findAll(cfg.Chains, chainName, name, names...)
+}
+
+// find returns the object of the name in the list, nil is returned if the name is empty or not found.
+func find[T any](list []*T, nameOf func(*T) string, name string) *T {
+	if name == "" {
+		return nil
+	}
+	for _, v := range list {
+		if v != nil && nameOf(v) == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// findAll returns the objects of the name and the names in the list as the parsers do.
+func findAll[T any](list []*T, nameOf func(*T) string, name string, names ...string) (objs []*T) {
+	for _, name := range append([]string{name}, names...) {
+		if v := find(list, nameOf, name); v != nil {
+			objs = append(objs, v)
+		}
+	}
+	return
+}
+
+func admissionName(v *config.AdmissionConfig) string { return v.Name }
+func bypassName(v *config.BypassConfig) string       { return v.Name }
+func resolverName(v *config.ResolverConfig) string   { return v.Name }
+func hostsName(v *config.HostsConfig) string         { return v.Name }
+func limiterName(v *config.LimiterConfig) string     { return v.Name }
+func loggerName(v *config.LoggerConfig) string       { return v.Name }
+func observerName(v *config.ObserverConfig) string   { return v.Name }
+func recorderName(v *config.RecorderConfig) string   { return v.Name }
+func autherName(v *config.AutherConfig) string       { return v.Name }
+func chainName(v *config.ChainConfig) string         { return v.Name }
@@ -41,7 +41,7 @@ func createHop(ctx *gin.Context) {
 		return
 	}
 
-	v, err := parser.ParseHop(&req.Data, logger.Default())
+	v, err := parser.ParseHop(&req.Data, logger.Default(), config.Global().Templates...)
 	if err != nil {
 		writeError(ctx, ErrCreate)
 		return
@@ -100,7 +100,7 @@ func updateHop(ctx *gin.Context) {
 
 	req.Data.Name = req.Hop
 
-	v, err := parser.ParseHop(&req.Data, logger.Default())
+	v, err := parser.ParseHop(&req.Data, logger.Default(), config.Global().Templates...)
 	if err != nil {
 		writeError(ctx, ErrCreate)
 		return
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/x/registry"
+	"github.com/go-gost/x/service"
+)
+
+type maintainer interface {
+	SetMaintenance(m *service.Maintenance)
+}
+
+// maintenance is the response to the new connections of the service in maintenance mode.
+type maintenance struct {
+	// the type of the response, one of auto|http|socks|reset, default is auto.
+	// auto responds by the protocol sniffed from the connection, HTTP 503, SOCKS failure reply or TCP reset.
+	Response string `json:"response,omitempty"`
+	// the body of the HTTP response.
+	Message string `json:"message,omitempty"`
+	// the value of the Retry-After header of the HTTP response in seconds.
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+// swagger:parameters enableServiceMaintenanceRequest
+type enableServiceMaintenanceRequest struct {
+	// in: path
+	// required: true
+	Service string `uri:"service" json:"service"`
+	// in: body
+	Data maintenance `json:"data"`
+}
+
+// successful operation.
+// swagger:response enableServiceMaintenanceResponse
+type enableServiceMaintenanceResponse struct {
+	Data Response
+}
+
+func enableServiceMaintenance(ctx *gin.Context) {
+	// swagger:route PUT /config/services/{service}/maintenance Service enableServiceMaintenanceRequest
+	//
+	// Put the service into maintenance mode, the new connections are refused while the existing ones drain.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: enableServiceMaintenanceResponse
+
+	var req enableServiceMaintenanceRequest
+	ctx.ShouldBindUri(&req)
+	ctx.ShouldBindJSON(&req.Data)
+
+	switch req.Data.Response {
+	case "", service.MaintenanceAuto, service.MaintenanceHTTP, service.MaintenanceSOCKS, service.MaintenanceReset:
+	default:
+		writeError(ctx, ErrInvalid)
+		return
+	}
+
+	m, ok := registry.ServiceRegistry().Get(req.Service).(maintainer)
+	if !ok {
+		writeError(ctx, ErrNotFound)
+		return
+	}
+	m.SetMaintenance(&service.Maintenance{
+		Response:   req.Data.Response,
+		Message:    req.Data.Message,
+		RetryAfter: time.Duration(req.Data.RetryAfter) * time.Second,
+	})
+
+	ctx.JSON(http.StatusOK, Response{
+		Msg: "OK",
+	})
+}
+
+// swagger:parameters disableServiceMaintenanceRequest
+type disableServiceMaintenanceRequest struct {
+	// in: path
+	// required: true
+	Service string `uri:"service" json:"service"`
+}
+
+// successful operation.
+// swagger:response disableServiceMaintenanceResponse
+type disableServiceMaintenanceResponse struct {
+	Data Response
+}
+
+func disableServiceMaintenance(ctx *gin.Context) {
+	// swagger:route DELETE /config/services/{service}/maintenance Service disableServiceMaintenanceRequest
+	//
+	// Bring the service back from maintenance mode.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: disableServiceMaintenanceResponse
+
+	var req disableServiceMaintenanceRequest
+	ctx.ShouldBindUri(&req)
+
+	m, ok := registry.ServiceRegistry().Get(req.Service).(maintainer)
+	if !ok {
+		writeError(ctx, ErrNotFound)
+		return
+	}
+	m.SetMaintenance(nil)
+
+	ctx.JSON(http.StatusOK, Response{
+		Msg: "OK",
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/x/registry"
+	"github.com/go-gost/x/service"
+)
+
+type scheduleOverrider interface {
+	SetScheduleOverride(override string) error
+}
+
+// scheduleOverride overrides the schedule of the service.
+type scheduleOverride struct {
+	// one of auto|enable|disable, auto enables the service by its schedule.
+	Override string `json:"override"`
+}
+
+// swagger:parameters overrideServiceScheduleRequest
+type overrideServiceScheduleRequest struct {
+	// in: path
+	// required: true
+	Service string `uri:"service" json:"service"`
+	// in: body
+	Data scheduleOverride `json:"data"`
+}
+
+// successful operation.
+// swagger:response overrideServiceScheduleResponse
+type overrideServiceScheduleResponse struct {
+	Data Response
+}
+
+func overrideServiceSchedule(ctx *gin.Context) {
+	// swagger:route PUT /config/services/{service}/schedule Service overrideServiceScheduleRequest
+	//
+	// Override the schedule of the service, the service is enabled or disabled regardless of the schedule until it is set back to auto.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: overrideServiceScheduleResponse
+
+	var req overrideServiceScheduleRequest
+	ctx.ShouldBindUri(&req)
+	ctx.ShouldBindJSON(&req.Data)
+
+	switch req.Data.Override {
+	case service.ScheduleAuto, service.ScheduleEnable, service.ScheduleDisable:
+	default:
+		writeError(ctx, ErrInvalid)
+		return
+	}
+
+	svc := registry.ServiceRegistry().Get(req.Service)
+	if svc == nil {
+		writeError(ctx, ErrNotFound)
+		return
+	}
+	v, ok := svc.(scheduleOverrider)
+	if !ok {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+	if err := v.SetScheduleOverride(req.Data.Override); err != nil {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Response{
+		Msg: "OK",
+	})
+}
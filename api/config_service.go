@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-gost/x/config"
 	parser "github.com/go-gost/x/config/parsing/service"
+	"github.com/go-gost/x/internal/util/systemd"
 	"github.com/go-gost/x/registry"
 )
 
@@ -104,6 +105,10 @@ func updateService(ctx *gin.Context) {
 		writeError(ctx, ErrNotFound)
 		return
 	}
+
+	systemd.Reloading()
+	defer systemd.Notify(systemd.StateReady)
+
 	old.Close()
 
 	req.Data.Name = req.Service
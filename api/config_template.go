@@ -0,0 +1,249 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/config"
+	chain_parser "github.com/go-gost/x/config/parsing/chain"
+	hop_parser "github.com/go-gost/x/config/parsing/hop"
+	"github.com/go-gost/x/registry"
+)
+
+// swagger:parameters createTemplateRequest
+type createTemplateRequest struct {
+	// in: body
+	Data config.TemplateConfig `json:"data"`
+}
+
+// successful operation.
+// swagger:response createTemplateResponse
+type createTemplateResponse struct {
+	Data Response
+}
+
+func createTemplate(ctx *gin.Context) {
+	// swagger:route POST /config/templates Template createTemplateRequest
+	//
+	// Create a new hop template, the name of template must be unique in template list.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: createTemplateResponse
+
+	var req createTemplateRequest
+	ctx.ShouldBindJSON(&req.Data)
+
+	if req.Data.Name == "" {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+
+	err := config.OnUpdate(func(c *config.Config) error {
+		for _, v := range c.Templates {
+			if v.Name == req.Data.Name {
+				return ErrDup
+			}
+		}
+		c.Templates = append(c.Templates, &req.Data)
+		return nil
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	reloadTemplate(req.Data.Name)
+
+	ctx.JSON(http.StatusOK, Response{
+		Msg: "OK",
+	})
+}
+
+// swagger:parameters updateTemplateRequest
+type updateTemplateRequest struct {
+	// in: path
+	// required: true
+	// template name
+	Template string `uri:"template" json:"template"`
+	// in: body
+	Data config.TemplateConfig `json:"data"`
+}
+
+// successful operation.
+// swagger:response updateTemplateResponse
+type updateTemplateResponse struct {
+	Data Response
+}
+
+func updateTemplate(ctx *gin.Context) {
+	// swagger:route PUT /config/templates/{template} Template updateTemplateRequest
+	//
+	// Update hop template by name, the template must already exist.
+	// The hops and chains using the template are reloaded.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: updateTemplateResponse
+
+	var req updateTemplateRequest
+	ctx.ShouldBindUri(&req)
+	ctx.ShouldBindJSON(&req.Data)
+
+	req.Data.Name = req.Template
+
+	err := config.OnUpdate(func(c *config.Config) error {
+		for i := range c.Templates {
+			if c.Templates[i].Name == req.Template {
+				c.Templates[i] = &req.Data
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	reloadTemplate(req.Template)
+
+	ctx.JSON(http.StatusOK, Response{
+		Msg: "OK",
+	})
+}
+
+// swagger:parameters deleteTemplateRequest
+type deleteTemplateRequest struct {
+	// in: path
+	// required: true
+	Template string `uri:"template" json:"template"`
+}
+
+// successful operation.
+// swagger:response deleteTemplateResponse
+type deleteTemplateResponse struct {
+	Data Response
+}
+
+func deleteTemplate(ctx *gin.Context) {
+	// swagger:route DELETE /config/templates/{template} Template deleteTemplateRequest
+	//
+	// Delete hop template by name.
+	// The template can not be deleted while it is used by any hop or chain.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: deleteTemplateResponse
+
+	var req deleteTemplateRequest
+	ctx.ShouldBindUri(&req)
+
+	err := config.OnUpdate(func(c *config.Config) error {
+		// the hops and chains built from the template would be left with the stale settings.
+		for _, hc := range c.Hops {
+			if useTemplate(hc, req.Template) {
+				return ErrInUse
+			}
+		}
+		for _, cc := range c.Chains {
+			if cc == nil {
+				continue
+			}
+			for _, hc := range cc.Hops {
+				if useTemplate(hc, req.Template) {
+					return ErrInUse
+				}
+			}
+		}
+
+		templates := c.Templates
+		c.Templates = nil
+		found := false
+		for _, s := range templates {
+			if s.Name == req.Template {
+				found = true
+				continue
+			}
+			c.Templates = append(c.Templates, s)
+		}
+		if !found {
+			c.Templates = templates
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Response{
+		Msg: "OK",
+	})
+}
+
+// reloadTemplate re-creates the hops and chains whose nodes use the template,
+// so the changes of the template propagate to them.
+func reloadTemplate(name string) {
+	log := logger.Default()
+	cfg := config.Global()
+
+	for _, hc := range cfg.Hops {
+		if !useTemplate(hc, name) || !registry.HopRegistry().IsRegistered(hc.Name) {
+			continue
+		}
+		v, err := hop_parser.ParseHop(hc, log, cfg.Templates...)
+		if err != nil {
+			log.Errorf("reload hop %s: %v", hc.Name, err)
+			continue
+		}
+		registry.HopRegistry().Unregister(hc.Name)
+		registry.HopRegistry().Register(hc.Name, v)
+	}
+
+	for _, cc := range cfg.Chains {
+		if cc == nil || !registry.ChainRegistry().IsRegistered(cc.Name) {
+			continue
+		}
+		found := false
+		for _, hc := range cc.Hops {
+			if useTemplate(hc, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		v, err := chain_parser.ParseChain(cc, log, cfg.Templates...)
+		if err != nil {
+			log.Errorf("reload chain %s: %v", cc.Name, err)
+			continue
+		}
+		registry.ChainRegistry().Unregister(cc.Name)
+		registry.ChainRegistry().Register(cc.Name, v)
+	}
+}
+
+func useTemplate(hc *config.HopConfig, name string) bool {
+	if hc == nil {
+		return false
+	}
+	if hc.SD != nil && hc.SD.Template == name {
+		return true
+	}
+	for _, node := range hc.Nodes {
+		if node != nil && node.Template == name {
+			return true
+		}
+	}
+	return false
+}
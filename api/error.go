@@ -8,11 +8,13 @@ import (
 )
 
 var (
-	ErrInvalid  = &Error{statusCode: http.StatusBadRequest, Code: 40001, Msg: "object invalid"}
-	ErrDup      = &Error{statusCode: http.StatusBadRequest, Code: 40002, Msg: "object duplicated"}
-	ErrCreate   = &Error{statusCode: http.StatusConflict, Code: 40003, Msg: "object creation failed"}
-	ErrNotFound = &Error{statusCode: http.StatusBadRequest, Code: 40004, Msg: "object not found"}
-	ErrSave     = &Error{statusCode: http.StatusInternalServerError, Code: 40005, Msg: "save config failed"}
+	ErrInvalid   = &Error{statusCode: http.StatusBadRequest, Code: 40001, Msg: "object invalid"}
+	ErrDup       = &Error{statusCode: http.StatusBadRequest, Code: 40002, Msg: "object duplicated"}
+	ErrCreate    = &Error{statusCode: http.StatusConflict, Code: 40003, Msg: "object creation failed"}
+	ErrNotFound  = &Error{statusCode: http.StatusBadRequest, Code: 40004, Msg: "object not found"}
+	ErrSave      = &Error{statusCode: http.StatusInternalServerError, Code: 40005, Msg: "save config failed"}
+	ErrForbidden = &Error{statusCode: http.StatusForbidden, Code: 40007, Msg: "object forbidden"}
+	ErrInUse     = &Error{statusCode: http.StatusConflict, Code: 40008, Msg: "object in use"}
 )
 
 // Error is an api error.
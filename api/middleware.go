@@ -29,12 +29,29 @@ func mwLogger() gin.HandlerFunc {
 	}
 }
 
-func mwBasicAuth(auther auth.Authenticator) gin.HandlerFunc {
+// mwBasicAuth authenticates the request by the tenants first, then the auther of the API,
+// the namespace of the authenticated tenant is saved in the context.
+// If there are tenants, the administrators must be authenticated by the auther of the API.
+func mwBasicAuth(auther auth.Authenticator, tenants []tenant) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		u, p, ok := c.Request.BasicAuth()
+		if ok {
+			for _, t := range tenants {
+				if _, ok := t.auther.Authenticate(c, u, p); ok {
+					c.Set(namespaceKey, t.namespace)
+					return
+				}
+			}
+		}
+
+		if auther == nil && len(tenants) == 0 {
+			return
+		}
 		if auther == nil {
+			c.Writer.Header().Set("WWW-Authenticate", "Basic")
+			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-		u, p, _ := c.Request.BasicAuth()
 		if _, ok := auther.Authenticate(c, u, p); !ok {
 			c.Writer.Header().Set("WWW-Authenticate", "Basic")
 			c.AbortWithStatus(http.StatusUnauthorized)
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/x/selftest"
+)
+
+// swagger:parameters getSelfTestListRequest
+type getSelfTestListRequest struct {
+}
+
+// successful operation.
+// swagger:response getSelfTestListResponse
+type getSelfTestListResponse struct {
+	// in: body
+	Results selfTestList
+}
+
+type selfTestList struct {
+	Count int              `json:"count"`
+	List  []selfTestResult `json:"list"`
+}
+
+type selfTestResult struct {
+	Service string `json:"service"`
+	Addr    string `json:"addr,omitempty"`
+	// the protocol of the client, e.g. socks5+tls.
+	Protocol string `json:"protocol,omitempty"`
+	// the state of the test, one of pass|fail|skip.
+	State string `json:"state"`
+	// the reason of the failed or skipped test.
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	// the time of the test in unix seconds.
+	Time int64 `json:"time"`
+}
+
+func getSelfTestList(ctx *gin.Context) {
+	// swagger:route GET /diag/selftest Diagnostic getSelfTestListRequest
+	//
+	// Get the last self-test results of the services.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: getSelfTestListResponse
+
+	ctx.JSON(http.StatusOK, newSelfTestList(selftest.DefaultTester().Results()))
+}
+
+// swagger:parameters runSelfTestRequest
+type runSelfTestRequest struct {
+	// the names of the services to test, all services are tested if it is empty.
+	// in: query
+	Service []string `form:"service" json:"service"`
+}
+
+// successful operation.
+// swagger:response runSelfTestResponse
+type runSelfTestResponse struct {
+	// in: body
+	Results selfTestList
+}
+
+func runSelfTest(ctx *gin.Context) {
+	// swagger:route POST /diag/selftest Diagnostic runSelfTestRequest
+	//
+	// Test the services by connecting to them locally as a client.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: runSelfTestResponse
+
+	var req runSelfTestRequest
+	ctx.ShouldBindQuery(&req)
+
+	results := selftest.DefaultTester().Run(ctx.Request.Context(), req.Service...)
+	ctx.JSON(http.StatusOK, newSelfTestList(results))
+}
+
+func newSelfTestList(results []*selftest.Result) *selfTestList {
+	list := &selfTestList{
+		Count: len(results),
+		List:  []selfTestResult{},
+	}
+	for _, r := range results {
+		list.List = append(list.List, selfTestResult{
+			Service:  r.Service,
+			Addr:     r.Addr,
+			Protocol: r.Protocol,
+			State:    r.State,
+			Error:    r.Error,
+			Duration: r.Duration.String(),
+			Time:     r.Time.Unix(),
+		})
+	}
+	return list
+}
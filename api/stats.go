@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/x/observer/history"
+	"github.com/go-gost/x/registry"
+)
+
+// swagger:parameters getStatsHistoryRequest
+type getStatsHistoryRequest struct {
+	// the name of the service.
+	// in: query
+	// required: true
+	Service string `form:"service" json:"service"`
+	// the client of the service, the service itself if it is empty.
+	// in: query
+	Client string `form:"client" json:"client"`
+	// the resolution of the points, one of 1m|1h, default is 1m.
+	// in: query
+	Resolution string `form:"resolution" json:"resolution"`
+	// the start of the time range in unix seconds, default is the retention of the resolution ago.
+	// in: query
+	From int64 `form:"from" json:"from"`
+	// the end of the time range in unix seconds, default is now.
+	// in: query
+	To int64 `form:"to" json:"to"`
+}
+
+// successful operation.
+// swagger:response getStatsHistoryResponse
+type getStatsHistoryResponse struct {
+	// in: body
+	History statsHistory
+}
+
+type statsHistory struct {
+	Service    string `json:"service"`
+	Client     string `json:"client,omitempty"`
+	Resolution string `json:"resolution"`
+	// the clients of the service with the history, set if the client is not specified.
+	Clients []string        `json:"clients,omitempty"`
+	Points  []history.Point `json:"points"`
+}
+
+func getStatsHistory(ctx *gin.Context) {
+	// swagger:route GET /stats/history Stats getStatsHistoryRequest
+	//
+	// Get the traffic history of the service or the client of the service.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: getStatsHistoryResponse
+
+	var req getStatsHistoryRequest
+	ctx.ShouldBindQuery(&req)
+
+	if req.Service == "" {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+	service := registry.QualifiedName(namespaceFromContext(ctx), req.Service)
+
+	resolution := history.ResolutionMinute
+	switch req.Resolution {
+	case "1h":
+		resolution = history.ResolutionHour
+	default:
+		req.Resolution = "1m"
+	}
+	store := history.DefaultStore()
+
+	to := time.Now()
+	if req.To > 0 {
+		to = time.Unix(req.To, 0)
+	}
+	from := to.Add(-store.Retention(resolution))
+	if req.From > 0 {
+		from = time.Unix(req.From, 0)
+	}
+	if from.After(to) {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+
+	resp := statsHistory{
+		Service:    service,
+		Client:     req.Client,
+		Resolution: req.Resolution,
+		Points:     store.Query(service, req.Client, resolution, from, to),
+	}
+	if req.Client == "" {
+		resp.Clients = store.Clients(service)
+	}
+
+	ctx.JSON(http.StatusOK, &resp)
+}
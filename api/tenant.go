@@ -0,0 +1,391 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/core/auth"
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
+	"github.com/go-gost/x/registry"
+)
+
+const (
+	// namespaceKey saves the namespace of the tenant in the context.
+	namespaceKey = "namespace"
+)
+
+var (
+	// the handler and listener types the services of the tenants can use,
+	// the others access the resources of the host (e.g. tun, redirect, file and unix).
+	tenantHandlers = map[string]bool{
+		"": true, "auto": true, "forward": true, "tcp": true, "udp": true,
+		"http": true, "http2": true, "http3": true, "relay": true, "sni": true, "tunnel": true,
+		"socks": true, "socks4": true, "socks4a": true, "socks5": true, "ss": true, "ssu": true,
+	}
+	tenantListeners = map[string]bool{
+		"": true, "tcp": true, "udp": true, "tls": true, "mtls": true, "dtls": true, "mtcp": true, "ftcp": true, "uot": true,
+		"ws": true, "wss": true, "mws": true, "mwss": true, "h2": true, "h2c": true, "http2": true, "h3": true, "http3": true,
+		"grpc": true, "kcp": true, "quic": true, "ssh": true, "pht": true, "phts": true, "ohttp": true, "otls": true, "wt": true,
+	}
+	// the dialer and connector types the nodes of the tenants can use,
+	// the others access the resources of the host (e.g. unix, serial and icmp) or drop the traffic (sinkhole).
+	tenantDialers = map[string]bool{
+		"": true, "direct": true, "tcp": true, "udp": true, "tls": true, "mtls": true, "dtls": true, "mtcp": true, "ftcp": true, "uot": true,
+		"ws": true, "wss": true, "mws": true, "mwss": true, "h2": true, "h2c": true, "http2": true, "h3": true, "http3": true,
+		"grpc": true, "kcp": true, "quic": true, "ssh": true, "sshd": true, "pht": true, "phts": true, "ohttp": true, "otls": true, "wt": true,
+	}
+	tenantConnectors = map[string]bool{
+		"": true, "direct": true, "forward": true, "tcp": true, "http": true, "http2": true, "relay": true, "sni": true, "tunnel": true,
+		"socks": true, "socks4": true, "socks4a": true, "socks5": true, "ss": true, "ssu": true, "sshd": true,
+	}
+	// the networks the nodes of the tenants can dial, the unix sockets are on the host.
+	tenantNetworks = map[string]bool{
+		"": true, "tcp": true, "tcp4": true, "tcp6": true, "udp": true, "udp4": true, "udp6": true,
+	}
+	// the metadata running the commands on the host, binding to the interfaces or reading the files of the host,
+	// the keys ending with file or dir are also forbidden.
+	tenantForbiddenMetadata = map[string]bool{
+		strings.ToLower(parsing.MDKeyPreUp):     true,
+		strings.ToLower(parsing.MDKeyPostUp):    true,
+		strings.ToLower(parsing.MDKeyPreDown):   true,
+		strings.ToLower(parsing.MDKeyPostDown):  true,
+		strings.ToLower(parsing.MDKeyInterface): true,
+		strings.ToLower(parsing.MDKeySoMark):    true,
+		"netns":                                 true,
+		"authorizedkeys":                        true,
+		"fd.socket":                             true,
+	}
+)
+
+type tenant struct {
+	namespace string
+	auther    auth.Authenticator
+}
+
+// namespaceFromContext returns the namespace of the tenant of the request, it is empty for the administrators.
+func namespaceFromContext(ctx *gin.Context) string {
+	return ctx.GetString(namespaceKey)
+}
+
+// mwNamespace confines the requests of the tenants to the objects in their namespaces,
+// the names in the path and in the body are qualified by the namespace of the tenant.
+// The tenants can only read the config and the stats, trace the chains, and manage the services, chains, authers and limiters.
+func mwNamespace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ns := namespaceFromContext(c)
+		if ns == "" {
+			return
+		}
+
+		fullPath := c.FullPath()
+		// the service of the stats and the chain of the trace are qualified by the handlers.
+		if strings.HasSuffix(fullPath, "/stats/history") || strings.HasSuffix(fullPath, "/diag/trace") {
+			return
+		}
+		_, resource, ok := strings.Cut(fullPath, "/config")
+		if !ok {
+			writeError(c, ErrForbidden)
+			c.Abort()
+			return
+		}
+		// e.g. services/:service/maintenance
+		parts := strings.Split(strings.TrimPrefix(resource, "/"), "/")
+		resource = parts[0]
+		var sub string
+		if len(parts) > 2 {
+			sub = parts[2]
+		}
+
+		// qualify qualifies the names in the body, the body is passed unchanged if it is nil.
+		var qualify func(ns string, b []byte) ([]byte, error)
+		switch resource {
+		case "":
+			if c.Request.Method != http.MethodGet {
+				writeError(c, ErrForbidden)
+				c.Abort()
+			}
+			return
+		case "services":
+			switch sub {
+			case "":
+				qualify = qualifyJSON(qualifyService)
+			case "chain":
+				// the new chain of the service must be in the namespace.
+				qualify = qualifyJSON(func(ns string, v *chainSwap) error {
+					v.Chain = registry.QualifiedName(ns, v.Chain)
+					return nil
+				})
+			case "effective", "maintenance", "schedule":
+				// the bodies carry no names.
+			default:
+				writeError(c, ErrForbidden)
+				c.Abort()
+				return
+			}
+		case "chains":
+			qualify = qualifyJSON(qualifyChain)
+		case "authers":
+			qualify = qualifyJSON(func(ns string, cfg *config.AutherConfig) error {
+				// the loaders and plugins access the files and the services of the host.
+				if cfg.File != nil || cfg.Redis != nil || cfg.HTTP != nil || cfg.Plugin != nil {
+					return ErrForbidden
+				}
+				cfg.Name = registry.QualifiedName(ns, cfg.Name)
+				return nil
+			})
+		case "limiters", "climiters", "rlimiters":
+			qualify = qualifyJSON(func(ns string, cfg *config.LimiterConfig) error {
+				if cfg.File != nil || cfg.Redis != nil || cfg.HTTP != nil || cfg.Plugin != nil {
+					return ErrForbidden
+				}
+				cfg.Name = registry.QualifiedName(ns, cfg.Name)
+				return nil
+			})
+		default:
+			writeError(c, ErrForbidden)
+			c.Abort()
+			return
+		}
+
+		for i := range c.Params {
+			c.Params[i].Value = registry.QualifiedName(ns, c.Params[i].Value)
+		}
+
+		if qualify == nil || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			return
+		}
+		b, err := io.ReadAll(c.Request.Body)
+		c.Request.Body.Close()
+		if err != nil {
+			writeError(c, ErrInvalid)
+			c.Abort()
+			return
+		}
+		if len(bytes.TrimSpace(b)) > 0 {
+			if b, err = qualify(ns, b); err != nil {
+				if e, ok := err.(*Error); ok {
+					writeError(c, e)
+				} else {
+					writeError(c, ErrInvalid)
+				}
+				c.Abort()
+				return
+			}
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(b))
+		c.Request.ContentLength = int64(len(b))
+	}
+}
+
+func qualifyJSON[T any](fn func(ns string, v *T) error) func(ns string, b []byte) ([]byte, error) {
+	return func(ns string, b []byte) ([]byte, error) {
+		v := new(T)
+		if err := json.Unmarshal(b, v); err != nil {
+			return nil, err
+		}
+		if err := fn(ns, v); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}
+}
+
+func qualifyNames(ns string, names []string) {
+	for i := range names {
+		names[i] = registry.QualifiedName(ns, names[i])
+	}
+}
+
+func qualifyChainGroup(ns string, cfg *config.ChainGroupConfig) {
+	if cfg != nil {
+		qualifyNames(ns, cfg.Chains)
+	}
+}
+
+// tenantMetadata reports whether the metadata can be used by the tenants.
+func tenantMetadata(md map[string]any) bool {
+	for k, v := range md {
+		k = strings.ToLower(k)
+		if tenantForbiddenMetadata[k] || strings.HasSuffix(k, "file") || strings.HasSuffix(k, "dir") {
+			return false
+		}
+		// e.g. the fallback serving the files of the host.
+		if s, ok := v.(string); ok && strings.HasPrefix(s, "file:") {
+			return false
+		}
+	}
+	return true
+}
+
+// tenantTLS reports whether the TLS config can be used by the tenants, the certificates are not loaded from the files of the host.
+func tenantTLS(cfg *config.TLSConfig) bool {
+	return cfg == nil || (cfg.CertFile == "" && cfg.KeyFile == "" && cfg.CAFile == "" && cfg.ACME == nil)
+}
+
+// tenantNode reports whether the node can be used by the tenants.
+func tenantNode(cfg *config.NodeConfig) bool {
+	if cfg == nil {
+		return true
+	}
+	if !tenantNetworks[strings.ToLower(cfg.Network)] || cfg.Interface != "" || cfg.SockOpts != nil || !tenantMetadata(cfg.Metadata) {
+		return false
+	}
+	if cfg.Template != "" {
+		// the dialer and connector of the template are checked as the node's.
+		for _, t := range config.Global().Templates {
+			if t != nil && t.Name == cfg.Template && !tenantNode(&config.NodeConfig{Dialer: t.Dialer, Connector: t.Connector}) {
+				return false
+			}
+		}
+	}
+	if d := cfg.Dialer; d != nil && (!tenantDialers[d.Type] || !tenantTLS(d.TLS) || !tenantMetadata(d.Metadata)) {
+		return false
+	}
+	if c := cfg.Connector; c != nil && (!tenantConnectors[c.Type] || !tenantTLS(c.TLS) || !tenantMetadata(c.Metadata)) {
+		return false
+	}
+	return true
+}
+
+// tenantService reports whether the service can be used by the tenants,
+// the services running the commands, accessing the resources of the host,
+// or using the handlers and listeners not allowed for the tenants are forbidden.
+func tenantService(cfg *config.ServiceConfig) bool {
+	if cfg.Interface != "" || cfg.SockOpts != nil || cfg.Egress != nil || !tenantMetadata(cfg.Metadata) {
+		return false
+	}
+	if h := cfg.Handler; h != nil {
+		if !tenantHandlers[h.Type] || !tenantTLS(h.TLS) || !tenantMetadata(h.Metadata) {
+			return false
+		}
+		for _, m := range h.Middlewares {
+			if m != nil && !tenantMetadata(m.Metadata) {
+				return false
+			}
+		}
+	}
+	if l := cfg.Listener; l != nil && (!tenantListeners[l.Type] || !tenantTLS(l.TLS) || !tenantMetadata(l.Metadata)) {
+		return false
+	}
+	if p := cfg.Preset; p != nil && (!tenantTLS(p.TLS) || strings.HasPrefix(p.Fallback, "file:")) {
+		return false
+	}
+	if f := cfg.Forwarder; f != nil {
+		if f.SD != nil {
+			return false
+		}
+		for _, node := range f.Nodes {
+			if node == nil {
+				continue
+			}
+			if !tenantNetworks[strings.ToLower(node.Network)] || node.Interface != "" || node.SockOpts != nil || !tenantMetadata(node.Metadata) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// qualifyService qualifies the name of the service and the chains, authers and limiters it references,
+// the other objects (e.g. bypasses, resolvers and loggers) are shared by all the namespaces.
+func qualifyService(ns string, cfg *config.ServiceConfig) error {
+	if !tenantService(cfg) {
+		return ErrForbidden
+	}
+
+	cfg.Name = registry.QualifiedName(ns, cfg.Name)
+	cfg.Limiter = registry.QualifiedName(ns, cfg.Limiter)
+	cfg.CLimiter = registry.QualifiedName(ns, cfg.CLimiter)
+	cfg.RLimiter = registry.QualifiedName(ns, cfg.RLimiter)
+	qualifyNames(ns, cfg.DependsOn)
+	// the status is read-only.
+	cfg.Status = nil
+
+	if h := cfg.Handler; h != nil {
+		h.Chain = registry.QualifiedName(ns, h.Chain)
+		qualifyChainGroup(ns, h.ChainGroup)
+		h.Auther = registry.QualifiedName(ns, h.Auther)
+		qualifyNames(ns, h.Authers)
+		h.Limiter = registry.QualifiedName(ns, h.Limiter)
+	}
+	if l := cfg.Listener; l != nil {
+		l.Chain = registry.QualifiedName(ns, l.Chain)
+		qualifyChainGroup(ns, l.ChainGroup)
+		l.Auther = registry.QualifiedName(ns, l.Auther)
+		qualifyNames(ns, l.Authers)
+	}
+	return nil
+}
+
+// qualifyChain qualifies the name of the chain, the chains of the rules and the referenced hops.
+// The chains loading the hops from the loaders or plugins, or accessing the resources of the host are forbidden.
+func qualifyChain(ns string, cfg *config.ChainConfig) error {
+	if cfg.Interface != "" || cfg.SockOpts != nil || cfg.SystemProxy != nil || !tenantMetadata(cfg.Metadata) {
+		return ErrForbidden
+	}
+	for _, hop := range cfg.Hops {
+		if hop == nil {
+			continue
+		}
+		if hop.Interface != "" || hop.SockOpts != nil ||
+			hop.File != nil || hop.Redis != nil || hop.HTTP != nil || hop.SD != nil || hop.Plugin != nil {
+			return ErrForbidden
+		}
+		for _, node := range hop.Nodes {
+			if !tenantNode(node) {
+				return ErrForbidden
+			}
+		}
+	}
+
+	cfg.Name = registry.QualifiedName(ns, cfg.Name)
+	for _, hop := range cfg.Hops {
+		if hop != nil && hop.Nodes == nil && hop.Plugin == nil {
+			hop.Name = registry.QualifiedName(ns, hop.Name)
+		}
+	}
+	for _, rule := range cfg.Rules {
+		if rule != nil {
+			rule.Chain = registry.QualifiedName(ns, rule.Chain)
+		}
+	}
+	return nil
+}
+
+// filterConfig returns the config of the objects in the namespace.
+func filterConfig(cfg *config.Config, ns string) *config.Config {
+	c := &config.Config{}
+	for _, v := range cfg.Services {
+		if v != nil && registry.InNamespace(v.Name, ns) {
+			c.Services = append(c.Services, v)
+		}
+	}
+	for _, v := range cfg.Chains {
+		if v != nil && registry.InNamespace(v.Name, ns) {
+			c.Chains = append(c.Chains, v)
+		}
+	}
+	for _, v := range cfg.Authers {
+		if v != nil && registry.InNamespace(v.Name, ns) {
+			c.Authers = append(c.Authers, v)
+		}
+	}
+	c.Limiters = filterLimiters(cfg.Limiters, ns)
+	c.CLimiters = filterLimiters(cfg.CLimiters, ns)
+	c.RLimiters = filterLimiters(cfg.RLimiters, ns)
+	return c
+}
+
+func filterLimiters(limiters []*config.LimiterConfig, ns string) (list []*config.LimiterConfig) {
+	for _, v := range limiters {
+		if v != nil && registry.InNamespace(v.Name, ns) {
+			list = append(list, v)
+		}
+	}
+	return
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/core/logger"
+	xchain "github.com/go-gost/x/chain"
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/registry"
+)
+
+const (
+	defaultTraceTimeout = 15 * time.Second
+)
+
+// swagger:parameters traceChainRequest
+type traceChainRequest struct {
+	// the name of the chain.
+	// in: query
+	// required: true
+	Chain string `form:"chain" json:"chain"`
+	// the target address, e.g. example.com:80.
+	// in: query
+	// required: true
+	Address string `form:"address" json:"address"`
+	// the network of the target, default is tcp.
+	// in: query
+	Network string `form:"network" json:"network"`
+	// the timeout of the trace, default is 15s.
+	// in: query
+	Timeout string `form:"timeout" json:"timeout"`
+}
+
+// successful operation.
+// swagger:response traceChainResponse
+type traceChainResponse struct {
+	// in: body
+	Trace chainTrace
+}
+
+type chainTrace struct {
+	Chain   string `json:"chain"`
+	Network string `json:"network"`
+	Address string `json:"address"`
+	// the hops of the route in order.
+	Hops []chainTraceHop `json:"hops"`
+	// the time to connect to the target through the last hop.
+	Connect string `json:"connect,omitempty"`
+	// the total time of the trace.
+	Duration string `json:"duration"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+type chainTraceHop struct {
+	Node string `json:"node"`
+	Addr string `json:"addr"`
+	// the resolved address of the node.
+	Resolved string `json:"resolved,omitempty"`
+	// the connector and dialer of the node, e.g. socks5+tls.
+	Protocol string `json:"protocol,omitempty"`
+	// the time to reach the node.
+	Connect string `json:"connect,omitempty"`
+	// the time of the handshake with the node.
+	Handshake string `json:"handshake,omitempty"`
+	// the TLS version of the connection to the node, if the node is reached by TLS.
+	TLSVersion  string `json:"tlsVersion,omitempty"`
+	CipherSuite string `json:"cipherSuite,omitempty"`
+	ALPN        string `json:"alpn,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func traceChain(ctx *gin.Context) {
+	// swagger:route GET /diag/trace Diagnostic traceChainRequest
+	//
+	// Connect to the target through the chain hop by hop, and report the timings, protocols and errors of each hop.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: traceChainResponse
+
+	var req traceChainRequest
+	ctx.ShouldBindQuery(&req)
+
+	if req.Chain == "" || req.Address == "" {
+		writeError(ctx, ErrInvalid)
+		return
+	}
+	name := registry.QualifiedName(namespaceFromContext(ctx), req.Chain)
+
+	if !registry.ChainRegistry().IsRegistered(name) {
+		writeError(ctx, ErrNotFound)
+		return
+	}
+	chainer := registry.ChainRegistry().Get(name)
+
+	if req.Network == "" {
+		req.Network = "tcp"
+	}
+	timeout := defaultTraceTimeout
+	if v, _ := time.ParseDuration(req.Timeout); v > 0 {
+		timeout = v
+	}
+
+	c, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+	defer cancel()
+
+	log := logger.Default().WithFields(map[string]any{
+		"kind":  "api",
+		"chain": name,
+	})
+	tr := xchain.TraceRoute(c, chainer.Route(c, req.Network, req.Address), req.Network, req.Address, log)
+
+	protocols := nodeProtocols()
+	resp := chainTrace{
+		Chain:    name,
+		Network:  tr.Network,
+		Address:  tr.Address,
+		Hops:     []chainTraceHop{},
+		Duration: tr.Duration.String(),
+		OK:       tr.Error == "",
+		Error:    tr.Error,
+	}
+	if tr.Connect > 0 {
+		resp.Connect = tr.Connect.String()
+	}
+	for _, hop := range tr.Hops {
+		h := chainTraceHop{
+			Node:     hop.Node,
+			Addr:     hop.Addr,
+			Resolved: hop.Resolved,
+			Protocol: protocols[hop.Node],
+			Error:    hop.Error,
+		}
+		if hop.Connect > 0 {
+			h.Connect = hop.Connect.String()
+		}
+		if hop.Handshake > 0 {
+			h.Handshake = hop.Handshake.String()
+		}
+		if hop.TLS != nil {
+			h.TLSVersion = hop.TLS.Version
+			h.CipherSuite = hop.TLS.CipherSuite
+			h.ALPN = hop.TLS.ALPN
+		}
+		resp.Hops = append(resp.Hops, h)
+	}
+
+	ctx.JSON(http.StatusOK, &resp)
+}
+
+// nodeProtocols returns the protocols of the nodes in the config by name.
+func nodeProtocols() map[string]string {
+	cfg := config.Global()
+
+	var hops []*config.HopConfig
+	for _, c := range cfg.Chains {
+		if c != nil {
+			hops = append(hops, c.Hops...)
+		}
+	}
+	hops = append(hops, cfg.Hops...)
+
+	m := make(map[string]string)
+	for _, hop := range hops {
+		if hop == nil {
+			continue
+		}
+		for _, node := range hop.Nodes {
+			if node == nil {
+				continue
+			}
+			var protocol string
+			if node.Connector != nil {
+				protocol = node.Connector.Type
+			}
+			if node.Dialer != nil && node.Dialer.Type != "" {
+				protocol += "+" + node.Dialer.Type
+			}
+			m[node.Name] = protocol
+		}
+	}
+	return m
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-gost/x/handler/tunnel"
+)
+
+// swagger:parameters getTunnelListRequest
+type getTunnelListRequest struct {
+}
+
+// successful operation.
+// swagger:response getTunnelListResponse
+type getTunnelListResponse struct {
+	// in: body
+	Tunnels tunnelList
+}
+
+type tunnelList struct {
+	Count int                 `json:"count"`
+	List  []tunnel.TunnelInfo `json:"list"`
+}
+
+func getTunnelList(ctx *gin.Context) {
+	// swagger:route GET /tunnels Tunnel getTunnelListRequest
+	//
+	// Get the active tunnels of the tunnel handlers.
+	//
+	//     Security:
+	//       basicAuth: []
+	//
+	//     Responses:
+	//       200: getTunnelListResponse
+
+	tunnels := tunnel.Tunnels()
+	ctx.JSON(http.StatusOK, &tunnelList{
+		Count: len(tunnels),
+		List:  tunnels,
+	})
+}
@@ -2,7 +2,9 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
 	"io"
+	"net"
 
 	"github.com/go-gost/core/auth"
 	"github.com/go-gost/core/logger"
@@ -10,11 +12,13 @@ import (
 	ctxvalue "github.com/go-gost/x/ctx"
 	"github.com/go-gost/x/internal/plugin"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type grpcPlugin struct {
 	conn   grpc.ClientConnInterface
 	client proto.AuthenticatorClient
+	cache  *plugin.Cache
 	log    logger.Logger
 }
 
@@ -35,8 +39,9 @@ func NewGRPCPlugin(name string, addr string, opts ...plugin.Option) auth.Authent
 	}
 
 	p := &grpcPlugin{
-		conn: conn,
-		log:  log,
+		conn:  conn,
+		cache: plugin.NewCache(options.CacheTTL),
+		log:   log,
 	}
 
 	if conn != nil {
@@ -51,16 +56,29 @@ func (p *grpcPlugin) Authenticate(ctx context.Context, user, password string, op
 		return "", false
 	}
 
+	client := string(ctxvalue.ClientAddrFromContext(ctx))
+	// the password is hashed, so it is not kept in memory in plain text,
+	// and the client port is ignored, so the decision is shared by the connections of the client.
+	sum := sha256.Sum256([]byte(password))
+	host, _, _ := net.SplitHostPort(client)
+	key := plugin.ContextCacheKey(ctx, user, string(sum[:]), host)
+	if v, ok := p.cache.Get(key); ok {
+		r := v.(*proto.AuthenticateReply)
+		return r.Id, r.Ok
+	}
+
+	var header metadata.MD
 	r, err := p.client.Authenticate(ctx,
 		&proto.AuthenticateRequest{
 			Username: user,
 			Password: password,
-			Client:   string(ctxvalue.ClientAddrFromContext(ctx)),
-		})
+			Client:   client,
+		}, grpc.Header(&header))
 	if err != nil {
 		p.log.Error(err)
 		return "", false
 	}
+	p.cache.Set(key, r, plugin.HeaderTTL(header))
 	return r.Id, r.Ok
 }
 
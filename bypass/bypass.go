@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-gost/core/bypass"
 	"github.com/go-gost/core/logger"
+	ctxvalue "github.com/go-gost/x/ctx"
 	"github.com/go-gost/x/internal/loader"
 	"github.com/go-gost/x/internal/matcher"
 )
@@ -69,10 +70,16 @@ func LoggerOption(logger logger.Logger) Option {
 	}
 }
 
+const (
+	// the prefix of the patterns matching the protocol of the connection, e.g. protocol:bittorrent.
+	protocolPrefix = "protocol:"
+)
+
 type localBypass struct {
 	cidrMatcher     matcher.Matcher
 	addrMatcher     matcher.Matcher
 	wildcardMatcher matcher.Matcher
+	protocols       map[string]struct{}
 	cancelFunc      context.CancelFunc
 	options         options
 	mu              sync.RWMutex
@@ -135,7 +142,15 @@ func (bp *localBypass) reload(ctx context.Context) error {
 	var addrs []string
 	var inets []*net.IPNet
 	var wildcards []string
+	var protocols map[string]struct{}
 	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, protocolPrefix) {
+			if protocols == nil {
+				protocols = make(map[string]struct{})
+			}
+			protocols[strings.ToLower(strings.TrimPrefix(pattern, protocolPrefix))] = struct{}{}
+			continue
+		}
 		if _, inet, err := net.ParseCIDR(pattern); err == nil {
 			inets = append(inets, inet)
 			continue
@@ -153,6 +168,7 @@ func (bp *localBypass) reload(ctx context.Context) error {
 	bp.cidrMatcher = matcher.CIDRMatcher(inets)
 	bp.addrMatcher = matcher.AddrMatcher(addrs)
 	bp.wildcardMatcher = matcher.WildcardMatcher(wildcards)
+	bp.protocols = protocols
 
 	return nil
 }
@@ -231,6 +247,11 @@ func (bp *localBypass) Contains(ctx context.Context, network, addr string, opts
 	}
 
 	matched := bp.matched(addr)
+	if !matched && bp.hasProtocols() {
+		// the protocol is classified after the connection is established, the bypass is checked again then,
+		// so the protocol patterns are mainly for the blacklist.
+		matched = bp.matchProtocol(ctxvalue.ValuesFromContext(ctx).Protocol())
+	}
 
 	b := !bp.options.whitelist && matched ||
 		bp.options.whitelist && !matched
@@ -267,6 +288,23 @@ func (bp *localBypass) matched(addr string) bool {
 	return bp.wildcardMatcher.Match(addr)
 }
 
+func (bp *localBypass) hasProtocols() bool {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+	return len(bp.protocols) > 0
+}
+
+func (bp *localBypass) matchProtocol(protocol string) bool {
+	if protocol == "" {
+		return false
+	}
+
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+	_, ok := bp.protocols[strings.ToLower(protocol)]
+	return ok
+}
+
 func (bp *localBypass) Close() error {
 	bp.cancelFunc()
 	if bp.options.fileLoader != nil {
@@ -10,11 +10,13 @@ import (
 	ctxvalue "github.com/go-gost/x/ctx"
 	"github.com/go-gost/x/internal/plugin"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type grpcPlugin struct {
 	conn   grpc.ClientConnInterface
 	client proto.BypassClient
+	cache  *plugin.Cache
 	log    logger.Logger
 }
 
@@ -35,8 +37,9 @@ func NewGRPCPlugin(name string, addr string, opts ...plugin.Option) bypass.Bypas
 	}
 
 	p := &grpcPlugin{
-		conn: conn,
-		log:  log,
+		conn:  conn,
+		cache: plugin.NewCache(options.CacheTTL),
+		log:   log,
 	}
 	if conn != nil {
 		p.client = proto.NewBypassClient(conn)
@@ -54,18 +57,26 @@ func (p *grpcPlugin) Contains(ctx context.Context, network, addr string, opts ..
 		opt(&options)
 	}
 
+	client := string(ctxvalue.ClientIDFromContext(ctx))
+	key := plugin.ContextCacheKey(ctx, network, addr, client, options.Host, options.Path)
+	if v, ok := p.cache.Get(key); ok {
+		return v.(bool)
+	}
+
+	var header metadata.MD
 	r, err := p.client.Bypass(ctx,
 		&proto.BypassRequest{
 			Network: network,
 			Addr:    addr,
-			Client:  string(ctxvalue.ClientIDFromContext(ctx)),
+			Client:  client,
 			Host:    options.Host,
 			Path:    options.Path,
-		})
+		}, grpc.Header(&header))
 	if err != nil {
 		p.log.Error(err)
 		return true
 	}
+	p.cache.Set(key, r.Ok, plugin.HeaderTTL(header))
 	return r.Ok
 }
 
@@ -0,0 +1,67 @@
+package bypass
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-gost/core/bypass"
+	xnet "github.com/go-gost/x/internal/net"
+)
+
+type portsBypass struct {
+	allow []*xnet.PortRange
+	deny  []*xnet.PortRange
+}
+
+// PortsBypass restricts the destination ports the clients may request, the port can be a single port or a range MIN-MAX.
+// The address is bypassed if the allowed ports are set and do not include its port, or the denied ports include its port.
+// nil is returned if neither is set.
+func PortsBypass(allow, deny []string) bypass.Bypass {
+	bp := &portsBypass{
+		allow: parsePorts(allow),
+		deny:  parsePorts(deny),
+	}
+	if len(bp.allow) == 0 && len(bp.deny) == 0 {
+		return nil
+	}
+	return bp
+}
+
+func (bp *portsBypass) Contains(ctx context.Context, network, addr string, opts ...bypass.Option) bool {
+	_, sp, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	port, err := strconv.Atoi(sp)
+	if err != nil {
+		return false
+	}
+
+	if len(bp.allow) > 0 && !containsPort(bp.allow, port) {
+		return true
+	}
+	return containsPort(bp.deny, port)
+}
+
+func parsePorts(ports []string) (prs []*xnet.PortRange) {
+	for _, s := range ports {
+		for _, v := range strings.Split(s, ",") {
+			pr := &xnet.PortRange{}
+			if err := pr.Parse(strings.TrimSpace(v)); err == nil {
+				prs = append(prs, pr)
+			}
+		}
+	}
+	return
+}
+
+func containsPort(prs []*xnet.PortRange, port int) bool {
+	for _, pr := range prs {
+		if pr.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,12 +2,17 @@ package chain
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/hop"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/metadata"
+	"github.com/go-gost/core/metrics"
 	"github.com/go-gost/core/selector"
+	ctxvalue "github.com/go-gost/x/ctx"
+	xmetrics "github.com/go-gost/x/metrics"
 )
 
 var (
@@ -15,8 +20,12 @@ var (
 )
 
 type ChainOptions struct {
-	Metadata metadata.Metadata
-	Logger   logger.Logger
+	Metadata        metadata.Metadata
+	Rules           []*Rule
+	PoolSize        int
+	PoolIdleTimeout time.Duration
+	sysProxy        *sysProxyRouter
+	Logger          logger.Logger
 }
 
 type ChainOption func(*ChainOptions)
@@ -27,6 +36,24 @@ func MetadataChainOption(md metadata.Metadata) ChainOption {
 	}
 }
 
+// RulesChainOption sets the policy routing rules of the chain.
+// The rules are evaluated in order before the hops of the chain,
+// the first matched rule decides the route of the connection.
+func RulesChainOption(rules ...*Rule) ChainOption {
+	return func(opts *ChainOptions) {
+		opts.Rules = rules
+	}
+}
+
+// PoolChainOption enables the connection pool of the chain,
+// at most size pre-established connections are kept for each route.
+func PoolChainOption(size int, idleTimeout time.Duration) ChainOption {
+	return func(opts *ChainOptions) {
+		opts.PoolSize = size
+		opts.PoolIdleTimeout = idleTimeout
+	}
+}
+
 func LoggerChainOption(logger logger.Logger) ChainOption {
 	return func(opts *ChainOptions) {
 		opts.Logger = logger
@@ -40,6 +67,9 @@ type chainNamer interface {
 type Chain struct {
 	name     string
 	hops     []hop.Hop
+	rules    []*Rule
+	pool     *connPool
+	sysProxy *sysProxyRouter
 	marker   selector.Marker
 	metadata metadata.Metadata
 	logger   logger.Logger
@@ -53,12 +83,18 @@ func NewChain(name string, opts ...ChainOption) *Chain {
 		}
 	}
 
-	return &Chain{
+	c := &Chain{
 		name:     name,
 		metadata: options.Metadata,
+		rules:    options.Rules,
+		sysProxy: options.sysProxy,
 		marker:   selector.NewFailMarker(),
 		logger:   options.Logger,
 	}
+	if options.PoolSize > 0 {
+		c.pool = newConnPool(options.PoolSize, options.PoolIdleTimeout, options.Logger)
+	}
+	return c
 }
 
 func (c *Chain) AddHop(hop hop.Hop) {
@@ -79,8 +115,19 @@ func (c *Chain) Name() string {
 	return c.name
 }
 
+// Close implements io.Closer interface.
+func (c *Chain) Close() error {
+	if c.sysProxy != nil {
+		c.sysProxy.resolver.Close()
+	}
+	if c.pool != nil {
+		return c.pool.Close()
+	}
+	return nil
+}
+
 func (c *Chain) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
-	if c == nil || len(c.hops) == 0 {
+	if c == nil {
 		return nil
 	}
 
@@ -89,7 +136,39 @@ func (c *Chain) Route(ctx context.Context, network, address string, opts ...chai
 		opt(&options)
 	}
 
-	rt := NewRoute(ChainRouteOption(c))
+	for i, rule := range c.rules {
+		if !rule.Match(ctx, network, address, options.Host) {
+			continue
+		}
+		ctxvalue.ValuesFromContext(ctx).AddRule(fmt.Sprintf("%s#%d", c.name, i))
+		if c.logger != nil {
+			c.logger.Debugf("rule matched: %s", address)
+		}
+		// direct egress
+		if rule.Chain() == nil {
+			return nil
+		}
+		return rule.Chain().Route(ctx, network, address, opts...)
+	}
+
+	if c.sysProxy != nil {
+		return c.sysProxy.route(ctx, c, address, options.Host, c.logger)
+	}
+
+	if len(c.hops) == 0 {
+		return nil
+	}
+
+	if v := xmetrics.GetCounter(xmetrics.MetricChainRoutesCounter,
+		metrics.Labels{"chain": c.name}); v != nil {
+		v.Inc()
+	}
+
+	rt := NewRoute(ChainRouteOption(c), poolRouteOption(c.pool))
+	var tracked []trackedNode
+	defer func() {
+		rt.tracked = tracked
+	}()
 	for _, h := range c.hops {
 		node := h.Select(ctx,
 			hop.NetworkSelectOption(network),
@@ -99,6 +178,9 @@ func (c *Chain) Route(ctx context.Context, network, address string, opts ...chai
 		if node == nil {
 			return rt
 		}
+		if t, ok := h.(connTracker); ok {
+			tracked = append(tracked, trackedNode{tracker: t, node: node})
+		}
 		if node.Options().Transport.Multiplex() {
 			tr := node.Options().Transport.Copy()
 			tr.Options().Route = rt
@@ -0,0 +1,211 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+
+	"github.com/go-gost/core/bypass"
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metadata"
+	"github.com/go-gost/core/metrics"
+	"github.com/go-gost/core/recorder"
+	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
+	"github.com/go-gost/x/internal/util/classify"
+	xmetrics "github.com/go-gost/x/metrics"
+	xrecorder "github.com/go-gost/x/recorder"
+)
+
+type ClassifyOptions struct {
+	Service   string
+	Bypass    bypass.Bypass
+	Recorders []recorder.RecorderObject
+	Logger    logger.Logger
+}
+
+type ClassifyOption func(*ClassifyOptions)
+
+func ServiceClassifyOption(service string) ClassifyOption {
+	return func(o *ClassifyOptions) {
+		o.Service = service
+	}
+}
+
+// BypassClassifyOption sets the bypass checked again with the classified protocol,
+// the flow is closed if it is blocked, e.g. by the rule protocol:bittorrent.
+func BypassClassifyOption(bypass bypass.Bypass) ClassifyOption {
+	return func(o *ClassifyOptions) {
+		o.Bypass = bypass
+	}
+}
+
+// RecordersClassifyOption sets the recorders, the classified flows are recorded by the recorder
+// of recorder.service.handler.protocol.
+func RecordersClassifyOption(recorders ...recorder.RecorderObject) ClassifyOption {
+	return func(o *ClassifyOptions) {
+		o.Recorders = recorders
+	}
+}
+
+func LoggerClassifyOption(logger logger.Logger) ClassifyOption {
+	return func(o *ClassifyOptions) {
+		o.Logger = logger
+	}
+}
+
+type classifyChain struct {
+	chain.Chainer
+	options ClassifyOptions
+}
+
+// ClassifyChain classifies the protocol of the flows relayed through the routes of the chain c
+// by the first data sent by the client, c can be nil, then the default route is used.
+func ClassifyChain(c chain.Chainer, opts ...ClassifyOption) chain.Chainer {
+	var options ClassifyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Logger == nil {
+		options.Logger = logger.Default()
+	}
+
+	return &classifyChain{
+		Chainer: c,
+		options: options,
+	}
+}
+
+func (c *classifyChain) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
+	var route chain.Route
+	if c.Chainer != nil {
+		route = c.Chainer.Route(ctx, network, address, opts...)
+	}
+	if route == nil {
+		route = chain.DefaultRoute
+	}
+	return &classifyRoute{
+		Route:   route,
+		options: &c.options,
+	}
+}
+
+type classifyRoute struct {
+	chain.Route
+	options *ClassifyOptions
+}
+
+func (r *classifyRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	conn, err := r.Route.Dial(ctx, network, address, opts...)
+	if err != nil {
+		return conn, err
+	}
+
+	cc := &classifyConn{
+		Conn:    conn,
+		ctx:     ctx,
+		network: network,
+		address: address,
+		options: r.options,
+	}
+	if pc, ok := conn.(net.PacketConn); ok {
+		return &classifyPacketConn{classifyConn: cc, pc: pc}, nil
+	}
+	return cc, nil
+}
+
+// classifyConn classifies the flow by the first data written to the target.
+type classifyConn struct {
+	net.Conn
+	ctx     context.Context
+	network string
+	address string
+	options *ClassifyOptions
+	once    sync.Once
+	blocked bool
+}
+
+func (c *classifyConn) Write(b []byte) (int, error) {
+	if c.classify(b) {
+		return 0, xerrors.ErrBypass
+	}
+	return c.Conn.Write(b)
+}
+
+// classify classifies the flow on the first call, it reports whether the flow is blocked.
+func (c *classifyConn) classify(b []byte) bool {
+	c.once.Do(func() {
+		protocol := classify.Classify(c.network, b)
+
+		values := ctxvalue.ValuesFromContext(c.ctx)
+		if protocol != classify.ProtoUnknown || values.Protocol() == "" {
+			values.SetProtocol(protocol)
+		}
+
+		log := c.options.Logger
+		log.Debugf("classify: %s/%s is %s", c.address, c.network, protocol)
+
+		if v := xmetrics.GetCounter(xmetrics.MetricServiceFlowProtocolsCounter,
+			metrics.Labels{"service": c.options.Service, "protocol": protocol}); v != nil {
+			v.Inc()
+		}
+
+		for _, rec := range c.options.Recorders {
+			if rec.Record != xrecorder.RecorderServiceHandlerProtocol {
+				continue
+			}
+			b, _ := json.Marshal(classifyRecord{
+				Service:  c.options.Service,
+				SID:      string(ctxvalue.SidFromContext(c.ctx)),
+				Client:   string(ctxvalue.ClientAddrFromContext(c.ctx)),
+				Network:  c.network,
+				Address:  c.address,
+				Protocol: protocol,
+			})
+			if err := rec.Recorder.Record(c.ctx, b); err != nil {
+				log.Errorf("record %s: %v", rec.Record, err)
+			}
+		}
+
+		if c.options.Bypass != nil && c.options.Bypass.Contains(c.ctx, c.network, c.address) {
+			log.Debugf("classify: %s flow to %s/%s is blocked by bypass", protocol, c.address, c.network)
+			c.blocked = true
+			c.Conn.Close()
+		}
+	})
+	return c.blocked
+}
+
+func (c *classifyConn) Metadata() metadata.Metadata {
+	if md, ok := c.Conn.(metadata.Metadatable); ok {
+		return md.Metadata()
+	}
+	return nil
+}
+
+type classifyPacketConn struct {
+	*classifyConn
+	pc net.PacketConn
+}
+
+func (c *classifyPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	return c.pc.ReadFrom(p)
+}
+
+func (c *classifyPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if c.classify(p) {
+		return 0, xerrors.ErrBypass
+	}
+	return c.pc.WriteTo(p, addr)
+}
+
+type classifyRecord struct {
+	Service  string `json:"service,omitempty"`
+	SID      string `json:"sid,omitempty"`
+	Client   string `json:"client,omitempty"`
+	Network  string `json:"network"`
+	Address  string `json:"address"`
+	Protocol string `json:"protocol"`
+}
@@ -0,0 +1,240 @@
+package chain
+
+import (
+	"context"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	ctxvalue "github.com/go-gost/x/ctx"
+)
+
+const (
+	EgressStrategyRound  = "round"
+	EgressStrategyRandom = "random"
+	// the source IP is selected by the hash of the session, so that the session always uses the same IP.
+	EgressStrategyHash = "hash"
+
+	defaultEgressStickyTTL = 10 * time.Minute
+)
+
+// EgressRule maps the connections matching the rule to the pool of the source IPs.
+type EgressRule struct {
+	Rule *Rule
+	IPs  []string
+}
+
+type EgressOptions struct {
+	IPs       []string
+	Rules     []EgressRule
+	Strategy  string
+	StickyTTL time.Duration
+	Logger    logger.Logger
+}
+
+type EgressOption func(*EgressOptions)
+
+// IPsEgressOption sets the default pool of the source IPs, the IP can also be an interface name.
+func IPsEgressOption(ips ...string) EgressOption {
+	return func(o *EgressOptions) {
+		o.IPs = ips
+	}
+}
+
+// RulesEgressOption sets the rules mapping the users or the destinations to the pools of the source IPs,
+// the first matched rule is used, the default pool is used if no rule is matched.
+func RulesEgressOption(rules ...EgressRule) EgressOption {
+	return func(o *EgressOptions) {
+		o.Rules = rules
+	}
+}
+
+// StrategyEgressOption sets the strategy selecting the source IP from the pool for a new session,
+// one of round (default), random and hash.
+func StrategyEgressOption(strategy string) EgressOption {
+	return func(o *EgressOptions) {
+		o.Strategy = strategy
+	}
+}
+
+// StickyTTLEgressOption sets the idle time after which the session is assigned a new source IP, default is 10m.
+func StickyTTLEgressOption(ttl time.Duration) EgressOption {
+	return func(o *EgressOptions) {
+		o.StickyTTL = ttl
+	}
+}
+
+func LoggerEgressOption(logger logger.Logger) EgressOption {
+	return func(o *EgressOptions) {
+		o.Logger = logger
+	}
+}
+
+type egressSession struct {
+	ip      string
+	expires time.Time
+}
+
+// Egress selects the source IPs of the connections dialed directly by the service.
+// A session is the authenticated user, or the client IP if the client is not authenticated,
+// the session sticks to the selected IP of a pool until it is idle for the sticky TTL.
+type Egress struct {
+	// the round robin counters of the pools, the index len(rules) is the default pool.
+	counters []uint32
+	sessions map[string]*egressSession
+	sweep    time.Time
+	rand     *rand.Rand
+	mu       sync.Mutex
+	options  EgressOptions
+}
+
+func NewEgress(opts ...EgressOption) *Egress {
+	var options EgressOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.StickyTTL <= 0 {
+		options.StickyTTL = defaultEgressStickyTTL
+	}
+
+	return &Egress{
+		counters: make([]uint32, len(options.Rules)+1),
+		sessions: make(map[string]*egressSession),
+		sweep:    time.Now(),
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		options:  options,
+	}
+}
+
+// Select returns the source IP of the connection to address, empty string means the default source IP.
+// The host is the original requested address before resolving, and may be empty.
+func (e *Egress) Select(ctx context.Context, network, address, host string) string {
+	if e == nil {
+		return ""
+	}
+
+	pool := len(e.options.Rules)
+	ips := e.options.IPs
+	for i, rule := range e.options.Rules {
+		if rule.Rule.Match(ctx, network, address, host) {
+			pool, ips = i, rule.IPs
+			break
+		}
+	}
+
+	switch len(ips) {
+	case 0:
+		return ""
+	case 1:
+		return ips[0]
+	}
+
+	session := string(ctxvalue.ClientIDFromContext(ctx))
+	if session == "" {
+		session, _, _ = net.SplitHostPort(string(ctxvalue.ClientAddrFromContext(ctx)))
+	}
+
+	if e.options.Strategy == EgressStrategyHash {
+		return ips[crc32.ChecksumIEEE([]byte(session))%uint32(len(ips))]
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(e.sweep) > e.options.StickyTTL {
+		for k, v := range e.sessions {
+			if now.After(v.expires) {
+				delete(e.sessions, k)
+			}
+		}
+		e.sweep = now
+	}
+
+	// the sessions of the pools are independent, the user may use different pools for different destinations.
+	key := strconv.Itoa(pool) + "/" + session
+	if s := e.sessions[key]; s != nil && now.Before(s.expires) {
+		s.expires = now.Add(e.options.StickyTTL)
+		return s.ip
+	}
+
+	var ip string
+	switch e.options.Strategy {
+	case EgressStrategyRandom:
+		ip = ips[e.rand.Intn(len(ips))]
+	default:
+		ip = ips[e.counters[pool]%uint32(len(ips))]
+		e.counters[pool]++
+	}
+	if session != "" {
+		e.sessions[key] = &egressSession{
+			ip:      ip,
+			expires: now.Add(e.options.StickyTTL),
+		}
+	}
+	if e.options.Logger != nil {
+		e.options.Logger.Debugf("egress: session %s uses %s", session, ip)
+	}
+
+	return ip
+}
+
+type egressChain struct {
+	chain.Chainer
+	egress *Egress
+}
+
+// EgressChain binds the connections dialed directly by the routes of the chain c to the source IPs selected by egress,
+// the connections dialed through the nodes are not affected. c can be nil, then the default route is used.
+func EgressChain(c chain.Chainer, egress *Egress) chain.Chainer {
+	if egress == nil {
+		return c
+	}
+	return &egressChain{
+		Chainer: c,
+		egress:  egress,
+	}
+}
+
+func (c *egressChain) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
+	var route chain.Route
+	if c.Chainer != nil {
+		route = c.Chainer.Route(ctx, network, address, opts...)
+	}
+	if route == nil {
+		route = chain.DefaultRoute
+	}
+	if len(route.Nodes()) > 0 {
+		return route
+	}
+
+	var options chain.RouteOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	ip := c.egress.Select(ctx, network, address, options.Host)
+	if ip == "" {
+		return route
+	}
+	return &egressRoute{
+		Route: route,
+		ip:    ip,
+	}
+}
+
+type egressRoute struct {
+	chain.Route
+	ip string
+}
+
+func (r *egressRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	// the source IP overrides the interface of the service.
+	return r.Route.Dial(ctx, network, address, append(opts, chain.InterfaceDialOption(r.ip))...)
+}
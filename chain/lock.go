@@ -0,0 +1,137 @@
+package chain
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/go-gost/core/bypass"
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	xerrors "github.com/go-gost/x/errors"
+	"github.com/go-gost/x/internal/util/classify"
+	"github.com/go-gost/x/internal/util/forward"
+)
+
+const (
+	// the maximum number of the bytes watched for the host of the locked flow.
+	maxLockWatchSize = 16 * 1024
+)
+
+type lockChain struct {
+	chain.Chainer
+	bypass bypass.Bypass
+	strict bool
+	logger logger.Logger
+}
+
+// LockChain locks the TCP flows dialed by the routes of the chain c to the hosts allowed by the bypass (in whitelist mode),
+// the flow is closed if the SNI of the TLS client hello or the Host header of the HTTP request is not allowed,
+// the flows of the other protocols are closed too in strict mode. c can be nil, then the default route is used.
+func LockChain(c chain.Chainer, bp bypass.Bypass, strict bool, log logger.Logger) chain.Chainer {
+	if bp == nil {
+		return c
+	}
+	return &lockChain{
+		Chainer: c,
+		bypass:  bp,
+		strict:  strict,
+		logger:  log,
+	}
+}
+
+func (c *lockChain) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
+	var route chain.Route
+	if c.Chainer != nil {
+		route = c.Chainer.Route(ctx, network, address, opts...)
+	}
+	if route == nil {
+		route = chain.DefaultRoute
+	}
+	return &lockRoute{
+		Route: route,
+		chain: c,
+	}
+}
+
+type lockRoute struct {
+	chain.Route
+	chain *lockChain
+}
+
+func (r *lockRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	conn, err := r.Route.Dial(ctx, network, address, opts...)
+	if err != nil || !strings.HasPrefix(network, "tcp") {
+		return conn, err
+	}
+	return &lockConn{
+		Conn:    conn,
+		ctx:     ctx,
+		address: address,
+		chain:   r.chain,
+	}, nil
+}
+
+// lockConn checks the host of the flow carried by the data written to the target,
+// the data is written through while the host is not received yet.
+type lockConn struct {
+	net.Conn
+	ctx     context.Context
+	address string
+	chain   *lockChain
+	buf     []byte
+	done    bool
+}
+
+func (c *lockConn) Write(b []byte) (int, error) {
+	if !c.done {
+		if err := c.check(b); err != nil {
+			c.Conn.Close()
+			return 0, err
+		}
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *lockConn) check(b []byte) error {
+	if c.buf == nil {
+		switch classify.Classify("tcp", b) {
+		case classify.ProtoTLS, classify.ProtoHTTP:
+		default:
+			c.done = true
+			return c.refuse("unknown protocol")
+		}
+	}
+
+	c.buf = append(c.buf, b...)
+	host := forward.SniffHost(c.buf)
+	if host == "" {
+		if len(c.buf) > maxLockWatchSize {
+			c.done = true
+			c.buf = nil
+			return c.refuse("no host")
+		}
+		return nil
+	}
+	c.done = true
+	c.buf = nil
+
+	if c.chain.bypass.Contains(c.ctx, "tcp", host) {
+		if c.chain.logger != nil {
+			c.chain.logger.Warnf("lock: host %s of the flow to %s is not allowed", host, c.address)
+		}
+		return xerrors.ErrBypass
+	}
+	return nil
+}
+
+// refuse refuses the flow without the host in strict mode.
+func (c *lockConn) refuse(reason string) error {
+	if !c.chain.strict {
+		return nil
+	}
+	if c.chain.logger != nil {
+		c.chain.logger.Warnf("lock: flow to %s is refused: %s", c.address, reason)
+	}
+	return xerrors.ErrBypass
+}
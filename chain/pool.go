@@ -0,0 +1,187 @@
+package chain
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+)
+
+const (
+	defaultPoolIdleTimeout = 30 * time.Second
+	// the background dial of a pooled connection is abandoned after the timeout.
+	defaultPoolDialTimeout = 30 * time.Second
+)
+
+type pooledConn struct {
+	net.Conn
+	t time.Time
+}
+
+// connPool keeps pre-established connections for the routes of a chain.
+// A pooled connection has completed the dial and handshake with all the nodes of the route,
+// it is handed out only once, and a replacement is dialed in the background.
+type connPool struct {
+	size        int
+	idleTimeout time.Duration
+	conns       map[string][]*pooledConn
+	dialing     map[string]int
+	mu          sync.Mutex
+	closed      chan struct{}
+	logger      logger.Logger
+	// the pending dials are aborted when the pool is closed.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newConnPool(size int, idleTimeout time.Duration, logger logger.Logger) *connPool {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultPoolIdleTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &connPool{
+		size:        size,
+		idleTimeout: idleTimeout,
+		conns:       make(map[string][]*pooledConn),
+		dialing:     make(map[string]int),
+		closed:      make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		logger:      logger,
+	}
+	go p.reap()
+	return p
+}
+
+// Get returns an idle connection for the route or nil if none is available.
+func (p *connPool) Get(nodes []*chain.Node) net.Conn {
+	key := poolKey(nodes)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.conns[key]
+	for len(conns) > 0 {
+		pc := conns[0]
+		conns = conns[1:]
+		if time.Since(pc.t) < p.idleTimeout {
+			p.conns[key] = conns
+			return pc.Conn
+		}
+		pc.Close()
+	}
+	delete(p.conns, key)
+	return nil
+}
+
+// Fill dials connections in the background until the pool of the route is full.
+func (p *connPool) Fill(nodes []*chain.Node, dial func(ctx context.Context) (net.Conn, error)) {
+	key := poolKey(nodes)
+
+	p.mu.Lock()
+	n := p.size - len(p.conns[key]) - p.dialing[key]
+	if n <= 0 {
+		p.mu.Unlock()
+		return
+	}
+	p.dialing[key] += n
+	p.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(p.ctx, defaultPoolDialTimeout)
+			conn, err := dial(ctx)
+			cancel()
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			select {
+			case <-p.closed:
+				if conn != nil {
+					conn.Close()
+				}
+				return
+			default:
+			}
+
+			if p.dialing[key]--; p.dialing[key] <= 0 {
+				delete(p.dialing, key)
+			}
+			if err != nil {
+				if p.logger != nil {
+					p.logger.Debugf("pool: %v", err)
+				}
+				return
+			}
+			p.conns[key] = append(p.conns[key], &pooledConn{Conn: conn, t: time.Now()})
+		}()
+	}
+}
+
+func (p *connPool) reap() {
+	ticker := time.NewTicker(p.idleTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.mu.Lock()
+			for key, conns := range p.conns {
+				var l []*pooledConn
+				for _, pc := range conns {
+					if time.Since(pc.t) < p.idleTimeout {
+						l = append(l, pc)
+						continue
+					}
+					pc.Close()
+				}
+				if len(l) == 0 {
+					delete(p.conns, key)
+					continue
+				}
+				p.conns[key] = l
+			}
+			p.mu.Unlock()
+		case <-p.closed:
+			return
+		}
+	}
+}
+
+// Close closes all the idle connections and stops the pool.
+func (p *connPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.closed:
+		return nil
+	default:
+		close(p.closed)
+		p.cancel()
+	}
+
+	for _, conns := range p.conns {
+		for _, pc := range conns {
+			pc.Close()
+		}
+	}
+	p.conns = make(map[string][]*pooledConn)
+	return nil
+}
+
+func poolKey(nodes []*chain.Node) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		sb.WriteString(node.Name)
+		sb.WriteByte('@')
+		sb.WriteString(node.Addr)
+		sb.WriteByte('>')
+	}
+	return sb.String()
+}
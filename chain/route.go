@@ -10,11 +10,15 @@ import (
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/metrics"
 	"github.com/go-gost/core/selector"
+	xerrors "github.com/go-gost/x/errors"
+	xnet "github.com/go-gost/x/internal/net"
 	xmetrics "github.com/go-gost/x/metrics"
+	"github.com/go-gost/x/stats"
 )
 
 type RouteOptions struct {
 	Chain chain.Chainer
+	pool  *connPool
 }
 
 type RouteOption func(*RouteOptions)
@@ -26,7 +30,9 @@ func ChainRouteOption(c chain.Chainer) RouteOption {
 }
 
 type route struct {
-	nodes   []*chain.Node
+	nodes []*chain.Node
+	// the nodes of the route selected from the hops tracking the connections.
+	tracked []trackedNode
 	options RouteOptions
 }
 
@@ -43,13 +49,20 @@ func NewRoute(opts ...RouteOption) *route {
 	}
 }
 
+func poolRouteOption(pool *connPool) RouteOption {
+	return func(o *RouteOptions) {
+		o.pool = pool
+	}
+}
+
 func (r *route) addNode(nodes ...*chain.Node) {
 	r.nodes = append(r.nodes, nodes...)
 }
 
 func (r *route) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
 	if len(r.Nodes()) == 0 {
-		return chain.DefaultRoute.Dial(ctx, network, address, opts...)
+		conn, err := chain.DefaultRoute.Dial(ctx, network, address, opts...)
+		return conn, xerrors.Classify(err)
 	}
 
 	var options chain.DialOptions
@@ -58,17 +71,52 @@ func (r *route) Dial(ctx context.Context, network, address string, opts ...chain
 			opt(&options)
 		}
 	}
-	conn, err := r.connect(ctx, options.Logger)
-	if err != nil {
-		return nil, err
+	sts := nodeStats(r.nodes)
+	for _, st := range sts {
+		st.Add(stats.KindTotalConns, 1)
 	}
 
-	cc, err := r.getNode(len(r.Nodes())-1).Options().Transport.Connect(ctx, conn, network, address)
+	// the timeout of the dial attempt covers the connections and the handshakes with the nodes.
+	dialCtx := ctx
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	var conn net.Conn
+	if pool := r.options.pool; pool != nil {
+		conn = pool.Get(r.nodes)
+	}
+	if conn == nil {
+		var err error
+		if conn, err = r.connect(dialCtx, options.Logger); err != nil {
+			for _, st := range sts {
+				st.Add(stats.KindTotalErrs, 1)
+			}
+			return nil, xerrors.Classify(err)
+		}
+	}
+	if pool := r.options.pool; pool != nil {
+		pool.Fill(r.nodes, func(ctx context.Context) (net.Conn, error) {
+			return r.connect(ctx, options.Logger)
+		})
+	}
+
+	for _, st := range sts {
+		st.Add(stats.KindCurrentConns, 1)
+	}
+	conn = wrapStatsConn(wrapTrackedConn(conn, r.tracked), sts)
+
+	cc, err := r.getNode(len(r.Nodes())-1).Options().Transport.Connect(dialCtx, conn, network, address)
 	if err != nil {
+		for _, st := range sts {
+			st.Add(stats.KindTotalErrs, 1)
+		}
 		if conn != nil {
 			conn.Close()
 		}
-		return nil, err
+		return nil, xerrors.Classify(err)
 	}
 	return cc, nil
 }
@@ -147,7 +195,7 @@ func (r *route) connect(ctx context.Context, logger logger.Logger) (conn net.Con
 	}
 
 	start := time.Now()
-	cc, err := node.Options().Transport.Dial(ctx, addr)
+	cc, err := node.Options().Transport.Dial(xnet.ContextWithResolver(ctx, node.Options().Resolver), addr)
 	if err != nil {
 		if marker != nil {
 			marker.Mark()
@@ -181,7 +229,7 @@ func (r *route) connect(ctx context.Context, logger logger.Logger) (conn net.Con
 	preNode := node
 	for _, node := range r.nodes[1:] {
 		marker := node.Marker()
-		addr, err = chain.Resolve(ctx, network, node.Addr, node.Options().Resolver, node.Options().HostMapper, logger)
+		addr, err = chain.Resolve(ctx, network, node.Addr, xnet.UnwrapResolver(node.Options().Resolver), node.Options().HostMapper, logger)
 		if err != nil {
 			cn.Close()
 			if marker != nil {
@@ -0,0 +1,188 @@
+package chain
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-gost/core/bypass"
+	"github.com/go-gost/core/chain"
+	ctxvalue "github.com/go-gost/x/ctx"
+	"github.com/go-gost/x/internal/matcher"
+	xnet "github.com/go-gost/x/internal/net"
+)
+
+type RuleOptions struct {
+	Matchers []string
+	Ports    []string
+	Users    []string
+	Bypass   bypass.Bypass
+	Chain    chain.Chainer
+}
+
+type RuleOption func(*RuleOptions)
+
+// MatchersRuleOption sets the destination patterns of the rule,
+// the pattern can be an IP, CIDR, domain (example.com, .example.com), wildcard (*.example.com) or HOST:PORT.
+func MatchersRuleOption(matchers ...string) RuleOption {
+	return func(o *RuleOptions) {
+		o.Matchers = matchers
+	}
+}
+
+// PortsRuleOption sets the destination ports of the rule, the port can be a single port or a range MIN-MAX.
+func PortsRuleOption(ports ...string) RuleOption {
+	return func(o *RuleOptions) {
+		o.Ports = ports
+	}
+}
+
+// UsersRuleOption sets the authenticated client IDs of the rule.
+func UsersRuleOption(users ...string) RuleOption {
+	return func(o *RuleOptions) {
+		o.Users = users
+	}
+}
+
+// BypassRuleOption sets an external matcher for the rule (e.g. a bypass plugin doing geo lookups),
+// the destination is matched if the bypass contains it.
+func BypassRuleOption(bp bypass.Bypass) RuleOption {
+	return func(o *RuleOptions) {
+		o.Bypass = bp
+	}
+}
+
+// ChainRuleOption sets the target chain of the rule, nil means direct egress.
+func ChainRuleOption(c chain.Chainer) RuleOption {
+	return func(o *RuleOptions) {
+		o.Chain = c
+	}
+}
+
+// Rule is a policy routing rule of the chain.
+// All the conditions set in the rule must be satisfied for a connection to match the rule.
+type Rule struct {
+	addrMatcher     matcher.Matcher
+	cidrMatcher     matcher.Matcher
+	wildcardMatcher matcher.Matcher
+	ports           []*xnet.PortRange
+	users           map[string]struct{}
+	options         RuleOptions
+}
+
+func NewRule(opts ...RuleOption) *Rule {
+	var options RuleOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	r := &Rule{
+		options: options,
+	}
+
+	var addrs []string
+	var inets []*net.IPNet
+	var wildcards []string
+	for _, pattern := range options.Matchers {
+		if _, inet, err := net.ParseCIDR(pattern); err == nil {
+			inets = append(inets, inet)
+			continue
+		}
+		if strings.ContainsAny(pattern, "*?") {
+			wildcards = append(wildcards, pattern)
+			continue
+		}
+		addrs = append(addrs, pattern)
+	}
+	if len(options.Matchers) > 0 {
+		r.addrMatcher = matcher.AddrMatcher(addrs)
+		r.cidrMatcher = matcher.CIDRMatcher(inets)
+		r.wildcardMatcher = matcher.WildcardMatcher(wildcards)
+	}
+
+	for _, s := range options.Ports {
+		pr := &xnet.PortRange{}
+		if err := pr.Parse(strings.TrimSpace(s)); err == nil {
+			r.ports = append(r.ports, pr)
+		}
+	}
+
+	if len(options.Users) > 0 {
+		r.users = make(map[string]struct{})
+		for _, user := range options.Users {
+			r.users[user] = struct{}{}
+		}
+	}
+
+	return r
+}
+
+// Chain returns the target chain of the rule, nil means direct egress.
+func (r *Rule) Chain() chain.Chainer {
+	return r.options.Chain
+}
+
+// Match checks whether the connection to address matches the rule.
+// The host is the original requested address before resolving, and may be empty.
+func (r *Rule) Match(ctx context.Context, network, address, host string) bool {
+	if r == nil {
+		return false
+	}
+	if host == "" {
+		host = address
+	}
+
+	if r.users != nil {
+		if _, ok := r.users[string(ctxvalue.ClientIDFromContext(ctx))]; !ok {
+			return false
+		}
+	}
+
+	if len(r.ports) > 0 {
+		_, sp, _ := net.SplitHostPort(host)
+		port, _ := strconv.Atoi(sp)
+		found := false
+		for _, pr := range r.ports {
+			if pr.Contains(port) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.addrMatcher != nil && !r.matchAddr(address, host) {
+		return false
+	}
+
+	if bp := r.options.Bypass; bp != nil &&
+		!bp.Contains(ctx, network, address, bypass.WithHostOpton(host)) {
+		return false
+	}
+
+	return true
+}
+
+func (r *Rule) matchAddr(address, host string) bool {
+	if r.addrMatcher.Match(host) || r.wildcardMatcher.Match(host) {
+		return true
+	}
+
+	for _, addr := range []string{host, address} {
+		h, _, _ := net.SplitHostPort(addr)
+		if h == "" {
+			h = addr
+		}
+		if ip := net.ParseIP(h); ip != nil {
+			if r.addrMatcher.Match(addr) || r.cidrMatcher.Match(h) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package chain
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metrics"
+	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
+	xnet "github.com/go-gost/x/internal/net"
+	xmetrics "github.com/go-gost/x/metrics"
+)
+
+const (
+	// the connections to the SMTP ports are refused.
+	SMTPActionBlock = "block"
+	// the connections to the SMTP ports are limited by the rate of each user.
+	SMTPActionLimit = "limit"
+
+	defaultSMTPPort   = "25"
+	defaultSMTPPeriod = time.Hour
+)
+
+type SMTPOptions struct {
+	Action  string
+	Ports   []string
+	Allow   []*Rule
+	Rate    int
+	Period  time.Duration
+	Service string
+	Logger  logger.Logger
+}
+
+type SMTPOption func(*SMTPOptions)
+
+// ActionSMTPOption sets the action of the connections to the SMTP ports, one of block (default) and limit.
+func ActionSMTPOption(action string) SMTPOption {
+	return func(o *SMTPOptions) {
+		o.Action = action
+	}
+}
+
+// PortsSMTPOption sets the SMTP ports, the port can be a single port or a range MIN-MAX, default is 25.
+func PortsSMTPOption(ports ...string) SMTPOption {
+	return func(o *SMTPOptions) {
+		o.Ports = ports
+	}
+}
+
+// AllowSMTPOption sets the allowlist, the connections matching any of the rules are neither blocked nor limited.
+func AllowSMTPOption(rules ...*Rule) SMTPOption {
+	return func(o *SMTPOptions) {
+		o.Allow = rules
+	}
+}
+
+// RateSMTPOption sets the maximum connections to the SMTP ports of each user in the period of the limit action,
+// the period defaults to 1h.
+func RateSMTPOption(rate int, period time.Duration) SMTPOption {
+	return func(o *SMTPOptions) {
+		o.Rate = rate
+		o.Period = period
+	}
+}
+
+func ServiceSMTPOption(service string) SMTPOption {
+	return func(o *SMTPOptions) {
+		o.Service = service
+	}
+}
+
+func LoggerSMTPOption(logger logger.Logger) SMTPOption {
+	return func(o *SMTPOptions) {
+		o.Logger = logger
+	}
+}
+
+type smtpWindow struct {
+	start time.Time
+	n     int
+}
+
+// SMTPPolicy controls the connections of the service to the SMTP ports, so that the service is not abused as a spam relay.
+// A user is the authenticated client ID, or the client IP if the client is not authenticated.
+type SMTPPolicy struct {
+	ports   []*xnet.PortRange
+	windows map[string]*smtpWindow
+	sweep   time.Time
+	mu      sync.Mutex
+	options SMTPOptions
+}
+
+func NewSMTPPolicy(opts ...SMTPOption) *SMTPPolicy {
+	var options SMTPOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.Action == "" {
+		options.Action = SMTPActionBlock
+	}
+	if len(options.Ports) == 0 {
+		options.Ports = []string{defaultSMTPPort}
+	}
+	if options.Period <= 0 {
+		options.Period = defaultSMTPPeriod
+	}
+
+	p := &SMTPPolicy{
+		windows: make(map[string]*smtpWindow),
+		sweep:   time.Now(),
+		options: options,
+	}
+	for _, s := range options.Ports {
+		pr := &xnet.PortRange{}
+		if err := pr.Parse(strings.TrimSpace(s)); err == nil {
+			p.ports = append(p.ports, pr)
+		}
+	}
+	return p
+}
+
+// Allow checks whether the connection to address is allowed by the policy.
+// The host is the original requested address before resolving, and may be empty.
+func (p *SMTPPolicy) Allow(ctx context.Context, network, address, host string) error {
+	if p == nil || !strings.HasPrefix(network, "tcp") {
+		return nil
+	}
+	if host == "" {
+		host = address
+	}
+
+	_, sp, _ := net.SplitHostPort(host)
+	port, _ := strconv.Atoi(sp)
+	found := false
+	for _, pr := range p.ports {
+		if pr.Contains(port) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	for _, rule := range p.options.Allow {
+		if rule.Match(ctx, network, address, host) {
+			return nil
+		}
+	}
+
+	user := string(ctxvalue.ClientIDFromContext(ctx))
+	if user == "" {
+		user, _, _ = net.SplitHostPort(string(ctxvalue.ClientAddrFromContext(ctx)))
+	}
+
+	if p.options.Action == SMTPActionLimit && p.take(user) {
+		return nil
+	}
+
+	if v := xmetrics.GetCounter(xmetrics.MetricServiceSMTPBlockedCounter,
+		metrics.Labels{"service": p.options.Service, "action": p.options.Action}); v != nil {
+		v.Inc()
+	}
+	if p.options.Logger != nil {
+		p.options.Logger.Warnf("smtp: connection of %s to %s is refused by %s", user, host, p.options.Action)
+	}
+
+	if p.options.Action == SMTPActionLimit {
+		return xerrors.ErrRateLimit
+	}
+	return xerrors.ErrSMTP
+}
+
+// take takes a connection from the quota of the user in the current period.
+func (p *SMTPPolicy) take(user string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.sweep) > p.options.Period {
+		for k, w := range p.windows {
+			if now.Sub(w.start) > p.options.Period {
+				delete(p.windows, k)
+			}
+		}
+		p.sweep = now
+	}
+
+	w := p.windows[user]
+	if w == nil || now.Sub(w.start) > p.options.Period {
+		w = &smtpWindow{start: now}
+		p.windows[user] = w
+	}
+	if w.n >= p.options.Rate {
+		return false
+	}
+	w.n++
+	return true
+}
+
+type smtpChain struct {
+	chain.Chainer
+	policy *SMTPPolicy
+}
+
+// SMTPChain refuses the connections to the SMTP ports dialed by the routes of the chain c which are not allowed by the policy,
+// c can be nil, then the default route is used.
+func SMTPChain(c chain.Chainer, policy *SMTPPolicy) chain.Chainer {
+	if policy == nil {
+		return c
+	}
+	return &smtpChain{
+		Chainer: c,
+		policy:  policy,
+	}
+}
+
+func (c *smtpChain) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
+	var route chain.Route
+	if c.Chainer != nil {
+		route = c.Chainer.Route(ctx, network, address, opts...)
+	}
+	if route == nil {
+		route = chain.DefaultRoute
+	}
+
+	var options chain.RouteOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &smtpRoute{
+		Route:  route,
+		host:   options.Host,
+		policy: c.policy,
+	}
+}
+
+type smtpRoute struct {
+	chain.Route
+	host   string
+	policy *SMTPPolicy
+}
+
+func (r *smtpRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	if err := r.policy.Allow(ctx, network, address, r.host); err != nil {
+		return nil, err
+	}
+	return r.Route.Dial(ctx, network, address, opts...)
+}
@@ -0,0 +1,62 @@
+package chain
+
+import (
+	"context"
+	"net"
+
+	"github.com/go-gost/core/chain"
+	xnet "github.com/go-gost/x/internal/net"
+)
+
+type sockOptsChain struct {
+	chain.Chainer
+	sockOpts *xnet.SockOpts
+}
+
+// SockOptsChain applies the socket options to the connections dialed by the routes of the chain c,
+// c can be nil, then the default route is used.
+func SockOptsChain(c chain.Chainer, sockOpts *xnet.SockOpts) chain.Chainer {
+	if sockOpts == nil {
+		return c
+	}
+	return &sockOptsChain{
+		Chainer:  c,
+		sockOpts: sockOpts,
+	}
+}
+
+func (c *sockOptsChain) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
+	var route chain.Route
+	if c.Chainer != nil {
+		route = c.Chainer.Route(ctx, network, address, opts...)
+	}
+	if route == nil {
+		route = chain.DefaultRoute
+	}
+	return &sockOptsRoute{
+		Route:    route,
+		sockOpts: c.sockOpts,
+	}
+}
+
+type sockOptsRoute struct {
+	chain.Route
+	sockOpts *xnet.SockOpts
+}
+
+func (r *sockOptsRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	conn, err := r.Route.Dial(ctx, network, address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.sockOpts.Apply(conn); err != nil {
+		var options chain.DialOptions
+		for _, opt := range opts {
+			opt(&options)
+		}
+		if options.Logger != nil {
+			options.Logger.Warnf("sockopts: %v", err)
+		}
+	}
+	return conn, nil
+}
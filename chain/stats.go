@@ -0,0 +1,85 @@
+package chain
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/metadata"
+	xs "github.com/go-gost/x/selector"
+	"github.com/go-gost/x/stats"
+)
+
+var (
+	errUnsupport = errors.New("unsupported operation")
+)
+
+// statsConn feeds the traffic of the route to the live stats of its nodes.
+type statsConn struct {
+	net.Conn
+	stats []*stats.Stats
+	once  sync.Once
+}
+
+func nodeStats(nodes []*chain.Node) []*stats.Stats {
+	var l []*stats.Stats
+	for _, node := range nodes {
+		if st := xs.LiveStats(node); st != nil {
+			l = append(l, st)
+		}
+	}
+	return l
+}
+
+func wrapStatsConn(c net.Conn, stats []*stats.Stats) net.Conn {
+	if len(stats) == 0 {
+		return c
+	}
+	return &statsConn{
+		Conn:  c,
+		stats: stats,
+	}
+}
+
+func (c *statsConn) Read(b []byte) (n int, err error) {
+	n, err = c.Conn.Read(b)
+	for _, st := range c.stats {
+		st.Add(stats.KindInputBytes, int64(n))
+	}
+	return
+}
+
+func (c *statsConn) Write(b []byte) (n int, err error) {
+	n, err = c.Conn.Write(b)
+	for _, st := range c.stats {
+		st.Add(stats.KindOutputBytes, int64(n))
+	}
+	return
+}
+
+func (c *statsConn) Close() error {
+	c.once.Do(func() {
+		for _, st := range c.stats {
+			st.Add(stats.KindCurrentConns, -1)
+		}
+	})
+	return c.Conn.Close()
+}
+
+func (c *statsConn) SyscallConn() (rc syscall.RawConn, err error) {
+	if sc, ok := c.Conn.(syscall.Conn); ok {
+		rc, err = sc.SyscallConn()
+		return
+	}
+	err = errUnsupport
+	return
+}
+
+func (c *statsConn) Metadata() metadata.Metadata {
+	if md, ok := c.Conn.(metadata.Metadatable); ok {
+		return md.Metadata()
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package chain
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metadata"
+	"github.com/go-gost/core/selector"
+)
+
+// SwapChainer routes by the chain which can be swapped at runtime without restarting the service,
+// the connections dialed by the old chain keep running until the drain grace period expires.
+type SwapChainer struct {
+	current atomic.Pointer[swapGeneration]
+	logger  logger.Logger
+}
+
+// swapGeneration is the chain in use between two swaps and the connections dialed by it.
+type swapGeneration struct {
+	chainer chain.Chainer
+	conns   map[io.Closer]struct{}
+	mu      sync.Mutex
+}
+
+func SwapChain(c chain.Chainer, log logger.Logger) *SwapChainer {
+	if log == nil {
+		log = logger.Default()
+	}
+	s := &SwapChainer{
+		logger: log,
+	}
+	s.current.Store(&swapGeneration{chainer: c})
+	return s
+}
+
+// Swap replaces the chain by c, the connections dialed by the old chain are closed after grace,
+// they are kept until they finish if grace is not positive. It returns the number of the connections of the old chain.
+func (s *SwapChainer) Swap(c chain.Chainer, grace time.Duration) int {
+	old := s.current.Swap(&swapGeneration{chainer: c})
+
+	old.mu.Lock()
+	n := len(old.conns)
+	old.mu.Unlock()
+
+	if grace > 0 && n > 0 {
+		s.logger.Infof("swap: drain %d connections of the old chain in %v", n, grace)
+		time.AfterFunc(grace, func() {
+			old.mu.Lock()
+			conns := old.conns
+			old.conns = nil
+			old.mu.Unlock()
+
+			if len(conns) > 0 {
+				s.logger.Infof("swap: close %d connections of the old chain", len(conns))
+			}
+			for c := range conns {
+				c.Close()
+			}
+		})
+	}
+
+	return n
+}
+
+func (s *SwapChainer) Marker() selector.Marker {
+	if mi, ok := s.current.Load().chainer.(selector.Markable); ok {
+		return mi.Marker()
+	}
+	return nil
+}
+
+func (s *SwapChainer) Metadata() metadata.Metadata {
+	if mi, ok := s.current.Load().chainer.(metadata.Metadatable); ok {
+		return mi.Metadata()
+	}
+	return nil
+}
+
+func (s *SwapChainer) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
+	gen := s.current.Load()
+	if gen.chainer == nil {
+		return nil
+	}
+	route := gen.chainer.Route(ctx, network, address, opts...)
+	if route == nil {
+		return nil
+	}
+	return &swapRoute{Route: route, gen: gen}
+}
+
+func (g *swapGeneration) track(c io.Closer) func() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.conns == nil {
+		g.conns = make(map[io.Closer]struct{})
+	}
+	g.conns[c] = struct{}{}
+
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		delete(g.conns, c)
+	}
+}
+
+type swapRoute struct {
+	chain.Route
+	gen *swapGeneration
+}
+
+func (r *swapRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	conn, err := r.Route.Dial(ctx, network, address, opts...)
+	if err != nil {
+		return conn, err
+	}
+
+	sc := &swapConn{Conn: conn}
+	sc.untrack = r.gen.track(sc)
+	if pc, ok := conn.(net.PacketConn); ok {
+		return &swapPacketConn{swapConn: sc, pc: pc}, nil
+	}
+	return sc, nil
+}
+
+// swapConn is tracked by the generation of the chain dialing it until it is closed.
+type swapConn struct {
+	net.Conn
+	untrack func()
+	once    sync.Once
+}
+
+func (c *swapConn) Close() error {
+	c.once.Do(c.untrack)
+	return c.Conn.Close()
+}
+
+func (c *swapConn) Metadata() metadata.Metadata {
+	if md, ok := c.Conn.(metadata.Metadatable); ok {
+		return md.Metadata()
+	}
+	return nil
+}
+
+type swapPacketConn struct {
+	*swapConn
+	pc net.PacketConn
+}
+
+func (c *swapPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	return c.pc.ReadFrom(p)
+}
+
+func (c *swapPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return c.pc.WriteTo(p, addr)
+}
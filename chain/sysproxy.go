@@ -0,0 +1,174 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/internal/util/sysproxy"
+)
+
+const (
+	// the cached nodes of the proxies are dropped when the limit is reached.
+	maxSysProxyNodes = 64
+)
+
+// SystemProxyChainOption makes the chain decide the upstream proxy of each connection by the system proxy settings instead of the hops,
+// the nodes of the proxies are created by newNode on the first use.
+// The connections fail if the proxies cannot be found, unless failOpen is set to connect directly.
+func SystemProxyChainOption(resolver *sysproxy.Resolver, failOpen bool, newNode func(proxy sysproxy.Proxy) (*chain.Node, error)) ChainOption {
+	return func(opts *ChainOptions) {
+		if resolver == nil || newNode == nil {
+			return
+		}
+		opts.sysProxy = &sysProxyRouter{
+			resolver: resolver,
+			failOpen: failOpen,
+			newNode:  newNode,
+			nodes:    make(map[string]*chain.Node),
+		}
+	}
+}
+
+type sysProxyRouter struct {
+	resolver *sysproxy.Resolver
+	failOpen bool
+	newNode  func(proxy sysproxy.Proxy) (*chain.Node, error)
+	nodes    map[string]*chain.Node
+	mu       sync.Mutex
+}
+
+func (p *sysProxyRouter) node(proxy sysproxy.Proxy) (*chain.Node, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if node := p.nodes[proxy.String()]; node != nil {
+		return node, nil
+	}
+	node, err := p.newNode(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.nodes) >= maxSysProxyNodes {
+		clear(p.nodes)
+	}
+	p.nodes[proxy.String()] = node
+	return node, nil
+}
+
+// route returns the route of the proxies of the system proxy settings, nil means direct egress.
+// If no proxy can be used, the returned route fails the connections unless failOpen is set.
+func (p *sysProxyRouter) route(ctx context.Context, c *Chain, address, host string, log logger.Logger) chain.Route {
+	if host == "" {
+		host = address
+	}
+	h, port, _ := net.SplitHostPort(host)
+	if h == "" {
+		h = host
+	}
+
+	proxies, err := p.resolver.FindProxy(ctx, h, port)
+	if err != nil {
+		return p.fail(host, err, log)
+	}
+	if log != nil {
+		log.Debugf("sysproxy: %s > %v", host, proxies)
+	}
+
+	var routes []chain.Route
+	for _, proxy := range proxies {
+		if proxy.Type == sysproxy.ProxyDirect {
+			routes = append(routes, nil)
+			continue
+		}
+		node, err := p.node(proxy)
+		if err != nil {
+			if log != nil {
+				log.Warnf("sysproxy: %s: %v", proxy, err)
+			}
+			continue
+		}
+		rt := NewRoute(ChainRouteOption(c), poolRouteOption(c.pool))
+		rt.addNode(node)
+		routes = append(routes, rt)
+	}
+
+	switch len(routes) {
+	case 0:
+		return p.fail(host, errors.New("sysproxy: no available proxy"), log)
+	case 1:
+		if routes[0] == nil {
+			return nil
+		}
+		return routes[0]
+	}
+	return &sysProxyRoute{routes: routes}
+}
+
+func (p *sysProxyRouter) fail(host string, err error, log logger.Logger) chain.Route {
+	if p.failOpen {
+		if log != nil {
+			log.Warnf("sysproxy: %s: %v, connect directly", host, err)
+		}
+		return nil
+	}
+	if log != nil {
+		log.Errorf("sysproxy: %s: %v", host, err)
+	}
+	return &errRoute{err: err}
+}
+
+// errRoute fails all the connections with the error.
+type errRoute struct {
+	err error
+}
+
+func (r *errRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	return nil, r.err
+}
+
+func (r *errRoute) Bind(ctx context.Context, network, address string, opts ...chain.BindOption) (net.Listener, error) {
+	return nil, r.err
+}
+
+func (r *errRoute) Nodes() []*chain.Node {
+	return nil
+}
+
+// sysProxyRoute tries the routes of the proxies in order, the nil route is the direct egress.
+type sysProxyRoute struct {
+	routes []chain.Route
+}
+
+func (r *sysProxyRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (conn net.Conn, err error) {
+	for _, rt := range r.routes {
+		if rt == nil {
+			rt = chain.DefaultRoute
+		}
+		if conn, err = rt.Dial(ctx, network, address, opts...); err == nil {
+			return
+		}
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+	}
+	return
+}
+
+func (r *sysProxyRoute) Bind(ctx context.Context, network, address string, opts ...chain.BindOption) (net.Listener, error) {
+	rt := r.routes[0]
+	if rt == nil {
+		rt = chain.DefaultRoute
+	}
+	return rt.Bind(ctx, network, address, opts...)
+}
+
+func (r *sysProxyRoute) Nodes() []*chain.Node {
+	if r.routes[0] == nil {
+		return nil
+	}
+	return r.routes[0].Nodes()
+}
@@ -0,0 +1,169 @@
+package chain
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
+)
+
+// Trace is the result of tracing the connection to the target through the nodes of a route.
+type Trace struct {
+	Network string
+	Address string
+	Hops    []*TraceHop
+	// the time to connect to the target through the last node.
+	Connect time.Duration
+	// the total time of the trace.
+	Duration time.Duration
+	// the error of the trace, it is the error of the failed hop or the connection to the target.
+	Error string
+}
+
+// TraceHop is the trace of a node of the route.
+type TraceHop struct {
+	Node     string
+	Addr     string
+	Resolved string
+	// the time to reach the node, by dialing the first node or connecting through the previous node.
+	Connect time.Duration
+	// the time of the handshake with the node.
+	Handshake time.Duration
+	// the state of the TLS connection to the node, if the node is reached by TLS.
+	TLS   *TraceTLS
+	Error string
+}
+
+type TraceTLS struct {
+	Version     string
+	CipherSuite string
+	ALPN        string
+	ServerName  string
+}
+
+// TraceRoute connects to the address through the nodes of the route step by step, and reports the timings of each node.
+// Unlike the Dial of the route, the nodes are not marked as failed and the connection pool is not used.
+func TraceRoute(ctx context.Context, rt chain.Route, network, address string, log logger.Logger) *Trace {
+	start := time.Now()
+	t := &Trace{
+		Network: network,
+		Address: address,
+	}
+	defer func() {
+		t.Duration = time.Since(start)
+	}()
+
+	var nodes []*chain.Node
+	if rt != nil {
+		nodes = rt.Nodes()
+	}
+	if len(nodes) == 0 {
+		began := time.Now()
+		conn, err := chain.DefaultRoute.Dial(ctx, network, address, chain.LoggerDialOption(log))
+		t.Connect = time.Since(began)
+		if err != nil {
+			t.Error = err.Error()
+			return t
+		}
+		conn.Close()
+		return t
+	}
+
+	var cn net.Conn
+	defer func() {
+		if cn != nil {
+			cn.Close()
+		}
+	}()
+
+	var preNode *chain.Node
+	for _, node := range nodes {
+		hop := &TraceHop{
+			Node: node.Name,
+			Addr: node.Addr,
+		}
+		t.Hops = append(t.Hops, hop)
+
+		fail := func(err error) *Trace {
+			hop.Error = err.Error()
+			t.Error = err.Error()
+			return t
+		}
+
+		// the first node is resolved by the dialer with the address family preference.
+		r := node.Options().Resolver
+		if preNode != nil {
+			r = xnet.UnwrapResolver(r)
+		}
+		addr, err := chain.Resolve(ctx, "ip", node.Addr, r, node.Options().HostMapper, log)
+		if err != nil {
+			return fail(err)
+		}
+		hop.Resolved = addr
+
+		began := time.Now()
+		var cc net.Conn
+		if preNode == nil {
+			cc, err = node.Options().Transport.Dial(xnet.ContextWithResolver(ctx, r), addr)
+		} else {
+			cc, err = preNode.Options().Transport.Connect(ctx, cn, "tcp", addr)
+		}
+		hop.Connect = time.Since(began)
+		if err != nil {
+			return fail(err)
+		}
+		cn = cc
+
+		began = time.Now()
+		cc, err = node.Options().Transport.Handshake(ctx, cn)
+		hop.Handshake = time.Since(began)
+		if err != nil {
+			return fail(err)
+		}
+		hop.TLS = traceTLS(cn, cc)
+		cn = cc
+		preNode = node
+	}
+
+	began := time.Now()
+	cc, err := preNode.Options().Transport.Connect(ctx, cn, network, address)
+	t.Connect = time.Since(began)
+	if err != nil {
+		t.Error = err.Error()
+		return t
+	}
+	cn = cc
+
+	return t
+}
+
+// traceTLS returns the TLS state of the first of the conns established by TLS.
+func traceTLS(conns ...net.Conn) *TraceTLS {
+	for _, c := range conns {
+		var state *tls.ConnectionState
+		switch v := c.(type) {
+		case interface{ ConnectionState() tls.ConnectionState }:
+			st := v.ConnectionState()
+			state = &st
+		case metadata.Metadatable:
+			if md := v.Metadata(); md != nil {
+				state, _ = md.Get("tls").(*tls.ConnectionState)
+			}
+		}
+		if state == nil || !state.HandshakeComplete {
+			continue
+		}
+		return &TraceTLS{
+			Version:     tls.VersionName(state.Version),
+			CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+			ALPN:        state.NegotiatedProtocol,
+			ServerName:  state.ServerName,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"io"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/metadata"
+)
+
+// connTracker is implemented by the hop draining the connections to its removed nodes.
+type connTracker interface {
+	TrackConn(node *chain.Node, c io.Closer) func()
+}
+
+type trackedNode struct {
+	tracker connTracker
+	node    *chain.Node
+}
+
+// trackedConn is tracked by the hops of the route until it is closed.
+type trackedConn struct {
+	net.Conn
+	untracks []func()
+	once     sync.Once
+}
+
+func wrapTrackedConn(c net.Conn, nodes []trackedNode) net.Conn {
+	if len(nodes) == 0 {
+		return c
+	}
+
+	tc := &trackedConn{
+		Conn: c,
+	}
+	for _, tn := range nodes {
+		tc.untracks = append(tc.untracks, tn.tracker.TrackConn(tn.node, tc))
+	}
+	return tc
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		for _, untrack := range c.untracks {
+			untrack()
+		}
+	})
+	return c.Conn.Close()
+}
+
+func (c *trackedConn) SyscallConn() (rc syscall.RawConn, err error) {
+	if sc, ok := c.Conn.(syscall.Conn); ok {
+		rc, err = sc.SyscallConn()
+		return
+	}
+	err = errUnsupport
+	return
+}
+
+func (c *trackedConn) Metadata() metadata.Metadata {
+	if md, ok := c.Conn.(metadata.Metadatable); ok {
+		return md.Metadata()
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package chain
+
+import (
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/metadata"
+	"github.com/go-gost/core/selector"
+)
+
+const (
+	labelWeight = "weight"
+)
+
+type weightedChain struct {
+	chain.Chainer
+	weight int
+}
+
+// WeightedChain overrides the weight of the chain for the selector,
+// it is used to split the traffic of a chain group by percentage.
+func WeightedChain(c chain.Chainer, weight int) chain.Chainer {
+	if c == nil || weight <= 0 {
+		return c
+	}
+	return &weightedChain{
+		Chainer: c,
+		weight:  weight,
+	}
+}
+
+// Marker implements selector.Markable interface.
+func (c *weightedChain) Marker() selector.Marker {
+	if m, ok := c.Chainer.(selector.Markable); ok {
+		return m.Marker()
+	}
+	return nil
+}
+
+// Metadata implements metadata.Metadatable interface.
+func (c *weightedChain) Metadata() metadata.Metadata {
+	var md metadata.Metadata
+	if mi, ok := c.Chainer.(metadata.Metadatable); ok {
+		md = mi.Metadata()
+	}
+	return &weightMetadata{
+		Metadata: md,
+		weight:   c.weight,
+	}
+}
+
+type weightMetadata struct {
+	metadata.Metadata
+	weight int
+}
+
+func (md *weightMetadata) IsExists(key string) bool {
+	if key == labelWeight {
+		return true
+	}
+	return md.Metadata != nil && md.Metadata.IsExists(key)
+}
+
+func (md *weightMetadata) Set(key string, value any) {
+	if md.Metadata != nil {
+		md.Metadata.Set(key, value)
+	}
+}
+
+func (md *weightMetadata) Get(key string) any {
+	if key == labelWeight {
+		return md.weight
+	}
+	if md.Metadata == nil {
+		return nil
+	}
+	return md.Metadata.Get(key)
+}
@@ -94,6 +94,15 @@ type APIConfig struct {
 	AccessLog  bool        `yaml:"accesslog,omitempty" json:"accesslog,omitempty"`
 	Auth       *AuthConfig `yaml:",omitempty" json:"auth,omitempty"`
 	Auther     string      `yaml:",omitempty" json:"auther,omitempty"`
+	// Tenants are the API users of the namespaces, a tenant only sees and manages
+	// the services, chains, authers and limiters in its namespace.
+	Tenants []*APITenantConfig `yaml:",omitempty" json:"tenants,omitempty"`
+}
+
+type APITenantConfig struct {
+	Namespace string      `json:"namespace"`
+	Auth      *AuthConfig `yaml:",omitempty" json:"auth,omitempty"`
+	Auther    string      `yaml:",omitempty" json:"auther,omitempty"`
 }
 
 type MetricsConfig struct {
@@ -103,6 +112,28 @@ type MetricsConfig struct {
 	Auther string      `yaml:",omitempty" json:"auther,omitempty"`
 }
 
+type BufferPoolConfig struct {
+	// the size of the largest pooled buffer in bytes.
+	MaxSize int `yaml:"maxSize,omitempty" json:"maxSize,omitempty"`
+}
+
+type IdleConfig struct {
+	// the idle timeouts of the relayed TCP, UDP and tunnel connections,
+	// zero value is the default, negative value disables the reaping.
+	TCP    time.Duration `yaml:"tcp,omitempty" json:"tcp,omitempty"`
+	UDP    time.Duration `yaml:"udp,omitempty" json:"udp,omitempty"`
+	Tunnel time.Duration `yaml:",omitempty" json:"tunnel,omitempty"`
+}
+
+type SelfTestConfig struct {
+	// the interval of the scheduled tests, the services are only tested on demand if it is zero.
+	Interval time.Duration `yaml:",omitempty" json:"interval,omitempty"`
+	// the timeout of the test of each service.
+	Timeout time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+	// the target address connected through the services, default is a local target.
+	Target string `yaml:",omitempty" json:"target,omitempty"`
+}
+
 type TLSConfig struct {
 	CertFile   string      `yaml:"certFile,omitempty" json:"certFile,omitempty"`
 	KeyFile    string      `yaml:"keyFile,omitempty" json:"keyFile,omitempty"`
@@ -110,6 +141,7 @@ type TLSConfig struct {
 	Secure     bool        `yaml:",omitempty" json:"secure,omitempty"`
 	ServerName string      `yaml:"serverName,omitempty" json:"serverName,omitempty"`
 	Options    *TLSOptions `yaml:",omitempty" json:"options,omitempty"`
+	ACME       *ACMEConfig `yaml:"acme,omitempty" json:"acme,omitempty"`
 
 	// for auto-generated default certificate.
 	Validity     time.Duration `yaml:",omitempty" json:"validity,omitempty"`
@@ -117,18 +149,30 @@ type TLSConfig struct {
 	Organization string        `yaml:",omitempty" json:"organization,omitempty"`
 }
 
+type ACMEConfig struct {
+	Domains  []string `json:"domains"`
+	Email    string   `yaml:",omitempty" json:"email,omitempty"`
+	CacheDir string   `yaml:"cacheDir,omitempty" json:"cacheDir,omitempty"`
+	CA       string   `yaml:"ca,omitempty" json:"ca,omitempty"`
+}
+
 type TLSOptions struct {
 	MinVersion   string   `yaml:"minVersion,omitempty" json:"minVersion,omitempty"`
 	MaxVersion   string   `yaml:"maxVersion,omitempty" json:"maxVersion,omitempty"`
 	CipherSuites []string `yaml:"cipherSuites,omitempty" json:"cipherSuites,omitempty"`
 }
 
+// PluginConfig is the config of a plugin client.
+// CacheTTL is the default TTL of the cached decisions of the gRPC plugin,
+// the plugin can set the TTL of a decision by the cache-ttl response header.
 type PluginConfig struct {
-	Type    string        `json:"type"`
-	Addr    string        `json:"addr"`
-	TLS     *TLSConfig    `yaml:",omitempty" json:"tls,omitempty"`
-	Timeout time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
-	Token   string        `yaml:",omitempty" json:"token,omitempty"`
+	Type     string        `json:"type"`
+	Addr     string        `json:"addr"`
+	TLS      *TLSConfig    `yaml:",omitempty" json:"tls,omitempty"`
+	Timeout  time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+	Token    string        `yaml:",omitempty" json:"token,omitempty"`
+	Retries  int           `yaml:",omitempty" json:"retries,omitempty"`
+	CacheTTL time.Duration `yaml:"cacheTTL,omitempty" json:"cacheTTL,omitempty"`
 }
 
 type AutherConfig struct {
@@ -147,9 +191,18 @@ type AuthConfig struct {
 }
 
 type SelectorConfig struct {
-	Strategy    string        `json:"strategy"`
-	MaxFails    int           `yaml:"maxFails" json:"maxFails"`
-	FailTimeout time.Duration `yaml:"failTimeout" json:"failTimeout"`
+	Strategy       string                `json:"strategy"`
+	MaxFails       int                   `yaml:"maxFails" json:"maxFails"`
+	FailTimeout    time.Duration         `yaml:"failTimeout" json:"failTimeout"`
+	Affinity       bool                  `yaml:",omitempty" json:"affinity,omitempty"`
+	AffinityTTL    time.Duration         `yaml:"affinityTTL,omitempty" json:"affinityTTL,omitempty"`
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuitBreaker,omitempty" json:"circuitBreaker,omitempty"`
+}
+
+type CircuitBreakerConfig struct {
+	Threshold      int           `yaml:",omitempty" json:"threshold,omitempty"`
+	OpenTimeout    time.Duration `yaml:"openTimeout,omitempty" json:"openTimeout,omitempty"`
+	HalfOpenProbes int           `yaml:"halfOpenProbes,omitempty" json:"halfOpenProbes,omitempty"`
 }
 
 type AdmissionConfig struct {
@@ -195,6 +248,32 @@ type HTTPLoader struct {
 	Timeout time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
 }
 
+type SDLoader struct {
+	SD       string `yaml:"sd" json:"sd"`
+	Service  string `json:"service"`
+	Template string `yaml:",omitempty" json:"template,omitempty"`
+}
+
+// HealthCheckConfig is the active health check of the nodes of a hop.
+type HealthCheckConfig struct {
+	// the probe type: tcp (default), tls or http.
+	Type     string        `yaml:",omitempty" json:"type,omitempty"`
+	Interval time.Duration `yaml:",omitempty" json:"interval,omitempty"`
+	Timeout  time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+	// the request path of the http probe.
+	Path string `yaml:",omitempty" json:"path,omitempty"`
+	// the consecutive failures to mark the node unhealthy and the successes to mark it healthy again.
+	Fails  int `yaml:",omitempty" json:"fails,omitempty"`
+	Passes int `yaml:",omitempty" json:"passes,omitempty"`
+}
+
+// PinConfig is the DNS pinning of the nodes with the domain addresses.
+type PinConfig struct {
+	// the lower and upper bounds of the TTL of the resolved addresses.
+	MinTTL time.Duration `yaml:"minTTL,omitempty" json:"minTTL,omitempty"`
+	MaxTTL time.Duration `yaml:"maxTTL,omitempty" json:"maxTTL,omitempty"`
+}
+
 type NameserverConfig struct {
 	Addr     string        `json:"addr"`
 	Chain    string        `yaml:",omitempty" json:"chain,omitempty"`
@@ -210,7 +289,35 @@ type NameserverConfig struct {
 type ResolverConfig struct {
 	Name        string              `json:"name"`
 	Nameservers []*NameserverConfig `yaml:",omitempty" json:"nameservers,omitempty"`
-	Plugin      *PluginConfig       `yaml:",omitempty" json:"plugin,omitempty"`
+	// Chain is the default chain of the nameservers, the queries are sent through it
+	// so that the DNS and the data traffic share the same egress.
+	Chain string `yaml:",omitempty" json:"chain,omitempty"`
+	// Hosts is looked up before the nameservers,
+	// it also maps the hostnames of the nameservers.
+	Hosts string `yaml:",omitempty" json:"hosts,omitempty"`
+	// Rewrites rewrite the answers before they are returned by the resolver,
+	// the first rule matching the hostname is applied.
+	Rewrites []*ResolverRewriteConfig `yaml:",omitempty" json:"rewrites,omitempty"`
+	Plugin   *PluginConfig            `yaml:",omitempty" json:"plugin,omitempty"`
+}
+
+type ResolverRewriteConfig struct {
+	// Hosts is the patterns of the hostnames, the pattern can be a domain (example.com, .example.com)
+	// or wildcard (*.example.com), all the hostnames are matched if it is empty.
+	Hosts []string `yaml:",omitempty" json:"hosts,omitempty"`
+	// Remap maps the IPs of the A/AAAA records, the first matched remapping is applied.
+	Remap []*IPRemapConfig `yaml:",omitempty" json:"remap,omitempty"`
+	// Block drops the answer records of the types (e.g. AAAA) or the IPs in the networks (IP or CIDR).
+	Block []string `yaml:",omitempty" json:"block,omitempty"`
+	// Flatten resolves the target of the CNAME record if the answer has no address record.
+	Flatten bool `yaml:",omitempty" json:"flatten,omitempty"`
+}
+
+// IPRemapConfig maps the IPs in the IP or CIDR From to the IP or CIDR To, the host part of the IP is kept,
+// e.g. 1.2.3.0/24 to 10.0.0.0/24 maps 1.2.3.4 to 10.0.0.4.
+type IPRemapConfig struct {
+	From string `json:"from"`
+	To   string `json:"to"`
 }
 
 type HostMappingConfig struct {
@@ -230,8 +337,11 @@ type HostsConfig struct {
 }
 
 type IngressRuleConfig struct {
-	Hostname string `json:"hostname"`
-	Endpoint string `json:"endpoint"`
+	Hostname string            `json:"hostname"`
+	Endpoint string            `json:"endpoint"`
+	Path     string            `yaml:",omitempty" json:"path,omitempty"`
+	Headers  map[string]string `yaml:",omitempty" json:"headers,omitempty"`
+	Chain    string            `yaml:",omitempty" json:"chain,omitempty"`
 }
 
 type IngressConfig struct {
@@ -244,9 +354,48 @@ type IngressConfig struct {
 	Plugin *PluginConfig        `yaml:",omitempty" json:"plugin,omitempty"`
 }
 
+type ConsulSDConfig struct {
+	Addr    string        `json:"addr"`
+	Token   string        `yaml:",omitempty" json:"token,omitempty"`
+	TTL     time.Duration `yaml:",omitempty" json:"ttl,omitempty"`
+	Timeout time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+	TLS     *TLSConfig    `yaml:",omitempty" json:"tls,omitempty"`
+}
+
+type EtcdSDConfig struct {
+	Addr     string        `json:"addr"`
+	Username string        `yaml:",omitempty" json:"username,omitempty"`
+	Password string        `yaml:",omitempty" json:"password,omitempty"`
+	Prefix   string        `yaml:",omitempty" json:"prefix,omitempty"`
+	TTL      time.Duration `yaml:",omitempty" json:"ttl,omitempty"`
+	Timeout  time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+	TLS      *TLSConfig    `yaml:",omitempty" json:"tls,omitempty"`
+}
+
+type KubernetesSDConfig struct {
+	Addr      string        `yaml:",omitempty" json:"addr,omitempty"`
+	Namespace string        `yaml:",omitempty" json:"namespace,omitempty"`
+	Port      string        `yaml:",omitempty" json:"port,omitempty"`
+	Token     string        `yaml:",omitempty" json:"token,omitempty"`
+	Timeout   time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+	TLS       *TLSConfig    `yaml:",omitempty" json:"tls,omitempty"`
+}
+
+type RedisSDConfig struct {
+	Addr     string        `json:"addr"`
+	DB       int           `yaml:",omitempty" json:"db,omitempty"`
+	Password string        `yaml:",omitempty" json:"password,omitempty"`
+	Prefix   string        `yaml:",omitempty" json:"prefix,omitempty"`
+	TTL      time.Duration `yaml:",omitempty" json:"ttl,omitempty"`
+}
+
 type SDConfig struct {
-	Name   string        `json:"name"`
-	Plugin *PluginConfig `yaml:",omitempty" json:"plugin,omitempty"`
+	Name       string              `json:"name"`
+	Consul     *ConsulSDConfig     `yaml:",omitempty" json:"consul,omitempty"`
+	Etcd       *EtcdSDConfig       `yaml:",omitempty" json:"etcd,omitempty"`
+	Kubernetes *KubernetesSDConfig `yaml:",omitempty" json:"kubernetes,omitempty"`
+	Redis      *RedisSDConfig      `yaml:",omitempty" json:"redis,omitempty"`
+	Plugin     *PluginConfig       `yaml:",omitempty" json:"plugin,omitempty"`
 }
 
 type RouterRouteConfig struct {
@@ -270,7 +419,23 @@ type RecorderConfig struct {
 	TCP    *TCPRecorder   `yaml:"tcp,omitempty" json:"tcp,omitempty"`
 	HTTP   *HTTPRecorder  `yaml:"http,omitempty" json:"http,omitempty"`
 	Redis  *RedisRecorder `yaml:",omitempty" json:"redis,omitempty"`
+	S3     *S3Recorder    `yaml:"s3,omitempty" json:"s3,omitempty"`
 	Plugin *PluginConfig  `yaml:",omitempty" json:"plugin,omitempty"`
+	Redact *RedactConfig  `yaml:",omitempty" json:"redact,omitempty"`
+}
+
+// RedactConfig is the redaction of the sensitive data applied to the records before they are sent.
+type RedactConfig struct {
+	// the HTTP headers to redact the values of.
+	Headers []string `yaml:",omitempty" json:"headers,omitempty"`
+	// redact the values of the cookies.
+	Cookies bool `yaml:",omitempty" json:"cookies,omitempty"`
+	// the URL query parameters to redact the values of, * for all the parameters.
+	Query []string `yaml:",omitempty" json:"query,omitempty"`
+	// the regular expressions of the data to redact.
+	Patterns []string `yaml:",omitempty" json:"patterns,omitempty"`
+	// the replacement of the redacted data, default is ***.
+	Replacement string `yaml:",omitempty" json:"replacement,omitempty"`
 }
 
 type FileRecorder struct {
@@ -296,6 +461,26 @@ type RedisRecorder struct {
 	Type     string `yaml:",omitempty" json:"type,omitempty"`
 }
 
+// S3Recorder records the data to the S3 compatible object storage in batches.
+type S3Recorder struct {
+	Endpoint  string `json:"endpoint"`
+	Bucket    string `json:"bucket"`
+	Region    string `yaml:",omitempty" json:"region,omitempty"`
+	AccessKey string `yaml:"accessKey,omitempty" json:"accessKey,omitempty"`
+	SecretKey string `yaml:"secretKey,omitempty" json:"secretKey,omitempty"`
+	// the prefix of the object keys.
+	Prefix string `yaml:",omitempty" json:"prefix,omitempty"`
+	// address the bucket by the path instead of the host name, e.g. for MinIO.
+	PathStyle bool `yaml:"pathStyle,omitempty" json:"pathStyle,omitempty"`
+	// the size of the records before compression to upload an object.
+	BatchSize int `yaml:"batchSize,omitempty" json:"batchSize,omitempty"`
+	// the max interval to upload the records.
+	FlushInterval time.Duration `yaml:"flushInterval,omitempty" json:"flushInterval,omitempty"`
+	// the directory to keep the objects failed to upload until the storage is available.
+	SpoolDir string        `yaml:"spoolDir,omitempty" json:"spoolDir,omitempty"`
+	Timeout  time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+}
+
 type RecorderObject struct {
 	Name     string `json:"name"`
 	Record   string `json:"record"`
@@ -315,6 +500,15 @@ type LimiterConfig struct {
 type ObserverConfig struct {
 	Name   string        `json:"name"`
 	Plugin *PluginConfig `yaml:",omitempty" json:"plugin,omitempty"`
+	// History records the stats events in memory for the stats API, the events are also sent to the plugin if any.
+	History *ObserverHistoryConfig `yaml:",omitempty" json:"history,omitempty"`
+}
+
+type ObserverHistoryConfig struct {
+	// the retention of the points at the minute resolution, default is 24h.
+	Retention time.Duration `yaml:",omitempty" json:"retention,omitempty"`
+	// the retention of the points at the hour resolution, default is 720h.
+	HourlyRetention time.Duration `yaml:"hourlyRetention,omitempty" json:"hourlyRetention,omitempty"`
 }
 
 type ListenerConfig struct {
@@ -329,36 +523,69 @@ type ListenerConfig struct {
 }
 
 type HandlerConfig struct {
-	Type       string            `json:"type"`
-	Retries    int               `yaml:",omitempty" json:"retries,omitempty"`
-	Chain      string            `yaml:",omitempty" json:"chain,omitempty"`
-	ChainGroup *ChainGroupConfig `yaml:"chainGroup,omitempty" json:"chainGroup,omitempty"`
-	Auther     string            `yaml:",omitempty" json:"auther,omitempty"`
-	Authers    []string          `yaml:",omitempty" json:"authers,omitempty"`
-	Auth       *AuthConfig       `yaml:",omitempty" json:"auth,omitempty"`
-	TLS        *TLSConfig        `yaml:",omitempty" json:"tls,omitempty"`
-	Limiter    string            `yaml:",omitempty" json:"limiter,omitempty"`
-	Observer   string            `yaml:",omitempty" json:"observer,omitempty"`
-	Metadata   map[string]any    `yaml:",omitempty" json:"metadata,omitempty"`
+	Type        string              `json:"type"`
+	Retries     int                 `yaml:",omitempty" json:"retries,omitempty"`
+	Chain       string              `yaml:",omitempty" json:"chain,omitempty"`
+	ChainGroup  *ChainGroupConfig   `yaml:"chainGroup,omitempty" json:"chainGroup,omitempty"`
+	Auther      string              `yaml:",omitempty" json:"auther,omitempty"`
+	Authers     []string            `yaml:",omitempty" json:"authers,omitempty"`
+	Auth        *AuthConfig         `yaml:",omitempty" json:"auth,omitempty"`
+	TLS         *TLSConfig          `yaml:",omitempty" json:"tls,omitempty"`
+	Limiter     string              `yaml:",omitempty" json:"limiter,omitempty"`
+	Observer    string              `yaml:",omitempty" json:"observer,omitempty"`
+	Middlewares []*MiddlewareConfig `yaml:",omitempty" json:"middlewares,omitempty"`
+	Ports       *PortsConfig        `yaml:",omitempty" json:"ports,omitempty"`
+	Lock        *LockConfig         `yaml:",omitempty" json:"lock,omitempty"`
+	Metadata    map[string]any      `yaml:",omitempty" json:"metadata,omitempty"`
+}
+
+// PortsConfig restricts the destination ports the clients of the handler may request, it is checked before dialing.
+type PortsConfig struct {
+	// Allow is the allowed ports, e.g. 80,443 or 8000-8080, all the ports are allowed if empty.
+	Allow []string `yaml:",omitempty" json:"allow,omitempty"`
+	// Deny is the denied ports, it is checked after the allowed ports.
+	Deny []string `yaml:",omitempty" json:"deny,omitempty"`
+}
+
+// LockConfig locks the handler to the allowed domains, all the other destinations are denied.
+type LockConfig struct {
+	// Domains are the allowed domains, e.g. example.com, .example.com (and the subdomains) or *.example.com.
+	// The SNI or the Host header of the flows to the domains must also be allowed.
+	Domains []string `yaml:",omitempty" json:"domains,omitempty"`
+	// Strict refuses the flows without the SNI or Host header, e.g. the protocols other than TLS and HTTP.
+	Strict bool `yaml:",omitempty" json:"strict,omitempty"`
+}
+
+type MiddlewareConfig struct {
+	Type     string         `json:"type"`
+	Metadata map[string]any `yaml:",omitempty" json:"metadata,omitempty"`
 }
 
 type ForwarderConfig struct {
 	Name     string               `yaml:",omitempty" json:"name,omitempty"`
 	Selector *SelectorConfig      `yaml:",omitempty" json:"selector,omitempty"`
 	Nodes    []*ForwardNodeConfig `json:"nodes"`
+	SD       *SDLoader            `yaml:"sd,omitempty" json:"sd,omitempty"`
+	// HealthCheck probes the targets, the unhealthy targets get no new connections.
+	HealthCheck *HealthCheckConfig `yaml:"healthCheck,omitempty" json:"healthCheck,omitempty"`
+	// Drain is the grace period after which the connections to the removed or unhealthy targets are closed,
+	// zero keeps the existing connections.
+	Drain time.Duration `yaml:",omitempty" json:"drain,omitempty"`
 }
 
 type ForwardNodeConfig struct {
-	Name     string          `yaml:",omitempty" json:"name,omitempty"`
-	Addr     string          `yaml:",omitempty" json:"addr,omitempty"`
-	Host     string          `yaml:",omitempty" json:"host,omitempty"`
-	Network  string          `yaml:",omitempty" json:"network,omitempty"`
-	Protocol string          `yaml:",omitempty" json:"protocol,omitempty"`
-	Path     string          `yaml:",omitempty" json:"path,omitempty"`
-	Bypass   string          `yaml:",omitempty" json:"bypass,omitempty"`
-	Bypasses []string        `yaml:",omitempty" json:"bypasses,omitempty"`
-	HTTP     *HTTPNodeConfig `yaml:",omitempty" json:"http,omitempty"`
-	TLS      *TLSNodeConfig  `yaml:",omitempty" json:"tls,omitempty"`
+	Name      string          `yaml:",omitempty" json:"name,omitempty"`
+	Addr      string          `yaml:",omitempty" json:"addr,omitempty"`
+	Host      string          `yaml:",omitempty" json:"host,omitempty"`
+	Network   string          `yaml:",omitempty" json:"network,omitempty"`
+	Protocol  string          `yaml:",omitempty" json:"protocol,omitempty"`
+	Path      string          `yaml:",omitempty" json:"path,omitempty"`
+	Interface string          `yaml:",omitempty" json:"interface,omitempty"`
+	SockOpts  *SockOptsConfig `yaml:"sockopts,omitempty" json:"sockopts,omitempty"`
+	Bypass    string          `yaml:",omitempty" json:"bypass,omitempty"`
+	Bypasses  []string        `yaml:",omitempty" json:"bypasses,omitempty"`
+	HTTP      *HTTPNodeConfig `yaml:",omitempty" json:"http,omitempty"`
+	TLS       *TLSNodeConfig  `yaml:",omitempty" json:"tls,omitempty"`
 	// DEPRECATED by HTTP.Auth
 	Auth     *AuthConfig    `yaml:",omitempty" json:"auth,omitempty"`
 	Metadata map[string]any `yaml:",omitempty" json:"metadata,omitempty"`
@@ -402,6 +629,8 @@ type SockOptsConfig struct {
 
 type ServiceConfig struct {
 	Name string `json:"name"`
+	// Addr can be a port range or list, e.g. :10000-10100, :8000,8080,9000-9010,
+	// the service listens on each port, the {port} (or {port+N}, {port-N}) in the forwarder node address is replaced by the port.
 	Addr string `yaml:",omitempty" json:"addr,omitempty"`
 	// DEPRECATED by metadata.interface since beta.5
 	Interface string `yaml:",omitempty" json:"interface,omitempty"`
@@ -424,15 +653,107 @@ type ServiceConfig struct {
 	Listener   *ListenerConfig   `yaml:",omitempty" json:"listener,omitempty"`
 	Forwarder  *ForwarderConfig  `yaml:",omitempty" json:"forwarder,omitempty"`
 	Metadata   map[string]any    `yaml:",omitempty" json:"metadata,omitempty"`
+	// DependsOn is the names of the services or chains the service depends on,
+	// the service starts serving after they are ready.
+	DependsOn []string       `yaml:"dependsOn,omitempty" json:"dependsOn,omitempty"`
+	Startup   *StartupConfig `yaml:",omitempty" json:"startup,omitempty"`
+	// Egress selects the source IPs of the connections dialed directly by the service.
+	Egress *EgressConfig `yaml:",omitempty" json:"egress,omitempty"`
+	// Preset expands to the handler and listener of the common setup,
+	// the handler and listener set explicitly are merged into it.
+	Preset *PresetConfig `yaml:",omitempty" json:"preset,omitempty"`
+	// Schedule enables the service only in the time windows, the port is closed out of the windows.
+	Schedule *ScheduleConfig `yaml:",omitempty" json:"schedule,omitempty"`
+	// SMTP controls the connections of the service to the SMTP ports.
+	SMTP *SMTPConfig `yaml:"smtp,omitempty" json:"smtp,omitempty"`
 	// service status, read-only
 	Status *ServiceStatus `yaml:",omitempty" json:"status,omitempty"`
 }
 
+type ScheduleConfig struct {
+	// Windows are the weekly time windows in the form of [days] HH:MM-HH:MM,
+	// e.g. mon-fri 08:00-18:00, sat,sun 10:00-12:00, the window ending before it starts ends on the next day.
+	Windows []string `json:"windows"`
+	// Timezone is the IANA timezone of the windows, e.g. Europe/Berlin, default is the local timezone.
+	Timezone string `yaml:",omitempty" json:"timezone,omitempty"`
+}
+
+// PresetConfig is the composite preset of the handler and listener.
+type PresetConfig struct {
+	// Type is the preset type, socks5+tls or socks5+wss.
+	Type string `json:"type"`
+	// Path is the websocket path of socks5+wss, default is /ws.
+	Path string      `yaml:",omitempty" json:"path,omitempty"`
+	Auth *AuthConfig `yaml:",omitempty" json:"auth,omitempty"`
+	TLS  *TLSConfig  `yaml:",omitempty" json:"tls,omitempty"`
+	// Fallback is the response to the non-proxy requests in the form of type:value,
+	// e.g. file:/var/www/html, web:example.com, host:127.0.0.1:8080 or code:404.
+	Fallback string `yaml:",omitempty" json:"fallback,omitempty"`
+}
+
+type StartupConfig struct {
+	// Timeout is the time to wait for the dependencies of each attempt, default is 30s.
+	Timeout time.Duration `yaml:",omitempty" json:"timeout,omitempty"`
+	// Retries is the number of the retries after the timeout, -1 for unlimited.
+	Retries int `yaml:",omitempty" json:"retries,omitempty"`
+}
+
+type EgressConfig struct {
+	// IPs is the default pool of the source IPs, the IP can also be an interface name.
+	IPs []string `yaml:"ips,omitempty" json:"ips,omitempty"`
+	// Rules map the users or the destinations to the pools of the source IPs, the first matched rule is used.
+	Rules []*EgressRuleConfig `yaml:",omitempty" json:"rules,omitempty"`
+	// Strategy selects the source IP from the pool for a new session, one of round (default), random and hash.
+	Strategy string `yaml:",omitempty" json:"strategy,omitempty"`
+	// StickyTTL is the idle time after which the session is assigned a new source IP, default is 10m.
+	StickyTTL time.Duration `yaml:"stickyTTL,omitempty" json:"stickyTTL,omitempty"`
+}
+
+type SMTPConfig struct {
+	// Action is one of block (default) and limit.
+	Action string `yaml:",omitempty" json:"action,omitempty"`
+	// Ports are the SMTP ports, the port can be a range MIN-MAX, default is 25.
+	Ports []string `yaml:",omitempty" json:"ports,omitempty"`
+	// Allow is the allowlist, the connections matching any of the rules are neither blocked nor limited.
+	Allow []*SMTPAllowConfig `yaml:",omitempty" json:"allow,omitempty"`
+	// Rate is the maximum connections of each user in the period of the limit action.
+	Rate int `yaml:",omitempty" json:"rate,omitempty"`
+	// Period is the period of the rate, default is 1h.
+	Period time.Duration `yaml:",omitempty" json:"period,omitempty"`
+}
+
+// SMTPAllowConfig allows the users to connect to the destinations, all the users or destinations are allowed if empty.
+type SMTPAllowConfig struct {
+	Matchers []string `yaml:",omitempty" json:"matchers,omitempty"`
+	Users    []string `yaml:",omitempty" json:"users,omitempty"`
+}
+
+type EgressRuleConfig struct {
+	Matchers []string `yaml:",omitempty" json:"matchers,omitempty"`
+	Ports    []string `yaml:",omitempty" json:"ports,omitempty"`
+	Users    []string `yaml:",omitempty" json:"users,omitempty"`
+	Bypass   string   `yaml:",omitempty" json:"bypass,omitempty"`
+	IPs      []string `yaml:"ips,omitempty" json:"ips,omitempty"`
+}
+
 type ServiceStatus struct {
 	CreateTime int64          `yaml:"createTime" json:"createTime"`
 	State      string         `yaml:"state" json:"state"`
 	Events     []ServiceEvent `yaml:",omitempty" json:"events,omitempty"`
 	Stats      *ServiceStats  `yaml:",omitempty" json:"stats,omitempty"`
+	// the actual address of the service, e.g. the port allocated by the server for the remote port forwarding on port 0.
+	Addr string `yaml:",omitempty" json:"addr,omitempty"`
+	// the resource consumption of the service if the accounting is enabled.
+	Resources *ServiceResources `yaml:",omitempty" json:"resources,omitempty"`
+}
+
+type ServiceResources struct {
+	Goroutines int64 `yaml:"goroutines" json:"goroutines"`
+	FDs        int64 `yaml:"fds" json:"fds"`
+	// the estimated memory in bytes.
+	Memory int64 `yaml:"memory" json:"memory"`
+	// the bandwidth in bytes per second.
+	Bandwidth int64 `yaml:"bandwidth" json:"bandwidth"`
 }
 
 type ServiceEvent struct {
@@ -446,16 +767,42 @@ type ServiceStats struct {
 	TotalErrs    uint64 `yaml:"totalErrs" json:"totalErrs"`
 	InputBytes   uint64 `yaml:"inputBytes" json:"inputBytes"`
 	OutputBytes  uint64 `yaml:"outputBytes" json:"outputBytes"`
+	// the number of the handler errors by the kinds: resolve, refused, timeout, auth, bypass, limiter and other.
+	Errors map[string]uint64 `yaml:"errors,omitempty" json:"errors,omitempty"`
 }
 
 type ChainConfig struct {
-	Name     string         `json:"name"`
-	Hops     []*HopConfig   `json:"hops"`
-	Metadata map[string]any `yaml:",omitempty" json:"metadata,omitempty"`
+	Name      string             `json:"name"`
+	Interface string             `yaml:",omitempty" json:"interface,omitempty"`
+	SockOpts  *SockOptsConfig    `yaml:"sockopts,omitempty" json:"sockopts,omitempty"`
+	Hops      []*HopConfig       `json:"hops"`
+	Rules     []*ChainRuleConfig `yaml:",omitempty" json:"rules,omitempty"`
+	// SystemProxy decides the upstream proxy of each connection by the system proxy settings instead of the hops.
+	SystemProxy *SystemProxyConfig `yaml:"systemProxy,omitempty" json:"systemProxy,omitempty"`
+	Metadata    map[string]any     `yaml:",omitempty" json:"metadata,omitempty"`
+}
+
+// SystemProxyConfig uses the proxy settings of Windows or macOS, or the environment variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+type SystemProxyConfig struct {
+	Reload time.Duration `yaml:",omitempty" json:"reload,omitempty"`
+	// FailOpen connects directly if the proxy settings cannot be found,
+	// otherwise the connections fail.
+	FailOpen bool `yaml:"failOpen,omitempty" json:"failOpen,omitempty"`
+	// Auth is the authentication of the proxies of the settings.
+	Auth *AuthConfig `yaml:",omitempty" json:"auth,omitempty"`
+}
+
+type ChainRuleConfig struct {
+	Matchers []string `yaml:",omitempty" json:"matchers,omitempty"`
+	Ports    []string `yaml:",omitempty" json:"ports,omitempty"`
+	Users    []string `yaml:",omitempty" json:"users,omitempty"`
+	Bypass   string   `yaml:",omitempty" json:"bypass,omitempty"`
+	Chain    string   `yaml:",omitempty" json:"chain,omitempty"`
 }
 
 type ChainGroupConfig struct {
 	Chains   []string        `yaml:",omitempty" json:"chains,omitempty"`
+	Weights  []int           `yaml:",omitempty" json:"weights,omitempty"`
 	Selector *SelectorConfig `yaml:",omitempty" json:"selector,omitempty"`
 }
 
@@ -473,7 +820,21 @@ type HopConfig struct {
 	File      *FileLoader     `yaml:",omitempty" json:"file,omitempty"`
 	Redis     *RedisLoader    `yaml:",omitempty" json:"redis,omitempty"`
 	HTTP      *HTTPLoader     `yaml:"http,omitempty" json:"http,omitempty"`
+	SD        *SDLoader       `yaml:"sd,omitempty" json:"sd,omitempty"`
 	Plugin    *PluginConfig   `yaml:",omitempty" json:"plugin,omitempty"`
+	// HealthCheck probes the nodes, the unhealthy nodes are not selected.
+	HealthCheck *HealthCheckConfig `yaml:"healthCheck,omitempty" json:"healthCheck,omitempty"`
+	// Drain is the grace period after which the tracked connections to the removed or unhealthy nodes are closed.
+	Drain time.Duration `yaml:",omitempty" json:"drain,omitempty"`
+	// Pin pins the nodes with the domain addresses to the resolved IP addresses,
+	// the domains are re-resolved when the TTL expires.
+	Pin *PinConfig `yaml:",omitempty" json:"pin,omitempty"`
+}
+
+type TemplateConfig struct {
+	Name      string           `json:"name"`
+	Connector *ConnectorConfig `yaml:",omitempty" json:"connector,omitempty"`
+	Dialer    *DialerConfig    `yaml:",omitempty" json:"dialer,omitempty"`
 }
 
 type NodeConfig struct {
@@ -489,6 +850,7 @@ type NodeConfig struct {
 	Bypasses  []string         `yaml:",omitempty" json:"bypasses,omitempty"`
 	Resolver  string           `yaml:",omitempty" json:"resolver,omitempty"`
 	Hosts     string           `yaml:",omitempty" json:"hosts,omitempty"`
+	Template  string           `yaml:",omitempty" json:"template,omitempty"`
 	Connector *ConnectorConfig `yaml:",omitempty" json:"connector,omitempty"`
 	Dialer    *DialerConfig    `yaml:",omitempty" json:"dialer,omitempty"`
 	HTTP      *HTTPNodeConfig  `yaml:",omitempty" json:"http,omitempty"`
@@ -501,6 +863,7 @@ type Config struct {
 	Services   []*ServiceConfig   `json:"services"`
 	Chains     []*ChainConfig     `yaml:",omitempty" json:"chains,omitempty"`
 	Hops       []*HopConfig       `yaml:",omitempty" json:"hops,omitempty"`
+	Templates  []*TemplateConfig  `yaml:",omitempty" json:"templates,omitempty"`
 	Authers    []*AutherConfig    `yaml:",omitempty" json:"authers,omitempty"`
 	Admissions []*AdmissionConfig `yaml:",omitempty" json:"admissions,omitempty"`
 	Bypasses   []*BypassConfig    `yaml:",omitempty" json:"bypasses,omitempty"`
@@ -520,6 +883,11 @@ type Config struct {
 	Profiling  *ProfilingConfig   `yaml:",omitempty" json:"profiling,omitempty"`
 	API        *APIConfig         `yaml:",omitempty" json:"api,omitempty"`
 	Metrics    *MetricsConfig     `yaml:",omitempty" json:"metrics,omitempty"`
+	BufferPool *BufferPoolConfig  `yaml:"bufferPool,omitempty" json:"bufferPool,omitempty"`
+	Idle       *IdleConfig        `yaml:",omitempty" json:"idle,omitempty"`
+	SelfTest   *SelfTestConfig    `yaml:"selftest,omitempty" json:"selftest,omitempty"`
+	// the unknown keys and type mismatches in the metadata of the listeners, handlers, dialers and connectors are errors.
+	StrictMetadata bool `yaml:"strictMetadata,omitempty" json:"strictMetadata,omitempty"`
 }
 
 func (c *Config) Load() error {
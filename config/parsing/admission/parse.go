@@ -1,7 +1,6 @@
 package admission
 
 import (
-	"crypto/tls"
 	"strings"
 
 	"github.com/go-gost/core/admission"
@@ -9,6 +8,7 @@ import (
 	xadmission "github.com/go-gost/x/admission"
 	admission_plugin "github.com/go-gost/x/admission/plugin"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	"github.com/go-gost/x/internal/loader"
 	"github.com/go-gost/x/internal/plugin"
 	"github.com/go-gost/x/registry"
@@ -20,12 +20,10 @@ func ParseAdmission(cfg *config.AdmissionConfig) admission.Admission {
 	}
 
 	if cfg.Plugin != nil {
-		var tlsCfg *tls.Config
-		if cfg.Plugin.TLS != nil {
-			tlsCfg = &tls.Config{
-				ServerName:         cfg.Plugin.TLS.ServerName,
-				InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
-			}
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+		if err != nil {
+			logger.Default().Errorf("admission %s: %v", cfg.Name, err)
+			return nil
 		}
 		switch strings.ToLower(cfg.Plugin.Type) {
 		case "http":
@@ -38,6 +36,8 @@ func ParseAdmission(cfg *config.AdmissionConfig) admission.Admission {
 			return admission_plugin.NewGRPCPlugin(
 				cfg.Name, cfg.Plugin.Addr,
 				plugin.TokenOption(cfg.Plugin.Token),
+				plugin.RetriesOption(cfg.Plugin.Retries),
+				plugin.CacheTTLOption(cfg.Plugin.CacheTTL),
 				plugin.TLSConfigOption(tlsCfg),
 			)
 		}
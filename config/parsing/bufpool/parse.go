@@ -0,0 +1,13 @@
+package bufpool
+
+import (
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/internal/bufpool"
+)
+
+func ParseBufferPool(cfg *config.BufferPoolConfig) {
+	if cfg == nil {
+		return
+	}
+	bufpool.Init(bufpool.MaxSizeOption(cfg.MaxSize))
+}
@@ -1,7 +1,6 @@
 package bypass
 
 import (
-	"crypto/tls"
 	"strings"
 
 	"github.com/go-gost/core/bypass"
@@ -9,6 +8,7 @@ import (
 	xbypass "github.com/go-gost/x/bypass"
 	bypass_plugin "github.com/go-gost/x/bypass/plugin"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	"github.com/go-gost/x/internal/loader"
 	"github.com/go-gost/x/internal/plugin"
 	"github.com/go-gost/x/registry"
@@ -20,12 +20,10 @@ func ParseBypass(cfg *config.BypassConfig) bypass.Bypass {
 	}
 
 	if cfg.Plugin != nil {
-		var tlsCfg *tls.Config
-		if cfg.Plugin.TLS != nil {
-			tlsCfg = &tls.Config{
-				ServerName:         cfg.Plugin.TLS.ServerName,
-				InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
-			}
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+		if err != nil {
+			logger.Default().Errorf("bypass %s: %v", cfg.Name, err)
+			return nil
 		}
 		switch strings.ToLower(cfg.Plugin.Type) {
 		case "http":
@@ -38,6 +36,8 @@ func ParseBypass(cfg *config.BypassConfig) bypass.Bypass {
 			return bypass_plugin.NewGRPCPlugin(
 				cfg.Name, cfg.Plugin.Addr,
 				plugin.TokenOption(cfg.Plugin.Token),
+				plugin.RetriesOption(cfg.Plugin.Retries),
+				plugin.CacheTTLOption(cfg.Plugin.CacheTTL),
 				plugin.TLSConfigOption(tlsCfg),
 			)
 		}
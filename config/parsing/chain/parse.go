@@ -1,18 +1,26 @@
 package chain
 
 import (
+	"time"
+
 	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/hop"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
 	xchain "github.com/go-gost/x/chain"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	hop_parser "github.com/go-gost/x/config/parsing/hop"
+	node_parser "github.com/go-gost/x/config/parsing/node"
+	"github.com/go-gost/x/internal/util/sysproxy"
 	mdx "github.com/go-gost/x/metadata"
 	"github.com/go-gost/x/registry"
 )
 
-func ParseChain(cfg *config.ChainConfig, log logger.Logger) (chain.Chainer, error) {
+// ParseChain creates the chain from the chain config,
+// the templates are used by the nodes of the inline hops referencing a template.
+func ParseChain(cfg *config.ChainConfig, log logger.Logger, templates ...*config.TemplateConfig) (chain.Chainer, error) {
 	if cfg == nil {
 		return nil, nil
 	}
@@ -23,12 +31,42 @@ func ParseChain(cfg *config.ChainConfig, log logger.Logger) (chain.Chainer, erro
 	})
 
 	var md metadata.Metadata
+	var poolSize int
+	var poolIdleTimeout time.Duration
 	if cfg.Metadata != nil {
 		md = mdx.NewMetadata(cfg.Metadata)
+		poolSize = mdutil.GetInt(md, parsing.MDKeyPoolSize)
+		poolIdleTimeout = mdutil.GetDuration(md, parsing.MDKeyPoolIdleTimeout)
+	}
+
+	var rules []*xchain.Rule
+	for _, rule := range cfg.Rules {
+		if rule == nil {
+			continue
+		}
+		rules = append(rules, xchain.NewRule(
+			xchain.MatchersRuleOption(rule.Matchers...),
+			xchain.PortsRuleOption(rule.Ports...),
+			xchain.UsersRuleOption(rule.Users...),
+			xchain.BypassRuleOption(registry.BypassRegistry().Get(rule.Bypass)),
+			xchain.ChainRuleOption(registry.ChainRegistry().Get(rule.Chain)),
+		))
+	}
+
+	var sysProxyResolver *sysproxy.Resolver
+	var sysProxyFailOpen bool
+	if cfg.SystemProxy != nil {
+		sysProxyResolver = parseSystemProxy(cfg.SystemProxy, chainLogger)
+		sysProxyFailOpen = cfg.SystemProxy.FailOpen
 	}
 
 	c := xchain.NewChain(cfg.Name,
 		xchain.MetadataChainOption(md),
+		xchain.RulesChainOption(rules...),
+		xchain.SystemProxyChainOption(sysProxyResolver, sysProxyFailOpen, func(proxy sysproxy.Proxy) (*chain.Node, error) {
+			return parseSystemProxyNode(cfg, proxy, log)
+		}),
+		xchain.PoolChainOption(poolSize, poolIdleTimeout),
 		xchain.LoggerChainOption(chainLogger),
 	)
 
@@ -37,7 +75,15 @@ func ParseChain(cfg *config.ChainConfig, log logger.Logger) (chain.Chainer, erro
 		var err error
 
 		if ch.Nodes != nil || ch.Plugin != nil {
-			if hop, err = hop_parser.ParseHop(ch, log); err != nil {
+			// the hop inherits the egress settings of the chain without modifying the stored config.
+			hc := *ch
+			if hc.Interface == "" {
+				hc.Interface = cfg.Interface
+			}
+			if hc.SockOpts == nil {
+				hc.SockOpts = cfg.SockOpts
+			}
+			if hop, err = hop_parser.ParseHop(&hc, log, templates...); err != nil {
 				return nil, err
 			}
 		} else {
@@ -50,3 +96,38 @@ func ParseChain(cfg *config.ChainConfig, log logger.Logger) (chain.Chainer, erro
 
 	return c, nil
 }
+
+func parseSystemProxy(cfg *config.SystemProxyConfig, log logger.Logger) *sysproxy.Resolver {
+	return sysproxy.NewResolver(
+		sysproxy.ReloadPeriodOption(cfg.Reload),
+		sysproxy.LoggerOption(log.WithFields(map[string]any{
+			"kind": "sysproxy",
+		})),
+	)
+}
+
+// parseSystemProxyNode creates the node of the proxy of the system proxy settings.
+func parseSystemProxyNode(cfg *config.ChainConfig, proxy sysproxy.Proxy, log logger.Logger) (*chain.Node, error) {
+	nodeCfg := &config.NodeConfig{
+		Name:      proxy.String(),
+		Addr:      proxy.Addr,
+		Interface: cfg.Interface,
+		SockOpts:  cfg.SockOpts,
+		Connector: &config.ConnectorConfig{
+			Type: "http",
+			Auth: cfg.SystemProxy.Auth,
+		},
+		Dialer: &config.DialerConfig{
+			Type: "tcp",
+		},
+	}
+	switch proxy.Type {
+	case sysproxy.ProxyHTTPS:
+		nodeCfg.Dialer.Type = "tls"
+	case sysproxy.ProxySOCKS4:
+		nodeCfg.Connector.Type = "socks4"
+	case sysproxy.ProxySOCKS5:
+		nodeCfg.Connector.Type = "socks5"
+	}
+	return node_parser.ParseNode(cfg.Name, nodeCfg, log)
+}
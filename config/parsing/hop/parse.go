@@ -1,14 +1,15 @@
 package hop
 
 import (
-	"crypto/tls"
 	"strings"
+	"time"
 
 	"github.com/go-gost/core/bypass"
 	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/hop"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	bypass_parser "github.com/go-gost/x/config/parsing/bypass"
 	node_parser "github.com/go-gost/x/config/parsing/node"
 	selector_parser "github.com/go-gost/x/config/parsing/selector"
@@ -16,20 +17,24 @@ import (
 	hop_plugin "github.com/go-gost/x/hop/plugin"
 	"github.com/go-gost/x/internal/loader"
 	"github.com/go-gost/x/internal/plugin"
+	"github.com/go-gost/x/registry"
 )
 
-func ParseHop(cfg *config.HopConfig, log logger.Logger) (hop.Hop, error) {
+const (
+	defaultDiscoveryPeriod = 30 * time.Second
+)
+
+// ParseHop creates the hop from the hop config,
+// the templates are used by the nodes referencing a template, including the nodes discovered later.
+func ParseHop(cfg *config.HopConfig, log logger.Logger, templates ...*config.TemplateConfig) (hop.Hop, error) {
 	if cfg == nil {
 		return nil, nil
 	}
 
 	if cfg.Plugin != nil {
-		var tlsCfg *tls.Config
-		if cfg.Plugin.TLS != nil {
-			tlsCfg = &tls.Config{
-				ServerName:         cfg.Plugin.TLS.ServerName,
-				InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
-			}
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+		if err != nil {
+			return nil, err
 		}
 		switch strings.ToLower(cfg.Plugin.Type) {
 		case "http":
@@ -42,12 +47,15 @@ func ParseHop(cfg *config.HopConfig, log logger.Logger) (hop.Hop, error) {
 			return hop_plugin.NewGRPCPlugin(
 				cfg.Name, cfg.Plugin.Addr,
 				plugin.TokenOption(cfg.Plugin.Token),
+				plugin.RetriesOption(cfg.Plugin.Retries),
 				plugin.TLSConfigOption(tlsCfg),
 			), nil
 		}
 	}
 
 	var nodes []*chain.Node
+	var srvNodes []*config.NodeConfig
+	var pinNodes []*config.NodeConfig
 	for _, v := range cfg.Nodes {
 		if v == nil {
 			continue
@@ -66,19 +74,29 @@ func ParseHop(cfg *config.HopConfig, log logger.Logger) (hop.Hop, error) {
 			v.SockOpts = cfg.SockOpts
 		}
 
-		if v.Connector == nil {
+		// the dialer and connector of the node using a template are filled in by the template.
+		if v.Connector == nil && v.Template == "" {
 			v.Connector = &config.ConnectorConfig{
 				Type: "http",
 			}
 		}
 
-		if v.Dialer == nil {
+		if v.Dialer == nil && v.Template == "" {
 			v.Dialer = &config.DialerConfig{
 				Type: "tcp",
 			}
 		}
 
-		node, err := node_parser.ParseNode(cfg.Name, v, log)
+		if xhop.IsSRVAddr(v.Addr) {
+			srvNodes = append(srvNodes, v)
+			continue
+		}
+		if cfg.Pin != nil && v.Template == "" && xhop.IsDomainAddr(v.Addr) {
+			pinNodes = append(pinNodes, v)
+			continue
+		}
+
+		node, err := node_parser.ParseNode(cfg.Name, v, log, templates...)
 		if err != nil {
 			return nil, err
 		}
@@ -98,6 +116,7 @@ func ParseHop(cfg *config.HopConfig, log logger.Logger) (hop.Hop, error) {
 		xhop.SelectorOption(sel),
 		xhop.BypassOption(bypass.BypassGroup(bypass_parser.List(cfg.Bypass, cfg.Bypasses...)...)),
 		xhop.ReloadPeriodOption(cfg.Reload),
+		xhop.TemplatesOption(templates...),
 		xhop.LoggerOption(log.WithFields(map[string]any{
 			"kind": "hop",
 			"hop":  cfg.Name,
@@ -121,5 +140,38 @@ func ParseHop(cfg *config.HopConfig, log logger.Logger) (hop.Hop, error) {
 			loader.TimeoutHTTPLoaderOption(cfg.HTTP.Timeout),
 		)))
 	}
+	if len(srvNodes) > 0 {
+		opts = append(opts, xhop.SRVNodeOption(srvNodes...))
+	}
+	if len(pinNodes) > 0 {
+		opts = append(opts, xhop.PinNodeOption(&xhop.Pin{
+			MinTTL: cfg.Pin.MinTTL,
+			MaxTTL: cfg.Pin.MaxTTL,
+		}, pinNodes...))
+	}
+	if cfg.SD != nil && cfg.SD.SD != "" {
+		opts = append(opts, xhop.SDOption(
+			registry.SDRegistry().Get(cfg.SD.SD),
+			cfg.SD.Service,
+			cfg.SD.Template,
+		))
+	}
+	if hc := cfg.HealthCheck; hc != nil {
+		opts = append(opts, xhop.HealthCheckOption(&xhop.HealthCheck{
+			Type:     hc.Type,
+			Interval: hc.Interval,
+			Timeout:  hc.Timeout,
+			Path:     hc.Path,
+			Fails:    hc.Fails,
+			Passes:   hc.Passes,
+		}))
+	}
+	if cfg.Drain > 0 {
+		opts = append(opts, xhop.DrainOption(cfg.Drain))
+	}
+	// the discovered nodes must be refreshed periodically.
+	if (len(srvNodes) > 0 || cfg.SD != nil) && cfg.Reload <= 0 {
+		opts = append(opts, xhop.ReloadPeriodOption(defaultDiscoveryPeriod))
+	}
 	return xhop.NewHop(opts...), nil
 }
@@ -1,13 +1,13 @@
 package hosts
 
 import (
-	"crypto/tls"
 	"net"
 	"strings"
 
 	"github.com/go-gost/core/hosts"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	xhosts "github.com/go-gost/x/hosts"
 	hosts_plugin "github.com/go-gost/x/hosts/plugin"
 	"github.com/go-gost/x/internal/loader"
@@ -20,12 +20,10 @@ func ParseHostMapper(cfg *config.HostsConfig) hosts.HostMapper {
 	}
 
 	if cfg.Plugin != nil {
-		var tlsCfg *tls.Config
-		if cfg.Plugin.TLS != nil {
-			tlsCfg = &tls.Config{
-				ServerName:         cfg.Plugin.TLS.ServerName,
-				InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
-			}
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+		if err != nil {
+			logger.Default().Errorf("hosts %s: %v", cfg.Name, err)
+			return nil
 		}
 		switch strings.ToLower(cfg.Plugin.Type) {
 		case "http":
@@ -38,6 +36,7 @@ func ParseHostMapper(cfg *config.HostsConfig) hosts.HostMapper {
 			return hosts_plugin.NewGRPCPlugin(
 				cfg.Name, cfg.Plugin.Addr,
 				plugin.TokenOption(cfg.Plugin.Token),
+				plugin.RetriesOption(cfg.Plugin.Retries),
 				plugin.TLSConfigOption(tlsCfg),
 			)
 		}
@@ -0,0 +1,17 @@
+package idle
+
+import (
+	"github.com/go-gost/x/config"
+	xnet "github.com/go-gost/x/internal/net"
+)
+
+func ParseIdle(cfg *config.IdleConfig) {
+	if cfg == nil {
+		return
+	}
+	xnet.InitIdleReaper(
+		xnet.IdleTimeoutOption(xnet.IdleTCP, cfg.TCP),
+		xnet.IdleTimeoutOption(xnet.IdleUDP, cfg.UDP),
+		xnet.IdleTimeoutOption(xnet.IdleTunnel, cfg.Tunnel),
+	)
+}
@@ -1,12 +1,12 @@
 package ingress
 
 import (
-	"crypto/tls"
 	"strings"
 
 	"github.com/go-gost/core/ingress"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	xingress "github.com/go-gost/x/ingress"
 	ingress_plugin "github.com/go-gost/x/ingress/plugin"
 	"github.com/go-gost/x/internal/loader"
@@ -19,12 +19,10 @@ func ParseIngress(cfg *config.IngressConfig) ingress.Ingress {
 	}
 
 	if cfg.Plugin != nil {
-		var tlsCfg *tls.Config
-		if cfg.Plugin.TLS != nil {
-			tlsCfg = &tls.Config{
-				ServerName:         cfg.Plugin.TLS.ServerName,
-				InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
-			}
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+		if err != nil {
+			logger.Default().Errorf("ingress %s: %v", cfg.Name, err)
+			return nil
 		}
 		switch strings.ToLower(cfg.Plugin.Type) {
 		case "http":
@@ -37,20 +35,26 @@ func ParseIngress(cfg *config.IngressConfig) ingress.Ingress {
 			return ingress_plugin.NewGRPCPlugin(
 				cfg.Name, cfg.Plugin.Addr,
 				plugin.TokenOption(cfg.Plugin.Token),
+				plugin.RetriesOption(cfg.Plugin.Retries),
 				plugin.TLSConfigOption(tlsCfg),
 			)
 		}
 	}
 
-	var rules []*ingress.Rule
+	var rules []*xingress.Rule
 	for _, rule := range cfg.Rules {
 		if rule.Hostname == "" || rule.Endpoint == "" {
 			continue
 		}
 
-		rules = append(rules, &ingress.Rule{
-			Hostname: rule.Hostname,
-			Endpoint: rule.Endpoint,
+		rules = append(rules, &xingress.Rule{
+			Rule: ingress.Rule{
+				Hostname: rule.Hostname,
+				Endpoint: rule.Endpoint,
+			},
+			Path:    rule.Path,
+			Headers: rule.Headers,
+			Chain:   rule.Chain,
 		})
 	}
 	opts := []xingress.Option{
@@ -19,16 +19,24 @@ import (
 	"github.com/go-gost/x/config/parsing"
 	auth_parser "github.com/go-gost/x/config/parsing/auth"
 	bypass_parser "github.com/go-gost/x/config/parsing/bypass"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/mux"
 	tls_util "github.com/go-gost/x/internal/util/tls"
 	mdx "github.com/go-gost/x/metadata"
 	"github.com/go-gost/x/registry"
 )
 
-func ParseNode(hop string, cfg *config.NodeConfig, log logger.Logger) (*chain.Node, error) {
+// ParseNode creates the node of the hop from the node config,
+// the template referenced by the node is looked up in the templates.
+func ParseNode(hop string, cfg *config.NodeConfig, log logger.Logger, templates ...*config.TemplateConfig) (*chain.Node, error) {
 	if cfg == nil {
 		return nil, nil
 	}
 
+	if cfg.Template != "" {
+		cfg = applyTemplate(cfg, templates, log)
+	}
+
 	if cfg.Connector == nil {
 		cfg.Connector = &config.ConnectorConfig{
 			Type: "http",
@@ -68,6 +76,7 @@ func ParseNode(hop string, cfg *config.NodeConfig, log logger.Logger) (*chain.No
 	if cfg.Metadata != nil {
 		nm = mdx.NewMetadata(cfg.Metadata)
 	}
+	strict := parsing.IsStrictMetadata(nm)
 
 	connectorLogger := nodeLogger.WithFields(map[string]any{
 		"kind": "connector",
@@ -86,10 +95,15 @@ func ParseNode(hop string, cfg *config.NodeConfig, log logger.Logger) (*chain.No
 	if cfg.Connector.Metadata == nil {
 		cfg.Connector.Metadata = make(map[string]any)
 	}
-	if err := cr.Init(mdx.NewMetadata(cfg.Connector.Metadata)); err != nil {
+	cmd := parsing.NewMetadata(cfg.Connector.Metadata, strict)
+	if err := cr.Init(cmd); err != nil {
 		connectorLogger.Error("init: ", err)
 		return nil, err
 	}
+	if err := parsing.CheckMetadata(cmd); err != nil {
+		connectorLogger.Error(err)
+		return nil, fmt.Errorf("hop %s: node %s: connector %s: %w", hop, cfg.Name, cfg.Connector.Type, err)
+	}
 
 	tlsCfg = cfg.Dialer.TLS
 	if tlsCfg == nil {
@@ -128,10 +142,21 @@ func ParseNode(hop string, cfg *config.NodeConfig, log logger.Logger) (*chain.No
 	if cfg.Dialer.Metadata == nil {
 		cfg.Dialer.Metadata = make(map[string]any)
 	}
-	if err := d.Init(mdx.NewMetadata(cfg.Dialer.Metadata)); err != nil {
+	dmd := parsing.NewMetadata(cfg.Dialer.Metadata, strict)
+	if err := d.Init(dmd); err != nil {
 		dialerLogger.Error("init: ", err)
 		return nil, err
 	}
+	muxCfg, err := mux.ParseConfig(dmd)
+	if err != nil {
+		dialerLogger.Error(err)
+		return nil, err
+	}
+	d = mux.WrapDialer(d, muxCfg, dialerLogger)
+	if err := parsing.CheckMetadata(dmd); err != nil {
+		dialerLogger.Error(err)
+		return nil, fmt.Errorf("hop %s: node %s: dialer %s: %w", hop, cfg.Name, cfg.Dialer.Type, err)
+	}
 
 	var sockOpts *chain.SockOpts
 	if cfg.SockOpts != nil {
@@ -160,7 +185,8 @@ func ParseNode(hop string, cfg *config.NodeConfig, log logger.Logger) (*chain.No
 	opts := []chain.NodeOption{
 		chain.TransportNodeOption(tr),
 		chain.BypassNodeOption(bypass.BypassGroup(bypass_parser.List(cfg.Bypass, cfg.Bypasses...)...)),
-		chain.ResoloverNodeOption(registry.ResolverRegistry().Get(cfg.Resolver)),
+		// the node address is resolved by the dialer if it prefers an address family.
+		chain.ResoloverNodeOption(xnet.FamilyResolver(registry.ResolverRegistry().Get(cfg.Resolver), xnet.ParseIPFamily(dmd))),
 		chain.HostMapperNodeOption(registry.HostsRegistry().Get(cfg.Hosts)),
 		chain.MetadataNodeOption(nm),
 		chain.HostNodeOption(host),
@@ -214,3 +240,38 @@ func ParseNode(hop string, cfg *config.NodeConfig, log logger.Logger) (*chain.No
 	}
 	return chain.NewNode(cfg.Name, cfg.Addr, opts...), nil
 }
+
+// applyTemplate returns a copy of the node config completed with the dialer and connector of the template,
+// the settings of the node itself take precedence over the template.
+func applyTemplate(cfg *config.NodeConfig, templates []*config.TemplateConfig, log logger.Logger) *config.NodeConfig {
+	var tpl *config.TemplateConfig
+	for _, v := range templates {
+		if v != nil && v.Name == cfg.Template {
+			tpl = v
+			break
+		}
+	}
+	if tpl == nil {
+		log.Warnf("template %s not found", cfg.Template)
+		return cfg
+	}
+
+	c := *cfg
+	if c.Connector == nil && tpl.Connector != nil {
+		connector := *tpl.Connector
+		if connector.TLS != nil {
+			tlsCfg := *connector.TLS
+			connector.TLS = &tlsCfg
+		}
+		c.Connector = &connector
+	}
+	if c.Dialer == nil && tpl.Dialer != nil {
+		dialer := *tpl.Dialer
+		if dialer.TLS != nil {
+			tlsCfg := *dialer.TLS
+			dialer.TLS = &tlsCfg
+		}
+		c.Dialer = &dialer
+	}
+	return &c
+}
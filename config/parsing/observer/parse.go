@@ -1,26 +1,39 @@
 package observer
 
 import (
-	"crypto/tls"
 	"strings"
 
+	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/observer"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	"github.com/go-gost/x/internal/plugin"
+	"github.com/go-gost/x/observer/history"
 	observer_plugin "github.com/go-gost/x/observer/plugin"
 )
 
 func ParseObserver(cfg *config.ObserverConfig) observer.Observer {
-	if cfg == nil || cfg.Plugin == nil {
+	if cfg == nil {
 		return nil
 	}
 
-	var tlsCfg *tls.Config
-	if cfg.Plugin.TLS != nil {
-		tlsCfg = &tls.Config{
-			ServerName:         cfg.Plugin.TLS.ServerName,
-			InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
-		}
+	obs := parsePlugin(cfg)
+	if cfg.History != nil {
+		history.SetRetention(cfg.History.Retention, cfg.History.HourlyRetention)
+		return history.NewObserver(obs)
+	}
+	return obs
+}
+
+func parsePlugin(cfg *config.ObserverConfig) observer.Observer {
+	if cfg.Plugin == nil {
+		return nil
+	}
+
+	tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+	if err != nil {
+		logger.Default().Errorf("observer %s: %v", cfg.Name, err)
+		return nil
 	}
 	switch strings.ToLower(cfg.Plugin.Type) {
 	case "http":
@@ -33,6 +46,7 @@ func ParseObserver(cfg *config.ObserverConfig) observer.Observer {
 		return observer_plugin.NewGRPCPlugin(
 			cfg.Name, cfg.Plugin.Addr,
 			plugin.TokenOption(cfg.Plugin.Token),
+			plugin.RetriesOption(cfg.Plugin.Retries),
 			plugin.TLSConfigOption(tlsCfg),
 		)
 	}
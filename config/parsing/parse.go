@@ -1,5 +1,12 @@
 package parsing
 
+import (
+	"github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/config"
+	mdx "github.com/go-gost/x/metadata"
+)
+
 const (
 	MDKeyProxyProtocol = "proxyProtocol"
 	MDKeyInterface     = "interface"
@@ -11,8 +18,74 @@ const (
 	MDKeyPostDown      = "postDown"
 	MDKeyIgnoreChain   = "ignoreChain"
 	MDKeyEnableStats   = "enableStats"
+	MDKeyDrainTimeout  = "drainTimeout"
+
+	MDKeyMaxConns             = "maxConns"
+	MDKeyMaxConnsQueue        = "maxConns.queue"
+	MDKeyMaxConnsQueueTimeout = "maxConns.queueTimeout"
+	MDKeyMaxConnsOverflow     = "maxConns.overflow"
+
+	MDKeyResources              = "resources"
+	MDKeyResourcesMaxGoroutines = "resources.maxGoroutines"
+	MDKeyResourcesMaxFDs        = "resources.maxFDs"
+	MDKeyResourcesMaxMemory     = "resources.maxMemory"
+	MDKeyResourcesMaxBandwidth  = "resources.maxBandwidth"
+
+	// MDKeyClassify enables the protocol classification of the relayed flows.
+	MDKeyClassify = "classify"
+
+	// the alias of the handler retries, the router picks an alternate node or chain for each retry.
+	MDKeyDialRetries = "dialRetries"
+	// MDKeyDialTimeout is the timeout of each dial attempt through the chain,
+	// the failover gives up after (retries+1)*dialTimeout at most.
+	MDKeyDialTimeout = "dialTimeout"
+
+	MDKeyPoolSize        = "pool.size"
+	MDKeyPoolIdleTimeout = "pool.idleTimeout"
+
+	MDKeySD              = "sd"
+	MDKeySDService       = "sd.service"
+	MDKeySDAddr          = "sd.address"
+	MDKeySDRenewInterval = "sd.renewInterval"
+
+	MDKeyNATSTUN        = "nat.stun"
+	MDKeyNATMapping     = "nat.mapping"
+	MDKeyNATGateway     = "nat.gateway"
+	MDKeyNATLifetime    = "nat.lifetime"
+	MDKeyNATIngress     = "nat.ingress"
+	MDKeyNATIngressHost = "nat.ingress.host"
 
 	MDKeyRecorderDirection       = "direction"
 	MDKeyRecorderTimestampFormat = "timeStampFormat"
 	MDKeyRecorderHexdump         = "hexdump"
 )
+
+const (
+	// MDKeyStrictMetadata overrides the strict metadata parsing of the config for the service or node.
+	MDKeyStrictMetadata = "strictMetadata"
+)
+
+// IsStrictMetadata reports whether the strict metadata parsing is enabled for the service or node of the metadata md.
+func IsStrictMetadata(md metadata.Metadata) bool {
+	if md != nil && md.IsExists(MDKeyStrictMetadata) {
+		return mdutil.GetBool(md, MDKeyStrictMetadata)
+	}
+	return config.Global().StrictMetadata
+}
+
+// NewMetadata creates the metadata of the listener, handler, dialer or connector,
+// the keys and the type coercions are tracked in the strict mode and checked by CheckMetadata.
+func NewMetadata(m map[string]any, strict bool) metadata.Metadata {
+	if strict {
+		return mdx.NewStrictMetadata(m)
+	}
+	return mdx.NewMetadata(m)
+}
+
+// CheckMetadata returns the error of the unknown keys and type mismatches of the strict metadata.
+func CheckMetadata(md metadata.Metadata) error {
+	if v, ok := md.(*mdx.StrictMetadata); ok {
+		return v.Err()
+	}
+	return nil
+}
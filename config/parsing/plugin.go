@@ -0,0 +1,17 @@
+package parsing
+
+import (
+	"crypto/tls"
+
+	"github.com/go-gost/x/config"
+	tls_util "github.com/go-gost/x/internal/util/tls"
+)
+
+// ParsePluginTLSConfig creates the TLS config of a plugin client,
+// the client certificate is loaded for mutual TLS if the cert and key files are set.
+func ParsePluginTLSConfig(cfg *config.TLSConfig) (*tls.Config, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	return tls_util.LoadClientConfig(cfg)
+}
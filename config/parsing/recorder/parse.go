@@ -1,28 +1,49 @@
 package recorder
 
 import (
-	"crypto/tls"
 	"strings"
 
+	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/recorder"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	"github.com/go-gost/x/internal/plugin"
 	xrecorder "github.com/go-gost/x/recorder"
 	recorder_plugin "github.com/go-gost/x/recorder/plugin"
 )
 
-func ParseRecorder(cfg *config.RecorderConfig) (r recorder.Recorder) {
+func ParseRecorder(cfg *config.RecorderConfig) recorder.Recorder {
 	if cfg == nil {
 		return nil
 	}
 
+	r := parseRecorder(cfg)
+	if r == nil || cfg.Redact == nil {
+		return r
+	}
+
+	rr, err := xrecorder.RedactRecorder(r,
+		xrecorder.HeadersRedactRecorderOption(cfg.Redact.Headers...),
+		xrecorder.CookiesRedactRecorderOption(cfg.Redact.Cookies),
+		xrecorder.QueriesRedactRecorderOption(cfg.Redact.Query...),
+		xrecorder.PatternsRedactRecorderOption(cfg.Redact.Patterns...),
+		xrecorder.ReplacementRedactRecorderOption(cfg.Redact.Replacement),
+	)
+	if err != nil {
+		// the records are not sent rather than leaking the sensitive data.
+		logger.Default().Errorf("recorder %s: %v", cfg.Name, err)
+		return nil
+	}
+	return rr
+}
+
+func parseRecorder(cfg *config.RecorderConfig) (r recorder.Recorder) {
+
 	if cfg.Plugin != nil {
-		var tlsCfg *tls.Config
-		if cfg.Plugin.TLS != nil {
-			tlsCfg = &tls.Config{
-				ServerName:         cfg.Plugin.TLS.ServerName,
-				InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
-			}
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+		if err != nil {
+			logger.Default().Errorf("recorder %s: %v", cfg.Name, err)
+			return nil
 		}
 		switch strings.ToLower(cfg.Plugin.Type) {
 		case "http":
@@ -35,6 +56,7 @@ func ParseRecorder(cfg *config.RecorderConfig) (r recorder.Recorder) {
 			return recorder_plugin.NewGRPCPlugin(
 				cfg.Name, cfg.Plugin.Addr,
 				plugin.TokenOption(cfg.Plugin.Token),
+				plugin.RetriesOption(cfg.Plugin.Retries),
 				plugin.TLSConfigOption(tlsCfg),
 			)
 		}
@@ -79,5 +101,27 @@ func ParseRecorder(cfg *config.RecorderConfig) (r recorder.Recorder) {
 		}
 	}
 
+	if cfg.S3 != nil && cfg.S3.Bucket != "" {
+		r, err := xrecorder.S3Recorder(cfg.S3.Endpoint, cfg.S3.Bucket,
+			xrecorder.RegionS3RecorderOption(cfg.S3.Region),
+			xrecorder.CredentialsS3RecorderOption(cfg.S3.AccessKey, cfg.S3.SecretKey),
+			xrecorder.PrefixS3RecorderOption(cfg.S3.Prefix),
+			xrecorder.PathStyleS3RecorderOption(cfg.S3.PathStyle),
+			xrecorder.BatchSizeS3RecorderOption(cfg.S3.BatchSize),
+			xrecorder.FlushIntervalS3RecorderOption(cfg.S3.FlushInterval),
+			xrecorder.SpoolDirS3RecorderOption(cfg.S3.SpoolDir),
+			xrecorder.TimeoutS3RecorderOption(cfg.S3.Timeout),
+			xrecorder.LoggerS3RecorderOption(logger.Default().WithFields(map[string]any{
+				"kind":     "recorder",
+				"recorder": cfg.Name,
+			})),
+		)
+		if err != nil {
+			logger.Default().Errorf("recorder %s: %v", cfg.Name, err)
+			return nil
+		}
+		return r
+	}
+
 	return
 }
@@ -1,13 +1,14 @@
 package resolver
 
 import (
-	"crypto/tls"
+	"fmt"
 	"net"
 	"strings"
 
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/resolver"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	"github.com/go-gost/x/internal/plugin"
 	"github.com/go-gost/x/registry"
 	xresolver "github.com/go-gost/x/resolver"
@@ -20,12 +21,9 @@ func ParseResolver(cfg *config.ResolverConfig) (resolver.Resolver, error) {
 	}
 
 	if cfg.Plugin != nil {
-		var tlsCfg *tls.Config
-		if cfg.Plugin.TLS != nil {
-			tlsCfg = &tls.Config{
-				ServerName:         cfg.Plugin.TLS.ServerName,
-				InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
-			}
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+		if err != nil {
+			return nil, err
 		}
 		switch strings.ToLower(cfg.Plugin.Type) {
 		case "http":
@@ -38,6 +36,7 @@ func ParseResolver(cfg *config.ResolverConfig) (resolver.Resolver, error) {
 			return resolver_plugin.NewGRPCPlugin(
 				cfg.Name, cfg.Plugin.Addr,
 				plugin.TokenOption(cfg.Plugin.Token),
+				plugin.RetriesOption(cfg.Plugin.Retries),
 				plugin.TLSConfigOption(tlsCfg),
 			)
 		}
@@ -45,9 +44,13 @@ func ParseResolver(cfg *config.ResolverConfig) (resolver.Resolver, error) {
 
 	var nameservers []xresolver.NameServer
 	for _, server := range cfg.Nameservers {
+		chainName := server.Chain
+		if chainName == "" {
+			chainName = cfg.Chain
+		}
 		nameservers = append(nameservers, xresolver.NameServer{
 			Addr:     server.Addr,
-			Chain:    registry.ChainRegistry().Get(server.Chain),
+			Chain:    registry.ChainRegistry().Get(chainName),
 			TTL:      server.TTL,
 			Timeout:  server.Timeout,
 			ClientIP: net.ParseIP(server.ClientIP),
@@ -58,8 +61,33 @@ func ParseResolver(cfg *config.ResolverConfig) (resolver.Resolver, error) {
 		})
 	}
 
+	var rewrites []*xresolver.RewriteRule
+	for _, rewrite := range cfg.Rewrites {
+		if rewrite == nil {
+			continue
+		}
+		rule := &xresolver.RewriteRule{
+			Hosts:   rewrite.Hosts,
+			Block:   rewrite.Block,
+			Flatten: rewrite.Flatten,
+		}
+		for _, remap := range rewrite.Remap {
+			if remap == nil {
+				continue
+			}
+			m, err := xresolver.ParseIPRemap(remap.From, remap.To)
+			if err != nil {
+				return nil, fmt.Errorf("resolver %s: %w", cfg.Name, err)
+			}
+			rule.Remaps = append(rule.Remaps, m)
+		}
+		rewrites = append(rewrites, rule)
+	}
+
 	return xresolver.NewResolver(
 		nameservers,
+		xresolver.HostsOption(registry.HostsRegistry().Get(cfg.Hosts)),
+		xresolver.RewritesOption(rewrites...),
 		xresolver.LoggerOption(
 			logger.Default().WithFields(map[string]any{
 				"kind":     "resolver",
@@ -1,26 +1,106 @@
 package sd
 
 import (
-	"crypto/tls"
 	"strings"
 
+	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/sd"
 	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
 	"github.com/go-gost/x/internal/plugin"
+	"github.com/go-gost/x/sd/consul"
+	"github.com/go-gost/x/sd/etcd"
+	"github.com/go-gost/x/sd/kubernetes"
 	sd_plugin "github.com/go-gost/x/sd/plugin"
+	"github.com/go-gost/x/sd/redis"
 )
 
 func ParseSD(cfg *config.SDConfig) sd.SD {
-	if cfg == nil || cfg.Plugin == nil {
+	if cfg == nil {
 		return nil
 	}
 
-	var tlsCfg *tls.Config
-	if cfg.Plugin.TLS != nil {
-		tlsCfg = &tls.Config{
-			ServerName:         cfg.Plugin.TLS.ServerName,
-			InsecureSkipVerify: !cfg.Plugin.TLS.Secure,
+	if cfg.Consul != nil && cfg.Consul.Addr != "" {
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Consul.TLS)
+		if err != nil {
+			logger.Default().Errorf("sd %s: %v", cfg.Name, err)
+			return nil
 		}
+		v, err := consul.NewSD(cfg.Name, cfg.Consul.Addr,
+			consul.TokenOption(cfg.Consul.Token),
+			consul.TTLOption(cfg.Consul.TTL),
+			consul.TimeoutOption(cfg.Consul.Timeout),
+			consul.TLSConfigOption(tlsCfg),
+		)
+		if err != nil {
+			logger.Default().Errorf("sd %s: %v", cfg.Name, err)
+			return nil
+		}
+		return v
+	}
+
+	if cfg.Etcd != nil && cfg.Etcd.Addr != "" {
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Etcd.TLS)
+		if err != nil {
+			logger.Default().Errorf("sd %s: %v", cfg.Name, err)
+			return nil
+		}
+		v, err := etcd.NewSD(cfg.Name, cfg.Etcd.Addr,
+			etcd.AuthOption(cfg.Etcd.Username, cfg.Etcd.Password),
+			etcd.PrefixOption(cfg.Etcd.Prefix),
+			etcd.TTLOption(cfg.Etcd.TTL),
+			etcd.TimeoutOption(cfg.Etcd.Timeout),
+			etcd.TLSConfigOption(tlsCfg),
+		)
+		if err != nil {
+			logger.Default().Errorf("sd %s: %v", cfg.Name, err)
+			return nil
+		}
+		return v
+	}
+
+	if cfg.Kubernetes != nil {
+		tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Kubernetes.TLS)
+		if err != nil {
+			logger.Default().Errorf("sd %s: %v", cfg.Name, err)
+			return nil
+		}
+		v, err := kubernetes.NewSD(cfg.Name, cfg.Kubernetes.Addr,
+			kubernetes.NamespaceOption(cfg.Kubernetes.Namespace),
+			kubernetes.PortOption(cfg.Kubernetes.Port),
+			kubernetes.TokenOption(cfg.Kubernetes.Token),
+			kubernetes.TimeoutOption(cfg.Kubernetes.Timeout),
+			kubernetes.TLSConfigOption(tlsCfg),
+		)
+		if err != nil {
+			logger.Default().Errorf("sd %s: %v", cfg.Name, err)
+			return nil
+		}
+		return v
+	}
+
+	if cfg.Redis != nil && cfg.Redis.Addr != "" {
+		v, err := redis.NewSD(cfg.Name, cfg.Redis.Addr,
+			redis.DBOption(cfg.Redis.DB),
+			redis.PasswordOption(cfg.Redis.Password),
+			redis.PrefixOption(cfg.Redis.Prefix),
+			redis.TTLOption(cfg.Redis.TTL),
+		)
+		if err != nil {
+			logger.Default().Errorf("sd %s: %v", cfg.Name, err)
+			return nil
+		}
+		return v
+	}
+
+	if cfg.Plugin == nil {
+		return nil
+	}
+
+	tlsCfg, err := parsing.ParsePluginTLSConfig(cfg.Plugin.TLS)
+	if err != nil {
+		logger.Default().Errorf("sd %s: %v", cfg.Name, err)
+		return nil
 	}
 	switch strings.ToLower(cfg.Plugin.Type) {
 	case "http":
@@ -33,6 +113,7 @@ func ParseSD(cfg *config.SDConfig) sd.SD {
 		return sd_plugin.NewGRPCPlugin(
 			cfg.Name, cfg.Plugin.Addr,
 			plugin.TokenOption(cfg.Plugin.Token),
+			plugin.RetriesOption(cfg.Plugin.Retries),
 			plugin.TLSConfigOption(tlsCfg),
 		)
 	}
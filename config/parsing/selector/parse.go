@@ -25,9 +25,17 @@ func ParseChainSelector(cfg *config.SelectorConfig) selector.Selector[chain.Chai
 	default:
 		strategy = xs.RoundRobinStrategy[chain.Chainer]()
 	}
+	if cfg.Affinity {
+		strategy = xs.AffinityStrategy(strategy, cfg.AffinityTTL)
+	}
+	failFilter := xs.FailFilter[chain.Chainer](cfg.MaxFails, cfg.FailTimeout)
+	if cb := cfg.CircuitBreaker; cb != nil {
+		failFilter = xs.CircuitBreakerFilter[chain.Chainer](cb.Threshold, cb.OpenTimeout, cb.HalfOpenProbes)
+	}
+
 	return xs.NewSelector(
 		strategy,
-		xs.FailFilter[chain.Chainer](cfg.MaxFails, cfg.FailTimeout),
+		failFilter,
 		xs.BackupFilter[chain.Chainer](),
 	)
 }
@@ -47,13 +55,23 @@ func ParseNodeSelector(cfg *config.SelectorConfig) selector.Selector[*chain.Node
 		strategy = xs.FIFOStrategy[*chain.Node]()
 	case "hash":
 		strategy = xs.HashStrategy[*chain.Node]()
+	case "bandwidth", "bw":
+		strategy = xs.BandwidthStrategy[*chain.Node]()
 	default:
 		strategy = xs.RoundRobinStrategy[*chain.Node]()
 	}
+	if cfg.Affinity {
+		strategy = xs.AffinityStrategy(strategy, cfg.AffinityTTL)
+	}
+
+	failFilter := xs.FailFilter[*chain.Node](cfg.MaxFails, cfg.FailTimeout)
+	if cb := cfg.CircuitBreaker; cb != nil {
+		failFilter = xs.CircuitBreakerFilter[*chain.Node](cb.Threshold, cb.OpenTimeout, cb.HalfOpenProbes)
+	}
 
 	return xs.NewSelector(
 		strategy,
-		xs.FailFilter[*chain.Node](cfg.MaxFails, cfg.FailTimeout),
+		failFilter,
 		xs.BackupFilter[*chain.Node](),
 	)
 }
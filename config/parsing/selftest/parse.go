@@ -0,0 +1,19 @@
+package selftest
+
+import (
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/selftest"
+)
+
+func ParseSelfTest(cfg *config.SelfTestConfig) {
+	if cfg == nil {
+		return
+	}
+
+	t := selftest.NewTester(
+		selftest.TimeoutOption(cfg.Timeout),
+		selftest.TargetOption(cfg.Target),
+	)
+	selftest.SetDefaultTester(t)
+	t.Start(cfg.Interval)
+}
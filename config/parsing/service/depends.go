@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/registry"
+	xservice "github.com/go-gost/x/service"
+)
+
+// dependency is the service or chain with the name, the service takes precedence if both exist.
+type dependency struct {
+	name string
+}
+
+func (d *dependency) Name() string {
+	return d.name
+}
+
+// Ready reports whether the service is serving, or the chain has an available route.
+func (d *dependency) Ready() bool {
+	if registry.ServiceRegistry().IsRegistered(d.name) {
+		svc := registry.ServiceRegistry().Get(d.name)
+		if ss, ok := svc.(interface{ Status() *xservice.Status }); ok {
+			status := ss.Status()
+			return status != nil && status.State() == xservice.StateReady
+		}
+		return svc != nil
+	}
+
+	if registry.ChainRegistry().IsRegistered(d.name) {
+		chain := registry.ChainRegistry().Get(d.name)
+		if chain == nil {
+			return false
+		}
+		route := chain.Route(context.Background(), "tcp", "")
+		return route != nil && len(route.Nodes()) > 0
+	}
+
+	return false
+}
+
+func parseDependencies(cfg *config.ServiceConfig) []xservice.Dependency {
+	var deps []xservice.Dependency
+	for _, name := range cfg.DependsOn {
+		if name != "" && name != cfg.Name {
+			deps = append(deps, &dependency{name: name})
+		}
+	}
+	return deps
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"fmt"
+	"net/netip"
+	"strings"
+	"time"
 
 	"github.com/go-gost/core/admission"
 	"github.com/go-gost/core/auth"
@@ -15,6 +18,7 @@ import (
 	"github.com/go-gost/core/recorder"
 	"github.com/go-gost/core/selector"
 	"github.com/go-gost/core/service"
+	xbypass "github.com/go-gost/x/bypass"
 	xchain "github.com/go-gost/x/chain"
 	"github.com/go-gost/x/config"
 	"github.com/go-gost/x/config/parsing"
@@ -24,15 +28,36 @@ import (
 	hop_parser "github.com/go-gost/x/config/parsing/hop"
 	logger_parser "github.com/go-gost/x/config/parsing/logger"
 	selector_parser "github.com/go-gost/x/config/parsing/selector"
+	"github.com/go-gost/x/handler/middleware"
+	"github.com/go-gost/x/hook"
 	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/nat"
+	"github.com/go-gost/x/internal/util/schedule"
 	tls_util "github.com/go-gost/x/internal/util/tls"
 	"github.com/go-gost/x/metadata"
 	"github.com/go-gost/x/registry"
+	xs "github.com/go-gost/x/selector"
 	xservice "github.com/go-gost/x/service"
 	"github.com/go-gost/x/stats"
 )
 
 func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
+	if err := applyPreset(cfg); err != nil {
+		return nil, err
+	}
+
+	addrs := xnet.AddrPortRange(cfg.Addr).Addrs()
+	if len(addrs) > 1 {
+		return parseMultiPortService(cfg, addrs)
+	}
+	if len(addrs) == 1 && hasPortTemplate(cfg) {
+		cfg = portServiceConfig(cfg, addrs[0])
+	}
+	return parseService(cfg)
+}
+
+func parseService(cfg *config.ServiceConfig) (service.Service, error) {
 	if cfg.Listener == nil {
 		cfg.Listener = &config.ListenerConfig{
 			Type: "tcp",
@@ -43,6 +68,11 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 			Type: "auto",
 		}
 	}
+	for _, group := range []*config.ChainGroupConfig{cfg.Listener.ChainGroup, cfg.Handler.ChainGroup} {
+		if err := checkChainGroup(group); err != nil {
+			return nil, fmt.Errorf("service %s: %w", cfg.Name, err)
+		}
+	}
 
 	log := logger.Default()
 	if loggers := logger_parser.List(cfg.Logger, cfg.Loggers...); len(loggers) > 0 {
@@ -93,11 +123,27 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		}
 	}
 
+	strict := parsing.IsStrictMetadata(metadata.NewMetadata(cfg.Metadata))
+
 	var ppv int
 	ifce := cfg.Interface
 	var preUp, preDown, postUp, postDown []string
 	var ignoreChain bool
+	var dialRetries int
+	var dialTimeout time.Duration
+	var sdName, sdService, sdAddr string
+	var sdRenewInterval time.Duration
 	var pStats *stats.Stats
+	var tcpSockOpts *xnet.SockOpts
+	var drainTimeout time.Duration
+	var maxConns, maxConnsQueue int
+	var maxConnsQueueTimeout time.Duration
+	var maxConnsOverflow string
+	var resources bool
+	var classify bool
+	var resourceCaps xservice.Resources
+	var natTraversal *nat.Traversal
+	var natIngress, natIngressHost string
 	if cfg.Metadata != nil {
 		md := metadata.NewMetadata(cfg.Metadata)
 		ppv = mdutil.GetInt(md, parsing.MDKeyProxyProtocol)
@@ -114,10 +160,69 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		postUp = mdutil.GetStrings(md, parsing.MDKeyPostUp)
 		postDown = mdutil.GetStrings(md, parsing.MDKeyPostDown)
 		ignoreChain = mdutil.GetBool(md, parsing.MDKeyIgnoreChain)
+		dialRetries = mdutil.GetInt(md, parsing.MDKeyDialRetries)
+		dialTimeout = mdutil.GetDuration(md, parsing.MDKeyDialTimeout)
+		sdName = mdutil.GetString(md, parsing.MDKeySD)
+		sdService = mdutil.GetString(md, parsing.MDKeySDService)
+		sdAddr = mdutil.GetString(md, parsing.MDKeySDAddr)
+		sdRenewInterval = mdutil.GetDuration(md, parsing.MDKeySDRenewInterval)
 
 		if mdutil.GetBool(md, parsing.MDKeyEnableStats) {
 			pStats = &stats.Stats{}
 		}
+		tcpSockOpts = xnet.ParseSockOpts(md)
+		drainTimeout = mdutil.GetDuration(md, parsing.MDKeyDrainTimeout)
+		maxConns = mdutil.GetInt(md, parsing.MDKeyMaxConns)
+		maxConnsQueue = mdutil.GetInt(md, parsing.MDKeyMaxConnsQueue)
+		maxConnsQueueTimeout = mdutil.GetDuration(md, parsing.MDKeyMaxConnsQueueTimeout)
+		maxConnsOverflow = mdutil.GetString(md, parsing.MDKeyMaxConnsOverflow)
+		resources = mdutil.GetBool(md, parsing.MDKeyResources)
+		resourceCaps = xservice.Resources{
+			Goroutines: int64(mdutil.GetInt(md, parsing.MDKeyResourcesMaxGoroutines)),
+			FDs:        int64(mdutil.GetInt(md, parsing.MDKeyResourcesMaxFDs)),
+			Memory:     int64(mdutil.GetInt(md, parsing.MDKeyResourcesMaxMemory)),
+			Bandwidth:  int64(mdutil.GetInt(md, parsing.MDKeyResourcesMaxBandwidth)),
+		}
+		if resources || resourceCaps != (xservice.Resources{}) {
+			resources = true
+			// the bandwidth is accounted by the stats.
+			if pStats == nil {
+				pStats = &stats.Stats{}
+			}
+		}
+
+		classify = mdutil.GetBool(md, parsing.MDKeyClassify)
+
+		stunServers := mdutil.GetStrings(md, parsing.MDKeyNATSTUN)
+		if len(stunServers) == 0 {
+			if v := mdutil.GetString(md, parsing.MDKeyNATSTUN); v != "" {
+				stunServers = strings.Split(v, ",")
+			}
+		}
+		natMethods := mdutil.GetStrings(md, parsing.MDKeyNATMapping)
+		if len(natMethods) == 0 {
+			if v := mdutil.GetString(md, parsing.MDKeyNATMapping); v != "" {
+				natMethods = strings.Split(v, ",")
+			}
+		}
+		if len(stunServers) > 0 || len(natMethods) > 0 {
+			for i := range stunServers {
+				stunServers[i] = strings.TrimSpace(stunServers[i])
+			}
+			for i := range natMethods {
+				natMethods[i] = strings.ToLower(strings.TrimSpace(natMethods[i]))
+			}
+			gateway, _ := netip.ParseAddr(mdutil.GetString(md, parsing.MDKeyNATGateway))
+			natTraversal = nat.NewTraversal(
+				nat.STUNServersOption(stunServers),
+				nat.MethodsOption(natMethods),
+				nat.GatewayOption(gateway),
+				nat.LifetimeOption(mdutil.GetDuration(md, parsing.MDKeyNATLifetime)),
+				nat.LoggerOption(serviceLogger),
+			)
+			natIngress = mdutil.GetString(md, parsing.MDKeyNATIngress)
+			natIngressHost = mdutil.GetString(md, parsing.MDKeyNATIngressHost)
+		}
 	}
 
 	listenOpts := []listener.Option{
@@ -150,10 +255,39 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		cfg.Listener.Metadata = make(map[string]any)
 	}
 	listenerLogger.Debugf("metadata: %v", cfg.Listener.Metadata)
-	if err := ln.Init(metadata.NewMetadata(cfg.Listener.Metadata)); err != nil {
+	lmd := parsing.NewMetadata(cfg.Listener.Metadata, strict)
+	if err := ln.Init(lmd); err != nil {
 		listenerLogger.Error("init: ", err)
 		return nil, err
 	}
+	muxCfg, err := mux.ParseConfig(lmd)
+	if err != nil {
+		listenerLogger.Error(err)
+		ln.Close()
+		return nil, err
+	}
+	ln = mux.WrapListener(ln, muxCfg, listenerLogger)
+	if err := parsing.CheckMetadata(lmd); err != nil {
+		listenerLogger.Error(err)
+		return nil, fmt.Errorf("service %s: listener %s: %w", cfg.Name, cfg.Listener.Type, err)
+	}
+
+	var sched *schedule.Schedule
+	if cfg.Schedule != nil {
+		if sched, err = schedule.Parse(cfg.Schedule.Windows, cfg.Schedule.Timezone); err != nil {
+			serviceLogger.Error(err)
+			ln.Close()
+			return nil, err
+		}
+	}
+	// the listener is created again when the next window of the schedule begins.
+	newListener := func() (listener.Listener, error) {
+		ln := registry.ListenerRegistry().Get(cfg.Listener.Type)(listenOpts...)
+		if err := ln.Init(lmd); err != nil {
+			return nil, err
+		}
+		return mux.WrapListener(ln, muxCfg, listenerLogger), nil
+	}
 
 	handlerLogger := serviceLogger.WithFields(map[string]any{
 		"kind": "handler",
@@ -181,7 +315,7 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 
 	auther = nil
 	if len(authers) > 0 {
-		auther = auth.AuthenticatorGroup(authers...)
+		auther = hook.WrapAuthenticator(auth.AuthenticatorGroup(authers...))
 	}
 
 	var recorders []recorder.RecorderObject
@@ -198,9 +332,13 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		})
 	}
 
+	retries := cfg.Handler.Retries
+	if retries <= 0 {
+		retries = dialRetries
+	}
 	routerOpts := []chain.RouterOption{
-		chain.RetriesRouterOption(cfg.Handler.Retries),
-		// chain.TimeoutRouterOption(10*time.Second),
+		chain.RetriesRouterOption(retries),
+		chain.TimeoutRouterOption(dialTimeout),
 		chain.InterfaceRouterOption(ifce),
 		chain.SockOptsRouterOption(sockOpts),
 		chain.ResolverRouterOption(registry.ResolverRegistry().Get(cfg.Resolver)),
@@ -208,11 +346,42 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		chain.RecordersRouterOption(recorders...),
 		chain.LoggerRouterOption(handlerLogger),
 	}
+	var chainer chain.Chainer
+	// the chain can be swapped by the API without restarting the service.
+	var swapper xservice.ChainSwapper
 	if !ignoreChain {
-		routerOpts = append(routerOpts,
-			chain.ChainRouterOption(chainGroup(cfg.Handler.Chain, cfg.Handler.ChainGroup)),
+		chainer = chainGroup(cfg.Handler.Chain, cfg.Handler.ChainGroup)
+		if chainer != nil {
+			sc := xchain.SwapChain(chainer, handlerLogger)
+			swapper, chainer = sc, sc
+		}
+	}
+	chainer = xchain.EgressChain(chainer, parseEgress(cfg.Egress, handlerLogger))
+	chainer = xchain.SMTPChain(chainer, parseSMTP(cfg.Name, cfg.SMTP, handlerLogger))
+	// the socket options also apply to the connections dialed by the handler.
+	chainer = xchain.SockOptsChain(chainer, tcpSockOpts)
+	// the dial hooks apply to the services created after the hooks are registered.
+	if hook.Enabled() {
+		chainer = hook.WrapChainer(chainer)
+	}
+	if resources {
+		chainer = xservice.ResourceChainer(chainer)
+	}
+	lock := parseLock(cfg.Handler.Lock, handlerLogger)
+	if lock != nil {
+		chainer = xchain.LockChain(chainer, lock, cfg.Handler.Lock.Strict, handlerLogger)
+	}
+	if classify {
+		chainer = xchain.ClassifyChain(chainer,
+			xchain.ServiceClassifyOption(cfg.Name),
+			xchain.BypassClassifyOption(bypass.BypassGroup(bypass_parser.List(cfg.Bypass, cfg.Bypasses...)...)),
+			xchain.RecordersClassifyOption(recorders...),
+			xchain.LoggerClassifyOption(handlerLogger),
 		)
 	}
+	if chainer != nil {
+		routerOpts = append(routerOpts, chain.ChainRouterOption(chainer))
+	}
 	router := chain.NewRouter(routerOpts...)
 
 	var h handler.Handler
@@ -221,7 +390,7 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 			handler.RouterOption(router),
 			handler.AutherOption(auther),
 			handler.AuthOption(auth_parser.Info(cfg.Handler.Auth)),
-			handler.BypassOption(bypass.BypassGroup(bypass_parser.List(cfg.Bypass, cfg.Bypasses...)...)),
+			handler.BypassOption(bypass.BypassGroup(append(bypass_parser.List(cfg.Bypass, cfg.Bypasses...), parsePorts(cfg.Handler.Ports), lock)...)),
 			handler.TLSConfigOption(tlsConfig),
 			handler.RateLimiterOption(registry.RateLimiterRegistry().Get(cfg.RLimiter)),
 			handler.TrafficLimiterOption(registry.TrafficLimiterRegistry().Get(cfg.Handler.Limiter)),
@@ -245,10 +414,30 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		cfg.Handler.Metadata = make(map[string]any)
 	}
 	handlerLogger.Debugf("metadata: %v", cfg.Handler.Metadata)
-	if err := h.Init(metadata.NewMetadata(cfg.Handler.Metadata)); err != nil {
+	hmd := parsing.NewMetadata(cfg.Handler.Metadata, strict)
+	if err := h.Init(hmd); err != nil {
 		handlerLogger.Error("init: ", err)
 		return nil, err
 	}
+	if err := parsing.CheckMetadata(hmd); err != nil {
+		handlerLogger.Error(err)
+		return nil, fmt.Errorf("service %s: handler %s: %w", cfg.Name, cfg.Handler.Type, err)
+	}
+
+	if len(cfg.Handler.Middlewares) > 0 {
+		mws, err := parseMiddlewares(cfg.Name, cfg.Handler.Middlewares, handlerLogger)
+		if err != nil {
+			return nil, err
+		}
+		h = middleware.Chain(h, mws...)
+	}
+
+	var startupTimeout time.Duration
+	var startupRetries int
+	if cfg.Startup != nil {
+		startupTimeout = cfg.Startup.Timeout
+		startupRetries = cfg.Startup.Retries
+	}
 
 	s := xservice.NewService(cfg.Name, ln, h,
 		xservice.AdmissionOption(admission.AdmissionGroup(admissions...)),
@@ -259,6 +448,15 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 		xservice.RecordersOption(recorders...),
 		xservice.StatsOption(pStats),
 		xservice.ObserverOption(registry.ObserverRegistry().Get(cfg.Observer)),
+		xservice.SDOption(registry.SDRegistry().Get(sdName), sdService, sdAddr, sdRenewInterval),
+		xservice.SockOptsOption(tcpSockOpts),
+		xservice.DrainTimeoutOption(drainTimeout),
+		xservice.ConnLimitOption(maxConns, maxConnsQueue, maxConnsQueueTimeout, maxConnsOverflow),
+		xservice.NATOption(natTraversal, registry.IngressRegistry().Get(natIngress), natIngressHost),
+		xservice.DependsOnOption(parseDependencies(cfg), startupTimeout, startupRetries),
+		xservice.ResourcesOption(resources, resourceCaps),
+		xservice.ChainSwapperOption(swapper),
+		xservice.ScheduleOption(sched, newListener),
 		xservice.LoggerOption(serviceLogger),
 	)
 
@@ -266,14 +464,43 @@ func ParseService(cfg *config.ServiceConfig) (service.Service, error) {
 	return s, nil
 }
 
+func parseMiddlewares(service string, cfgs []*config.MiddlewareConfig, log logger.Logger) ([]middleware.Middleware, error) {
+	var mws []middleware.Middleware
+	for _, cfg := range cfgs {
+		if cfg == nil {
+			continue
+		}
+		mf := registry.MiddlewareRegistry().Get(cfg.Type)
+		if mf == nil {
+			return nil, fmt.Errorf("unregistered middleware: %s", cfg.Type)
+		}
+		mwLogger := log.WithFields(map[string]any{
+			"middleware": cfg.Type,
+		})
+		mw := mf(
+			middleware.ServiceOption(service),
+			middleware.LoggerOption(mwLogger),
+		)
+		if err := mw.Init(metadata.NewMetadata(cfg.Metadata)); err != nil {
+			mwLogger.Error("init: ", err)
+			return nil, err
+		}
+		mws = append(mws, mw)
+	}
+	return mws, nil
+}
+
 func parseForwarder(cfg *config.ForwarderConfig, log logger.Logger) (hop.Hop, error) {
 	if cfg == nil {
 		return nil, nil
 	}
 
 	hc := config.HopConfig{
-		Name:     cfg.Name,
-		Selector: cfg.Selector,
+		Name:        cfg.Name,
+		Selector:    cfg.Selector,
+		SD:          cfg.SD,
+		HealthCheck: cfg.HealthCheck,
+		Drain:       cfg.Drain,
 	}
 	for _, node := range cfg.Nodes {
 		if node != nil {
@@ -287,28 +514,123 @@ func parseForwarder(cfg *config.ForwarderConfig, log logger.Logger) (hop.Hop, er
 					name = fmt.Sprintf("%s-%d", node.Name, i)
 				}
 				hc.Nodes = append(hc.Nodes, &config.NodeConfig{
-					Name:     name,
-					Addr:     addr,
-					Host:     node.Host,
-					Network:  node.Network,
-					Protocol: node.Protocol,
-					Path:     node.Path,
-					Bypass:   node.Bypass,
-					Bypasses: node.Bypasses,
-					HTTP:     node.HTTP,
-					TLS:      node.TLS,
-					Auth:     node.Auth,
-					Metadata: node.Metadata,
+					Name:      name,
+					Addr:      addr,
+					Host:      node.Host,
+					Network:   node.Network,
+					Protocol:  node.Protocol,
+					Path:      node.Path,
+					Interface: node.Interface,
+					SockOpts:  node.SockOpts,
+					Bypass:    node.Bypass,
+					Bypasses:  node.Bypasses,
+					HTTP:      node.HTTP,
+					TLS:       node.TLS,
+					Auth:      node.Auth,
+					Metadata:  node.Metadata,
 				})
 			}
 		}
 	}
-	if len(hc.Nodes) > 0 {
+	if len(hc.Nodes) > 0 || hc.SD != nil {
 		return hop_parser.ParseHop(&hc, log)
 	}
 	return registry.HopRegistry().Get(hc.Name), nil
 }
 
+func parsePorts(cfg *config.PortsConfig) bypass.Bypass {
+	if cfg == nil {
+		return nil
+	}
+	return xbypass.PortsBypass(cfg.Allow, cfg.Deny)
+}
+
+// parseLock returns the whitelist bypass of the allowed domains of the locked handler.
+func parseLock(cfg *config.LockConfig, log logger.Logger) bypass.Bypass {
+	if cfg == nil {
+		return nil
+	}
+	return xbypass.NewBypass(
+		xbypass.WhitelistOption(true),
+		xbypass.MatchersOption(cfg.Domains),
+		xbypass.LoggerOption(log.WithFields(map[string]any{"kind": "lock"})),
+	)
+}
+
+func parseSMTP(service string, cfg *config.SMTPConfig, log logger.Logger) *xchain.SMTPPolicy {
+	if cfg == nil {
+		return nil
+	}
+
+	var allow []*xchain.Rule
+	for _, rule := range cfg.Allow {
+		if rule == nil {
+			continue
+		}
+		allow = append(allow, xchain.NewRule(
+			xchain.MatchersRuleOption(rule.Matchers...),
+			xchain.UsersRuleOption(rule.Users...),
+		))
+	}
+
+	return xchain.NewSMTPPolicy(
+		xchain.ActionSMTPOption(cfg.Action),
+		xchain.PortsSMTPOption(cfg.Ports...),
+		xchain.AllowSMTPOption(allow...),
+		xchain.RateSMTPOption(cfg.Rate, cfg.Period),
+		xchain.ServiceSMTPOption(service),
+		xchain.LoggerSMTPOption(log.WithFields(map[string]any{"kind": "smtp"})),
+	)
+}
+
+func parseEgress(cfg *config.EgressConfig, log logger.Logger) *xchain.Egress {
+	if cfg == nil {
+		return nil
+	}
+
+	var rules []xchain.EgressRule
+	for _, rule := range cfg.Rules {
+		if rule == nil {
+			continue
+		}
+		rules = append(rules, xchain.EgressRule{
+			Rule: xchain.NewRule(
+				xchain.MatchersRuleOption(rule.Matchers...),
+				xchain.PortsRuleOption(rule.Ports...),
+				xchain.UsersRuleOption(rule.Users...),
+				xchain.BypassRuleOption(registry.BypassRegistry().Get(rule.Bypass)),
+			),
+			IPs: rule.IPs,
+		})
+	}
+
+	return xchain.NewEgress(
+		xchain.IPsEgressOption(cfg.IPs...),
+		xchain.RulesEgressOption(rules...),
+		xchain.StrategyEgressOption(cfg.Strategy),
+		xchain.StickyTTLEgressOption(cfg.StickyTTL),
+		xchain.LoggerEgressOption(log.WithFields(map[string]any{"kind": "egress"})),
+	)
+}
+
+// checkChainGroup rejects the weights of the chain group with a selector not honoring them,
+// only the random strategy selects the chains by the weights.
+func checkChainGroup(group *config.ChainGroupConfig) error {
+	if group == nil || group.Selector == nil {
+		return nil
+	}
+	switch group.Selector.Strategy {
+	case "random", "rand":
+		return nil
+	}
+	for _, w := range group.Weights {
+		if w > 0 {
+			return fmt.Errorf("chain group: the weights require the random strategy of the selector, got %q", group.Selector.Strategy)
+		}
+	}
+	return nil
+}
+
 func chainGroup(name string, group *config.ChainGroupConfig) chain.Chainer {
 	var chains []chain.Chainer
 	var sel selector.Selector[chain.Chainer]
@@ -316,11 +638,19 @@ func chainGroup(name string, group *config.ChainGroupConfig) chain.Chainer {
 	if c := registry.ChainRegistry().Get(name); c != nil {
 		chains = append(chains, c)
 	}
+	weighted := false
 	if group != nil {
-		for _, s := range group.Chains {
-			if c := registry.ChainRegistry().Get(s); c != nil {
-				chains = append(chains, c)
+		for i, s := range group.Chains {
+			c := registry.ChainRegistry().Get(s)
+			if c == nil {
+				continue
+			}
+			// the weights split the traffic across the chains of the group by percentage.
+			if i < len(group.Weights) && group.Weights[i] > 0 {
+				c = xchain.WeightedChain(c, group.Weights[i])
+				weighted = true
 			}
+			chains = append(chains, c)
 		}
 		sel = selector_parser.ParseChainSelector(group.Selector)
 	}
@@ -329,7 +659,15 @@ func chainGroup(name string, group *config.ChainGroupConfig) chain.Chainer {
 	}
 
 	if sel == nil {
-		sel = selector_parser.DefaultChainSelector()
+		if weighted {
+			sel = selector_parser.ParseChainSelector(&config.SelectorConfig{
+				Strategy:    "random",
+				MaxFails:    xs.DefaultMaxFails,
+				FailTimeout: xs.DefaultFailTimeout,
+			})
+		} else {
+			sel = selector_parser.DefaultChainSelector()
+		}
 	}
 
 	return xchain.NewChainGroup(chains...).
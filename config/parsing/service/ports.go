@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	"github.com/go-gost/core/service"
+	"github.com/go-gost/x/config"
+	xservice "github.com/go-gost/x/service"
+)
+
+// portTemplate is the port of the service in the forwarder node address,
+// with an optional offset, e.g. 192.168.1.1:{port}, 192.168.1.1:{port+10000}.
+var portTemplate = regexp.MustCompile(`\{port([+-]\d+)?\}`)
+
+// parseMultiPortService parses the service listening on the port range or list,
+// e.g. :10000-10100 or :8000,8080,9000-9010, each port shares the same configuration.
+func parseMultiPortService(cfg *config.ServiceConfig, addrs []string) (service.Service, error) {
+	var services []service.Service
+	for _, addr := range addrs {
+		s, err := parseService(portServiceConfig(cfg, addr))
+		if err != nil {
+			for _, s := range services {
+				s.Close()
+			}
+			return nil, fmt.Errorf("%s: %w", addr, err)
+		}
+		services = append(services, s)
+	}
+	return xservice.NewGroupService(services...), nil
+}
+
+// portServiceConfig returns the config of the service listening on addr,
+// the port templates in the forwarder nodes are replaced by the port.
+func portServiceConfig(cfg *config.ServiceConfig, addr string) *config.ServiceConfig {
+	c := *cfg
+	c.Addr = addr
+	if cfg.Listener != nil {
+		ln := *cfg.Listener
+		c.Listener = &ln
+	}
+	if cfg.Handler != nil {
+		h := *cfg.Handler
+		c.Handler = &h
+	}
+
+	_, sp, _ := net.SplitHostPort(addr)
+	port, err := strconv.Atoi(sp)
+	if err != nil || cfg.Forwarder == nil {
+		return &c
+	}
+
+	fwd := *cfg.Forwarder
+	fwd.Nodes = nil
+	for _, node := range cfg.Forwarder.Nodes {
+		if node == nil {
+			continue
+		}
+		nd := *node
+		nd.Addr = expandPort(node.Addr, port)
+		nd.Host = expandPort(node.Host, port)
+		fwd.Nodes = append(fwd.Nodes, &nd)
+	}
+	c.Forwarder = &fwd
+
+	return &c
+}
+
+func expandPort(s string, port int) string {
+	return portTemplate.ReplaceAllStringFunc(s, func(m string) string {
+		offset := 0
+		if sub := portTemplate.FindStringSubmatch(m); sub[1] != "" {
+			offset, _ = strconv.Atoi(sub[1])
+		}
+		return strconv.Itoa(port + offset)
+	})
+}
+
+func hasPortTemplate(cfg *config.ServiceConfig) bool {
+	if cfg.Forwarder == nil {
+		return false
+	}
+	for _, node := range cfg.Forwarder.Nodes {
+		if node != nil && (portTemplate.MatchString(node.Addr) || portTemplate.MatchString(node.Host)) {
+			return true
+		}
+	}
+	return false
+}
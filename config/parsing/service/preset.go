@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/internal/util/fallback"
+)
+
+const (
+	presetSOCKS5TLS = "socks5+tls"
+	presetSOCKS5WSS = "socks5+wss"
+)
+
+// applyPreset expands the preset of the service into the handler and listener,
+// the settings of the handler and listener set explicitly take precedence.
+func applyPreset(cfg *config.ServiceConfig) error {
+	preset := cfg.Preset
+	if preset == nil {
+		return nil
+	}
+
+	var handlerType, listenerType string
+	switch strings.ToLower(preset.Type) {
+	case presetSOCKS5TLS:
+		handlerType, listenerType = "socks5", "tls"
+	case presetSOCKS5WSS:
+		handlerType, listenerType = "socks5", "wss"
+	default:
+		return fmt.Errorf("service %s: unknown preset %s", cfg.Name, preset.Type)
+	}
+	if preset.Fallback != "" && fallback.Parse(preset.Fallback) == nil {
+		return fmt.Errorf("service %s: preset %s: invalid fallback %s", cfg.Name, preset.Type, preset.Fallback)
+	}
+
+	if cfg.Handler == nil {
+		cfg.Handler = &config.HandlerConfig{}
+	}
+	if cfg.Handler.Type != "" && cfg.Handler.Type != handlerType {
+		return fmt.Errorf("service %s: preset %s: conflicts with handler %s", cfg.Name, preset.Type, cfg.Handler.Type)
+	}
+	if cfg.Listener == nil {
+		cfg.Listener = &config.ListenerConfig{}
+	}
+	if cfg.Listener.Type != "" && cfg.Listener.Type != listenerType {
+		return fmt.Errorf("service %s: preset %s: conflicts with listener %s", cfg.Name, preset.Type, cfg.Listener.Type)
+	}
+
+	cfg.Handler.Type = handlerType
+	if cfg.Handler.Auth == nil && cfg.Handler.Auther == "" && len(cfg.Handler.Authers) == 0 {
+		cfg.Handler.Auth = preset.Auth
+	}
+	cfg.Listener.Type = listenerType
+	if cfg.Listener.TLS == nil {
+		cfg.Listener.TLS = preset.TLS
+	}
+
+	switch listenerType {
+	case "tls":
+		// the non-proxy requests are received by the handler after the TLS handshake.
+		setDefaultMetadata(&cfg.Handler.Metadata, "fallback", preset.Fallback)
+	case "wss":
+		// the requests other than the websocket handshake are received by the listener.
+		setDefaultMetadata(&cfg.Listener.Metadata, "path", preset.Path)
+		setDefaultMetadata(&cfg.Listener.Metadata, "fallback", preset.Fallback)
+	}
+
+	return nil
+}
+
+func setDefaultMetadata(md *map[string]any, key string, value string) {
+	if value == "" {
+		return
+	}
+	if *md == nil {
+		*md = make(map[string]any)
+	}
+	if _, ok := (*md)[key]; !ok {
+		(*md)[key] = value
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-gost/core/connector"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/registry"
 )
 
@@ -15,7 +16,8 @@ func init() {
 }
 
 type directConnector struct {
-	options connector.Options
+	ipFamily xnet.IPFamily
+	options  connector.Options
 }
 
 func NewConnector(opts ...connector.Option) connector.Connector {
@@ -30,6 +32,7 @@ func NewConnector(opts ...connector.Option) connector.Connector {
 }
 
 func (c *directConnector) Init(md md.Metadata) (err error) {
+	c.ipFamily = xnet.ParseIPFamily(md)
 	return nil
 }
 
@@ -39,7 +42,7 @@ func (c *directConnector) Connect(ctx context.Context, _ net.Conn, network, addr
 		opt(&cOpts)
 	}
 
-	conn, err := cOpts.NetDialer.Dial(ctx, network, address)
+	conn, err := xnet.DialFamily(ctx, network, address, c.ipFamily, cOpts.NetDialer.Dial)
 	if err != nil {
 		return nil, err
 	}
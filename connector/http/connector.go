@@ -5,15 +5,19 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/go-gost/core/connector"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xerrors "github.com/go-gost/x/errors"
+	"github.com/go-gost/x/internal/util/ntlm"
 	"github.com/go-gost/x/internal/util/socks"
 	"github.com/go-gost/x/registry"
 )
@@ -57,7 +61,7 @@ func (c *httpConnector) Connect(ctx context.Context, conn net.Conn, network, add
 		Host:       address,
 		ProtoMajor: 1,
 		ProtoMinor: 1,
-		Header:     c.md.header,
+		Header:     c.md.header.Clone(),
 	}
 
 	if req.Header == nil {
@@ -65,11 +69,19 @@ func (c *httpConnector) Connect(ctx context.Context, conn net.Conn, network, add
 	}
 	req.Header.Set("Proxy-Connection", "keep-alive")
 
-	if user := c.options.Auth; user != nil {
-		u := user.Username()
-		p, _ := user.Password()
-		req.Header.Set("Proxy-Authorization",
-			"Basic "+base64.StdEncoding.EncodeToString([]byte(u+":"+p)))
+	user := c.options.Auth
+	scheme := c.md.authScheme
+	if user != nil {
+		switch scheme {
+		case authSchemeNTLM, authSchemeNegotiate:
+			// the handshake starts with the negotiate message.
+			req.Header.Set("Proxy-Authorization", scheme+" "+base64.StdEncoding.EncodeToString(ntlm.Negotiate()))
+		default:
+			u := user.Username()
+			p, _ := user.Password()
+			req.Header.Set("Proxy-Authorization",
+				"Basic "+base64.StdEncoding.EncodeToString([]byte(u+":"+p)))
+		}
 	}
 
 	switch network {
@@ -87,22 +99,59 @@ func (c *httpConnector) Connect(ctx context.Context, conn net.Conn, network, add
 		return nil, err
 	}
 
-	if log.IsLevelEnabled(logger.TraceLevel) {
-		dump, _ := httputil.DumpRequest(req, false)
-		log.Trace(string(dump))
-	}
-
 	if c.md.connectTimeout > 0 {
 		conn.SetDeadline(time.Now().Add(c.md.connectTimeout))
 		defer conn.SetDeadline(time.Time{})
 	}
 
 	req = req.WithContext(ctx)
+	br := bufio.NewReader(conn)
+	resp, err := c.roundTrip(conn, br, req, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// the proxy refusing the Basic auth may accept the NTLM auth on the same connection.
+	if resp.StatusCode == http.StatusProxyAuthRequired && user != nil && !resp.Close {
+		if scheme == "" {
+			if scheme = ntlmScheme(resp); scheme != "" {
+				discardBody(resp)
+				req.Header.Set("Proxy-Authorization", scheme+" "+base64.StdEncoding.EncodeToString(ntlm.Negotiate()))
+				if resp, err = c.roundTrip(conn, br, req, log); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if resp.StatusCode == http.StatusProxyAuthRequired && scheme != "" && !resp.Close {
+			if resp, err = c.authenticate(conn, br, req, resp, scheme, log); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	if network == "udp" {
+		addr, _ := net.ResolveUDPAddr(network, address)
+		return socks.UDPTunClientConn(conn, addr), nil
+	}
+
+	return conn, nil
+}
+
+func (c *httpConnector) roundTrip(conn net.Conn, br *bufio.Reader, req *http.Request, log logger.Logger) (*http.Response, error) {
+	if log.IsLevelEnabled(logger.TraceLevel) {
+		dump, _ := httputil.DumpRequest(req, false)
+		log.Trace(string(dump))
+	}
+
 	if err := req.Write(conn); err != nil {
 		return nil, err
 	}
 
-	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	resp, err := http.ReadResponse(br, req)
 	if err != nil {
 		return nil, err
 	}
@@ -116,14 +165,69 @@ func (c *httpConnector) Connect(ctx context.Context, conn net.Conn, network, add
 		log.Trace(string(dump))
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s", resp.Status)
+	return resp, nil
+}
+
+// authenticate responds to the NTLM challenge of the proxy with the authenticate message.
+func (c *httpConnector) authenticate(conn net.Conn, br *bufio.Reader, req *http.Request, resp *http.Response, scheme string, log logger.Logger) (*http.Response, error) {
+	var challenge *ntlm.Challenge
+	for _, v := range resp.Header.Values("Proxy-Authenticate") {
+		s, token, _ := strings.Cut(v, " ")
+		if !strings.EqualFold(s, scheme) || token == "" {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(token))
+		if err != nil {
+			continue
+		}
+		if challenge, err = ntlm.ParseChallenge(b); err == nil {
+			break
+		}
+	}
+	if challenge == nil {
+		return resp, nil
 	}
+	discardBody(resp)
 
-	if network == "udp" {
-		addr, _ := net.ResolveUDPAddr(network, address)
-		return socks.UDPTunClientConn(conn, addr), nil
+	p, _ := c.options.Auth.Password()
+	b, err := ntlm.Authenticate(challenge, c.options.Auth.Username(), p)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("Proxy-Authorization", scheme+" "+base64.StdEncoding.EncodeToString(b))
+	log.Debugf("%s authentication", scheme)
 
-	return conn, nil
+	return c.roundTrip(conn, br, req, log)
+}
+
+// ntlmScheme returns the NTLM scheme offered by the proxy, NTLM is preferred to Negotiate.
+func ntlmScheme(resp *http.Response) (scheme string) {
+	for _, v := range resp.Header.Values("Proxy-Authenticate") {
+		s, _, _ := strings.Cut(v, " ")
+		switch {
+		case strings.EqualFold(s, authSchemeNTLM):
+			return authSchemeNTLM
+		case strings.EqualFold(s, authSchemeNegotiate):
+			scheme = authSchemeNegotiate
+		}
+	}
+	return
+}
+
+// discardBody reads the body of the response so that the connection can be reused for the next request.
+func discardBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+}
+
+func statusError(resp *http.Response) error {
+	err := fmt.Errorf("%s", resp.Status)
+	switch resp.StatusCode {
+	case http.StatusProxyAuthRequired:
+		return xerrors.Wrap(xerrors.KindAuth, err)
+	case http.StatusForbidden:
+		return xerrors.Wrap(xerrors.KindBypass, err)
+	default:
+		return err
+	}
 }
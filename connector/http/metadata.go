@@ -2,25 +2,42 @@ package http
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
 )
 
+const (
+	authSchemeNTLM      = "NTLM"
+	authSchemeNegotiate = "Negotiate"
+)
+
 type metadata struct {
 	connectTimeout time.Duration
 	header         http.Header
+	// the auth scheme used by the proxy, one of basic (default), ntlm and negotiate.
+	authScheme string
 }
 
 func (c *httpConnector) parseMetadata(md mdata.Metadata) (err error) {
 	const (
 		connectTimeout = "timeout"
 		header         = "header"
+		authScheme     = "authScheme"
 	)
 
 	c.md.connectTimeout = mdutil.GetDuration(md, connectTimeout)
 
+	// the NTLM over Negotiate is accepted by the proxies supporting Negotiate, Kerberos is not supported.
+	switch strings.ToLower(mdutil.GetString(md, authScheme)) {
+	case "ntlm":
+		c.md.authScheme = authSchemeNTLM
+	case "negotiate":
+		c.md.authScheme = authSchemeNegotiate
+	}
+
 	if mm := mdutil.GetStringMapString(md, header); len(mm) > 0 {
 		hd := http.Header{}
 		for k, v := range mm {
@@ -15,6 +15,7 @@ import (
 	"github.com/go-gost/core/connector"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xerrors "github.com/go-gost/x/errors"
 	"github.com/go-gost/x/registry"
 )
 
@@ -104,7 +105,7 @@ func (c *http2Connector) Connect(ctx context.Context, conn net.Conn, network, ad
 	}
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
-		err = fmt.Errorf("%s", resp.Status)
+		err = statusError(resp)
 		log.Error(err)
 		return nil, err
 	}
@@ -119,3 +120,15 @@ func (c *http2Connector) Connect(ctx context.Context, conn net.Conn, network, ad
 
 	return hc, nil
 }
+
+func statusError(resp *http.Response) error {
+	err := fmt.Errorf("%s", resp.Status)
+	switch resp.StatusCode {
+	case http.StatusProxyAuthRequired:
+		return xerrors.Wrap(xerrors.KindAuth, err)
+	case http.StatusForbidden:
+		return xerrors.Wrap(xerrors.KindBypass, err)
+	default:
+		return err
+	}
+}
@@ -4,15 +4,14 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"io"
 	"math"
 	"net"
 	"sync"
 
-	"github.com/go-gost/core/common/bufpool"
 	mdata "github.com/go-gost/core/metadata"
 	"github.com/go-gost/relay"
+	"github.com/go-gost/x/internal/bufpool"
 	xrelay "github.com/go-gost/x/internal/util/relay"
 )
 
@@ -130,7 +129,7 @@ func readResponse(r io.Reader) (err error) {
 	}
 
 	if resp.Status != relay.StatusOK {
-		err = fmt.Errorf("%d %s", resp.Status, xrelay.StatusText(resp.Status))
+		err = xrelay.StatusError(resp.Status)
 		return
 	}
 	return nil
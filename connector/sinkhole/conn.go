@@ -0,0 +1,115 @@
+package sinkhole
+
+import (
+	"encoding/hex"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+)
+
+type addr struct {
+	network string
+	address string
+}
+
+func (a *addr) Network() string {
+	return a.network
+}
+
+func (a *addr) String() string {
+	return a.address
+}
+
+// conn swallows the data written by the client and never responds,
+// the captured payload is logged when the connection is closed.
+type conn struct {
+	raddr    net.Addr
+	maxBytes int
+	buf      []byte
+	total    int
+	start    time.Time
+	timer    *time.Timer
+	closed   chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+	log      logger.Logger
+}
+
+func newConn(network, address string, maxBytes int, timeout time.Duration, log logger.Logger) *conn {
+	c := &conn{
+		raddr:    &addr{network: network, address: address},
+		maxBytes: maxBytes,
+		start:    time.Now(),
+		closed:   make(chan struct{}),
+		log:      log,
+	}
+	c.timer = time.AfterFunc(timeout, func() { c.Close() })
+	return c
+}
+
+// Read blocks until the connection is closed or the timeout of the sinkhole expires.
+func (c *conn) Read(b []byte) (n int, err error) {
+	<-c.closed
+	return 0, io.EOF
+}
+
+func (c *conn) Write(b []byte) (n int, err error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total += len(b)
+	if n := c.maxBytes - len(c.buf); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		c.buf = append(c.buf, b[:n]...)
+	}
+	return len(b), nil
+}
+
+func (c *conn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+		c.timer.Stop()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.log.WithFields(map[string]any{
+			"duration": time.Since(c.start),
+			"bytes":    c.total,
+		}).Warnf("sinkhole %s/%s: %d bytes captured of %d\n%s",
+			c.raddr, c.raddr.Network(), len(c.buf), c.total, hex.Dump(c.buf))
+	})
+	return nil
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return &addr{network: c.raddr.Network()}
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+// The deadlines are ignored, the connection is bounded by the timeout of the sinkhole.
+func (c *conn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
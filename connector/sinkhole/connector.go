@@ -0,0 +1,65 @@
+// Package sinkhole is the quarantine target of the connections routed to it.
+// The sinkhole accepts the payload sent by the client without connecting to the destination,
+// and logs the payload up to a cap, so that the beacons of malware caught by the proxy can be analyzed.
+//
+// The blocked or suspicious connections are routed to the sinkhole by the bypass of the nodes, e.g.
+//
+//	hops:
+//	- name: hop-0
+//	  nodes:
+//	  - name: sinkhole
+//	    addr: :0
+//	    bypass: suspicious-whitelist
+//	    connector:
+//	      type: sinkhole
+//	      metadata:
+//	        maxBytes: 4096
+//	        timeout: 30s
+//	    dialer:
+//	      type: virtual
+package sinkhole
+
+import (
+	"context"
+	"net"
+
+	"github.com/go-gost/core/connector"
+	md "github.com/go-gost/core/metadata"
+	ctxvalue "github.com/go-gost/x/ctx"
+	"github.com/go-gost/x/registry"
+)
+
+func init() {
+	registry.ConnectorRegistry().Register("sinkhole", NewConnector)
+}
+
+type sinkholeConnector struct {
+	md      metadata
+	options connector.Options
+}
+
+func NewConnector(opts ...connector.Option) connector.Connector {
+	options := connector.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &sinkholeConnector{
+		options: options,
+	}
+}
+
+func (c *sinkholeConnector) Init(md md.Metadata) (err error) {
+	return c.parseMetadata(md)
+}
+
+func (c *sinkholeConnector) Connect(ctx context.Context, _ net.Conn, network, address string, opts ...connector.ConnectOption) (net.Conn, error) {
+	log := c.options.Logger.WithFields(map[string]any{
+		"client":  string(ctxvalue.ClientAddrFromContext(ctx)),
+		"network": network,
+		"address": address,
+	})
+	log.Warnf("sinkhole %s/%s", address, network)
+
+	return newConn(network, address, c.md.maxBytes, c.md.timeout, log), nil
+}
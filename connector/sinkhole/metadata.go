@@ -0,0 +1,39 @@
+package sinkhole
+
+import (
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	defaultMaxBytes = 4096
+	defaultTimeout  = 30 * time.Second
+)
+
+type metadata struct {
+	// the maximum bytes of the payload captured and logged for each connection.
+	maxBytes int
+	// the connection is held open for the timeout, then closed by the sinkhole.
+	timeout time.Duration
+}
+
+func (c *sinkholeConnector) parseMetadata(md mdata.Metadata) (err error) {
+	const (
+		maxBytes = "maxBytes"
+		timeout  = "timeout"
+	)
+
+	c.md.maxBytes = mdutil.GetInt(md, maxBytes)
+	if c.md.maxBytes <= 0 {
+		c.md.maxBytes = defaultMaxBytes
+	}
+
+	c.md.timeout = mdutil.GetDuration(md, timeout)
+	if c.md.timeout <= 0 {
+		c.md.timeout = defaultTimeout
+	}
+
+	return
+}
@@ -5,9 +5,9 @@ import (
 	"net"
 	"time"
 
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/gosocks5"
+	"github.com/go-gost/x/internal/bufpool"
 )
 
 type bindConn struct {
@@ -12,6 +12,7 @@ import (
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
 	"github.com/go-gost/gosocks5"
+	xerrors "github.com/go-gost/x/errors"
 	"github.com/go-gost/x/internal/util/socks"
 	"github.com/go-gost/x/registry"
 )
@@ -146,7 +147,14 @@ func (c *socks5Connector) Connect(ctx context.Context, conn net.Conn, network, a
 	log.Trace(reply)
 
 	if reply.Rep != gosocks5.Succeeded {
-		err = errors.New("host unreachable")
+		switch reply.Rep {
+		case gosocks5.NotAllowed:
+			err = xerrors.Wrap(xerrors.KindBypass, errors.New("not allowed"))
+		case gosocks5.ConnRefused:
+			err = xerrors.Wrap(xerrors.KindRefused, errors.New("connection refused"))
+		default:
+			err = errors.New("host unreachable")
+		}
 		log.Error(err)
 		return nil, err
 	}
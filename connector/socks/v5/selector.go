@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/gosocks5"
+	xerrors "github.com/go-gost/x/errors"
 	"github.com/go-gost/x/internal/util/socks"
 )
 
@@ -65,7 +66,7 @@ func (s *clientSelector) OnSelected(method uint8, conn net.Conn) (string, net.Co
 		s.logger.Trace(resp)
 
 		if resp.Status != gosocks5.Succeeded {
-			return "", nil, gosocks5.ErrAuthFailure
+			return "", nil, xerrors.Wrap(xerrors.KindAuth, gosocks5.ErrAuthFailure)
 		}
 
 	case gosocks5.MethodNoAcceptable:
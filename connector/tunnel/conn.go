@@ -8,9 +8,9 @@ import (
 	"math"
 	"net"
 
-	"github.com/go-gost/core/common/bufpool"
 	mdata "github.com/go-gost/core/metadata"
 	"github.com/go-gost/relay"
+	"github.com/go-gost/x/internal/bufpool"
 	xrelay "github.com/go-gost/x/internal/util/relay"
 )
 
@@ -56,6 +56,14 @@ func (c *tunnelConnector) parseMetadata(md mdata.Metadata) (err error) {
 	if c.md.muxCfg.Version == 0 {
 		c.md.muxCfg.Version = 2
 	}
+	// the tunnel is re-established if nothing, including the keepalives of the relay, arrives within the idle timeout,
+	// the healthy tunnels without the traffic are kept by the keepalives.
+	if d := mdutil.GetDuration(md, "tunnel.idleTimeout"); d > 0 && c.md.muxCfg.KeepAliveTimeout <= 0 {
+		c.md.muxCfg.KeepAliveTimeout = d
+		if c.md.muxCfg.KeepAliveInterval <= 0 || c.md.muxCfg.KeepAliveInterval >= d {
+			c.md.muxCfg.KeepAliveInterval = d / 3
+		}
+	}
 
 	return
 }
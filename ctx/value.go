@@ -1,6 +1,9 @@
 package ctx
 
-import "context"
+import (
+	"context"
+	"crypto/tls"
+)
 
 // clientAddrKey saves the client address.
 type clientAddrKey struct{}
@@ -66,7 +69,9 @@ var (
 	keyClientID = &clientIDKey{}
 )
 
+// ContextWithClientID saves the client ID, it is recorded in the value bag of the context as well.
 func ContextWithClientID(ctx context.Context, clientID ClientID) context.Context {
+	ValuesFromContext(ctx).SetClientID(string(clientID))
 	return context.WithValue(ctx, keyClientID, clientID)
 }
 
@@ -74,3 +79,19 @@ func ClientIDFromContext(ctx context.Context) ClientID {
 	v, _ := ctx.Value(keyClientID).(ClientID)
 	return v
 }
+
+// tlsStateKey saves the state of the TLS connection of the client.
+type tlsStateKey struct{}
+
+var (
+	keyTLSState = &tlsStateKey{}
+)
+
+func ContextWithTLSState(ctx context.Context, state *tls.ConnectionState) context.Context {
+	return context.WithValue(ctx, keyTLSState, state)
+}
+
+func TLSStateFromContext(ctx context.Context) *tls.ConnectionState {
+	v, _ := ctx.Value(keyTLSState).(*tls.ConnectionState)
+	return v
+}
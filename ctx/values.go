@@ -0,0 +1,172 @@
+package ctx
+
+import (
+	"context"
+	"sync"
+)
+
+// valuesKey saves the value bag of the connection.
+type valuesKey struct{}
+
+var (
+	keyValues = &valuesKey{}
+)
+
+// Values is the per-connection value bag shared by the components handling the same connection,
+// the values derived by the listeners and handlers (e.g. the client identity, the sniffed host and the matched rules)
+// are readable by the connectors, limiters, recorders and plugins later in the pipeline.
+// All methods are safe for concurrent use and for a nil Values.
+type Values struct {
+	mu       sync.RWMutex
+	traceID  string
+	clientID string
+	host     string
+	protocol string
+	rules    []string
+	values   map[string]any
+}
+
+func NewValues() *Values {
+	return &Values{}
+}
+
+func ContextWithValues(ctx context.Context, values *Values) context.Context {
+	return context.WithValue(ctx, keyValues, values)
+}
+
+// ValuesFromContext returns the value bag of the context, nil is returned if there is none.
+func ValuesFromContext(ctx context.Context) *Values {
+	if ctx == nil {
+		return nil
+	}
+	v, _ := ctx.Value(keyValues).(*Values)
+	return v
+}
+
+// TraceID is the ID tracing the connection across the components, it is the session ID by default.
+func (v *Values) TraceID() string {
+	if v == nil {
+		return ""
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.traceID
+}
+
+func (v *Values) SetTraceID(id string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.traceID = id
+}
+
+// ClientID is the identity of the authenticated client.
+func (v *Values) ClientID() string {
+	if v == nil {
+		return ""
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.clientID
+}
+
+func (v *Values) SetClientID(id string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.clientID = id
+}
+
+// Host is the target host of the connection, e.g. the sniffed HTTP Host or TLS SNI.
+func (v *Values) Host() string {
+	if v == nil {
+		return ""
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.host
+}
+
+func (v *Values) SetHost(host string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.host = host
+}
+
+// Protocol is the sniffed application protocol of the connection, e.g. http or tls.
+func (v *Values) Protocol() string {
+	if v == nil {
+		return ""
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.protocol
+}
+
+func (v *Values) SetProtocol(protocol string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.protocol = protocol
+}
+
+// Rules returns the rules matched by the connection in order.
+func (v *Values) Rules() []string {
+	if v == nil {
+		return nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return append([]string(nil), v.rules...)
+}
+
+// AddRule records the rule matched by the connection, the duplicated rule is ignored.
+func (v *Values) AddRule(rule string) {
+	if v == nil || rule == "" {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, r := range v.rules {
+		if r == rule {
+			return
+		}
+	}
+	v.rules = append(v.rules, rule)
+}
+
+// Get returns the custom value of key.
+func (v *Values) Get(key string) any {
+	if v == nil {
+		return nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.values[key]
+}
+
+// Set sets the custom value of key, the value is deleted if it is nil.
+func (v *Values) Set(key string, value any) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if value == nil {
+		delete(v.values, key)
+		return
+	}
+	if v.values == nil {
+		v.values = make(map[string]any)
+	}
+	v.values[key] = value
+}
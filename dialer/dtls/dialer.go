@@ -8,6 +8,7 @@ import (
 	"github.com/go-gost/core/dialer"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 	xdtls "github.com/go-gost/x/internal/util/dtls"
 	"github.com/go-gost/x/registry"
 	"github.com/pion/dtls/v2"
@@ -45,7 +46,7 @@ func (d *dtlsDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 		opt(&options)
 	}
 
-	conn, err := options.NetDialer.Dial(ctx, "udp", addr)
+	conn, err := xnet.DialFamily(ctx, "udp", addr, d.md.ipFamily, options.NetDialer.Dial)
 	if err != nil {
 		return nil, err
 	}
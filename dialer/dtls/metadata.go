@@ -5,6 +5,7 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
 )
 
 const (
@@ -15,9 +16,12 @@ type metadata struct {
 	mtu            int
 	bufferSize     int
 	flightInterval time.Duration
+	ipFamily       xnet.IPFamily
 }
 
 func (d *dtlsDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	d.md.mtu = mdutil.GetInt(md, "dtls.mtu", "mtu")
 	d.md.bufferSize = mdutil.GetInt(md, "dtls.bufferSize", "bufferSize")
 	if d.md.bufferSize <= 0 {
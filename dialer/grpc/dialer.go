@@ -7,7 +7,9 @@ import (
 
 	"github.com/go-gost/core/dialer"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 	pb "github.com/go-gost/x/internal/util/grpc/proto"
+	"github.com/go-gost/x/internal/util/reconnect"
 	"github.com/go-gost/x/registry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
@@ -23,6 +25,7 @@ func init() {
 type grpcDialer struct {
 	clients     map[string]pb.GostTunelClientX
 	clientMutex sync.Mutex
+	reconnect   *reconnect.Manager
 	md          metadata
 	options     dialer.Options
 }
@@ -40,7 +43,12 @@ func NewDialer(opts ...dialer.Option) dialer.Dialer {
 }
 
 func (d *grpcDialer) Init(md md.Metadata) (err error) {
-	return d.parseMetadata(md)
+	if err = d.parseMetadata(md); err != nil {
+		return
+	}
+	d.reconnect = reconnect.NewManager(d.md.reconnect, reconnect.LoggerOption(d.options.Logger))
+
+	return nil
 }
 
 // Multiplex implements dialer.Multiplexer interface.
@@ -52,6 +60,10 @@ func (d *grpcDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 	d.clientMutex.Lock()
 	defer d.clientMutex.Unlock()
 
+	if err := d.reconnect.Allow(addr); err != nil {
+		return nil, err
+	}
+
 	client, ok := d.clients[addr]
 	if !ok {
 		var options dialer.DialOptions
@@ -68,14 +80,25 @@ func (d *grpcDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 		}
 		// d.options.Logger.Infof("grpc dialer, addr %s, host %s/%s", addr, d.md.host, options.Host)
 
+		bc := backoff.DefaultConfig
+		if p := d.md.reconnect; p != nil {
+			// the connection is re-established by grpc with the policy.
+			bc = backoff.Config{
+				BaseDelay:  p.MinDelay,
+				Multiplier: p.Factor,
+				Jitter:     p.Jitter,
+				MaxDelay:   p.MaxDelay,
+			}
+		}
+
 		grpcOpts := []grpc.DialOption{
 			// grpc.WithBlock(),
 			grpc.WithContextDialer(func(c context.Context, s string) (net.Conn, error) {
-				return options.NetDialer.Dial(c, "tcp", s)
+				return xnet.DialFamily(c, "tcp", s, d.md.ipFamily, options.NetDialer.Dial)
 			}),
 			grpc.WithAuthority(host),
 			grpc.WithConnectParams(grpc.ConnectParams{
-				Backoff:           backoff.DefaultConfig,
+				Backoff:           bc,
 				MinConnectTimeout: d.md.minConnectTimeout,
 			}),
 			grpc.FailOnNonTempDialError(true),
@@ -97,6 +120,7 @@ func (d *grpcDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 		cc, err := grpc.DialContext(ctx, addr, grpcOpts...)
 		if err != nil {
 			d.options.Logger.Error(err)
+			d.reconnect.Failure(addr, err)
 			return nil, err
 		}
 		client = pb.NewGostTunelClientX(cc)
@@ -107,8 +131,10 @@ func (d *grpcDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 	cli, err := client.TunnelX(ctx2, d.md.path)
 	if err != nil {
 		cancel()
+		d.reconnect.Failure(addr, err)
 		return nil, err
 	}
+	d.reconnect.Success(addr)
 
 	return &conn{
 		c:          cli,
@@ -5,6 +5,8 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/reconnect"
 )
 
 type metadata struct {
@@ -16,9 +18,13 @@ type metadata struct {
 	keepaliveTimeout             time.Duration
 	keepalivePermitWithoutStream bool
 	minConnectTimeout            time.Duration
+	ipFamily                     xnet.IPFamily
+	reconnect                    *reconnect.Policy
 }
 
 func (d *grpcDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	d.md.insecure = mdutil.GetBool(md, "grpc.insecure", "grpcInsecure", "insecure")
 	d.md.host = mdutil.GetString(md, "grpc.authority", "grpc.host", "host")
 	d.md.path = mdutil.GetString(md, "grpc.path", "path")
@@ -38,6 +44,7 @@ func (d *grpcDialer) parseMetadata(md mdata.Metadata) (err error) {
 	if d.md.minConnectTimeout <= 0 {
 		d.md.minConnectTimeout = 30 * time.Second
 	}
+	d.md.reconnect = reconnect.ParsePolicy(md)
 
 	return
 }
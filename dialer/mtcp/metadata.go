@@ -5,15 +5,19 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/util/mux"
 )
 
 type metadata struct {
 	handshakeTimeout time.Duration
 	muxCfg           *mux.Config
+	ipFamily         xnet.IPFamily
 }
 
 func (d *mtcpDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	d.md.handshakeTimeout = mdutil.GetDuration(md, "handshakeTimeout")
 
 	d.md.muxCfg = &mux.Config{
@@ -11,7 +11,9 @@ import (
 	"github.com/go-gost/core/dialer"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/shaping"
 	"github.com/go-gost/x/registry"
 )
 
@@ -68,7 +70,7 @@ func (d *mtlsDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 			opt(&options)
 		}
 
-		conn, err = options.NetDialer.Dial(ctx, "tcp", addr)
+		conn, err = xnet.DialFamily(ctx, "tcp", addr, d.md.ipFamily, options.NetDialer.Dial)
 		if err != nil {
 			return
 		}
@@ -127,7 +129,7 @@ func (d *mtlsDialer) initSession(ctx context.Context, conn net.Conn) (*muxSessio
 	if err := tlsConn.HandshakeContext(ctx); err != nil {
 		return nil, err
 	}
-	conn = tlsConn
+	conn = shaping.Conn(tlsConn, d.md.shaping)
 
 	// stream multiplex
 	session, err := mux.ClientSession(conn, d.md.muxCfg)
@@ -5,15 +5,21 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/shaping"
 )
 
 type metadata struct {
 	handshakeTimeout time.Duration
 	muxCfg           *mux.Config
+	ipFamily         xnet.IPFamily
+	shaping          *shaping.Profile
 }
 
 func (d *mtlsDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	d.md.handshakeTimeout = mdutil.GetDuration(md, "handshakeTimeout")
 
 	d.md.muxCfg = &mux.Config{
@@ -25,5 +31,6 @@ func (d *mtlsDialer) parseMetadata(md mdata.Metadata) (err error) {
 		MaxReceiveBuffer:  mdutil.GetInt(md, "mux.maxReceiveBuffer"),
 		MaxStreamBuffer:   mdutil.GetInt(md, "mux.maxStreamBuffer"),
 	}
+	d.md.shaping, err = shaping.ParseProfile(md)
 	return
 }
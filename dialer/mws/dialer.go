@@ -11,7 +11,10 @@ import (
 	"github.com/go-gost/core/dialer"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/reconnect"
+	"github.com/go-gost/x/internal/util/shaping"
 	ws_util "github.com/go-gost/x/internal/util/ws"
 	"github.com/go-gost/x/registry"
 	"github.com/gorilla/websocket"
@@ -25,6 +28,7 @@ func init() {
 type mwsDialer struct {
 	sessions     map[string]*muxSession
 	sessionMutex sync.Mutex
+	reconnect    *reconnect.Manager
 	tlsEnabled   bool
 	md           metadata
 	options      dialer.Options
@@ -58,6 +62,7 @@ func (d *mwsDialer) Init(md md.Metadata) (err error) {
 	if err = d.parseMetadata(md); err != nil {
 		return
 	}
+	d.reconnect = reconnect.NewManager(reconnect.ParsePolicy(md), reconnect.LoggerOption(d.options.Logger))
 
 	return nil
 }
@@ -77,13 +82,18 @@ func (d *mwsDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOp
 		ok = false
 	}
 	if !ok {
+		if err = d.reconnect.Allow(addr); err != nil {
+			return
+		}
+
 		var options dialer.DialOptions
 		for _, opt := range opts {
 			opt(&options)
 		}
 
-		conn, err = options.NetDialer.Dial(ctx, "tcp", addr)
+		conn, err = xnet.DialFamily(ctx, "tcp", addr, d.md.ipFamily, options.NetDialer.Dial)
 		if err != nil {
+			d.reconnect.Failure(addr, err)
 			return
 		}
 
@@ -127,8 +137,10 @@ func (d *mwsDialer) Handshake(ctx context.Context, conn net.Conn, options ...dia
 			log.Error(err)
 			conn.Close()
 			delete(d.sessions, opts.Addr)
+			d.reconnect.Failure(opts.Addr, err)
 			return nil, err
 		}
+		d.reconnect.Success(opts.Addr)
 		session = s
 		d.sessions[opts.Addr] = session
 	}
@@ -187,7 +199,7 @@ func (d *mwsDialer) initSession(ctx context.Context, host string, conn net.Conn,
 	}
 
 	// stream multiplex
-	session, err := mux.ClientSession(cc, d.md.muxCfg)
+	session, err := mux.ClientSession(shaping.Conn(cc, d.md.shaping), d.md.muxCfg)
 	if err != nil {
 		log.Error(err)
 		return nil, err
@@ -6,7 +6,9 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/shaping"
 )
 
 const (
@@ -27,9 +29,13 @@ type metadata struct {
 	header            http.Header
 	keepaliveInterval time.Duration
 	muxCfg            *mux.Config
+	ipFamily          xnet.IPFamily
+	shaping           *shaping.Profile
 }
 
 func (d *mwsDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	d.md.host = mdutil.GetString(md, "ws.host", "host")
 	d.md.path = mdutil.GetString(md, "ws.path", "path")
 	if d.md.path == "" {
@@ -67,5 +73,6 @@ func (d *mwsDialer) parseMetadata(md mdata.Metadata) (err error) {
 		}
 	}
 
+	d.md.shaping, err = shaping.ParseProfile(md)
 	return
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/go-gost/core/dialer"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/registry"
 )
 
@@ -40,7 +41,7 @@ func (d *obfsHTTPDialer) Dial(ctx context.Context, addr string, opts ...dialer.D
 		opt(options)
 	}
 
-	conn, err := options.NetDialer.Dial(ctx, "tcp", addr)
+	conn, err := xnet.DialFamily(ctx, "tcp", addr, d.md.ipFamily, options.NetDialer.Dial)
 	if err != nil {
 		d.logger.Error(err)
 	}
@@ -5,14 +5,18 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
 )
 
 type metadata struct {
-	host   string
-	header http.Header
+	host     string
+	header   http.Header
+	ipFamily xnet.IPFamily
 }
 
 func (d *obfsHTTPDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	const (
 		header = "header"
 		host   = "host"
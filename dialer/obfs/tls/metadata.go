@@ -3,13 +3,17 @@ package tls
 import (
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
 )
 
 type metadata struct {
-	host string
+	host     string
+	ipFamily xnet.IPFamily
 }
 
 func (d *obfsTLSDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	const (
 		host = "host"
 	)
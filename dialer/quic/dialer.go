@@ -10,6 +10,7 @@ import (
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
 	quic_util "github.com/go-gost/x/internal/util/quic"
+	"github.com/go-gost/x/internal/util/reconnect"
 	"github.com/go-gost/x/registry"
 	"github.com/quic-go/quic-go"
 )
@@ -21,6 +22,7 @@ func init() {
 type quicDialer struct {
 	sessions     map[string]*quicSession
 	sessionMutex sync.Mutex
+	reconnect    *reconnect.Manager
 	logger       logger.Logger
 	md           metadata
 	options      dialer.Options
@@ -43,6 +45,7 @@ func (d *quicDialer) Init(md md.Metadata) (err error) {
 	if err = d.parseMetadata(md); err != nil {
 		return
 	}
+	d.reconnect = reconnect.NewManager(reconnect.ParsePolicy(md), reconnect.LoggerOption(d.logger))
 
 	return nil
 }
@@ -62,6 +65,10 @@ func (d *quicDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 
 	session, ok := d.sessions[addr]
 	if !ok {
+		if err := d.reconnect.Allow(addr); err != nil {
+			return nil, err
+		}
+
 		options := &dialer.DialOptions{}
 		for _, opt := range opts {
 			opt(options)
@@ -69,6 +76,7 @@ func (d *quicDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 
 		c, err := options.NetDialer.Dial(ctx, "udp", "")
 		if err != nil {
+			d.reconnect.Failure(addr, err)
 			return nil, err
 		}
 		pc, ok := c.(net.PacketConn)
@@ -85,8 +93,10 @@ func (d *quicDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialO
 		if err != nil {
 			d.logger.Error(err)
 			pc.Close()
+			d.reconnect.Failure(addr, err)
 			return nil, err
 		}
+		d.reconnect.Success(addr)
 
 		d.sessions[addr] = session
 	}
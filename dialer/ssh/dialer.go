@@ -8,6 +8,8 @@ import (
 
 	"github.com/go-gost/core/dialer"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/reconnect"
 	ssh_util "github.com/go-gost/x/internal/util/ssh"
 	"github.com/go-gost/x/registry"
 	"golang.org/x/crypto/ssh"
@@ -20,6 +22,7 @@ func init() {
 type sshDialer struct {
 	sessions     map[string]*ssh_util.Session
 	sessionMutex sync.Mutex
+	reconnect    *reconnect.Manager
 	md           metadata
 	options      dialer.Options
 }
@@ -40,6 +43,7 @@ func (d *sshDialer) Init(md md.Metadata) (err error) {
 	if err = d.parseMetadata(md); err != nil {
 		return
 	}
+	d.reconnect = reconnect.NewManager(reconnect.ParsePolicy(md), reconnect.LoggerOption(d.options.Logger))
 
 	return nil
 }
@@ -59,13 +63,18 @@ func (d *sshDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOp
 		ok = false
 	}
 	if !ok {
+		if err = d.reconnect.Allow(addr); err != nil {
+			return
+		}
+
 		var options dialer.DialOptions
 		for _, opt := range opts {
 			opt(&options)
 		}
 
-		conn, err = options.NetDialer.Dial(ctx, "tcp", addr)
+		conn, err = xnet.DialFamily(ctx, "tcp", addr, d.md.ipFamily, options.NetDialer.Dial)
 		if err != nil {
+			d.reconnect.Failure(addr, err)
 			return
 		}
 		if d.md.handshakeTimeout > 0 {
@@ -76,8 +85,10 @@ func (d *sshDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOp
 		session, err = d.initSession(ctx, addr, conn)
 		if err != nil {
 			conn.Close()
+			d.reconnect.Failure(addr, err)
 			return nil, err
 		}
+		d.reconnect.Success(addr)
 		if d.md.keepalive {
 			go session.Keepalive(d.md.keepaliveInterval, d.md.keepaliveTimeout, d.md.keepaliveRetries)
 		}
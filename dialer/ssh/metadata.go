@@ -6,6 +6,7 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -16,9 +17,12 @@ type metadata struct {
 	keepaliveInterval time.Duration
 	keepaliveTimeout  time.Duration
 	keepaliveRetries  int
+	ipFamily          xnet.IPFamily
 }
 
 func (d *sshDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	const (
 		handshakeTimeout = "handshakeTimeout"
 		privateKeyFile   = "privateKeyFile"
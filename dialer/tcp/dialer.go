@@ -7,6 +7,7 @@ import (
 	"github.com/go-gost/core/dialer"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/registry"
 )
 
@@ -40,9 +41,13 @@ func (d *tcpDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOp
 		opt(&options)
 	}
 
-	conn, err := options.NetDialer.Dial(ctx, "tcp", addr)
+	conn, err := xnet.DialFamily(ctx, "tcp", addr, d.md.ipFamily, options.NetDialer.Dial)
 	if err != nil {
 		d.logger.Error(err)
+		return nil, err
 	}
-	return conn, err
+	if err := d.md.sockOpts.Apply(conn); err != nil {
+		d.logger.Warnf("sockopts: %v", err)
+	}
+	return conn, nil
 }
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 )
 
 const (
@@ -16,8 +17,13 @@ const (
 
 type metadata struct {
 	dialTimeout time.Duration
+	sockOpts    *xnet.SockOpts
+	ipFamily    xnet.IPFamily
 }
 
 func (d *tcpDialer) parseMetadata(md md.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
+	d.md.sockOpts = xnet.ParseSockOpts(md)
 	return
 }
@@ -9,6 +9,8 @@ import (
 	"github.com/go-gost/core/dialer"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/shaping"
 	"github.com/go-gost/x/registry"
 )
 
@@ -44,11 +46,15 @@ func (d *tlsDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOp
 		opt(&options)
 	}
 
-	conn, err := options.NetDialer.Dial(ctx, "tcp", addr)
+	conn, err := xnet.DialFamily(ctx, "tcp", addr, d.md.ipFamily, options.NetDialer.Dial)
 	if err != nil {
 		d.logger.Error(err)
+		return nil, err
+	}
+	if err := d.md.sockOpts.Apply(conn); err != nil {
+		d.logger.Warnf("sockopts: %v", err)
 	}
-	return conn, err
+	return conn, nil
 }
 
 // Handshake implements dialer.Handshaker
@@ -64,5 +70,5 @@ func (d *tlsDialer) Handshake(ctx context.Context, conn net.Conn, options ...dia
 		return nil, err
 	}
 
-	return tlsConn, nil
+	return shaping.Conn(tlsConn, d.md.shaping), nil
 }
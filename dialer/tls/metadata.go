@@ -5,18 +5,27 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/shaping"
 )
 
 type metadata struct {
 	handshakeTimeout time.Duration
+	sockOpts         *xnet.SockOpts
+	ipFamily         xnet.IPFamily
+	shaping          *shaping.Profile
 }
 
 func (d *tlsDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	const (
 		handshakeTimeout = "handshakeTimeout"
 	)
 
 	d.md.handshakeTimeout = mdutil.GetDuration(md, handshakeTimeout)
+	d.md.sockOpts = xnet.ParseSockOpts(md)
 
+	d.md.shaping, err = shaping.ParseProfile(md)
 	return
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/go-gost/core/dialer"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/registry"
 )
 
@@ -40,7 +41,7 @@ func (d *udpDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOp
 		opt(&options)
 	}
 
-	c, err := options.NetDialer.Dial(ctx, "udp", addr)
+	c, err := xnet.DialFamily(ctx, "udp", addr, d.md.ipFamily, options.NetDialer.Dial)
 	if err != nil {
 		return nil, err
 	}
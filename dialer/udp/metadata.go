@@ -4,6 +4,7 @@ import (
 	"time"
 
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
 )
 
 const (
@@ -16,8 +17,11 @@ const (
 
 type metadata struct {
 	dialTimeout time.Duration
+	ipFamily    xnet.IPFamily
 }
 
 func (d *udpDialer) parseMetadata(md md.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	return
 }
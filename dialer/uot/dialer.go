@@ -0,0 +1,63 @@
+package uot
+
+import (
+	"context"
+	"net"
+
+	"github.com/go-gost/core/dialer"
+	"github.com/go-gost/core/logger"
+	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/uot"
+	"github.com/go-gost/x/registry"
+)
+
+func init() {
+	registry.DialerRegistry().Register("uot", NewDialer)
+}
+
+// uotDialer carries the UDP datagrams over the stream connection dialed by the chain,
+// each datagram is written and read with the length prefix.
+type uotDialer struct {
+	md     metadata
+	logger logger.Logger
+}
+
+func NewDialer(opts ...dialer.Option) dialer.Dialer {
+	options := &dialer.Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &uotDialer{
+		logger: options.Logger,
+	}
+}
+
+func (d *uotDialer) Init(md md.Metadata) (err error) {
+	return d.parseMetadata(md)
+}
+
+func (d *uotDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOption) (net.Conn, error) {
+	var options dialer.DialOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	conn, err := xnet.DialFamily(ctx, "tcp", addr, d.md.ipFamily, options.NetDialer.Dial)
+	if err != nil {
+		d.logger.Error(err)
+		return nil, err
+	}
+	if err := d.md.sockOpts.Apply(conn); err != nil {
+		d.logger.Warnf("sockopts: %v", err)
+	}
+
+	return uot.Conn(conn,
+		uot.TimestampOption(d.md.timestamp),
+		uot.MaxAgeOption(d.md.maxAge),
+		uot.DropCallbackOption(func() {
+			d.logger.Tracef("stale datagram from %s is dropped", addr)
+		}),
+	), nil
+}
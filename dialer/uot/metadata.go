@@ -0,0 +1,29 @@
+package uot
+
+import (
+	"time"
+
+	md "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
+)
+
+type metadata struct {
+	timestamp bool
+	maxAge    time.Duration
+	sockOpts  *xnet.SockOpts
+	ipFamily  xnet.IPFamily
+}
+
+func (d *uotDialer) parseMetadata(md md.Metadata) (err error) {
+	const (
+		timestamp = "timestamp"
+		maxAge    = "maxAge"
+	)
+
+	d.md.timestamp = mdutil.GetBool(md, timestamp)
+	d.md.maxAge = mdutil.GetDuration(md, maxAge)
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+	d.md.sockOpts = xnet.ParseSockOpts(md)
+	return
+}
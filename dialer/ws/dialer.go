@@ -8,6 +8,8 @@ import (
 
 	"github.com/go-gost/core/dialer"
 	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/shaping"
 	ws_util "github.com/go-gost/x/internal/util/ws"
 	"github.com/go-gost/x/registry"
 	"github.com/gorilla/websocket"
@@ -57,11 +59,15 @@ func (d *wsDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOpt
 		opt(&options)
 	}
 
-	conn, err := options.NetDialer.Dial(ctx, "tcp", addr)
+	conn, err := xnet.DialFamily(ctx, "tcp", addr, d.md.ipFamily, options.NetDialer.Dial)
 	if err != nil {
 		d.options.Logger.Error(err)
+		return nil, err
+	}
+	if err := d.md.sockOpts.Apply(conn); err != nil {
+		d.options.Logger.Warnf("sockopts: %v", err)
 	}
-	return conn, err
+	return conn, nil
 }
 
 // Handshake implements dialer.Handshaker
@@ -116,7 +122,7 @@ func (d *wsDialer) Handshake(ctx context.Context, conn net.Conn, options ...dial
 		go d.keepalive(cc)
 	}
 
-	return cc, nil
+	return shaping.Conn(cc, d.md.shaping), nil
 }
 
 func (d *wsDialer) keepalive(conn ws_util.WebsocketConn) {
@@ -6,6 +6,8 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/shaping"
 )
 
 const (
@@ -25,9 +27,14 @@ type metadata struct {
 
 	header            http.Header
 	keepaliveInterval time.Duration
+	sockOpts          *xnet.SockOpts
+	ipFamily          xnet.IPFamily
+	shaping           *shaping.Profile
 }
 
 func (d *wsDialer) parseMetadata(md mdata.Metadata) (err error) {
+	d.md.ipFamily = xnet.ParseIPFamily(md)
+
 	d.md.host = mdutil.GetString(md, "ws.host", "host")
 
 	d.md.path = mdutil.GetString(md, "ws.path", "path")
@@ -36,6 +43,7 @@ func (d *wsDialer) parseMetadata(md mdata.Metadata) (err error) {
 	}
 
 	d.md.handshakeTimeout = mdutil.GetDuration(md, "ws.handshakeTimeout", "handshakeTimeout")
+	d.md.sockOpts = xnet.ParseSockOpts(md)
 	d.md.readHeaderTimeout = mdutil.GetDuration(md, "ws.readHeaderTimeout", "readHeaderTimeout")
 	d.md.readBufferSize = mdutil.GetInt(md, "ws.readBufferSize", "readBufferSize")
 	d.md.writeBufferSize = mdutil.GetInt(md, "ws.writeBufferSize", "writeBufferSize")
@@ -56,5 +64,6 @@ func (d *wsDialer) parseMetadata(md mdata.Metadata) (err error) {
 		}
 	}
 
+	d.md.shaping, err = shaping.ParseProfile(md)
 	return
 }
@@ -0,0 +1,128 @@
+// Package errors defines the kinds of the connection errors,
+// which are used in the handler logs, metrics labels and API instead of the opaque error strings.
+package errors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+type Kind string
+
+const (
+	// the domain name can not be resolved.
+	KindResolve Kind = "resolve"
+	// the connection is refused by the target or the node.
+	KindRefused Kind = "refused"
+	KindTimeout Kind = "timeout"
+	// the authentication is rejected by the service or the node.
+	KindAuth Kind = "auth"
+	// the target is blocked by the bypass.
+	KindBypass Kind = "bypass"
+	// the request is rejected by the rate limiter.
+	KindLimiter Kind = "limiter"
+	KindOther   Kind = "other"
+)
+
+var (
+	ErrResolve   = New(KindResolve, "resolve failed")
+	ErrRefused   = New(KindRefused, "connection refused")
+	ErrTimeout   = New(KindTimeout, "timeout")
+	ErrAuth      = New(KindAuth, "authentication failed")
+	ErrBypass    = New(KindBypass, "blocked by bypass")
+	ErrRateLimit = New(KindLimiter, "rate limiting exceeded")
+
+	// the BitTorrent flow is blocked by the handler.
+	ErrBitTorrent = New(KindBypass, "bittorrent is blocked")
+	// the connection to the SMTP port is blocked by the SMTP policy of the service.
+	ErrSMTP = New(KindBypass, "smtp is blocked")
+	// the client has too many handshakes in progress.
+	ErrHandshakeLimit = New(KindLimiter, "too many handshakes")
+)
+
+// Error is an error with the kind.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func New(kind Kind, text string) error {
+	return &Error{
+		Kind: kind,
+		Err:  errors.New(text),
+	}
+}
+
+// Wrap returns an error of the kind wrapping err, err is returned as is if it is already of the kind.
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) && e.Kind == kind {
+		return err
+	}
+	return &Error{
+		Kind: kind,
+		Err:  err,
+	}
+}
+
+// Classify wraps err with the kind detected by KindOf.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	return Wrap(KindOf(err), err)
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an error of the same kind, so that errors.Is(err, ErrAuth) matches any authentication error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind
+}
+
+// KindOf returns the kind of err, the untyped errors are classified by the underlying network errors.
+func KindOf(err error) Kind {
+	if err == nil {
+		return ""
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return KindResolve
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return KindRefused
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return KindTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return KindTimeout
+	}
+	// the resolving error of the router is not typed.
+	if strings.HasPrefix(err.Error(), "resolver: ") {
+		return KindResolve
+	}
+
+	return KindOther
+}
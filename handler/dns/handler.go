@@ -9,14 +9,16 @@ import (
 	"time"
 
 	"github.com/go-gost/core/chain"
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/handler"
 	"github.com/go-gost/core/hop"
 	"github.com/go-gost/core/hosts"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xerrors "github.com/go-gost/x/errors"
 	xhop "github.com/go-gost/x/hop"
+	"github.com/go-gost/x/internal/bufpool"
 	resolver_util "github.com/go-gost/x/internal/util/resolver"
+	mdx "github.com/go-gost/x/metadata"
 	"github.com/go-gost/x/registry"
 	"github.com/go-gost/x/resolver/exchanger"
 	"github.com/miekg/dns"
@@ -28,12 +30,18 @@ const (
 
 func init() {
 	registry.HandlerRegistry().Register("dns", NewHandler)
+	mdx.RegisterDefaults(mdx.KindHandler, "dns",
+		mdx.Default{Keys: []string{"timeout"}, Value: defaultTimeout},
+		mdx.Default{Keys: []string{"bufferSize"}, Value: defaultBufferSize},
+		mdx.Default{Keys: []string{"stub.addr"}, Value: defaultStubAddr},
+	)
 }
 
 type dnsHandler struct {
 	hop        hop.Hop
 	exchangers map[string]exchanger.Exchanger
 	cache      *resolver_util.Cache
+	stub       stubResolver
 	router     *chain.Router
 	hostMapper hosts.HostMapper
 	md         metadata
@@ -113,9 +121,27 @@ func (h *dnsHandler) Init(md md.Metadata) (err error) {
 		h.exchangers["default"] = ex
 	}
 
+	if h.md.stub {
+		if h.stub, err = newStubResolver(h.md.stubMode, h.md.stubAddr, h.md.stubPath, h.md.stubInterface, log); err != nil {
+			return err
+		}
+		if err = h.stub.Install(); err != nil {
+			h.stub = nil
+			return err
+		}
+	}
+
 	return
 }
 
+// Close implements io.Closer, the settings of the system stub resolver are restored.
+func (h *dnsHandler) Close() error {
+	if h.stub == nil {
+		return nil
+	}
+	return h.stub.Restore()
+}
+
 // Forward implements handler.Forwarder.
 func (h *dnsHandler) Forward(hop hop.Hop) {
 	h.hop = hop
@@ -138,7 +164,7 @@ func (h *dnsHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	b := bufpool.Get(h.md.bufferSize)
@@ -23,6 +23,12 @@ type metadata struct {
 	dns        []string
 	bufferSize int
 	async      bool
+	// install the handler as the system stub resolver.
+	stub          bool
+	stubMode      string
+	stubAddr      string
+	stubPath      string
+	stubInterface string
 }
 
 func (h *dnsHandler) parseMetadata(md mdata.Metadata) (err error) {
@@ -34,6 +40,12 @@ func (h *dnsHandler) parseMetadata(md mdata.Metadata) (err error) {
 		dns         = "dns"
 		bufferSize  = "bufferSize"
 		async       = "async"
+
+		stub          = "stub"
+		stubMode      = "stub.mode"
+		stubAddr      = "stub.addr"
+		stubPath      = "stub.path"
+		stubInterface = "stub.interface"
 	)
 
 	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
@@ -60,5 +72,14 @@ func (h *dnsHandler) parseMetadata(md mdata.Metadata) (err error) {
 	}
 	h.md.async = mdutil.GetBool(md, async)
 
+	h.md.stub = mdutil.GetBool(md, stub)
+	h.md.stubMode = mdutil.GetString(md, stubMode)
+	h.md.stubAddr = mdutil.GetString(md, stubAddr)
+	if h.md.stubAddr == "" {
+		h.md.stubAddr = defaultStubAddr
+	}
+	h.md.stubPath = mdutil.GetString(md, stubPath)
+	h.md.stubInterface = mdutil.GetString(md, stubInterface)
+
 	return
 }
@@ -0,0 +1,235 @@
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/go-gost/core/logger"
+)
+
+const (
+	stubModeAuto       = "auto"
+	stubModeResolvConf = "resolvconf"
+	stubModeResolved   = "resolved"
+
+	defaultStubAddr       = "127.0.0.53"
+	defaultResolvConfPath = "/etc/resolv.conf"
+	// the suffix of the backup of the original resolv.conf, which is kept until the settings are restored,
+	// so that the settings left by a crash are restored on the next start.
+	resolvConfBackupSuffix = ".gost-backup"
+)
+
+// stubResolver installs the DNS handler as the system stub resolver and restores the original settings.
+type stubResolver interface {
+	Install() error
+	Restore() error
+}
+
+func newStubResolver(mode, addr, path, ifce string, log logger.Logger) (stubResolver, error) {
+	if ip := net.ParseIP(addr); ip == nil {
+		return nil, fmt.Errorf("stub: invalid address %s, the address must be an IP", addr)
+	}
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("stub: not supported on windows")
+	}
+
+	if mode == "" || mode == stubModeAuto {
+		mode = stubModeResolvConf
+		if ifce != "" && hasResolved() {
+			mode = stubModeResolved
+		}
+	}
+
+	switch mode {
+	case stubModeResolvConf:
+		if path == "" {
+			path = defaultResolvConfPath
+		}
+		return &resolvConf{
+			addr:   addr,
+			path:   path,
+			logger: log,
+		}, nil
+	case stubModeResolved:
+		if ifce == "" {
+			return nil, errors.New("stub: the interface is required by systemd-resolved")
+		}
+		if !hasResolved() {
+			return nil, errors.New("stub: systemd-resolved is not running")
+		}
+		return &resolved{
+			addr:   addr,
+			ifce:   ifce,
+			logger: log,
+		}, nil
+	default:
+		return nil, fmt.Errorf("stub: unknown mode %s", mode)
+	}
+}
+
+// hasResolved checks whether systemd-resolved is running and resolvectl is available.
+func hasResolved() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if _, err := os.Stat("/run/systemd/resolve"); err != nil {
+		return false
+	}
+	_, err := exec.LookPath("resolvectl")
+	return err == nil
+}
+
+// resolvConf rewrites resolv.conf to use the stub address as the only nameserver,
+// the search and options lines of the original file are kept.
+type resolvConf struct {
+	addr   string
+	path   string
+	logger logger.Logger
+}
+
+func (r *resolvConf) Install() error {
+	backup := r.path + resolvConfBackupSuffix
+
+	// the backup left by the previous run is the original file.
+	if _, err := os.Lstat(backup); err == nil {
+		r.logger.Warnf("stub: restore %s from %s left by the previous run", r.path, backup)
+		if err := r.Restore(); err != nil {
+			return err
+		}
+	}
+
+	fi, err := os.Lstat(r.path)
+	if err != nil {
+		return fmt.Errorf("stub: %w", err)
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("stub: %w", err)
+	}
+
+	// the symlink (e.g. to the stub-resolv.conf of systemd-resolved) is backed up as a symlink.
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(r.path)
+		if err != nil {
+			return fmt.Errorf("stub: %w", err)
+		}
+		if err := os.Symlink(target, backup); err != nil {
+			return fmt.Errorf("stub: %w", err)
+		}
+	} else if err := os.WriteFile(backup, data, fi.Mode().Perm()); err != nil {
+		return fmt.Errorf("stub: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# generated by gost, the original file is saved to %s and restored on exit.\n", backup)
+	fmt.Fprintf(&buf, "nameserver %s\n", r.addr)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "search ") || strings.HasPrefix(line, "domain ") || strings.HasPrefix(line, "options ") {
+			fmt.Fprintln(&buf, line)
+		}
+	}
+
+	if err := writeFile(r.path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("stub: %w", err)
+	}
+	r.logger.Infof("stub: %s uses nameserver %s", r.path, r.addr)
+	return nil
+}
+
+func (r *resolvConf) Restore() error {
+	backup := r.path + resolvConfBackupSuffix
+	fi, err := os.Lstat(backup)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("stub: %w", err)
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(backup)
+		if err != nil {
+			return fmt.Errorf("stub: %w", err)
+		}
+		os.Remove(r.path)
+		if err := os.Symlink(target, r.path); err != nil {
+			return fmt.Errorf("stub: %w", err)
+		}
+	} else {
+		data, err := os.ReadFile(backup)
+		if err != nil {
+			return fmt.Errorf("stub: %w", err)
+		}
+		if err := writeFile(r.path, data, fi.Mode().Perm()); err != nil {
+			return fmt.Errorf("stub: %w", err)
+		}
+	}
+
+	r.logger.Infof("stub: %s is restored", r.path)
+	return os.Remove(backup)
+}
+
+// writeFile replaces the file atomically, the symlink is replaced rather than the file it points to.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// resolved sets the stub address as the DNS server of the interface by resolvectl,
+// and routes all the domains to the interface.
+type resolved struct {
+	addr   string
+	ifce   string
+	logger logger.Logger
+}
+
+func (r *resolved) Install() error {
+	cmds := [][]string{
+		{"resolvectl", "dns", r.ifce, r.addr},
+		{"resolvectl", "domain", r.ifce, "~."},
+		{"resolvectl", "default-route", r.ifce, "true"},
+	}
+	for _, args := range cmds {
+		r.logger.Debugf("stub: %s", strings.Join(args, " "))
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			r.Restore()
+			return fmt.Errorf("stub: %s: %v: %s", strings.Join(args, " "), err, bytes.TrimSpace(out))
+		}
+	}
+	r.logger.Infof("stub: systemd-resolved uses nameserver %s on %s", r.addr, r.ifce)
+	return nil
+}
+
+func (r *resolved) Restore() error {
+	if out, err := exec.Command("resolvectl", "revert", r.ifce).CombinedOutput(); err != nil {
+		return fmt.Errorf("stub: resolvectl revert %s: %v: %s", r.ifce, err, bytes.TrimSpace(out))
+	}
+	r.logger.Infof("stub: systemd-resolved settings of %s are restored", r.ifce)
+	return nil
+}
@@ -0,0 +1,226 @@
+// Package echo implements the diagnostic handlers of RFC 862 (echo), RFC 863 (discard) and RFC 864 (chargen)
+// over TCP and UDP, they are the targets to validate the chains end-to-end.
+package echo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/go-gost/core/handler"
+	md "github.com/go-gost/core/metadata"
+	"github.com/go-gost/x/internal/bufpool"
+	"github.com/go-gost/x/registry"
+)
+
+const (
+	modeEcho    = "echo"
+	modeDiscard = "discard"
+	modeChargen = "chargen"
+)
+
+func init() {
+	registry.HandlerRegistry().Register(modeEcho, NewEchoHandler)
+	registry.HandlerRegistry().Register(modeDiscard, NewDiscardHandler)
+	registry.HandlerRegistry().Register(modeChargen, NewChargenHandler)
+}
+
+type echoHandler struct {
+	mode    string
+	md      metadata
+	options handler.Options
+}
+
+// NewEchoHandler creates the handler sending back the received data.
+func NewEchoHandler(opts ...handler.Option) handler.Handler {
+	return newHandler(modeEcho, opts...)
+}
+
+// NewDiscardHandler creates the handler throwing away the received data.
+func NewDiscardHandler(opts ...handler.Option) handler.Handler {
+	return newHandler(modeDiscard, opts...)
+}
+
+// NewChargenHandler creates the handler sending the character stream,
+// each datagram of UDP is answered by a line of the characters.
+func NewChargenHandler(opts ...handler.Option) handler.Handler {
+	return newHandler(modeChargen, opts...)
+}
+
+func newHandler(mode string, opts ...handler.Option) handler.Handler {
+	options := handler.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &echoHandler{
+		mode:    mode,
+		options: options,
+	}
+}
+
+func (h *echoHandler) Init(md md.Metadata) (err error) {
+	return h.parseMetadata(md)
+}
+
+func (h *echoHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.HandleOption) error {
+	defer conn.Close()
+
+	start := time.Now()
+	log := h.options.Logger.WithFields(map[string]any{
+		"remote": conn.RemoteAddr().String(),
+		"local":  conn.LocalAddr().String(),
+		"mode":   h.mode,
+	})
+	log.Infof("%s <> %s", conn.RemoteAddr(), conn.LocalAddr())
+
+	var n int64
+	var err error
+	switch h.mode {
+	case modeDiscard:
+		n, err = h.discard(conn)
+	case modeChargen:
+		n, err = h.chargen(conn)
+	default:
+		n, err = h.echo(conn)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		err = nil
+	}
+
+	log.WithFields(map[string]any{
+		"duration": time.Since(start),
+		"bytes":    n,
+	}).Infof("%s >< %s", conn.RemoteAddr(), conn.LocalAddr())
+	if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (h *echoHandler) read(conn net.Conn, b []byte) (int, error) {
+	if h.md.timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(h.md.timeout))
+	}
+	return conn.Read(b)
+}
+
+// echo writes back the data or the datagrams read from conn.
+func (h *echoHandler) echo(conn net.Conn) (total int64, err error) {
+	b := bufpool.Get(h.md.bufferSize)
+	defer bufpool.Put(b)
+
+	for {
+		n, err := h.read(conn, b)
+		if n > 0 {
+			if _, err := conn.Write(b[:n]); err != nil {
+				return total, err
+			}
+			total += int64(n)
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+func (h *echoHandler) discard(conn net.Conn) (total int64, err error) {
+	b := bufpool.Get(h.md.bufferSize)
+	defer bufpool.Put(b)
+
+	for {
+		n, err := h.read(conn, b)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// chargen writes the lines of the rotating printable characters, the stream is written continuously for TCP,
+// and a line is written for each datagram received for UDP.
+func (h *echoHandler) chargen(conn net.Conn) (total int64, err error) {
+	g := &generator{}
+
+	if !isUDP(conn) {
+		// the data sent by the client is discarded.
+		go io.Copy(io.Discard, conn)
+
+		b := bufpool.Get(h.md.bufferSize)
+		defer bufpool.Put(b)
+		for {
+			g.Read(b)
+			if h.md.timeout > 0 {
+				conn.SetWriteDeadline(time.Now().Add(h.md.timeout))
+			}
+			n, err := conn.Write(b)
+			total += int64(n)
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+
+	b := bufpool.Get(h.md.bufferSize)
+	defer bufpool.Put(b)
+	line := make([]byte, lineSize)
+	for {
+		if _, err := h.read(conn, b); err != nil {
+			return total, err
+		}
+		g.Read(line)
+		n, err := conn.Write(line)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+func isUDP(conn net.Conn) bool {
+	if _, ok := conn.(net.PacketConn); ok {
+		return true
+	}
+	if addr := conn.LocalAddr(); addr != nil {
+		return addr.Network() == "udp" || addr.Network() == "udp4" || addr.Network() == "udp6"
+	}
+	return false
+}
+
+const (
+	// the 72 characters and CRLF of each line.
+	lineChars = 72
+	lineSize  = lineChars + 2
+	// the printable ASCII characters.
+	firstChar = ' '
+	numChars  = '~' - ' ' + 1
+)
+
+// generator generates the character stream of RFC 864,
+// each line starts at the character next to the start of the previous line.
+type generator struct {
+	line int
+	pos  int
+}
+
+func (g *generator) Read(b []byte) (int, error) {
+	for i := range b {
+		switch g.pos {
+		case lineChars:
+			b[i] = '\r'
+		case lineChars + 1:
+			b[i] = '\n'
+		default:
+			b[i] = byte(firstChar + (g.line+g.pos)%numChars)
+		}
+		if g.pos++; g.pos == lineSize {
+			g.pos = 0
+			g.line = (g.line + 1) % numChars
+		}
+	}
+	return len(b), nil
+}
@@ -0,0 +1,32 @@
+package echo
+
+import (
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	defaultBufferSize = 64 * 1024
+)
+
+type metadata struct {
+	// the connection is closed if it is idle for the timeout.
+	timeout    time.Duration
+	bufferSize int
+}
+
+func (h *echoHandler) parseMetadata(md mdata.Metadata) (err error) {
+	const (
+		timeout    = "timeout"
+		bufferSize = "bufferSize"
+	)
+
+	h.md.timeout = mdutil.GetDuration(md, timeout)
+	h.md.bufferSize = mdutil.GetInt(md, bufferSize)
+	if h.md.bufferSize <= 0 {
+		h.md.bufferSize = defaultBufferSize
+	}
+	return
+}
@@ -0,0 +1,36 @@
+package file
+
+import (
+	"net/http"
+	"os"
+	"path"
+)
+
+// noListingFS is the file system without the directory listing,
+// the directories without the index.html are not found.
+type noListingFS struct {
+	fs http.FileSystem
+}
+
+func (fs noListingFS) Open(name string) (http.File, error) {
+	f, err := fs.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.IsDir() {
+		index, err := fs.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			f.Close()
+			return nil, os.ErrNotExist
+		}
+		index.Close()
+	}
+
+	return f, nil
+}
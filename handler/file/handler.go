@@ -2,6 +2,7 @@ package file
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"sync"
@@ -40,7 +41,11 @@ func (h *fileHandler) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	h.handler = http.FileServer(http.Dir(h.md.dir))
+	var fs http.FileSystem = http.Dir(h.md.dir)
+	if !h.md.listing {
+		fs = noListingFS{fs: fs}
+	}
+	h.handler = http.FileServer(fs)
 	h.server = &http.Server{
 		Handler: http.HandlerFunc(h.handleFunc),
 	}
@@ -70,16 +75,27 @@ func (h *fileHandler) Close() error {
 }
 
 func (h *fileHandler) handleFunc(w http.ResponseWriter, r *http.Request) {
+	log := h.options.Logger
+
 	if auther := h.options.Auther; auther != nil {
 		u, p, _ := r.BasicAuth()
-		if _, ok := auther.Authenticate(r.Context(), u, p); !ok {
-			w.Header().Set("WWW-Authenticate", "Basic")
+		id, ok := auther.Authenticate(r.Context(), u, p)
+		if !ok {
+			realm := defaultRealm
+			if h.md.authBasicRealm != "" {
+				realm = h.md.authBasicRealm
+			}
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=\"%s\"", realm))
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+		if id != "" {
+			log = log.WithFields(map[string]any{
+				"client": id,
+			})
+		}
 	}
 
-	log := h.options.Logger
 	start := time.Now()
 
 	h.handler.ServeHTTP(w, r)
@@ -5,11 +5,24 @@ import (
 	mdutil "github.com/go-gost/core/metadata/util"
 )
 
+const (
+	defaultRealm = "gost"
+)
+
 type metadata struct {
-	dir string
+	dir            string
+	listing        bool
+	authBasicRealm string
 }
 
 func (h *fileHandler) parseMetadata(md mdata.Metadata) (err error) {
 	h.md.dir = mdutil.GetString(md, "file.dir", "dir")
+
+	// the directory listing is enabled by default.
+	h.md.listing = true
+	if md.IsExists("file.listing") {
+		h.md.listing = mdutil.GetBool(md, "file.listing")
+	}
+	h.md.authBasicRealm = mdutil.GetString(md, "authBasicRealm")
 	return
 }
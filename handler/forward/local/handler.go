@@ -3,15 +3,12 @@ package local
 import (
 	"bufio"
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-gost/core/bypass"
@@ -20,12 +17,14 @@ import (
 	"github.com/go-gost/core/hop"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
-	"github.com/go-gost/x/config"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
+	xhop "github.com/go-gost/x/hop"
 	xio "github.com/go-gost/x/internal/io"
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/util/forward"
-	tls_util "github.com/go-gost/x/internal/util/tls"
+	"github.com/go-gost/x/internal/util/ftp"
+	"github.com/go-gost/x/internal/util/sip"
 	"github.com/go-gost/x/registry"
 )
 
@@ -88,7 +87,7 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	network := "tcp"
@@ -109,9 +108,28 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 			conn.SetReadDeadline(time.Time{})
 		}
 	}
+	if network == "udp" && h.md.sniffingUDP {
+		if h.md.sniffingTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(h.md.sniffingTimeout))
+		}
+		conn, host, protocol, _ = forward.SniffingDatagram(ctx, conn)
+		rw = conn
+		log.Debugf("sniffing: host=%s, protocol=%s", host, protocol)
+		if h.md.sniffingTimeout > 0 {
+			conn.SetReadDeadline(time.Time{})
+		}
+	}
+
+	if values := ctxvalue.ValuesFromContext(ctx); values != nil && protocol != "" {
+		values.SetHost(host)
+		values.SetProtocol(protocol)
+	}
 
 	if protocol == forward.ProtoHTTP {
-		h.handleHTTP(ctx, rw, conn.RemoteAddr(), log)
+		if h.md.clientCert != nil {
+			ctx = ctxvalue.ContextWithTLSState(ctx, forward.TLSState(conn))
+		}
+		h.handleHTTP(ctx, rw, conn.RemoteAddr(), conn.LocalAddr(), log)
 		return nil
 	}
 
@@ -125,6 +143,10 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 			Addr: host,
 		}
 	}
+	switch h.md.hash {
+	case "host":
+		ctx = ctxvalue.ContextWithHash(ctx, &ctxvalue.Hash{Source: host})
+	}
 	if h.hop != nil {
 		target = h.hop.Select(ctx,
 			hop.HostSelectOption(host),
@@ -157,7 +179,13 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 
 	log.Debugf("%s >> %s", conn.RemoteAddr(), addr)
 
-	cc, err := h.router.Dial(ctx, network, addr)
+	var cc net.Conn
+	var err error
+	if network == "udp" {
+		cc, err = forward.DialUDP(ctx, forward.NodeRouter(h.router, target), h.md.nat, addr)
+	} else {
+		cc, err = forward.NodeRouter(h.router, target).Dial(ctx, network, addr)
+	}
 	if err != nil {
 		log.Error(err)
 		// TODO: the router itself may be failed due to the failed node in the router,
@@ -171,10 +199,42 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 	if marker := target.Marker(); marker != nil {
 		marker.Reset()
 	}
+	if t, ok := h.hop.(xhop.ConnTracker); ok {
+		defer t.TrackConn(target, cc)()
+	}
+
+	if network == "udp" && h.md.replicate != "" {
+		replicas := h.dialReplicas(ctx, target, log)
+		for _, rc := range replicas {
+			defer rc.Close()
+		}
+		cc = forward.ReplicaConn(cc, replicas, h.md.replicateRatio)
+	}
+
+	cc = forward.ProxyProtocolConn(cc, target, 0, conn.RemoteAddr(), conn.LocalAddr())
+	// the TLS of the client is relayed as is.
+	if protocol != forward.ProtoTLS {
+		cc = forward.TLSConn(cc, target)
+	}
 
 	t := time.Now()
 	log.Infof("%s <-> %s", conn.RemoteAddr(), target.Addr)
-	xnet.Transport(rw, cc)
+	switch {
+	case h.md.ftp && network == "tcp" && !h.md.sniffing:
+		router := forward.NodeRouter(h.router, target)
+		ftp.Relay(ctx, conn, cc, addr, router.Dial,
+			ftp.TimeoutOption(h.md.ftpTimeout),
+			ftp.LoggerOption(log),
+		)
+	case h.md.sip && network == "udp":
+		sip.Relay(ctx, conn, cc,
+			sip.AdvertiseOption(h.md.sipAdvertise),
+			sip.MediaTimeoutOption(h.md.sipMediaTimeout),
+			sip.LoggerOption(log),
+		)
+	default:
+		xnet.Transport(rw, cc)
+	}
 	log.WithFields(map[string]any{
 		"duration": time.Since(t),
 	}).Infof("%s >-< %s", conn.RemoteAddr(), target.Addr)
@@ -182,8 +242,10 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 	return nil
 }
 
-func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remoteAddr net.Addr, log logger.Logger) (err error) {
+func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remoteAddr net.Addr, localAddr net.Addr, log logger.Logger) (err error) {
 	br := bufio.NewReader(rw)
+	peerAddr := remoteAddr
+	clientCert := h.md.clientCert.Value(ctxvalue.TLSStateFromContext(ctx))
 
 	var cc net.Conn
 	for {
@@ -212,11 +274,14 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 			}
 			if bp := h.options.Bypass; bp != nil && bp.Contains(ctx, "tcp", host, bypass.WithPathOption(req.RequestURI)) {
 				log.Debugf("bypass: %s %s", host, req.RequestURI)
+				if res := h.md.block.Response(req, host); res != nil {
+					return res.Write(rw)
+				}
 				resp.StatusCode = http.StatusForbidden
 				return resp.Write(rw)
 			}
 
-			if addr := getRealClientAddr(req, remoteAddr); addr != remoteAddr {
+			if addr := h.md.forwarded.ClientAddr(req, peerAddr); addr != remoteAddr {
 				log = log.WithFields(map[string]any{
 					"src": addr.String(),
 				})
@@ -227,6 +292,10 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 			target := &chain.Node{
 				Addr: req.Host,
 			}
+			switch h.md.hash {
+			case "host":
+				ctx = ctxvalue.ContextWithHash(ctx, &ctxvalue.Hash{Source: req.Host})
+			}
 			if h.hop != nil {
 				target = h.hop.Select(ctx,
 					hop.HostSelectOption(req.Host),
@@ -277,7 +346,34 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 				}
 			}
 
-			cc, err = h.router.Dial(ctx, "tcp", target.Addr)
+			acceptEncoding := req.Header.Get("Accept-Encoding")
+			h.md.forwarded.Apply(req, peerAddr)
+			h.md.clientCert.Apply(req, clientCert)
+
+			res, passthrough := h.md.bodyLimit.Request(req)
+			if res != nil {
+				log.Warnf("request body of %d bytes exceeds the limit", req.ContentLength)
+				res.Write(rw)
+				return forward.ErrBodyTooLarge
+			}
+			if !passthrough {
+				if p := h.md.httpProcessor; p != nil {
+					if res, err := p.ProcessRequest(ctx, req, remoteAddr.String()); res != nil {
+						log.Warnf("httpproc: %v", err)
+						return res.Write(rw)
+					}
+				}
+				res, err := h.md.icap.ReqMod(ctx, req)
+				if err != nil {
+					log.Warnf("icap: %v", err)
+				}
+				if res != nil {
+					return res.Write(rw)
+				}
+				h.md.bodyRewriter.PrepareRequest(req)
+			}
+
+			cc, err = forward.NodeRouter(h.router, target).Dial(ctx, "tcp", target.Addr)
 			if err != nil {
 				// TODO: the router itself may be failed due to the failed node in the router,
 				// the dead marker may be a wrong operation.
@@ -293,18 +389,8 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 
 			log.Debugf("connection to node %s(%s)", target.Name, target.Addr)
 
-			if tlsSettings := target.Options().TLS; tlsSettings != nil {
-				cfg := &tls.Config{
-					ServerName:         tlsSettings.ServerName,
-					InsecureSkipVerify: !tlsSettings.Secure,
-				}
-				tls_util.SetTLSOptions(cfg, &config.TLSOptions{
-					MinVersion:   tlsSettings.Options.MinVersion,
-					MaxVersion:   tlsSettings.Options.MaxVersion,
-					CipherSuites: tlsSettings.Options.CipherSuites,
-				})
-				cc = tls.Client(cc, cfg)
-			}
+			cc = forward.ProxyProtocolConn(cc, target, 0, remoteAddr, localAddr)
+			cc = forward.TLSConn(cc, target)
 
 			if err := req.Write(cc); err != nil {
 				cc.Close()
@@ -330,6 +416,25 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 					return
 				}
 
+				rejected, passthrough := h.md.bodyLimit.Response(res)
+				if rejected != nil {
+					log.Warnf("response body of %d bytes from node %s(%s) exceeds the limit", res.ContentLength, target.Name, target.Addr)
+					res = rejected
+				} else if !passthrough {
+					if p := h.md.httpProcessor; p != nil {
+						if res, err = p.ProcessResponse(ctx, res, remoteAddr.String()); err != nil {
+							log.Warnf("httpproc: %v", err)
+						}
+					}
+					if res, err = h.md.icap.RespMod(ctx, res); err != nil {
+						log.Warnf("icap: %v", err)
+					}
+					if err = h.md.bodyRewriter.Rewrite(res); err != nil {
+						log.Warnf("rewrite: %v", err)
+					}
+					h.md.compressor.Compress(res, acceptEncoding)
+				}
+
 				if log.IsLevelEnabled(logger.TraceLevel) {
 					dump, _ := httputil.DumpResponse(res, false)
 					log.Trace(string(dump))
@@ -366,33 +471,22 @@ func (h *forwardHandler) checkRateLimit(addr net.Addr) bool {
 	return true
 }
 
-func getRealClientAddr(req *http.Request, raddr net.Addr) net.Addr {
-	if req == nil {
-		return nil
+// dialReplicas dials the nodes of the hop other than the target, the datagrams to the target are replicated to them.
+func (h *forwardHandler) dialReplicas(ctx context.Context, target *chain.Node, log logger.Logger) (replicas []net.Conn) {
+	nl, ok := h.hop.(hop.NodeList)
+	if !ok {
+		return
 	}
-	// cloudflare CDN
-	sip := req.Header.Get("CF-Connecting-IP")
-	if sip == "" {
-		ss := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
-		if len(ss) > 0 && ss[0] != "" {
-			sip = ss[0]
+	for _, node := range nl.Nodes() {
+		if node == nil || node == target || (node.Name == target.Name && node.Addr == target.Addr) {
+			continue
 		}
+		c, err := forward.NodeRouter(h.router, node).Dial(ctx, "udp", node.Addr)
+		if err != nil {
+			log.Warnf("replica %s: %v", node.Addr, err)
+			continue
+		}
+		replicas = append(replicas, c)
 	}
-	if sip == "" {
-		sip = req.Header.Get("X-Real-Ip")
-	}
-
-	ip := net.ParseIP(sip)
-	if ip == nil {
-		return raddr
-	}
-
-	_, sp, _ := net.SplitHostPort(raddr.String())
-
-	port, _ := strconv.Atoi(sp)
-
-	return &net.TCPAddr{
-		IP:   ip,
-		Port: port,
-	}
+	return
 }
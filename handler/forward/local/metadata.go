@@ -1,26 +1,109 @@
 package local
 
 import (
+	"net"
 	"time"
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/compress"
+	"github.com/go-gost/x/internal/util/forward"
+	"github.com/go-gost/x/internal/util/forwarded"
+	"github.com/go-gost/x/internal/util/httpproc"
+	"github.com/go-gost/x/internal/util/icap"
+	"github.com/go-gost/x/internal/util/rewrite"
+)
+
+const (
+	defaultReplicateRatio = 0.1
 )
 
 type metadata struct {
 	readTimeout     time.Duration
 	sniffing        bool
 	sniffingTimeout time.Duration
+	sniffingUDP     bool
+	hash            string
+	nat             string
+	ftp             bool
+	ftpTimeout      time.Duration
+	sip             bool
+	sipAdvertise    net.IP
+	sipMediaTimeout time.Duration
+	replicate       string
+	replicateRatio  float64
+	httpProcessor   *httpproc.Processor
+	icap            *icap.Client
+	bodyLimit       *forward.BodyLimit
+	block           *forward.Block
+	bodyRewriter    *rewrite.BodyRewriter
+	compressor      *compress.Compressor
+	clientCert      *forward.ClientCert
+	forwarded       *forwarded.Policy
 }
 
 func (h *forwardHandler) parseMetadata(md mdata.Metadata) (err error) {
 	const (
 		readTimeout = "readTimeout"
 		sniffing    = "sniffing"
+		hash        = "hash"
+		nat         = "nat"
+		ftp         = "ftp"
+		sip         = "sip"
+		replicate   = "replicate"
 	)
 
 	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
 	h.md.sniffing = mdutil.GetBool(md, sniffing)
 	h.md.sniffingTimeout = mdutil.GetDuration(md, "sniffing.timeout")
+	h.md.sniffingUDP = mdutil.GetBool(md, "sniffing.udp")
+	h.md.hash = mdutil.GetString(md, hash)
+	h.md.nat = mdutil.GetString(md, nat)
+	h.md.ftp = mdutil.GetBool(md, ftp)
+	h.md.ftpTimeout = mdutil.GetDuration(md, "ftp.timeout")
+	h.md.sip = mdutil.GetBool(md, sip)
+	h.md.sipAdvertise = net.ParseIP(mdutil.GetString(md, "sip.advertise"))
+	h.md.sipMediaTimeout = mdutil.GetDuration(md, "sip.mediaTimeout")
+
+	switch h.md.replicate = mdutil.GetString(md, replicate); h.md.replicate {
+	case forward.ReplicateAll:
+		h.md.replicateRatio = 1
+	case forward.ReplicateSample:
+		h.md.replicateRatio = mdutil.GetFloat(md, "replicate.ratio")
+		if h.md.replicateRatio <= 0 {
+			h.md.replicateRatio = defaultReplicateRatio
+		}
+	}
+
+	if addr := mdutil.GetString(md, "httpProcessor"); addr != "" {
+		h.md.httpProcessor = httpproc.NewProcessor(addr,
+			httpproc.TimeoutOption(mdutil.GetDuration(md, "httpProcessor.timeout")),
+			httpproc.MaxBodySizeOption(int64(mdutil.GetInt(md, "httpProcessor.maxBodySize"))),
+			httpproc.FailOpenOption(mdutil.GetBool(md, "httpProcessor.failOpen")),
+		)
+	}
+
+	h.md.bodyLimit = forward.ParseBodyLimit(md)
+	if h.md.block, err = forward.ParseBlock(md); err != nil {
+		return
+	}
+	h.md.clientCert = forward.ParseClientCert(md)
+	h.md.forwarded = forwarded.ParsePolicy(md, forwarded.ModeNone)
+	if h.md.bodyRewriter, err = rewrite.ParseBodyRewriter(md); err != nil {
+		return
+	}
+	h.md.compressor = compress.ParseCompressor(md)
+
+	if addr := mdutil.GetString(md, "icap"); addr != "" {
+		h.md.icap, err = icap.NewClient(addr,
+			icap.ModesOption(mdutil.GetStrings(md, "icap.modes")...),
+			icap.TimeoutOption(mdutil.GetDuration(md, "icap.timeout")),
+			icap.MaxBodySizeOption(int64(mdutil.GetInt(md, "icap.maxBodySize"))),
+			icap.BypassOption(mdutil.GetBool(md, "icap.bypass")),
+		)
+		if err != nil {
+			return
+		}
+	}
 	return
 }
@@ -3,7 +3,6 @@ package remote
 import (
 	"bufio"
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -11,7 +10,6 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-gost/core/bypass"
@@ -21,13 +19,12 @@ import (
 	"github.com/go-gost/core/logger"
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
-	"github.com/go-gost/x/config"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
+	xhop "github.com/go-gost/x/hop"
 	xio "github.com/go-gost/x/internal/io"
 	xnet "github.com/go-gost/x/internal/net"
-	"github.com/go-gost/x/internal/net/proxyproto"
 	"github.com/go-gost/x/internal/util/forward"
-	tls_util "github.com/go-gost/x/internal/util/tls"
 	"github.com/go-gost/x/registry"
 )
 
@@ -89,7 +86,7 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	network := "tcp"
@@ -112,7 +109,26 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 			conn.SetReadDeadline(time.Time{})
 		}
 	}
+	if network == "udp" && h.md.sniffingUDP {
+		if h.md.sniffingTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(h.md.sniffingTimeout))
+		}
+		conn, host, protocol, _ = forward.SniffingDatagram(ctx, conn)
+		rw = conn
+		log.Debugf("sniffing: host=%s, protocol=%s", host, protocol)
+		if h.md.sniffingTimeout > 0 {
+			conn.SetReadDeadline(time.Time{})
+		}
+	}
+	if values := ctxvalue.ValuesFromContext(ctx); values != nil && protocol != "" {
+		values.SetHost(host)
+		values.SetProtocol(protocol)
+	}
+
 	if protocol == forward.ProtoHTTP {
+		if h.md.clientCert != nil {
+			ctx = ctxvalue.ContextWithTLSState(ctx, forward.TLSState(conn))
+		}
 		h.handleHTTP(ctx, rw, conn.RemoteAddr(), localAddr, log)
 		return nil
 	}
@@ -128,6 +144,10 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 			Addr: host,
 		}
 	}
+	switch h.md.hash {
+	case "host":
+		ctx = ctxvalue.ContextWithHash(ctx, &ctxvalue.Hash{Source: host})
+	}
 	if h.hop != nil {
 		target = h.hop.Select(ctx,
 			hop.HostSelectOption(host),
@@ -156,7 +176,7 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 
 	log.Debugf("%s >> %s", conn.RemoteAddr(), target.Addr)
 
-	cc, err := h.router.Dial(ctx, network, target.Addr)
+	cc, err := forward.NodeRouter(h.router, target).Dial(ctx, network, target.Addr)
 	if err != nil {
 		log.Error(err)
 		// TODO: the router itself may be failed due to the failed node in the router,
@@ -170,8 +190,15 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 	if marker := target.Marker(); marker != nil {
 		marker.Reset()
 	}
+	if t, ok := h.hop.(xhop.ConnTracker); ok {
+		defer t.TrackConn(target, cc)()
+	}
 
-	cc = proxyproto.WrapClientConn(h.md.proxyProtocol, conn.RemoteAddr(), localAddr, cc)
+	cc = forward.ProxyProtocolConn(cc, target, h.md.proxyProtocol, conn.RemoteAddr(), localAddr)
+	// the TLS of the client is relayed as is.
+	if protocol != forward.ProtoTLS {
+		cc = forward.TLSConn(cc, target)
+	}
 
 	t := time.Now()
 	log.Infof("%s <-> %s", conn.RemoteAddr(), target.Addr)
@@ -185,6 +212,8 @@ func (h *forwardHandler) Handle(ctx context.Context, conn net.Conn, opts ...hand
 
 func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remoteAddr net.Addr, localAddr net.Addr, log logger.Logger) (err error) {
 	br := bufio.NewReader(rw)
+	peerAddr := remoteAddr
+	clientCert := h.md.clientCert.Value(ctxvalue.TLSStateFromContext(ctx))
 	var cc net.Conn
 
 	for {
@@ -213,11 +242,14 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 			}
 			if bp := h.options.Bypass; bp != nil && bp.Contains(ctx, "tcp", host, bypass.WithPathOption(req.RequestURI)) {
 				log.Debugf("bypass: %s %s", host, req.RequestURI)
+				if res := h.md.block.Response(req, host); res != nil {
+					return res.Write(rw)
+				}
 				resp.StatusCode = http.StatusForbidden
 				return resp.Write(rw)
 			}
 
-			if addr := getRealClientAddr(req, remoteAddr); addr != remoteAddr {
+			if addr := h.md.forwarded.ClientAddr(req, peerAddr); addr != remoteAddr {
 				log = log.WithFields(map[string]any{
 					"src": addr.String(),
 				})
@@ -228,6 +260,10 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 			target := &chain.Node{
 				Addr: req.Host,
 			}
+			switch h.md.hash {
+			case "host":
+				ctx = ctxvalue.ContextWithHash(ctx, &ctxvalue.Hash{Source: req.Host})
+			}
 			if h.hop != nil {
 				target = h.hop.Select(ctx,
 					hop.HostSelectOption(req.Host),
@@ -277,7 +313,34 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 				}
 			}
 
-			cc, err = h.router.Dial(ctx, "tcp", target.Addr)
+			acceptEncoding := req.Header.Get("Accept-Encoding")
+			h.md.forwarded.Apply(req, peerAddr)
+			h.md.clientCert.Apply(req, clientCert)
+
+			res, passthrough := h.md.bodyLimit.Request(req)
+			if res != nil {
+				log.Warnf("request body of %d bytes exceeds the limit", req.ContentLength)
+				res.Write(rw)
+				return forward.ErrBodyTooLarge
+			}
+			if !passthrough {
+				if p := h.md.httpProcessor; p != nil {
+					if res, err := p.ProcessRequest(ctx, req, remoteAddr.String()); res != nil {
+						log.Warnf("httpproc: %v", err)
+						return res.Write(rw)
+					}
+				}
+				res, err := h.md.icap.ReqMod(ctx, req)
+				if err != nil {
+					log.Warnf("icap: %v", err)
+				}
+				if res != nil {
+					return res.Write(rw)
+				}
+				h.md.bodyRewriter.PrepareRequest(req)
+			}
+
+			cc, err = forward.NodeRouter(h.router, target).Dial(ctx, "tcp", target.Addr)
 			if err != nil {
 				// TODO: the router itself may be failed due to the failed node in the router,
 				// the dead marker may be a wrong operation.
@@ -293,20 +356,8 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 
 			log.Debugf("new connection to node %s(%s)", target.Name, target.Addr)
 
-			if tlsSettings := target.Options().TLS; tlsSettings != nil {
-				cfg := &tls.Config{
-					ServerName:         tlsSettings.ServerName,
-					InsecureSkipVerify: !tlsSettings.Secure,
-				}
-				tls_util.SetTLSOptions(cfg, &config.TLSOptions{
-					MinVersion:   tlsSettings.Options.MinVersion,
-					MaxVersion:   tlsSettings.Options.MaxVersion,
-					CipherSuites: tlsSettings.Options.CipherSuites,
-				})
-				cc = tls.Client(cc, cfg)
-			}
-
-			cc = proxyproto.WrapClientConn(h.md.proxyProtocol, remoteAddr, localAddr, cc)
+			cc = forward.ProxyProtocolConn(cc, target, h.md.proxyProtocol, remoteAddr, localAddr)
+			cc = forward.TLSConn(cc, target)
 
 			if err := req.Write(cc); err != nil {
 				cc.Close()
@@ -332,6 +383,25 @@ func (h *forwardHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, remot
 					return
 				}
 
+				rejected, passthrough := h.md.bodyLimit.Response(res)
+				if rejected != nil {
+					log.Warnf("response body of %d bytes from node %s(%s) exceeds the limit", res.ContentLength, target.Name, target.Addr)
+					res = rejected
+				} else if !passthrough {
+					if p := h.md.httpProcessor; p != nil {
+						if res, err = p.ProcessResponse(ctx, res, remoteAddr.String()); err != nil {
+							log.Warnf("httpproc: %v", err)
+						}
+					}
+					if res, err = h.md.icap.RespMod(ctx, res); err != nil {
+						log.Warnf("icap: %v", err)
+					}
+					if err = h.md.bodyRewriter.Rewrite(res); err != nil {
+						log.Warnf("rewrite: %v", err)
+					}
+					h.md.compressor.Compress(res, acceptEncoding)
+				}
+
 				if log.IsLevelEnabled(logger.TraceLevel) {
 					dump, _ := httputil.DumpResponse(res, false)
 					log.Trace(string(dump))
@@ -391,34 +461,3 @@ func convertAddr(addr net.Addr) net.Addr {
 		}
 	}
 }
-
-func getRealClientAddr(req *http.Request, raddr net.Addr) net.Addr {
-	if req == nil {
-		return nil
-	}
-	// cloudflare CDN
-	sip := req.Header.Get("CF-Connecting-IP")
-	if sip == "" {
-		ss := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
-		if len(ss) > 0 && ss[0] != "" {
-			sip = ss[0]
-		}
-	}
-	if sip == "" {
-		sip = req.Header.Get("X-Real-Ip")
-	}
-
-	ip := net.ParseIP(sip)
-	if ip == nil {
-		return raddr
-	}
-
-	_, sp, _ := net.SplitHostPort(raddr.String())
-
-	port, _ := strconv.Atoi(sp)
-
-	return &net.TCPAddr{
-		IP:   ip,
-		Port: port,
-	}
-}
@@ -5,12 +5,28 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/compress"
+	"github.com/go-gost/x/internal/util/forward"
+	"github.com/go-gost/x/internal/util/forwarded"
+	"github.com/go-gost/x/internal/util/httpproc"
+	"github.com/go-gost/x/internal/util/icap"
+	"github.com/go-gost/x/internal/util/rewrite"
 )
 
 type metadata struct {
 	readTimeout     time.Duration
 	sniffing        bool
 	sniffingTimeout time.Duration
+	sniffingUDP     bool
+	hash            string
+	httpProcessor   *httpproc.Processor
+	icap            *icap.Client
+	bodyLimit       *forward.BodyLimit
+	block           *forward.Block
+	bodyRewriter    *rewrite.BodyRewriter
+	compressor      *compress.Compressor
+	clientCert      *forward.ClientCert
+	forwarded       *forwarded.Policy
 	proxyProtocol   int
 }
 
@@ -18,12 +34,46 @@ func (h *forwardHandler) parseMetadata(md mdata.Metadata) (err error) {
 	const (
 		readTimeout   = "readTimeout"
 		sniffing      = "sniffing"
+		hash          = "hash"
 		proxyProtocol = "proxyProtocol"
 	)
 
 	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
 	h.md.sniffing = mdutil.GetBool(md, sniffing)
 	h.md.sniffingTimeout = mdutil.GetDuration(md, "sniffing.timeout")
+	h.md.sniffingUDP = mdutil.GetBool(md, "sniffing.udp")
+	h.md.hash = mdutil.GetString(md, hash)
+
+	if addr := mdutil.GetString(md, "httpProcessor"); addr != "" {
+		h.md.httpProcessor = httpproc.NewProcessor(addr,
+			httpproc.TimeoutOption(mdutil.GetDuration(md, "httpProcessor.timeout")),
+			httpproc.MaxBodySizeOption(int64(mdutil.GetInt(md, "httpProcessor.maxBodySize"))),
+			httpproc.FailOpenOption(mdutil.GetBool(md, "httpProcessor.failOpen")),
+		)
+	}
+
+	h.md.bodyLimit = forward.ParseBodyLimit(md)
+	if h.md.block, err = forward.ParseBlock(md); err != nil {
+		return
+	}
+	h.md.clientCert = forward.ParseClientCert(md)
+	h.md.forwarded = forwarded.ParsePolicy(md, forwarded.ModeNone)
+	if h.md.bodyRewriter, err = rewrite.ParseBodyRewriter(md); err != nil {
+		return
+	}
+	h.md.compressor = compress.ParseCompressor(md)
+
+	if addr := mdutil.GetString(md, "icap"); addr != "" {
+		h.md.icap, err = icap.NewClient(addr,
+			icap.ModesOption(mdutil.GetStrings(md, "icap.modes")...),
+			icap.TimeoutOption(mdutil.GetDuration(md, "icap.timeout")),
+			icap.MaxBodySizeOption(int64(mdutil.GetInt(md, "icap.maxBodySize"))),
+			icap.BypassOption(mdutil.GetBool(md, "icap.bypass")),
+		)
+		if err != nil {
+			return
+		}
+	}
 	h.md.proxyProtocol = mdutil.GetInt(md, proxyProtocol)
 	return
 }
@@ -23,9 +23,12 @@ import (
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	netpkg "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/classify"
 	stats_util "github.com/go-gost/x/internal/util/stats"
 	traffic_wrapper "github.com/go-gost/x/limiter/traffic/wrapper"
+	mdx "github.com/go-gost/x/metadata"
 	"github.com/go-gost/x/registry"
 	"github.com/go-gost/x/stats"
 	stats_wrapper "github.com/go-gost/x/stats/wrapper"
@@ -33,6 +36,9 @@ import (
 
 func init() {
 	registry.HandlerRegistry().Register("http", NewHandler)
+	mdx.RegisterDefaults(mdx.KindHandler, "http",
+		mdx.Default{Keys: []string{"authBasicRealm"}, Value: defaultRealm},
+	)
 }
 
 type httpHandler struct {
@@ -94,8 +100,15 @@ func (h *httpHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
+	}
+
+	done, ok := h.md.handshake.Begin(conn)
+	if !ok {
+		log.Warnf("handshake: too many handshakes from %s", conn.RemoteAddr())
+		return xerrors.ErrHandshakeLimit
 	}
+	defer done()
 
 	req, err := http.ReadRequest(bufio.NewReader(conn))
 	if err != nil {
@@ -103,6 +116,7 @@ func (h *httpHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler
 		return err
 	}
 	defer req.Body.Close()
+	done()
 
 	return h.handleRequest(ctx, conn, req, log)
 }
@@ -170,7 +184,7 @@ func (h *httpHandler) handleRequest(ctx context.Context, conn net.Conn, req *htt
 
 	clientID, ok := h.authenticate(ctx, conn, req, resp, log)
 	if !ok {
-		return nil
+		return xerrors.ErrAuth
 	}
 	ctx = ctxvalue.ContextWithClientID(ctx, ctxvalue.ClientID(clientID))
 
@@ -183,7 +197,8 @@ func (h *httpHandler) handleRequest(ctx context.Context, conn net.Conn, req *htt
 		}
 		log.Debug("bypass: ", addr)
 
-		return resp.Write(conn)
+		resp.Write(conn)
+		return xerrors.ErrBypass
 	}
 
 	if network == "udp" {
@@ -222,6 +237,10 @@ func (h *httpHandler) handleRequest(ctx context.Context, conn net.Conn, req *htt
 	}
 	defer cc.Close()
 
+	if h.md.blockBitTorrent {
+		cc = classify.BlockBitTorrent(cc, h.options.Service, log)
+	}
+
 	if req.Method == http.MethodConnect {
 		resp.StatusCode = http.StatusOK
 		resp.Status = "200 Connection established"
@@ -236,6 +255,7 @@ func (h *httpHandler) handleRequest(ctx context.Context, conn net.Conn, req *htt
 		}
 	} else {
 		req.Header.Del("Proxy-Connection")
+		h.md.forwarded.Apply(req, conn.RemoteAddr())
 		if err = req.Write(cc); err != nil {
 			log.Error(err)
 			return err
@@ -6,6 +6,8 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/forwarded"
+	"github.com/go-gost/x/internal/util/handshake"
 )
 
 const (
@@ -18,6 +20,11 @@ type metadata struct {
 	header          http.Header
 	hash            string
 	authBasicRealm  string
+	forwarded       *forwarded.Policy
+	handshake       *handshake.Handshake
+	// blockBitTorrent refuses the BitTorrent handshakes and tracker announces,
+	// and drops the DHT and UDP tracker datagrams.
+	blockBitTorrent bool
 }
 
 func (h *httpHandler) parseMetadata(md mdata.Metadata) error {
@@ -29,6 +36,7 @@ func (h *httpHandler) parseMetadata(md mdata.Metadata) error {
 		enableUDP       = "udp"
 		hash            = "hash"
 		authBasicRealm  = "authBasicRealm"
+		blockBitTorrent = "blockBitTorrent"
 	)
 
 	if m := mdutil.GetStringMapString(md, header); len(m) > 0 {
@@ -55,6 +63,9 @@ func (h *httpHandler) parseMetadata(md mdata.Metadata) error {
 	h.md.enableUDP = mdutil.GetBool(md, enableUDP)
 	h.md.hash = mdutil.GetString(md, hash)
 	h.md.authBasicRealm = mdutil.GetString(md, authBasicRealm)
+	h.md.blockBitTorrent = mdutil.GetBool(md, blockBitTorrent)
+	h.md.forwarded = forwarded.ParsePolicy(md, forwarded.ModeNone)
+	h.md.handshake = handshake.Parse(md)
 
 	return nil
 }
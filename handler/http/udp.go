@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/x/internal/net/udp"
+	"github.com/go-gost/x/internal/util/classify"
 	"github.com/go-gost/x/internal/util/socks"
 )
 
@@ -64,6 +65,9 @@ func (h *httpHandler) handleUDP(ctx context.Context, conn net.Conn, log logger.L
 		log.Error(err)
 		return err
 	}
+	if h.md.blockBitTorrent {
+		pc = classify.BlockBitTorrentPacketConn(pc, h.options.Service, log)
+	}
 
 	relay := udp.NewRelay(socks.UDPTunServerConn(conn), pc).
 		WithBypass(h.options.Bypass).
@@ -24,10 +24,12 @@ import (
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	xio "github.com/go-gost/x/internal/io"
 	netpkg "github.com/go-gost/x/internal/net"
 	stats_util "github.com/go-gost/x/internal/util/stats"
 	"github.com/go-gost/x/limiter/traffic/wrapper"
+	mdx "github.com/go-gost/x/metadata"
 	"github.com/go-gost/x/registry"
 	"github.com/go-gost/x/stats"
 	stats_wrapper "github.com/go-gost/x/stats/wrapper"
@@ -35,6 +37,9 @@ import (
 
 func init() {
 	registry.HandlerRegistry().Register("http2", NewHandler)
+	mdx.RegisterDefaults(mdx.KindHandler, "http2",
+		mdx.Default{Keys: []string{"authBasicRealm"}, Value: defaultRealm},
+	)
 }
 
 type http2Handler struct {
@@ -92,7 +97,7 @@ func (h *http2Handler) Handle(ctx context.Context, conn net.Conn, opts ...handle
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	v, ok := conn.(md.Metadatable)
@@ -169,14 +174,14 @@ func (h *http2Handler) roundTrip(ctx context.Context, w http.ResponseWriter, req
 
 	clientID, ok := h.authenticate(ctx, w, req, resp, log)
 	if !ok {
-		return nil
+		return xerrors.ErrAuth
 	}
 	ctx = ctxvalue.ContextWithClientID(ctx, ctxvalue.ClientID(clientID))
 
 	if h.options.Bypass != nil && h.options.Bypass.Contains(ctx, "tcp", addr) {
 		w.WriteHeader(http.StatusForbidden)
 		log.Debug("bypass: ", addr)
-		return nil
+		return xerrors.ErrBypass
 	}
 
 	// delete the proxy related headers.
@@ -378,6 +383,9 @@ func (h *http2Handler) authenticate(ctx context.Context, w http.ResponseWriter,
 	return
 }
 func (h *http2Handler) forwardRequest(w http.ResponseWriter, r *http.Request, rw io.ReadWriter) (err error) {
+	if raddr, _ := net.ResolveTCPAddr("tcp", r.RemoteAddr); raddr != nil {
+		h.md.forwarded.Apply(r, raddr)
+	}
 	if err = r.Write(rw); err != nil {
 		return
 	}
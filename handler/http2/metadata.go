@@ -6,6 +6,7 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/forwarded"
 )
 
 const (
@@ -17,6 +18,7 @@ type metadata struct {
 	header          http.Header
 	hash            string
 	authBasicRealm  string
+	forwarded       *forwarded.Policy
 }
 
 func (h *http2Handler) parseMetadata(md mdata.Metadata) error {
@@ -52,6 +54,7 @@ func (h *http2Handler) parseMetadata(md mdata.Metadata) error {
 	}
 	h.md.hash = mdutil.GetString(md, hash)
 	h.md.authBasicRealm = mdutil.GetString(md, authBasicRealm)
+	h.md.forwarded = forwarded.ParsePolicy(md, forwarded.ModeNone)
 
 	return nil
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	"github.com/go-gost/x/registry"
 )
 
@@ -74,7 +75,7 @@ func (h *http3Handler) Handle(ctx context.Context, conn net.Conn, opts ...handle
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	v, ok := conn.(md.Metadatable)
@@ -0,0 +1,204 @@
+package ingress
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/handler"
+	"github.com/go-gost/core/logger"
+	mdata "github.com/go-gost/core/metadata"
+	xerrors "github.com/go-gost/x/errors"
+	xio "github.com/go-gost/x/internal/io"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/forward"
+	"github.com/go-gost/x/registry"
+)
+
+var (
+	ErrNoIngress = errors.New("ingress: ingress is required")
+)
+
+func init() {
+	registry.HandlerRegistry().Register("ingress", NewHandler)
+}
+
+// ingressHandler is a minimal L7 ingress, the HTTP requests are routed
+// to the endpoints of the matched ingress rules through the chains of the rules.
+// The TLS is terminated by the listener (e.g. a tls listener with ACME certificates).
+type ingressHandler struct {
+	router  *chain.Router
+	md      metadata
+	options handler.Options
+}
+
+func NewHandler(opts ...handler.Option) handler.Handler {
+	options := handler.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &ingressHandler{
+		options: options,
+	}
+}
+
+func (h *ingressHandler) Init(md mdata.Metadata) (err error) {
+	if err = h.parseMetadata(md); err != nil {
+		return
+	}
+	if h.md.ingress == nil {
+		return ErrNoIngress
+	}
+
+	h.router = h.options.Router
+	if h.router == nil {
+		h.router = chain.NewRouter(chain.LoggerRouterOption(h.options.Logger))
+	}
+
+	return
+}
+
+func (h *ingressHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.HandleOption) error {
+	defer conn.Close()
+
+	start := time.Now()
+	log := h.options.Logger.WithFields(map[string]any{
+		"remote": conn.RemoteAddr().String(),
+		"local":  conn.LocalAddr().String(),
+	})
+
+	log.Infof("%s <> %s", conn.RemoteAddr(), conn.LocalAddr())
+	defer func() {
+		log.WithFields(map[string]any{
+			"duration": time.Since(start),
+		}).Infof("%s >< %s", conn.RemoteAddr(), conn.LocalAddr())
+	}()
+
+	if !h.checkRateLimit(conn.RemoteAddr()) {
+		return xerrors.ErrRateLimit
+	}
+
+	br := bufio.NewReader(conn)
+	for {
+		if h.md.readTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(h.md.readTimeout))
+		}
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		conn.SetReadDeadline(time.Time{})
+
+		if err := h.handleRequest(ctx, xio.NewReadWriter(br, conn), conn.RemoteAddr(), req, log); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if req.Close {
+			return nil
+		}
+	}
+}
+
+func (h *ingressHandler) handleRequest(ctx context.Context, rw io.ReadWriter, remoteAddr net.Addr, req *http.Request, log logger.Logger) error {
+	if log.IsLevelEnabled(logger.TraceLevel) {
+		dump, _ := httputil.DumpRequest(req, false)
+		log.Trace(string(dump))
+	}
+
+	resp := &http.Response{
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		StatusCode: http.StatusServiceUnavailable,
+	}
+
+	rule := h.md.ingress.MatchHTTP(ctx, req)
+	if rule == nil {
+		log.Debugf("ingress: no rule for %s%s", req.Host, req.URL.Path)
+		resp.StatusCode = http.StatusNotFound
+		return resp.Write(rw)
+	}
+
+	addr := rule.Endpoint
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "80")
+	}
+
+	log = log.WithFields(map[string]any{
+		"host":  req.Host,
+		"dst":   addr,
+		"chain": rule.Chain,
+	})
+	log.Debugf("%s%s >> %s", req.Host, req.URL.Path, addr)
+
+	router := h.router
+	if rule.Chain != "" {
+		router = forward.ChainRouter(h.router, registry.ChainRegistry().Get(rule.Chain))
+	}
+	cc, err := router.Dial(ctx, "tcp", addr)
+	if err != nil {
+		log.Warnf("connect to %s: %v", addr, err)
+		resp.StatusCode = http.StatusBadGateway
+		return resp.Write(rw)
+	}
+	defer cc.Close()
+
+	acceptEncoding := req.Header.Get("Accept-Encoding")
+	h.md.forwarded.Apply(req, remoteAddr)
+	req.Header.Set("X-Forwarded-Host", req.Host)
+
+	if err := req.Write(cc); err != nil {
+		log.Warnf("send request to %s: %v", addr, err)
+		resp.StatusCode = http.StatusBadGateway
+		return resp.Write(rw)
+	}
+
+	if req.Header.Get("Upgrade") == "websocket" {
+		err := xnet.Transport(cc, rw)
+		if err == nil {
+			err = io.EOF
+		}
+		return err
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(cc), req)
+	if err != nil {
+		log.Warnf("read response from %s: %v", addr, err)
+		resp.StatusCode = http.StatusBadGateway
+		return resp.Write(rw)
+	}
+	defer res.Body.Close()
+
+	h.md.compressor.Compress(res, acceptEncoding)
+
+	if log.IsLevelEnabled(logger.TraceLevel) {
+		dump, _ := httputil.DumpResponse(res, false)
+		log.Trace(string(dump))
+	}
+
+	return res.Write(rw)
+}
+
+func (h *ingressHandler) checkRateLimit(addr net.Addr) bool {
+	if h.options.RateLimiter == nil {
+		return true
+	}
+	host, _, _ := net.SplitHostPort(addr.String())
+	if limiter := h.options.RateLimiter.Limiter(host); limiter != nil {
+		return limiter.Allow(1)
+	}
+
+	return true
+}
@@ -0,0 +1,35 @@
+package ingress
+
+import (
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	xingress "github.com/go-gost/x/ingress"
+	"github.com/go-gost/x/internal/util/compress"
+	"github.com/go-gost/x/internal/util/forwarded"
+	"github.com/go-gost/x/registry"
+)
+
+type metadata struct {
+	readTimeout time.Duration
+	ingress     xingress.HTTPMatcher
+	forwarded   *forwarded.Policy
+	compressor  *compress.Compressor
+}
+
+func (h *ingressHandler) parseMetadata(md mdata.Metadata) (err error) {
+	const (
+		readTimeout = "readTimeout"
+		ingress     = "ingress"
+	)
+
+	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
+	if v, _ := registry.IngressRegistry().Get(mdutil.GetString(md, ingress)).(xingress.HTTPMatcher); v != nil {
+		h.md.ingress = v
+	}
+	// the address of the client is appended to X-Forwarded-For by default.
+	h.md.forwarded = forwarded.ParsePolicy(md, forwarded.ModeAppend)
+	h.md.compressor = compress.ParseCompressor(md)
+	return
+}
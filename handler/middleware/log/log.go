@@ -0,0 +1,71 @@
+package log
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/handler"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/handler/middleware"
+	"github.com/go-gost/x/registry"
+)
+
+func init() {
+	registry.MiddlewareRegistry().Register("log", NewMiddleware)
+}
+
+type logMiddleware struct {
+	level   logger.LogLevel
+	options middleware.Options
+}
+
+// NewMiddleware creates a middleware logging the connections of the handler with the duration and the result.
+func NewMiddleware(opts ...middleware.Option) middleware.Middleware {
+	var options middleware.Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &logMiddleware{
+		options: options,
+	}
+}
+
+func (m *logMiddleware) Init(md metadata.Metadata) error {
+	m.level = logger.LogLevel(mdutil.GetString(md, "level"))
+	if m.level == "" {
+		m.level = logger.InfoLevel
+	}
+	if m.options.Logger == nil {
+		m.options.Logger = logger.Default()
+	}
+	return nil
+}
+
+func (m *logMiddleware) Handle(ctx context.Context, conn net.Conn, next handler.Handler, opts ...handler.HandleOption) error {
+	start := time.Now()
+	err := next.Handle(ctx, conn, opts...)
+
+	log := m.options.Logger.WithFields(map[string]any{
+		"remote":   conn.RemoteAddr().String(),
+		"local":    conn.LocalAddr().String(),
+		"duration": time.Since(start),
+	})
+	if err != nil {
+		log.Warnf("%s - %s: %v", conn.RemoteAddr(), conn.LocalAddr(), err)
+		return err
+	}
+
+	msg := "%s - %s"
+	switch m.level {
+	case logger.TraceLevel:
+		log.Tracef(msg, conn.RemoteAddr(), conn.LocalAddr())
+	case logger.DebugLevel:
+		log.Debugf(msg, conn.RemoteAddr(), conn.LocalAddr())
+	default:
+		log.Infof(msg, conn.RemoteAddr(), conn.LocalAddr())
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"github.com/go-gost/core/handler"
+	"github.com/go-gost/core/hop"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metadata"
+)
+
+// Middleware intercepts the connections of a handler,
+// it calls next to pass the connection on, or rejects it by returning without calling next.
+type Middleware interface {
+	Init(md metadata.Metadata) error
+	Handle(ctx context.Context, conn net.Conn, next handler.Handler, opts ...handler.HandleOption) error
+}
+
+type Options struct {
+	Service string
+	Logger  logger.Logger
+}
+
+type Option func(opts *Options)
+
+func ServiceOption(service string) Option {
+	return func(opts *Options) {
+		opts.Service = service
+	}
+}
+
+func LoggerOption(logger logger.Logger) Option {
+	return func(opts *Options) {
+		opts.Logger = logger
+	}
+}
+
+type chainHandler struct {
+	mw   Middleware
+	next handler.Handler
+}
+
+// Chain layers the middlewares onto the handler,
+// the first middleware sees the connection first.
+func Chain(h handler.Handler, mws ...Middleware) handler.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i] == nil {
+			continue
+		}
+		h = &chainHandler{
+			mw:   mws[i],
+			next: h,
+		}
+	}
+	return h
+}
+
+// Init does nothing, the handler and the middlewares are initialized before chained.
+func (h *chainHandler) Init(md metadata.Metadata) error {
+	return nil
+}
+
+func (h *chainHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.HandleOption) error {
+	return h.mw.Handle(ctx, conn, h.next, opts...)
+}
+
+// Forward implements handler.Forwarder.
+func (h *chainHandler) Forward(hop hop.Hop) {
+	if f, ok := h.next.(handler.Forwarder); ok {
+		f.Forward(hop)
+	}
+}
+
+// Close implements io.Closer interface.
+func (h *chainHandler) Close() error {
+	if closer, ok := h.mw.(io.Closer); ok {
+		closer.Close()
+	}
+	if closer, ok := h.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package timeout
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/handler"
+	"github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/handler/middleware"
+	"github.com/go-gost/x/registry"
+)
+
+func init() {
+	registry.MiddlewareRegistry().Register("timeout", NewMiddleware)
+}
+
+type timeoutMiddleware struct {
+	timeout time.Duration
+	options middleware.Options
+}
+
+// NewMiddleware creates a middleware limiting the lifetime of the connections of the handler,
+// a connection is closed by the deadline when it lasts longer than the timeout.
+func NewMiddleware(opts ...middleware.Option) middleware.Middleware {
+	var options middleware.Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &timeoutMiddleware{
+		options: options,
+	}
+}
+
+func (m *timeoutMiddleware) Init(md metadata.Metadata) error {
+	m.timeout = mdutil.GetDuration(md, "timeout")
+	return nil
+}
+
+func (m *timeoutMiddleware) Handle(ctx context.Context, conn net.Conn, next handler.Handler, opts ...handler.HandleOption) error {
+	if m.timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+
+		// the handlers may reset the deadline of the connection, so it is closed explicitly.
+		go func() {
+			<-ctx.Done()
+			if ctx.Err() == context.DeadlineExceeded {
+				conn.Close()
+			}
+		}()
+		return next.Handle(ctx, conn, opts...)
+	}
+	return next.Handle(ctx, conn, opts...)
+}
@@ -0,0 +1,114 @@
+package nfqueue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/bypass"
+	"github.com/go-gost/core/handler"
+	"github.com/go-gost/core/logger"
+	md "github.com/go-gost/core/metadata"
+	xio "github.com/go-gost/x/internal/io"
+	"github.com/go-gost/x/internal/util/forward"
+	"github.com/go-gost/x/internal/util/nfqueue"
+	"github.com/go-gost/x/registry"
+)
+
+func init() {
+	registry.HandlerRegistry().Register("nfqueue", NewHandler)
+}
+
+type nfqueueHandler struct {
+	md      metadata
+	options handler.Options
+}
+
+// NewHandler creates a handler giving verdicts to the packets of a netfilter queue,
+// the packets to the destinations in the bypass are dropped, others are accepted.
+func NewHandler(opts ...handler.Option) handler.Handler {
+	options := handler.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &nfqueueHandler{
+		options: options,
+	}
+}
+
+func (h *nfqueueHandler) Init(md md.Metadata) (err error) {
+	return h.parseMetadata(md)
+}
+
+func (h *nfqueueHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.HandleOption) error {
+	defer conn.Close()
+
+	log := h.options.Logger
+
+	pc, _ := conn.(nfqueue.PacketConn)
+	if pc == nil {
+		err := errors.New("nfqueue: wrong connection type")
+		log.Error(err)
+		return err
+	}
+
+	start := time.Now()
+	log = log.WithFields(map[string]any{
+		"local": conn.LocalAddr().String(),
+	})
+
+	log.Infof("queue %s", conn.LocalAddr())
+	defer func() {
+		log.WithFields(map[string]any{
+			"duration": time.Since(start),
+		}).Infof("queue %s closed", conn.LocalAddr())
+	}()
+
+	for {
+		p, err := pc.ReadPacket()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Error(err)
+			}
+			return err
+		}
+
+		verdict := h.verdict(ctx, p, log)
+		if err := pc.SetVerdict(p.ID, verdict); err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+}
+
+func (h *nfqueueHandler) verdict(ctx context.Context, p *nfqueue.Packet, log logger.Logger) nfqueue.Verdict {
+	hdr, err := nfqueue.ParseHeader(p.Payload)
+	if err != nil {
+		log.Debug(err)
+		return nfqueue.VerdictAccept
+	}
+
+	if h.options.Bypass == nil {
+		return nfqueue.VerdictAccept
+	}
+
+	var host string
+	if h.md.sniffing && hdr.Network == "tcp" && len(hdr.Payload) > 0 {
+		// only the first segment of the stream carries the ClientHello or the HTTP request line.
+		_, host, _, _ = forward.Sniffing(ctx, xio.NewReadWriter(bytes.NewReader(hdr.Payload), io.Discard))
+	}
+
+	if h.options.Bypass.Contains(ctx, hdr.Network, hdr.Dst.String(), bypass.WithHostOpton(host)) {
+		log.Debugf("bypass: %s > %s %s drop", hdr.Src, hdr.Dst, host)
+		return nfqueue.VerdictDrop
+	}
+
+	if log.IsLevelEnabled(logger.TraceLevel) {
+		log.Tracef("%s %s > %s %s accept", hdr.Network, hdr.Src, hdr.Dst, host)
+	}
+	return nfqueue.VerdictAccept
+}
@@ -0,0 +1,15 @@
+package nfqueue
+
+import (
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+type metadata struct {
+	sniffing bool
+}
+
+func (h *nfqueueHandler) parseMetadata(md mdata.Metadata) (err error) {
+	h.md.sniffing = mdutil.GetBool(md, "sniffing")
+	return
+}
@@ -0,0 +1,97 @@
+package redirect
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/go-gost/core/bypass"
+	"github.com/go-gost/core/logger"
+	netpkg "github.com/go-gost/x/internal/net"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// the client connection preface of HTTP/2 starts with the PRI method (RFC 9113, 3.4).
+	h2cPrefacePrefix = "PRI *"
+)
+
+var (
+	errMismatchBlocked = errors.New("h2c: blocked by mismatch")
+)
+
+// handleH2C terminates the HTTP/2 cleartext connection with the prior knowledge (h2c),
+// and relays each stream to the host of its :authority pseudo-header, the bypass is evaluated for each stream.
+func (h *redirectHandler) handleH2C(ctx context.Context, conn net.Conn, rw io.ReadWriter, dstAddr net.Addr, log logger.Logger) error {
+	tr := &http2.Transport{
+		AllowHTTP: true,
+		// the upstream connection is in cleartext as the client's.
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			cc, ok, err := h.dial(ctx, addr, dstAddr, nil, log)
+			if !ok {
+				return nil, errMismatchBlocked
+			}
+			return cc, err
+		},
+	}
+	defer tr.CloseIdleConnections()
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = "http"
+			pr.Out.URL.Host = hostPort(pr.In.Host, dstAddr, "80")
+			pr.Out.Host = pr.In.Host
+		},
+		Transport: tr,
+		// the streams (e.g. gRPC) are relayed as they are received.
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Errorf("h2c: %s %s: %v", r.Host, r.RequestURI, err)
+			if errors.Is(err, errMismatchBlocked) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := hostPort(r.Host, dstAddr, "80")
+		log.Debugf("h2c: %s %s %s", r.Method, host, r.RequestURI)
+
+		if h.options.Bypass != nil && h.options.Bypass.Contains(r.Context(), "tcp", host, bypass.WithPathOption(r.RequestURI)) {
+			log.Debugf("bypass: %s %s", host, r.RequestURI)
+			if res := h.md.block.Response(r, host); res != nil {
+				defer res.Body.Close()
+				for k, v := range res.Header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(res.StatusCode)
+				io.Copy(w, res.Body)
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		proxy.ServeHTTP(w, r)
+	})
+
+	t := time.Now()
+	log.Infof("%s <-> %s (h2c)", conn.RemoteAddr(), dstAddr)
+	(&http2.Server{}).ServeConn(netpkg.NewBufferReaderConn(conn, bufio.NewReader(rw)), &http2.ServeConnOpts{
+		Context: ctx,
+		Handler: handler,
+	})
+	log.WithFields(map[string]any{
+		"duration": time.Since(t),
+	}).Infof("%s >-< %s (h2c)", conn.RemoteAddr(), dstAddr)
+
+	return nil
+}
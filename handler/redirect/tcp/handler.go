@@ -20,8 +20,11 @@ import (
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
 	dissector "github.com/go-gost/tls-dissector"
+	xerrors "github.com/go-gost/x/errors"
 	xio "github.com/go-gost/x/internal/io"
 	netpkg "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/forward"
+	"github.com/go-gost/x/internal/util/ftp"
 	"github.com/go-gost/x/registry"
 )
 
@@ -78,7 +81,7 @@ func (h *redirectHandler) Handle(ctx context.Context, conn net.Conn, opts ...han
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	var dstAddr net.Addr
@@ -97,6 +100,12 @@ func (h *redirectHandler) Handle(ctx context.Context, conn net.Conn, opts ...han
 		"dst": fmt.Sprintf("%s/%s", dstAddr, dstAddr.Network()),
 	})
 
+	if h.md.ftp {
+		if _, port, _ := net.SplitHostPort(dstAddr.String()); port == "21" {
+			return h.handleFTP(ctx, conn, dstAddr, log)
+		}
+	}
+
 	var rw io.ReadWriter = conn
 	if h.md.sniffing {
 		if h.md.sniffingTimeout > 0 {
@@ -116,6 +125,11 @@ func (h *redirectHandler) Handle(ctx context.Context, conn net.Conn, opts ...han
 			return h.handleHTTPS(ctx, rw, conn.RemoteAddr(), dstAddr, log)
 		}
 
+		// try to sniff HTTP/2 cleartext traffic with the prior knowledge
+		if err == nil && string(hdr[:]) == h2cPrefacePrefix {
+			return h.handleH2C(ctx, conn, rw, dstAddr, log)
+		}
+
 		// try to sniff HTTP traffic
 		if isHTTP(string(hdr[:])) {
 			return h.handleHTTP(ctx, rw, conn.RemoteAddr(), dstAddr, log)
@@ -146,8 +160,44 @@ func (h *redirectHandler) Handle(ctx context.Context, conn net.Conn, opts ...han
 	return nil
 }
 
+// handleFTP relays the FTP control channel, the client speaks after the server so it is not sniffed.
+func (h *redirectHandler) handleFTP(ctx context.Context, conn net.Conn, dstAddr net.Addr, log logger.Logger) error {
+	if h.options.Bypass != nil && h.options.Bypass.Contains(ctx, dstAddr.Network(), dstAddr.String()) {
+		log.Debug("bypass: ", dstAddr)
+		return nil
+	}
+
+	cc, err := h.router.Dial(ctx, dstAddr.Network(), dstAddr.String())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	defer cc.Close()
+
+	t := time.Now()
+	log.Infof("%s <-> %s", conn.RemoteAddr(), dstAddr)
+	ftp.Relay(ctx, conn, cc, dstAddr.String(), h.router.Dial,
+		ftp.TimeoutOption(h.md.ftpTimeout),
+		ftp.LoggerOption(log),
+	)
+	log.WithFields(map[string]any{
+		"duration": time.Since(t),
+	}).Infof("%s >-< %s", conn.RemoteAddr(), dstAddr)
+
+	return nil
+}
+
 func (h *redirectHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, raddr, dstAddr net.Addr, log logger.Logger) error {
-	req, err := http.ReadRequest(bufio.NewReader(rw))
+	var r io.Reader = rw
+	var pf *prefetch
+	if h.md.prefetch {
+		r = forward.WatchHTTPHost(r, func(host string) {
+			pf = h.prefetch(ctx, hostPort(host, nil, "80"), dstAddr, log)
+		})
+		defer func() { pf.close() }()
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(r))
 	if err != nil {
 		return err
 	}
@@ -157,20 +207,28 @@ func (h *redirectHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, radd
 		log.Trace(string(dump))
 	}
 
-	host := req.Host
-	if _, _, err := net.SplitHostPort(host); err != nil {
-		host = net.JoinHostPort(host, "80")
-	}
+	host := hostPort(req.Host, nil, "80")
 	log = log.WithFields(map[string]any{
 		"host": host,
 	})
 
 	if h.options.Bypass != nil && h.options.Bypass.Contains(ctx, "tcp", host, bypass.WithPathOption(req.RequestURI)) {
 		log.Debugf("bypass: %s %s", host, req.RequestURI)
+		if res := h.md.block.Response(req, host); res != nil {
+			return res.Write(rw)
+		}
 		return nil
 	}
 
-	cc, err := h.router.Dial(ctx, "tcp", host)
+	if res, _ := h.md.bodyLimit.Request(req); res != nil {
+		log.Warnf("request body of %d bytes exceeds the limit", req.ContentLength)
+		return res.Write(rw)
+	}
+
+	cc, ok, err := h.dial(ctx, host, dstAddr, pf, log)
+	if !ok {
+		return nil
+	}
 	if err != nil {
 		log.Error(err)
 	}
@@ -220,7 +278,16 @@ func (h *redirectHandler) handleHTTP(ctx context.Context, rw io.ReadWriter, radd
 
 func (h *redirectHandler) handleHTTPS(ctx context.Context, rw io.ReadWriter, raddr, dstAddr net.Addr, log logger.Logger) error {
 	buf := new(bytes.Buffer)
-	host, err := h.getServerName(ctx, io.TeeReader(rw, buf))
+	var r io.Reader = io.TeeReader(rw, buf)
+	var pf *prefetch
+	if h.md.prefetch {
+		r = forward.WatchServerName(r, func(host string) {
+			pf = h.prefetch(ctx, hostPort(host, dstAddr, "443"), dstAddr, log)
+		})
+		defer func() { pf.close() }()
+	}
+
+	host, err := h.getServerName(ctx, r)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -229,26 +296,26 @@ func (h *redirectHandler) handleHTTPS(ctx context.Context, rw io.ReadWriter, rad
 	var cc io.ReadWriteCloser
 
 	if host != "" {
-		if _, _, err := net.SplitHostPort(host); err != nil {
-			_, port, _ := net.SplitHostPort(dstAddr.String())
-			if port == "" {
-				port = "443"
-			}
-			host = net.JoinHostPort(host, port)
-		}
+		host = hostPort(host, dstAddr, "443")
 		log = log.WithFields(map[string]any{
 			"host": host,
 		})
 
 		if h.options.Bypass != nil && h.options.Bypass.Contains(ctx, "tcp", host) {
 			log.Debug("bypass: ", host)
-			return nil
+			return h.md.block.WriteAlert(rw)
 		}
 
-		cc, err = h.router.Dial(ctx, "tcp", host)
+		c, ok, err := h.dial(ctx, host, dstAddr, pf, log)
+		if !ok {
+			return nil
+		}
 		if err != nil {
 			log.Error(err)
 		}
+		if c != nil {
+			cc = c
+		}
 	}
 
 	if cc == nil {
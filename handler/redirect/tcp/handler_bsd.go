@@ -0,0 +1,83 @@
+//go:build darwin || freebsd || openbsd
+
+package redirect
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	pfOut = 2
+)
+
+// getOriginalDstAddr looks up the original destination of the connection redirected by
+// the rdr rule of pf (DIOCNATLOOK). If pf is not available on FreeBSD, the connection is assumed to be forwarded
+// by the fwd rule of IPFW, which keeps the original destination as the local address.
+func (h *redirectHandler) getOriginalDstAddr(conn net.Conn) (addr net.Addr, err error) {
+	raddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		err = errors.New("wrong connection type, must be TCP Conn")
+		return
+	}
+	laddr, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		err = errors.New("wrong connection type, must be TCP Conn")
+		return
+	}
+
+	f, err := os.Open("/dev/pf")
+	if err != nil {
+		if ipfwFwd && errors.Is(err, os.ErrNotExist) {
+			return laddr, nil
+		}
+		return
+	}
+	defer f.Close()
+
+	var nl pfiocNatlook
+	nl.proto = unix.IPPROTO_TCP
+	nl.direction = pfOut
+	binary.BigEndian.PutUint16(nl.sport[:], uint16(raddr.Port))
+	binary.BigEndian.PutUint16(nl.dport[:], uint16(laddr.Port))
+	if ip := raddr.IP.To4(); ip != nil {
+		nl.af = unix.AF_INET
+		copy(nl.saddr[:4], ip)
+		copy(nl.daddr[:4], laddr.IP.To4())
+	} else {
+		nl.af = unix.AF_INET6
+		copy(nl.saddr[:], raddr.IP.To16())
+		copy(nl.daddr[:], laddr.IP.To16())
+	}
+
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return
+	}
+	var errno unix.Errno
+	err = rc.Control(func(fd uintptr) {
+		_, _, errno = unix.Syscall(unix.SYS_IOCTL, fd, diocNatlook, uintptr(unsafe.Pointer(&nl)))
+	})
+	if err != nil {
+		return
+	}
+	if errno != 0 {
+		return nil, os.NewSyscallError("ioctl DIOCNATLOOK", errno)
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, nl.rdaddr[:])
+	if nl.af == unix.AF_INET {
+		ip = ip[:net.IPv4len]
+	}
+	addr = &net.TCPAddr{
+		IP:   ip,
+		Port: int(binary.BigEndian.Uint16(nl.rdport[:])),
+	}
+	return
+}
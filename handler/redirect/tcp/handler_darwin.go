@@ -0,0 +1,24 @@
+package redirect
+
+// IPFW is not available on macOS, the connections must be redirected by pf.
+const ipfwFwd = false
+
+// DIOCNATLOOK is _IOWR('D', 23, struct pfioc_natlook)
+const diocNatlook = 0xc0544417
+
+// struct pfioc_natlook of XNU bsd/net/pfvar.h,
+// the ports are the union pf_state_xport with the port in the first two bytes.
+type pfiocNatlook struct {
+	saddr        [16]byte
+	daddr        [16]byte
+	rsaddr       [16]byte
+	rdaddr       [16]byte
+	sport        [4]byte
+	dport        [4]byte
+	rsport       [4]byte
+	rdport       [4]byte
+	af           uint8
+	proto        uint8
+	protoVariant uint8
+	direction    uint8
+}
@@ -0,0 +1,23 @@
+package redirect
+
+// the connections may be forwarded by the fwd rule of IPFW if pf is not available.
+const ipfwFwd = true
+
+// DIOCNATLOOK is _IOWR('D', 23, struct pfioc_natlook)
+const diocNatlook = 0xc04c4417
+
+// struct pfioc_natlook of FreeBSD net/pfvar.h
+type pfiocNatlook struct {
+	saddr     [16]byte
+	daddr     [16]byte
+	rsaddr    [16]byte
+	rdaddr    [16]byte
+	sport     [2]byte
+	dport     [2]byte
+	rsport    [2]byte
+	rdport    [2]byte
+	af        uint8
+	proto     uint8
+	direction uint8
+	_         [1]byte
+}
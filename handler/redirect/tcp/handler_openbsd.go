@@ -0,0 +1,25 @@
+package redirect
+
+// IPFW is not available on OpenBSD, the connections must be redirected by pf.
+const ipfwFwd = false
+
+// DIOCNATLOOK is _IOWR('D', 23, struct pfioc_natlook)
+const diocNatlook = 0xc0504417
+
+// struct pfioc_natlook of OpenBSD net/pfvar.h
+type pfiocNatlook struct {
+	saddr     [16]byte
+	daddr     [16]byte
+	rsaddr    [16]byte
+	rdaddr    [16]byte
+	rdomain   uint16
+	rrdomain  uint16
+	sport     [2]byte
+	dport     [2]byte
+	rsport    [2]byte
+	rdport    [2]byte
+	af        uint8
+	proto     uint8
+	direction uint8
+	_         [1]byte
+}
@@ -1,4 +1,4 @@
-//go:build !linux
+//go:build !linux && !darwin && !freebsd && !openbsd
 
 package redirect
 
@@ -8,6 +8,6 @@ import (
 )
 
 func (h *redirectHandler) getOriginalDstAddr(conn net.Conn) (addr net.Addr, err error) {
-	err = errors.New("TCP redirect is not available on non-linux platform")
+	err = errors.New("TCP redirect is not available on this platform")
 	return
 }
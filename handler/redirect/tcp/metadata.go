@@ -5,21 +5,38 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/forward"
 )
 
 type metadata struct {
 	tproxy          bool
 	sniffing        bool
 	sniffingTimeout time.Duration
+	ftp             bool
+	ftpTimeout      time.Duration
+	bodyLimit       *forward.BodyLimit
+	block           *forward.Block
+	mismatch        string
+	// prefetch connects to the sniffed host before the whole handshake is received.
+	prefetch bool
 }
 
 func (h *redirectHandler) parseMetadata(md mdata.Metadata) (err error) {
 	const (
 		tproxy   = "tproxy"
 		sniffing = "sniffing"
+		ftp      = "ftp"
 	)
 	h.md.tproxy = mdutil.GetBool(md, tproxy)
 	h.md.sniffing = mdutil.GetBool(md, sniffing)
 	h.md.sniffingTimeout = mdutil.GetDuration(md, "sniffing.timeout")
+	h.md.ftp = mdutil.GetBool(md, ftp)
+	h.md.ftpTimeout = mdutil.GetDuration(md, "ftp.timeout")
+	h.md.bodyLimit = forward.ParseBodyLimit(md)
+	if h.md.block, err = forward.ParseBlock(md); err != nil {
+		return
+	}
+	h.md.mismatch = mdutil.GetString(md, "sniffing.mismatch")
+	h.md.prefetch = mdutil.GetBool(md, "sniffing.prefetch")
 	return
 }
@@ -0,0 +1,81 @@
+package redirect
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/logger"
+)
+
+const (
+	// the mismatch is logged, the connection goes to the sniffed host.
+	mismatchLog = "log"
+	// the connection is closed.
+	mismatchBlock = "block"
+	// the connection goes to the sniffed host.
+	mismatchHost = "host"
+	// the connection goes to the original destination.
+	mismatchDst = "dst"
+)
+
+const (
+	mismatchResolveTimeout = 5 * time.Second
+)
+
+// checkMismatch checks whether the sniffed host (the Host header or the SNI) resolves to the original destination,
+// which detects the domain fronting through the transparent proxy. The host that cannot be resolved is mismatched.
+// It returns the address to connect to, or false if the connection is blocked.
+func (h *redirectHandler) checkMismatch(ctx context.Context, host string, dstAddr net.Addr, log logger.Logger) (string, bool) {
+	if h.md.mismatch == "" || host == "" {
+		return host, true
+	}
+
+	if h.matchDst(ctx, host, dstAddr) {
+		return host, true
+	}
+
+	switch h.md.mismatch {
+	case mismatchBlock:
+		log.Warnf("mismatch: %s does not match the destination %s, blocked", host, dstAddr)
+		return "", false
+	case mismatchDst:
+		log.Warnf("mismatch: %s does not match the destination %s, connect to the destination", host, dstAddr)
+		return dstAddr.String(), true
+	case mismatchHost:
+		log.Debugf("mismatch: %s does not match the destination %s", host, dstAddr)
+		return host, true
+	default:
+		log.Warnf("mismatch: %s does not match the destination %s", host, dstAddr)
+		return host, true
+	}
+}
+
+func (h *redirectHandler) matchDst(ctx context.Context, host string, dstAddr net.Addr) bool {
+	dstHost, _, _ := net.SplitHostPort(dstAddr.String())
+	dstIP := net.ParseIP(dstHost)
+	if dstIP == nil {
+		return false
+	}
+
+	if v, _, _ := net.SplitHostPort(host); v != "" {
+		host = v
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.Equal(dstIP)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mismatchResolveTimeout)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		if ip.Equal(dstIP) {
+			return true
+		}
+	}
+	return false
+}
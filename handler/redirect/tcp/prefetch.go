@@ -0,0 +1,90 @@
+package redirect
+
+import (
+	"context"
+	"net"
+
+	"github.com/go-gost/core/logger"
+)
+
+// prefetch connects to the sniffed host in the background while the rest of the handshake is being received,
+// which saves the time of the DNS resolution and the connection of the route.
+type prefetch struct {
+	host     string
+	addr     string
+	ok       bool
+	bypassed bool
+	conn     net.Conn
+	err      error
+	used     bool
+	done     chan struct{}
+}
+
+func (h *redirectHandler) prefetch(ctx context.Context, host string, dstAddr net.Addr, log logger.Logger) *prefetch {
+	pf := &prefetch{
+		host: host,
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(pf.done)
+
+		if h.options.Bypass != nil && h.options.Bypass.Contains(ctx, "tcp", host) {
+			pf.bypassed = true
+			return
+		}
+		if pf.addr, pf.ok = h.checkMismatch(ctx, host, dstAddr, log); !pf.ok {
+			return
+		}
+		log.Debugf("prefetch: %s", pf.addr)
+		pf.conn, pf.err = h.router.Dial(ctx, "tcp", pf.addr)
+	}()
+
+	return pf
+}
+
+// dial returns the connection to the host, the prefetched connection is used if it is for the host.
+// It returns false if the connection is blocked by the mismatch check.
+func (h *redirectHandler) dial(ctx context.Context, host string, dstAddr net.Addr, pf *prefetch, log logger.Logger) (net.Conn, bool, error) {
+	if pf != nil {
+		<-pf.done
+		if pf.host == host && !pf.bypassed {
+			pf.used = true
+			return pf.conn, pf.ok, pf.err
+		}
+	}
+
+	addr, ok := h.checkMismatch(ctx, host, dstAddr, log)
+	if !ok {
+		return nil, false, nil
+	}
+	cc, err := h.router.Dial(ctx, "tcp", addr)
+	return cc, true, err
+}
+
+// close closes the prefetched connection if it is not used.
+func (pf *prefetch) close() {
+	if pf == nil {
+		return
+	}
+	go func() {
+		<-pf.done
+		if !pf.used && pf.conn != nil {
+			pf.conn.Close()
+		}
+	}()
+}
+
+// hostPort returns the host with the port of the destination, or the default port if the host has no port.
+func hostPort(host string, dstAddr net.Addr, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	port := defaultPort
+	if dstAddr != nil {
+		if _, p, _ := net.SplitHostPort(dstAddr.String()); p != "" {
+			port = p
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
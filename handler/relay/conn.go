@@ -7,6 +7,8 @@ import (
 	"io"
 	"math"
 	"net"
+
+	"github.com/go-gost/x/internal/bufpool"
 )
 
 type tcpConn struct {
@@ -45,7 +47,8 @@ func (c *udpConn) Read(b []byte) (n int, err error) {
 	if len(b) >= dlen {
 		return io.ReadFull(c.Conn, b[:dlen])
 	}
-	buf := make([]byte, dlen)
+	buf := bufpool.Get(dlen)
+	defer bufpool.Put(buf)
 	_, err = io.ReadFull(c.Conn, buf)
 	n = copy(b, buf)
 
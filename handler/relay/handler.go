@@ -13,6 +13,7 @@ import (
 	md "github.com/go-gost/core/metadata"
 	"github.com/go-gost/relay"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	stats_util "github.com/go-gost/x/internal/util/stats"
 	"github.com/go-gost/x/registry"
 )
@@ -20,8 +21,8 @@ import (
 var (
 	ErrBadVersion   = errors.New("relay: bad version")
 	ErrUnknownCmd   = errors.New("relay: unknown command")
-	ErrUnauthorized = errors.New("relay: unauthorized")
-	ErrRateLimit    = errors.New("relay: rate limiting exceeded")
+	ErrUnauthorized = xerrors.Wrap(xerrors.KindAuth, errors.New("relay: unauthorized"))
+	ErrRateLimit    = xerrors.Wrap(xerrors.KindLimiter, errors.New("relay: rate limiting exceeded"))
 )
 
 func init() {
@@ -100,12 +101,20 @@ func (h *relayHandler) Handle(ctx context.Context, conn net.Conn, opts ...handle
 		conn.SetReadDeadline(time.Now().Add(h.md.readTimeout))
 	}
 
+	done, ok := h.md.handshake.Begin(conn)
+	if !ok {
+		log.Warnf("handshake: too many handshakes from %s", conn.RemoteAddr())
+		return xerrors.ErrHandshakeLimit
+	}
+	defer done()
+
 	req := relay.Request{}
 	if _, err := req.ReadFrom(conn); err != nil {
 		return err
 	}
 
 	conn.SetReadDeadline(time.Time{})
+	done()
 
 	resp := relay.Response{
 		Version: relay.Version1,
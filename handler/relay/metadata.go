@@ -6,6 +6,7 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/handshake"
 	"github.com/go-gost/x/internal/util/mux"
 )
 
@@ -16,6 +17,7 @@ type metadata struct {
 	noDelay       bool
 	hash          string
 	muxCfg        *mux.Config
+	handshake     *handshake.Handshake
 }
 
 func (h *relayHandler) parseMetadata(md mdata.Metadata) (err error) {
@@ -38,6 +40,7 @@ func (h *relayHandler) parseMetadata(md mdata.Metadata) (err error) {
 	}
 
 	h.md.hash = mdutil.GetString(md, hash)
+	h.md.handshake = handshake.Parse(md)
 
 	h.md.muxCfg = &mux.Config{
 		Version:           mdutil.GetInt(md, "mux.version"),
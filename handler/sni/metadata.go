@@ -5,11 +5,13 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/forward"
 )
 
 type metadata struct {
 	readTimeout time.Duration
 	hash        string
+	block       *forward.Block
 }
 
 func (h *sniHandler) parseMetadata(md mdata.Metadata) (err error) {
@@ -20,5 +22,6 @@ func (h *sniHandler) parseMetadata(md mdata.Metadata) (err error) {
 
 	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
 	h.md.hash = mdutil.GetString(md, hash)
+	h.md.block, err = forward.ParseBlock(md)
 	return
 }
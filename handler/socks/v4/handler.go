@@ -13,10 +13,12 @@ import (
 	md "github.com/go-gost/core/metadata"
 	"github.com/go-gost/gosocks4"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	netpkg "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/classify"
+	stats_util "github.com/go-gost/x/internal/util/stats"
 	"github.com/go-gost/x/limiter/traffic/wrapper"
 	"github.com/go-gost/x/registry"
-	stats_util "github.com/go-gost/x/internal/util/stats"
 	"github.com/go-gost/x/stats"
 	stats_wrapper "github.com/go-gost/x/stats/wrapper"
 )
@@ -89,13 +91,20 @@ func (h *socks4Handler) Handle(ctx context.Context, conn net.Conn, opts ...handl
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	if h.md.readTimeout > 0 {
 		conn.SetReadDeadline(time.Now().Add(h.md.readTimeout))
 	}
 
+	done, ok := h.md.handshake.Begin(conn)
+	if !ok {
+		log.Warnf("handshake: too many handshakes from %s", conn.RemoteAddr())
+		return xerrors.ErrHandshakeLimit
+	}
+	defer done()
+
 	req, err := gosocks4.ReadRequest(conn)
 	if err != nil {
 		log.Error(err)
@@ -104,6 +113,7 @@ func (h *socks4Handler) Handle(ctx context.Context, conn net.Conn, opts ...handl
 	log.Trace(req)
 
 	conn.SetReadDeadline(time.Time{})
+	done()
 
 	if h.options.Auther != nil {
 		id, ok := h.options.Auther.Authenticate(ctx, string(req.Userid), "")
@@ -146,7 +156,8 @@ func (h *socks4Handler) handleConnect(ctx context.Context, conn net.Conn, req *g
 		resp := gosocks4.NewReply(gosocks4.Rejected, nil)
 		log.Trace(resp)
 		log.Debug("bypass: ", addr)
-		return resp.Write(conn)
+		resp.Write(conn)
+		return xerrors.ErrBypass
 	}
 
 	switch h.md.hash {
@@ -164,6 +175,10 @@ func (h *socks4Handler) handleConnect(ctx context.Context, conn net.Conn, req *g
 
 	defer cc.Close()
 
+	if h.md.blockBitTorrent {
+		cc = classify.BlockBitTorrent(cc, h.options.Service, log)
+	}
+
 	resp := gosocks4.NewReply(gosocks4.Granted, nil)
 	log.Trace(resp)
 	if err := resp.Write(conn); err != nil {
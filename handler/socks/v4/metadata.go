@@ -5,20 +5,27 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/handshake"
 )
 
 type metadata struct {
 	readTimeout time.Duration
 	hash        string
+	handshake   *handshake.Handshake
+	// blockBitTorrent refuses the BitTorrent handshakes and tracker announces.
+	blockBitTorrent bool
 }
 
 func (h *socks4Handler) parseMetadata(md mdata.Metadata) (err error) {
 	const (
-		readTimeout = "readTimeout"
-		hash        = "hash"
+		readTimeout     = "readTimeout"
+		hash            = "hash"
+		blockBitTorrent = "blockBitTorrent"
 	)
 
 	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
 	h.md.hash = mdutil.GetString(md, hash)
+	h.md.handshake = handshake.Parse(md)
+	h.md.blockBitTorrent = mdutil.GetBool(md, blockBitTorrent)
 	return
 }
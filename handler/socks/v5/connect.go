@@ -10,7 +10,9 @@ import (
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/gosocks5"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	netpkg "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/classify"
 	"github.com/go-gost/x/limiter/traffic/wrapper"
 	"github.com/go-gost/x/stats"
 	stats_wrapper "github.com/go-gost/x/stats/wrapper"
@@ -27,7 +29,8 @@ func (h *socks5Handler) handleConnect(ctx context.Context, conn net.Conn, networ
 		resp := gosocks5.NewReply(gosocks5.NotAllowed, nil)
 		log.Trace(resp)
 		log.Debug("bypass: ", address)
-		return resp.Write(conn)
+		resp.Write(conn)
+		return xerrors.ErrBypass
 	}
 
 	switch h.md.hash {
@@ -45,6 +48,10 @@ func (h *socks5Handler) handleConnect(ctx context.Context, conn net.Conn, networ
 
 	defer cc.Close()
 
+	if h.md.blockBitTorrent {
+		cc = classify.BlockBitTorrent(cc, h.options.Service, log)
+	}
+
 	resp := gosocks5.NewReply(gosocks5.Succeeded, nil)
 	log.Trace(resp)
 	if err := resp.Write(conn); err != nil {
@@ -1,6 +1,7 @@
 package v5
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"net"
@@ -11,6 +12,8 @@ import (
 	md "github.com/go-gost/core/metadata"
 	"github.com/go-gost/gosocks5"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/util/socks"
 	stats_util "github.com/go-gost/x/internal/util/stats"
 	"github.com/go-gost/x/registry"
@@ -91,13 +94,37 @@ func (h *socks5Handler) Handle(ctx context.Context, conn net.Conn, opts ...handl
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	if h.md.readTimeout > 0 {
 		conn.SetReadDeadline(time.Now().Add(h.md.readTimeout))
 	}
 
+	done, ok := h.md.handshake.Begin(conn)
+	if !ok {
+		log.Warnf("handshake: too many handshakes from %s", conn.RemoteAddr())
+		return xerrors.ErrHandshakeLimit
+	}
+	defer done()
+
+	if h.md.fallback != nil {
+		br := bufio.NewReader(conn)
+		b, err := br.Peek(1)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		// the non-SOCKS5 requests, e.g. the probes of the web browsers, are served by the fallback.
+		if b[0] != gosocks5.Ver5 {
+			done()
+			conn.SetReadDeadline(time.Time{})
+			log.Debugf("fallback to %s", h.md.fallback)
+			return h.md.fallback.ServeConn(conn, br, log)
+		}
+		conn = xnet.NewBufferReaderConn(conn, br)
+	}
+
 	sc := gosocks5.ServerConn(conn, h.selector)
 	req, err := gosocks5.ReadRequest(sc)
 	if err != nil {
@@ -112,6 +139,7 @@ func (h *socks5Handler) Handle(ctx context.Context, conn net.Conn, opts ...handl
 
 	conn = sc
 	conn.SetReadDeadline(time.Time{})
+	done()
 
 	address := req.Addr.String()
 
@@ -6,6 +6,8 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/fallback"
+	"github.com/go-gost/x/internal/util/handshake"
 	"github.com/go-gost/x/internal/util/mux"
 )
 
@@ -18,6 +20,11 @@ type metadata struct {
 	compatibilityMode bool
 	hash              string
 	muxCfg            *mux.Config
+	fallback          *fallback.Fallback
+	handshake         *handshake.Handshake
+	// blockBitTorrent refuses the BitTorrent handshakes and tracker announces,
+	// and drops the DHT and UDP tracker datagrams.
+	blockBitTorrent bool
 }
 
 func (h *socks5Handler) parseMetadata(md mdata.Metadata) (err error) {
@@ -29,6 +36,8 @@ func (h *socks5Handler) parseMetadata(md mdata.Metadata) (err error) {
 		udpBufferSize     = "udpBufferSize"
 		compatibilityMode = "comp"
 		hash              = "hash"
+		fallbackKey       = "fallback"
+		blockBitTorrent   = "blockBitTorrent"
 	)
 
 	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
@@ -44,6 +53,9 @@ func (h *socks5Handler) parseMetadata(md mdata.Metadata) (err error) {
 
 	h.md.compatibilityMode = mdutil.GetBool(md, compatibilityMode)
 	h.md.hash = mdutil.GetString(md, hash)
+	h.md.fallback = fallback.Parse(mdutil.GetString(md, fallbackKey))
+	h.md.handshake = handshake.Parse(md)
+	h.md.blockBitTorrent = mdutil.GetBool(md, blockBitTorrent)
 
 	h.md.muxCfg = &mux.Config{
 		Version:           mdutil.GetInt(md, "mux.version"),
@@ -9,6 +9,7 @@ import (
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/gosocks5"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	"github.com/go-gost/x/internal/util/socks"
 )
 
@@ -80,7 +81,7 @@ func (s *serverSelector) OnSelected(method uint8, conn net.Conn) (string, net.Co
 				}
 				s.logger.Info(resp)
 
-				return "", nil, gosocks5.ErrAuthFailure
+				return "", nil, xerrors.Wrap(xerrors.KindAuth, gosocks5.ErrAuthFailure)
 			}
 		}
 
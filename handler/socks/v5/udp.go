@@ -12,6 +12,7 @@ import (
 	"github.com/go-gost/gosocks5"
 	ctxvalue "github.com/go-gost/x/ctx"
 	"github.com/go-gost/x/internal/net/udp"
+	"github.com/go-gost/x/internal/util/classify"
 	"github.com/go-gost/x/internal/util/socks"
 	"github.com/go-gost/x/stats"
 	stats_wrapper "github.com/go-gost/x/stats/wrapper"
@@ -70,6 +71,10 @@ func (h *socks5Handler) handleUDP(ctx context.Context, conn net.Conn, log logger
 		return err
 	}
 
+	if h.md.blockBitTorrent {
+		pc = classify.BlockBitTorrentPacketConn(pc, h.options.Service, log)
+	}
+
 	var lc net.PacketConn = cc
 	clientID := ctxvalue.ClientIDFromContext(ctx)
 	if h.options.Observer != nil {
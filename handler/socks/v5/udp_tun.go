@@ -9,6 +9,7 @@ import (
 	"github.com/go-gost/gosocks5"
 	ctxvalue "github.com/go-gost/x/ctx"
 	"github.com/go-gost/x/internal/net/udp"
+	"github.com/go-gost/x/internal/util/classify"
 	"github.com/go-gost/x/internal/util/socks"
 	"github.com/go-gost/x/stats"
 	stats_wrapper "github.com/go-gost/x/stats/wrapper"
@@ -68,7 +69,12 @@ func (h *socks5Handler) handleUDPTun(ctx context.Context, conn net.Conn, network
 		conn = stats_wrapper.WrapConn(conn, pstats)
 	}
 
-	r := udp.NewRelay(socks.UDPTunServerConn(conn), pc).
+	var rpc net.PacketConn = pc
+	if h.md.blockBitTorrent {
+		rpc = classify.BlockBitTorrentPacketConn(pc, h.options.Service, log)
+	}
+
+	r := udp.NewRelay(socks.UDPTunServerConn(conn), rpc).
 		WithBypass(h.options.Bypass).
 		WithLogger(log)
 	r.SetBufferSize(h.md.udpBufferSize)
@@ -0,0 +1,274 @@
+// Package speedtest implements the HTTP speedtest and latency probe endpoints to validate the chains end-to-end:
+//
+//	GET /download?size=N    sends N bytes, N can be suffixed by K, M or G.
+//	POST /upload            receives the body and reports the throughput.
+//	GET /ping               responds immediately with the server time, the target of the latency probes.
+package speedtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/handler"
+	md "github.com/go-gost/core/metadata"
+	"github.com/go-gost/x/registry"
+)
+
+func init() {
+	registry.HandlerRegistry().Register("speedtest", NewHandler)
+}
+
+type speedtestHandler struct {
+	server  *http.Server
+	ln      *singleConnListener
+	md      metadata
+	options handler.Options
+}
+
+func NewHandler(opts ...handler.Option) handler.Handler {
+	options := handler.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &speedtestHandler{
+		options: options,
+	}
+}
+
+func (h *speedtestHandler) Init(md md.Metadata) (err error) {
+	if err = h.parseMetadata(md); err != nil {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download", h.download)
+	mux.HandleFunc("/upload", h.upload)
+	mux.HandleFunc("/ping", h.ping)
+
+	h.server = &http.Server{
+		Handler: h.auth(mux),
+	}
+	h.ln = &singleConnListener{
+		conn: make(chan net.Conn),
+		done: make(chan struct{}),
+	}
+	go h.server.Serve(h.ln)
+
+	return
+}
+
+func (h *speedtestHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.HandleOption) error {
+	h.options.Logger.WithFields(map[string]any{
+		"remote": conn.RemoteAddr().String(),
+		"local":  conn.LocalAddr().String(),
+	}).Debugf("%s - %s", conn.RemoteAddr(), conn.LocalAddr())
+
+	h.ln.send(conn)
+
+	return nil
+}
+
+func (h *speedtestHandler) Close() error {
+	return h.server.Close()
+}
+
+func (h *speedtestHandler) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := h.options.Logger.WithFields(map[string]any{
+			"remote": r.RemoteAddr,
+		})
+
+		if auther := h.options.Auther; auther != nil {
+			u, p, _ := r.BasicAuth()
+			id, ok := auther.Authenticate(r.Context(), u, p)
+			if !ok {
+				realm := defaultRealm
+				if h.md.authBasicRealm != "" {
+					realm = h.md.authBasicRealm
+				}
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=\"%s\"", realm))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if id != "" {
+				log = log.WithFields(map[string]any{
+					"client": id,
+				})
+			}
+		}
+
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.WithFields(map[string]any{
+			"duration": time.Since(start),
+		}).Infof("%s %s", r.Method, r.RequestURI)
+	})
+}
+
+type result struct {
+	Bytes    int64   `json:"bytes"`
+	Duration float64 `json:"duration"`
+	// the throughput in bits per second.
+	BPS float64 `json:"bps"`
+}
+
+func (h *speedtestHandler) download(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	size := h.md.size
+	if v := r.URL.Query().Get("size"); v != "" {
+		n, err := parseSize(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid size", http.StatusBadRequest)
+			return
+		}
+		size = n
+	}
+	if size > h.md.maxSize {
+		http.Error(w, fmt.Sprintf("size exceeds %d", h.md.maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Cache-Control", "no-store, no-transform")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	io.CopyN(w, newPayload(), size)
+}
+
+func (h *speedtestHandler) upload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, http.MaxBytesReader(w, r.Body, h.md.maxSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	d := time.Since(start)
+	res := result{
+		Bytes:    n,
+		Duration: d.Seconds(),
+	}
+	if d > 0 {
+		res.BPS = float64(n*8) / d.Seconds()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *speedtestHandler) ping(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]int64{
+		"time": time.Now().UnixNano(),
+	})
+}
+
+// parseSize parses the size with the optional suffix K, M or G (case insensitive, of the base 1024).
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "B")
+
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		unit = 1024
+	case strings.HasSuffix(s, "M"):
+		unit = 1024 * 1024
+	case strings.HasSuffix(s, "G"):
+		unit = 1024 * 1024 * 1024
+	}
+	if unit > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * unit, nil
+}
+
+// payload is the incompressible stream of the download,
+// the xorshift generator is fast enough to saturate the links and defeats the compression of the links.
+type payload struct {
+	x uint64
+}
+
+func newPayload() *payload {
+	return &payload{x: uint64(time.Now().UnixNano()) | 1}
+}
+
+func (p *payload) Read(b []byte) (int, error) {
+	x := p.x
+	for i := range b {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+		b[i] = byte(x)
+	}
+	p.x = x
+	return len(b), nil
+}
+
+type singleConnListener struct {
+	conn chan net.Conn
+	addr net.Addr
+	done chan struct{}
+	mu   sync.Mutex
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conn:
+		return conn, nil
+
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *singleConnListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *singleConnListener) send(conn net.Conn) {
+	select {
+	case l.conn <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
@@ -0,0 +1,50 @@
+package speedtest
+
+import (
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	defaultRealm   = "gost"
+	defaultSize    = 10 * 1024 * 1024
+	defaultMaxSize = 1024 * 1024 * 1024
+)
+
+type metadata struct {
+	// the default size of the download payload, it can be suffixed by K, M or G.
+	size int64
+	// the max size of the payloads of the download and the upload.
+	maxSize        int64
+	authBasicRealm string
+}
+
+func (h *speedtestHandler) parseMetadata(md mdata.Metadata) (err error) {
+	const (
+		size           = "speedtest.size"
+		maxSize        = "speedtest.maxSize"
+		authBasicRealm = "authBasicRealm"
+	)
+
+	if v := mdutil.GetString(md, size); v != "" {
+		if h.md.size, err = parseSize(v); err != nil {
+			return
+		}
+	}
+	if h.md.size <= 0 {
+		h.md.size = defaultSize
+	}
+	if v := mdutil.GetString(md, maxSize); v != "" {
+		if h.md.maxSize, err = parseSize(v); err != nil {
+			return
+		}
+	}
+	if h.md.maxSize <= 0 {
+		h.md.maxSize = defaultMaxSize
+	}
+	if h.md.size > h.md.maxSize {
+		h.md.size = h.md.maxSize
+	}
+	h.md.authBasicRealm = mdutil.GetString(md, authBasicRealm)
+	return
+}
@@ -11,7 +11,9 @@ import (
 	md "github.com/go-gost/core/metadata"
 	"github.com/go-gost/gosocks5"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	netpkg "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/classify"
 	"github.com/go-gost/x/internal/util/ss"
 	"github.com/go-gost/x/registry"
 	"github.com/shadowsocks/go-shadowsocks2/core"
@@ -77,7 +79,7 @@ func (h *ssHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.H
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
 	}
 
 	if h.cipher != nil {
@@ -88,12 +90,20 @@ func (h *ssHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.H
 		conn.SetReadDeadline(time.Now().Add(h.md.readTimeout))
 	}
 
+	done, ok := h.md.handshake.Begin(conn)
+	if !ok {
+		log.Warnf("handshake: too many handshakes from %s", conn.RemoteAddr())
+		return xerrors.ErrHandshakeLimit
+	}
+	defer done()
+
 	addr := &gosocks5.Addr{}
 	if _, err := addr.ReadFrom(conn); err != nil {
 		log.Error(err)
 		io.Copy(io.Discard, conn)
 		return err
 	}
+	done()
 
 	log = log.WithFields(map[string]any{
 		"dst": addr.String(),
@@ -103,7 +113,7 @@ func (h *ssHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.H
 
 	if h.options.Bypass != nil && h.options.Bypass.Contains(ctx, "tcp", addr.String()) {
 		log.Debug("bypass: ", addr.String())
-		return nil
+		return xerrors.ErrBypass
 	}
 
 	switch h.md.hash {
@@ -117,6 +127,10 @@ func (h *ssHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.H
 	}
 	defer cc.Close()
 
+	if h.md.blockBitTorrent {
+		cc = classify.BlockBitTorrent(cc, h.options.Service, log)
+	}
+
 	t := time.Now()
 	log.Infof("%s <-> %s", conn.RemoteAddr(), addr)
 	netpkg.Transport(conn, cc)
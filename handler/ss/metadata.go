@@ -5,24 +5,31 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/handshake"
 )
 
 type metadata struct {
 	key         string
 	readTimeout time.Duration
 	hash        string
+	handshake   *handshake.Handshake
+	// blockBitTorrent refuses the BitTorrent handshakes and tracker announces.
+	blockBitTorrent bool
 }
 
 func (h *ssHandler) parseMetadata(md mdata.Metadata) (err error) {
 	const (
-		key         = "key"
-		readTimeout = "readTimeout"
-		hash        = "hash"
+		key             = "key"
+		readTimeout     = "readTimeout"
+		hash            = "hash"
+		blockBitTorrent = "blockBitTorrent"
 	)
 
 	h.md.key = mdutil.GetString(md, key)
 	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
 	h.md.hash = mdutil.GetString(md, hash)
+	h.md.handshake = handshake.Parse(md)
+	h.md.blockBitTorrent = mdutil.GetBool(md, blockBitTorrent)
 
 	return
 }
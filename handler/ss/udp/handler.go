@@ -4,13 +4,19 @@ import (
 	"context"
 	"errors"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-gost/core/bypass"
 	"github.com/go-gost/core/chain"
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/handler"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	xerrors "github.com/go-gost/x/errors"
+	"github.com/go-gost/x/internal/bufpool"
+	"github.com/go-gost/x/internal/util/classify"
+	"github.com/go-gost/x/internal/util/forward"
 	"github.com/go-gost/x/internal/util/relay"
 	"github.com/go-gost/x/internal/util/ss"
 	"github.com/go-gost/x/registry"
@@ -78,7 +84,17 @@ func (h *ssuHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.
 	}()
 
 	if !h.checkRateLimit(conn.RemoteAddr()) {
-		return nil
+		return xerrors.ErrRateLimit
+	}
+
+	var targets []net.Addr
+	for _, target := range h.md.targets {
+		addr, err := net.ResolveUDPAddr("udp", target)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		targets = append(targets, addr)
 	}
 
 	pc, ok := conn.(net.PacketConn)
@@ -110,20 +126,37 @@ func (h *ssuHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.
 		log.Error(err)
 		return err
 	}
+	if h.md.blockBitTorrent {
+		cc = classify.BlockBitTorrentPacketConn(cc, h.options.Service, log)
+	}
 
 	t := time.Now()
 	log.Infof("%s <-> %s", conn.LocalAddr(), cc.LocalAddr())
-	h.relayPacket(pc, cc, log)
+	h.relayPacket(pc, cc, targets, log)
 	log.WithFields(map[string]any{"duration": time.Since(t)}).
 		Infof("%s >-< %s", conn.LocalAddr(), cc.LocalAddr())
 
 	return nil
 }
 
-func (h *ssuHandler) relayPacket(pc1, pc2 net.PacketConn, log logger.Logger) (err error) {
+// relayPacket relays the datagrams between the client (pc1) and the destinations (pc2).
+// If the targets are specified, the datagrams are relayed to the targets instead of the destinations requested by the client,
+// and the replies of the targets are returned to the client as from the last requested destination.
+func (h *ssuHandler) relayPacket(pc1, pc2 net.PacketConn, targets []net.Addr, log logger.Logger) (err error) {
 	bufSize := h.md.bufferSize
 	errc := make(chan error, 2)
 
+	var dst atomic.Pointer[net.Addr]
+
+	// the server names sniffed from the datagrams, keyed by the target address.
+	var hosts sync.Map
+	hostOf := func(addr net.Addr) string {
+		if v, ok := hosts.Load(addr.String()); ok {
+			return v.(string)
+		}
+		return ""
+	}
+
 	go func() {
 		for {
 			err := func() error {
@@ -135,17 +168,33 @@ func (h *ssuHandler) relayPacket(pc1, pc2 net.PacketConn, log logger.Logger) (er
 					return err
 				}
 
-				if h.options.Bypass != nil && h.options.Bypass.Contains(context.Background(), addr.Network(), addr.String()) {
-					log.Warn("bypass: ", addr)
-					return nil
+				addrs := []net.Addr{addr}
+				if len(targets) > 0 {
+					dst.Store(&addr)
+					addrs = targets
 				}
 
-				if _, err = pc2.WriteTo(b[:n], addr); err != nil {
-					return err
+				for _, addr := range addrs {
+					host := hostOf(addr)
+					if h.md.sniffing && host == "" {
+						if host, _ = forward.SniffDatagram(b[:n]); host != "" {
+							log.Debugf("sniffing: %s host=%s", addr, host)
+							hosts.Store(addr.String(), host)
+						}
+					}
+
+					if h.options.Bypass != nil && h.options.Bypass.Contains(context.Background(), addr.Network(), addr.String(), bypass.WithHostOpton(host)) {
+						log.Warn("bypass: ", addr)
+						continue
+					}
+
+					if _, err = pc2.WriteTo(b[:n], addr); err != nil {
+						return err
+					}
+
+					log.Tracef("%s >>> %s data: %d",
+						pc2.LocalAddr(), addr, n)
 				}
-
-				log.Tracef("%s >>> %s data: %d",
-					pc2.LocalAddr(), addr, n)
 				return nil
 			}()
 
@@ -167,12 +216,21 @@ func (h *ssuHandler) relayPacket(pc1, pc2 net.PacketConn, log logger.Logger) (er
 					return err
 				}
 
-				if h.options.Bypass != nil && h.options.Bypass.Contains(context.Background(), raddr.Network(), raddr.String()) {
+				if h.options.Bypass != nil && h.options.Bypass.Contains(context.Background(), raddr.Network(), raddr.String(), bypass.WithHostOpton(hostOf(raddr))) {
 					log.Warn("bypass: ", raddr)
 					return nil
 				}
 
-				if _, err = pc1.WriteTo(b[:n], raddr); err != nil {
+				addr := raddr
+				if len(targets) > 0 {
+					v := dst.Load()
+					if v == nil {
+						return nil
+					}
+					addr = *v
+				}
+
+				if _, err = pc1.WriteTo(b[:n], addr); err != nil {
 					return err
 				}
 
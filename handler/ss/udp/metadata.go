@@ -12,17 +12,34 @@ type metadata struct {
 	key         string
 	readTimeout time.Duration
 	bufferSize  int
+	sniffing    bool
+	// targets override the destinations of the datagrams from the client,
+	// each datagram is relayed to all the targets.
+	targets []string
+	// blockBitTorrent drops the DHT and UDP tracker datagrams.
+	blockBitTorrent bool
 }
 
 func (h *ssuHandler) parseMetadata(md mdata.Metadata) (err error) {
 	const (
-		key         = "key"
-		readTimeout = "readTimeout"
-		bufferSize  = "bufferSize"
+		key             = "key"
+		readTimeout     = "readTimeout"
+		bufferSize      = "bufferSize"
+		sniffing        = "sniffing"
+		target          = "target"
+		targets         = "targets"
+		blockBitTorrent = "blockBitTorrent"
 	)
 
 	h.md.key = mdutil.GetString(md, key)
 	h.md.readTimeout = mdutil.GetDuration(md, readTimeout)
+	h.md.sniffing = mdutil.GetBool(md, sniffing)
+	h.md.blockBitTorrent = mdutil.GetBool(md, blockBitTorrent)
+
+	if v := mdutil.GetString(md, target); v != "" {
+		h.md.targets = append(h.md.targets, v)
+	}
+	h.md.targets = append(h.md.targets, mdutil.GetStrings(md, targets)...)
 
 	if bs := mdutil.GetInt(md, bufferSize); bs > 0 {
 		h.md.bufferSize = int(math.Min(math.Max(float64(bs), 512), 64*1024))
@@ -6,16 +6,19 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/go-gost/core/chain"
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/handler"
 	"github.com/go-gost/core/hop"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
+	"github.com/go-gost/x/internal/bufpool"
+	"github.com/go-gost/x/internal/util/mcast"
+	"github.com/go-gost/x/internal/util/mss"
 	"github.com/go-gost/x/internal/util/ss"
 	tap_util "github.com/go-gost/x/internal/util/tap"
 	"github.com/go-gost/x/registry"
@@ -189,6 +192,7 @@ func (h *tapHandler) handleLoop(ctx context.Context, conn net.Conn, addr net.Add
 
 func (h *tapHandler) transport(tap net.Conn, conn net.PacketConn, raddr net.Addr, config *tap_util.Config, log logger.Logger) error {
 	errc := make(chan error, 1)
+	mss4, mss6 := h.mss(config)
 
 	go func() {
 		for {
@@ -208,6 +212,8 @@ func (h *tapHandler) transport(tap net.Conn, conn net.PacketConn, raddr net.Addr
 					return nil
 				}
 
+				mss.ClampFrame(b[:n], mss4, mss6)
+
 				src := waterutil.MACSource(b[:n])
 				dst := waterutil.MACDestination(b[:n])
 				eType := etherType(waterutil.MACEthertype(b[:n]))
@@ -229,6 +235,13 @@ func (h *tapHandler) transport(tap net.Conn, conn net.PacketConn, raddr net.Addr
 					return nil
 				}
 
+				if group, ok := mcast.FrameDestination(b[:n]); ok && h.md.multicast != nil {
+					if h.md.multicast.Allow(group, "") {
+						h.forwardMulticast(conn, b[:n], group, "")
+					}
+					return nil
+				}
+
 				var addr net.Addr
 				if v, ok := h.routes.Load(hwAddrToTapRouteKey(dst)); ok {
 					addr = v.(net.Addr)
@@ -267,6 +280,8 @@ func (h *tapHandler) transport(tap net.Conn, conn net.PacketConn, raddr net.Addr
 					return nil
 				}
 
+				mss.ClampFrame(b[:n], mss4, mss6)
+
 				src := waterutil.MACSource(b[:n])
 				dst := waterutil.MACDestination(b[:n])
 				eType := etherType(waterutil.MACEthertype(b[:n]))
@@ -300,6 +315,16 @@ func (h *tapHandler) transport(tap net.Conn, conn net.PacketConn, raddr net.Addr
 					})
 				}
 
+				if h.md.multicast != nil {
+					h.md.multicast.ObserveFrame(b[:n], addr.String())
+					if group, ok := mcast.FrameDestination(b[:n]); ok {
+						if !h.md.multicast.Allow(group, addr.String()) {
+							return nil
+						}
+						h.forwardMulticast(conn, b[:n], group, addr.String())
+					}
+				}
+
 				if v, ok := h.routes.Load(hwAddrToTapRouteKey(dst)); ok {
 					log.Debugf("find route: %s -> %s", dst, v)
 					_, err := conn.WriteTo(b[:n], v.(net.Addr))
@@ -350,3 +375,33 @@ func hwAddrToTapRouteKey(addr net.HardwareAddr) (key tapRouteKey) {
 	copy(key[:], addr)
 	return
 }
+
+// forwardMulticast sends the multicast frame b to the peers joined the group except the source peer src.
+func (h *tapHandler) forwardMulticast(conn net.PacketConn, b []byte, group netip.Addr, src string) {
+	sent := make(map[string]struct{})
+	h.routes.Range(func(k, v any) bool {
+		addr := v.(net.Addr)
+		key := addr.String()
+		if _, ok := sent[key]; ok || key == src {
+			return true
+		}
+		sent[key] = struct{}{}
+
+		if h.md.multicast.Member(group, key) {
+			conn.WriteTo(b, addr)
+		}
+		return true
+	})
+}
+
+// mss returns the maximum segment sizes of IPv4 and IPv6 the TCP SYN packets are clamped to,
+// zero means no clamping.
+func (h *tapHandler) mss(config *tap_util.Config) (v4, v6 int) {
+	if h.md.mss > 0 {
+		return h.md.mss, h.md.mss
+	}
+	if h.md.mssAuto && config != nil {
+		return mss.FromMTU(config.MTU)
+	}
+	return 0, 0
+}
@@ -3,17 +3,24 @@ package tap
 import (
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/mcast"
 )
 
 type metadata struct {
 	key        string
 	bufferSize int
+	// the MSS option of the TCP SYN packets is clamped to mss,
+	// or derived from the MTU of the tap device if mssAuto is true.
+	mss       int
+	mssAuto   bool
+	multicast *mcast.Forwarder
 }
 
 func (h *tapHandler) parseMetadata(md mdata.Metadata) (err error) {
 	const (
 		key        = "key"
 		bufferSize = "bufferSize"
+		mss        = "mss"
 	)
 
 	h.md.key = mdutil.GetString(md, key)
@@ -21,5 +28,13 @@ func (h *tapHandler) parseMetadata(md mdata.Metadata) (err error) {
 	if h.md.bufferSize <= 0 {
 		h.md.bufferSize = 4096
 	}
+
+	if mdutil.GetString(md, mss) == "auto" {
+		h.md.mssAuto = true
+	} else {
+		h.md.mss = mdutil.GetInt(md, mss)
+	}
+
+	h.md.multicast = mcast.ParseForwarder(md)
 	return
 }
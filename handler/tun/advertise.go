@@ -0,0 +1,197 @@
+package tun
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+)
+
+const (
+	// 16-byte address followed by 1-byte prefix length.
+	advertEntryLength = 17
+)
+
+var (
+	advertHeader = []byte("GOSR")
+)
+
+// advertise sends the subnets behind the client to the server periodically,
+// so the server routes the packets destined to them to this client.
+// The server learns only the subnets allowed for the client by its advertise.allow metadata.
+//
+// The advertisement is a 4-byte magic header followed by the route entries.
+func (h *tunHandler) advertise(ctx context.Context, conn net.Conn, log logger.Logger) {
+	if len(h.md.advertise) == 0 {
+		return
+	}
+
+	b := make([]byte, len(advertHeader), len(advertHeader)+len(h.md.advertise)*advertEntryLength)
+	copy(b, advertHeader)
+	for _, prefix := range h.md.advertise {
+		a16 := prefix.Addr().As16()
+		bits := prefix.Bits()
+		if prefix.Addr().Is4() {
+			bits += 96
+		}
+		b = append(b, a16[:]...)
+		b = append(b, byte(bits))
+	}
+
+	period := h.md.keepAlivePeriod
+	if period <= 0 {
+		period = defaultKeepAlivePeriod
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		if _, err := conn.Write(b); err != nil {
+			log.Warnf("advertise: %v", err)
+			return
+		}
+		log.Debugf("advertise %v", h.md.advertise)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func isAdvert(b []byte) bool {
+	return len(b) > len(advertHeader) &&
+		(len(b)-len(advertHeader))%advertEntryLength == 0 &&
+		bytes.Equal(b[:len(advertHeader)], advertHeader)
+}
+
+func parseAdvert(b []byte) (prefixes []netip.Prefix) {
+	b = b[len(advertHeader):]
+	for ; len(b) >= advertEntryLength; b = b[advertEntryLength:] {
+		addr := netip.AddrFrom16([16]byte(b[:16]))
+		bits := int(b[16])
+		if addr.Is4In6() {
+			addr = addr.Unmap()
+			bits -= 96
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return
+}
+
+type learnedRoute struct {
+	prefix netip.Prefix
+	addr   net.Addr
+	expire time.Time
+}
+
+// routeTable is the table of the subnets learned from the advertisements of the clients.
+type routeTable struct {
+	routes []*learnedRoute
+	mu     sync.RWMutex
+}
+
+// update replaces the routes advertised by addr, the prefixes not within the allowed ones are ignored.
+// The default route is accepted only if it is allowed explicitly.
+func (t *routeTable) update(addr net.Addr, prefixes []netip.Prefix, allowed []netip.Prefix, ttl time.Duration, log logger.Logger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	routes := t.routes[:0]
+	for _, r := range t.routes {
+		if r.addr.String() == addr.String() || now.After(r.expire) {
+			continue
+		}
+		routes = append(routes, r)
+	}
+
+	for _, prefix := range prefixes {
+		if !prefixAllowed(prefix, allowed) {
+			log.Warnf("route %s from %s is not allowed, ignored", prefix, addr)
+			continue
+		}
+
+		found := false
+		for _, r := range routes {
+			if r.prefix == prefix {
+				found = true
+				break
+			}
+		}
+		if found {
+			log.Warnf("route %s from %s conflicts with an existing route, ignored", prefix, addr)
+			continue
+		}
+		routes = append(routes, &learnedRoute{
+			prefix: prefix,
+			addr:   addr,
+			expire: now.Add(ttl),
+		})
+	}
+	t.routes = routes
+}
+
+// lookup returns the peer address of the longest matched prefix for dst.
+func (t *routeTable) lookup(dst net.IP) net.Addr {
+	ip, ok := netip.AddrFromSlice(dst)
+	if !ok {
+		return nil
+	}
+	ip = ip.Unmap()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now := time.Now()
+	var route *learnedRoute
+	for _, r := range t.routes {
+		if now.After(r.expire) || !r.prefix.Contains(ip) {
+			continue
+		}
+		if route == nil || r.prefix.Bits() > route.prefix.Bits() {
+			route = r
+		}
+	}
+	if route == nil {
+		return nil
+	}
+	return route.addr
+}
+
+// prefixAllowed reports whether prefix is within one of the allowed prefixes.
+func prefixAllowed(prefix netip.Prefix, allowed []netip.Prefix) bool {
+	for _, p := range allowed {
+		if p.Addr().Is4() == prefix.Addr().Is4() &&
+			p.Bits() <= prefix.Bits() && p.Contains(prefix.Addr()) &&
+			// the default route must be allowed explicitly.
+			(prefix.Bits() > 0 || p.Bits() == 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerPrefixes returns the prefixes the client at addr is allowed to advertise,
+// ok is false if addr is not the address of a client which has sent the keepalive.
+func (h *tunHandler) peerPrefixes(addr net.Addr) (allowed []netip.Prefix, ok bool) {
+	allowed = append(allowed, h.md.advertiseAllow["*"]...)
+	h.routes.Range(func(k, v any) bool {
+		if v.(net.Addr).String() == addr.String() {
+			ok = true
+			ip := netip.AddrFrom16(k.(tunRouteKey)).Unmap()
+			allowed = append(allowed, h.md.advertiseAllow[ip.String()]...)
+		}
+		return true
+	})
+	return
+}
@@ -7,8 +7,9 @@ import (
 	"net"
 	"time"
 
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/internal/bufpool"
+	"github.com/go-gost/x/internal/util/mss"
 	tun_util "github.com/go-gost/x/internal/util/tun"
 	"github.com/songgao/water/waterutil"
 	"golang.org/x/net/ipv4"
@@ -45,8 +46,9 @@ func (h *tunHandler) handleClient(ctx context.Context, conn net.Conn, raddr stri
 			defer cancel()
 
 			go h.keepalive(ctx, cc, ips)
+			go h.advertise(ctx, cc, log)
 
-			return h.transportClient(conn, cc, log)
+			return h.transportClient(conn, cc, config, log)
 		}()
 		if err == ErrTun {
 			return err
@@ -94,8 +96,9 @@ func (h *tunHandler) keepalive(ctx context.Context, conn net.Conn, ips []net.IP)
 	}
 }
 
-func (h *tunHandler) transportClient(tun io.ReadWriter, conn net.Conn, log logger.Logger) error {
+func (h *tunHandler) transportClient(tun io.ReadWriter, conn net.Conn, config *tun_util.Config, log logger.Logger) error {
 	errc := make(chan error, 1)
+	mss4, mss6 := h.mss(config)
 
 	go func() {
 		for {
@@ -107,6 +110,7 @@ func (h *tunHandler) transportClient(tun io.ReadWriter, conn net.Conn, log logge
 				if err != nil {
 					return ErrTun
 				}
+				mss.Clamp(b[:n], mss4, mss6)
 
 				if waterutil.IsIPv4(b[:n]) {
 					header, err := ipv4.ParseHeader(b[:n])
@@ -192,6 +196,7 @@ func (h *tunHandler) transportClient(tun io.ReadWriter, conn net.Conn, log logge
 					return nil
 				}
 
+				mss.Clamp(b[:n], mss4, mss6)
 				if _, err = tun.Write(b[:n]); err != nil {
 					return ErrTun
 				}
@@ -13,6 +13,7 @@ import (
 	"github.com/go-gost/core/hop"
 	md "github.com/go-gost/core/metadata"
 	"github.com/go-gost/core/router"
+	"github.com/go-gost/x/internal/util/mss"
 	tun_util "github.com/go-gost/x/internal/util/tun"
 	"github.com/go-gost/x/registry"
 	"github.com/songgao/water/waterutil"
@@ -30,6 +31,7 @@ func init() {
 type tunHandler struct {
 	hop     hop.Hop
 	routes  sync.Map
+	learned routeTable
 	router  *chain.Router
 	md      metadata
 	options handler.Options
@@ -109,11 +111,27 @@ func (h *tunHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.
 	return h.handleServer(ctx, conn, config, log)
 }
 
+// mss returns the maximum segment sizes of IPv4 and IPv6 the TCP SYN packets are clamped to,
+// zero means no clamping.
+func (h *tunHandler) mss(config *tun_util.Config) (v4, v6 int) {
+	if h.md.mss > 0 {
+		return h.md.mss, h.md.mss
+	}
+	if h.md.mssAuto && config != nil {
+		return mss.FromMTU(config.MTU)
+	}
+	return 0, 0
+}
+
 func (h *tunHandler) findRouteFor(ctx context.Context, dst net.IP, router router.Router) net.Addr {
 	if v, ok := h.routes.Load(ipToTunRouteKey(dst)); ok {
 		return v.(net.Addr)
 	}
 
+	if addr := h.learned.lookup(dst); addr != nil {
+		return addr
+	}
+
 	if router == nil {
 		return nil
 	}
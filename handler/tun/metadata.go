@@ -1,10 +1,14 @@
 package tun
 
 import (
+	"fmt"
+	"net/netip"
+	"strings"
 	"time"
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/mcast"
 )
 
 const (
@@ -16,6 +20,15 @@ type metadata struct {
 	bufferSize      int
 	keepAlivePeriod time.Duration
 	passphrase      string
+	// the MSS option of the TCP SYN packets is clamped to mss,
+	// or derived from the MTU of the tun device if mssAuto is true.
+	mss     int
+	mssAuto bool
+	// the subnets behind the client advertised to the server.
+	advertise []netip.Prefix
+	multicast *mcast.Forwarder
+	// the subnets the clients are allowed to advertise, keyed by the client IP or "*" for all clients.
+	advertiseAllow map[string][]netip.Prefix
 }
 
 func (h *tunHandler) parseMetadata(md mdata.Metadata) (err error) {
@@ -24,6 +37,9 @@ func (h *tunHandler) parseMetadata(md mdata.Metadata) (err error) {
 		keepAlive       = "keepAlive"
 		keepAlivePeriod = "ttl"
 		passphrase      = "passphrase"
+		mss             = "mss"
+		advertise       = "advertise"
+		advertiseAllow  = "advertise.allow"
 	)
 
 	h.md.bufferSize = mdutil.GetInt(md, bufferSize)
@@ -39,5 +55,51 @@ func (h *tunHandler) parseMetadata(md mdata.Metadata) (err error) {
 	}
 
 	h.md.passphrase = mdutil.GetString(md, passphrase)
+
+	if mdutil.GetString(md, mss) == "auto" {
+		h.md.mssAuto = true
+	} else {
+		h.md.mss = mdutil.GetInt(md, mss)
+	}
+
+	h.md.multicast = mcast.ParseForwarder(md)
+
+	ss := mdutil.GetStrings(md, advertise)
+	if len(ss) == 0 {
+		ss = strings.Split(mdutil.GetString(md, advertise), ",")
+	}
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return fmt.Errorf("advertise %s: %w", s, err)
+		}
+		h.md.advertise = append(h.md.advertise, prefix.Masked())
+	}
+
+	for k, v := range mdutil.GetStringMapString(md, advertiseAllow) {
+		if k != "*" {
+			ip, err := netip.ParseAddr(k)
+			if err != nil {
+				return fmt.Errorf("advertise.allow %s: %w", k, err)
+			}
+			k = ip.Unmap().String()
+		}
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				return fmt.Errorf("advertise.allow %s: %w", s, err)
+			}
+			if h.md.advertiseAllow == nil {
+				h.md.advertiseAllow = make(map[string][]netip.Prefix)
+			}
+			h.md.advertiseAllow[k] = append(h.md.advertiseAllow[k], prefix.Masked())
+		}
+	}
 	return
 }
@@ -8,8 +8,10 @@ import (
 	"net/netip"
 	"time"
 
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/internal/bufpool"
+	"github.com/go-gost/x/internal/util/mcast"
+	"github.com/go-gost/x/internal/util/mss"
 	tun_util "github.com/go-gost/x/internal/util/tun"
 	"github.com/songgao/water/waterutil"
 	"golang.org/x/net/ipv4"
@@ -38,6 +40,7 @@ func (h *tunHandler) handleServer(ctx context.Context, conn net.Conn, config *tu
 
 func (h *tunHandler) transportServer(ctx context.Context, tun io.ReadWriter, conn net.PacketConn, config *tun_util.Config, log logger.Logger) error {
 	errc := make(chan error, 1)
+	mss4, mss6 := h.mss(config)
 
 	go func() {
 		for {
@@ -52,6 +55,7 @@ func (h *tunHandler) transportServer(ctx context.Context, tun io.ReadWriter, con
 				if n == 0 {
 					return nil
 				}
+				mss.Clamp(b[:n], mss4, mss6)
 
 				var src, dst net.IP
 				if waterutil.IsIPv4(b[:n]) {
@@ -82,6 +86,13 @@ func (h *tunHandler) transportServer(ctx context.Context, tun io.ReadWriter, con
 					return nil
 				}
 
+				if group, ok := mcast.Destination(b[:n], config.Net); ok && h.md.multicast != nil {
+					if h.md.multicast.Allow(group, "") {
+						h.forwardMulticast(conn, b[:n], group, nil, log)
+					}
+					return nil
+				}
+
 				addr := h.findRouteFor(ctx, dst, config.Router)
 				if addr == nil {
 					log.Debugf("no route for %s -> %s, packet discarded", src, dst)
@@ -116,6 +127,24 @@ func (h *tunHandler) transportServer(ctx context.Context, tun io.ReadWriter, con
 				if n == 0 {
 					return nil
 				}
+				if isAdvert(b[:n]) {
+					// only the authenticated clients can advertise routes.
+					allowed, ok := h.peerPrefixes(addr)
+					if !ok {
+						log.Debugf("advertisement from unknown peer %v, discarded", addr)
+						return nil
+					}
+					prefixes := parseAdvert(b[:n])
+					log.Debugf("advertisement from %v => %v", addr, prefixes)
+
+					ttl := h.md.keepAlivePeriod
+					if ttl <= 0 {
+						ttl = defaultKeepAlivePeriod
+					}
+					h.learned.update(addr, prefixes, allowed, 3*ttl, log)
+					return nil
+				}
+
 				if n > keepAliveHeaderLength && bytes.Equal(b[:4], magicHeader) {
 					var peerIPs []net.IP
 					data := b[keepAliveHeaderLength:n]
@@ -203,6 +232,23 @@ func (h *tunHandler) transportServer(ctx context.Context, tun io.ReadWriter, con
 					return nil
 				}
 
+				mss.Clamp(b[:n], mss4, mss6)
+
+				if h.md.multicast != nil {
+					h.md.multicast.Observe(b[:n], addr.String())
+					if group, ok := mcast.Destination(b[:n], config.Net); ok {
+						if !h.md.multicast.Allow(group, addr.String()) {
+							return nil
+						}
+						h.forwardMulticast(conn, b[:n], group, addr, log)
+						// delivered to the local network as well.
+						if _, err := tun.Write(b[:n]); err != nil {
+							return ErrTun
+						}
+						return nil
+					}
+				}
+
 				if addr := h.findRouteFor(ctx, dst, config.Router); addr != nil {
 					log.Debugf("find route: %s -> %s", dst, addr)
 
@@ -230,6 +276,28 @@ func (h *tunHandler) transportServer(ctx context.Context, tun io.ReadWriter, con
 	return err
 }
 
+// forwardMulticast sends the multicast or broadcast packet b to the peers except the source peer src.
+func (h *tunHandler) forwardMulticast(conn net.PacketConn, b []byte, group netip.Addr, src net.Addr, log logger.Logger) {
+	sent := make(map[string]struct{})
+	h.routes.Range(func(k, v any) bool {
+		addr := v.(net.Addr)
+		key := addr.String()
+		if _, ok := sent[key]; ok || (src != nil && key == src.String()) {
+			return true
+		}
+		sent[key] = struct{}{}
+
+		if !h.md.multicast.Member(group, key) {
+			return true
+		}
+		if _, err := conn.WriteTo(b, addr); err != nil {
+			log.Warnf("multicast %s to %s: %v", group, addr, err)
+		}
+		return true
+	})
+	log.Tracef("multicast %s to %d peers", group, len(sent))
+}
+
 func (h *tunHandler) updateRoute(ip net.IP, addr net.Addr, log logger.Logger) {
 	rkey := ipToTunRouteKey(ip)
 	if actual, loaded := h.routes.LoadOrStore(rkey, addr); loaded {
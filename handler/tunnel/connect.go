@@ -10,6 +10,7 @@ import (
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/relay"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/limiter/traffic/wrapper"
 )
@@ -29,8 +30,8 @@ func (h *tunnelHandler) handleConnect(ctx context.Context, req *relay.Request, c
 	if h.options.Bypass != nil && h.options.Bypass.Contains(ctx, network, dstAddr) {
 		log.Debug("bypass: ", dstAddr)
 		resp.Status = relay.StatusForbidden
-		_, err := resp.WriteTo(conn)
-		return err
+		resp.WriteTo(conn)
+		return xerrors.ErrBypass
 	}
 
 	host, _, _ := net.SplitHostPort(dstAddr)
@@ -109,7 +110,7 @@ func (h *tunnelHandler) handleConnect(ctx context.Context, req *relay.Request, c
 
 	t := time.Now()
 	log.Debugf("%s <-> %s", conn.RemoteAddr(), cc.RemoteAddr())
-	xnet.Transport(rw, cc)
+	xnet.Transport(rw, cc, xnet.IdleKindTransportOption(xnet.IdleTunnel))
 	log.WithFields(map[string]any{
 		"duration": time.Since(t),
 	}).Debugf("%s >-< %s", conn.RemoteAddr(), cc.RemoteAddr())
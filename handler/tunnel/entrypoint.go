@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-gost/core/handler"
@@ -23,17 +22,19 @@ import (
 	xio "github.com/go-gost/x/internal/io"
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/net/proxyproto"
+	"github.com/go-gost/x/internal/util/forwarded"
 	climiter "github.com/go-gost/x/limiter/conn/wrapper"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
 	metrics "github.com/go-gost/x/metrics/wrapper"
 )
 
 type entrypoint struct {
-	node    string
-	pool    *ConnectorPool
-	ingress ingress.Ingress
-	sd      sd.SD
-	log     logger.Logger
+	node      string
+	pool      *ConnectorPool
+	ingress   ingress.Ingress
+	sd        sd.SD
+	forwarded *forwarded.Policy
+	log       logger.Logger
 }
 
 func (ep *entrypoint) handle(ctx context.Context, conn net.Conn) error {
@@ -108,7 +109,7 @@ func (ep *entrypoint) handle(ctx context.Context, conn net.Conn) error {
 			})
 
 			remoteAddr := conn.RemoteAddr()
-			if addr := ep.getRealClientAddr(req, remoteAddr); addr != remoteAddr {
+			if addr := ep.forwarded.ClientAddr(req, remoteAddr); addr != remoteAddr {
 				log = log.WithFields(map[string]any{
 					"src": addr.String(),
 				})
@@ -152,6 +153,8 @@ func (ep *entrypoint) handle(ctx context.Context, conn net.Conn) error {
 				}).WriteTo(cc)
 			}
 
+			ep.forwarded.Apply(req, conn.RemoteAddr())
+
 			if err := req.Write(cc); err != nil {
 				cc.Close()
 				log.Errorf("send request: %v", err)
@@ -159,7 +162,7 @@ func (ep *entrypoint) handle(ctx context.Context, conn net.Conn) error {
 			}
 
 			if req.Header.Get("Upgrade") == "websocket" {
-				err := xnet.Transport(cc, xio.NewReadWriter(br, conn))
+				err := xnet.Transport(cc, xio.NewReadWriter(br, conn), xnet.IdleKindTransportOption(xnet.IdleTunnel))
 				if err == nil {
 					err = io.EOF
 				}
@@ -285,7 +288,7 @@ func (ep *entrypoint) handleConnect(ctx context.Context, conn net.Conn, log logg
 
 	t := time.Now()
 	log.Debugf("%s <-> %s", conn.RemoteAddr(), cc.RemoteAddr())
-	xnet.Transport(conn, cc)
+	xnet.Transport(conn, cc, xnet.IdleKindTransportOption(xnet.IdleTunnel))
 	log.WithFields(map[string]any{
 		"duration": time.Since(t),
 	}).Debugf("%s >-< %s", conn.RemoteAddr(), cc.RemoteAddr())
@@ -293,37 +296,6 @@ func (ep *entrypoint) handleConnect(ctx context.Context, conn net.Conn, log logg
 	return nil
 }
 
-func (ep *entrypoint) getRealClientAddr(req *http.Request, raddr net.Addr) net.Addr {
-	if req == nil {
-		return nil
-	}
-	// cloudflare CDN
-	sip := req.Header.Get("CF-Connecting-IP")
-	if sip == "" {
-		ss := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
-		if len(ss) > 0 && ss[0] != "" {
-			sip = ss[0]
-		}
-	}
-	if sip == "" {
-		sip = req.Header.Get("X-Real-Ip")
-	}
-
-	ip := net.ParseIP(sip)
-	if ip == nil {
-		return raddr
-	}
-
-	_, sp, _ := net.SplitHostPort(raddr.String())
-
-	port, _ := strconv.Atoi(sp)
-
-	return &net.TCPAddr{
-		IP:   ip,
-		Port: port,
-	}
-}
-
 type tcpListener struct {
 	ln      net.Listener
 	options listener.Options
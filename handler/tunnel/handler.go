@@ -16,7 +16,9 @@ import (
 	"github.com/go-gost/core/service"
 	"github.com/go-gost/relay"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
 	xnet "github.com/go-gost/x/internal/net"
+	mdx "github.com/go-gost/x/metadata"
 	xrecorder "github.com/go-gost/x/recorder"
 	"github.com/go-gost/x/registry"
 	xservice "github.com/go-gost/x/service"
@@ -28,12 +30,15 @@ var (
 	ErrUnknownCmd         = errors.New("unknown command")
 	ErrTunnelID           = errors.New("invalid tunnel ID")
 	ErrTunnelNotAvailable = errors.New("tunnel not available")
-	ErrUnauthorized       = errors.New("unauthorized")
-	ErrRateLimit          = errors.New("rate limiting exceeded")
+	ErrUnauthorized       = xerrors.Wrap(xerrors.KindAuth, errors.New("unauthorized"))
+	ErrRateLimit          = xerrors.Wrap(xerrors.KindLimiter, errors.New("rate limiting exceeded"))
 )
 
 func init() {
 	registry.HandlerRegistry().Register("tunnel", NewHandler)
+	mdx.RegisterDefaults(mdx.KindHandler, "tunnel",
+		mdx.Default{Keys: []string{"tunnel.ttl"}, Value: defaultTTL},
+	)
 }
 
 type tunnelHandler struct {
@@ -83,12 +88,14 @@ func (h *tunnelHandler) Init(md md.Metadata) (err error) {
 	}
 
 	h.pool = NewConnectorPool(h.id, h.md.sd)
+	registerPool(h.options.Service, h.pool)
 
 	h.ep = &entrypoint{
-		node:    h.id,
-		pool:    h.pool,
-		ingress: h.md.ingress,
-		sd:      h.md.sd,
+		node:      h.id,
+		pool:      h.pool,
+		ingress:   h.md.ingress,
+		sd:        h.md.sd,
+		forwarded: h.md.forwarded,
 		log: h.log.WithFields(map[string]any{
 			"kind": "entrypoint",
 		}),
@@ -254,6 +261,11 @@ func (h *tunnelHandler) Handle(ctx context.Context, conn net.Conn, opts ...handl
 
 	case relay.CmdBind:
 		log.Debugf("bind: %s >> %s/%s", srcAddr, dstAddr, network)
+		if !h.authTunnel(ctx, tunnelID, pass) {
+			resp.Status = relay.StatusUnauthorized
+			resp.WriteTo(conn)
+			return ErrUnauthorized
+		}
 		return h.handleBind(ctx, conn, network, dstAddr, tunnelID, log)
 	default:
 		resp.Status = relay.StatusBadRequest
@@ -264,9 +276,22 @@ func (h *tunnelHandler) Handle(ctx context.Context, conn net.Conn, opts ...handl
 
 // Close implements io.Closer interface.
 func (h *tunnelHandler) Close() error {
+	if h.pool != nil {
+		unregisterPool(h.pool)
+	}
 	return nil
 }
 
+// authTunnel checks the secret of the tunnel against the tunnel authenticator,
+// the tunnel ID is used as the username, so only the clients knowing the secret can bind to it.
+func (h *tunnelHandler) authTunnel(ctx context.Context, tunnelID relay.TunnelID, secret string) bool {
+	if h.md.tunnelAuther == nil {
+		return true
+	}
+	_, ok := h.md.tunnelAuther.Authenticate(ctx, tunnelID.String(), secret)
+	return ok
+}
+
 func (h *tunnelHandler) checkRateLimit(addr net.Addr) bool {
 	if h.options.RateLimiter == nil {
 		return true
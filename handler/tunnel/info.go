@@ -0,0 +1,107 @@
+package tunnel
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConnectorInfo is the runtime information of a tunnel connector.
+type ConnectorInfo struct {
+	ID        string    `json:"id"`
+	Network   string    `json:"network"`
+	Weight    int       `json:"weight"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TunnelInfo is the runtime information of an active tunnel.
+type TunnelInfo struct {
+	ID         string          `json:"id"`
+	Service    string          `json:"service"`
+	Node       string          `json:"node"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	Connectors []ConnectorInfo `json:"connectors"`
+}
+
+var (
+	pools   = make(map[*ConnectorPool]string)
+	poolsMu sync.RWMutex
+)
+
+func registerPool(service string, p *ConnectorPool) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	pools[p] = service
+}
+
+func unregisterPool(p *ConnectorPool) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	delete(pools, p)
+}
+
+// Tunnels returns the active tunnels of all the tunnel handlers,
+// a tunnel is active if it has at least one connector alive.
+func Tunnels() []TunnelInfo {
+	poolsMu.RLock()
+	defer poolsMu.RUnlock()
+
+	var tunnels []TunnelInfo
+	for p, service := range pools {
+		for _, t := range p.Tunnels() {
+			t.Service = service
+			tunnels = append(tunnels, t)
+		}
+	}
+	sort.Slice(tunnels, func(i, j int) bool {
+		if tunnels[i].Service != tunnels[j].Service {
+			return tunnels[i].Service < tunnels[j].Service
+		}
+		return tunnels[i].ID < tunnels[j].ID
+	})
+	return tunnels
+}
+
+// Tunnels returns the active tunnels of the pool.
+func (p *ConnectorPool) Tunnels() []TunnelInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var tunnels []TunnelInfo
+	for _, t := range p.tunnels {
+		if info := t.Info(); len(info.Connectors) > 0 {
+			tunnels = append(tunnels, info)
+		}
+	}
+	return tunnels
+}
+
+// Info returns the runtime information of the tunnel with the connectors alive.
+func (t *Tunnel) Info() TunnelInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	info := TunnelInfo{
+		ID:        t.id.String(),
+		Node:      t.node,
+		CreatedAt: t.t,
+	}
+	for _, c := range t.connectors {
+		if c.Session().IsClosed() {
+			continue
+		}
+		network := "tcp"
+		if c.id.IsUDP() {
+			network = "udp"
+		}
+		info.Connectors = append(info.Connectors, ConnectorInfo{
+			ID:        c.id.String(),
+			Network:   network,
+			Weight:    int(c.id.Weight()),
+			CreatedAt: c.t,
+		})
+	}
+	return info
+}
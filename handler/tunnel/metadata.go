@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-gost/core/auth"
 	"github.com/go-gost/core/ingress"
 	"github.com/go-gost/core/logger"
 	mdata "github.com/go-gost/core/metadata"
@@ -11,6 +12,7 @@ import (
 	"github.com/go-gost/core/sd"
 	"github.com/go-gost/relay"
 	xingress "github.com/go-gost/x/ingress"
+	"github.com/go-gost/x/internal/util/forwarded"
 	"github.com/go-gost/x/internal/util/mux"
 	"github.com/go-gost/x/registry"
 )
@@ -26,9 +28,11 @@ type metadata struct {
 	entryPointProxyProtocol int
 	directTunnel            bool
 	tunnelTTL               time.Duration
+	tunnelAuther            auth.Authenticator
 	ingress                 ingress.Ingress
 	sd                      sd.SD
 	muxCfg                  *mux.Config
+	forwarded               *forwarded.Policy
 }
 
 func (h *tunnelHandler) parseMetadata(md mdata.Metadata) (err error) {
@@ -39,21 +43,25 @@ func (h *tunnelHandler) parseMetadata(md mdata.Metadata) (err error) {
 		h.md.tunnelTTL = defaultTTL
 	}
 	h.md.directTunnel = mdutil.GetBool(md, "tunnel.direct")
+	h.md.tunnelAuther = registry.AutherRegistry().Get(mdutil.GetString(md, "tunnel.auther"))
 	h.md.entryPoint = mdutil.GetString(md, "entrypoint")
 	h.md.entryPointID = parseTunnelID(mdutil.GetString(md, "entrypoint.id"))
 	h.md.entryPointProxyProtocol = mdutil.GetInt(md, "entrypoint.ProxyProtocol")
+	h.md.forwarded = forwarded.ParsePolicy(md, forwarded.ModeNone)
 
 	h.md.ingress = registry.IngressRegistry().Get(mdutil.GetString(md, "ingress"))
 	if h.md.ingress == nil {
-		var rules []*ingress.Rule
+		var rules []*xingress.Rule
 		for _, s := range strings.Split(mdutil.GetString(md, "tunnel"), ",") {
 			ss := strings.SplitN(s, ":", 2)
 			if len(ss) != 2 {
 				continue
 			}
-			rules = append(rules, &ingress.Rule{
-				Hostname: ss[0],
-				Endpoint: ss[1],
+			rules = append(rules, &xingress.Rule{
+				Rule: ingress.Rule{
+					Hostname: ss[0],
+					Endpoint: ss[1],
+				},
 			})
 		}
 		if len(rules) > 0 {
@@ -0,0 +1,318 @@
+package wsbridge
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/handler"
+	"github.com/go-gost/core/hop"
+	"github.com/go-gost/core/logger"
+	md "github.com/go-gost/core/metadata"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/ws"
+	"github.com/go-gost/x/registry"
+	"github.com/gorilla/websocket"
+)
+
+func init() {
+	registry.HandlerRegistry().Register("wsbridge", NewHandler)
+}
+
+// wsbridgeHandler bridges the websocket clients to the TCP backends by the paths,
+// or the TCP clients to the websocket backend if the bridge URL is set.
+type wsbridgeHandler struct {
+	hop      hop.Hop
+	router   *chain.Router
+	upgrader *websocket.Upgrader
+	server   *http.Server
+	ln       *singleConnListener
+	md       metadata
+	options  handler.Options
+}
+
+func NewHandler(opts ...handler.Option) handler.Handler {
+	options := handler.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &wsbridgeHandler{
+		options: options,
+	}
+}
+
+func (h *wsbridgeHandler) Init(md md.Metadata) (err error) {
+	if err = h.parseMetadata(md); err != nil {
+		return
+	}
+
+	h.router = h.options.Router
+	if h.router == nil {
+		h.router = chain.NewRouter(chain.LoggerRouterOption(h.options.Logger))
+	}
+
+	if h.md.url != nil {
+		return
+	}
+
+	h.upgrader = &websocket.Upgrader{
+		HandshakeTimeout:  h.md.handshakeTimeout,
+		ReadBufferSize:    h.md.readBufferSize,
+		WriteBufferSize:   h.md.writeBufferSize,
+		EnableCompression: h.md.enableCompression,
+		CheckOrigin:       h.checkOrigin,
+	}
+	h.server = &http.Server{
+		Handler:           http.HandlerFunc(h.handleFunc),
+		ReadHeaderTimeout: h.md.handshakeTimeout,
+	}
+	h.ln = &singleConnListener{
+		conn: make(chan net.Conn),
+		done: make(chan struct{}),
+	}
+	go h.server.Serve(h.ln)
+
+	return
+}
+
+// Forward implements handler.Forwarder.
+func (h *wsbridgeHandler) Forward(hop hop.Hop) {
+	h.hop = hop
+}
+
+func (h *wsbridgeHandler) Handle(ctx context.Context, conn net.Conn, opts ...handler.HandleOption) error {
+	log := h.options.Logger.WithFields(map[string]any{
+		"remote": conn.RemoteAddr().String(),
+		"local":  conn.LocalAddr().String(),
+	})
+
+	if h.md.url != nil {
+		defer conn.Close()
+		return h.handleTCP(ctx, conn, log)
+	}
+
+	log.Debugf("%s - %s", conn.RemoteAddr(), conn.LocalAddr())
+	h.ln.send(conn)
+
+	return nil
+}
+
+func (h *wsbridgeHandler) Close() error {
+	if h.server != nil {
+		return h.server.Close()
+	}
+	return nil
+}
+
+// handleTCP bridges the TCP client conn to the websocket backend.
+func (h *wsbridgeHandler) handleTCP(ctx context.Context, conn net.Conn, log logger.Logger) error {
+	u := h.md.url
+	log = log.WithFields(map[string]any{
+		"dst": u.String(),
+	})
+
+	addr := u.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := "80"
+		if u.Scheme == "wss" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout:  h.md.handshakeTimeout,
+		ReadBufferSize:    h.md.readBufferSize,
+		WriteBufferSize:   h.md.writeBufferSize,
+		EnableCompression: h.md.enableCompression,
+		TLSClientConfig: &tls.Config{
+			ServerName: u.Hostname(),
+		},
+		NetDialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return h.router.Dial(ctx, network, addr)
+		},
+	}
+
+	c, resp, err := dialer.DialContext(ctx, u.String(), h.md.header)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		log.Errorf("dial %s: %v", u, err)
+		return err
+	}
+	cc := ws.Conn(c)
+	defer cc.Close()
+
+	start := time.Now()
+	log.Infof("%s <-> %s", conn.RemoteAddr(), u)
+	xnet.Transport(conn, cc)
+	log.WithFields(map[string]any{
+		"duration": time.Since(start),
+	}).Infof("%s >-< %s", conn.RemoteAddr(), u)
+
+	return nil
+}
+
+// handleFunc bridges the websocket clients to the TCP backends.
+func (h *wsbridgeHandler) handleFunc(w http.ResponseWriter, r *http.Request) {
+	log := h.options.Logger.WithFields(map[string]any{
+		"remote": r.RemoteAddr,
+		"path":   r.URL.Path,
+	})
+
+	if auther := h.options.Auther; auther != nil {
+		u, p, _ := r.BasicAuth()
+		id, ok := auther.Authenticate(r.Context(), u, p)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Basic")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if id != "" {
+			log = log.WithFields(map[string]any{
+				"client": id,
+			})
+		}
+	}
+
+	target := h.target(r)
+	if target == "" {
+		log.Warnf("no target for %s%s", r.Host, r.URL.Path)
+		http.NotFound(w, r)
+		return
+	}
+	log = log.WithFields(map[string]any{
+		"dst": target,
+	})
+
+	cc, err := h.router.Dial(r.Context(), "tcp", target)
+	if err != nil {
+		log.Errorf("connect to %s: %v", target, err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer cc.Close()
+
+	c, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	conn := ws.Conn(c)
+	defer conn.Close()
+
+	start := time.Now()
+	log.Infof("%s <-> %s", r.RemoteAddr, target)
+	xnet.Transport(conn, cc)
+	log.WithFields(map[string]any{
+		"duration": time.Since(start),
+	}).Infof("%s >-< %s", r.RemoteAddr, target)
+}
+
+// target returns the target of the longest matched path, or the node selected by the hop.
+func (h *wsbridgeHandler) target(r *http.Request) string {
+	p := path.Clean("/" + r.URL.Path)
+
+	var target string
+	var matched int
+	for k, v := range h.md.paths {
+		k = path.Clean("/" + k)
+		if k != "/" && p != k && !strings.HasPrefix(p, k+"/") {
+			continue
+		}
+		if len(k) > matched {
+			target, matched = v, len(k)
+		}
+	}
+	if target != "" {
+		return target
+	}
+
+	if h.hop != nil {
+		if node := h.hop.Select(r.Context(),
+			hop.HostSelectOption(r.Host),
+			hop.PathSelectOption(p),
+		); node != nil {
+			return node.Addr
+		}
+	}
+	return ""
+}
+
+// checkOrigin checks the Origin of the request against the allowed origins,
+// the origin of the same host is allowed if no origin is specified.
+func (h *wsbridgeHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if len(h.md.origins) == 0 {
+		return strings.EqualFold(u.Host, r.Host)
+	}
+	for _, v := range h.md.origins {
+		if v == "*" || strings.EqualFold(v, origin) || strings.EqualFold(v, u.Host) {
+			return true
+		}
+		// wildcard subdomains, e.g. *.example.com
+		if strings.HasPrefix(v, "*.") && strings.HasSuffix(strings.ToLower(u.Hostname()), strings.ToLower(v[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+type singleConnListener struct {
+	conn chan net.Conn
+	addr net.Addr
+	done chan struct{}
+	mu   sync.Mutex
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conn:
+		return conn, nil
+
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *singleConnListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *singleConnListener) send(conn net.Conn) {
+	select {
+	case l.conn <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
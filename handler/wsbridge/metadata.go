@@ -0,0 +1,73 @@
+package wsbridge
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	defaultHandshakeTimeout = 10 * time.Second
+)
+
+type metadata struct {
+	// ws -> tcp, the targets of the paths.
+	paths   map[string]string
+	origins []string
+	// tcp -> ws, the URL of the websocket backend.
+	url    *url.URL
+	header http.Header
+
+	handshakeTimeout  time.Duration
+	readBufferSize    int
+	writeBufferSize   int
+	enableCompression bool
+}
+
+func (h *wsbridgeHandler) parseMetadata(md mdata.Metadata) (err error) {
+	const (
+		paths             = "bridge.paths"
+		origins           = "bridge.origins"
+		bridgeURL         = "bridge.url"
+		header            = "bridge.header"
+		handshakeTimeout  = "handshakeTimeout"
+		readBufferSize    = "readBufferSize"
+		writeBufferSize   = "writeBufferSize"
+		enableCompression = "enableCompression"
+	)
+
+	h.md.paths = mdutil.GetStringMapString(md, paths)
+	h.md.origins = mdutil.GetStrings(md, origins)
+
+	if v := mdutil.GetString(md, bridgeURL); v != "" {
+		if h.md.url, err = url.Parse(v); err != nil {
+			return
+		}
+		switch strings.ToLower(h.md.url.Scheme) {
+		case "http":
+			h.md.url.Scheme = "ws"
+		case "https":
+			h.md.url.Scheme = "wss"
+		}
+	}
+	if m := mdutil.GetStringMapString(md, header); len(m) > 0 {
+		hd := http.Header{}
+		for k, v := range m {
+			hd.Add(k, v)
+		}
+		h.md.header = hd
+	}
+
+	h.md.handshakeTimeout = mdutil.GetDuration(md, handshakeTimeout)
+	if h.md.handshakeTimeout <= 0 {
+		h.md.handshakeTimeout = defaultHandshakeTimeout
+	}
+	h.md.readBufferSize = mdutil.GetInt(md, readBufferSize)
+	h.md.writeBufferSize = mdutil.GetInt(md, writeBufferSize)
+	h.md.enableCompression = mdutil.GetBool(md, enableCompression)
+	return
+}
@@ -0,0 +1,209 @@
+// Package hook is the connection event hooks for the programs embedding gost,
+// the hooks observe the connections of the services accepted, authenticated, dialed and closed,
+// and can veto the connection by returning an error.
+package hook
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/x/stats"
+)
+
+// Event is the event of a connection of a service.
+type Event struct {
+	Service    string
+	Sid        string
+	RemoteAddr string
+	LocalAddr  string
+	// the client ID of the authentication.
+	Client string
+	// the network and address dialed by the handler.
+	Network string
+	Addr    string
+	// the live counters of the connection, it is nil if the counters are not available, e.g. the UDP connections.
+	Stats *stats.Stats
+	// the duration of the connection or the dial.
+	Duration time.Duration
+	Err      error
+}
+
+// Hooks is the callbacks of the connection events, the nil callbacks are ignored.
+// The connection is closed or the operation fails if a callback returns an error.
+type Hooks struct {
+	// the connection is accepted by the listener of the service.
+	OnAccepted func(ctx context.Context, e Event) error
+	// the client is authenticated by the auther of the handler.
+	OnAuthenticated func(ctx context.Context, e Event) error
+	// the handler is going to dial the address.
+	OnDial func(ctx context.Context, e Event) error
+	// the dial is done, Err is set if it failed.
+	OnDialed func(ctx context.Context, e Event)
+	// the connection is closed.
+	OnClosed func(ctx context.Context, e Event)
+}
+
+type entry struct {
+	name  string
+	hooks *Hooks
+}
+
+var (
+	mu      sync.Mutex
+	entries atomic.Pointer[[]entry]
+)
+
+// Register registers the hooks by name, the hooks of the same name are replaced.
+func Register(name string, hooks *Hooks) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var list []entry
+	if p := entries.Load(); p != nil {
+		for _, e := range *p {
+			if e.name != name {
+				list = append(list, e)
+			}
+		}
+	}
+	if hooks != nil {
+		list = append(list, entry{name: name, hooks: hooks})
+	}
+	entries.Store(&list)
+}
+
+// Unregister removes the hooks of name.
+func Unregister(name string) {
+	Register(name, nil)
+}
+
+// Enabled reports whether any hooks are registered.
+func Enabled() bool {
+	p := entries.Load()
+	return p != nil && len(*p) > 0
+}
+
+func list() []entry {
+	if p := entries.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// state is the event of the connection in the context.
+type state struct {
+	mu sync.Mutex
+	ev Event
+}
+
+type stateKey struct{}
+
+var (
+	keyState = &stateKey{}
+)
+
+// NewContext returns the context carrying the event of the connection.
+func NewContext(ctx context.Context, e Event) context.Context {
+	return context.WithValue(ctx, keyState, &state{ev: e})
+}
+
+// EventFromContext returns the event of the connection in the context.
+func EventFromContext(ctx context.Context) (Event, bool) {
+	st, _ := ctx.Value(keyState).(*state)
+	if st == nil {
+		return Event{}, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.ev, true
+}
+
+func update(ctx context.Context, fn func(e *Event)) (Event, bool) {
+	st, _ := ctx.Value(keyState).(*state)
+	if st == nil {
+		return Event{}, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	fn(&st.ev)
+	return st.ev, true
+}
+
+// Accepted fires the OnAccepted hooks of the connection in the context.
+func Accepted(ctx context.Context) error {
+	e, ok := EventFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	for _, en := range list() {
+		if fn := en.hooks.OnAccepted; fn != nil {
+			if err := fn(ctx, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Authenticated records the client of the connection in the context and fires the OnAuthenticated hooks.
+func Authenticated(ctx context.Context, client string) error {
+	e, ok := update(ctx, func(e *Event) { e.Client = client })
+	if !ok {
+		return nil
+	}
+	for _, en := range list() {
+		if fn := en.hooks.OnAuthenticated; fn != nil {
+			if err := fn(ctx, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Dial fires the OnDial hooks of the connection in the context before dialing the address.
+func Dial(ctx context.Context, network, addr string) error {
+	e, ok := EventFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	e.Network, e.Addr = network, addr
+	for _, en := range list() {
+		if fn := en.hooks.OnDial; fn != nil {
+			if err := fn(ctx, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Dialed fires the OnDialed hooks of the connection in the context after dialing the address.
+func Dialed(ctx context.Context, network, addr string, d time.Duration, err error) {
+	e, ok := EventFromContext(ctx)
+	if !ok {
+		return
+	}
+	e.Network, e.Addr, e.Duration, e.Err = network, addr, d, err
+	for _, en := range list() {
+		if fn := en.hooks.OnDialed; fn != nil {
+			fn(ctx, e)
+		}
+	}
+}
+
+// Closed fires the OnClosed hooks of the connection in the context.
+func Closed(ctx context.Context, d time.Duration, err error) {
+	e, ok := EventFromContext(ctx)
+	if !ok {
+		return
+	}
+	e.Duration, e.Err = d, err
+	for _, en := range list() {
+		if fn := en.hooks.OnClosed; fn != nil {
+			fn(ctx, e)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package hook
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/auth"
+	"github.com/go-gost/core/chain"
+)
+
+type authenticator struct {
+	auth.Authenticator
+}
+
+// WrapAuthenticator wraps the auther with the OnAuthenticated hooks, nil is returned if a is nil.
+func WrapAuthenticator(a auth.Authenticator) auth.Authenticator {
+	if a == nil {
+		return nil
+	}
+	return &authenticator{Authenticator: a}
+}
+
+func (a *authenticator) Authenticate(ctx context.Context, user, password string, opts ...auth.Option) (string, bool) {
+	id, ok := a.Authenticator.Authenticate(ctx, user, password, opts...)
+	if !ok || !Enabled() {
+		return id, ok
+	}
+
+	client := id
+	if client == "" {
+		client = user
+	}
+	if err := Authenticated(ctx, client); err != nil {
+		return "", false
+	}
+	return id, ok
+}
+
+type chainer struct {
+	chain.Chainer
+}
+
+// WrapChainer wraps the chain with the OnDial and OnDialed hooks,
+// the direct connections without chain are hooked as well.
+func WrapChainer(c chain.Chainer) chain.Chainer {
+	return &chainer{Chainer: c}
+}
+
+func (c *chainer) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
+	var route chain.Route
+	if c.Chainer != nil {
+		route = c.Chainer.Route(ctx, network, address, opts...)
+	}
+	if route == nil {
+		route = chain.DefaultRoute
+	}
+	return &hookRoute{Route: route}
+}
+
+type hookRoute struct {
+	chain.Route
+}
+
+func (r *hookRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	if !Enabled() {
+		return r.Route.Dial(ctx, network, address, opts...)
+	}
+
+	if err := Dial(ctx, network, address); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	conn, err := r.Route.Dial(ctx, network, address, opts...)
+	Dialed(ctx, network, address, time.Since(start), err)
+	return conn, err
+}
@@ -0,0 +1,340 @@
+package hop
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/chain"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+)
+
+// HealthCheck is the active health check of the nodes.
+type HealthCheck struct {
+	// the probe type: tcp (default), tls or http.
+	Type     string
+	Interval time.Duration
+	Timeout  time.Duration
+	// the request path of the http probe.
+	Path string
+	// the consecutive failures to mark the node unhealthy and the successes to mark it healthy again.
+	Fails  int
+	Passes int
+}
+
+// ConnTracker is implemented by the hop tracking the connections to its nodes,
+// the connections are closed after the drain grace period when the node is removed or becomes unhealthy.
+type ConnTracker interface {
+	TrackConn(node *chain.Node, c io.Closer) (untrack func())
+}
+
+type nodeHealth struct {
+	healthy bool
+	fails   int
+	passes  int
+}
+
+// nodeTracker keeps the health of the nodes and the connections to them, the nodes are keyed by name and address
+// as they are recreated on reloading.
+type nodeTracker struct {
+	health map[string]*nodeHealth
+	conns  map[string]map[io.Closer]struct{}
+	mu     sync.Mutex
+}
+
+func nodeKey(node *chain.Node) string {
+	return node.Name + "@" + node.Addr
+}
+
+func (p *chainHop) isHealthy(node *chain.Node) bool {
+	if p.options.healthCheck == nil {
+		return true
+	}
+
+	p.tracker.mu.Lock()
+	defer p.tracker.mu.Unlock()
+	if h := p.tracker.health[nodeKey(node)]; h != nil {
+		return h.healthy
+	}
+	// the nodes not probed yet are healthy.
+	return true
+}
+
+// TrackConn implements ConnTracker interface.
+func (p *chainHop) TrackConn(node *chain.Node, c io.Closer) func() {
+	if p.options.drain <= 0 || node == nil || c == nil {
+		return func() {}
+	}
+
+	key := nodeKey(node)
+
+	p.tracker.mu.Lock()
+	if p.tracker.conns == nil {
+		p.tracker.conns = make(map[string]map[io.Closer]struct{})
+	}
+	m := p.tracker.conns[key]
+	if m == nil {
+		m = make(map[io.Closer]struct{})
+		p.tracker.conns[key] = m
+	}
+	m[c] = struct{}{}
+	p.tracker.mu.Unlock()
+
+	return func() {
+		p.tracker.mu.Lock()
+		defer p.tracker.mu.Unlock()
+		if m := p.tracker.conns[key]; m != nil {
+			delete(m, c)
+			if len(m) == 0 {
+				delete(p.tracker.conns, key)
+			}
+		}
+	}
+}
+
+// drain closes the connections to the node key after the grace period,
+// unless the node is added back or recovered by then.
+func (p *chainHop) drain(key string) {
+	if p.options.drain <= 0 {
+		return
+	}
+
+	time.AfterFunc(p.options.drain, func() {
+		if p.isActive(key) {
+			return
+		}
+
+		p.tracker.mu.Lock()
+		conns := p.tracker.conns[key]
+		delete(p.tracker.conns, key)
+		p.tracker.mu.Unlock()
+
+		if len(conns) > 0 {
+			p.options.logger.Infof("drain: close %d connections to node %s", len(conns), key)
+		}
+		for c := range conns {
+			c.Close()
+		}
+	})
+}
+
+func (p *chainHop) isActive(key string) bool {
+	for _, node := range p.Nodes() {
+		if node != nil && nodeKey(node) == key {
+			return p.isHealthy(node)
+		}
+	}
+	return false
+}
+
+// removed handles the nodes removed on reloading.
+func (p *chainHop) removed(old, nodes []*chain.Node) {
+	keys := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		if node != nil {
+			keys[nodeKey(node)] = struct{}{}
+		}
+	}
+
+	for _, node := range old {
+		if node == nil {
+			continue
+		}
+		key := nodeKey(node)
+		if _, ok := keys[key]; ok {
+			continue
+		}
+		p.options.logger.Debugf("node %s is removed", key)
+
+		p.tracker.mu.Lock()
+		delete(p.tracker.health, key)
+		p.tracker.mu.Unlock()
+
+		p.drain(key)
+	}
+}
+
+func (p *chainHop) healthCheck(ctx context.Context) {
+	hc := p.options.healthCheck
+	interval := hc.Interval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, node := range p.Nodes() {
+			if node == nil {
+				continue
+			}
+			wg.Add(1)
+			go func(node *chain.Node) {
+				defer wg.Done()
+				p.updateHealth(node, p.probe(ctx, node))
+			}(node)
+		}
+		wg.Wait()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *chainHop) updateHealth(node *chain.Node, err error) {
+	hc := p.options.healthCheck
+	fails := hc.Fails
+	if fails <= 0 {
+		fails = 1
+	}
+	passes := hc.Passes
+	if passes <= 0 {
+		passes = 1
+	}
+
+	key := nodeKey(node)
+
+	p.tracker.mu.Lock()
+	if p.tracker.health == nil {
+		p.tracker.health = make(map[string]*nodeHealth)
+	}
+	h := p.tracker.health[key]
+	if h == nil {
+		h = &nodeHealth{healthy: true}
+		p.tracker.health[key] = h
+	}
+
+	changed := false
+	if err != nil {
+		h.passes = 0
+		h.fails++
+		if h.healthy && h.fails >= fails {
+			h.healthy = false
+			changed = true
+		}
+	} else {
+		h.fails = 0
+		h.passes++
+		if !h.healthy && h.passes >= passes {
+			h.healthy = true
+			changed = true
+		}
+	}
+	healthy := h.healthy
+	p.tracker.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if healthy {
+		p.options.logger.Infof("node %s is healthy", key)
+		return
+	}
+	p.options.logger.Warnf("node %s is unhealthy: %v", key, err)
+	p.drain(key)
+}
+
+// probe checks the node with the probe type of the health check.
+func (p *chainHop) probe(ctx context.Context, node *chain.Node) error {
+	hc := p.options.healthCheck
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	network := "tcp"
+	if node.Options().Network == "unix" {
+		network = "unix"
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, node.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	switch hc.Type {
+	case "tls":
+		return tls.Client(conn, probeTLSConfig(node)).HandshakeContext(ctx)
+
+	case "http", "https":
+		scheme := "http"
+		if hc.Type == "https" || node.Options().TLS != nil {
+			scheme = "https"
+			conn = tls.Client(conn, probeTLSConfig(node))
+		}
+		host := node.Options().Host
+		if settings := node.Options().HTTP; settings != nil && settings.Host != "" {
+			host = settings.Host
+		}
+		if host == "" {
+			host = node.Addr
+		}
+		path := hc.Path
+		if path == "" {
+			path = "/"
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s%s", scheme, host, path), nil)
+		if err != nil {
+			return err
+		}
+		tr := &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return conn, nil
+			},
+			DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return conn, nil
+			},
+			DisableKeepAlives: true,
+		}
+		defer tr.CloseIdleConnections()
+
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("http status %d", resp.StatusCode)
+		}
+	}
+
+	return nil
+}
+
+func probeTLSConfig(node *chain.Node) *tls.Config {
+	cfg := &tls.Config{
+		InsecureSkipVerify: true,
+	}
+	if settings := node.Options().TLS; settings != nil {
+		cfg.ServerName = settings.ServerName
+		cfg.InsecureSkipVerify = !settings.Secure
+	}
+	if cfg.ServerName == "" {
+		if host, _, _ := net.SplitHostPort(node.Addr); net.ParseIP(host) == nil {
+			cfg.ServerName = host
+		}
+	}
+	return cfg
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/hop"
 	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/sd"
 	"github.com/go-gost/core/selector"
 	"github.com/go-gost/x/config"
 	node_parser "github.com/go-gost/x/config/parsing/node"
@@ -29,7 +30,16 @@ type options struct {
 	fileLoader  loader.Loader
 	redisLoader loader.Loader
 	httpLoader  loader.Loader
+	sd          sd.SD
+	sdService   string
+	sdTemplate  string
+	templates   []*config.TemplateConfig
+	srvNodes    []*config.NodeConfig
+	pinNodes    []*config.NodeConfig
+	pin         *Pin
 	period      time.Duration
+	healthCheck *HealthCheck
+	drain       time.Duration
 	logger      logger.Logger
 }
 
@@ -81,6 +91,54 @@ func HTTPLoaderOption(httpLoader loader.Loader) Option {
 		opts.httpLoader = httpLoader
 	}
 }
+
+// SDOption resolves the nodes from the instances of the service registered in the service discovery,
+// the dialer and connector of the nodes are taken from the template.
+func SDOption(sd sd.SD, service string, template string) Option {
+	return func(opts *options) {
+		opts.sd = sd
+		opts.sdService = service
+		opts.sdTemplate = template
+	}
+}
+
+// TemplatesOption sets the templates referenced by the nodes resolved at runtime.
+func TemplatesOption(templates ...*config.TemplateConfig) Option {
+	return func(opts *options) {
+		opts.templates = templates
+	}
+}
+
+// SRVNodeOption sets the nodes whose addresses are DNS SRV names,
+// the SRV records are resolved to nodes on each reload.
+func SRVNodeOption(ncs ...*config.NodeConfig) Option {
+	return func(opts *options) {
+		opts.srvNodes = ncs
+	}
+}
+
+// PinNodeOption sets the nodes whose addresses are domain names, the nodes are pinned to the resolved
+// IP addresses which are re-resolved when the TTL expires, the hop is reloaded if the addresses change.
+func PinNodeOption(pin *Pin, ncs ...*config.NodeConfig) Option {
+	return func(opts *options) {
+		opts.pin = pin
+		opts.pinNodes = ncs
+	}
+}
+
+func HealthCheckOption(hc *HealthCheck) Option {
+	return func(opts *options) {
+		opts.healthCheck = hc
+	}
+}
+
+// DrainOption sets the grace period after which the tracked connections to the removed or unhealthy nodes are closed.
+func DrainOption(grace time.Duration) Option {
+	return func(opts *options) {
+		opts.drain = grace
+	}
+}
+
 func LoggerOption(logger logger.Logger) Option {
 	return func(opts *options) {
 		opts.logger = logger
@@ -90,6 +148,8 @@ func LoggerOption(logger logger.Logger) Option {
 type chainHop struct {
 	nodes      []*chain.Node
 	mu         sync.RWMutex
+	tracker    nodeTracker
+	pin        pinState
 	cancelFunc context.CancelFunc
 	options    options
 }
@@ -102,6 +162,16 @@ func NewHop(opts ...Option) hop.Hop {
 		}
 	}
 
+	if pin := options.pin; pin != nil {
+		if pin.MinTTL <= 0 {
+			pin.MinTTL = defaultPinMinTTL
+		}
+		if pin.MaxTTL <= 0 {
+			pin.MaxTTL = defaultPinMaxTTL
+		}
+		pin.MaxTTL = max(pin.MinTTL, pin.MaxTTL)
+	}
+
 	ctx, cancel := context.WithCancel(context.TODO())
 	p := &chainHop{
 		cancelFunc: cancel,
@@ -114,6 +184,12 @@ func NewHop(opts ...Option) hop.Hop {
 	if p.options.period > 0 {
 		go p.periodReload(ctx)
 	}
+	if p.options.healthCheck != nil {
+		go p.healthCheck(ctx)
+	}
+	if p.options.pin != nil && len(p.options.pinNodes) > 0 {
+		go p.pinReload(ctx)
+	}
 
 	return p
 }
@@ -145,7 +221,7 @@ func (p *chainHop) Select(ctx context.Context, opts ...hop.SelectOption) *chain.
 
 	var nodes []*chain.Node
 	for _, node := range filters {
-		if node == nil {
+		if node == nil || !p.isHealthy(node) {
 			continue
 		}
 		// node level bypass
@@ -300,9 +376,11 @@ func (p *chainHop) reload(ctx context.Context) (err error) {
 	p.options.logger.Debugf("load items %d", len(nodes))
 
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
+	old := p.nodes
 	p.nodes = nodes
+	p.mu.Unlock()
+
+	p.removed(old, nodes)
 
 	return
 }
@@ -337,6 +415,39 @@ func (p *chainHop) load(ctx context.Context) (nodes []*chain.Node, err error) {
 			nodes = append(nodes, node...)
 		}
 	}
+	for _, nc := range p.options.srvNodes {
+		nodes = append(nodes, p.resolveSRV(ctx, nc)...)
+	}
+	if p.options.pin != nil {
+		for _, nc := range p.options.pinNodes {
+			nodes = append(nodes, p.resolvePinned(ctx, nc)...)
+		}
+	}
+	if p.options.sd != nil {
+		services, er := p.options.sd.Get(ctx, p.options.sdService)
+		if er != nil {
+			p.options.logger.Warnf("sd: %v", er)
+		}
+		for _, service := range services {
+			if service == nil || service.Address == "" {
+				continue
+			}
+			name := service.ID
+			if name == "" {
+				name = service.Address
+			}
+			node, er := node_parser.ParseNode(p.options.name, &config.NodeConfig{
+				Name:     name,
+				Addr:     service.Address,
+				Template: p.options.sdTemplate,
+			}, logger.Default(), p.options.templates...)
+			if er != nil {
+				p.options.logger.Warnf("sd: %v", er)
+				continue
+			}
+			nodes = append(nodes, node)
+		}
+	}
 
 	return
 }
@@ -353,7 +464,7 @@ func (p *chainHop) parseNode(r io.Reader) ([]*chain.Node, error) {
 			continue
 		}
 
-		node, err := node_parser.ParseNode(p.options.name, nc, logger.Default())
+		node, err := node_parser.ParseNode(p.options.name, nc, logger.Default(), p.options.templates...)
 		if err != nil {
 			return nodes, err
 		}
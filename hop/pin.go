@@ -0,0 +1,275 @@
+package hop
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/config"
+	node_parser "github.com/go-gost/x/config/parsing/node"
+	"github.com/go-gost/x/registry"
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultPinMinTTL = 10 * time.Second
+	defaultPinMaxTTL = 10 * time.Minute
+)
+
+// Pin is the DNS pinning of the nodes with the domain addresses.
+type Pin struct {
+	// the lower and upper bounds of the TTL of the resolved addresses.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+}
+
+// pinnedNode is the nodes pinned to the resolved addresses of a node config.
+type pinnedNode struct {
+	ips    []string
+	nodes  []*chain.Node
+	expiry time.Time
+}
+
+type pinState struct {
+	nodes map[string]*pinnedNode
+	mu    sync.Mutex
+}
+
+// IsDomainAddr checks whether the host of the address is a domain name.
+func IsDomainAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || IsSRVAddr(addr) {
+		return false
+	}
+	return net.ParseIP(host) == nil
+}
+
+// resolvePinned returns the nodes pinned to the addresses of the domain of the node config,
+// the domain is re-resolved after the TTL expires. The nodes are kept if the addresses do not change,
+// and the previous nodes are kept if the resolution fails.
+func (p *chainHop) resolvePinned(ctx context.Context, nc *config.NodeConfig) []*chain.Node {
+	p.pin.mu.Lock()
+	defer p.pin.mu.Unlock()
+
+	if p.pin.nodes == nil {
+		p.pin.nodes = make(map[string]*pinnedNode)
+	}
+	key := nc.Name + "@" + nc.Addr
+	pn := p.pin.nodes[key]
+	if pn != nil && time.Now().Before(pn.expiry) {
+		return pn.nodes
+	}
+
+	host, port, _ := net.SplitHostPort(nc.Addr)
+	ips, ttl, err := p.lookupPinned(ctx, nc, host)
+	if err == nil && len(ips) == 0 {
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	if err != nil {
+		p.options.logger.Warnf("pin %s: %v", host, err)
+		if pn == nil {
+			pn = &pinnedNode{}
+			p.pin.nodes[key] = pn
+		}
+		pn.expiry = time.Now().Add(p.options.pin.MinTTL)
+		return pn.nodes
+	}
+
+	ttl = max(p.options.pin.MinTTL, min(ttl, p.options.pin.MaxTTL))
+
+	if pn != nil && strings.Join(pn.ips, ",") == strings.Join(ips, ",") {
+		pn.expiry = time.Now().Add(ttl)
+		return pn.nodes
+	}
+
+	var nodes []*chain.Node
+	for _, ip := range ips {
+		node, err := node_parser.ParseNode(p.options.name, pinnedNodeConfig(nc, host, net.JoinHostPort(ip, port)), logger.Default())
+		if err != nil {
+			p.options.logger.Warnf("pin %s: %v", host, err)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	if pn != nil {
+		p.options.logger.Infof("pin %s: %s -> %s", host, strings.Join(pn.ips, ","), strings.Join(ips, ","))
+	} else {
+		p.options.logger.Debugf("pin %s: %s, ttl %s", host, strings.Join(ips, ","), ttl)
+	}
+
+	p.pin.nodes[key] = &pinnedNode{
+		ips:    ips,
+		nodes:  nodes,
+		expiry: time.Now().Add(ttl),
+	}
+	return nodes
+}
+
+// pinnedNodeConfig copies the node config with the address addr,
+// the TLS server name and the host of the dialer keep the domain.
+func pinnedNodeConfig(nc *config.NodeConfig, host string, addr string) *config.NodeConfig {
+	c := *nc
+	c.Addr = addr
+
+	if c.Connector != nil {
+		cc := *c.Connector
+		cc.TLS = pinnedTLSConfig(cc.TLS, host)
+		c.Connector = &cc
+	}
+	if c.Dialer != nil {
+		dc := *c.Dialer
+		dc.TLS = pinnedTLSConfig(dc.TLS, host)
+
+		// the dialers use the dialed address as the host by default.
+		md := make(map[string]any)
+		for k, v := range dc.Metadata {
+			md[k] = v
+		}
+		if _, ok := md["host"]; !ok {
+			md["host"] = nc.Addr
+		}
+		dc.Metadata = md
+		c.Dialer = &dc
+	}
+
+	return &c
+}
+
+func pinnedTLSConfig(cfg *config.TLSConfig, host string) *config.TLSConfig {
+	var c config.TLSConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.ServerName == "" {
+		c.ServerName = host
+	}
+	return &c
+}
+
+// lookupPinned resolves the host by the resolver of the node, or by the nameservers of the system with the TTL.
+// The TTL is zero if it is unknown.
+func (p *chainHop) lookupPinned(ctx context.Context, nc *config.NodeConfig, host string) (ips []string, ttl time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if nc.Resolver != "" {
+		if r := registry.ResolverRegistry().Get(nc.Resolver); r != nil {
+			var v []net.IP
+			if v, err = r.Resolve(ctx, "ip", host); err != nil {
+				return
+			}
+			for _, ip := range v {
+				ips = append(ips, ip.String())
+			}
+			sort.Strings(ips)
+			return
+		}
+	}
+
+	if ips, ttl, err = lookupTTL(ctx, host); err == nil && len(ips) > 0 {
+		sort.Strings(ips)
+		return
+	}
+
+	// the host is not resolved by the nameservers, e.g. the system without resolv.conf,
+	// or the host in the hosts file or in the search domains.
+	v, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, 0, err
+	}
+	ips = nil
+	for _, ip := range v {
+		ips = append(ips, ip.String())
+	}
+	sort.Strings(ips)
+	return ips, 0, nil
+}
+
+// lookupTTL queries the A and AAAA records of the host from the system nameservers,
+// the TTL is the minimum TTL of the records.
+func lookupTTL(ctx context.Context, host string) (ips []string, ttl time.Duration, err error) {
+	conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return
+	}
+	if len(conf.Servers) == 0 {
+		return nil, 0, &net.DNSError{Err: "no nameserver", Name: host}
+	}
+
+	var c dns.Client
+	var found bool
+	var minTTL uint32
+	for _, t := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		var mr *dns.Msg
+		for _, server := range conf.Servers {
+			mq := new(dns.Msg)
+			mq.SetQuestion(dns.Fqdn(host), t)
+			mr, _, err = c.ExchangeContext(ctx, mq, net.JoinHostPort(server, conf.Port))
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if mr.Rcode != dns.RcodeSuccess && mr.Rcode != dns.RcodeNameError {
+			return nil, 0, &net.DNSError{Err: dns.RcodeToString[mr.Rcode], Name: host}
+		}
+
+		for _, rr := range mr.Answer {
+			var ip net.IP
+			switch v := rr.(type) {
+			case *dns.A:
+				ip = v.A
+			case *dns.AAAA:
+				ip = v.AAAA
+			}
+			if ip != nil {
+				ips = append(ips, ip.String())
+			}
+			// the TTL of the CNAME records counts as well.
+			if h := rr.Header(); !found || h.Ttl < minTTL {
+				minTTL = h.Ttl
+				found = true
+			}
+		}
+	}
+	return ips, time.Duration(minTTL) * time.Second, nil
+}
+
+// pinExpiry returns the earliest expiry of the pinned nodes.
+func (p *chainHop) pinExpiry() (t time.Time) {
+	p.pin.mu.Lock()
+	defer p.pin.mu.Unlock()
+
+	for _, pn := range p.pin.nodes {
+		if t.IsZero() || pn.expiry.Before(t) {
+			t = pn.expiry
+		}
+	}
+	return
+}
+
+// pinReload reloads the hop when the TTL of the pinned nodes expires.
+func (p *chainHop) pinReload(ctx context.Context) {
+	for {
+		wait := p.options.pin.MinTTL
+		if t := p.pinExpiry(); !t.IsZero() {
+			wait = max(time.Until(t), time.Second)
+		}
+
+		select {
+		case <-time.After(wait):
+			if err := p.reload(ctx); err != nil {
+				p.options.logger.Warnf("reload: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
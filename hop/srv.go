@@ -0,0 +1,88 @@
+package hop
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/config"
+	node_parser "github.com/go-gost/x/config/parsing/node"
+)
+
+const (
+	// SRVScheme is the address prefix of the nodes resolved from DNS SRV records,
+	// e.g. srv://_gost._tcp.example.com
+	SRVScheme = "srv://"
+)
+
+const (
+	labelWeight = "weight"
+	labelBackup = "backup"
+)
+
+// IsSRVAddr checks whether the address is a DNS SRV name.
+func IsSRVAddr(addr string) bool {
+	return strings.HasPrefix(addr, SRVScheme)
+}
+
+// resolveSRV creates a node for each target of the SRV records of the node config.
+// The weight of the record is set as the weight of the node, and the targets
+// with a lower priority (higher priority value) are marked as backup nodes.
+func (p *chainHop) resolveSRV(ctx context.Context, nc *config.NodeConfig) (nodes []*chain.Node) {
+	name := strings.TrimPrefix(nc.Addr, SRVScheme)
+	_, srvs, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		p.options.logger.Warnf("srv %s: %v", name, err)
+	}
+	if len(srvs) == 0 {
+		return
+	}
+
+	// keep the node names stable between reloads.
+	sort.Slice(srvs, func(i, j int) bool {
+		if srvs[i].Target != srvs[j].Target {
+			return srvs[i].Target < srvs[j].Target
+		}
+		return srvs[i].Port < srvs[j].Port
+	})
+
+	priority := srvs[0].Priority
+	for _, srv := range srvs {
+		if srv.Priority < priority {
+			priority = srv.Priority
+		}
+	}
+
+	for i, srv := range srvs {
+		c := *nc
+		c.Name = fmt.Sprintf("%s-%d", nc.Name, i)
+		c.Addr = net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port)))
+
+		md := make(map[string]any)
+		for k, v := range nc.Metadata {
+			md[k] = v
+		}
+		if srv.Weight > 0 {
+			md[labelWeight] = int(srv.Weight)
+		}
+		if srv.Priority > priority {
+			md[labelBackup] = true
+		}
+		c.Metadata = md
+
+		node, err := node_parser.ParseNode(p.options.name, &c, logger.Default(), p.options.templates...)
+		if err != nil {
+			p.options.logger.Warnf("srv %s: %v", name, err)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	p.options.logger.Debugf("srv %s: %d nodes", name, len(nodes))
+	return
+}
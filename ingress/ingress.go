@@ -5,6 +5,8 @@ import (
 	"context"
 	"io"
 	"net"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,8 +16,37 @@ import (
 	"github.com/go-gost/x/internal/loader"
 )
 
+// Rule is an ingress rule with the optional HTTP matching conditions.
+type Rule struct {
+	ingress.Rule
+	// Path is the path prefix of the HTTP request.
+	Path string
+	// Headers are the header values the HTTP request must have.
+	Headers map[string]string
+	// Chain is the name of the chain used to reach the endpoint.
+	Chain string
+}
+
+func (r *Rule) match(req *http.Request) bool {
+	if r.Path != "" && !strings.HasPrefix(req.URL.Path, r.Path) {
+		return false
+	}
+	for k, v := range r.Headers {
+		if req.Header.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// HTTPMatcher is an ingress matching HTTP requests against the rules.
+type HTTPMatcher interface {
+	// MatchHTTP returns the rule of the host with the longest path prefix matching the request.
+	MatchHTTP(ctx context.Context, req *http.Request) *Rule
+}
+
 type options struct {
-	rules       []*ingress.Rule
+	rules       []*Rule
 	fileLoader  loader.Loader
 	redisLoader loader.Loader
 	httpLoader  loader.Loader
@@ -25,7 +56,7 @@ type options struct {
 
 type Option func(opts *options)
 
-func RulesOption(rules []*ingress.Rule) Option {
+func RulesOption(rules []*Rule) Option {
 	return func(opts *options) {
 		opts.rules = rules
 	}
@@ -62,7 +93,7 @@ func LoggerOption(logger logger.Logger) Option {
 }
 
 type localIngress struct {
-	rules      map[string]*ingress.Rule
+	rules      map[string][]*Rule
 	cancelFunc context.CancelFunc
 	options    options
 	mu         sync.RWMutex
@@ -114,17 +145,17 @@ func (ing *localIngress) periodReload(ctx context.Context) error {
 }
 
 func (ing *localIngress) reload(ctx context.Context) error {
-	rules := make(map[string]*ingress.Rule)
+	rules := make(map[string][]*Rule)
 
-	fn := func(rule *ingress.Rule) {
-		if rule.Hostname == "" || rule.Endpoint == "" {
+	fn := func(rule *Rule) {
+		if rule == nil || rule.Hostname == "" || rule.Endpoint == "" {
 			return
 		}
 		host := rule.Hostname
 		if host[0] == '*' {
 			host = host[1:]
 		}
-		rules[host] = rule
+		rules[host] = append(rules[host], rule)
 	}
 
 	for _, rule := range ing.options.rules {
@@ -139,6 +170,16 @@ func (ing *localIngress) reload(ctx context.Context) error {
 		fn(rule)
 	}
 
+	// the more specific rules take precedence.
+	for _, l := range rules {
+		sort.SliceStable(l, func(i, j int) bool {
+			if len(l[i].Path) != len(l[j].Path) {
+				return len(l[i].Path) > len(l[j].Path)
+			}
+			return len(l[i].Headers) > len(l[j].Headers)
+		})
+	}
+
 	ing.options.logger.Debugf("load items %d", len(rules))
 
 	ing.mu.Lock()
@@ -149,7 +190,7 @@ func (ing *localIngress) reload(ctx context.Context) error {
 	return nil
 }
 
-func (ing *localIngress) load(ctx context.Context) (rules []*ingress.Rule, err error) {
+func (ing *localIngress) load(ctx context.Context) (rules []*Rule, err error) {
 	if ing.options.fileLoader != nil {
 		if lister, ok := ing.options.fileLoader.(loader.Lister); ok {
 			list, er := lister.List(ctx)
@@ -199,14 +240,14 @@ func (ing *localIngress) load(ctx context.Context) (rules []*ingress.Rule, err e
 	return
 }
 
-func (ing *localIngress) parseRules(r io.Reader) (rules []*ingress.Rule, err error) {
+func (ing *localIngress) parseRules(r io.Reader) (rules []*Rule, err error) {
 	if r == nil {
 		return
 	}
 
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		if rule := ing.parseLine(scanner.Text()); rule.Hostname != "" {
+		if rule := ing.parseLine(scanner.Text()); rule != nil && rule.Hostname != "" {
 			rules = append(rules, rule)
 		}
 	}
@@ -216,6 +257,40 @@ func (ing *localIngress) parseRules(r io.Reader) (rules []*ingress.Rule, err err
 }
 
 func (ing *localIngress) GetRule(ctx context.Context, host string, opts ...ingress.Option) *ingress.Rule {
+	rules := ing.find(host)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	// prefer the rule without HTTP conditions.
+	rule := rules[0]
+	for _, r := range rules {
+		if r.Path == "" && len(r.Headers) == 0 {
+			rule = r
+			break
+		}
+	}
+	ing.options.logger.Debugf("ingress: %s -> %s", host, rule.Endpoint)
+
+	return &rule.Rule
+}
+
+// MatchHTTP implements HTTPMatcher interface.
+func (ing *localIngress) MatchHTTP(ctx context.Context, req *http.Request) *Rule {
+	if req == nil {
+		return nil
+	}
+
+	for _, rule := range ing.find(req.Host) {
+		if rule.match(req) {
+			ing.options.logger.Debugf("ingress: %s%s -> %s", req.Host, req.URL.Path, rule.Endpoint)
+			return rule
+		}
+	}
+	return nil
+}
+
+func (ing *localIngress) find(host string) []*Rule {
 	if host == "" || ing == nil {
 		return nil
 	}
@@ -226,17 +301,17 @@ func (ing *localIngress) GetRule(ctx context.Context, host string, opts ...ingre
 	}
 
 	ing.options.logger.Debugf("ingress: lookup %s", host)
-	ep := ing.lookup(host)
-	if ep == nil {
-		ep = ing.lookup("." + host)
+	rules := ing.lookup(host)
+	if rules == nil {
+		rules = ing.lookup("." + host)
 	}
-	if ep == nil {
+	if rules == nil {
 		s := host
 		for {
 			if index := strings.IndexByte(s, '.'); index > 0 {
-				ep = ing.lookup(s[index:])
+				rules = ing.lookup(s[index:])
 				s = s[index+1:]
-				if ep == nil {
+				if rules == nil {
 					continue
 				}
 			}
@@ -244,18 +319,47 @@ func (ing *localIngress) GetRule(ctx context.Context, host string, opts ...ingre
 		}
 	}
 
-	if ep != nil {
-		ing.options.logger.Debugf("ingress: %s -> %s", host, ep)
-	}
-
-	return ep
+	return rules
 }
 
+// SetRule stores the rule to the redis hash loader, the rule is shared by
+// all the ingresses loading from the same redis, and it takes effect immediately in this ingress.
 func (ing *localIngress) SetRule(ctx context.Context, rule *ingress.Rule, opts ...ingress.Option) bool {
-	return false
+	if rule == nil || rule.Hostname == "" || rule.Endpoint == "" {
+		return false
+	}
+
+	setter, ok := ing.options.redisLoader.(loader.Setter)
+	if !ok {
+		return false
+	}
+	if err := setter.Set(ctx, rule.Hostname, rule.Endpoint); err != nil {
+		ing.options.logger.Warnf("redis loader: %v", err)
+		return false
+	}
+
+	host := rule.Hostname
+	if host[0] == '*' {
+		host = host[1:]
+	}
+
+	ing.mu.Lock()
+	defer ing.mu.Unlock()
+
+	// the new rule replaces the one of the same hostname without HTTP conditions.
+	var rules []*Rule
+	for _, r := range ing.rules[host] {
+		if r.Hostname == rule.Hostname && r.Path == "" && len(r.Headers) == 0 {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	ing.rules[host] = append(rules, &Rule{Rule: *rule})
+
+	return true
 }
 
-func (ing *localIngress) lookup(host string) *ingress.Rule {
+func (ing *localIngress) lookup(host string) []*Rule {
 	if ing == nil {
 		return nil
 	}
@@ -266,7 +370,7 @@ func (ing *localIngress) lookup(host string) *ingress.Rule {
 	return ing.rules[host]
 }
 
-func (ing *localIngress) parseLine(s string) (rule *ingress.Rule) {
+func (ing *localIngress) parseLine(s string) (rule *Rule) {
 	line := strings.Replace(s, "\t", " ", -1)
 	line = strings.TrimSpace(line)
 	if n := strings.IndexByte(line, '#'); n >= 0 {
@@ -282,9 +386,11 @@ func (ing *localIngress) parseLine(s string) (rule *ingress.Rule) {
 		return // invalid lines are ignored
 	}
 
-	return &ingress.Rule{
-		Hostname: sp[0],
-		Endpoint: sp[1],
+	return &Rule{
+		Rule: ingress.Rule{
+			Hostname: sp[0],
+			Endpoint: sp[1],
+		},
 	}
 }
 
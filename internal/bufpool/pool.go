@@ -0,0 +1,144 @@
+package bufpool
+
+import (
+	"math/bits"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/metrics"
+	xmetrics "github.com/go-gost/x/metrics"
+)
+
+const (
+	minSize = 128
+	// DefaultMaxSize is the default size of the largest pooled buffer.
+	DefaultMaxSize = 64 * 1024
+
+	reportInterval = 5 * time.Second
+)
+
+type sizeClass struct {
+	size   int
+	pool   sync.Pool
+	inUse  atomic.Int64
+	allocs atomic.Uint64
+}
+
+type bufferPool struct {
+	classes []*sizeClass
+}
+
+var (
+	global     atomic.Pointer[bufferPool]
+	reportOnce sync.Once
+)
+
+func init() {
+	global.Store(newBufferPool(DefaultMaxSize))
+}
+
+func newBufferPool(maxSize int) *bufferPool {
+	p := &bufferPool{}
+	for size := minSize; size <= maxSize; size <<= 1 {
+		c := &sizeClass{size: size}
+		c.pool.New = func() any {
+			c.allocs.Add(1)
+			b := make([]byte, c.size)
+			return &b
+		}
+		p.classes = append(p.classes, c)
+	}
+	return p
+}
+
+// class returns the smallest size class fitting the size, or nil if the size exceeds the largest class.
+func (p *bufferPool) class(size int) *sizeClass {
+	i := 0
+	if size > minSize {
+		i = bits.Len(uint(size-1)) - bits.Len(uint(minSize-1))
+	}
+	if i >= len(p.classes) {
+		return nil
+	}
+	return p.classes[i]
+}
+
+type options struct {
+	maxSize int
+}
+
+type Option func(opts *options)
+
+// MaxSizeOption sets the size of the largest pooled buffer, it is rounded up to a power of two.
+func MaxSizeOption(size int) Option {
+	return func(opts *options) {
+		opts.maxSize = size
+	}
+}
+
+// Init re-creates the global pool, it should be called before any buffer is got.
+func Init(opts ...Option) {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.maxSize <= 0 {
+		options.maxSize = DefaultMaxSize
+	}
+	if options.maxSize < minSize {
+		options.maxSize = minSize
+	}
+	global.Store(newBufferPool(1 << bits.Len(uint(options.maxSize-1))))
+}
+
+// Get returns a buffer of specified size.
+func Get(size int) []byte {
+	reportOnce.Do(func() {
+		go report()
+	})
+
+	c := global.Load().class(size)
+	if c == nil {
+		return make([]byte, size)
+	}
+	c.inUse.Add(1)
+	b := c.pool.Get().(*[]byte)
+	return (*b)[:size]
+}
+
+// Put puts the buffer got by Get back to the pool.
+func Put(b []byte) {
+	c := global.Load().class(cap(b))
+	if c == nil || c.size != cap(b) {
+		return
+	}
+	c.inUse.Add(-1)
+	b = b[:cap(b)]
+	c.pool.Put(&b)
+}
+
+// report exports the utilization of the pool to the metrics.
+func report() {
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !xmetrics.IsEnabled() {
+			continue
+		}
+		for _, c := range global.Load().classes {
+			labels := metrics.Labels{"size": strconv.Itoa(c.size)}
+			if v := xmetrics.GetGauge(xmetrics.MetricBufferPoolBuffersInUseGauge, labels); v != nil {
+				v.Set(float64(c.inUse.Load()))
+			}
+			labels = metrics.Labels{"size": strconv.Itoa(c.size)}
+			if v := xmetrics.GetGauge(xmetrics.MetricBufferPoolBuffersAllocatedGauge, labels); v != nil {
+				v.Set(float64(c.allocs.Load()))
+			}
+		}
+	}
+}
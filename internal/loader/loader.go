@@ -17,3 +17,7 @@ type Lister interface {
 type Mapper interface {
 	Map(ctx context.Context) (map[string]string, error)
 }
+
+type Setter interface {
+	Set(ctx context.Context, key, value string) error
+}
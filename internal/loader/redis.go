@@ -230,6 +230,11 @@ func (p *redisHashLoader) Map(ctx context.Context) (map[string]string, error) {
 	return p.client.HGetAll(ctx, p.key).Result()
 }
 
+// Set implements Setter interface{}
+func (p *redisHashLoader) Set(ctx context.Context, key, value string) error {
+	return p.client.HSet(ctx, p.key, key, value).Err()
+}
+
 func (p *redisHashLoader) Close() error {
 	return p.client.Close()
 }
@@ -8,7 +8,7 @@ import (
 )
 
 // AddrPortRange is the network address with port range supported.
-// e.g. 192.168.1.1:0-65535
+// e.g. 192.168.1.1:0-65535, or a list of ports and port ranges 192.168.1.1:8000,8080,9000-9010
 type AddrPortRange string
 
 func (p AddrPortRange) Addrs() (addrs []string) {
@@ -22,11 +22,13 @@ func (p AddrPortRange) Addrs() (addrs []string) {
 		return nil
 	}
 
-	pr := PortRange{}
-	pr.Parse(sp)
+	for _, s := range strings.Split(sp, ",") {
+		pr := PortRange{}
+		pr.Parse(strings.TrimSpace(s))
 
-	for i := pr.Min; i <= pr.Max; i++ {
-		addrs = append(addrs, net.JoinHostPort(h, strconv.Itoa(i)))
+		for i := pr.Min; i <= pr.Max; i++ {
+			addrs = append(addrs, net.JoinHostPort(h, strconv.Itoa(i)))
+		}
 	}
 	return addrs
 }
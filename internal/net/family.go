@@ -0,0 +1,165 @@
+package net
+
+import (
+	"context"
+	"net"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/core/resolver"
+)
+
+const (
+	MDKeyIPv6Only  = "ipv6Only"
+	MDKeyDualStack = "dualStack"
+	MDKeyIPFamily  = "ipFamily"
+)
+
+// IPFamily is the address family preference of the dialer.
+type IPFamily string
+
+const (
+	IPFamilyAny        IPFamily = ""
+	IPFamilyIPv4       IPFamily = "ipv4"
+	IPFamilyIPv6       IPFamily = "ipv6"
+	IPFamilyPreferIPv4 IPFamily = "prefer-ipv4"
+	IPFamilyPreferIPv6 IPFamily = "prefer-ipv6"
+)
+
+// ListenNetwork returns the network ("tcp" or "udp" with the family suffix) to listen on the address addr:
+// IPv6 only (IPV6_V6ONLY) with the ipv6Only metadata, dual-stack with the dualStack metadata,
+// in which the IPv4 clients are accepted as v4-mapped IPv6 addresses, otherwise IPv4 only for the IPv4 address.
+func ListenNetwork(network string, addr string, md mdata.Metadata) string {
+	if mdutil.GetBool(md, MDKeyIPv6Only) {
+		return network + "6"
+	}
+	if mdutil.GetBool(md, MDKeyDualStack) {
+		return network
+	}
+	if IsIPv4(addr) {
+		return network + "4"
+	}
+	return network
+}
+
+// ParseIPFamily parses the address family preference from metadata.
+func ParseIPFamily(md mdata.Metadata) IPFamily {
+	switch v := IPFamily(mdutil.GetString(md, MDKeyIPFamily)); v {
+	case IPFamilyIPv4, IPFamilyIPv6, IPFamilyPreferIPv4, IPFamilyPreferIPv6:
+		return v
+	}
+	return IPFamilyAny
+}
+
+// DialFamily applies the address family preference to the network ("tcp" or "udp") and address, and dials them by dial.
+// The v4-only and v6-only families restrict the network, the preferred families
+// resolve the host by the resolver of the node (see ContextWithResolver) or the system resolver,
+// and try the addresses of the preferred family first, then the remaining ones.
+func DialFamily(ctx context.Context, network, addr string, family IPFamily,
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)) (conn net.Conn, err error) {
+
+	network, addrs := dialAddrs(ctx, network, addr, family)
+	for _, addr := range addrs {
+		if conn, err = dial(ctx, network, addr); err == nil || ctx.Err() != nil {
+			return
+		}
+	}
+	return
+}
+
+func dialAddrs(ctx context.Context, network, addr string, family IPFamily) (string, []string) {
+	if network != "tcp" && network != "udp" {
+		return network, []string{addr}
+	}
+
+	switch family {
+	case IPFamilyIPv4:
+		return network + "4", []string{addr}
+	case IPFamilyIPv6:
+		return network + "6", []string{addr}
+	case IPFamilyPreferIPv4, IPFamilyPreferIPv6:
+	default:
+		return network, []string{addr}
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || net.ParseIP(host) != nil {
+		return network, []string{addr}
+	}
+	ips, err := lookupIP(ctx, host)
+	if err != nil || len(ips) == 0 {
+		return network, []string{addr}
+	}
+
+	var preferred, others []string
+	for _, ip := range ips {
+		if (ip.To4() != nil) == (family == IPFamilyPreferIPv4) {
+			preferred = append(preferred, net.JoinHostPort(ip.String(), port))
+		} else {
+			others = append(others, net.JoinHostPort(ip.String(), port))
+		}
+	}
+	return network, append(preferred, others...)
+}
+
+func lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	if r, _ := ctx.Value(resolverKey{}).(resolver.Resolver); r != nil {
+		return r.Resolve(ctx, "ip", host)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+type resolverKey struct{}
+
+// ContextWithResolver returns the context carrying the resolver of the node to be dialed,
+// DialFamily resolves the node address by it.
+func ContextWithResolver(ctx context.Context, r resolver.Resolver) context.Context {
+	if r = UnwrapResolver(r); r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, resolverKey{}, r)
+}
+
+// familyResolver is the resolver of the node whose dialer has the address family preference,
+// the route leaves the node address to the dialer which resolves it by the wrapped resolver.
+type familyResolver struct {
+	resolver.Resolver
+}
+
+// FamilyResolver wraps the resolver r of the node whose dialer has the address family preference.
+func FamilyResolver(r resolver.Resolver, family IPFamily) resolver.Resolver {
+	if r == nil || (family != IPFamilyPreferIPv4 && family != IPFamilyPreferIPv6) {
+		return r
+	}
+	return &familyResolver{Resolver: r}
+}
+
+func (r *familyResolver) Resolve(ctx context.Context, network, host string, opts ...resolver.Option) ([]net.IP, error) {
+	return nil, resolver.ErrInvalid
+}
+
+// UnwrapResolver returns the resolver wrapped by FamilyResolver.
+func UnwrapResolver(r resolver.Resolver) resolver.Resolver {
+	if fr, ok := r.(*familyResolver); ok {
+		return fr.Resolver
+	}
+	return r
+}
+
+// NormalizeHost converts the v4-mapped IPv6 address host to the IPv4 form,
+// so the IPv4 clients of a dual-stack listener have the same key as the IPv4 ones.
+func NormalizeHost(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return host
+}
@@ -0,0 +1,206 @@
+package net
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/metrics"
+	xmetrics "github.com/go-gost/x/metrics"
+)
+
+// IdleKind is the kind of the relayed connections which determines the idle timeout.
+type IdleKind int
+
+const (
+	IdleTCP IdleKind = iota
+	IdleUDP
+	IdleTunnel
+)
+
+func (k IdleKind) String() string {
+	switch k {
+	case IdleUDP:
+		return "udp"
+	case IdleTunnel:
+		return "tunnel"
+	default:
+		return "tcp"
+	}
+}
+
+// default idle timeouts, used when the idle reaper is enabled
+// without the timeout of the kind.
+const (
+	DefaultIdleTCPTimeout    = time.Hour
+	DefaultIdleUDPTimeout    = 2 * time.Minute
+	DefaultIdleTunnelTimeout = 10 * time.Minute
+)
+
+type idleOptions struct {
+	tcp    time.Duration
+	udp    time.Duration
+	tunnel time.Duration
+}
+
+type IdleOption func(opts *idleOptions)
+
+// IdleTimeoutOption sets the idle timeout of the kind, negative value disables the reaping of the kind.
+func IdleTimeoutOption(kind IdleKind, timeout time.Duration) IdleOption {
+	return func(opts *idleOptions) {
+		switch kind {
+		case IdleUDP:
+			opts.udp = timeout
+		case IdleTunnel:
+			opts.tunnel = timeout
+		default:
+			opts.tcp = timeout
+		}
+	}
+}
+
+type idleEntry struct {
+	kind    IdleKind
+	atime   atomic.Int64
+	closers []io.Closer
+}
+
+func (e *idleEntry) touch() {
+	e.atime.Store(time.Now().UnixNano())
+}
+
+// idleReaper closes the relayed connections with no traffic in either direction for the idle timeout.
+type idleReaper struct {
+	timeouts [3]time.Duration
+	entries  map[*idleEntry]struct{}
+	mu       sync.Mutex
+}
+
+var reaper atomic.Pointer[idleReaper]
+
+// InitIdleReaper enables the idle reaper of the relayed connections,
+// the zero timeouts are set to the defaults.
+// It can be called again to change the timeouts, the tracked connections are reaped with the new ones.
+func InitIdleReaper(opts ...IdleOption) {
+	options := idleOptions{
+		tcp:    DefaultIdleTCPTimeout,
+		udp:    DefaultIdleUDPTimeout,
+		tunnel: DefaultIdleTunnelTimeout,
+	}
+	var o idleOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	if o.tcp != 0 {
+		options.tcp = o.tcp
+	}
+	if o.udp != 0 {
+		options.udp = o.udp
+	}
+	if o.tunnel != 0 {
+		options.tunnel = o.tunnel
+	}
+
+	timeouts := [3]time.Duration{options.tcp, options.udp, options.tunnel}
+	r := &idleReaper{
+		timeouts: timeouts,
+		entries:  make(map[*idleEntry]struct{}),
+	}
+	if reaper.CompareAndSwap(nil, r) {
+		go reap()
+		return
+	}
+
+	r = reaper.Load()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeouts = timeouts
+}
+
+// track registers the relay of rw1 and rw2, it returns nil if the kind is not reaped.
+func (r *idleReaper) track(kind IdleKind, rw1, rw2 io.ReadWriter) *idleEntry {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	timeout := r.timeouts[kind]
+	r.mu.Unlock()
+	if timeout <= 0 {
+		return nil
+	}
+
+	e := &idleEntry{kind: kind}
+	for _, rw := range []io.ReadWriter{rw1, rw2} {
+		if c, ok := rw.(io.Closer); ok {
+			e.closers = append(e.closers, c)
+		}
+	}
+	if len(e.closers) == 0 {
+		return nil
+	}
+	e.touch()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[e] = struct{}{}
+	return e
+}
+
+func (r *idleReaper) untrack(e *idleEntry) {
+	if r == nil || e == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, e)
+}
+
+func (r *idleReaper) reap(now time.Time) {
+	var l []*idleEntry
+
+	r.mu.Lock()
+	for e := range r.entries {
+		if t := r.timeouts[e.kind]; t > 0 && now.Sub(time.Unix(0, e.atime.Load())) > t {
+			l = append(l, e)
+			delete(r.entries, e)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, e := range l {
+		for _, c := range e.closers {
+			c.Close()
+		}
+		if v := xmetrics.GetCounter(xmetrics.MetricIdleConnsReapedCounter,
+			metrics.Labels{"kind": e.kind.String()}); v != nil {
+			v.Inc()
+		}
+	}
+}
+
+func reap() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		reaper.Load().reap(now)
+	}
+}
+
+// idleReader updates the activity time of the relay when data is read.
+type idleReader struct {
+	io.Reader
+	e *idleEntry
+}
+
+func (r *idleReader) Read(b []byte) (n int, err error) {
+	n, err = r.Reader.Read(b)
+	if n > 0 {
+		r.e.touch()
+	}
+	return
+}
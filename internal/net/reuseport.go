@@ -0,0 +1,24 @@
+package net
+
+import "syscall"
+
+// ReusePortControl returns the Control function of net.ListenConfig which enables SO_REUSEPORT
+// before calling control, so another process (e.g. an upgraded one) can listen on the same address
+// and take over the new connections, while this process drains the existing ones.
+func ReusePortControl(control func(network, address string, c syscall.RawConn) error) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var serr error
+		if err := c.Control(func(fd uintptr) {
+			serr = setReusePort(fd)
+		}); err != nil {
+			return err
+		}
+		if serr != nil {
+			return serr
+		}
+		if control != nil {
+			return control(network, address, c)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package net
+
+import "errors"
+
+func setReusePort(fd uintptr) error {
+	return errors.New("SO_REUSEPORT is not supported")
+}
@@ -0,0 +1,81 @@
+package net
+
+import (
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyReadBuffer        = "tcp.readBuffer"
+	MDKeyWriteBuffer       = "tcp.writeBuffer"
+	MDKeyNoDelay           = "tcp.noDelay"
+	MDKeyKeepAlive         = "tcp.keepAlive"
+	MDKeyKeepAliveInterval = "tcp.keepAliveInterval"
+	MDKeyKeepAliveCount    = "tcp.keepAliveCount"
+)
+
+// SockOpts is the socket options applied to the established TCP connections.
+type SockOpts struct {
+	// SO_RCVBUF and SO_SNDBUF in bytes.
+	ReadBuffer  int
+	WriteBuffer int
+	// TCP_NODELAY, nil keeps the default (enabled in Go).
+	NoDelay *bool
+	// the idle time before the first keepalive probe, zero keeps the default.
+	KeepAlive time.Duration
+	// the interval between the keepalive probes.
+	KeepAliveInterval time.Duration
+	// the number of unacknowledged probes before the connection is dropped.
+	KeepAliveCount int
+}
+
+// ParseSockOpts parses the socket options from metadata, nil is returned if none is set.
+func ParseSockOpts(md mdata.Metadata) *SockOpts {
+	if md == nil {
+		return nil
+	}
+
+	opts := &SockOpts{
+		ReadBuffer:        mdutil.GetInt(md, MDKeyReadBuffer),
+		WriteBuffer:       mdutil.GetInt(md, MDKeyWriteBuffer),
+		KeepAlive:         mdutil.GetDuration(md, MDKeyKeepAlive),
+		KeepAliveInterval: mdutil.GetDuration(md, MDKeyKeepAliveInterval),
+		KeepAliveCount:    mdutil.GetInt(md, MDKeyKeepAliveCount),
+	}
+	if md.IsExists(MDKeyNoDelay) {
+		v := mdutil.GetBool(md, MDKeyNoDelay)
+		opts.NoDelay = &v
+	}
+
+	if opts.ReadBuffer <= 0 && opts.WriteBuffer <= 0 && opts.NoDelay == nil &&
+		opts.KeepAlive <= 0 && opts.KeepAliveInterval <= 0 && opts.KeepAliveCount <= 0 {
+		return nil
+	}
+	return opts
+}
+
+// Apply sets the socket options on the connection c, which must be (or wrap) a TCP connection
+// implementing the SyscallConn interface, otherwise the options are ignored.
+func (o *SockOpts) Apply(c any) error {
+	if o == nil {
+		return nil
+	}
+	sc, ok := c.(SyscallConn)
+	if !ok {
+		return nil
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil || rc == nil {
+		return err
+	}
+
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		serr = o.apply(fd)
+	}); err != nil {
+		return err
+	}
+	return serr
+}
@@ -0,0 +1,58 @@
+package net
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func (o *SockOpts) apply(fd uintptr) error {
+	s := int(fd)
+	if o.ReadBuffer > 0 {
+		if err := unix.SetsockoptInt(s, unix.SOL_SOCKET, unix.SO_RCVBUF, o.ReadBuffer); err != nil {
+			return err
+		}
+	}
+	if o.WriteBuffer > 0 {
+		if err := unix.SetsockoptInt(s, unix.SOL_SOCKET, unix.SO_SNDBUF, o.WriteBuffer); err != nil {
+			return err
+		}
+	}
+	if o.NoDelay != nil {
+		v := 0
+		if *o.NoDelay {
+			v = 1
+		}
+		if err := unix.SetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_NODELAY, v); err != nil {
+			return err
+		}
+	}
+
+	if o.KeepAlive <= 0 && o.KeepAliveInterval <= 0 && o.KeepAliveCount <= 0 {
+		return nil
+	}
+	if err := unix.SetsockoptInt(s, unix.SOL_SOCKET, unix.SO_KEEPALIVE, 1); err != nil {
+		return err
+	}
+	if o.KeepAlive > 0 {
+		if err := unix.SetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_KEEPIDLE, seconds(o.KeepAlive)); err != nil {
+			return err
+		}
+	}
+	if o.KeepAliveInterval > 0 {
+		if err := unix.SetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, seconds(o.KeepAliveInterval)); err != nil {
+			return err
+		}
+	}
+	if o.KeepAliveCount > 0 {
+		if err := unix.SetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_KEEPCNT, o.KeepAliveCount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seconds rounds the duration up to whole seconds.
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package net
+
+// the socket options are supported only on linux.
+func (o *SockOpts) apply(fd uintptr) error {
+	return nil
+}
@@ -4,22 +4,62 @@ import (
 	"bufio"
 	"io"
 	"net"
+	"strings"
 
-	"github.com/go-gost/core/common/bufpool"
+	"github.com/go-gost/x/internal/bufpool"
 )
 
 const (
 	bufferSize = 64 * 1024
 )
 
-func Transport(rw1, rw2 io.ReadWriter) error {
+type transportOptions struct {
+	idleKind *IdleKind
+}
+
+type TransportOption func(opts *transportOptions)
+
+// IdleKindTransportOption sets the kind of the relay for the idle reaper,
+// by default it is IdleUDP for the UDP connections, otherwise IdleTCP.
+func IdleKindTransportOption(kind IdleKind) TransportOption {
+	return func(opts *transportOptions) {
+		opts.idleKind = &kind
+	}
+}
+
+func Transport(rw1, rw2 io.ReadWriter, opts ...TransportOption) error {
+	var options transportOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	kind := IdleTCP
+	if options.idleKind != nil {
+		kind = *options.idleKind
+	} else if isUDP(rw1) || isUDP(rw2) {
+		kind = IdleUDP
+	}
+
+	r := reaper.Load()
+	e := r.track(kind, rw1, rw2)
+	defer r.untrack(e)
+
+	// the plain TCP connections are relayed by splice(2) on Linux in TCPConn.ReadFrom,
+	// unless they are wrapped, e.g. by the idle reaper.
+	r1, r2 := io.Reader(rw1), io.Reader(rw2)
+	if e != nil {
+		r1, r2 = &idleReader{Reader: rw1, e: e}, &idleReader{Reader: rw2, e: e}
+	}
+
 	errc := make(chan error, 1)
 	go func() {
-		errc <- CopyBuffer(rw1, rw2, bufferSize)
+		errc <- CopyBuffer(rw1, r2, bufferSize)
 	}()
 
 	go func() {
-		errc <- CopyBuffer(rw2, rw1, bufferSize)
+		errc <- CopyBuffer(rw2, r1, bufferSize)
 	}()
 
 	if err := <-errc; err != nil && err != io.EOF {
@@ -37,6 +77,18 @@ func CopyBuffer(dst io.Writer, src io.Reader, bufSize int) error {
 	return err
 }
 
+func isUDP(rw io.ReadWriter) bool {
+	switch v := rw.(type) {
+	case net.PacketConn:
+		return true
+	case net.Conn:
+		if addr := v.LocalAddr(); addr != nil {
+			return strings.HasPrefix(addr.Network(), "udp")
+		}
+	}
+	return false
+}
+
 type bufferReaderConn struct {
 	net.Conn
 	br *bufio.Reader
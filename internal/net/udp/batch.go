@@ -0,0 +1,35 @@
+package udp
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// the maximum number of packets read or written by one syscall.
+	batchSize = 16
+)
+
+// batchConn reads and writes multiple packets through one syscall (recvmmsg/sendmmsg).
+type batchConn interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// writeBatch writes all the messages, it returns the first error encountered.
+func writeBatch(bc batchConn, ms []ipv4.Message) error {
+	for len(ms) > 0 {
+		n, err := bc.WriteBatch(ms, 0)
+		if err != nil {
+			return err
+		}
+		ms = ms[n:]
+	}
+	return nil
+}
+
+func isIPv4(pc net.PacketConn) bool {
+	addr, _ := pc.LocalAddr().(*net.UDPAddr)
+	return addr != nil && addr.IP.To4() != nil
+}
@@ -0,0 +1,20 @@
+package udp
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// newBatchConn returns the batchConn of pc if it is a plain UDP socket, otherwise nil.
+func newBatchConn(pc net.PacketConn) batchConn {
+	conn, ok := pc.(*net.UDPConn)
+	if !ok {
+		return nil
+	}
+	if isIPv4(conn) {
+		return ipv4.NewPacketConn(conn)
+	}
+	return ipv6.NewPacketConn(conn)
+}
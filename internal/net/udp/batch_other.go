@@ -0,0 +1,10 @@
+//go:build !linux
+
+package udp
+
+import "net"
+
+// recvmmsg/sendmmsg are available only on linux.
+func newBatchConn(pc net.PacketConn) batchConn {
+	return nil
+}
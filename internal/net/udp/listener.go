@@ -0,0 +1,356 @@
+package udp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/common/bufpool"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metrics"
+	xmetrics "github.com/go-gost/x/metrics"
+)
+
+const (
+	DropReasonSessions = "sessions"
+	DropReasonBacklog  = "backlog"
+	DropReasonQueue    = "queue"
+)
+
+var (
+	ErrQueueFull = errors.New("recv queue is full")
+)
+
+type ListenConfig struct {
+	Addr           net.Addr
+	Backlog        int
+	ReadQueueSize  int
+	ReadBufferSize int
+	// the idle timeout of the sessions.
+	TTL       time.Duration
+	KeepAlive bool
+	// the maximum number of the concurrent sessions, 0 for unlimited.
+	MaxSessions int
+	Service     string
+	Logger      logger.Logger
+}
+
+// listener is the UDP listener with the session table,
+// the session is keyed by the client address, together with the local address of the listener
+// it is the 5-tuple of the flow.
+type listener struct {
+	conn     net.PacketConn
+	cqueue   chan net.Conn
+	sessions map[string]*session
+	mu       sync.Mutex
+	closed   chan struct{}
+	errChan  chan error
+	config   *ListenConfig
+}
+
+func NewListener(conn net.PacketConn, cfg *ListenConfig) net.Listener {
+	if cfg == nil {
+		cfg = &ListenConfig{}
+	}
+
+	ln := &listener{
+		conn:     conn,
+		cqueue:   make(chan net.Conn, cfg.Backlog),
+		sessions: make(map[string]*session),
+		closed:   make(chan struct{}),
+		errChan:  make(chan error, 1),
+		config:   cfg,
+	}
+	go ln.listenLoop()
+	if cfg.KeepAlive && cfg.TTL > 0 {
+		go ln.idleCheck()
+	}
+
+	return ln
+}
+
+func (ln *listener) Accept() (conn net.Conn, err error) {
+	select {
+	case conn = <-ln.cqueue:
+		return
+	case <-ln.closed:
+		return nil, net.ErrClosed
+	case err = <-ln.errChan:
+		if err == nil {
+			err = net.ErrClosed
+		}
+		return
+	}
+}
+
+func (ln *listener) listenLoop() {
+	for {
+		select {
+		case <-ln.closed:
+			return
+		default:
+		}
+
+		b := bufpool.Get(ln.config.ReadBufferSize)
+
+		n, raddr, err := ln.conn.ReadFrom(b)
+		if err != nil {
+			ln.errChan <- err
+			close(ln.errChan)
+			return
+		}
+
+		s := ln.getSession(raddr)
+		if s == nil {
+			bufpool.Put(b)
+			continue
+		}
+
+		if err := s.writeQueue(b[:n]); err != nil {
+			bufpool.Put(b)
+			if errors.Is(err, ErrQueueFull) {
+				ln.drop(DropReasonQueue)
+			}
+			ln.config.Logger.Warnf("data from %s discarded: %v", raddr, err)
+		}
+	}
+}
+
+func (ln *listener) Addr() net.Addr {
+	if ln.config.Addr != nil {
+		return ln.config.Addr
+	}
+	return ln.conn.LocalAddr()
+}
+
+func (ln *listener) Close() error {
+	select {
+	case <-ln.closed:
+	default:
+		close(ln.closed)
+		ln.conn.Close()
+
+		ln.mu.Lock()
+		sessions := ln.sessions
+		ln.sessions = make(map[string]*session)
+		ln.mu.Unlock()
+
+		for _, s := range sessions {
+			s.Close()
+			ln.sessionsGauge(-1)
+		}
+	}
+
+	return nil
+}
+
+func (ln *listener) getSession(raddr net.Addr) *session {
+	key := raddr.String()
+
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	if s := ln.sessions[key]; s != nil {
+		return s
+	}
+
+	if n := ln.config.MaxSessions; n > 0 && len(ln.sessions) >= n {
+		ln.drop(DropReasonSessions)
+		ln.config.Logger.Warnf("session table is full (%d), client %s discarded", n, raddr)
+		return nil
+	}
+
+	s := newSession(ln.conn, ln.Addr(), raddr, ln.config.ReadQueueSize, ln.config.KeepAlive)
+	select {
+	case ln.cqueue <- s:
+	default:
+		s.Close()
+		ln.drop(DropReasonBacklog)
+		ln.config.Logger.Warnf("connection queue is full, client %s discarded", raddr)
+		return nil
+	}
+
+	if !ln.config.KeepAlive {
+		return s
+	}
+
+	ln.sessions[key] = s
+	ln.sessionsGauge(1)
+	// the session may be closed by the handler before it is added to the table.
+	s.setOnClose(func() {
+		ln.mu.Lock()
+		if ln.sessions[key] == s {
+			delete(ln.sessions, key)
+			ln.sessionsGauge(-1)
+		}
+		ln.mu.Unlock()
+	})
+	return s
+}
+
+func (ln *listener) sessionsGauge(delta float64) {
+	if v := xmetrics.GetGauge(xmetrics.MetricUDPSessionsGauge, metrics.Labels{
+		"service": ln.config.Service,
+	}); v != nil {
+		v.Add(delta)
+	}
+}
+
+func (ln *listener) drop(reason string) {
+	if v := xmetrics.GetCounter(xmetrics.MetricUDPSessionDropsCounter, metrics.Labels{
+		"service": ln.config.Service,
+		"reason":  reason,
+	}); v != nil {
+		v.Inc()
+	}
+}
+
+// idleCheck closes the sessions idle longer than the TTL.
+func (ln *listener) idleCheck() {
+	interval := ln.config.TTL / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			var idles []*session
+			ln.mu.Lock()
+			size := len(ln.sessions)
+			for _, s := range ln.sessions {
+				if now.Sub(time.Unix(0, s.atime.Load())) > ln.config.TTL {
+					idles = append(idles, s)
+				}
+			}
+			ln.mu.Unlock()
+
+			for _, s := range idles {
+				s.Close()
+			}
+			if len(idles) > 0 {
+				ln.config.Logger.Debugf("session table: size=%d, idle=%d", size, len(idles))
+			}
+
+		case <-ln.closed:
+			return
+		}
+	}
+}
+
+// session is the server side connection of the UDP client peer, it implements net.Conn and net.PacketConn.
+type session struct {
+	net.PacketConn
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	rc         chan []byte
+	atime      atomic.Int64
+	closed     chan struct{}
+	closeMu    sync.Mutex
+	onClose    func()
+	keepAlive  bool
+}
+
+func newSession(c net.PacketConn, laddr, raddr net.Addr, queueSize int, keepAlive bool) *session {
+	s := &session{
+		PacketConn: c,
+		localAddr:  laddr,
+		remoteAddr: raddr,
+		rc:         make(chan []byte, queueSize),
+		closed:     make(chan struct{}),
+		keepAlive:  keepAlive,
+	}
+	s.atime.Store(time.Now().UnixNano())
+	return s
+}
+
+func (s *session) ReadFrom(b []byte) (n int, addr net.Addr, err error) {
+	select {
+	case bb := <-s.rc:
+		n = copy(b, bb)
+		bufpool.Put(bb)
+	case <-s.closed:
+		err = net.ErrClosed
+		return
+	}
+
+	addr = s.remoteAddr
+	return
+}
+
+func (s *session) Read(b []byte) (n int, err error) {
+	n, _, err = s.ReadFrom(b)
+	return
+}
+
+func (s *session) Write(b []byte) (n int, err error) {
+	s.atime.Store(time.Now().UnixNano())
+	n, err = s.WriteTo(b, s.remoteAddr)
+	if !s.keepAlive {
+		s.Close()
+	}
+	return
+}
+
+func (s *session) Close() error {
+	s.closeMu.Lock()
+	select {
+	case <-s.closed:
+		s.closeMu.Unlock()
+		return nil
+	default:
+		close(s.closed)
+	}
+	onClose := s.onClose
+	s.closeMu.Unlock()
+
+	if onClose != nil {
+		onClose()
+	}
+	return nil
+}
+
+// setOnClose sets the callback called once the session is closed, it is called asynchronously if the session is already closed.
+func (s *session) setOnClose(f func()) {
+	s.closeMu.Lock()
+	select {
+	case <-s.closed:
+		s.closeMu.Unlock()
+		go f()
+		return
+	default:
+	}
+	s.onClose = f
+	s.closeMu.Unlock()
+}
+
+func (s *session) LocalAddr() net.Addr {
+	return s.localAddr
+}
+
+func (s *session) RemoteAddr() net.Addr {
+	return s.remoteAddr
+}
+
+func (s *session) writeQueue(b []byte) error {
+	s.atime.Store(time.Now().UnixNano())
+
+	select {
+	case <-s.closed:
+		return net.ErrClosed
+	default:
+	}
+
+	select {
+	case s.rc <- b:
+		return nil
+	case <-s.closed:
+		return net.ErrClosed
+	default:
+		return ErrQueueFull
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"net"
 
 	"github.com/go-gost/core/bypass"
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/internal/bufpool"
+	"golang.org/x/net/ipv4"
 )
 
 type Relay struct {
@@ -48,80 +49,103 @@ func (r *Relay) Run(ctx context.Context) (err error) {
 	errc := make(chan error, 2)
 
 	go func() {
-		for {
-			err := func() error {
-				b := bufpool.Get(bufSize)
-				defer bufpool.Put(b)
+		errc <- r.relay(ctx, r.pc1, r.pc2, bufSize, "%s >>> %s data: %d")
+	}()
 
-				n, raddr, err := r.pc1.ReadFrom(b)
-				if err != nil {
-					return err
-				}
+	go func() {
+		errc <- r.relay(ctx, r.pc2, r.pc1, bufSize, "%s <<< %s data: %d")
+	}()
 
-				if r.bypass != nil && r.bypass.Contains(ctx, "udp", raddr.String()) {
-					if r.logger != nil {
-						r.logger.Warn("bypass: ", raddr)
-					}
-					return nil
-				}
+	return <-errc
+}
 
-				if _, err := r.pc2.WriteTo(b[:n], raddr); err != nil {
-					return err
-				}
+// relay forwards the packets from src to dst, the packets are read (and written
+// if both sides support it) in batches if src is a plain UDP socket.
+func (r *Relay) relay(ctx context.Context, src, dst net.PacketConn, bufSize int, format string) error {
+	rbc := newBatchConn(src)
+	if rbc == nil {
+		for {
+			if err := r.relayOnce(ctx, src, dst, bufSize, format); err != nil {
+				return err
+			}
+		}
+	}
+	wbc := newBatchConn(dst)
+
+	rms := make([]ipv4.Message, batchSize)
+	wms := make([]ipv4.Message, 0, batchSize)
+	for i := range rms {
+		b := bufpool.Get(bufSize)
+		defer bufpool.Put(b)
+		rms[i].Buffers = [][]byte{b}
+	}
 
-				if r.logger != nil {
-					r.logger.Tracef("%s >>> %s data: %d",
-						r.pc2.LocalAddr(), raddr, n)
+	for {
+		n, err := rbc.ReadBatch(rms, 0)
+		if err != nil {
+			return err
+		}
 
+		wms = wms[:0]
+		for i := range rms[:n] {
+			m := &rms[i]
+			if !r.allow(ctx, m.Addr) {
+				continue
+			}
+			b := m.Buffers[0][:m.N]
+			if wbc == nil {
+				if _, err := dst.WriteTo(b, m.Addr); err != nil {
+					return err
 				}
-
-				return nil
-			}()
-
-			if err != nil {
-				errc <- err
-				return
+			} else {
+				wms = append(wms, ipv4.Message{
+					Buffers: [][]byte{b},
+					Addr:    m.Addr,
+				})
+			}
+			if r.logger != nil {
+				r.logger.Tracef(format, r.pc2.LocalAddr(), m.Addr, m.N)
 			}
 		}
-	}()
 
-	go func() {
-		for {
-			err := func() error {
-				b := bufpool.Get(bufSize)
-				defer bufpool.Put(b)
+		if len(wms) > 0 {
+			if err := writeBatch(wbc, wms); err != nil {
+				return err
+			}
+		}
+	}
+}
 
-				n, raddr, err := r.pc2.ReadFrom(b)
-				if err != nil {
-					return err
-				}
+func (r *Relay) relayOnce(ctx context.Context, src, dst net.PacketConn, bufSize int, format string) error {
+	b := bufpool.Get(bufSize)
+	defer bufpool.Put(b)
 
-				if r.bypass != nil && r.bypass.Contains(ctx, "udp", raddr.String()) {
-					if r.logger != nil {
-						r.logger.Warn("bypass: ", raddr)
-					}
-					return nil
-				}
+	n, raddr, err := src.ReadFrom(b)
+	if err != nil {
+		return err
+	}
 
-				if _, err := r.pc1.WriteTo(b[:n], raddr); err != nil {
-					return err
-				}
+	if !r.allow(ctx, raddr) {
+		return nil
+	}
 
-				if r.logger != nil {
-					r.logger.Tracef("%s <<< %s data: %d",
-						r.pc2.LocalAddr(), raddr, n)
+	if _, err := dst.WriteTo(b[:n], raddr); err != nil {
+		return err
+	}
 
-				}
+	if r.logger != nil {
+		r.logger.Tracef(format, r.pc2.LocalAddr(), raddr, n)
+	}
 
-				return nil
-			}()
+	return nil
+}
 
-			if err != nil {
-				errc <- err
-				return
-			}
+func (r *Relay) allow(ctx context.Context, addr net.Addr) bool {
+	if r.bypass != nil && r.bypass.Contains(ctx, "udp", addr.String()) {
+		if r.logger != nil {
+			r.logger.Warn("bypass: ", addr)
 		}
-	}()
-
-	return <-errc
+		return false
+	}
+	return true
 }
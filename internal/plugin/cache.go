@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// the response header of the gRPC plugins carrying the TTL of the decision,
+	// the value is a duration (e.g. 30s) or a number of seconds.
+	cacheTTLHeader = "cache-ttl"
+
+	defaultCacheCleanupInterval = time.Minute
+)
+
+// Cache caches the decisions of a plugin, so the repeated requests are served
+// locally within the TTL without a round trip to the plugin.
+type Cache struct {
+	c   *cache.Cache
+	ttl time.Duration
+}
+
+// NewCache creates a decision cache with the default TTL,
+// zero TTL caches only the decisions with the TTL set by the plugin.
+func NewCache(ttl time.Duration) *Cache {
+	interval := defaultCacheCleanupInterval
+	if ttl > 0 {
+		interval = 2 * ttl
+	}
+	return &Cache{
+		c:   cache.New(ttl, interval),
+		ttl: ttl,
+	}
+}
+
+func (c *Cache) Get(key string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return c.c.Get(key)
+}
+
+// Set caches the decision v for the TTL, the default TTL is used if the TTL is not positive.
+func (c *Cache) Set(key string, v any, ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.c.Set(key, v, ttl)
+}
+
+// CacheKey joins the request fields into a cache key.
+func CacheKey(fields ...string) string {
+	return strings.Join(fields, "\x00")
+}
+
+// ContextCacheKey joins the request fields and the values of the connection sent to the plugins into a cache key,
+// so the cached decision is not shared by the flows with the different values.
+func ContextCacheKey(ctx context.Context, fields ...string) string {
+	return CacheKey(append(fields, cacheValues(ctx)...)...)
+}
+
+// HeaderTTL returns the TTL of the decision set by the plugin in the response header md, zero if not set.
+func HeaderTTL(md metadata.MD) time.Duration {
+	v := md.Get(cacheTTLHeader)
+	if len(v) == 0 {
+		return 0
+	}
+	if d, err := time.ParseDuration(v[0]); err == nil {
+		return d
+	}
+	n, _ := strconv.Atoi(v[0])
+	return time.Duration(n) * time.Second
+}
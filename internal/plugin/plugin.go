@@ -3,6 +3,7 @@ package plugin
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -17,6 +18,8 @@ type Options struct {
 	TLSConfig *tls.Config
 	Header    http.Header
 	Timeout   time.Duration
+	CacheTTL  time.Duration
+	Retries   int
 }
 
 type Option func(opts *Options)
@@ -45,6 +48,20 @@ func TimeoutOption(timeout time.Duration) Option {
 	}
 }
 
+// CacheTTLOption sets the TTL of the cached plugin decisions, zero disables the cache.
+func CacheTTLOption(ttl time.Duration) Option {
+	return func(opts *Options) {
+		opts.CacheTTL = ttl
+	}
+}
+
+// RetriesOption sets the number of retries of a failed gRPC call when the plugin is unavailable.
+func RetriesOption(retries int) Option {
+	return func(opts *Options) {
+		opts.Retries = retries
+	}
+}
+
 func NewGRPCConn(addr string, opts *Options) (*grpc.ClientConn, error) {
 	grpcOpts := []grpc.DialOption{
 		// grpc.WithBlock(),
@@ -52,6 +69,10 @@ func NewGRPCConn(addr string, opts *Options) (*grpc.ClientConn, error) {
 			Backoff: backoff.DefaultConfig,
 		}),
 		grpc.FailOnNonTempDialError(true),
+		grpc.WithChainUnaryInterceptor(valuesUnaryInterceptor),
+	}
+	if opts.Retries > 0 {
+		grpcOpts = append(grpcOpts, grpc.WithDefaultServiceConfig(retryServiceConfig(opts.Retries)))
 	}
 	if opts.TLSConfig != nil {
 		grpcOpts = append(grpcOpts,
@@ -67,6 +88,16 @@ func NewGRPCConn(addr string, opts *Options) (*grpc.ClientConn, error) {
 	return grpc.Dial(addr, grpcOpts...)
 }
 
+// retryServiceConfig returns the service config retrying all the methods on UNAVAILABLE status.
+func retryServiceConfig(retries int) string {
+	// gRPC caps the attempts at 5.
+	attempts := retries + 1
+	if attempts > 5 {
+		attempts = 5
+	}
+	return fmt.Sprintf(`{"methodConfig":[{"name":[{}],"retryPolicy":{"maxAttempts":%d,"initialBackoff":"0.1s","maxBackoff":"1s","backoffMultiplier":2,"retryableStatusCodes":["UNAVAILABLE"]}}]}`, attempts)
+}
+
 type rpcCredentials struct {
 	token string
 }
@@ -84,8 +115,10 @@ func (c *rpcCredentials) RequireTransportSecurity() bool {
 func NewHTTPClient(opts *Options) *http.Client {
 	return &http.Client{
 		Timeout: opts.Timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: opts.TLSConfig,
+		Transport: &valuesTransport{
+			RoundTripper: &http.Transport{
+				TLSClientConfig: opts.TLSConfig,
+			},
 		},
 	}
 }
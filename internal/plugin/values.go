@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	ctxvalue "github.com/go-gost/x/ctx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// the headers and gRPC metadata keys carrying the values of the connection to the plugins.
+const (
+	headerTraceID  = "X-Gost-Trace-Id"
+	headerClientID = "X-Gost-Client-Id"
+	headerHost     = "X-Gost-Host"
+	headerProtocol = "X-Gost-Protocol"
+	headerRules    = "X-Gost-Rules"
+)
+
+func valuePairs(ctx context.Context) []string {
+	values := ctxvalue.ValuesFromContext(ctx)
+	if values == nil {
+		return nil
+	}
+
+	var pairs []string
+	add := func(k, v string) {
+		if v != "" {
+			pairs = append(pairs, k, v)
+		}
+	}
+	add(headerTraceID, values.TraceID())
+	add(headerClientID, values.ClientID())
+	add(headerHost, values.Host())
+	add(headerProtocol, values.Protocol())
+	add(headerRules, strings.Join(values.Rules(), ","))
+	return pairs
+}
+
+// cacheValues returns the values of the connection which may change the decisions of the plugins,
+// the trace ID is unique for each connection and is left out.
+func cacheValues(ctx context.Context) []string {
+	values := ctxvalue.ValuesFromContext(ctx)
+	if values == nil {
+		return nil
+	}
+	return []string{values.ClientID(), values.Host(), values.Protocol(), strings.Join(values.Rules(), ",")}
+}
+
+// valuesUnaryInterceptor appends the values of the connection in the context to the outgoing metadata of the calls.
+func valuesUnaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if pairs := valuePairs(ctx); len(pairs) > 0 {
+		for i := 0; i < len(pairs); i += 2 {
+			pairs[i] = strings.ToLower(pairs[i])
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// valuesTransport sets the values of the connection in the request context as the headers of the request.
+type valuesTransport struct {
+	http.RoundTripper
+}
+
+func (t *valuesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	pairs := valuePairs(req.Context())
+	if len(pairs) == 0 {
+		return t.RoundTripper.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	for i := 0; i < len(pairs); i += 2 {
+		req.Header.Set(pairs[i], pairs[i+1])
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
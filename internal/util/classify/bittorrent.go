@@ -0,0 +1,84 @@
+package classify
+
+import (
+	"net"
+	"sync"
+
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metrics"
+	xerrors "github.com/go-gost/x/errors"
+	xmetrics "github.com/go-gost/x/metrics"
+)
+
+// BlockBitTorrent wraps the connection c dialed by the handler of the service to the target,
+// c is closed if the first data written by the client is a BitTorrent handshake or tracker announce.
+func BlockBitTorrent(c net.Conn, service string, log logger.Logger) net.Conn {
+	if log == nil {
+		log = logger.Default()
+	}
+	return &btConn{
+		Conn:    c,
+		service: service,
+		log:     log,
+	}
+}
+
+type btConn struct {
+	net.Conn
+	service string
+	log     logger.Logger
+	once    sync.Once
+	blocked bool
+}
+
+func (c *btConn) Write(b []byte) (int, error) {
+	c.once.Do(func() {
+		if Classify("tcp", b) != ProtoBitTorrent {
+			return
+		}
+		c.log.Warnf("bittorrent: flow to %s is blocked", c.Conn.RemoteAddr())
+		btBlocked(c.service, "tcp")
+		c.blocked = true
+		c.Conn.Close()
+	})
+	if c.blocked {
+		return 0, xerrors.ErrBitTorrent
+	}
+	return c.Conn.Write(b)
+}
+
+// BlockBitTorrentPacketConn wraps the UDP association pc of the handler of the service,
+// the DHT and UDP tracker datagrams written to pc are dropped.
+func BlockBitTorrentPacketConn(pc net.PacketConn, service string, log logger.Logger) net.PacketConn {
+	if log == nil {
+		log = logger.Default()
+	}
+	return &btPacketConn{
+		PacketConn: pc,
+		service:    service,
+		log:        log,
+	}
+}
+
+type btPacketConn struct {
+	net.PacketConn
+	service string
+	log     logger.Logger
+}
+
+// WriteTo drops the BitTorrent datagrams silently, so that the other datagrams of the association are still relayed.
+func (c *btPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if Classify("udp", p) == ProtoBitTorrent {
+		c.log.Debugf("bittorrent: datagram to %s is dropped", addr)
+		btBlocked(c.service, "udp")
+		return len(p), nil
+	}
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+func btBlocked(service, network string) {
+	if v := xmetrics.GetCounter(xmetrics.MetricServiceBitTorrentBlockedCounter,
+		metrics.Labels{"service": service, "network": network}); v != nil {
+		v.Inc()
+	}
+}
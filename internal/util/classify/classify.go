@@ -0,0 +1,140 @@
+// Package classify detects the application protocol of a flow by the signatures of the first bytes sent by the client.
+package classify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	ProtoBitTorrent = "bittorrent"
+	ProtoSSH        = "ssh"
+	ProtoRDP        = "rdp"
+	ProtoSMTP       = "smtp"
+	ProtoDNS        = "dns"
+	ProtoQUIC       = "quic"
+	ProtoTLS        = "tls"
+	ProtoHTTP       = "http"
+	ProtoUnknown    = "unknown"
+)
+
+var (
+	btHandshake = []byte("\x13BitTorrent protocol")
+	// the magic of the connect request of the UDP tracker protocol (BEP 15).
+	btTrackerMagic = []byte{0x00, 0x00, 0x04, 0x17, 0x27, 0x10, 0x19, 0x80}
+
+	httpMethods = []string{"GET ", "POST ", "PUT ", "DELETE ", "HEAD ", "OPTIONS ", "PATCH ", "CONNECT ", "TRACE "}
+)
+
+// Classify returns the protocol of the flow on network (tcp or udp) by the first bytes b sent by the client,
+// ProtoUnknown is returned if no signature is matched.
+func Classify(network string, b []byte) string {
+	if strings.HasPrefix(network, "udp") {
+		return classifyDatagram(b)
+	}
+	return classifyStream(b)
+}
+
+func classifyStream(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, btHandshake):
+		return ProtoBitTorrent
+	case bytes.HasPrefix(b, []byte("SSH-")):
+		return ProtoSSH
+	case isTLS(b):
+		return ProtoTLS
+	case isRDP(b):
+		return ProtoRDP
+	case isSMTP(b):
+		return ProtoSMTP
+	case isTrackerAnnounce(b):
+		return ProtoBitTorrent
+	case isHTTP(b):
+		return ProtoHTTP
+	case len(b) > 2 && isDNS(b[2:]) && int(binary.BigEndian.Uint16(b)) >= len(b)-2:
+		// DNS over TCP is prefixed with the length of the message.
+		return ProtoDNS
+	}
+	return ProtoUnknown
+}
+
+func classifyDatagram(b []byte) string {
+	switch {
+	case isDHT(b) || bytes.HasPrefix(b, btTrackerMagic):
+		return ProtoBitTorrent
+	case isQUIC(b):
+		return ProtoQUIC
+	case isDNS(b):
+		return ProtoDNS
+	}
+	return ProtoUnknown
+}
+
+func isTLS(b []byte) bool {
+	// the handshake record of TLS 1.0 - 1.3.
+	return len(b) >= 3 && b[0] == 0x16 && b[1] == 0x03 && b[2] <= 0x04
+}
+
+// isRDP checks the TPKT header followed by the X.224 connection request.
+func isRDP(b []byte) bool {
+	return len(b) >= 11 && b[0] == 0x03 && b[1] == 0x00 &&
+		int(binary.BigEndian.Uint16(b[2:])) >= 11 && b[5]&0xf0 == 0xe0
+}
+
+func isSMTP(b []byte) bool {
+	if len(b) < 5 {
+		return false
+	}
+	cmd := strings.ToUpper(string(b[:5]))
+	return cmd == "EHLO " || cmd == "HELO "
+}
+
+func isHTTP(b []byte) bool {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(b, []byte(method)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrackerAnnounce checks the announce or scrape request of the HTTP tracker protocol (BEP 3).
+func isTrackerAnnounce(b []byte) bool {
+	if !bytes.HasPrefix(b, []byte("GET ")) {
+		return false
+	}
+	line, _, _ := bytes.Cut(b, []byte("\r\n"))
+	return bytes.Contains(line, []byte("info_hash="))
+}
+
+func isDNS(b []byte) bool {
+	if len(b) < 12 {
+		return false
+	}
+	var m dns.Msg
+	if err := m.Unpack(b); err != nil {
+		return false
+	}
+	return !m.Response && m.Opcode == dns.OpcodeQuery && len(m.Question) > 0
+}
+
+// isDHT checks the bencoded KRPC message of the mainline DHT (BEP 5).
+func isDHT(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("d1:")) &&
+		(bytes.Contains(b, []byte("1:y1:q")) || bytes.Contains(b, []byte("1:y1:r")) || bytes.Contains(b, []byte("1:y1:e")))
+}
+
+// isQUIC checks the long header packet of QUIC v1, v2 or the drafts.
+func isQUIC(b []byte) bool {
+	if len(b) < 5 || b[0]&0xc0 != 0xc0 {
+		return false
+	}
+	switch v := binary.BigEndian.Uint32(b[1:5]); {
+	case v == 0x00000001, v == 0x6b3343cf, v&0xffffff00 == 0xff000000:
+		return true
+	}
+	return false
+}
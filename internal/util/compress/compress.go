@@ -0,0 +1,197 @@
+// Package compress implements the on-the-fly compression of the HTTP responses relayed to the clients.
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyCompress          = "compress"
+	MDKeyCompressEncodings = "compress.encodings"
+	MDKeyCompressMinSize   = "compress.minSize"
+	MDKeyCompressTypes     = "compress.types"
+)
+
+const (
+	defaultMinSize = 1024
+)
+
+var (
+	defaultEncodings = []string{"gzip", "deflate"}
+	defaultTypes     = []string{
+		"text/*",
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"image/svg+xml",
+	}
+)
+
+// Encoder creates the compressing writer of an encoding.
+type Encoder func(w io.Writer) io.WriteCloser
+
+var (
+	encoders = map[string]Encoder{
+		"gzip": func(w io.Writer) io.WriteCloser {
+			return gzip.NewWriter(w)
+		},
+		"deflate": func(w io.Writer) io.WriteCloser {
+			return zlib.NewWriter(w)
+		},
+	}
+)
+
+// RegisterEncoder registers the encoder of the content coding name, e.g. br or zstd.
+func RegisterEncoder(name string, encoder Encoder) {
+	encoders[strings.ToLower(name)] = encoder
+}
+
+// Compressor compresses the responses of the content types of at least the min size,
+// with the encoding preferred by the Accept-Encoding of the request.
+type Compressor struct {
+	encodings []string
+	types     []string
+	minSize   int64
+}
+
+// ParseCompressor parses the compressor from metadata, nil is returned if the compression is disabled.
+// The encodings without the encoder are ignored.
+func ParseCompressor(md mdata.Metadata) *Compressor {
+	if !mdutil.GetBool(md, MDKeyCompress) {
+		return nil
+	}
+
+	c := &Compressor{
+		types:   mdutil.GetStrings(md, MDKeyCompressTypes),
+		minSize: int64(mdutil.GetInt(md, MDKeyCompressMinSize)),
+	}
+	encodings := mdutil.GetStrings(md, MDKeyCompressEncodings)
+	if len(encodings) == 0 {
+		encodings = defaultEncodings
+	}
+	for _, name := range encodings {
+		if name = strings.ToLower(name); encoders[name] != nil {
+			c.encodings = append(c.encodings, name)
+		}
+	}
+	if len(c.encodings) == 0 {
+		return nil
+	}
+	if len(c.types) == 0 {
+		c.types = defaultTypes
+	}
+	if c.minSize <= 0 {
+		c.minSize = defaultMinSize
+	}
+	return c
+}
+
+// Compress compresses the body of the response by the accepted encodings of the request.
+// The response is unmodified if it is already encoded, or the body is not compressible.
+func (c *Compressor) Compress(res *http.Response, acceptEncoding string) {
+	if c == nil || res == nil || res.Body == nil || res.Body == http.NoBody {
+		return
+	}
+	if res.StatusCode < http.StatusOK || res.StatusCode == http.StatusNoContent ||
+		res.StatusCode == http.StatusNotModified || res.StatusCode == http.StatusPartialContent {
+		return
+	}
+	if req := res.Request; req != nil && req.Method == http.MethodHead {
+		return
+	}
+	if v := res.Header.Get("Content-Encoding"); v != "" && !strings.EqualFold(v, "identity") {
+		return
+	}
+	if strings.Contains(strings.ToLower(res.Header.Get("Cache-Control")), "no-transform") {
+		return
+	}
+	if res.ContentLength >= 0 && res.ContentLength < c.minSize {
+		return
+	}
+	if !c.matchType(res.Header.Get("Content-Type")) {
+		return
+	}
+
+	encoding := c.negotiate(acceptEncoding)
+	if encoding == "" {
+		return
+	}
+	encoder := encoders[encoding]
+
+	pr, pw := io.Pipe()
+	go func(r io.Reader) {
+		w := encoder(pw)
+		_, err := io.Copy(w, r)
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}(res.Body)
+
+	res.Body = &readCloser{Reader: pr, Closer: res.Body}
+	res.ContentLength = -1
+	res.Header.Del("Content-Length")
+	res.Header.Set("Content-Encoding", encoding)
+	res.Header.Add("Vary", "Accept-Encoding")
+	// the compressed representation is not byte-identical.
+	if etag := res.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		res.Header.Set("ETag", "W/"+etag)
+	}
+	res.TransferEncoding = []string{"chunked"}
+}
+
+// negotiate returns the encoding of the highest quality in the Accept-Encoding,
+// the configured order is used for the equal qualities.
+func (c *Compressor) negotiate(acceptEncoding string) string {
+	qs := make(map[string]float64)
+	for _, s := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(s), ";")
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.EqualFold(strings.TrimSpace(k), "q") {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			qs[name] = q
+		}
+	}
+
+	var encoding string
+	var quality float64
+	for _, name := range c.encodings {
+		q, ok := qs[name]
+		if !ok {
+			q, ok = qs["*"]
+		}
+		if ok && q > quality {
+			encoding, quality = name, q
+		}
+	}
+	return encoding
+}
+
+func (c *Compressor) matchType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, t := range c.types {
+		if t == "*" || strings.EqualFold(t, mediaType) ||
+			strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.ToLower(t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
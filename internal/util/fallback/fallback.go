@@ -0,0 +1,190 @@
+// Package fallback serves the non-proxy HTTP requests received by the proxy services,
+// so that the services look like an ordinary web server to the probes.
+package fallback
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-gost/core/logger"
+	xnet "github.com/go-gost/x/internal/net"
+)
+
+const (
+	// TypeCode responds with the status code.
+	TypeCode = "code"
+	// TypeWeb responds with the page fetched from the website.
+	TypeWeb = "web"
+	// TypeHost forwards the connection to the web server.
+	TypeHost = "host"
+	// TypeFile responds with the file, or the files of the directory.
+	TypeFile = "file"
+)
+
+// Fallback is the response to the non-proxy requests.
+type Fallback struct {
+	Type  string
+	Value string
+}
+
+// Parse parses the fallback in the form of type:value, e.g. file:/var/www/index.html,
+// web:example.com, host:127.0.0.1:8080 or code:404. nil is returned if s is invalid.
+func Parse(s string) *Fallback {
+	typ, value, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || value == "" {
+		return nil
+	}
+	switch typ {
+	case TypeCode, TypeWeb, TypeHost, TypeFile:
+		return &Fallback{Type: typ, Value: value}
+	}
+	return nil
+}
+
+func (f *Fallback) String() string {
+	return f.Type + ":" + f.Value
+}
+
+// ServeConn serves the HTTP requests read from the connection until it is closed,
+// the data already read from the connection should be in br.
+func (f *Fallback) ServeConn(conn net.Conn, br *bufio.Reader, log logger.Logger) error {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+
+	for {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		log.Debugf("fallback: %s %s", req.Method, req.RequestURI)
+
+		if f.Type == TypeHost {
+			cc, err := net.Dial("tcp", f.Value)
+			if err != nil {
+				return err
+			}
+			defer cc.Close()
+
+			if err := req.Write(cc); err != nil {
+				return err
+			}
+			return xnet.Transport(xnet.NewBufferReaderConn(conn, br), cc)
+		}
+
+		resp := f.response(req, log)
+		resp.Close = resp.Close || req.Close
+		err = resp.Write(conn)
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+		if err != nil || resp.Close {
+			return err
+		}
+	}
+}
+
+// ServeHTTP serves the request by the fallback, it is used by the HTTP based listeners.
+func (f *Fallback) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.Type == TypeHost {
+		(&httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(&url.URL{Scheme: "http", Host: f.Value})
+				pr.Out.Host = r.Host
+			},
+		}).ServeHTTP(w, r)
+		return
+	}
+
+	resp := f.response(r, logger.Default())
+	if resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	if resp.Body != nil && r.Method != http.MethodHead {
+		io.Copy(w, resp.Body)
+	}
+}
+
+func (f *Fallback) response(req *http.Request, log logger.Logger) *http.Response {
+	resp := &http.Response{
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		StatusCode: http.StatusNotFound,
+	}
+
+	switch f.Type {
+	case TypeCode:
+		if code, _ := strconv.Atoi(f.Value); code > 0 {
+			resp.StatusCode = code
+		}
+	case TypeWeb:
+		u := f.Value
+		if !strings.HasPrefix(u, "http") {
+			u = "http://" + u
+		}
+		u = strings.TrimSuffix(u, "/") + req.URL.RequestURI()
+		r, err := http.Get(u)
+		if err != nil {
+			log.Errorf("fallback: %v", err)
+			resp.StatusCode = http.StatusBadGateway
+			break
+		}
+		r.ProtoMajor, r.ProtoMinor = 1, 1
+		// the body is written chunked as the content encoding may be decoded by the client.
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		r.TransferEncoding = []string{"chunked"}
+		r.Close = false
+		return r
+	case TypeFile:
+		name := f.Value
+		if fi, err := os.Stat(name); err == nil && fi.IsDir() {
+			p := path.Clean("/" + req.URL.Path)
+			if p == "/" {
+				p = "/index.html"
+			}
+			name = filepath.Join(name, filepath.FromSlash(p))
+		}
+		file, err := os.Open(name)
+		if err != nil {
+			break
+		}
+		fi, err := file.Stat()
+		if err != nil || fi.IsDir() {
+			file.Close()
+			break
+		}
+		resp.StatusCode = http.StatusOK
+		resp.ContentLength = fi.Size()
+		ct := mime.TypeByExtension(filepath.Ext(name))
+		if ct == "" {
+			ct = "text/html; charset=utf-8"
+		}
+		resp.Header.Set("Content-Type", ct)
+		resp.Body = file
+	}
+
+	if resp.Body == nil {
+		resp.ContentLength = 0
+	}
+	resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	return resp
+}
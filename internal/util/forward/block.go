@@ -0,0 +1,130 @@
+package forward
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyBlockPage         = "sniffing.blockPage"
+	MDKeyBlockPageTemplate = "sniffing.blockPage.template"
+	MDKeyBlockPageStatus   = "sniffing.blockPage.status"
+	MDKeyBlockAlert        = "sniffing.blockAlert"
+)
+
+const (
+	tlsRecordAlert = 0x15
+	tlsAlertFatal  = 2
+	// the access_denied alert.
+	tlsAlertAccessDenied = 49
+)
+
+var defaultBlockPage = template.Must(template.New("block").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.StatusText}}</title></head>
+<body>
+<h1>{{.StatusText}}</h1>
+<p>Access to {{.Host}} is blocked by the proxy.</p>
+</body>
+</html>
+`))
+
+// Block is the response to the sniffed HTTP and TLS traffic blocked by the bypass,
+// so that the users see why the access failed instead of the connection being closed silently.
+type Block struct {
+	// Page responds to the blocked HTTP request with the block page.
+	Page     bool
+	Status   int
+	Template *template.Template
+	// Alert responds to the blocked TLS client hello with the access_denied alert.
+	Alert bool
+}
+
+// BlockPageData is the data of the template of the block page.
+type BlockPageData struct {
+	Host       string
+	Path       string
+	Status     int
+	StatusText string
+	Time       time.Time
+}
+
+// ParseBlock parses the block response from metadata, nil is returned if neither the page nor the alert is enabled.
+// The template of the block page is a html/template file executed with BlockPageData.
+func ParseBlock(md mdata.Metadata) (*Block, error) {
+	b := &Block{
+		Page:   mdutil.GetBool(md, MDKeyBlockPage),
+		Status: mdutil.GetInt(md, MDKeyBlockPageStatus),
+		Alert:  mdutil.GetBool(md, MDKeyBlockAlert),
+	}
+	if name := mdutil.GetString(md, MDKeyBlockPageTemplate); name != "" {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if b.Template, err = template.New("block").Parse(string(data)); err != nil {
+			return nil, err
+		}
+		b.Page = true
+	}
+	if !b.Page && !b.Alert {
+		return nil, nil
+	}
+	if b.Status <= 0 {
+		b.Status = http.StatusForbidden
+	}
+	if b.Template == nil {
+		b.Template = defaultBlockPage
+	}
+	return b, nil
+}
+
+// Response returns the block page responding to the request req to host, nil is returned if the page is not enabled.
+func (b *Block) Response(req *http.Request, host string) *http.Response {
+	if b == nil || !b.Page {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	b.Template.Execute(&buf, &BlockPageData{
+		Host:       host,
+		Path:       req.URL.RequestURI(),
+		Status:     b.Status,
+		StatusText: http.StatusText(b.Status),
+		Time:       time.Now(),
+	})
+
+	res := &http.Response{
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		StatusCode:    b.Status,
+		Status:        strconv.Itoa(b.Status) + " " + http.StatusText(b.Status),
+		Header:        http.Header{},
+		ContentLength: int64(buf.Len()),
+		Body:          io.NopCloser(&buf),
+		Request:       req,
+		Close:         true,
+	}
+	res.Header.Set("Content-Type", "text/html; charset=utf-8")
+	res.Header.Set("Cache-Control", "no-store")
+	return res
+}
+
+// WriteAlert writes the fatal access_denied alert to the client of the blocked TLS client hello,
+// nothing is written if the alert is not enabled.
+func (b *Block) WriteAlert(w io.Writer) error {
+	if b == nil || !b.Alert {
+		return nil
+	}
+	// the alert is sent in plaintext as the handshake is not started, TLS 1.2 record version is used as TLS 1.3 does.
+	_, err := w.Write([]byte{tlsRecordAlert, 0x03, 0x03, 0x00, 0x02, tlsAlertFatal, tlsAlertAccessDenied})
+	return err
+}
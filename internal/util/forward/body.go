@@ -0,0 +1,126 @@
+package forward
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyMaxRequestBody  = "sniffing.maxRequestBody"
+	MDKeyMaxResponseBody = "sniffing.maxResponseBody"
+	MDKeyBodyPassthrough = "sniffing.bodyPassthrough"
+)
+
+var (
+	ErrBodyTooLarge = errors.New("http: body too large")
+)
+
+// BodyLimit is the limits of the bodies of the sniffed HTTP traffic.
+type BodyLimit struct {
+	// zero means no limit.
+	MaxRequestBody  int64
+	MaxResponseBody int64
+	// the bodies over the limits (or of the unknown length) are relayed as is without being processed,
+	// instead of being rejected.
+	Passthrough bool
+}
+
+// ParseBodyLimit parses the body limits from metadata, nil is returned if there is no limit.
+func ParseBodyLimit(md mdata.Metadata) *BodyLimit {
+	l := &BodyLimit{
+		MaxRequestBody:  int64(mdutil.GetInt(md, MDKeyMaxRequestBody)),
+		MaxResponseBody: int64(mdutil.GetInt(md, MDKeyMaxResponseBody)),
+		Passthrough:     mdutil.GetBool(md, MDKeyBodyPassthrough),
+	}
+	if l.MaxRequestBody <= 0 && l.MaxResponseBody <= 0 {
+		return nil
+	}
+	return l
+}
+
+// Request checks the body of the request against the limit.
+// A 413 response is returned if the request is rejected, and passthrough is true
+// if the body must be relayed without being processed. In reject mode, the body of the unknown length
+// fails with ErrBodyTooLarge when it exceeds the limit.
+func (l *BodyLimit) Request(req *http.Request) (res *http.Response, passthrough bool) {
+	if l == nil || l.MaxRequestBody <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return nil, false
+	}
+
+	if req.ContentLength >= 0 && req.ContentLength <= l.MaxRequestBody {
+		return nil, false
+	}
+	if l.Passthrough {
+		return nil, true
+	}
+	if req.ContentLength > l.MaxRequestBody {
+		return rejectBody(req, http.StatusRequestEntityTooLarge), false
+	}
+	req.Body = &limitedBody{ReadCloser: req.Body, n: l.MaxRequestBody}
+	return nil, false
+}
+
+// Response checks the body of the response against the limit.
+// A 507 response is returned if the response is rejected, and passthrough is true
+// if the body must be relayed without being processed.
+func (l *BodyLimit) Response(res *http.Response) (*http.Response, bool) {
+	if l == nil || l.MaxResponseBody <= 0 || res.Body == nil || res.Body == http.NoBody {
+		return nil, false
+	}
+
+	if res.ContentLength >= 0 && res.ContentLength <= l.MaxResponseBody {
+		return nil, false
+	}
+	if l.Passthrough {
+		return nil, true
+	}
+	if res.ContentLength > l.MaxResponseBody {
+		res.Body.Close()
+		return rejectBody(res.Request, http.StatusInsufficientStorage), false
+	}
+	res.Body = &limitedBody{ReadCloser: res.Body, n: l.MaxResponseBody}
+	return nil, false
+}
+
+func rejectBody(req *http.Request, status int) *http.Response {
+	body := fmt.Sprintf("%d %s\n", status, http.StatusText(status))
+	res := &http.Response{
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		StatusCode:    status,
+		Header:        http.Header{},
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(strings.NewReader(body)),
+		Request:       req,
+		Close:         true,
+	}
+	res.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	return res
+}
+
+// limitedBody fails with ErrBodyTooLarge after n bytes.
+type limitedBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.n -= int64(n)
+	if b.n < 0 {
+		return n + int(b.n), ErrBodyTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,125 @@
+package forward
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyClientCert        = "clientCert"
+	MDKeyClientCertHeader  = "clientCert.header"
+	MDKeyClientCertDetails = "clientCert.details"
+)
+
+const (
+	ClientCertHash    = "hash"
+	ClientCertSubject = "subject"
+	ClientCertURI     = "uri"
+	ClientCertDNS     = "dns"
+	ClientCertCert    = "cert"
+)
+
+const (
+	defaultClientCertHeader = "X-Forwarded-Client-Cert"
+)
+
+var (
+	defaultClientCertDetails = []string{ClientCertHash, ClientCertSubject, ClientCertURI, ClientCertDNS}
+)
+
+// ClientCert forwards the verified certificate of the TLS client to the backend in the header,
+// in the format of X-Forwarded-Client-Cert of Envoy, e.g.
+//
+//	Hash=<sha256>;Subject="CN=client";URI=spiffe://example.org/client;DNS=client.example.org
+type ClientCert struct {
+	Header  string
+	Details []string
+}
+
+// ParseClientCert parses the client certificate forwarding from metadata, nil is returned if it is disabled.
+func ParseClientCert(md mdata.Metadata) *ClientCert {
+	if !mdutil.GetBool(md, MDKeyClientCert) {
+		return nil
+	}
+
+	c := &ClientCert{
+		Header:  mdutil.GetString(md, MDKeyClientCertHeader),
+		Details: mdutil.GetStrings(md, MDKeyClientCertDetails),
+	}
+	if c.Header == "" {
+		c.Header = defaultClientCertHeader
+	}
+	if len(c.Details) == 0 {
+		c.Details = defaultClientCertDetails
+	}
+	return c
+}
+
+// TLSState returns the state of the TLS connection of conn, nil is returned if conn is not a TLS connection.
+func TLSState(conn net.Conn) *tls.ConnectionState {
+	if md, ok := conn.(mdata.Metadatable); ok && md.Metadata() != nil {
+		state, _ := md.Metadata().Get("tls").(*tls.ConnectionState)
+		return state
+	}
+	return nil
+}
+
+// Value returns the header value of the client certificate in the TLS state,
+// it is empty if there is no verified client certificate.
+func (c *ClientCert) Value(state *tls.ConnectionState) string {
+	if c == nil || state == nil || len(state.PeerCertificates) == 0 || len(state.VerifiedChains) == 0 {
+		return ""
+	}
+	cert := state.PeerCertificates[0]
+
+	var parts []string
+	for _, detail := range c.Details {
+		switch strings.ToLower(detail) {
+		case ClientCertHash:
+			sum := sha256.Sum256(cert.Raw)
+			parts = append(parts, "Hash="+hex.EncodeToString(sum[:]))
+		case ClientCertSubject:
+			parts = append(parts, "Subject="+quoteClientCert(cert.Subject.String()))
+		case ClientCertURI:
+			for _, u := range cert.URIs {
+				parts = append(parts, "URI="+quoteClientCert(u.String()))
+			}
+		case ClientCertDNS:
+			for _, name := range cert.DNSNames {
+				parts = append(parts, "DNS="+quoteClientCert(name))
+			}
+		case ClientCertCert:
+			b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+			parts = append(parts, "Cert="+quoteClientCert(url.QueryEscape(string(b))))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// Apply removes the header of the client certificate from the request, which can not be trusted,
+// and sets it to the value if it is not empty.
+func (c *ClientCert) Apply(req *http.Request, value string) {
+	if c == nil {
+		return
+	}
+	req.Header.Del(c.Header)
+	if value != "" {
+		req.Header.Set(c.Header, value)
+	}
+}
+
+func quoteClientCert(s string) string {
+	if !strings.ContainsAny(s, ",;=\"") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,353 @@
+package forward
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/go-gost/core/common/bufpool"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	ProtoQUIC = "quic"
+	ProtoDTLS = "dtls"
+)
+
+const (
+	quicVersion1 = 0x00000001
+	quicVersion2 = 0x6b3343cf
+)
+
+var (
+	quicSaltV1 = []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a}
+	quicSaltV2 = []byte{0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93, 0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9}
+
+	errShortPacket = errors.New("short packet")
+)
+
+// SniffingDatagram sniffs the first datagram of the UDP conn for the server name in the QUIC Initial packet
+// or the DTLS ClientHello, the returned conn reads the sniffed datagram first.
+// Only the ClientHello carried in the first datagram is recognized.
+func SniffingDatagram(ctx context.Context, conn net.Conn) (c net.Conn, host string, protocol string, err error) {
+	b := bufpool.Get(64 * 1024)
+	defer bufpool.Put(b)
+
+	n, err := conn.Read(b)
+	if err != nil {
+		return conn, "", "", err
+	}
+	first := make([]byte, n)
+	copy(first, b[:n])
+
+	host, protocol = SniffDatagram(first)
+	return &datagramConn{Conn: conn, first: first}, host, protocol, nil
+}
+
+// SniffDatagram returns the server name and the protocol of the QUIC Initial packet or the DTLS ClientHello in b.
+func SniffDatagram(b []byte) (host string, protocol string) {
+	if len(b) == 0 {
+		return
+	}
+	if b[0]&0xc0 == 0xc0 {
+		if hello, err := quicClientHello(b); err == nil {
+			return clientHelloServerName(hello, false), ProtoQUIC
+		}
+		return
+	}
+	if hello, err := dtlsClientHello(b); err == nil {
+		return clientHelloServerName(hello, true), ProtoDTLS
+	}
+	return
+}
+
+type datagramConn struct {
+	net.Conn
+	first []byte
+	mu    sync.Mutex
+}
+
+func (c *datagramConn) Read(b []byte) (n int, err error) {
+	c.mu.Lock()
+	if first := c.first; first != nil {
+		c.first = nil
+		c.mu.Unlock()
+		return copy(b, first), nil
+	}
+	c.mu.Unlock()
+
+	return c.Conn.Read(b)
+}
+
+// quicClientHello decrypts the QUIC Initial packet in b and returns the ClientHello in the CRYPTO frames.
+func quicClientHello(b []byte) ([]byte, error) {
+	if len(b) < 7 {
+		return nil, errShortPacket
+	}
+	version := binary.BigEndian.Uint32(b[1:5])
+
+	var salt []byte
+	var labelPrefix string
+	initialType := byte(0)
+	switch version {
+	case quicVersion1:
+		salt, labelPrefix = quicSaltV1, "quic "
+	case quicVersion2:
+		salt, labelPrefix, initialType = quicSaltV2, "quicv2 ", 1
+	default:
+		return nil, errors.New("unsupported version")
+	}
+	if (b[0]>>4)&0x03 != initialType {
+		return nil, errors.New("not an initial packet")
+	}
+
+	pos := 5
+	dcid, pos, err := readBytes8(b, pos)
+	if err != nil {
+		return nil, err
+	}
+	if _, pos, err = readBytes8(b, pos); err != nil { // scid
+		return nil, err
+	}
+	tokenLen, pos, err := readVarint(b, pos)
+	if err != nil {
+		return nil, err
+	}
+	pos += int(tokenLen)
+	length, pos, err := readVarint(b, pos)
+	if err != nil {
+		return nil, err
+	}
+	pnOffset := pos
+	if pnOffset+int(length) > len(b) || length < 20 {
+		return nil, errShortPacket
+	}
+
+	secret := hkdf.Extract(sha256.New, dcid, salt)
+	clientSecret := hkdfExpandLabel(secret, "client in", 32)
+	key := hkdfExpandLabel(clientSecret, labelPrefix+"key", 16)
+	iv := hkdfExpandLabel(clientSecret, labelPrefix+"iv", 12)
+	hp := hkdfExpandLabel(clientSecret, labelPrefix+"hp", 16)
+
+	// remove the header protection.
+	hb, err := aes.NewCipher(hp)
+	if err != nil {
+		return nil, err
+	}
+	mask := make([]byte, aes.BlockSize)
+	hb.Encrypt(mask, b[pnOffset+4:pnOffset+4+aes.BlockSize])
+
+	header := make([]byte, pnOffset+4)
+	copy(header, b)
+	header[0] ^= mask[0] & 0x0f
+	pnLen := int(header[0]&0x03) + 1
+	var pn uint64
+	for i := 0; i < pnLen; i++ {
+		header[pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(header[pnOffset+i])
+	}
+	header = header[:pnOffset+pnLen]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, len(iv))
+	copy(nonce, iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * i))
+	}
+	payload, err := aead.Open(nil, nonce, b[pnOffset+pnLen:pnOffset+int(length)], header)
+	if err != nil {
+		return nil, err
+	}
+
+	return quicCryptoData(payload)
+}
+
+// quicCryptoData reassembles the data of the CRYPTO frames from the offset 0.
+func quicCryptoData(payload []byte) ([]byte, error) {
+	var data []byte
+	var got []bool
+	for pos := 0; pos < len(payload); {
+		switch payload[pos] {
+		case 0x00, 0x01: // PADDING, PING
+			pos++
+		case 0x06: // CRYPTO
+			var offset, n uint64
+			var err error
+			if offset, pos, err = readVarint(payload, pos+1); err != nil {
+				return nil, err
+			}
+			if n, pos, err = readVarint(payload, pos); err != nil {
+				return nil, err
+			}
+			if pos+int(n) > len(payload) || offset+n > 64*1024 {
+				return nil, errShortPacket
+			}
+			if end := int(offset + n); end > len(data) {
+				data = append(data, make([]byte, end-len(data))...)
+				got = append(got, make([]bool, end-len(got))...)
+			}
+			copy(data[offset:], payload[pos:pos+int(n)])
+			for i := offset; i < offset+n; i++ {
+				got[i] = true
+			}
+			pos += int(n)
+		default:
+			// the other frames are not expected before the ClientHello.
+			pos = len(payload)
+		}
+	}
+
+	// the contiguous data from the offset 0.
+	for i, ok := range got {
+		if !ok {
+			data = data[:i]
+			break
+		}
+	}
+	if len(data) == 0 {
+		return nil, errors.New("no crypto data")
+	}
+	return data, nil
+}
+
+// dtlsClientHello returns the ClientHello in the first DTLS record of b,
+// with the DTLS handshake header converted to the TLS one.
+func dtlsClientHello(b []byte) ([]byte, error) {
+	const (
+		recordHeaderLen    = 13
+		handshakeHeaderLen = 12
+	)
+	if len(b) < recordHeaderLen+handshakeHeaderLen {
+		return nil, errShortPacket
+	}
+	// handshake record of DTLS 1.0 or 1.2.
+	if b[0] != 22 || b[1] != 0xfe || (b[2] != 0xff && b[2] != 0xfd) {
+		return nil, errors.New("not a DTLS handshake")
+	}
+	n := int(binary.BigEndian.Uint16(b[11:13]))
+	record := b[recordHeaderLen:]
+	if n < len(record) {
+		record = record[:n]
+	}
+	if len(record) < handshakeHeaderLen || record[0] != 1 {
+		return nil, errors.New("not a ClientHello")
+	}
+
+	hello := make([]byte, 4, 4+len(record)-handshakeHeaderLen)
+	copy(hello, record[:4])
+	return append(hello, record[handshakeHeaderLen:]...), nil
+}
+
+// clientHelloServerName returns the server name in the handshake message of the ClientHello,
+// the message may be truncated after the server name extension.
+func clientHelloServerName(hello []byte, dtls bool) string {
+	if len(hello) < 4 || hello[0] != 1 {
+		return ""
+	}
+	b := hello[4:]
+
+	// version and random
+	pos := 2 + 32
+	// session id
+	if pos >= len(b) {
+		return ""
+	}
+	pos += 1 + int(b[pos])
+	// cookie
+	if dtls {
+		if pos >= len(b) {
+			return ""
+		}
+		pos += 1 + int(b[pos])
+	}
+	// cipher suites
+	if pos+2 > len(b) {
+		return ""
+	}
+	pos += 2 + int(binary.BigEndian.Uint16(b[pos:]))
+	// compression methods
+	if pos >= len(b) {
+		return ""
+	}
+	pos += 1 + int(b[pos])
+	// extensions
+	if pos+2 > len(b) {
+		return ""
+	}
+	pos += 2
+
+	for pos+4 <= len(b) {
+		typ := binary.BigEndian.Uint16(b[pos:])
+		n := int(binary.BigEndian.Uint16(b[pos+2:]))
+		pos += 4
+		if pos+n > len(b) {
+			return ""
+		}
+		if typ == 0 { // server_name
+			ext := b[pos : pos+n]
+			// list length, name type, name length
+			if len(ext) < 5 || ext[2] != 0 {
+				return ""
+			}
+			nameLen := int(binary.BigEndian.Uint16(ext[3:]))
+			if 5+nameLen > len(ext) {
+				return ""
+			}
+			return string(ext[5 : 5+nameLen])
+		}
+		pos += n
+	}
+	return ""
+}
+
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	label = "tls13 " + label
+	info := make([]byte, 0, 4+len(label))
+	info = binary.BigEndian.AppendUint16(info, uint16(length))
+	info = append(info, byte(len(label)))
+	info = append(info, label...)
+	info = append(info, 0)
+
+	out := make([]byte, length)
+	hkdf.Expand(sha256.New, secret, info).Read(out)
+	return out
+}
+
+func readBytes8(b []byte, pos int) ([]byte, int, error) {
+	if pos >= len(b) {
+		return nil, pos, errShortPacket
+	}
+	n := int(b[pos])
+	pos++
+	if pos+n > len(b) {
+		return nil, pos, errShortPacket
+	}
+	return b[pos : pos+n], pos + n, nil
+}
+
+func readVarint(b []byte, pos int) (uint64, int, error) {
+	if pos >= len(b) {
+		return 0, pos, errShortPacket
+	}
+	n := 1 << (b[pos] >> 6)
+	if pos+n > len(b) {
+		return 0, pos, errShortPacket
+	}
+	v := uint64(b[pos] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[pos+i])
+	}
+	return v, pos + n, nil
+}
@@ -0,0 +1,95 @@
+package forward
+
+import (
+	"bytes"
+	"io"
+	"net/textproto"
+	"strings"
+
+	dissector "github.com/go-gost/tls-dissector"
+)
+
+const (
+	// the maximum number of the bytes watched for the host.
+	maxWatchSize = 16 * 1024
+)
+
+// WatchServerName returns a reader which calls found with the server name of the TLS client hello read from r
+// as soon as the server name extension is received, before the rest of the handshake.
+func WatchServerName(r io.Reader, found func(host string)) io.Reader {
+	return &hostWatcher{
+		r:     r,
+		parse: partialServerName,
+		found: found,
+	}
+}
+
+// WatchHTTPHost returns a reader which calls found with the Host header of the HTTP request read from r
+// as soon as the header is received, before the rest of the request.
+func WatchHTTPHost(r io.Reader, found func(host string)) io.Reader {
+	return &hostWatcher{
+		r:     r,
+		parse: partialHTTPHost,
+		found: found,
+	}
+}
+
+type hostWatcher struct {
+	r     io.Reader
+	buf   []byte
+	parse func(b []byte) string
+	found func(host string)
+	done  bool
+}
+
+func (w *hostWatcher) Read(b []byte) (n int, err error) {
+	n, err = w.r.Read(b)
+	if w.done || n == 0 {
+		return
+	}
+
+	w.buf = append(w.buf, b[:n]...)
+	if host := w.parse(w.buf); host != "" {
+		w.done = true
+		w.buf = nil
+		w.found(host)
+	} else if len(w.buf) > maxWatchSize {
+		w.done = true
+		w.buf = nil
+	}
+	return
+}
+
+// SniffHost returns the server name of the TLS client hello or the Host header of the HTTP request in b,
+// empty string is returned if b is neither of them or the host is not received yet.
+func SniffHost(b []byte) string {
+	if host := partialServerName(b); host != "" {
+		return host
+	}
+	return partialHTTPHost(b)
+}
+
+func partialServerName(b []byte) string {
+	if len(b) <= dissector.RecordHeaderLen || b[0] != dissector.Handshake {
+		return ""
+	}
+	return clientHelloServerName(b[dissector.RecordHeaderLen:], false)
+}
+
+func partialHTTPHost(b []byte) string {
+	// skip the request line
+	i := bytes.Index(b, []byte("\r\n"))
+	if i < 0 {
+		return ""
+	}
+	for b = b[i+2:]; ; {
+		i = bytes.Index(b, []byte("\r\n"))
+		if i <= 0 {
+			return ""
+		}
+		if k, v, ok := strings.Cut(string(b[:i]), ":"); ok && textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(k)) == "Host" {
+			return strings.TrimSpace(v)
+		}
+		b = b[i+2:]
+	}
+}
@@ -0,0 +1,61 @@
+package forward
+
+import (
+	"math/rand"
+	"net"
+
+	"github.com/go-gost/core/common/bufpool"
+)
+
+const (
+	// the datagrams are replicated to all the replicas.
+	ReplicateAll = "all"
+	// the datagrams are replicated to the replicas by the sampling ratio.
+	ReplicateSample = "sample"
+)
+
+type replicaConn struct {
+	net.Conn
+	replicas []net.Conn
+	ratio    float64
+}
+
+// ReplicaConn replicates the datagrams written to c to the replicas with the probability ratio,
+// the replies of the replicas are discarded.
+func ReplicaConn(c net.Conn, replicas []net.Conn, ratio float64) net.Conn {
+	if len(replicas) == 0 || ratio <= 0 {
+		return c
+	}
+	for _, rc := range replicas {
+		go drain(rc)
+	}
+	return &replicaConn{
+		Conn:     c,
+		replicas: replicas,
+		ratio:    ratio,
+	}
+}
+
+func (c *replicaConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if err != nil {
+		return n, err
+	}
+	for _, rc := range c.replicas {
+		if c.ratio >= 1 || rand.Float64() < c.ratio {
+			// the failures of the replicas do not affect the primary target.
+			rc.Write(b)
+		}
+	}
+	return n, nil
+}
+
+func drain(c net.Conn) {
+	b := bufpool.Get(64 * 1024)
+	defer bufpool.Put(b)
+	for {
+		if _, err := c.Read(b); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package forward
+
+import (
+	"github.com/go-gost/core/chain"
+)
+
+// NodeRouter returns a router dialing with the egress interface (or source IP)
+// and socket options of the target node, the router is returned as is if the node has none.
+func NodeRouter(router *chain.Router, node *chain.Node) *chain.Router {
+	if router == nil || node == nil {
+		return router
+	}
+	tr := node.Options().Transport
+	if tr == nil {
+		return router
+	}
+	trOpts := tr.Options()
+	if trOpts.IfceName == "" && trOpts.SockOpts == nil {
+		return router
+	}
+
+	opts := router.Options()
+	ifce := opts.IfceName
+	if trOpts.IfceName != "" {
+		ifce = trOpts.IfceName
+	}
+	sockOpts := opts.SockOpts
+	if trOpts.SockOpts != nil {
+		sockOpts = trOpts.SockOpts
+	}
+
+	return chain.NewRouter(
+		chain.RetriesRouterOption(opts.Retries),
+		chain.TimeoutRouterOption(opts.Timeout),
+		chain.InterfaceRouterOption(ifce),
+		chain.SockOptsRouterOption(sockOpts),
+		chain.ChainRouterOption(opts.Chain),
+		chain.ResolverRouterOption(opts.Resolver),
+		chain.HostMapperRouterOption(opts.HostMapper),
+		chain.RecordersRouterOption(opts.Recorders...),
+		chain.LoggerRouterOption(opts.Logger),
+	)
+}
+
+// ChainRouter returns a router dialing through the chain c in place of the chain of the router.
+func ChainRouter(router *chain.Router, c chain.Chainer) *chain.Router {
+	if router == nil || c == nil {
+		return router
+	}
+
+	opts := router.Options()
+	return chain.NewRouter(
+		chain.RetriesRouterOption(opts.Retries),
+		chain.TimeoutRouterOption(opts.Timeout),
+		chain.InterfaceRouterOption(opts.IfceName),
+		chain.SockOptsRouterOption(opts.SockOpts),
+		chain.ChainRouterOption(c),
+		chain.ResolverRouterOption(opts.Resolver),
+		chain.HostMapperRouterOption(opts.HostMapper),
+		chain.RecordersRouterOption(opts.Recorders...),
+		chain.LoggerRouterOption(opts.Logger),
+	)
+}
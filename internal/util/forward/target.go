@@ -0,0 +1,55 @@
+package forward
+
+import (
+	"crypto/tls"
+	"net"
+
+	"github.com/go-gost/core/chain"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/internal/net/proxyproto"
+	tls_util "github.com/go-gost/x/internal/util/tls"
+)
+
+const (
+	MDKeyProxyProtocol = "proxyProtocol"
+)
+
+// ProxyProtocolConn sends the PROXY protocol header of the version in the metadata (proxyProtocol) of the target node,
+// ppv of the handler is used if the node does not set it.
+func ProxyProtocolConn(cc net.Conn, node *chain.Node, ppv int, src, dst net.Addr) net.Conn {
+	if node != nil {
+		if md := node.Options().Metadata; md != nil && md.IsExists(MDKeyProxyProtocol) {
+			ppv = mdutil.GetInt(md, MDKeyProxyProtocol)
+		}
+	}
+	return proxyproto.WrapClientConn(ppv, src, dst, cc)
+}
+
+// TLSConn establishes the TLS to the backend if the target node has the TLS settings,
+// the SNI is the server name of the settings, or the host of the node address.
+func TLSConn(cc net.Conn, node *chain.Node) net.Conn {
+	if node == nil {
+		return cc
+	}
+	tlsSettings := node.Options().TLS
+	if tlsSettings == nil {
+		return cc
+	}
+
+	cfg := &tls.Config{
+		ServerName:         tlsSettings.ServerName,
+		InsecureSkipVerify: !tlsSettings.Secure,
+	}
+	if cfg.ServerName == "" {
+		if host, _, _ := net.SplitHostPort(node.Addr); host != "" && net.ParseIP(host) == nil {
+			cfg.ServerName = host
+		}
+	}
+	tls_util.SetTLSOptions(cfg, &config.TLSOptions{
+		MinVersion:   tlsSettings.Options.MinVersion,
+		MaxVersion:   tlsSettings.Options.MaxVersion,
+		CipherSuites: tlsSettings.Options.CipherSuites,
+	})
+	return tls.Client(cc, cfg)
+}
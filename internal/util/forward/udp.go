@@ -0,0 +1,51 @@
+package forward
+
+import (
+	"context"
+	"net"
+
+	"github.com/go-gost/core/chain"
+)
+
+const (
+	// the replies are only accepted from the target.
+	NATStrict = "strict"
+	// the replies are accepted from any host, the mapping of the client is independent of the peers.
+	NATFullCone = "fullcone"
+)
+
+// DialUDP dials the UDP target addr through the router, in the full cone mode
+// the outbound socket is not connected if the target is reached directly.
+func DialUDP(ctx context.Context, router *chain.Router, nat string, addr string) (net.Conn, error) {
+	if nat != NATFullCone || (router != nil && router.Options().Chain != nil) {
+		return router.Dial(ctx, "udp", addr)
+	}
+
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &fullConeConn{UDPConn: pc, raddr: raddr}, nil
+}
+
+type fullConeConn struct {
+	*net.UDPConn
+	raddr *net.UDPAddr
+}
+
+func (c *fullConeConn) Read(b []byte) (int, error) {
+	n, _, err := c.UDPConn.ReadFromUDP(b)
+	return n, err
+}
+
+func (c *fullConeConn) Write(b []byte) (int, error) {
+	return c.UDPConn.WriteToUDP(b, c.raddr)
+}
+
+func (c *fullConeConn) RemoteAddr() net.Addr {
+	return c.raddr
+}
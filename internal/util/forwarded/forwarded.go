@@ -0,0 +1,215 @@
+// Package forwarded implements the policy of the X-Forwarded-For, X-Real-IP and Forwarded headers
+// of the HTTP requests relayed to the backends.
+package forwarded
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/matcher"
+)
+
+const (
+	MDKeyForwarded = "forwarded"
+	MDKeyTrusted   = "forwarded.trusted"
+)
+
+const (
+	// the headers are relayed as is.
+	ModeNone = "none"
+	// the address of the peer is appended to the headers of the trusted peer,
+	// the headers of the untrusted peer are overwritten.
+	ModeAppend = "append"
+	// the headers are overwritten with the address of the client.
+	ModeOverwrite = "overwrite"
+	// the headers are removed.
+	ModeStrip = "strip"
+)
+
+const (
+	headerXForwardedFor  = "X-Forwarded-For"
+	headerXRealIP        = "X-Real-Ip"
+	headerForwarded      = "Forwarded"
+	headerCFConnectingIP = "CF-Connecting-IP"
+)
+
+// Policy is the policy of the forwarded headers.
+type Policy struct {
+	Mode string
+	// the trusted upstream proxies, nil means all the peers are trusted.
+	trusted matcher.Matcher
+}
+
+// ParsePolicy parses the policy from metadata, mode is the default mode.
+func ParsePolicy(md mdata.Metadata, mode string) *Policy {
+	p := &Policy{
+		Mode: strings.ToLower(mdutil.GetString(md, MDKeyForwarded)),
+	}
+	if p.Mode == "" {
+		p.Mode = mode
+	}
+
+	if md != nil && md.IsExists(MDKeyTrusted) {
+		var inets []*net.IPNet
+		for _, s := range mdutil.GetStrings(md, MDKeyTrusted) {
+			if !strings.Contains(s, "/") {
+				if ip := net.ParseIP(s); ip != nil {
+					bits := 8 * net.IPv6len
+					if ip.To4() != nil {
+						ip, bits = ip.To4(), 8*net.IPv4len
+					}
+					inets = append(inets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				}
+				continue
+			}
+			if _, inet, err := net.ParseCIDR(s); err == nil {
+				inets = append(inets, inet)
+			}
+		}
+		p.trusted = matcher.CIDRMatcher(inets)
+	}
+	return p
+}
+
+// Trusted reports whether the peer of the address is a trusted upstream proxy.
+func (p *Policy) Trusted(addr net.Addr) bool {
+	if p == nil || p.trusted == nil {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	return p.trusted.Match(addrIP(addr.String()))
+}
+
+// ClientAddr returns the address of the client of the request from the peer raddr,
+// the forwarded headers are only used if the peer is trusted, and the X-Forwarded-For
+// is followed from the right across the trusted proxies.
+func (p *Policy) ClientAddr(req *http.Request, raddr net.Addr) net.Addr {
+	if req == nil || raddr == nil || !p.Trusted(raddr) {
+		return raddr
+	}
+
+	// cloudflare CDN
+	sip := req.Header.Get(headerCFConnectingIP)
+	if sip == "" {
+		if ips := forwardedFor(req.Header); len(ips) > 0 {
+			// the leftmost address if all the proxies are trusted.
+			sip = ips[0]
+			for i := len(ips) - 1; i > 0 && p.trusted != nil; i-- {
+				if ip := net.ParseIP(ips[i]); ip == nil || !p.trusted.Match(ip.String()) {
+					sip = ips[i]
+					break
+				}
+			}
+		}
+	}
+	if sip == "" {
+		sip = req.Header.Get(headerXRealIP)
+	}
+	if sip == "" {
+		sip = forwardedHeaderFor(req.Header)
+	}
+
+	ip := net.ParseIP(sip)
+	if ip == nil {
+		return raddr
+	}
+
+	_, sp, _ := net.SplitHostPort(raddr.String())
+	port, _ := strconv.Atoi(sp)
+
+	return &net.TCPAddr{
+		IP:   ip,
+		Port: port,
+	}
+}
+
+// Apply applies the policy to the headers of the request from the peer raddr.
+func (p *Policy) Apply(req *http.Request, raddr net.Addr) {
+	if p == nil || req == nil || raddr == nil {
+		return
+	}
+
+	switch p.Mode {
+	case ModeStrip:
+		req.Header.Del(headerXForwardedFor)
+		req.Header.Del(headerXRealIP)
+		req.Header.Del(headerForwarded)
+
+	case ModeOverwrite:
+		client := addrIP(p.ClientAddr(req, raddr).String())
+		req.Header.Set(headerXForwardedFor, client)
+		req.Header.Set(headerXRealIP, client)
+		req.Header.Set(headerForwarded, forwardedElement(req, client))
+
+	case ModeAppend:
+		client := addrIP(p.ClientAddr(req, raddr).String())
+		peer := addrIP(raddr.String())
+		xff, fwd := peer, forwardedElement(req, peer)
+		if p.Trusted(raddr) {
+			if prior := strings.Join(req.Header.Values(headerXForwardedFor), ", "); prior != "" {
+				xff = prior + ", " + xff
+			}
+			if prior := strings.Join(req.Header.Values(headerForwarded), ", "); prior != "" {
+				fwd = prior + ", " + fwd
+			}
+		}
+		req.Header.Set(headerXForwardedFor, xff)
+		req.Header.Set(headerXRealIP, client)
+		req.Header.Set(headerForwarded, fwd)
+	}
+}
+
+func forwardedFor(h http.Header) (ips []string) {
+	for _, v := range h.Values(headerXForwardedFor) {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				ips = append(ips, s)
+			}
+		}
+	}
+	return
+}
+
+// forwardedHeaderFor returns the first for parameter of the Forwarded header.
+func forwardedHeaderFor(h http.Header) string {
+	for _, v := range h.Values(headerForwarded) {
+		for _, elem := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if !strings.EqualFold(k, "for") {
+					continue
+				}
+				v = strings.Trim(v, `"`)
+				if host, _, err := net.SplitHostPort(v); err == nil {
+					return host
+				}
+				return strings.Trim(v, "[]")
+			}
+		}
+	}
+	return ""
+}
+
+func forwardedElement(req *http.Request, ip string) string {
+	if strings.Contains(ip, ":") {
+		ip = `"[` + ip + `]"`
+	}
+	s := "for=" + ip
+	if req.Host != "" {
+		s += `;host="` + req.Host + `"`
+	}
+	return s
+}
+
+func addrIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,331 @@
+// Package ftp implements the FTP helper of the forwarding,
+// which rewrites the data channel addresses of the control channel and relays the data connections.
+package ftp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	xnet "github.com/go-gost/x/internal/net"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+)
+
+var (
+	// h1,h2,h3,h4,p1,p2 of the PORT command and the PASV reply.
+	hostPortRegexp = regexp.MustCompile(`(\d{1,3}),(\d{1,3}),(\d{1,3}),(\d{1,3}),(\d{1,3}),(\d{1,3})`)
+	// (|||port|) of the EPSV reply.
+	epsvRegexp = regexp.MustCompile(`\(([^\d\s])([^\d\s]?)([^\d\s]?)(\d+)([^\d\s])\)`)
+)
+
+// DialFunc dials the data channel to the server.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+type options struct {
+	timeout time.Duration
+	logger  logger.Logger
+}
+
+type Option func(opts *options)
+
+// TimeoutOption sets the timeout of waiting for the data connection.
+func TimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+func LoggerOption(logger logger.Logger) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
+}
+
+// Relay relays the FTP control channel between the client conn and the server cc.
+//
+// The passive mode (PASV/EPSV) replies of the server are rewritten to the address
+// of the proxy on the client side, the data connections are forwarded to the server by dial.
+// The active mode (PORT/EPRT) commands of the client are rewritten to the address
+// of the proxy on the server side, which works only if the server is reached directly,
+// the data connections from the server are forwarded to the client.
+//
+// The control channel is relayed as is after the TLS is negotiated by AUTH.
+func Relay(ctx context.Context, conn net.Conn, cc net.Conn, server string, dial DialFunc, opts ...Option) error {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.timeout <= 0 {
+		options.timeout = defaultTimeout
+	}
+
+	serverHost, _, _ := net.SplitHostPort(server)
+	r := &relay{
+		conn:       conn,
+		cc:         cc,
+		serverHost: serverHost,
+		dial:       dial,
+		options:    options,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errc := make(chan error, 2)
+	go func() {
+		errc <- r.commands(ctx)
+	}()
+	go func() {
+		errc <- r.replies(ctx)
+	}()
+
+	err := <-errc
+	conn.Close()
+	cc.Close()
+	<-errc
+
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+type relay struct {
+	conn       net.Conn
+	cc         net.Conn
+	serverHost string
+	dial       DialFunc
+	options    options
+}
+
+// commands relays the commands from the client to the server.
+func (r *relay) commands(ctx context.Context) error {
+	br := bufio.NewReader(r.conn)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			if line != "" {
+				r.cc.Write([]byte(line))
+			}
+			return err
+		}
+
+		cmd, arg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch strings.ToUpper(cmd) {
+		case "PORT":
+			if s, err := r.active(ctx, arg, false); err == nil {
+				line = fmt.Sprintf("PORT %s\r\n", s)
+			} else {
+				r.logf("PORT %s: %v", arg, err)
+			}
+		case "EPRT":
+			if s, err := r.active(ctx, arg, true); err == nil {
+				line = fmt.Sprintf("EPRT %s\r\n", s)
+			} else {
+				r.logf("EPRT %s: %v", arg, err)
+			}
+		}
+
+		if _, err := r.cc.Write([]byte(line)); err != nil {
+			return err
+		}
+
+		if strings.EqualFold(cmd, "AUTH") {
+			// the commands are encrypted afterwards.
+			_, err := io.Copy(r.cc, br)
+			return err
+		}
+	}
+}
+
+// replies relays the replies from the server to the client.
+func (r *relay) replies(ctx context.Context) error {
+	br := bufio.NewReader(r.cc)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			if line != "" {
+				r.conn.Write([]byte(line))
+			}
+			return err
+		}
+
+		switch {
+		case strings.HasPrefix(line, "227 "):
+			if s, err := r.passive(ctx, line, false); err == nil {
+				line = fmt.Sprintf("227 Entering Passive Mode (%s).\r\n", s)
+			} else {
+				r.logf("PASV %s: %v", strings.TrimSpace(line), err)
+			}
+		case strings.HasPrefix(line, "229 "):
+			if s, err := r.passive(ctx, line, true); err == nil {
+				line = fmt.Sprintf("229 Entering Extended Passive Mode (|||%s|)\r\n", s)
+			} else {
+				r.logf("EPSV %s: %v", strings.TrimSpace(line), err)
+			}
+		}
+
+		if _, err := r.conn.Write([]byte(line)); err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(line, "234 ") {
+			// the TLS is negotiated.
+			_, err := io.Copy(r.conn, br)
+			return err
+		}
+	}
+}
+
+// passive listens on the client side for the data connection to the server address in the reply.
+func (r *relay) passive(ctx context.Context, reply string, extended bool) (string, error) {
+	var addr string
+	if extended {
+		m := epsvRegexp.FindStringSubmatch(reply)
+		if m == nil {
+			return "", fmt.Errorf("invalid reply")
+		}
+		addr = net.JoinHostPort(r.serverHost, m[4])
+	} else {
+		host, port, err := parseHostPort(reply)
+		if err != nil {
+			return "", err
+		}
+		addr = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+
+	host, _, _ := net.SplitHostPort(r.conn.LocalAddr().String())
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return "", err
+	}
+	laddr := ln.Addr().(*net.TCPAddr)
+
+	go r.forward(ctx, ln, func(ctx context.Context) (net.Conn, error) {
+		return r.dial(ctx, "tcp", addr)
+	})
+
+	if extended {
+		return strconv.Itoa(laddr.Port), nil
+	}
+	return formatHostPort(laddr.IP, laddr.Port)
+}
+
+// active listens on the server side for the data connection to the client address in the command.
+func (r *relay) active(ctx context.Context, arg string, extended bool) (string, error) {
+	var addr string
+	if extended {
+		// |af|host|port|
+		parts := strings.Split(arg, arg[:min(1, len(arg))])
+		if len(parts) != 5 {
+			return "", fmt.Errorf("invalid argument")
+		}
+		addr = net.JoinHostPort(parts[2], parts[3])
+	} else {
+		host, port, err := parseHostPort(arg)
+		if err != nil {
+			return "", err
+		}
+		addr = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+
+	host, _, _ := net.SplitHostPort(r.cc.LocalAddr().String())
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return "", err
+	}
+	laddr := ln.Addr().(*net.TCPAddr)
+
+	go r.forward(ctx, ln, func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	})
+
+	if extended {
+		af := "1"
+		if laddr.IP.To4() == nil {
+			af = "2"
+		}
+		return fmt.Sprintf("|%s|%s|%d|", af, laddr.IP, laddr.Port), nil
+	}
+	return formatHostPort(laddr.IP, laddr.Port)
+}
+
+// forward accepts a data connection on ln and forwards it to the peer.
+func (r *relay) forward(ctx context.Context, ln net.Listener, dial func(ctx context.Context) (net.Conn, error)) {
+	defer ln.Close()
+
+	if tl, ok := ln.(*net.TCPListener); ok {
+		tl.SetDeadline(time.Now().Add(r.options.timeout))
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			ln.Close()
+		case <-done:
+		}
+	}()
+
+	c, err := ln.Accept()
+	if err != nil {
+		r.logf("data channel %s: %v", ln.Addr(), err)
+		return
+	}
+	defer c.Close()
+
+	dctx, cancel := context.WithTimeout(ctx, r.options.timeout)
+	defer cancel()
+	pc, err := dial(dctx)
+	if err != nil {
+		r.logf("data channel %s: %v", ln.Addr(), err)
+		return
+	}
+	defer pc.Close()
+
+	if log := r.options.logger; log != nil {
+		log.Debugf("data channel %s <-> %s", c.RemoteAddr(), pc.RemoteAddr())
+	}
+	xnet.Transport(c, pc)
+}
+
+func (r *relay) logf(format string, args ...any) {
+	if r.options.logger != nil {
+		r.options.logger.Warnf("ftp: "+format, args...)
+	}
+}
+
+func parseHostPort(s string) (string, int, error) {
+	m := hostPortRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return "", 0, fmt.Errorf("invalid address")
+	}
+	var v [6]int
+	for i := range v {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil || n > 255 {
+			return "", 0, fmt.Errorf("invalid address")
+		}
+		v[i] = n
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", v[0], v[1], v[2], v[3]), v[4]<<8 | v[5], nil
+}
+
+func formatHostPort(ip net.IP, port int) (string, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return "", fmt.Errorf("IPv6 address %s is not supported by PASV/PORT", ip)
+	}
+	return fmt.Sprintf("%d,%d,%d,%d,%d,%d", ip4[0], ip4[1], ip4[2], ip4[3], port>>8, port&0xff), nil
+}
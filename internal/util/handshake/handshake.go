@@ -0,0 +1,79 @@
+// Package handshake bounds the handshakes of the proxy handlers, so that the clients opening connections
+// without completing the handshakes (slowloris) can not exhaust the service.
+package handshake
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyTimeout     = "handshake.timeout"
+	MDKeyMaxHalfOpen = "handshake.maxHalfOpen"
+)
+
+// Handshake limits the duration of the handshakes and the concurrent half-open handshakes of each client IP.
+type Handshake struct {
+	// the handshake including the authentication must complete in the timeout, zero means no timeout.
+	Timeout time.Duration
+	// the maximum concurrent handshakes of each client IP, zero means no limit.
+	MaxHalfOpen int
+	halfOpen    map[string]int
+	mu          sync.Mutex
+}
+
+// Parse parses the handshake limits from metadata, nil is returned if there is no limit.
+func Parse(md mdata.Metadata) *Handshake {
+	h := &Handshake{
+		Timeout:     mdutil.GetDuration(md, MDKeyTimeout),
+		MaxHalfOpen: mdutil.GetInt(md, MDKeyMaxHalfOpen),
+	}
+	if h.Timeout <= 0 && h.MaxHalfOpen <= 0 {
+		return nil
+	}
+	h.halfOpen = make(map[string]int)
+	return h
+}
+
+// Begin starts the handshake of conn, false is returned if the client IP has reached the limit of the half-open handshakes.
+// The returned done must be called when the handshake completes, it clears the read deadline of conn set by the timeout,
+// and can be called more than once.
+func (h *Handshake) Begin(conn net.Conn) (done func(), ok bool) {
+	if h == nil {
+		return func() {}, true
+	}
+
+	ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	if h.MaxHalfOpen > 0 {
+		h.mu.Lock()
+		if h.halfOpen[ip] >= h.MaxHalfOpen {
+			h.mu.Unlock()
+			return func() {}, false
+		}
+		h.halfOpen[ip]++
+		h.mu.Unlock()
+	}
+	if h.Timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(h.Timeout))
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if h.Timeout > 0 {
+				conn.SetReadDeadline(time.Time{})
+			}
+			if h.MaxHalfOpen > 0 {
+				h.mu.Lock()
+				if h.halfOpen[ip]--; h.halfOpen[ip] <= 0 {
+					delete(h.halfOpen, ip)
+				}
+				h.mu.Unlock()
+			}
+		})
+	}, true
+}
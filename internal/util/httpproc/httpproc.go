@@ -0,0 +1,311 @@
+// Package httpproc posts the sniffed HTTP traffic of the forward handlers to an external HTTP processor.
+//
+// The processor receives the headers and the body of each request and response as a JSON document,
+// and may mutate them or reject the request. The body is buffered up to the maximum body size,
+// it is not streamed in chunks. This is not the gRPC ext_proc protocol of Envoy,
+// and the processor is only applied to the forward handlers (the httpProcessor metadata).
+package httpproc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTimeout     = 5 * time.Second
+	defaultMaxBodySize = 1024 * 1024
+)
+
+const (
+	PhaseRequest  = "request"
+	PhaseResponse = "response"
+)
+
+const (
+	ActionContinue = "continue"
+	ActionReject   = "reject"
+)
+
+var (
+	ErrRejected = errors.New("httpproc: rejected")
+)
+
+type options struct {
+	timeout     time.Duration
+	maxBodySize int64
+	failOpen    bool
+}
+
+type Option func(opts *options)
+
+func TimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+// MaxBodySizeOption sets the maximum size of the body sent to the processor,
+// the larger bodies are passed through without processing.
+func MaxBodySizeOption(size int64) Option {
+	return func(opts *options) {
+		opts.maxBodySize = size
+	}
+}
+
+// FailOpenOption lets the traffic pass unmodified when the processor is unavailable,
+// otherwise the request is rejected.
+func FailOpenOption(failOpen bool) Option {
+	return func(opts *options) {
+		opts.failOpen = failOpen
+	}
+}
+
+type processRequest struct {
+	Phase   string      `json:"phase"`
+	Client  string      `json:"client,omitempty"`
+	Method  string      `json:"method,omitempty"`
+	URI     string      `json:"uri,omitempty"`
+	Host    string      `json:"host,omitempty"`
+	Status  int         `json:"status,omitempty"`
+	Header  http.Header `json:"header,omitempty"`
+	Body    []byte      `json:"body,omitempty"`
+	Partial bool        `json:"partial,omitempty"`
+}
+
+type processResponse struct {
+	Action string `json:"action"`
+	// the status code of the reject response.
+	Status       int               `json:"status,omitempty"`
+	SetHeader    map[string]string `json:"setHeader,omitempty"`
+	RemoveHeader []string          `json:"removeHeader,omitempty"`
+	// the replacement of the body, nil keeps the original body.
+	Body []byte `json:"body,omitempty"`
+}
+
+// Processor is an external processor of HTTP traffic.
+// The headers and body of each request and response are posted to the processor as JSON,
+// which may mutate them or reject the request. The bodies are base64 encoded in JSON.
+type Processor struct {
+	url     string
+	client  *http.Client
+	options options
+}
+
+func NewProcessor(url string, opts ...Option) *Processor {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.timeout <= 0 {
+		options.timeout = defaultTimeout
+	}
+	if options.maxBodySize <= 0 {
+		options.maxBodySize = defaultMaxBodySize
+	}
+
+	return &Processor{
+		url: url,
+		client: &http.Client{
+			Timeout: options.timeout,
+		},
+		options: options,
+	}
+}
+
+// ProcessRequest processes the request, a non-nil response is returned if the request is rejected.
+func (p *Processor) ProcessRequest(ctx context.Context, req *http.Request, client string) (*http.Response, error) {
+	pr := &processRequest{
+		Phase:  PhaseRequest,
+		Client: client,
+		Method: req.Method,
+		URI:    req.RequestURI,
+		Host:   req.Host,
+		Header: req.Header,
+	}
+
+	body, partial, err := p.readBody(req.Body)
+	if err != nil {
+		return p.reject(req, http.StatusBadRequest), err
+	}
+	pr.Body, pr.Partial = body, partial
+
+	r, err := p.process(ctx, pr)
+	if err != nil {
+		req.Body = p.restoreBody(req.Body, body, partial, nil)
+		if p.options.failOpen {
+			return nil, nil
+		}
+		return p.reject(req, http.StatusBadGateway), err
+	}
+	if r.Action == ActionReject {
+		status := r.Status
+		if status == 0 {
+			status = http.StatusForbidden
+		}
+		return p.reject(req, status), ErrRejected
+	}
+
+	applyHeader(req.Header, r)
+	req.Body = p.restoreBody(req.Body, body, partial, r.Body)
+	if n, ok := bodyLength(body, partial, r.Body); ok {
+		req.ContentLength = n
+		req.TransferEncoding = nil
+		req.Header.Set("Content-Length", strconv.FormatInt(n, 10))
+	}
+	return nil, nil
+}
+
+// ProcessResponse processes the response of the request, the response is replaced if it is rejected.
+func (p *Processor) ProcessResponse(ctx context.Context, res *http.Response, client string) (*http.Response, error) {
+	pr := &processRequest{
+		Phase:  PhaseResponse,
+		Client: client,
+		Status: res.StatusCode,
+		Header: res.Header,
+	}
+	if res.Request != nil {
+		pr.Method = res.Request.Method
+		pr.URI = res.Request.RequestURI
+		pr.Host = res.Request.Host
+	}
+
+	body, partial, err := p.readBody(res.Body)
+	if err != nil {
+		return p.reject(res.Request, http.StatusBadGateway), err
+	}
+	pr.Body, pr.Partial = body, partial
+
+	r, err := p.process(ctx, pr)
+	if err != nil {
+		res.Body = p.restoreBody(res.Body, body, partial, nil)
+		if p.options.failOpen {
+			return res, nil
+		}
+		return p.reject(res.Request, http.StatusBadGateway), err
+	}
+	if r.Action == ActionReject {
+		status := r.Status
+		if status == 0 {
+			status = http.StatusForbidden
+		}
+		return p.reject(res.Request, status), ErrRejected
+	}
+
+	applyHeader(res.Header, r)
+	res.Body = p.restoreBody(res.Body, body, partial, r.Body)
+	if n, ok := bodyLength(body, partial, r.Body); ok {
+		res.ContentLength = n
+		res.TransferEncoding = nil
+		res.Header.Set("Content-Length", strconv.FormatInt(n, 10))
+	}
+	return res, nil
+}
+
+func (p *Processor) process(ctx context.Context, pr *processRequest) (*processResponse, error) {
+	v, err := json.Marshal(pr)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(v))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("httpproc: %s", resp.Status)
+	}
+
+	r := &processResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// readBody reads at most maxBodySize bytes of the body,
+// partial reports whether the body is larger than that.
+func (p *Processor) readBody(r io.ReadCloser) (body []byte, partial bool, err error) {
+	if r == nil || r == http.NoBody {
+		return
+	}
+	body, err = io.ReadAll(io.LimitReader(r, p.options.maxBodySize+1))
+	if err != nil {
+		return
+	}
+	if int64(len(body)) > p.options.maxBodySize {
+		partial = true
+	}
+	return
+}
+
+func (p *Processor) restoreBody(r io.ReadCloser, body []byte, partial bool, replacement []byte) io.ReadCloser {
+	if r == nil || r == http.NoBody {
+		if replacement != nil {
+			return io.NopCloser(bytes.NewReader(replacement))
+		}
+		return r
+	}
+	if replacement != nil {
+		r.Close()
+		return io.NopCloser(bytes.NewReader(replacement))
+	}
+	if partial {
+		return &readCloser{
+			Reader: io.MultiReader(bytes.NewReader(body), r),
+			Closer: r,
+		}
+	}
+	r.Close()
+	return io.NopCloser(bytes.NewReader(body))
+}
+
+func (p *Processor) reject(req *http.Request, status int) *http.Response {
+	return &http.Response{
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		StatusCode: status,
+		Request:    req,
+	}
+}
+
+// bodyLength returns the length of the body to send if it is known after the processing.
+func bodyLength(body []byte, partial bool, replacement []byte) (int64, bool) {
+	if replacement != nil {
+		return int64(len(replacement)), true
+	}
+	if body != nil && !partial {
+		return int64(len(body)), true
+	}
+	return 0, false
+}
+
+func applyHeader(h http.Header, r *processResponse) {
+	for _, k := range r.RemoveHeader {
+		h.Del(k)
+	}
+	for k, v := range r.SetHeader {
+		h.Set(k, v)
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,469 @@
+package icap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPort        = "1344"
+	defaultTimeout     = 10 * time.Second
+	defaultMaxBodySize = 1024 * 1024
+	// the idle connections to the ICAP server kept for the next messages.
+	maxIdleConns = 4
+)
+
+const (
+	ModeReqMod  = "reqmod"
+	ModeRespMod = "respmod"
+)
+
+var (
+	ErrBadResponse  = errors.New("icap: bad response")
+	ErrBodyTooLarge = errors.New("icap: body too large to scan")
+)
+
+type options struct {
+	modes       []string
+	timeout     time.Duration
+	maxBodySize int64
+	bypass      bool
+}
+
+type Option func(opts *options)
+
+// ModesOption sets the modes (reqmod, respmod) of the client, both are enabled by default.
+func ModesOption(modes ...string) Option {
+	return func(opts *options) {
+		opts.modes = modes
+	}
+}
+
+func TimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+// MaxBodySizeOption sets the maximum size of the body to scan,
+// the messages with larger bodies are not sent to the ICAP server,
+// they are passed unscanned if the bypass is enabled, otherwise rejected.
+func MaxBodySizeOption(size int64) Option {
+	return func(opts *options) {
+		opts.maxBodySize = size
+	}
+}
+
+// BypassOption lets the traffic pass unmodified when the ICAP server fails or the body is too large to scan,
+// otherwise the request is rejected.
+func BypassOption(bypass bool) Option {
+	return func(opts *options) {
+		opts.bypass = bypass
+	}
+}
+
+// Client is an ICAP (RFC 3507) client sending the HTTP messages
+// to an ICAP server (e.g. antivirus or DLP) for adaptation.
+type Client struct {
+	url     *url.URL
+	addr    string
+	reqMod  bool
+	respMod bool
+	idle    []*icapConn
+	mu      sync.Mutex
+	options options
+}
+
+// icapConn is a persistent connection to the ICAP server.
+type icapConn struct {
+	net.Conn
+	br *bufio.Reader
+}
+
+// NewClient creates an ICAP client of the service URL, e.g. icap://127.0.0.1:1344/avscan.
+func NewClient(rawURL string, opts ...Option) (*Client, error) {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.timeout <= 0 {
+		options.timeout = defaultTimeout
+	}
+	if options.maxBodySize <= 0 {
+		options.maxBodySize = defaultMaxBodySize
+	}
+
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "icap://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+
+	c := &Client{
+		url:     u,
+		addr:    addr,
+		options: options,
+	}
+	if len(options.modes) == 0 {
+		c.reqMod, c.respMod = true, true
+	}
+	for _, mode := range options.modes {
+		switch strings.ToLower(strings.TrimSpace(mode)) {
+		case ModeReqMod:
+			c.reqMod = true
+		case ModeRespMod:
+			c.respMod = true
+		}
+	}
+	return c, nil
+}
+
+// ReqMod sends the request to the ICAP server in REQMOD mode.
+// The request is adapted in place, a non-nil response is returned if the ICAP server
+// responds to the request (e.g. blocks it) and the response should be sent to the client.
+func (c *Client) ReqMod(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c == nil || !c.reqMod {
+		return nil, nil
+	}
+
+	body, ok, err := c.readBody(req.Body)
+	if err != nil {
+		return reject(req, http.StatusBadRequest), err
+	}
+	req.Body = body
+	if !ok {
+		if c.options.bypass {
+			return nil, nil
+		}
+		return reject(req, http.StatusRequestEntityTooLarge), ErrBodyTooLarge
+	}
+
+	hdr, err := httputil.DumpRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := io.ReadAll(body)
+	req.Body = io.NopCloser(bytes.NewReader(data))
+
+	msg, err := c.do(ctx, "REQMOD", hdr, nil, data, req)
+	if err != nil {
+		if c.options.bypass {
+			return nil, nil
+		}
+		return reject(req, http.StatusBadGateway), err
+	}
+	if msg == nil {
+		// 204, unmodified
+		return nil, nil
+	}
+
+	if r := msg.res; r != nil {
+		setBody(r.Header, msg.body, func(rc io.ReadCloser, n int64) {
+			r.Body, r.ContentLength, r.TransferEncoding = rc, n, nil
+		})
+		return r, nil
+	}
+	if msg.req != nil {
+		req.Method = msg.req.Method
+		req.URL = msg.req.URL
+		req.RequestURI = msg.req.RequestURI
+		req.Header = msg.req.Header
+		if msg.req.Host != "" {
+			req.Host = msg.req.Host
+		}
+		setBody(req.Header, msg.body, func(rc io.ReadCloser, n int64) {
+			req.Body, req.ContentLength, req.TransferEncoding = rc, n, nil
+		})
+	}
+	return nil, nil
+}
+
+// RespMod sends the response to the ICAP server in RESPMOD mode, the adapted response is returned.
+func (c *Client) RespMod(ctx context.Context, res *http.Response) (*http.Response, error) {
+	if c == nil || !c.respMod {
+		return res, nil
+	}
+
+	body, ok, err := c.readBody(res.Body)
+	if err != nil {
+		return reject(res.Request, http.StatusBadGateway), err
+	}
+	res.Body = body
+	if !ok {
+		if c.options.bypass {
+			return res, nil
+		}
+		res.Body.Close()
+		return reject(res.Request, http.StatusBadGateway), ErrBodyTooLarge
+	}
+
+	var reqHdr []byte
+	if res.Request != nil {
+		reqHdr, _ = httputil.DumpRequest(res.Request, false)
+	}
+	resHdr, err := httputil.DumpResponse(res, false)
+	if err != nil {
+		return res, err
+	}
+	data, _ := io.ReadAll(body)
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	msg, err := c.do(ctx, "RESPMOD", reqHdr, resHdr, data, res.Request)
+	if err != nil {
+		if c.options.bypass {
+			return res, nil
+		}
+		return reject(res.Request, http.StatusBadGateway), err
+	}
+	if msg == nil || msg.res == nil {
+		return res, nil
+	}
+
+	r := msg.res
+	setBody(r.Header, msg.body, func(rc io.ReadCloser, n int64) {
+		r.Body, r.ContentLength, r.TransferEncoding = rc, n, nil
+	})
+	return r, nil
+}
+
+type message struct {
+	req  *http.Request
+	res  *http.Response
+	body []byte
+}
+
+// do sends an ICAP request with the encapsulated HTTP headers and body,
+// nil message is returned if the ICAP server responds 204 (no modifications).
+func (c *Client) do(ctx context.Context, method string, reqHdr, resHdr []byte, body []byte, req *http.Request) (*message, error) {
+	data := c.encode(method, reqHdr, resHdr, body)
+
+	for retry := false; ; retry = true {
+		conn, reused, err := c.getConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		msg, keepAlive, err := c.roundTrip(conn, method, data, req)
+		if err != nil {
+			conn.Close()
+			// the idle connection may have been closed by the server, the message is sent again on a new connection.
+			if reused && !retry {
+				continue
+			}
+			return nil, err
+		}
+		if keepAlive {
+			c.putConn(conn)
+		} else {
+			conn.Close()
+		}
+		return msg, nil
+	}
+}
+
+func (c *Client) encode(method string, reqHdr, resHdr []byte, body []byte) []byte {
+	var encap []string
+	offset := 0
+	if reqHdr != nil {
+		encap = append(encap, "req-hdr=0")
+		offset += len(reqHdr)
+	}
+	if resHdr != nil {
+		encap = append(encap, fmt.Sprintf("res-hdr=%d", offset))
+		offset += len(resHdr)
+	}
+	bodyKey := "req-body"
+	if method == "RESPMOD" {
+		bodyKey = "res-body"
+	}
+	if len(body) > 0 {
+		encap = append(encap, fmt.Sprintf("%s=%d", bodyKey, offset))
+	} else {
+		encap = append(encap, fmt.Sprintf("null-body=%d", offset))
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s ICAP/1.0\r\n", method, c.url.String())
+	fmt.Fprintf(&b, "Host: %s\r\n", c.url.Host)
+	fmt.Fprintf(&b, "Allow: 204\r\n")
+	fmt.Fprintf(&b, "Encapsulated: %s\r\n\r\n", strings.Join(encap, ", "))
+	b.Write(reqHdr)
+	b.Write(resHdr)
+	if len(body) > 0 {
+		fmt.Fprintf(&b, "%x\r\n", len(body))
+		b.Write(body)
+		b.WriteString("\r\n0\r\n\r\n")
+	}
+	return b.Bytes()
+}
+
+// roundTrip sends the encoded ICAP request on the connection and reads the response,
+// keepAlive reports whether the connection can be reused.
+func (c *Client) roundTrip(conn *icapConn, method string, data []byte, req *http.Request) (msg *message, keepAlive bool, err error) {
+	conn.SetDeadline(time.Now().Add(c.options.timeout))
+	if _, err = conn.Write(data); err != nil {
+		return
+	}
+
+	br := conn.br
+	tp := textproto.NewReader(br)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return
+	}
+	status, err := parseStatus(line)
+	if err != nil {
+		return
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return
+	}
+	keepAlive = !strings.EqualFold(header.Get("Connection"), "close")
+
+	switch status {
+	case http.StatusNoContent:
+		return nil, keepAlive, nil
+	case http.StatusOK:
+	default:
+		return nil, false, fmt.Errorf("icap: %s", line)
+	}
+
+	msg = &message{}
+	for _, v := range strings.Split(header.Get("Encapsulated"), ",") {
+		k, _, _ := strings.Cut(strings.TrimSpace(v), "=")
+		switch k {
+		case "req-hdr":
+			if msg.req, err = http.ReadRequest(br); err != nil {
+				return nil, false, err
+			}
+		case "res-hdr":
+			if msg.res, err = http.ReadResponse(br, req); err != nil {
+				return nil, false, err
+			}
+		case "req-body", "res-body":
+			if msg.body, err = io.ReadAll(httputil.NewChunkedReader(br)); err != nil {
+				return nil, false, err
+			}
+			// the trailer of the chunked body.
+			if _, err = tp.ReadLine(); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	if msg.req == nil && msg.res == nil {
+		return nil, false, ErrBadResponse
+	}
+	return msg, keepAlive, nil
+}
+
+// getConn returns an idle connection to the ICAP server, or dials a new one.
+func (c *Client) getConn(ctx context.Context) (conn *icapConn, reused bool, err error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn = c.idle[n-1]
+		c.idle = c.idle[:n-1]
+	}
+	c.mu.Unlock()
+	if conn != nil {
+		return conn, true, nil
+	}
+
+	d := net.Dialer{Timeout: c.options.timeout}
+	cc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, false, err
+	}
+	return &icapConn{Conn: cc, br: bufio.NewReader(cc)}, false, nil
+}
+
+func (c *Client) putConn(conn *icapConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle) >= maxIdleConns {
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	c.idle = append(c.idle, conn)
+}
+
+// readBody buffers the body, ok reports whether the body is small enough to be scanned.
+func (c *Client) readBody(r io.ReadCloser) (rc io.ReadCloser, ok bool, err error) {
+	if r == nil || r == http.NoBody {
+		return r, true, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r, c.options.maxBodySize+1))
+	if err != nil {
+		return r, false, err
+	}
+	if int64(len(data)) > c.options.maxBodySize {
+		return &readCloser{
+			Reader: io.MultiReader(bytes.NewReader(data), r),
+			Closer: r,
+		}, false, nil
+	}
+	r.Close()
+	return io.NopCloser(bytes.NewReader(data)), true, nil
+}
+
+func parseStatus(line string) (int, error) {
+	// ICAP/1.0 200 OK
+	proto, status, _ := strings.Cut(line, " ")
+	if !strings.HasPrefix(proto, "ICAP/") {
+		return 0, ErrBadResponse
+	}
+	code, _, _ := strings.Cut(status, " ")
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return 0, ErrBadResponse
+	}
+	return n, nil
+}
+
+func setBody(h http.Header, body []byte, set func(rc io.ReadCloser, n int64)) {
+	if body == nil {
+		set(http.NoBody, 0)
+		h.Del("Content-Length")
+		return
+	}
+	set(io.NopCloser(bytes.NewReader(body)), int64(len(body)))
+	h.Del("Transfer-Encoding")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+}
+
+func reject(req *http.Request, status int) *http.Response {
+	return &http.Response{
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		StatusCode: status,
+		Request:    req,
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
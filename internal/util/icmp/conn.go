@@ -8,8 +8,8 @@ import (
 	"net"
 	"sync/atomic"
 
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/internal/bufpool"
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
 )
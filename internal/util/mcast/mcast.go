@@ -0,0 +1,370 @@
+// Package mcast forwards the multicast and broadcast packets between the peers of a tunnel.
+package mcast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// the group membership interval of IGMPv2 (RFC 2236) and MLD (RFC 2710).
+	membershipInterval = 260 * time.Second
+)
+
+const (
+	MDKeyMulticast         = "multicast"
+	MDKeyMulticastGroups   = "multicast.groups"
+	MDKeyMulticastSnooping = "multicast.snooping"
+	MDKeyMulticastRate     = "multicast.rate"
+	MDKeyMulticastBurst    = "multicast.burst"
+)
+
+var (
+	// the link-local scope groups are always flooded.
+	linkLocal4 = netip.MustParsePrefix("224.0.0.0/24")
+	linkLocal6 = netip.MustParsePrefix("ff02::/16")
+
+	broadcastMAC = []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+)
+
+type options struct {
+	groups   []netip.Prefix
+	snooping bool
+	rate     float64
+	burst    int
+}
+
+type Option func(opts *options)
+
+// GroupsOption restricts the forwarded multicast groups, all groups are forwarded if not set.
+func GroupsOption(groups []netip.Prefix) Option {
+	return func(opts *options) {
+		opts.groups = groups
+	}
+}
+
+// SnoopingOption enables the IGMP/MLD snooping, so a group is forwarded only to the peers joined it.
+func SnoopingOption(snooping bool) Option {
+	return func(opts *options) {
+		opts.snooping = snooping
+	}
+}
+
+// RateOption limits the multicast and broadcast packets per second from each source.
+func RateOption(r float64, burst int) Option {
+	return func(opts *options) {
+		opts.rate = r
+		opts.burst = burst
+	}
+}
+
+// Forwarder decides which multicast and broadcast packets are forwarded to which peers.
+type Forwarder struct {
+	options  options
+	members  map[netip.Addr]map[string]time.Time
+	limiters map[string]*rate.Limiter
+	mu       sync.Mutex
+}
+
+func NewForwarder(opts ...Option) *Forwarder {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.rate > 0 && options.burst <= 0 {
+		options.burst = int(options.rate) + 1
+	}
+
+	return &Forwarder{
+		options:  options,
+		members:  make(map[netip.Addr]map[string]time.Time),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// ParseForwarder parses the forwarder from metadata, nil is returned if the forwarding is not enabled.
+func ParseForwarder(md mdata.Metadata) *Forwarder {
+	if !mdutil.GetBool(md, MDKeyMulticast) {
+		return nil
+	}
+
+	ss := mdutil.GetStrings(md, MDKeyMulticastGroups)
+	if len(ss) == 0 {
+		ss = strings.Split(mdutil.GetString(md, MDKeyMulticastGroups), ",")
+	}
+	var groups []netip.Prefix
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(s); err == nil {
+			groups = append(groups, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(s); err == nil {
+			groups = append(groups, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+
+	return NewForwarder(
+		GroupsOption(groups),
+		SnoopingOption(mdutil.GetBool(md, MDKeyMulticastSnooping)),
+		RateOption(mdutil.GetFloat(md, MDKeyMulticastRate), mdutil.GetInt(md, MDKeyMulticastBurst)),
+	)
+}
+
+// Destination returns the destination of the IP packet b if it is a multicast or broadcast packet,
+// the broadcast addresses are the limited broadcast address and the directed broadcast addresses of nets.
+func Destination(b []byte, nets []net.IPNet) (dst netip.Addr, ok bool) {
+	if len(b) < 1 {
+		return
+	}
+	switch b[0] >> 4 {
+	case 4:
+		if len(b) < 20 {
+			return
+		}
+		dst = netip.AddrFrom4([4]byte(b[16:20]))
+		if dst.IsMulticast() || dst == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+			return dst, true
+		}
+		for _, n := range nets {
+			ip4, mask := n.IP.To4(), n.Mask
+			if ip4 == nil || len(mask) != net.IPv4len {
+				continue
+			}
+			var bcast [4]byte
+			for i := range bcast {
+				bcast[i] = ip4[i] | ^mask[i]
+			}
+			if dst == netip.AddrFrom4(bcast) {
+				return dst, true
+			}
+		}
+	case 6:
+		if len(b) < 40 {
+			return
+		}
+		dst = netip.AddrFrom16([16]byte(b[24:40]))
+		if dst.IsMulticast() {
+			return dst, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// FrameDestination reports whether the Ethernet frame b is a multicast frame,
+// the destination group is returned if it carries an IP multicast packet.
+func FrameDestination(b []byte) (dst netip.Addr, ok bool) {
+	// the broadcast frames are always flooded.
+	if len(b) < 14 || b[0]&0x01 == 0 || bytes.Equal(b[:6], broadcastMAC) {
+		return
+	}
+	if et := binary.BigEndian.Uint16(b[12:14]); et == 0x0800 || et == 0x86dd {
+		dst, _ = Destination(b[14:], nil)
+	}
+	return dst, true
+}
+
+// ObserveFrame is the same as Observe but for the Ethernet frame b.
+func (f *Forwarder) ObserveFrame(b []byte, peer string) {
+	if len(b) < 14 {
+		return
+	}
+	if et := binary.BigEndian.Uint16(b[12:14]); et == 0x0800 || et == 0x86dd {
+		f.Observe(b[14:], peer)
+	}
+}
+
+// Allow reports whether the multicast or broadcast packet to dst from the source src can be forwarded.
+func (f *Forwarder) Allow(dst netip.Addr, src string) bool {
+	if f == nil {
+		return false
+	}
+
+	if dst.IsMulticast() && len(f.options.groups) > 0 {
+		found := false
+		for _, g := range f.options.groups {
+			if g.Contains(dst) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.options.rate <= 0 {
+		return true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lim := f.limiters[src]
+	if lim == nil {
+		lim = rate.NewLimiter(rate.Limit(f.options.rate), f.options.burst)
+		f.limiters[src] = lim
+	}
+	return lim.Allow()
+}
+
+// Member reports whether the peer should receive the packet to dst.
+func (f *Forwarder) Member(dst netip.Addr, peer string) bool {
+	if f == nil {
+		return false
+	}
+	if !f.options.snooping || !dst.IsMulticast() ||
+		linkLocal4.Contains(dst) || linkLocal6.Contains(dst) {
+		return true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	expire, ok := f.members[dst][peer]
+	if ok && time.Now().After(expire) {
+		delete(f.members[dst], peer)
+		return false
+	}
+	return ok
+}
+
+// Observe learns the group memberships of the peer from the IGMP or MLD report in the IP packet b.
+func (f *Forwarder) Observe(b []byte, peer string) {
+	if f == nil || !f.options.snooping || len(b) < 1 {
+		return
+	}
+
+	var joins, leaves []netip.Addr
+	switch b[0] >> 4 {
+	case 4:
+		joins, leaves = parseIGMP(b)
+	case 6:
+		joins, leaves = parseMLD(b)
+	}
+	if len(joins) == 0 && len(leaves) == 0 {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	expire := time.Now().Add(membershipInterval)
+	for _, group := range joins {
+		m := f.members[group]
+		if m == nil {
+			m = make(map[string]time.Time)
+			f.members[group] = m
+		}
+		m[peer] = expire
+	}
+	for _, group := range leaves {
+		delete(f.members[group], peer)
+		if len(f.members[group]) == 0 {
+			delete(f.members, group)
+		}
+	}
+}
+
+func parseIGMP(b []byte) (joins, leaves []netip.Addr) {
+	if len(b) < 20 || b[9] != 2 {
+		return
+	}
+	ihl := int(b[0]&0x0f) * 4
+	if ihl < 20 || len(b) < ihl+8 {
+		return
+	}
+	igmp := b[ihl:]
+
+	switch igmp[0] {
+	case 0x12, 0x16: // v1, v2 membership report
+		joins = append(joins, netip.AddrFrom4([4]byte(igmp[4:8])))
+	case 0x17: // v2 leave group
+		leaves = append(leaves, netip.AddrFrom4([4]byte(igmp[4:8])))
+	case 0x22: // v3 membership report
+		n := int(binary.BigEndian.Uint16(igmp[6:8]))
+		recs := igmp[8:]
+		for i := 0; i < n && len(recs) >= 8; i++ {
+			nsrc := int(binary.BigEndian.Uint16(recs[2:4]))
+			l := 8 + nsrc*4 + int(recs[1])*4
+			if len(recs) < l {
+				break
+			}
+			group := netip.AddrFrom4([4]byte(recs[4:8]))
+			if joined(recs[0], nsrc) {
+				joins = append(joins, group)
+			} else {
+				leaves = append(leaves, group)
+			}
+			recs = recs[l:]
+		}
+	}
+	return
+}
+
+func parseMLD(b []byte) (joins, leaves []netip.Addr) {
+	if len(b) < 40 {
+		return
+	}
+	nh, p := b[6], b[40:]
+	// the MLD messages are sent with the router alert option in the hop-by-hop options header.
+	if nh == 0 {
+		if len(p) < 8 || len(p) < (int(p[1])+1)*8 {
+			return
+		}
+		nh, p = p[0], p[(int(p[1])+1)*8:]
+	}
+	if nh != 58 || len(p) < 8 {
+		return
+	}
+
+	switch p[0] {
+	case 131: // v1 report
+		if len(p) >= 24 {
+			joins = append(joins, netip.AddrFrom16([16]byte(p[8:24])))
+		}
+	case 132: // v1 done
+		if len(p) >= 24 {
+			leaves = append(leaves, netip.AddrFrom16([16]byte(p[8:24])))
+		}
+	case 143: // v2 report
+		n := int(binary.BigEndian.Uint16(p[6:8]))
+		recs := p[8:]
+		for i := 0; i < n && len(recs) >= 20; i++ {
+			nsrc := int(binary.BigEndian.Uint16(recs[2:4]))
+			l := 20 + nsrc*16 + int(recs[1])*4
+			if len(recs) < l {
+				break
+			}
+			group := netip.AddrFrom16([16]byte(recs[4:20]))
+			if joined(recs[0], nsrc) {
+				joins = append(joins, group)
+			} else {
+				leaves = append(leaves, group)
+			}
+			recs = recs[l:]
+		}
+	}
+	return
+}
+
+// joined reports whether the multicast address record of IGMPv3/MLDv2 means the membership.
+func joined(typ byte, nsrc int) bool {
+	switch typ {
+	case 2, 4: // MODE_IS_EXCLUDE, CHANGE_TO_EXCLUDE_MODE
+		return true
+	case 1, 3, 5: // MODE_IS_INCLUDE, CHANGE_TO_INCLUDE_MODE, ALLOW_NEW_SOURCES
+		return nsrc > 0
+	}
+	// BLOCK_OLD_SOURCES does not change the membership of the group.
+	return true
+}
@@ -0,0 +1,115 @@
+// Package mss clamps the TCP maximum segment size of the packets passing through a tunnel,
+// so the TCP segments fit in the tunnel MTU without relying on ICMP (path MTU discovery),
+// which is often blocked and causes the connections to hang silently.
+package mss
+
+import (
+	"encoding/binary"
+)
+
+const (
+	ipv4HeaderOverhead = 40 // IPv4 header(20) + TCP header(20)
+	ipv6HeaderOverhead = 60 // IPv6 header(40) + TCP header(20)
+
+	tcpFlagSYN   = 0x02
+	tcpOptEnd    = 0
+	tcpOptNOP    = 1
+	tcpOptMSS    = 2
+	tcpOptMSSLen = 4
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+)
+
+// FromMTU returns the MSS of IPv4 and IPv6 TCP segments fitting in the MTU.
+func FromMTU(mtu int) (v4, v6 int) {
+	if mtu <= ipv6HeaderOverhead {
+		return 0, 0
+	}
+	return mtu - ipv4HeaderOverhead, mtu - ipv6HeaderOverhead
+}
+
+// Clamp lowers the MSS option of the TCP SYN packet b (an IPv4 or IPv6 packet) to
+// mss4 or mss6 by the IP version, it reports whether the packet is modified.
+func Clamp(b []byte, mss4, mss6 int) bool {
+	if len(b) < 1 {
+		return false
+	}
+
+	var tcp []byte
+	var mss int
+	switch b[0] >> 4 {
+	case 4:
+		if len(b) < 20 {
+			return false
+		}
+		ihl := int(b[0]&0x0f) * 4
+		// TCP and not a fragment
+		if b[9] != 6 || ihl < 20 || len(b) < ihl || binary.BigEndian.Uint16(b[6:8])&0x1fff != 0 {
+			return false
+		}
+		tcp, mss = b[ihl:], mss4
+	case 6:
+		// the extension headers are not supported.
+		if len(b) < 40 || b[6] != 6 {
+			return false
+		}
+		tcp, mss = b[40:], mss6
+	default:
+		return false
+	}
+
+	if mss <= 0 || len(tcp) < 20 || tcp[13]&tcpFlagSYN == 0 {
+		return false
+	}
+	off := int(tcp[12]>>4) * 4
+	if off <= 20 || off > len(tcp) {
+		return false
+	}
+
+	opts := tcp[20:off]
+	for len(opts) > 0 {
+		switch opts[0] {
+		case tcpOptEnd:
+			return false
+		case tcpOptNOP:
+			opts = opts[1:]
+			continue
+		}
+		if len(opts) < 2 || opts[1] < 2 || int(opts[1]) > len(opts) {
+			return false
+		}
+		if opts[0] == tcpOptMSS && opts[1] == tcpOptMSSLen {
+			old := binary.BigEndian.Uint16(opts[2:4])
+			if int(old) <= mss {
+				return false
+			}
+			binary.BigEndian.PutUint16(opts[2:4], uint16(mss))
+			sum := binary.BigEndian.Uint16(tcp[16:18])
+			binary.BigEndian.PutUint16(tcp[16:18], updateChecksum(sum, old, uint16(mss)))
+			return true
+		}
+		opts = opts[opts[1]:]
+	}
+	return false
+}
+
+// ClampFrame is the same as Clamp but for the Ethernet frame b.
+func ClampFrame(b []byte, mss4, mss6 int) bool {
+	if len(b) < 14 {
+		return false
+	}
+	switch binary.BigEndian.Uint16(b[12:14]) {
+	case etherTypeIPv4, etherTypeIPv6:
+		return Clamp(b[14:], mss4, mss6)
+	}
+	return false
+}
+
+// updateChecksum updates the internet checksum incrementally, see RFC 1624.
+func updateChecksum(sum, old, new uint16) uint16 {
+	v := uint32(^sum) + uint32(^old) + uint32(new)
+	v = (v >> 16) + (v & 0xffff)
+	v += v >> 16
+	return ^uint16(v)
+}
@@ -0,0 +1,155 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/go-gost/core/dialer"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metadata"
+)
+
+type dialerSession struct {
+	conn    net.Conn
+	session *Session
+}
+
+func (s *dialerSession) isAvailable(maxStreams int) bool {
+	if s.session == nil {
+		// the handshake is in progress.
+		return true
+	}
+	if s.session.IsClosed() {
+		return false
+	}
+	return maxStreams <= 0 || s.session.NumStreams() < maxStreams
+}
+
+// muxDialer multiplexes the streams over the connections of the wrapped dialer.
+type muxDialer struct {
+	dialer   dialer.Dialer
+	cfg      *Config
+	sessions map[string][]*dialerSession
+	mu       sync.Mutex
+	logger   logger.Logger
+}
+
+// WrapDialer wraps the dialer d with the multiplexing of the config cfg, d is returned if cfg is nil.
+func WrapDialer(d dialer.Dialer, cfg *Config, logger logger.Logger) dialer.Dialer {
+	if d == nil || cfg == nil {
+		return d
+	}
+	return &muxDialer{
+		dialer:   d,
+		cfg:      cfg,
+		sessions: make(map[string][]*dialerSession),
+		logger:   logger,
+	}
+}
+
+func (d *muxDialer) Init(md metadata.Metadata) error {
+	return d.dialer.Init(md)
+}
+
+// Multiplex implements dialer.Multiplexer interface.
+func (d *muxDialer) Multiplex() bool {
+	return true
+}
+
+func (d *muxDialer) Dial(ctx context.Context, addr string, opts ...dialer.DialOption) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sessions := d.sessions[addr][:0]
+	var session *dialerSession
+	for _, s := range d.sessions[addr] {
+		if s.session != nil && s.session.IsClosed() {
+			continue // session is dead
+		}
+		sessions = append(sessions, s)
+		if session == nil && s.isAvailable(d.cfg.MaxStreams) {
+			session = s
+		}
+	}
+	d.sessions[addr] = sessions
+
+	if session == nil {
+		conn, err := d.dialer.Dial(ctx, addr, opts...)
+		if err != nil {
+			return nil, err
+		}
+		session = &dialerSession{conn: conn}
+		d.sessions[addr] = append(d.sessions[addr], session)
+	}
+
+	return session.conn, nil
+}
+
+// Handshake implements dialer.Handshaker
+func (d *muxDialer) Handshake(ctx context.Context, conn net.Conn, options ...dialer.HandshakeOption) (net.Conn, error) {
+	opts := &dialer.HandshakeOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var session *dialerSession
+	for _, s := range d.sessions[opts.Addr] {
+		if s.conn == conn {
+			session = s
+			break
+		}
+	}
+	if session == nil {
+		conn.Close()
+		return nil, errors.New("mux: unrecognized connection")
+	}
+
+	if session.session == nil {
+		s, err := d.initSession(ctx, conn, options...)
+		if err != nil {
+			d.logger.Error(err)
+			conn.Close()
+			d.removeSession(opts.Addr, session)
+			return nil, err
+		}
+		session.session = s
+	}
+
+	cc, err := session.session.GetConn()
+	if err != nil {
+		session.session.Close()
+		d.removeSession(opts.Addr, session)
+		return nil, err
+	}
+	return cc, nil
+}
+
+func (d *muxDialer) initSession(ctx context.Context, conn net.Conn, options ...dialer.HandshakeOption) (*Session, error) {
+	if hs, ok := d.dialer.(dialer.Handshaker); ok {
+		var err error
+		if conn, err = hs.Handshake(ctx, conn, options...); err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := ClientSession(conn, d.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return withMetrics(s, "dialer"), nil
+}
+
+func (d *muxDialer) removeSession(addr string, session *dialerSession) {
+	sessions := d.sessions[addr][:0]
+	for _, s := range d.sessions[addr] {
+		if s != session {
+			sessions = append(sessions, s)
+		}
+	}
+	d.sessions[addr] = sessions
+}
@@ -0,0 +1,97 @@
+package mux
+
+import (
+	"net"
+	"sync"
+
+	"github.com/go-gost/core/listener"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/metadata"
+)
+
+const (
+	defaultBacklog = 128
+)
+
+// muxListener accepts the streams multiplexed over the connections of the wrapped listener.
+type muxListener struct {
+	listener.Listener
+	cfg       *Config
+	cqueue    chan net.Conn
+	errChan   chan error
+	startOnce sync.Once
+	logger    logger.Logger
+}
+
+// WrapListener wraps the listener ln with the multiplexing of the config cfg, ln is returned if cfg is nil.
+func WrapListener(ln listener.Listener, cfg *Config, logger logger.Logger) listener.Listener {
+	if ln == nil || cfg == nil {
+		return ln
+	}
+	return &muxListener{
+		Listener: ln,
+		cfg:      cfg,
+		cqueue:   make(chan net.Conn, defaultBacklog),
+		errChan:  make(chan error, 1),
+		logger:   logger,
+	}
+}
+
+func (l *muxListener) Init(md metadata.Metadata) error {
+	return l.Listener.Init(md)
+}
+
+func (l *muxListener) Accept() (conn net.Conn, err error) {
+	l.startOnce.Do(func() {
+		go l.listenLoop()
+	})
+
+	var ok bool
+	select {
+	case conn = <-l.cqueue:
+	case err, ok = <-l.errChan:
+		if !ok {
+			err = listener.ErrClosed
+		}
+	}
+	return
+}
+
+func (l *muxListener) listenLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.errChan <- err
+			close(l.errChan)
+			return
+		}
+		go l.mux(conn)
+	}
+}
+
+func (l *muxListener) mux(conn net.Conn) {
+	defer conn.Close()
+
+	session, err := ServerSession(conn, l.cfg)
+	if err != nil {
+		l.logger.Error(err)
+		return
+	}
+	session = withMetrics(session, "listener")
+	defer session.Close()
+
+	for {
+		stream, err := session.Accept()
+		if err != nil {
+			l.logger.Error("accept stream: ", err)
+			return
+		}
+
+		select {
+		case l.cqueue <- stream:
+		default:
+			stream.Close()
+			l.logger.Warnf("connection queue is full, stream %s discarded", conn.RemoteAddr())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package mux
+
+import (
+	"fmt"
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyProtocol          = "mux"
+	MDKeyVersion           = "mux.version"
+	MDKeyKeepAliveInterval = "mux.keepaliveInterval"
+	MDKeyKeepAliveDisabled = "mux.keepaliveDisabled"
+	MDKeyKeepAliveTimeout  = "mux.keepaliveTimeout"
+	MDKeyMaxFrameSize      = "mux.maxFrameSize"
+	MDKeyMaxReceiveBuffer  = "mux.maxReceiveBuffer"
+	MDKeyMaxStreamBuffer   = "mux.maxStreamBuffer"
+	MDKeyMaxStreams        = "mux.maxStreams"
+)
+
+// ParseConfig parses the config of the generic multiplexing of the dialer or listener from metadata,
+// nil is returned if the mux protocol is not set. Only smux is supported, the other protocols (e.g. yamux) are rejected.
+func ParseConfig(md mdata.Metadata) (*Config, error) {
+	if md == nil {
+		return nil, nil
+	}
+
+	switch protocol := mdutil.GetString(md, MDKeyProtocol); protocol {
+	case "":
+		return nil, nil
+	case ProtocolSMux:
+	default:
+		return nil, fmt.Errorf("mux: unsupported protocol %s, only %s is supported", protocol, ProtocolSMux)
+	}
+
+	return &Config{
+		Version:           mdutil.GetInt(md, MDKeyVersion),
+		KeepAliveInterval: mdutil.GetDuration(md, MDKeyKeepAliveInterval),
+		KeepAliveDisabled: mdutil.GetBool(md, MDKeyKeepAliveDisabled),
+		KeepAliveTimeout:  mdutil.GetDuration(md, MDKeyKeepAliveTimeout),
+		MaxFrameSize:      mdutil.GetInt(md, MDKeyMaxFrameSize),
+		MaxReceiveBuffer:  mdutil.GetInt(md, MDKeyMaxReceiveBuffer),
+		MaxStreamBuffer:   mdutil.GetInt(md, MDKeyMaxStreamBuffer),
+		MaxStreams:        mdutil.GetInt(md, MDKeyMaxStreams),
+	}, nil
+}
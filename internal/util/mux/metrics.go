@@ -0,0 +1,30 @@
+package mux
+
+import (
+	"github.com/go-gost/core/metrics"
+	xmetrics "github.com/go-gost/x/metrics"
+)
+
+// withMetrics records the session and its streams in the gauges of the kind (dialer or listener),
+// the streams are counted per kind, so no series is left behind by the closed sessions.
+func withMetrics(s *Session, kind string) *Session {
+	if !xmetrics.IsEnabled() {
+		return s
+	}
+
+	sessions := xmetrics.GetGauge(xmetrics.MetricMuxSessionsGauge, metrics.Labels{
+		"kind": kind,
+	})
+	if sessions != nil {
+		sessions.Inc()
+		go func() {
+			<-s.session.CloseChan()
+			sessions.Dec()
+		}()
+	}
+
+	s.streams = xmetrics.GetGauge(xmetrics.MetricMuxStreamsGauge, metrics.Labels{
+		"kind": kind,
+	})
+	return s
+}
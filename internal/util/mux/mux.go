@@ -2,13 +2,17 @@ package mux
 
 import (
 	"net"
+	"sync"
 	"time"
 
+	"github.com/go-gost/core/metrics"
 	smux "github.com/xtaci/smux"
 )
 
 const (
 	defaultVersion = 1
+
+	ProtocolSMux = "smux"
 )
 
 type Config struct {
@@ -36,6 +40,10 @@ type Config struct {
 	// MaxStreamBuffer is used to control the maximum
 	// number of data per stream
 	MaxStreamBuffer int
+
+	// MaxStreams is the maximum number of streams of a session opened by the dialer,
+	// a new session is created when all sessions are full, zero means unlimited.
+	MaxStreams int
 }
 
 func convertConfig(cfg *Config) *smux.Config {
@@ -72,6 +80,8 @@ func convertConfig(cfg *Config) *smux.Config {
 type Session struct {
 	conn    net.Conn
 	session *smux.Session
+	// the streams gauge of the session, set if the metrics are enabled.
+	streams metrics.Gauge
 }
 
 func ClientSession(conn net.Conn, cfg *Config) (*Session, error) {
@@ -101,7 +111,7 @@ func (session *Session) GetConn() (net.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &streamConn{Conn: session.conn, stream: stream}, nil
+	return session.streamConn(stream), nil
 }
 
 func (session *Session) Accept() (net.Conn, error) {
@@ -109,7 +119,15 @@ func (session *Session) Accept() (net.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &streamConn{Conn: session.conn, stream: stream}, nil
+	return session.streamConn(stream), nil
+}
+
+func (session *Session) streamConn(stream *smux.Stream) net.Conn {
+	if session.streams == nil {
+		return &streamConn{Conn: session.conn, stream: stream}
+	}
+	session.streams.Inc()
+	return &streamConn{Conn: session.conn, stream: stream, gauge: session.streams}
 }
 
 func (session *Session) Close() error {
@@ -132,7 +150,9 @@ func (session *Session) NumStreams() int {
 
 type streamConn struct {
 	net.Conn
-	stream *smux.Stream
+	stream    *smux.Stream
+	gauge     metrics.Gauge
+	closeOnce sync.Once
 }
 
 func (c *streamConn) Read(b []byte) (n int, err error) {
@@ -144,5 +164,8 @@ func (c *streamConn) Write(b []byte) (n int, err error) {
 }
 
 func (c *streamConn) Close() error {
+	if c.gauge != nil {
+		c.closeOnce.Do(c.gauge.Dec)
+	}
 	return c.stream.Close()
 }
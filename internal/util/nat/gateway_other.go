@@ -0,0 +1,13 @@
+//go:build !linux
+
+package nat
+
+import (
+	"errors"
+	"net/netip"
+)
+
+// defaultGateway is not supported, the gateway must be specified.
+func defaultGateway() (netip.Addr, error) {
+	return netip.Addr{}, errors.New("default gateway discovery is not supported, the gateway must be specified")
+}
@@ -0,0 +1,258 @@
+// Package nat discovers the public address of the listeners behind NAT by STUN,
+// and maps their ports on the gateway by UPnP, NAT-PMP or PCP.
+package nat
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/go-gost/core/logger"
+)
+
+// the port mapping methods.
+const (
+	MethodUPnP   = "upnp"
+	MethodNATPMP = "natpmp"
+	MethodPCP    = "pcp"
+	// all the methods, tried in the order of PCP, NAT-PMP and UPnP.
+	MethodAuto = "auto"
+)
+
+const (
+	defaultLifetime = time.Hour
+	// the interval of the STUN discovery without the port mapping.
+	defaultSTUNInterval = 5 * time.Minute
+	retryInterval       = 30 * time.Second
+)
+
+// Mapping is the port mapping on the gateway.
+type Mapping struct {
+	// tcp or udp
+	Network      string
+	InternalPort int
+	External     netip.AddrPort
+	Lifetime     time.Duration
+}
+
+type mapper interface {
+	Map(ctx context.Context, network string, port int, lifetime time.Duration) (*Mapping, error)
+	Unmap(ctx context.Context, mapping *Mapping) error
+	String() string
+}
+
+type options struct {
+	stunServers []string
+	methods     []string
+	gateway     netip.Addr
+	lifetime    time.Duration
+	logger      logger.Logger
+}
+
+type Option func(opts *options)
+
+// STUNServersOption sets the STUN servers to discover the public address.
+func STUNServersOption(servers []string) Option {
+	return func(opts *options) {
+		opts.stunServers = servers
+	}
+}
+
+// MethodsOption sets the port mapping methods, no port is mapped if not set.
+func MethodsOption(methods []string) Option {
+	return func(opts *options) {
+		opts.methods = methods
+	}
+}
+
+// GatewayOption sets the gateway of NAT-PMP and PCP, the default gateway is used if not set.
+func GatewayOption(gateway netip.Addr) Option {
+	return func(opts *options) {
+		opts.gateway = gateway
+	}
+}
+
+// LifetimeOption sets the requested lifetime of the port mapping, the mapping is renewed at half of the lifetime.
+func LifetimeOption(lifetime time.Duration) Option {
+	return func(opts *options) {
+		opts.lifetime = lifetime
+	}
+}
+
+func LoggerOption(logger logger.Logger) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
+}
+
+// Traversal keeps the public address of a listener up to date.
+type Traversal struct {
+	options options
+}
+
+func NewTraversal(opts ...Option) *Traversal {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.lifetime <= 0 {
+		options.lifetime = defaultLifetime
+	}
+	if options.logger == nil {
+		options.logger = logger.Default()
+	}
+
+	return &Traversal{
+		options: options,
+	}
+}
+
+// Run maps the port of the listener with the address addr on the network (tcp or udp)
+// and discovers its public address until ctx is done, then the mapping is deleted.
+// The function update is called when the public address is changed.
+func (t *Traversal) Run(ctx context.Context, network string, addr net.Addr, update func(addr string)) {
+	log := t.options.logger
+
+	_, sport, _ := net.SplitHostPort(addr.String())
+	port, _ := strconv.Atoi(sport)
+	if port <= 0 {
+		log.Warnf("nat: invalid address %s", addr)
+		return
+	}
+
+	mappers := t.mappers()
+
+	var m mapper
+	var mapping *Mapping
+	defer func() {
+		if mapping == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := m.Unmap(ctx, mapping); err != nil {
+			log.Warnf("nat: %s unmap %s/%d: %v", m, network, port, err)
+		}
+	}()
+
+	var public string
+	for {
+		mapping = nil
+		for _, v := range mappers {
+			if m != nil && m != v {
+				continue
+			}
+			mp, err := v.Map(ctx, network, port, t.options.lifetime)
+			if err != nil {
+				log.Debugf("nat: %s map %s/%d: %v", v, network, port, err)
+				continue
+			}
+			m, mapping = v, mp
+			log.Debugf("nat: %s map %s/%d to %s, lifetime %v", v, network, port, mp.External, mp.Lifetime)
+			break
+		}
+		if mapping == nil {
+			// the working method is tried again first in the next round.
+			m = nil
+		}
+
+		var ip netip.Addr
+		extPort := uint16(port)
+		if mapping != nil {
+			ip = mapping.External.Addr()
+			if p := mapping.External.Port(); p > 0 {
+				extPort = p
+			}
+		}
+		// the address of the gateway may not be the public one behind the multiple NATs.
+		if v, err := t.stun(ctx); err == nil {
+			ip = v
+		} else if len(t.options.stunServers) > 0 {
+			log.Debugf("nat: stun: %v", err)
+		}
+
+		if ip.IsValid() && !ip.IsUnspecified() {
+			if s := netip.AddrPortFrom(ip, extPort).String(); s != public {
+				public = s
+				log.Infof("nat: public address of %s/%d is %s", network, port, public)
+				update(public)
+			}
+		}
+
+		interval := defaultSTUNInterval
+		if mapping != nil && mapping.Lifetime > 0 {
+			interval = mapping.Lifetime / 2
+		} else if len(mappers) > 0 {
+			interval = retryInterval
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *Traversal) mappers() (mappers []mapper) {
+	var methods []string
+	for _, method := range t.options.methods {
+		if method == MethodAuto {
+			methods = append(methods, MethodPCP, MethodNATPMP, MethodUPnP)
+		} else {
+			methods = append(methods, method)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, method := range methods {
+		if seen[method] {
+			continue
+		}
+		seen[method] = true
+
+		switch method {
+		case MethodPCP, MethodNATPMP:
+			gw := t.options.gateway
+			if !gw.IsValid() {
+				v, err := defaultGateway()
+				if err != nil {
+					t.options.logger.Warnf("nat: %s: %v", method, err)
+					continue
+				}
+				gw = v
+			}
+			mappers = append(mappers, newPMPMapper(gw, method == MethodPCP))
+		case MethodUPnP:
+			mappers = append(mappers, &upnpMapper{})
+		default:
+			t.options.logger.Warnf("nat: unknown method %s", method)
+		}
+	}
+	return
+}
+
+// stun discovers the public IP address by the STUN servers in turn.
+func (t *Traversal) stun(ctx context.Context) (netip.Addr, error) {
+	if len(t.options.stunServers) == 0 {
+		return netip.Addr{}, errors.New("no stun server")
+	}
+
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer conn.Close()
+
+	for _, server := range t.options.stunServers {
+		var addr netip.AddrPort
+		addr, err = STUN(ctx, conn, server)
+		if err == nil {
+			return addr.Addr().Unmap(), nil
+		}
+	}
+	return netip.Addr{}, err
+}
@@ -0,0 +1,218 @@
+package nat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+const (
+	// the port of the NAT-PMP (RFC 6886) and PCP (RFC 6887) servers.
+	pmpPort = 5351
+
+	pcpVersion   = 2
+	pcpOpcodeMap = 1
+	// the result code of the PCP and NAT-PMP servers which do not support the version.
+	pcpUnsupportedVersion = 1
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported version")
+)
+
+// pmpMapper maps the ports by NAT-PMP or PCP on the gateway.
+type pmpMapper struct {
+	gateway netip.Addr
+	pcp     bool
+	nonce   [12]byte
+}
+
+func newPMPMapper(gateway netip.Addr, pcp bool) *pmpMapper {
+	m := &pmpMapper{
+		gateway: gateway,
+		pcp:     pcp,
+	}
+	rand.Read(m.nonce[:])
+	return m
+}
+
+func (m *pmpMapper) String() string {
+	if m.pcp {
+		return MethodPCP
+	}
+	return MethodNATPMP
+}
+
+func (m *pmpMapper) Map(ctx context.Context, network string, port int, lifetime time.Duration) (*Mapping, error) {
+	if m.pcp {
+		return m.mapPCP(ctx, network, port, port, lifetime)
+	}
+	return m.mapNATPMP(ctx, network, port, port, lifetime)
+}
+
+func (m *pmpMapper) Unmap(ctx context.Context, mapping *Mapping) error {
+	// the mapping is deleted by the request with zero lifetime.
+	var err error
+	if m.pcp {
+		_, err = m.mapPCP(ctx, mapping.Network, mapping.InternalPort, 0, 0)
+	} else {
+		_, err = m.mapNATPMP(ctx, mapping.Network, mapping.InternalPort, 0, 0)
+	}
+	return err
+}
+
+func (m *pmpMapper) mapNATPMP(ctx context.Context, network string, port int, extPort int, lifetime time.Duration) (*Mapping, error) {
+	// the external address request.
+	resp, err := m.exchange(ctx, []byte{0, 0}, func(b []byte) bool {
+		return len(b) >= 12 && b[0] == 0 && b[1] == 128
+	})
+	if err != nil {
+		return nil, err
+	}
+	if code := binary.BigEndian.Uint16(resp[2:4]); code != 0 {
+		return nil, fmt.Errorf("nat-pmp: result code %d", code)
+	}
+	extIP := netip.AddrFrom4([4]byte(resp[8:12]))
+
+	op := byte(1)
+	if network == "tcp" {
+		op = 2
+	}
+	req := make([]byte, 12)
+	req[1] = op
+	binary.BigEndian.PutUint16(req[4:6], uint16(port))
+	binary.BigEndian.PutUint16(req[6:8], uint16(extPort))
+	binary.BigEndian.PutUint32(req[8:12], uint32(lifetime.Seconds()))
+
+	resp, err = m.exchange(ctx, req, func(b []byte) bool {
+		return len(b) >= 16 && b[0] == 0 && b[1] == op+128 &&
+			binary.BigEndian.Uint16(b[8:10]) == uint16(port)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if code := binary.BigEndian.Uint16(resp[2:4]); code != 0 {
+		return nil, fmt.Errorf("nat-pmp: result code %d", code)
+	}
+
+	return &Mapping{
+		Network:      network,
+		InternalPort: port,
+		External:     netip.AddrPortFrom(extIP, binary.BigEndian.Uint16(resp[10:12])),
+		Lifetime:     time.Duration(binary.BigEndian.Uint32(resp[12:16])) * time.Second,
+	}, nil
+}
+
+func (m *pmpMapper) mapPCP(ctx context.Context, network string, port int, extPort int, lifetime time.Duration) (*Mapping, error) {
+	local, err := localAddr(net.JoinHostPort(m.gateway.String(), strconv.Itoa(pmpPort)))
+	if err != nil {
+		return nil, err
+	}
+
+	proto := byte(17)
+	if network == "tcp" {
+		proto = 6
+	}
+
+	// the common request header followed by the MAP opcode data.
+	req := make([]byte, 60)
+	req[0] = pcpVersion
+	req[1] = pcpOpcodeMap
+	binary.BigEndian.PutUint32(req[4:8], uint32(lifetime.Seconds()))
+	a16 := local.As16()
+	copy(req[8:24], a16[:])
+	copy(req[24:36], m.nonce[:])
+	req[36] = proto
+	binary.BigEndian.PutUint16(req[40:42], uint16(port))
+	binary.BigEndian.PutUint16(req[42:44], uint16(extPort))
+	// the suggested external address, any IPv4 address.
+	a16 = netip.IPv4Unspecified().As16()
+	copy(req[44:60], a16[:])
+
+	resp, err := m.exchange(ctx, req, func(b []byte) bool {
+		// the NAT-PMP server replies the unsupported version with the version 0.
+		if len(b) >= 4 && b[0] == 0 {
+			return true
+		}
+		return len(b) >= 60 && b[0] == pcpVersion && b[1] == pcpOpcodeMap|0x80 &&
+			string(b[24:36]) == string(m.nonce[:])
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp[0] != pcpVersion {
+		return nil, fmt.Errorf("pcp: %w", ErrUnsupportedVersion)
+	}
+	if code := resp[3]; code != 0 {
+		if code == pcpUnsupportedVersion {
+			return nil, fmt.Errorf("pcp: %w", ErrUnsupportedVersion)
+		}
+		return nil, fmt.Errorf("pcp: result code %d", code)
+	}
+
+	return &Mapping{
+		Network:      network,
+		InternalPort: port,
+		External: netip.AddrPortFrom(
+			netip.AddrFrom16([16]byte(resp[44:60])).Unmap(),
+			binary.BigEndian.Uint16(resp[42:44])),
+		Lifetime: time.Duration(binary.BigEndian.Uint32(resp[4:8])) * time.Second,
+	}, nil
+}
+
+// exchange sends the request to the gateway with the retransmission until the matched response is received.
+func (m *pmpMapper) exchange(ctx context.Context, req []byte, match func(b []byte) bool) ([]byte, error) {
+	conn, err := net.DialUDP("udp4", nil, net.UDPAddrFromAddrPort(netip.AddrPortFrom(m.gateway, pmpPort)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	b := make([]byte, 1100)
+	// the initial retransmission timeout is 250ms and doubled for each attempt.
+	for timeout := 250 * time.Millisecond; timeout <= 4*time.Second; timeout *= 2 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+
+		for {
+			n, err := conn.Read(b)
+			if err != nil {
+				if ne, ok := err.(net.Error); ok && ne.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			if match(b[:n]) {
+				return b[:n], nil
+			}
+		}
+	}
+	return nil, context.DeadlineExceeded
+}
+
+// localAddr returns the local address used to reach the address addr.
+func localAddr(addr string) (netip.Addr, error) {
+	conn, err := net.Dial("udp4", addr)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).AddrPort().Addr().Unmap(), nil
+}
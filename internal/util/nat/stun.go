@@ -0,0 +1,151 @@
+package nat
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// STUN binding (RFC 5389).
+const (
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMagicCookie     = 0x2112a442
+	stunHeaderLength    = 20
+
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXORMappedAddress = 0x0020
+)
+
+var (
+	ErrSTUNNoAddress = errors.New("stun: no mapped address")
+)
+
+// STUN discovers the public address of the socket conn by the binding request to the STUN server.
+func STUN(ctx context.Context, conn net.PacketConn, server string) (netip.AddrPort, error) {
+	raddr, err := net.ResolveUDPAddr("udp4", server)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	req := make([]byte, stunHeaderLength)
+	binary.BigEndian.PutUint16(req[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint32(req[4:8], stunMagicCookie)
+	if _, err := rand.Read(req[8:20]); err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	b := make([]byte, 1500)
+	for timeout := 500 * time.Millisecond; timeout <= 4*time.Second; timeout *= 2 {
+		if err := ctx.Err(); err != nil {
+			return netip.AddrPort{}, err
+		}
+		if _, err := conn.WriteTo(req, raddr); err != nil {
+			return netip.AddrPort{}, err
+		}
+
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+
+		for {
+			n, _, err := conn.ReadFrom(b)
+			if err != nil {
+				if ne, ok := err.(net.Error); ok && ne.Timeout() {
+					break
+				}
+				return netip.AddrPort{}, err
+			}
+			if n < stunHeaderLength ||
+				binary.BigEndian.Uint16(b[0:2]) != stunBindingResponse ||
+				!bytes.Equal(b[8:20], req[8:20]) {
+				continue
+			}
+			conn.SetReadDeadline(time.Time{})
+			return parseSTUNResponse(b[:n])
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	return netip.AddrPort{}, context.DeadlineExceeded
+}
+
+func parseSTUNResponse(b []byte) (netip.AddrPort, error) {
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	attrs := b[stunHeaderLength:]
+	if len(attrs) > length {
+		attrs = attrs[:length]
+	}
+
+	var mapped netip.AddrPort
+	for len(attrs) >= 4 {
+		typ := binary.BigEndian.Uint16(attrs[0:2])
+		l := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if len(attrs) < 4+l {
+			break
+		}
+		v := attrs[4 : 4+l]
+
+		switch typ {
+		case stunAttrXORMappedAddress:
+			if addr, ok := parseSTUNAddress(v, b[4:20]); ok {
+				return addr, nil
+			}
+		case stunAttrMappedAddress:
+			if addr, ok := parseSTUNAddress(v, nil); ok {
+				mapped = addr
+			}
+		}
+
+		// the attributes are padded to the multiple of 4 bytes.
+		l = (l + 3) &^ 3
+		if len(attrs) < 4+l {
+			break
+		}
+		attrs = attrs[4+l:]
+	}
+
+	if mapped.IsValid() {
+		return mapped, nil
+	}
+	return netip.AddrPort{}, ErrSTUNNoAddress
+}
+
+// parseSTUNAddress parses the (XOR-)MAPPED-ADDRESS attribute,
+// xor is the magic cookie and the transaction ID for the XOR-MAPPED-ADDRESS.
+func parseSTUNAddress(v []byte, xor []byte) (netip.AddrPort, bool) {
+	if len(v) < 8 {
+		return netip.AddrPort{}, false
+	}
+
+	port := binary.BigEndian.Uint16(v[2:4])
+	var ip []byte
+	switch v[1] {
+	case 0x01:
+		ip = append(ip, v[4:8]...)
+	case 0x02:
+		if len(v) < 20 {
+			return netip.AddrPort{}, false
+		}
+		ip = append(ip, v[4:20]...)
+	default:
+		return netip.AddrPort{}, false
+	}
+
+	if xor != nil {
+		port ^= uint16(stunMagicCookie >> 16)
+		for i := range ip {
+			ip[i] ^= xor[i]
+		}
+	}
+
+	addr, _ := netip.AddrFromSlice(ip)
+	return netip.AddrPortFrom(addr, port), true
+}
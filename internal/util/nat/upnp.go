@@ -0,0 +1,295 @@
+package nat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ssdpAddr = "239.255.255.250:1900"
+	ssdpST   = "urn:schemas-upnp-org:device:InternetGatewayDevice:1"
+
+	upnpDescription = "gost"
+)
+
+var (
+	ErrUPnPNoGateway = errors.New("upnp: no internet gateway device")
+)
+
+// upnpMapper maps the ports by the WANIPConnection or WANPPPConnection service of the UPnP internet gateway device.
+type upnpMapper struct {
+	controlURL  string
+	serviceType string
+	client      *http.Client
+}
+
+func (m *upnpMapper) String() string {
+	return MethodUPnP
+}
+
+func (m *upnpMapper) Map(ctx context.Context, network string, port int, lifetime time.Duration) (*Mapping, error) {
+	if m.controlURL == "" {
+		if err := m.discover(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := url.Parse(m.controlURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "80")
+	}
+	local, err := localAddr(host)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = m.soap(ctx, "AddPortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(port)},
+		{"NewProtocol", strings.ToUpper(network)},
+		{"NewInternalPort", strconv.Itoa(port)},
+		{"NewInternalClient", local.String()},
+		{"NewEnabled", "1"},
+		{"NewPortMappingDescription", upnpDescription},
+		{"NewLeaseDuration", strconv.Itoa(int(lifetime.Seconds()))},
+	})
+	if err != nil {
+		// the discovered device may be gone, it is discovered again for the next mapping.
+		m.controlURL = ""
+		return nil, err
+	}
+
+	mapping := &Mapping{
+		Network:      network,
+		InternalPort: port,
+		Lifetime:     lifetime,
+	}
+
+	var extIP netip.Addr
+	if resp, err := m.soap(ctx, "GetExternalIPAddress", nil); err == nil {
+		extIP, _ = netip.ParseAddr(soapValue(resp, "NewExternalIPAddress"))
+	}
+	mapping.External = netip.AddrPortFrom(extIP, uint16(port))
+
+	return mapping, nil
+}
+
+func (m *upnpMapper) Unmap(ctx context.Context, mapping *Mapping) error {
+	if m.controlURL == "" {
+		return nil
+	}
+	_, err := m.soap(ctx, "DeletePortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(int(mapping.External.Port()))},
+		{"NewProtocol", strings.ToUpper(mapping.Network)},
+	})
+	return err
+}
+
+// discover finds the internet gateway device by SSDP and the control URL of its connection service.
+func (m *upnpMapper) discover(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	raddr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return err
+	}
+
+	req := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"ST: " + ssdpST + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+	if _, err := conn.WriteTo([]byte(req), raddr); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	b := make([]byte, 4096)
+	for {
+		n, _, err := conn.ReadFrom(b)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return ErrUPnPNoGateway
+			}
+			return err
+		}
+
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b[:n])), nil)
+		if err != nil {
+			continue
+		}
+		location := resp.Header.Get("Location")
+		resp.Body.Close()
+		if location == "" {
+			continue
+		}
+
+		if err := m.describe(ctx, location); err == nil {
+			return nil
+		}
+	}
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+type upnpDevice struct {
+	DeviceType string        `xml:"deviceType"`
+	Services   []upnpService `xml:"serviceList>service"`
+	Devices    []upnpDevice  `xml:"deviceList>device"`
+}
+
+func (d *upnpDevice) find() *upnpService {
+	for i := range d.Services {
+		st := d.Services[i].ServiceType
+		if strings.Contains(st, ":WANIPConnection:") || strings.Contains(st, ":WANPPPConnection:") {
+			return &d.Services[i]
+		}
+	}
+	for i := range d.Devices {
+		if svc := d.Devices[i].find(); svc != nil {
+			return svc
+		}
+	}
+	return nil
+}
+
+// describe fetches the device description from location.
+func (m *upnpMapper) describe(ctx context.Context, location string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upnp: %s: %s", location, resp.Status)
+	}
+
+	var root struct {
+		URLBase string     `xml:"URLBase"`
+		Device  upnpDevice `xml:"device"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&root); err != nil {
+		return err
+	}
+
+	svc := root.Device.find()
+	if svc == nil {
+		return ErrUPnPNoGateway
+	}
+
+	base := location
+	if root.URLBase != "" {
+		base = root.URLBase
+	}
+	bu, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	cu, err := bu.Parse(strings.TrimSpace(svc.ControlURL))
+	if err != nil {
+		return err
+	}
+
+	m.controlURL = cu.String()
+	m.serviceType = strings.TrimSpace(svc.ServiceType)
+	return nil
+}
+
+// soap invokes the action of the connection service with the arguments args.
+func (m *upnpMapper) soap(ctx context.Context, action string, args [][2]string) ([]byte, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, m.serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(&body, "<%s>", arg[0])
+		xml.EscapeText(&body, []byte(arg[1]))
+		fmt.Fprintf(&body, "</%s>", arg[0])
+	}
+	fmt.Fprintf(&body, `</u:%s></s:Body></s:Envelope>`, action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.controlURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, m.serviceType, action))
+
+	resp, err := m.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if code := soapValue(b, "errorCode"); code != "" {
+			return nil, fmt.Errorf("upnp: %s: error code %s %s", action, code, soapValue(b, "errorDescription"))
+		}
+		return nil, fmt.Errorf("upnp: %s: %s", action, resp.Status)
+	}
+	return b, nil
+}
+
+func (m *upnpMapper) httpClient() *http.Client {
+	if m.client == nil {
+		m.client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return m.client
+}
+
+// soapValue returns the text of the first element named name in the response b.
+func soapValue(b []byte, name string) string {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == name {
+			var v string
+			if d.DecodeElement(&v, &se) != nil {
+				return ""
+			}
+			return strings.TrimSpace(v)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package nfqueue
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// Verdict is the verdict of a queued packet.
+type Verdict uint32
+
+const (
+	VerdictDrop   Verdict = 0
+	VerdictAccept Verdict = 1
+)
+
+var (
+	ErrUnsupported = errors.New("nfqueue: unsupported platform")
+)
+
+// Packet is a packet received from the queue.
+type Packet struct {
+	ID      uint32
+	Payload []byte
+}
+
+// PacketConn is the connection of a netfilter queue,
+// each packet read from it must be given a verdict.
+type PacketConn interface {
+	ReadPacket() (*Packet, error)
+	SetVerdict(id uint32, verdict Verdict) error
+}
+
+// Header is the network and transport header of an IP packet.
+type Header struct {
+	// Network is tcp, udp or ip for other protocols.
+	Network string
+	Src     net.Addr
+	Dst     net.Addr
+	// Payload is the transport payload of the packet.
+	Payload []byte
+}
+
+// ParseHeader parses the IPv4 or IPv6 packet.
+func ParseHeader(b []byte) (*Header, error) {
+	if len(b) < 1 {
+		return nil, errors.New("nfqueue: short packet")
+	}
+
+	var proto byte
+	var src, dst net.IP
+	var data []byte
+	switch b[0] >> 4 {
+	case 4:
+		if len(b) < 20 {
+			return nil, errors.New("nfqueue: short IPv4 header")
+		}
+		ihl := int(b[0]&0x0f) * 4
+		if ihl < 20 || len(b) < ihl {
+			return nil, errors.New("nfqueue: bad IPv4 header")
+		}
+		proto = b[9]
+		src, dst = net.IP(b[12:16]), net.IP(b[16:20])
+		data = b[ihl:]
+	case 6:
+		if len(b) < 40 {
+			return nil, errors.New("nfqueue: short IPv6 header")
+		}
+		// the extension headers are not parsed.
+		proto = b[6]
+		src, dst = net.IP(b[8:24]), net.IP(b[24:40])
+		data = b[40:]
+	default:
+		return nil, errors.New("nfqueue: unknown IP version")
+	}
+
+	h := &Header{
+		Network: "ip",
+		Src:     &net.IPAddr{IP: src},
+		Dst:     &net.IPAddr{IP: dst},
+	}
+	switch proto {
+	case 6: // TCP
+		if len(data) < 20 {
+			return h, nil
+		}
+		h.Network = "tcp"
+		h.Src = &net.TCPAddr{IP: src, Port: int(binary.BigEndian.Uint16(data[0:2]))}
+		h.Dst = &net.TCPAddr{IP: dst, Port: int(binary.BigEndian.Uint16(data[2:4]))}
+		if off := int(data[12]>>4) * 4; off >= 20 && off <= len(data) {
+			h.Payload = data[off:]
+		}
+	case 17: // UDP
+		if len(data) < 8 {
+			return h, nil
+		}
+		h.Network = "udp"
+		h.Src = &net.UDPAddr{IP: src, Port: int(binary.BigEndian.Uint16(data[0:2]))}
+		h.Dst = &net.UDPAddr{IP: dst, Port: int(binary.BigEndian.Uint16(data[2:4]))}
+		h.Payload = data[8:]
+	}
+	return h, nil
+}
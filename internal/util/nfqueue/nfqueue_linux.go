@@ -0,0 +1,319 @@
+package nfqueue
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// the constants of linux/netfilter/nfnetlink_queue.h
+const (
+	nfnlSubsysQueue = 3
+
+	nfqnlMsgPacket  = 0
+	nfqnlMsgVerdict = 1
+	nfqnlMsgConfig  = 2
+
+	nfqaPacketHdr  = 1
+	nfqaVerdictHdr = 2
+	nfqaPayload    = 10
+
+	nfqaCfgCmd     = 1
+	nfqaCfgParams  = 2
+	nfqaCfgQMaxLen = 3
+	nfqaCfgMask    = 4
+	nfqaCfgFlags   = 5
+
+	nfqnlCfgCmdBind = 1
+
+	nfqnlCopyPacket = 2
+
+	nfqaCfgFFailOpen = 1
+
+	nlaTypeMask = 0x3fff
+)
+
+const (
+	defaultCopyRange = 0xffff
+	recvBufferSize   = 0xffff + 4096
+	recvTimeout      = time.Second
+)
+
+// Queue is a netfilter queue bound by netlink.
+type Queue struct {
+	fd      int
+	num     uint16
+	seq     uint32
+	buf     []byte
+	pending [][]byte
+	rmu     sync.Mutex
+	closed  atomic.Bool
+}
+
+// Open binds to the netfilter queue num, the packets are sent to the queue
+// by the NFQUEUE target of iptables/nftables, e.g. iptables -A INPUT -j NFQUEUE --queue-num 100.
+func Open(num uint16, opts ...Option) (*Queue, error) {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.copyRange == 0 {
+		options.copyRange = defaultCopyRange
+	}
+
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	tv := unix.NsecToTimeval(int64(recvTimeout))
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	q := &Queue{
+		fd:  fd,
+		num: num,
+		buf: make([]byte, recvBufferSize),
+	}
+
+	// struct nfqnl_msg_config_cmd
+	cmd := []byte{nfqnlCfgCmdBind, 0, 0, 0}
+	binary.BigEndian.PutUint16(cmd[2:], unix.AF_UNSPEC)
+	if err := q.request(nfqnlMsgConfig, attr(nfqaCfgCmd, cmd)); err != nil {
+		q.Close()
+		return nil, fmt.Errorf("nfqueue: bind queue %d: %w", num, err)
+	}
+
+	// struct nfqnl_msg_config_params
+	params := make([]byte, 5)
+	binary.BigEndian.PutUint32(params, options.copyRange)
+	params[4] = nfqnlCopyPacket
+	attrs := [][]byte{attr(nfqaCfgParams, params)}
+	if options.maxLen > 0 {
+		attrs = append(attrs, attr(nfqaCfgQMaxLen, be32(options.maxLen)))
+	}
+	if options.failOpen {
+		attrs = append(attrs,
+			attr(nfqaCfgFlags, be32(nfqaCfgFFailOpen)),
+			attr(nfqaCfgMask, be32(nfqaCfgFFailOpen)),
+		)
+	}
+	if err := q.request(nfqnlMsgConfig, attrs...); err != nil {
+		q.Close()
+		return nil, fmt.Errorf("nfqueue: config queue %d: %w", num, err)
+	}
+
+	return q, nil
+}
+
+// ReadPacket reads the next packet of the queue.
+func (q *Queue) ReadPacket() (*Packet, error) {
+	q.rmu.Lock()
+	defer q.rmu.Unlock()
+
+	for {
+		for len(q.pending) > 0 {
+			m := q.pending[0]
+			q.pending = q.pending[1:]
+			if p := parsePacket(m); p != nil {
+				return p, nil
+			}
+		}
+
+		msgs, err := q.recv()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			typ := binary.NativeEndian.Uint16(m[4:6])
+			if typ == nfnlSubsysQueue<<8|nfqnlMsgPacket {
+				q.pending = append(q.pending, m)
+			}
+		}
+	}
+}
+
+// SetVerdict issues the verdict of the packet.
+func (q *Queue) SetVerdict(id uint32, verdict Verdict) error {
+	// struct nfqnl_msg_verdict_hdr
+	hdr := make([]byte, 8)
+	binary.BigEndian.PutUint32(hdr[0:], uint32(verdict))
+	binary.BigEndian.PutUint32(hdr[4:], id)
+	return q.send(nfqnlMsgVerdict, 0, attr(nfqaVerdictHdr, hdr))
+}
+
+func (q *Queue) Close() error {
+	if q.closed.Swap(true) {
+		return nil
+	}
+	// wait for the pending read to notice the closing.
+	q.rmu.Lock()
+	defer q.rmu.Unlock()
+	return unix.Close(q.fd)
+}
+
+// request sends a message and waits for the acknowledgement.
+func (q *Queue) request(typ uint16, attrs ...[]byte) error {
+	q.rmu.Lock()
+	defer q.rmu.Unlock()
+
+	seq := atomic.AddUint32(&q.seq, 1)
+	if err := q.sendSeq(typ, unix.NLM_F_ACK, seq, attrs...); err != nil {
+		return err
+	}
+
+	for {
+		msgs, err := q.recv()
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if binary.NativeEndian.Uint32(m[8:12]) != seq {
+				q.pending = append(q.pending, m)
+				continue
+			}
+			if binary.NativeEndian.Uint16(m[4:6]) == unix.NLMSG_ERROR && len(m) >= unix.NLMSG_HDRLEN+4 {
+				if errno := int32(binary.NativeEndian.Uint32(m[unix.NLMSG_HDRLEN:])); errno != 0 {
+					return unix.Errno(-errno)
+				}
+				return nil
+			}
+		}
+	}
+}
+
+func (q *Queue) send(typ uint16, flags uint16, attrs ...[]byte) error {
+	return q.sendSeq(typ, flags, atomic.AddUint32(&q.seq, 1), attrs...)
+}
+
+func (q *Queue) sendSeq(typ uint16, flags uint16, seq uint32, attrs ...[]byte) error {
+	n := unix.NLMSG_HDRLEN + 4
+	for _, a := range attrs {
+		n += len(a)
+	}
+	b := make([]byte, n)
+	// struct nlmsghdr
+	binary.NativeEndian.PutUint32(b[0:], uint32(n))
+	binary.NativeEndian.PutUint16(b[4:], nfnlSubsysQueue<<8|typ)
+	binary.NativeEndian.PutUint16(b[6:], unix.NLM_F_REQUEST|flags)
+	binary.NativeEndian.PutUint32(b[8:], seq)
+	// struct nfgenmsg
+	b[16] = unix.AF_UNSPEC
+	b[17] = unix.NFNETLINK_V0
+	binary.BigEndian.PutUint16(b[18:], q.num)
+	off := unix.NLMSG_HDRLEN + 4
+	for _, a := range attrs {
+		off += copy(b[off:], a)
+	}
+
+	return unix.Sendto(q.fd, b, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+}
+
+func (q *Queue) recv() (msgs [][]byte, err error) {
+	var n int
+	for {
+		n, _, err = unix.Recvfrom(q.fd, q.buf, 0)
+		if q.closed.Load() {
+			return nil, net.ErrClosed
+		}
+		switch err {
+		case nil:
+		case unix.EAGAIN, unix.EINTR:
+			// the receive timeout lets the closing of the queue be noticed.
+			continue
+		case unix.ENOBUFS:
+			// the kernel dropped the packets as the socket buffer overflowed.
+			continue
+		default:
+			return nil, err
+		}
+		break
+	}
+
+	b := q.buf[:n]
+	for len(b) >= unix.NLMSG_HDRLEN {
+		l := int(binary.NativeEndian.Uint32(b[0:4]))
+		if l < unix.NLMSG_HDRLEN || l > len(b) {
+			return nil, errors.New("nfqueue: bad netlink message")
+		}
+		m := make([]byte, l)
+		copy(m, b[:l])
+		msgs = append(msgs, m)
+		if align(l) >= len(b) {
+			break
+		}
+		b = b[align(l):]
+	}
+	return
+}
+
+func parsePacket(m []byte) *Packet {
+	off := unix.NLMSG_HDRLEN + 4
+	if len(m) < off {
+		return nil
+	}
+
+	var p Packet
+	found := false
+	b := m[off:]
+	for len(b) >= 4 {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		typ := binary.NativeEndian.Uint16(b[2:4]) & nlaTypeMask
+		if l < 4 || l > len(b) {
+			break
+		}
+		data := b[4:l]
+		switch typ {
+		case nfqaPacketHdr:
+			// struct nfqnl_msg_packet_hdr
+			if len(data) >= 4 {
+				p.ID = binary.BigEndian.Uint32(data[0:4])
+				found = true
+			}
+		case nfqaPayload:
+			p.Payload = data
+		}
+		if align(l) > len(b) {
+			break
+		}
+		b = b[align(l):]
+	}
+	if !found {
+		return nil
+	}
+	return &p
+}
+
+// attr encodes a netlink attribute.
+func attr(typ uint16, data []byte) []byte {
+	l := 4 + len(data)
+	b := make([]byte, align(l))
+	binary.NativeEndian.PutUint16(b[0:], uint16(l))
+	binary.NativeEndian.PutUint16(b[2:], typ)
+	copy(b[4:], data)
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func align(n int) int {
+	return (n + unix.NLA_ALIGNTO - 1) & ^(unix.NLA_ALIGNTO - 1)
+}
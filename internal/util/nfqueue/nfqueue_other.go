@@ -0,0 +1,21 @@
+//go:build !linux
+
+package nfqueue
+
+type Queue struct{}
+
+func Open(num uint16, opts ...Option) (*Queue, error) {
+	return nil, ErrUnsupported
+}
+
+func (q *Queue) ReadPacket() (*Packet, error) {
+	return nil, ErrUnsupported
+}
+
+func (q *Queue) SetVerdict(id uint32, verdict Verdict) error {
+	return ErrUnsupported
+}
+
+func (q *Queue) Close() error {
+	return nil
+}
@@ -0,0 +1,30 @@
+package nfqueue
+
+type options struct {
+	copyRange uint32
+	maxLen    uint32
+	failOpen  bool
+}
+
+type Option func(opts *options)
+
+// CopyRangeOption sets the maximum number of the bytes of a packet copied to the user space.
+func CopyRangeOption(n uint32) Option {
+	return func(opts *options) {
+		opts.copyRange = n
+	}
+}
+
+// MaxLenOption sets the maximum number of the packets waiting for verdict in the kernel.
+func MaxLenOption(n uint32) Option {
+	return func(opts *options) {
+		opts.maxLen = n
+	}
+}
+
+// FailOpenOption lets the kernel accept the packets when the queue is full instead of dropping them.
+func FailOpenOption(failOpen bool) Option {
+	return func(opts *options) {
+		opts.failOpen = failOpen
+	}
+}
@@ -0,0 +1,193 @@
+// Package ntlm implements the client side of the NTLMv2 authentication used by the HTTP proxies,
+// the message signing and sealing are not supported as they are not used by HTTP.
+package ntlm
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+const (
+	negotiateUnicode                 = 0x00000001
+	negotiateOEM                     = 0x00000002
+	requestTarget                    = 0x00000004
+	negotiateNTLM                    = 0x00000200
+	negotiateAlwaysSign              = 0x00008000
+	negotiateExtendedSessionSecurity = 0x00080000
+	negotiateTargetInfo              = 0x00800000
+	negotiate128                     = 0x20000000
+	negotiate56                      = 0x80000000
+
+	negotiateFlags = negotiateUnicode | negotiateOEM | requestTarget | negotiateNTLM |
+		negotiateAlwaysSign | negotiateExtendedSessionSecurity | negotiate128 | negotiate56
+
+	avIDEOL       = 0
+	avIDTimestamp = 7
+)
+
+var (
+	signature = []byte("NTLMSSP\x00")
+
+	ErrInvalidChallenge = errors.New("ntlm: invalid challenge message")
+)
+
+// Negotiate returns the negotiate message (type 1) starting the authentication.
+func Negotiate() []byte {
+	b := make([]byte, 32)
+	copy(b, signature)
+	binary.LittleEndian.PutUint32(b[8:], 1)
+	binary.LittleEndian.PutUint32(b[12:], negotiateFlags)
+	// the domain and workstation fields are empty, the offsets point to the end of the message.
+	binary.LittleEndian.PutUint32(b[20:], 32)
+	binary.LittleEndian.PutUint32(b[28:], 32)
+	return b
+}
+
+// Challenge is the challenge message (type 2) of the server.
+type Challenge struct {
+	Flags      uint32
+	Challenge  [8]byte
+	TargetInfo []byte
+}
+
+// ParseChallenge parses the challenge message (type 2) of the server.
+func ParseChallenge(b []byte) (*Challenge, error) {
+	if len(b) < 32 || !bytes.Equal(b[:8], signature) || binary.LittleEndian.Uint32(b[8:]) != 2 {
+		return nil, ErrInvalidChallenge
+	}
+
+	c := &Challenge{
+		Flags: binary.LittleEndian.Uint32(b[20:]),
+	}
+	copy(c.Challenge[:], b[24:32])
+
+	if len(b) >= 48 {
+		n := int(binary.LittleEndian.Uint16(b[40:]))
+		offset := int(binary.LittleEndian.Uint32(b[44:]))
+		if offset+n > len(b) {
+			return nil, ErrInvalidChallenge
+		}
+		c.TargetInfo = b[offset : offset+n]
+	}
+
+	return c, nil
+}
+
+// timestamp returns the timestamp of the target info, nil is returned if it is not present.
+func (c *Challenge) timestamp() []byte {
+	for b := c.TargetInfo; len(b) >= 4; {
+		id := binary.LittleEndian.Uint16(b)
+		n := int(binary.LittleEndian.Uint16(b[2:]))
+		if id == avIDEOL || len(b) < 4+n {
+			return nil
+		}
+		if id == avIDTimestamp && n == 8 {
+			return b[4:12]
+		}
+		b = b[4+n:]
+	}
+	return nil
+}
+
+// Authenticate returns the authenticate message (type 3) responding to the challenge with the NTLMv2 response.
+// The user can be in the form of DOMAIN\user or user@domain.
+func Authenticate(c *Challenge, user, password string) ([]byte, error) {
+	domain := ""
+	if d, u, ok := strings.Cut(user, `\`); ok {
+		domain, user = d, u
+	} else if u, d, ok := strings.Cut(user, "@"); ok {
+		domain, user = d, u
+	}
+
+	h := md4.New()
+	h.Write(encodeUTF16(password))
+	ntHash := h.Sum(nil)
+
+	v2Hash := hmacMD5(ntHash, encodeUTF16(strings.ToUpper(user)+domain))
+
+	var clientChallenge [8]byte
+	if _, err := rand.Read(clientChallenge[:]); err != nil {
+		return nil, err
+	}
+
+	ts := c.timestamp()
+	if ts == nil {
+		ts = make([]byte, 8)
+		// the FILETIME, 100-nanosecond intervals since January 1, 1601.
+		binary.LittleEndian.PutUint64(ts, uint64(time.Now().UnixNano()/100+116444736000000000))
+	}
+
+	var temp bytes.Buffer
+	temp.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	temp.Write(ts)
+	temp.Write(clientChallenge[:])
+	temp.Write([]byte{0, 0, 0, 0})
+	temp.Write(c.TargetInfo)
+	temp.Write([]byte{0, 0, 0, 0})
+
+	ntProof := hmacMD5(v2Hash, append(c.Challenge[:], temp.Bytes()...))
+	ntResponse := append(ntProof, temp.Bytes()...)
+
+	// the LMv2 response is omitted if the server provides the timestamp.
+	lmResponse := make([]byte, 24)
+	if c.timestamp() == nil {
+		lmResponse = append(hmacMD5(v2Hash, append(c.Challenge[:], clientChallenge[:]...)), clientChallenge[:]...)
+	}
+
+	flags := negotiateFlags & c.Flags
+	flags |= negotiateUnicode | negotiateNTLM
+	flags &^= negotiateOEM
+
+	fields := [][]byte{
+		lmResponse,
+		ntResponse,
+		encodeUTF16(domain),
+		encodeUTF16(user),
+		// the workstation
+		nil,
+		// the encrypted random session key
+		nil,
+	}
+
+	const headerLen = 64
+	b := make([]byte, headerLen)
+	copy(b, signature)
+	binary.LittleEndian.PutUint32(b[8:], 3)
+	offset := headerLen
+	for i, field := range fields {
+		binary.LittleEndian.PutUint16(b[12+i*8:], uint16(len(field)))
+		binary.LittleEndian.PutUint16(b[14+i*8:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(b[16+i*8:], uint32(offset))
+		offset += len(field)
+	}
+	binary.LittleEndian.PutUint32(b[60:], flags)
+	for _, field := range fields {
+		b = append(b, field...)
+	}
+
+	return b, nil
+}
+
+func hmacMD5(key, data []byte) []byte {
+	h := hmac.New(md5.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func encodeUTF16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, len(u)*2)
+	for i, v := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], v)
+	}
+	return b
+}
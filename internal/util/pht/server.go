@@ -16,8 +16,8 @@ import (
 	"sync"
 	"time"
 
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/internal/bufpool"
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
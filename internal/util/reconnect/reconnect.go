@@ -0,0 +1,235 @@
+// Package reconnect implements the reconnection policy of the dialers maintaining the persistent sessions,
+// the failed session establishments are retried with the exponential backoff and jitter,
+// the reconnection is suspended for the max delay after the max retries.
+package reconnect
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyReconnect  = "reconnect"
+	MDKeyMinDelay   = "reconnect.minDelay"
+	MDKeyMaxDelay   = "reconnect.maxDelay"
+	MDKeyFactor     = "reconnect.factor"
+	MDKeyJitter     = "reconnect.jitter"
+	MDKeyMaxRetries = "reconnect.maxRetries"
+)
+
+const (
+	defaultMinDelay = time.Second
+	defaultMaxDelay = 30 * time.Second
+	defaultFactor   = 2
+	defaultJitter   = 0.2
+)
+
+// Policy is the parameters of the reconnection.
+type Policy struct {
+	// the delay after the first failure.
+	MinDelay time.Duration
+	// the upper bound of the delay.
+	MaxDelay time.Duration
+	// the delay is multiplied by Factor after each failure.
+	Factor float64
+	// the delay is randomized by ±Jitter of itself, in the range [0, 1].
+	Jitter float64
+	// the consecutive failures before the reconnection is suspended, zero means unlimited.
+	MaxRetries int
+}
+
+// ParsePolicy parses the reconnection policy from metadata, nil is returned if the reconnection policy is disabled.
+func ParsePolicy(md mdata.Metadata) *Policy {
+	if md == nil || !mdutil.GetBool(md, MDKeyReconnect) {
+		return nil
+	}
+
+	p := &Policy{
+		MinDelay:   mdutil.GetDuration(md, MDKeyMinDelay),
+		MaxDelay:   mdutil.GetDuration(md, MDKeyMaxDelay),
+		Factor:     mdutil.GetFloat(md, MDKeyFactor),
+		Jitter:     defaultJitter,
+		MaxRetries: mdutil.GetInt(md, MDKeyMaxRetries),
+	}
+	if md.IsExists(MDKeyJitter) {
+		p.Jitter = mdutil.GetFloat(md, MDKeyJitter)
+	}
+	if p.MinDelay <= 0 {
+		p.MinDelay = defaultMinDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultMaxDelay
+	}
+	if p.MaxDelay < p.MinDelay {
+		p.MaxDelay = p.MinDelay
+	}
+	if p.Factor < 1 {
+		p.Factor = defaultFactor
+	}
+	p.Jitter = math.Min(math.Max(p.Jitter, 0), 1)
+	return p
+}
+
+// Delay returns the delay after the n-th consecutive failure.
+func (p *Policy) Delay(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	d := math.Min(float64(p.MinDelay)*math.Pow(p.Factor, float64(n-1)), float64(p.MaxDelay))
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// RetryFunc is called after a failure, the next attempt is allowed after delay.
+type RetryFunc func(key string, failures int, delay time.Duration, err error)
+
+// GiveUpFunc is called when the failures reach the max retries.
+type GiveUpFunc func(key string, failures int, err error)
+
+type options struct {
+	onRetry  RetryFunc
+	onGiveUp GiveUpFunc
+	logger   logger.Logger
+}
+
+type Option func(opts *options)
+
+func RetryOption(fn RetryFunc) Option {
+	return func(opts *options) {
+		opts.onRetry = fn
+	}
+}
+
+// GiveUpOption sets the alert hook of the suspended reconnection.
+func GiveUpOption(fn GiveUpFunc) Option {
+	return func(opts *options) {
+		opts.onGiveUp = fn
+	}
+}
+
+func LoggerOption(logger logger.Logger) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
+}
+
+type state struct {
+	failures  int
+	next      time.Time
+	suspended bool
+}
+
+// Manager tracks the session establishments to the servers by key.
+// The methods of a nil Manager are no-ops, so the dialers can use it regardless of the policy.
+type Manager struct {
+	policy  Policy
+	states  map[string]*state
+	mu      sync.Mutex
+	options options
+}
+
+// NewManager creates a Manager of the policy, nil is returned if policy is nil.
+func NewManager(policy *Policy, opts ...Option) *Manager {
+	if policy == nil {
+		return nil
+	}
+
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Manager{
+		policy:  *policy,
+		states:  make(map[string]*state),
+		options: options,
+	}
+}
+
+// Allow reports whether a session establishment to key is allowed now,
+// the attempts are rejected in the backoff of the previous failure.
+func (m *Manager) Allow(key string) error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.states[key]
+	if st == nil {
+		return nil
+	}
+	if wait := time.Until(st.next); wait > 0 {
+		if st.suspended {
+			return fmt.Errorf("reconnect: %s is suspended after %d failures, retry in %s", key, st.failures, wait.Round(time.Millisecond))
+		}
+		return fmt.Errorf("reconnect: %s is in backoff, retry in %s", key, wait.Round(time.Millisecond))
+	}
+	if st.suspended {
+		// the suspension is over, start over again.
+		delete(m.states, key)
+	}
+	return nil
+}
+
+// Failure records a failed session establishment to key.
+func (m *Manager) Failure(key string, err error) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	st := m.states[key]
+	if st == nil {
+		st = &state{}
+		m.states[key] = st
+	}
+	st.failures++
+	failures := st.failures
+
+	if m.policy.MaxRetries > 0 && failures >= m.policy.MaxRetries {
+		st.suspended = true
+		st.next = time.Now().Add(m.policy.MaxDelay)
+		m.mu.Unlock()
+
+		if m.options.logger != nil {
+			m.options.logger.Errorf("reconnect: %s failed %d times, suspended for %s: %v", key, failures, m.policy.MaxDelay, err)
+		}
+		if m.options.onGiveUp != nil {
+			m.options.onGiveUp(key, failures, err)
+		}
+		return
+	}
+
+	delay := m.policy.Delay(failures)
+	st.next = time.Now().Add(delay)
+	m.mu.Unlock()
+
+	if m.options.logger != nil {
+		m.options.logger.Warnf("reconnect: %s failed %d times, retry in %s: %v", key, failures, delay.Round(time.Millisecond), err)
+	}
+	if m.options.onRetry != nil {
+		m.options.onRetry(key, failures, delay, err)
+	}
+}
+
+// Success records a successful session establishment to key, the backoff is reset.
+func (m *Manager) Success(key string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.states, key)
+}
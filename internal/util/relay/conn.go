@@ -2,11 +2,13 @@ package relay
 
 import (
 	"bytes"
+	"fmt"
 	"net"
 
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/gosocks5"
 	"github.com/go-gost/relay"
+	xerrors "github.com/go-gost/x/errors"
+	"github.com/go-gost/x/internal/bufpool"
 )
 
 func StatusText(code uint8) string {
@@ -32,6 +34,21 @@ func StatusText(code uint8) string {
 	}
 }
 
+// StatusError returns the error of the failed status.
+func StatusError(code uint8) error {
+	err := fmt.Errorf("%d %s", code, StatusText(code))
+	switch code {
+	case relay.StatusUnauthorized:
+		return xerrors.Wrap(xerrors.KindAuth, err)
+	case relay.StatusForbidden:
+		return xerrors.Wrap(xerrors.KindBypass, err)
+	case relay.StatusTimeout:
+		return xerrors.Wrap(xerrors.KindTimeout, err)
+	default:
+		return err
+	}
+}
+
 type udpTunConn struct {
 	net.Conn
 	taddr net.Addr
@@ -0,0 +1,257 @@
+// Package rewrite implements the rewriting of the bodies of the intercepted HTTP responses.
+package rewrite
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyBody         = "rewrite.body"
+	MDKeyBodyTemplate = "rewrite.body.template"
+	MDKeyBodyTypes    = "rewrite.body.types"
+	MDKeyBodyMaxSize  = "rewrite.body.maxSize"
+)
+
+const (
+	defaultMaxSize = 10 * 1024 * 1024
+	readBufferSize = 32 * 1024
+)
+
+var (
+	defaultTypes = []string{"text/html"}
+)
+
+// BodyRewriter rewrites the bodies of the responses of the content types,
+// by the template replacing the whole body and then the find/replace pairs on the stream.
+// The gzip encoded bodies are decoded and re-encoded transparently.
+type BodyRewriter struct {
+	replacements [][2][]byte
+	template     *template.Template
+	types        []string
+	maxSize      int64
+}
+
+// TemplateData is the data of the body template.
+type TemplateData struct {
+	Method string
+	Host   string
+	Path   string
+	Query  string
+	Status int
+	// the original body, it is empty if the body exceeds the max size.
+	Body string
+}
+
+// ParseBodyRewriter parses the body rewriter from metadata, nil is returned if there is no rewriting.
+func ParseBodyRewriter(md mdata.Metadata) (*BodyRewriter, error) {
+	rw := &BodyRewriter{
+		types:   mdutil.GetStrings(md, MDKeyBodyTypes),
+		maxSize: int64(mdutil.GetInt(md, MDKeyBodyMaxSize)),
+	}
+	for find, replace := range mdutil.GetStringMapString(md, MDKeyBody) {
+		if find != "" {
+			rw.replacements = append(rw.replacements, [2][]byte{[]byte(find), []byte(replace)})
+		}
+	}
+	// the longer patterns take precedence.
+	sort.Slice(rw.replacements, func(i, j int) bool {
+		a, b := rw.replacements[i][0], rw.replacements[j][0]
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return bytes.Compare(a, b) < 0
+	})
+
+	if file := mdutil.GetString(md, MDKeyBodyTemplate); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if rw.template, err = template.New("body").Parse(string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rw.replacements) == 0 && rw.template == nil {
+		return nil, nil
+	}
+	if len(rw.types) == 0 {
+		rw.types = defaultTypes
+	}
+	if rw.maxSize <= 0 {
+		rw.maxSize = defaultMaxSize
+	}
+	return rw, nil
+}
+
+// PrepareRequest limits the accepted encodings of the request to the ones the rewriter can decode.
+func (rw *BodyRewriter) PrepareRequest(req *http.Request) {
+	if rw == nil {
+		return
+	}
+	if v := req.Header.Get("Accept-Encoding"); v != "" {
+		if strings.Contains(strings.ToLower(v), "gzip") {
+			req.Header.Set("Accept-Encoding", "gzip")
+		} else {
+			req.Header.Del("Accept-Encoding")
+		}
+	}
+}
+
+// Rewrite rewrites the body of the response, the response is unmodified
+// if the content type does not match or the body exceeds the max size.
+func (rw *BodyRewriter) Rewrite(res *http.Response) error {
+	if rw == nil || res == nil || res.Body == nil || res.Body == http.NoBody {
+		return nil
+	}
+	if !rw.matchType(res.Header.Get("Content-Type")) || res.ContentLength > rw.maxSize {
+		return nil
+	}
+
+	var r io.Reader
+	gzipped := false
+	switch strings.ToLower(res.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		r = res.Body
+	case "gzip":
+		zr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return err
+		}
+		r, gzipped = zr, true
+	default:
+		// the encoding is not supported.
+		return nil
+	}
+
+	if rw.template != nil {
+		body, err := io.ReadAll(io.LimitReader(r, rw.maxSize+1))
+		if err != nil {
+			return err
+		}
+		if int64(len(body)) > rw.maxSize {
+			r = io.MultiReader(bytes.NewReader(body), r)
+		} else {
+			data := TemplateData{
+				Status: res.StatusCode,
+				Body:   string(body),
+			}
+			if req := res.Request; req != nil {
+				data.Method = req.Method
+				data.Host = req.Host
+				if req.URL != nil {
+					data.Path = req.URL.Path
+					data.Query = req.URL.RawQuery
+				}
+			}
+			var buf bytes.Buffer
+			if err := rw.template.Execute(&buf, &data); err != nil {
+				return err
+			}
+			r = &buf
+		}
+	}
+
+	if len(rw.replacements) > 0 {
+		r = &replaceReader{r: r, replacements: rw.replacements}
+	}
+
+	if gzipped {
+		pr, pw := io.Pipe()
+		go func(r io.Reader) {
+			zw := gzip.NewWriter(pw)
+			_, err := io.Copy(zw, r)
+			if err == nil {
+				err = zw.Close()
+			}
+			pw.CloseWithError(err)
+		}(r)
+		r = pr
+	}
+
+	res.Body = &readCloser{Reader: r, Closer: res.Body}
+	res.ContentLength = -1
+	res.Header.Del("Content-Length")
+	res.TransferEncoding = []string{"chunked"}
+	return nil
+}
+
+func (rw *BodyRewriter) matchType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, t := range rw.types {
+		if t == "*" || strings.EqualFold(t, mediaType) ||
+			strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.ToLower(t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// replaceReader replaces the patterns in the stream,
+// the lookahead of the longest pattern is buffered for the matches across the reads.
+type replaceReader struct {
+	r            io.Reader
+	replacements [][2][]byte
+	in           []byte
+	out          bytes.Buffer
+	err          error
+}
+
+func (r *replaceReader) Read(b []byte) (int, error) {
+	for r.out.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		buf := make([]byte, readBufferSize)
+		n, err := r.r.Read(buf)
+		r.in = append(r.in, buf[:n]...)
+		r.err = err
+		r.replace(err != nil)
+	}
+	return r.out.Read(b)
+}
+
+// replace moves the input to the output with the patterns replaced,
+// the tail which may be the prefix of a pattern is kept unless it is the end of the stream.
+func (r *replaceReader) replace(eof bool) {
+	keep := 0
+	if !eof {
+		// the longest pattern is the first.
+		keep = len(r.replacements[0][0]) - 1
+	}
+
+	i := 0
+	for i < len(r.in)-keep {
+		matched := false
+		for _, rp := range r.replacements {
+			if bytes.HasPrefix(r.in[i:], rp[0]) {
+				r.out.Write(rp[1])
+				i += len(rp[0])
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r.out.WriteByte(r.in[i])
+			i++
+		}
+	}
+	r.in = append(r.in[:0], r.in[i:]...)
+}
@@ -0,0 +1,165 @@
+// Package schedule implements the weekly time windows, e.g. mon-fri 08:00-18:00.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minutesPerDay = 24 * 60
+	// the schedule repeats weekly, so the changes are searched in a week and a day.
+	searchRange = 8 * minutesPerDay
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// window is the time of the day on the days of the week, the window ending before it starts ends on the next day.
+type window struct {
+	days  [7]bool
+	start int
+	end   int
+}
+
+func (w *window) active(wd time.Weekday, m int) bool {
+	if w.start < w.end {
+		return w.days[wd] && m >= w.start && m < w.end
+	}
+	// the window across midnight, or the whole day if start equals end.
+	return (w.days[wd] && m >= w.start) || (w.days[(wd+6)%7] && m < w.end)
+}
+
+// Schedule is a set of the weekly time windows.
+type Schedule struct {
+	windows []window
+	loc     *time.Location
+}
+
+// Parse parses the windows in the form of [days] HH:MM-HH:MM, the days are a comma separated list of
+// the weekdays (mon) or ranges (mon-fri), * or omitted for every day, e.g. mon-fri 08:00-18:00, sat,sun 10:00-12:00.
+// The times are in the timezone, the local timezone is used if it is empty.
+func Parse(windows []string, timezone string) (*Schedule, error) {
+	s := &Schedule{
+		loc: time.Local,
+	}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, err
+		}
+		s.loc = loc
+	}
+
+	for _, v := range windows {
+		w, err := parseWindow(v)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: invalid window %q: %w", v, err)
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, nil
+}
+
+func parseWindow(s string) (w window, err error) {
+	fields := strings.Fields(strings.ToLower(s))
+	var days, times string
+	switch len(fields) {
+	case 1:
+		days, times = "*", fields[0]
+	case 2:
+		days, times = fields[0], fields[1]
+	default:
+		err = fmt.Errorf("expect [days] HH:MM-HH:MM")
+		return
+	}
+
+	if days == "*" {
+		for i := range w.days {
+			w.days[i] = true
+		}
+	} else {
+		for _, d := range strings.Split(days, ",") {
+			from, to, ok := strings.Cut(d, "-")
+			if !ok {
+				to = from
+			}
+			fd, ok1 := weekdays[from]
+			td, ok2 := weekdays[to]
+			if !ok1 || !ok2 {
+				err = fmt.Errorf("unknown day %s", d)
+				return
+			}
+			for i := fd; ; i = (i + 1) % 7 {
+				w.days[i] = true
+				if i == td {
+					break
+				}
+			}
+		}
+	}
+
+	start, end, ok := strings.Cut(times, "-")
+	if !ok {
+		err = fmt.Errorf("expect HH:MM-HH:MM")
+		return
+	}
+	if w.start, err = parseTime(start); err != nil {
+		return
+	}
+	w.end, err = parseTime(end)
+	return
+}
+
+func parseTime(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %s", s)
+	}
+	h, err := strconv.Atoi(hh)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %s", s)
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %s", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h*60+m > minutesPerDay {
+		return 0, fmt.Errorf("invalid time %s", s)
+	}
+	return (h*60 + m) % minutesPerDay, nil
+}
+
+// Active reports whether t is in any of the windows.
+func (s *Schedule) Active(t time.Time) bool {
+	t = t.In(s.loc)
+	m := t.Hour()*60 + t.Minute()
+	for i := range s.windows {
+		if s.windows[i].active(t.Weekday(), m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the time of the next change of Active after t, the zero time is returned if it never changes.
+func (s *Schedule) Next(t time.Time) time.Time {
+	active := s.Active(t)
+	next := t.Truncate(time.Minute)
+	for i := 0; i < searchRange; i++ {
+		next = next.Add(time.Minute)
+		if s.Active(next) != active {
+			return next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,160 @@
+package shaping
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/metadata"
+)
+
+const (
+	// data length | padding length
+	recordHeaderLength = 4
+	maxRecordPayload   = 16*1024 - recordHeaderLength
+)
+
+type conn struct {
+	net.Conn
+	profile *Profile
+
+	rbuf []byte
+	rmu  sync.Mutex
+
+	wmu       sync.Mutex
+	lastWrite time.Time
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Conn wraps the connection c with the traffic shaping of the profile, c is returned if profile is nil.
+func Conn(c net.Conn, profile *Profile) net.Conn {
+	if profile == nil {
+		return c
+	}
+
+	cc := &conn{
+		Conn:      c,
+		profile:   profile,
+		lastWrite: time.Now(),
+		closed:    make(chan struct{}),
+	}
+	if profile.DummyInterval > 0 {
+		go cc.dummyLoop()
+	}
+	return cc
+}
+
+func (c *conn) Read(b []byte) (n int, err error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	for len(c.rbuf) == 0 {
+		var header [recordHeaderLength]byte
+		if _, err = io.ReadFull(c.Conn, header[:]); err != nil {
+			return
+		}
+		dlen := int(binary.BigEndian.Uint16(header[:2]))
+		plen := int(binary.BigEndian.Uint16(header[2:]))
+
+		record := make([]byte, dlen+plen)
+		if _, err = io.ReadFull(c.Conn, record); err != nil {
+			return
+		}
+		// the dummy record has no data.
+		c.rbuf = record[:dlen]
+	}
+
+	n = copy(b, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	return
+}
+
+func (c *conn) Write(b []byte) (n int, err error) {
+	if c.profile.Jitter > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(c.profile.Jitter))))
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	var buf []byte
+	for p := b; len(p) > 0; {
+		chunk := p
+		if len(chunk) > maxRecordPayload {
+			chunk = chunk[:maxRecordPayload]
+		}
+		buf = c.appendRecord(buf, chunk)
+		p = p[len(chunk):]
+	}
+
+	c.lastWrite = time.Now()
+	if _, err = c.Conn.Write(buf); err != nil {
+		return
+	}
+	return len(b), nil
+}
+
+func (c *conn) appendRecord(b []byte, data []byte) []byte {
+	padLen := 0
+	if bs := c.profile.BlockSize; bs > 0 {
+		if r := len(data) % bs; r > 0 || len(data) == 0 {
+			padLen = bs - r
+		}
+	}
+	if c.profile.MaxPadding > 0 {
+		padLen += rand.Intn(c.profile.MaxPadding + 1)
+	}
+	if len(data)+padLen > maxRecordPayload {
+		padLen = maxRecordPayload - len(data)
+	}
+
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	b = binary.BigEndian.AppendUint16(b, uint16(padLen))
+	b = append(b, data...)
+	return append(b, make([]byte, padLen)...)
+}
+
+// dummyLoop sends the dummy records at the random intervals while no data is written.
+func (c *conn) dummyLoop() {
+	interval := c.profile.DummyInterval
+	for {
+		// uniformly distributed in [interval/2, interval*3/2).
+		d := interval/2 + time.Duration(rand.Int63n(int64(interval)))
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-c.closed:
+			timer.Stop()
+			return
+		}
+
+		c.wmu.Lock()
+		var err error
+		if time.Since(c.lastWrite) >= interval/2 {
+			_, err = c.Conn.Write(c.appendRecord(nil, nil))
+			c.lastWrite = time.Now()
+		}
+		c.wmu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.Conn.Close()
+}
+
+func (c *conn) Metadata() metadata.Metadata {
+	if md, ok := c.Conn.(metadata.Metadatable); ok {
+		return md.Metadata()
+	}
+	return nil
+}
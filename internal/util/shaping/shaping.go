@@ -0,0 +1,93 @@
+// Package shaping implements the traffic shaping of the stream transports against the traffic analysis,
+// the data is carried in the padded records, the writes are delayed by a random jitter
+// and the dummy records are injected while the connection is idle.
+// Both ends of the connection must use the shaping, the parameters of the profiles may differ.
+package shaping
+
+import (
+	"fmt"
+	"time"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	MDKeyProfile   = "shaping"
+	MDKeyBlockSize = "shaping.blockSize"
+	MDKeyPadding   = "shaping.padding"
+	MDKeyJitter    = "shaping.jitter"
+	MDKeyDummy     = "shaping.dummy"
+)
+
+// Profile is the parameters of the traffic shaping.
+type Profile struct {
+	// the records are padded to the multiple of BlockSize.
+	BlockSize int
+	// the maximum random padding added to each record.
+	MaxPadding int
+	// the maximum random delay before each write.
+	Jitter time.Duration
+	// the mean interval of the dummy records sent while idle, zero disables the dummy traffic.
+	DummyInterval time.Duration
+}
+
+var profiles = map[string]Profile{
+	"light": {
+		BlockSize:  64,
+		MaxPadding: 64,
+	},
+	"medium": {
+		BlockSize:     256,
+		MaxPadding:    256,
+		Jitter:        10 * time.Millisecond,
+		DummyInterval: 5 * time.Second,
+	},
+	"heavy": {
+		BlockSize:     1024,
+		MaxPadding:    1024,
+		Jitter:        50 * time.Millisecond,
+		DummyInterval: time.Second,
+	},
+}
+
+// ParseProfile parses the shaping profile from metadata, nil is returned if the shaping is disabled.
+// The predefined profiles are light, medium and heavy, the parameters can be overridden individually.
+func ParseProfile(md mdata.Metadata) (*Profile, error) {
+	if md == nil {
+		return nil, nil
+	}
+
+	var p Profile
+	switch name := mdutil.GetString(md, MDKeyProfile); name {
+	case "", "none":
+	case "custom":
+	default:
+		v, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("shaping: unknown profile %s", name)
+		}
+		p = v
+	}
+
+	if md.IsExists(MDKeyBlockSize) {
+		p.BlockSize = mdutil.GetInt(md, MDKeyBlockSize)
+	}
+	if md.IsExists(MDKeyPadding) {
+		p.MaxPadding = mdutil.GetInt(md, MDKeyPadding)
+	}
+	if md.IsExists(MDKeyJitter) {
+		p.Jitter = mdutil.GetDuration(md, MDKeyJitter)
+	}
+	if md.IsExists(MDKeyDummy) {
+		p.DummyInterval = mdutil.GetDuration(md, MDKeyDummy)
+	}
+
+	if p.BlockSize > maxRecordPayload {
+		p.BlockSize = maxRecordPayload
+	}
+	if p.BlockSize <= 0 && p.MaxPadding <= 0 && p.Jitter <= 0 && p.DummyInterval <= 0 {
+		return nil, nil
+	}
+	return &p, nil
+}
@@ -0,0 +1,413 @@
+// Package sip implements the SIP ALG of the UDP forwarding,
+// which rewrites the SDP media addresses of the SIP messages and relays the RTP/RTCP streams.
+package sip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/common/bufpool"
+	"github.com/go-gost/core/logger"
+)
+
+const (
+	defaultMediaTimeout = 60 * time.Second
+	maxDatagramSize     = 65535
+	maxPortTrials       = 16
+)
+
+type options struct {
+	advertise    net.IP
+	mediaTimeout time.Duration
+	logger       logger.Logger
+}
+
+type Option func(opts *options)
+
+// AdvertiseOption sets the address of the proxy in the SDP sent to the clients,
+// the local address of the client connection is used by default.
+func AdvertiseOption(ip net.IP) Option {
+	return func(opts *options) {
+		opts.advertise = ip
+	}
+}
+
+// MediaTimeoutOption sets the idle timeout of the media relays.
+func MediaTimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.mediaTimeout = timeout
+	}
+}
+
+func LoggerOption(logger logger.Logger) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
+}
+
+// Relay relays the SIP datagrams between the client conn and the server cc.
+//
+// The media addresses in the SDP bodies are rewritten to the media relays of the proxy,
+// a pair of RTP/RTCP relays is opened for each media stream, on the server side for the media of the client
+// and on the client side for the media of the server.
+// The media relays send the datagrams from the proxy directly, so the server must be reached without a chain.
+func Relay(ctx context.Context, conn net.Conn, cc net.Conn, opts ...Option) error {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.mediaTimeout <= 0 {
+		options.mediaTimeout = defaultMediaTimeout
+	}
+
+	clientIP := options.advertise
+	if clientIP == nil {
+		clientIP = localIP(conn)
+	}
+	a := &alg{
+		clientIP: clientIP,
+		serverIP: localIP(cc),
+		relays:   make(map[string]*mediaRelay),
+		options:  options,
+	}
+	defer a.close()
+
+	errc := make(chan error, 2)
+	go func() {
+		errc <- a.relay(conn, cc, a.serverIP)
+	}()
+	go func() {
+		errc <- a.relay(cc, conn, a.clientIP)
+	}()
+
+	err := <-errc
+	conn.Close()
+	cc.Close()
+	<-errc
+
+	if errors.Is(err, net.ErrClosed) {
+		err = nil
+	}
+	return err
+}
+
+// localIP returns the local IP address of the connection to the peer.
+func localIP(c net.Conn) net.IP {
+	if addr, _ := c.LocalAddr().(*net.UDPAddr); addr != nil && !addr.IP.IsUnspecified() {
+		return addr.IP
+	}
+	// the listener is bound on the unspecified address, the outbound address to the peer is used.
+	if addr, _ := c.RemoteAddr().(*net.UDPAddr); addr != nil {
+		if uc, err := net.DialUDP("udp", nil, addr); err == nil {
+			defer uc.Close()
+			return uc.LocalAddr().(*net.UDPAddr).IP
+		}
+	}
+	return nil
+}
+
+type alg struct {
+	clientIP net.IP
+	serverIP net.IP
+	// the media relays keyed by the media address in the SDP.
+	relays  map[string]*mediaRelay
+	mu      sync.Mutex
+	options options
+}
+
+// relay relays the datagrams from src to dst, the SDP is rewritten to the media relays on ip.
+func (a *alg) relay(src, dst net.Conn, ip net.IP) error {
+	b := bufpool.Get(maxDatagramSize)
+	defer bufpool.Put(b)
+
+	for {
+		n, err := src.Read(b)
+		if err != nil {
+			return err
+		}
+
+		msg := b[:n]
+		if ip != nil {
+			if v, err := a.rewrite(msg, ip); err != nil {
+				a.logf("rewrite SDP: %v", err)
+			} else {
+				msg = v
+			}
+		}
+
+		if _, err := dst.Write(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// rewrite rewrites the SDP body of the SIP message, the media endpoints are replaced
+// with the media relays on ip, the message is returned unmodified if it has no SDP.
+func (a *alg) rewrite(msg []byte, ip net.IP) ([]byte, error) {
+	i := bytes.Index(msg, []byte("\r\n\r\n"))
+	if i < 0 {
+		return msg, nil
+	}
+	header, body := msg[:i+2], msg[i+4:]
+	if len(body) == 0 || !isSDP(header) {
+		return msg, nil
+	}
+
+	sdp, err := a.rewriteSDP(body, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(header, []byte("\r\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		name, _, _ := bytes.Cut(line, []byte(":"))
+		name = bytes.ToLower(bytes.TrimSpace(name))
+		if bytes.Equal(name, []byte("content-length")) || bytes.Equal(name, []byte("l")) {
+			fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(sdp))
+			continue
+		}
+		buf.Write(line)
+	}
+	buf.WriteString("\r\n")
+	buf.Write(sdp)
+	return buf.Bytes(), nil
+}
+
+// isSDP reports whether the Content-Type of the header is SDP.
+func isSDP(header []byte) bool {
+	for _, line := range bytes.Split(header, []byte("\r\n")) {
+		name, value, ok := bytes.Cut(line, []byte(":"))
+		if !ok {
+			continue
+		}
+		name = bytes.ToLower(bytes.TrimSpace(name))
+		if bytes.Equal(name, []byte("content-type")) || bytes.Equal(name, []byte("c")) {
+			return bytes.HasPrefix(bytes.ToLower(bytes.TrimSpace(value)), []byte("application/sdp"))
+		}
+	}
+	return false
+}
+
+func (a *alg) rewriteSDP(body []byte, ip net.IP) ([]byte, error) {
+	lines := bytes.Split(body, []byte("\n"))
+	for i := range lines {
+		lines[i] = bytes.TrimSuffix(lines[i], []byte("\r"))
+	}
+
+	// the connection address of the session and the media.
+	var sessionHost string
+	mediaHost := make(map[int]string)
+	media := -1
+	for i, line := range lines {
+		switch {
+		case bytes.HasPrefix(line, []byte("m=")):
+			media = i
+		case bytes.HasPrefix(line, []byte("c=")):
+			fields := bytes.Fields(line[2:])
+			if len(fields) < 3 {
+				continue
+			}
+			host, _, _ := bytes.Cut(fields[2], []byte("/"))
+			if media < 0 {
+				sessionHost = string(host)
+			} else {
+				mediaHost[media] = string(host)
+			}
+		}
+	}
+
+	addrType := "IP4"
+	if ip.To4() == nil {
+		addrType = "IP6"
+	}
+
+	var buf bytes.Buffer
+	for i, line := range lines {
+		switch {
+		case bytes.HasPrefix(line, []byte("m=")):
+			fields := bytes.Fields(line[2:])
+			if len(fields) < 2 {
+				break
+			}
+			port, err := strconv.Atoi(string(fields[1]))
+			if err != nil || port == 0 {
+				break
+			}
+			host := sessionHost
+			if h, ok := mediaHost[i]; ok {
+				host = h
+			}
+			if host == "" {
+				break
+			}
+			r, err := a.getRelay(net.JoinHostPort(host, strconv.Itoa(port)), ip)
+			if err != nil {
+				return nil, err
+			}
+			fields[1] = []byte(strconv.Itoa(r.port()))
+			line = append([]byte("m="), bytes.Join(fields, []byte(" "))...)
+
+		case bytes.HasPrefix(line, []byte("c=")):
+			fields := bytes.Fields(line[2:])
+			if len(fields) < 3 {
+				break
+			}
+			line = []byte(fmt.Sprintf("c=%s %s %s", fields[0], addrType, ip))
+		}
+
+		buf.Write(line)
+		if i < len(lines)-1 {
+			buf.WriteString("\r\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// getRelay returns the media relay on ip to the media endpoint addr, it is created if not exists.
+func (a *alg) getRelay(addr string, ip net.IP) (*mediaRelay, error) {
+	key := addr + "@" + ip.String()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if r := a.relays[key]; r != nil && !r.isClosed() {
+		return r, nil
+	}
+
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newMediaRelay(ip, raddr, a.options.mediaTimeout)
+	if err != nil {
+		return nil, err
+	}
+	a.relays[key] = r
+
+	if log := a.options.logger; log != nil {
+		log.Debugf("sip: media relay %d <-> %s", r.port(), raddr)
+	}
+	return r, nil
+}
+
+func (a *alg) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, r := range a.relays {
+		r.Close()
+	}
+	a.relays = nil
+}
+
+func (a *alg) logf(format string, args ...any) {
+	if a.options.logger != nil {
+		a.options.logger.Warnf("sip: "+format, args...)
+	}
+}
+
+// mediaRelay relays the RTP and the RTCP (on the next port) datagrams to the media endpoint,
+// the datagrams from the endpoint are sent back to the last peer.
+type mediaRelay struct {
+	rtp    *net.UDPConn
+	rtcp   *net.UDPConn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newMediaRelay(ip net.IP, raddr *net.UDPAddr, timeout time.Duration) (*mediaRelay, error) {
+	rtp, rtcp, err := listenPair(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &mediaRelay{
+		rtp:    rtp,
+		rtcp:   rtcp,
+		closed: make(chan struct{}),
+	}
+	go r.run(rtp, raddr, timeout)
+	if rtcp != nil {
+		go r.run(rtcp, &net.UDPAddr{IP: raddr.IP, Port: raddr.Port + 1, Zone: raddr.Zone}, timeout)
+	}
+	return r, nil
+}
+
+// listenPair listens on an even port for RTP and the next port for RTCP,
+// the RTCP is not relayed if no pair is available.
+func listenPair(ip net.IP) (rtp, rtcp *net.UDPConn, err error) {
+	for i := 0; i < maxPortTrials; i++ {
+		if rtp, err = net.ListenUDP("udp", &net.UDPAddr{IP: ip}); err != nil {
+			return
+		}
+		port := rtp.LocalAddr().(*net.UDPAddr).Port
+		if port%2 == 0 {
+			if rtcp, err = net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: port + 1}); err == nil {
+				return
+			}
+		}
+		rtp.Close()
+	}
+	rtp, err = net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+	return
+}
+
+func (r *mediaRelay) run(pc *net.UDPConn, endpoint *net.UDPAddr, timeout time.Duration) {
+	defer r.Close()
+
+	b := bufpool.Get(maxDatagramSize)
+	defer bufpool.Put(b)
+
+	var peer *net.UDPAddr
+	for {
+		pc.SetReadDeadline(time.Now().Add(timeout))
+		n, addr, err := pc.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+
+		dst := endpoint
+		if addr.IP.Equal(endpoint.IP) && addr.Port == endpoint.Port {
+			if peer == nil {
+				continue
+			}
+			dst = peer
+		} else {
+			peer = addr
+		}
+		pc.WriteToUDP(b[:n], dst)
+	}
+}
+
+func (r *mediaRelay) port() int {
+	return r.rtp.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (r *mediaRelay) isClosed() bool {
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *mediaRelay) Close() error {
+	r.once.Do(func() {
+		close(r.closed)
+		r.rtp.Close()
+		if r.rtcp != nil {
+			r.rtcp.Close()
+		}
+	})
+	return nil
+}
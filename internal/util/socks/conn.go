@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"net"
 
-	"github.com/go-gost/core/common/bufpool"
 	"github.com/go-gost/gosocks5"
+	"github.com/go-gost/x/internal/bufpool"
 )
 
 type udpTunConn struct {
@@ -0,0 +1,156 @@
+package sysproxy
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	ProxyDirect = "DIRECT"
+	// the HTTP proxy.
+	ProxyHTTP = "PROXY"
+	// the HTTP proxy over TLS.
+	ProxyHTTPS  = "HTTPS"
+	ProxySOCKS4 = "SOCKS4"
+	ProxySOCKS5 = "SOCKS5"
+)
+
+// Proxy is the upstream proxy of the proxy settings, e.g. PROXY 10.0.0.1:8080.
+type Proxy struct {
+	Type string
+	Addr string
+}
+
+func (p Proxy) String() string {
+	if p.Type == ProxyDirect {
+		return p.Type
+	}
+	return p.Type + " " + p.Addr
+}
+
+// parseProxyURL parses the proxy of the system settings, the proxy is a URL, e.g. socks5://10.0.0.1:1080,
+// or host:port of the proxy type typ.
+func parseProxyURL(s string, typ string) (Proxy, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Proxy{}, false
+	}
+	if !strings.Contains(s, "://") {
+		return Proxy{Type: typ, Addr: s}, true
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return Proxy{}, false
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks", "socks4", "socks5", "socks5h":
+			port = "1080"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	switch u.Scheme {
+	case "http":
+		return Proxy{Type: ProxyHTTP, Addr: addr}, true
+	case "https":
+		return Proxy{Type: ProxyHTTPS, Addr: addr}, true
+	case "socks4":
+		return Proxy{Type: ProxySOCKS4, Addr: addr}, true
+	case "socks", "socks5", "socks5h":
+		return Proxy{Type: ProxySOCKS5, Addr: addr}, true
+	}
+	return Proxy{}, false
+}
+
+// Settings is the static proxy settings of the system.
+type Settings struct {
+	// the URL of the proxy auto-config (PAC) file, it is not supported as it requires a JavaScript engine.
+	AutoConfigURL string
+	HTTP          string
+	HTTPS         string
+	SOCKS         string
+	// the hosts connected directly, the pattern can be a domain (example.com, .example.com),
+	// wildcard (*.example.com), CIDR, * for all hosts or <local> for the plain host names.
+	Bypass []string
+}
+
+// FindProxy returns the proxy of the settings for the url and host.
+func (s *Settings) FindProxy(ctx context.Context, url, host string) ([]Proxy, error) {
+	if s.bypass(host) {
+		return []Proxy{{Type: ProxyDirect}}, nil
+	}
+
+	candidates := []Proxy{{ProxyHTTP, s.HTTP}, {ProxySOCKS5, s.SOCKS}}
+	if strings.HasPrefix(url, "https://") {
+		candidates = []Proxy{{ProxyHTTP, s.HTTPS}, {ProxyHTTP, s.HTTP}, {ProxySOCKS5, s.SOCKS}}
+	}
+	for _, c := range candidates {
+		if p, ok := parseProxyURL(c.Addr, c.Type); ok {
+			return []Proxy{p}, nil
+		}
+	}
+	return []Proxy{{Type: ProxyDirect}}, nil
+}
+
+func (s *Settings) bypass(host string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, pattern := range s.Bypass {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		switch {
+		case pattern == "":
+		case pattern == "*":
+			return true
+		case pattern == "<local>":
+			if !strings.Contains(host, ".") {
+				return true
+			}
+		case strings.ContainsAny(pattern, "*?"):
+			if shExpMatch(host, pattern) {
+				return true
+			}
+		default:
+			if _, inet, err := net.ParseCIDR(pattern); err == nil {
+				if ip != nil && inet.Contains(ip) {
+					return true
+				}
+				continue
+			}
+			domain := strings.TrimPrefix(pattern, ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shExpMatch matches s against the shell expression exp with the wildcards * and ?.
+func shExpMatch(s, exp string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range exp {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(s)
+}
@@ -0,0 +1,152 @@
+package sysproxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+)
+
+var (
+	ErrAutoConfig = errors.New("sysproxy: the proxy auto-config (PAC) URL of the system settings is not supported")
+)
+
+type options struct {
+	period time.Duration
+	logger logger.Logger
+}
+
+type Option func(opts *options)
+
+func ReloadPeriodOption(period time.Duration) Option {
+	return func(opts *options) {
+		opts.period = period
+	}
+}
+
+func LoggerOption(logger logger.Logger) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
+}
+
+// Resolver finds the proxies of the destinations by the system proxy settings.
+type Resolver struct {
+	settings *Settings
+	// the error of the last loading, it is returned by FindProxy until the settings are loaded.
+	err        error
+	cancelFunc context.CancelFunc
+	options    options
+	mu         sync.RWMutex
+}
+
+func NewResolver(opts ...Option) *Resolver {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.logger == nil {
+		options.logger = logger.Default()
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	r := &Resolver{
+		cancelFunc: cancel,
+		options:    options,
+	}
+
+	if err := r.reload(ctx); err != nil {
+		options.logger.Warnf("reload: %v", err)
+	}
+	if r.options.period > 0 {
+		go r.periodReload(ctx)
+	}
+
+	return r
+}
+
+// FindProxy returns the proxies for the connection to the address host:port in the order to try,
+// an error is returned if the proxy settings are not loaded.
+func (r *Resolver) FindProxy(ctx context.Context, host, port string) ([]Proxy, error) {
+	r.mu.RLock()
+	settings, err := r.settings, r.err
+	r.mu.RUnlock()
+
+	if settings == nil {
+		if err == nil {
+			err = errors.New("sysproxy: no proxy settings")
+		}
+		return nil, err
+	}
+
+	// the URL of the connection is not known, it is guessed by the port as the browsers do for CONNECT.
+	u := "http://" + net.JoinHostPort(host, port) + "/"
+	switch port {
+	case "80", "":
+		u = "http://" + strings.TrimSuffix(net.JoinHostPort(host, "0"), ":0") + "/"
+	case "443":
+		u = "https://" + strings.TrimSuffix(net.JoinHostPort(host, "0"), ":0") + "/"
+	}
+	return settings.FindProxy(ctx, u, host)
+}
+
+func (r *Resolver) periodReload(ctx context.Context) error {
+	period := r.options.period
+	if period < time.Second {
+		period = time.Second
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.reload(ctx); err != nil {
+				r.options.logger.Warnf("reload: %v", err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// reload loads the system proxy settings, the previous settings are kept if it fails.
+func (r *Resolver) reload(ctx context.Context) error {
+	settings, err := r.load(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = err
+	if err != nil {
+		return err
+	}
+	r.settings = settings
+	return nil
+}
+
+func (r *Resolver) load(ctx context.Context) (*Settings, error) {
+	settings, err := SystemSettings()
+	if err != nil {
+		return nil, err
+	}
+	if settings.AutoConfigURL != "" {
+		r.options.logger.Debugf("system proxy: autoconfig=%s", settings.AutoConfigURL)
+		return nil, ErrAutoConfig
+	}
+
+	r.options.logger.Debugf("system proxy: http=%s, https=%s, socks=%s, bypass=%v",
+		settings.HTTP, settings.HTTPS, settings.SOCKS, settings.Bypass)
+	return settings, nil
+}
+
+func (r *Resolver) Close() error {
+	r.cancelFunc()
+	return nil
+}
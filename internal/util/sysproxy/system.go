@@ -0,0 +1,32 @@
+package sysproxy
+
+import (
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// SystemSettings returns the proxy settings of the system,
+// the settings of Windows (Internet Options) and macOS (Network Preferences) are read if set,
+// otherwise the environment variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used.
+func SystemSettings() (*Settings, error) {
+	settings, err := platformSettings()
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil && (settings.AutoConfigURL != "" || settings.HTTP != "" || settings.HTTPS != "" || settings.SOCKS != "") {
+		return settings, nil
+	}
+
+	env := httpproxy.FromEnvironment()
+	settings = &Settings{
+		HTTP:  env.HTTPProxy,
+		HTTPS: env.HTTPSProxy,
+	}
+	for _, s := range strings.Split(env.NoProxy, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			settings.Bypass = append(settings.Bypass, s)
+		}
+	}
+	return settings, nil
+}
@@ -0,0 +1,66 @@
+//go:build darwin
+
+package sysproxy
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// platformSettings reads the proxy settings of the Network Preferences by scutil --proxy.
+func platformSettings() (*Settings, error) {
+	out, err := exec.Command("scutil", "--proxy").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	settings := &Settings{}
+	inExceptions := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if inExceptions {
+			if line == "}" {
+				inExceptions = false
+				continue
+			}
+			// 0 : *.local
+			if _, v, found := strings.Cut(line, ":"); found {
+				settings.Bypass = append(settings.Bypass, strings.TrimSpace(v))
+			}
+			continue
+		}
+		k, v, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k == "ExceptionsList" {
+			inExceptions = true
+			continue
+		}
+		values[k] = v
+	}
+
+	if values["ProxyAutoConfigEnable"] == "1" {
+		settings.AutoConfigURL = values["ProxyAutoConfigURLString"]
+	}
+	if values["HTTPEnable"] == "1" {
+		settings.HTTP = net.JoinHostPort(values["HTTPProxy"], values["HTTPPort"])
+	}
+	if values["HTTPSEnable"] == "1" {
+		settings.HTTPS = net.JoinHostPort(values["HTTPSProxy"], values["HTTPSPort"])
+	}
+	if values["SOCKSEnable"] == "1" {
+		settings.SOCKS = net.JoinHostPort(values["SOCKSProxy"], values["SOCKSPort"])
+	}
+	if values["ExcludeSimpleHostnames"] == "1" {
+		settings.Bypass = append(settings.Bypass, "<local>")
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,7 @@
+//go:build !windows && !darwin
+
+package sysproxy
+
+func platformSettings() (*Settings, error) {
+	return nil, nil
+}
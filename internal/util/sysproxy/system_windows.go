@@ -0,0 +1,57 @@
+//go:build windows
+
+package sysproxy
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	internetSettingsKey = `Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+)
+
+// platformSettings reads the proxy settings of the Internet Options of the current user.
+func platformSettings() (*Settings, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	settings := &Settings{}
+	settings.AutoConfigURL, _, _ = k.GetStringValue("AutoConfigURL")
+
+	if enabled, _, _ := k.GetIntegerValue("ProxyEnable"); enabled == 0 {
+		return settings, nil
+	}
+
+	// the proxy server is host:port for all protocols, or http=host:port;https=host:port;socks=host:port.
+	server, _, _ := k.GetStringValue("ProxyServer")
+	for _, s := range strings.Split(server, ";") {
+		s = strings.TrimSpace(s)
+		proto, addr, found := strings.Cut(s, "=")
+		if !found {
+			settings.HTTP, settings.HTTPS = s, s
+			continue
+		}
+		switch strings.ToLower(proto) {
+		case "http":
+			settings.HTTP = addr
+		case "https":
+			settings.HTTPS = addr
+		case "socks":
+			settings.SOCKS = addr
+		}
+	}
+
+	override, _, _ := k.GetStringValue("ProxyOverride")
+	for _, s := range strings.Split(override, ";") {
+		if s = strings.TrimSpace(s); s != "" {
+			settings.Bypass = append(settings.Bypass, s)
+		}
+	}
+
+	return settings, nil
+}
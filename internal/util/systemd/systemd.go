@@ -0,0 +1,85 @@
+// Package systemd implements the socket activation and the readiness notification of systemd.
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the states reported by Notify, see sd_notify(3).
+const (
+	StateReady     = "READY=1"
+	StateReloading = "RELOADING=1"
+	StateStopping  = "STOPPING=1"
+	StateWatchdog  = "WATCHDOG=1"
+)
+
+var (
+	readyOnce    sync.Once
+	watchdogOnce sync.Once
+)
+
+// Listen returns the listener activated by systemd for the address if any,
+// otherwise it listens on the address by lc.
+func Listen(ctx context.Context, lc *net.ListenConfig, network, address string) (net.Listener, error) {
+	if ln := activatedListener(network, address); ln != nil {
+		return ln, nil
+	}
+	return lc.Listen(ctx, network, address)
+}
+
+// Ready notifies systemd that the services are ready, only the first call takes effect,
+// then the watchdog is kept alive if it is enabled.
+func Ready() {
+	readyOnce.Do(func() {
+		Notify(StateReady)
+		startWatchdog()
+	})
+}
+
+// Reloading notifies systemd that the configuration is reloading,
+// Notify(StateReady) should be called when the reload is done.
+func Reloading() {
+	Notify(StateReloading, "MONOTONIC_USEC="+strconv.FormatInt(monotonicUsec(), 10))
+}
+
+// startWatchdog sends WATCHDOG=1 at half of the interval required by WATCHDOG_USEC.
+func startWatchdog() {
+	watchdogOnce.Do(func() {
+		if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+			return
+		}
+		usec, _ := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+		if usec <= 0 {
+			return
+		}
+		go func() {
+			ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+			defer ticker.Stop()
+			for range ticker.C {
+				Notify(StateWatchdog)
+			}
+		}()
+	})
+}
+
+// sameAddr reports whether the listener address laddr serves the address.
+func sameAddr(laddr net.Addr, network, address string) bool {
+	addr, ok := laddr.(*net.TCPAddr)
+	if !ok || !strings.HasPrefix(network, "tcp") {
+		return false
+	}
+	taddr, err := net.ResolveTCPAddr(network, address)
+	if err != nil || taddr.Port != addr.Port {
+		return false
+	}
+	if taddr.IP == nil || taddr.IP.IsUnspecified() {
+		return addr.IP == nil || addr.IP.IsUnspecified()
+	}
+	return taddr.IP.Equal(addr.IP)
+}
@@ -0,0 +1,99 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// the first file descriptor passed by systemd, SD_LISTEN_FDS_START.
+	listenFdsStart = 3
+)
+
+var (
+	activated     []net.Listener
+	activatedOnce sync.Once
+	activatedMu   sync.Mutex
+)
+
+func loadActivated() {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID")); pid != os.Getpid() {
+		return
+	}
+	n, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < n; i++ {
+		fd := listenFdsStart + i
+		unix.CloseOnExec(fd)
+
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(f)
+		// FileListener dups the descriptor.
+		f.Close()
+		if err != nil {
+			continue
+		}
+		activated = append(activated, ln)
+	}
+}
+
+func activatedListener(network, address string) net.Listener {
+	activatedOnce.Do(loadActivated)
+
+	activatedMu.Lock()
+	defer activatedMu.Unlock()
+
+	for i, ln := range activated {
+		if sameAddr(ln.Addr(), network, address) {
+			// each activated listener is handed out once.
+			activated = append(activated[:i], activated[i+1:]...)
+			return ln
+		}
+	}
+	return nil
+}
+
+// Notify sends the states to the notification socket of systemd (NOTIFY_SOCKET),
+// it does nothing if the process is not started by systemd.
+func Notify(states ...string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" || len(states) == 0 {
+		return nil
+	}
+	if addr[0] == '@' {
+		// abstract namespace
+		addr = "\x00" + addr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(strings.Join(states, "\n")))
+	return err
+}
+
+func monotonicUsec() int64 {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0
+	}
+	return ts.Nano() / 1000
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package systemd
+
+import "net"
+
+func activatedListener(network, address string) net.Listener {
+	return nil
+}
+
+// Notify does nothing as systemd is available only on linux.
+func Notify(states ...string) error {
+	return nil
+}
+
+func monotonicUsec() int64 {
+	return 0
+}
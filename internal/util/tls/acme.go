@@ -0,0 +1,36 @@
+package tls
+
+import (
+	"crypto/tls"
+
+	"github.com/go-gost/x/config"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	defaultACMECacheDir = "acme"
+)
+
+// loadACMEConfig creates a server TLS config with the certificates issued on demand by an ACME CA,
+// the TLS-ALPN-01 challenge is answered on the TLS listener itself.
+func loadACMEConfig(cfg *config.ACMEConfig) *tls.Config {
+	dir := cfg.CacheDir
+	if dir == "" {
+		dir = defaultACMECacheDir
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(dir),
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Email:      cfg.Email,
+	}
+	if cfg.CA != "" {
+		m.Client = &acme.Client{
+			DirectoryURL: cfg.CA,
+		}
+	}
+
+	return m.TLSConfig()
+}
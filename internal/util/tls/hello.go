@@ -0,0 +1,76 @@
+package tls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	dissector "github.com/go-gost/tls-dissector"
+)
+
+const (
+	// ClientHelloBufferSize is the minimum size of the reader buffer of PeekClientHello,
+	// which holds the largest TLS record.
+	ClientHelloBufferSize = 16*1024 + dissector.RecordHeaderLen
+
+	// the ALPN extension, RFC 7301.
+	extALPN = 0x10
+)
+
+var (
+	ErrNotHandshake = errors.New("tls: not a handshake record")
+)
+
+// ClientHello is the information of the TLS client hello for routing the connection.
+type ClientHello struct {
+	ServerName string
+	// ALPN is the application protocols offered by the client in the order of preference.
+	ALPN []string
+}
+
+// PeekClientHello parses the client hello of the connection without consuming the data of the reader,
+// ErrNotHandshake is returned if the connection is not TLS.
+func PeekClientHello(br *bufio.Reader) (*ClientHello, error) {
+	hdr, err := br.Peek(dissector.RecordHeaderLen)
+	if err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[3:5]))
+	if hdr[0] != dissector.Handshake || hdr[1] != 0x03 || n == 0 {
+		return nil, ErrNotHandshake
+	}
+	if n > br.Size()-dissector.RecordHeaderLen {
+		return nil, bufio.ErrBufferFull
+	}
+
+	b, err := br.Peek(dissector.RecordHeaderLen + n)
+	if err != nil {
+		return nil, err
+	}
+	record, err := dissector.ReadRecord(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	msg := dissector.ClientHelloMsg{}
+	if err := msg.Decode(record.Opaque); err != nil {
+		return nil, err
+	}
+
+	hello := &ClientHello{}
+	for _, ext := range msg.Extensions {
+		switch ext.Type() {
+		case dissector.ExtServerName:
+			hello.ServerName = ext.(*dissector.ServerNameExtension).Name
+		case extALPN:
+			data, _ := ext.Encode()
+			if len(data) < 2 {
+				continue
+			}
+			for data = data[2:]; len(data) > 0 && len(data) >= 1+int(data[0]); data = data[1+int(data[0]):] {
+				hello.ALPN = append(hello.ALPN, string(data[1:1+int(data[0])]))
+			}
+		}
+	}
+	return hello, nil
+}
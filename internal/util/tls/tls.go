@@ -66,8 +66,15 @@ func LoadDefaultConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
 	return cfg, nil
 }
 
-// LoadServerConfig loads the certificate from cert & key files and client CA file.
+// LoadServerConfig loads the certificate from cert & key files and client CA file,
+// or obtains the certificates from an ACME CA if ACME is configured.
 func LoadServerConfig(config *config.TLSConfig) (*tls.Config, error) {
+	if config.ACME != nil && len(config.ACME.Domains) > 0 {
+		cfg := loadACMEConfig(config.ACME)
+		SetTLSOptions(cfg, config.Options)
+		return cfg, nil
+	}
+
 	if config.CertFile == "" && config.KeyFile == "" {
 		return nil, nil
 	}
@@ -0,0 +1,188 @@
+// Package uot frames the UDP datagrams over the stream connections.
+//
+// Each datagram is prefixed with the 2-byte data length, optionally followed by the
+// 8-byte sending time in Unix milliseconds, so the receiver can drop the datagrams
+// delayed by the stream longer than they are useful.
+package uot
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-gost/x/internal/bufpool"
+)
+
+const (
+	lengthHeaderSize    = 2
+	timestampHeaderSize = 8
+)
+
+var (
+	ErrDatagramTooLarge = errors.New("uot: datagram maximum exceeded")
+)
+
+// readDatagram reads a length-prefixed datagram from r into b,
+// the datagram is truncated if b is too small.
+func readDatagram(r io.Reader, b []byte) (n int, err error) {
+	var bb [lengthHeaderSize]byte
+	if _, err = io.ReadFull(r, bb[:]); err != nil {
+		return
+	}
+
+	dlen := int(binary.BigEndian.Uint16(bb[:]))
+	if len(b) >= dlen {
+		return io.ReadFull(r, b[:dlen])
+	}
+
+	buf := bufpool.Get(dlen)
+	defer bufpool.Put(buf)
+	_, err = io.ReadFull(r, buf)
+	n = copy(b, buf)
+
+	return
+}
+
+// writeDatagram writes the datagram b with the length prefix to w in a single write.
+func writeDatagram(w io.Writer, b []byte) (n int, err error) {
+	if len(b) > math.MaxUint16 {
+		return 0, ErrDatagramTooLarge
+	}
+
+	buf := bufpool.Get(lengthHeaderSize + len(b))
+	defer bufpool.Put(buf)
+
+	binary.BigEndian.PutUint16(buf, uint16(len(b)))
+	copy(buf[lengthHeaderSize:], b)
+	if _, err = w.Write(buf); err != nil {
+		return
+	}
+	return len(b), nil
+}
+
+type options struct {
+	timestamp bool
+	maxAge    time.Duration
+	onDrop    func()
+}
+
+type Option func(opts *options)
+
+// TimestampOption adds the sending time to each datagram, both peers must enable it.
+func TimestampOption(timestamp bool) Option {
+	return func(opts *options) {
+		opts.timestamp = timestamp
+	}
+}
+
+// MaxAgeOption drops the received datagrams sent more than maxAge ago, it requires the timestamp.
+func MaxAgeOption(maxAge time.Duration) Option {
+	return func(opts *options) {
+		opts.maxAge = maxAge
+	}
+}
+
+// DropCallbackOption sets the function called for each stale datagram dropped.
+func DropCallbackOption(f func()) Option {
+	return func(opts *options) {
+		opts.onDrop = f
+	}
+}
+
+// conn is the datagram connection over the stream connection,
+// each Read returns a datagram and each Write sends a datagram.
+type conn struct {
+	net.Conn
+	options options
+	rmu     sync.Mutex
+	wmu     sync.Mutex
+}
+
+// Conn wraps the stream connection c to the datagram connection.
+func Conn(c net.Conn, opts ...Option) net.Conn {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &conn{
+		Conn:    c,
+		options: options,
+	}
+}
+
+func (c *conn) Read(b []byte) (n int, err error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	if !c.options.timestamp {
+		return readDatagram(c.Conn, b)
+	}
+
+	for {
+		var hdr [lengthHeaderSize + timestampHeaderSize]byte
+		if _, err = io.ReadFull(c.Conn, hdr[:]); err != nil {
+			return
+		}
+		dlen := int(binary.BigEndian.Uint16(hdr[:lengthHeaderSize]))
+		ts := int64(binary.BigEndian.Uint64(hdr[lengthHeaderSize:]))
+
+		if c.options.maxAge > 0 && time.Since(time.UnixMilli(ts)) > c.options.maxAge {
+			if _, err = io.CopyN(io.Discard, c.Conn, int64(dlen)); err != nil {
+				return
+			}
+			if c.options.onDrop != nil {
+				c.options.onDrop()
+			}
+			continue
+		}
+
+		if len(b) >= dlen {
+			return io.ReadFull(c.Conn, b[:dlen])
+		}
+
+		buf := bufpool.Get(dlen)
+		_, err = io.ReadFull(c.Conn, buf)
+		n = copy(b, buf)
+		bufpool.Put(buf)
+		return
+	}
+}
+
+func (c *conn) Write(b []byte) (n int, err error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if !c.options.timestamp {
+		return writeDatagram(c.Conn, b)
+	}
+
+	if len(b) > math.MaxUint16 {
+		return 0, ErrDatagramTooLarge
+	}
+
+	buf := bufpool.Get(lengthHeaderSize + timestampHeaderSize + len(b))
+	defer bufpool.Put(buf)
+
+	binary.BigEndian.PutUint16(buf, uint16(len(b)))
+	binary.BigEndian.PutUint64(buf[lengthHeaderSize:], uint64(time.Now().UnixMilli()))
+	copy(buf[lengthHeaderSize+timestampHeaderSize:], b)
+	if _, err = c.Conn.Write(buf); err != nil {
+		return
+	}
+	return len(b), nil
+}
+
+func (c *conn) ReadFrom(b []byte) (n int, addr net.Addr, err error) {
+	n, err = c.Read(b)
+	addr = c.RemoteAddr()
+	return
+}
+
+func (c *conn) WriteTo(b []byte, addr net.Addr) (n int, err error) {
+	return c.Write(b)
+}
@@ -13,6 +13,7 @@ import (
 	limiter "github.com/go-gost/core/limiter/conn"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/x/internal/loader"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/yl2chen/cidranger"
 )
 
@@ -102,6 +103,8 @@ func NewConnLimiter(opts ...Option) limiter.ConnLimiter {
 }
 
 func (l *connLimiter) Limiter(key string) limiter.Limiter {
+	key = xnet.NormalizeHost(key)
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -201,7 +204,7 @@ func (l *connLimiter) reload(ctx context.Context) error {
 			ipLimits[key] = NewConnLimitGenerator(limit)
 		default:
 			if ip := net.ParseIP(key); ip != nil {
-				ipLimits[key] = NewConnLimitSingleGenerator(limit)
+				ipLimits[ip.String()] = NewConnLimitSingleGenerator(limit)
 				break
 			}
 			if _, ipNet, _ := net.ParseCIDR(key); ipNet != nil {
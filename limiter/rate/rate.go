@@ -13,6 +13,7 @@ import (
 	limiter "github.com/go-gost/core/limiter/rate"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/x/internal/loader"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/yl2chen/cidranger"
 )
 
@@ -102,6 +103,8 @@ func NewRateLimiter(opts ...Option) limiter.RateLimiter {
 }
 
 func (l *rateLimiter) Limiter(key string) limiter.Limiter {
+	key = xnet.NormalizeHost(key)
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -201,7 +204,7 @@ func (l *rateLimiter) reload(ctx context.Context) error {
 			ipLimits[key] = NewRateLimitGenerator(limit)
 		default:
 			if ip := net.ParseIP(key); ip != nil {
-				ipLimits[key] = NewRateLimitSingleGenerator(limit)
+				ipLimits[ip.String()] = NewRateLimitSingleGenerator(limit)
 				break
 			}
 			if _, ipNet, _ := net.ParseCIDR(key); ipNet != nil {
@@ -13,6 +13,7 @@ import (
 	limiter "github.com/go-gost/core/limiter/traffic"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/x/internal/loader"
+	xnet "github.com/go-gost/x/internal/net"
 	"github.com/patrickmn/go-cache"
 	"github.com/yl2chen/cidranger"
 )
@@ -149,6 +150,7 @@ func (l *trafficLimiter) In(ctx context.Context, key string, opts ...limiter.Opt
 	}
 
 	host, _, _ := net.SplitHostPort(key)
+	host = xnet.NormalizeHost(host)
 	// IP level limiter
 	if lim, ok := l.inLimits.Get(host); ok {
 		// cached IP limiter
@@ -213,6 +215,7 @@ func (l *trafficLimiter) Out(ctx context.Context, key string, opts ...limiter.Op
 	}
 
 	host, _, _ := net.SplitHostPort(key)
+	host = xnet.NormalizeHost(host)
 	// IP level limiter
 	if lim, ok := l.outLimits.Get(host); ok {
 		if lim != nil {
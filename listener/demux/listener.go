@@ -0,0 +1,198 @@
+package demux
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/listener"
+	"github.com/go-gost/core/logger"
+	md "github.com/go-gost/core/metadata"
+	admission "github.com/go-gost/x/admission/wrapper"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/net/proxyproto"
+	"github.com/go-gost/x/internal/util/systemd"
+	tls_util "github.com/go-gost/x/internal/util/tls"
+	climiter "github.com/go-gost/x/limiter/conn/wrapper"
+	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
+	metrics "github.com/go-gost/x/metrics/wrapper"
+	"github.com/go-gost/x/registry"
+	stats "github.com/go-gost/x/stats/wrapper"
+	pp "github.com/pires/go-proxyproto"
+)
+
+const (
+	dialTimeout = 10 * time.Second
+)
+
+func init() {
+	registry.ListenerRegistry().Register("demux", NewListener)
+}
+
+// demuxListener serves multiple protocols on one port,
+// the connections are dispatched by the sniffed protocol to the services or addresses of the routes,
+// the connections not routed are accepted by the handler of the service.
+type demuxListener struct {
+	ln      net.Listener
+	cqueue  chan net.Conn
+	errChan chan error
+	logger  logger.Logger
+	md      metadata
+	options listener.Options
+}
+
+func NewListener(opts ...listener.Option) listener.Listener {
+	options := listener.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &demuxListener{
+		logger:  options.Logger,
+		options: options,
+	}
+}
+
+func (l *demuxListener) Init(md md.Metadata) (err error) {
+	if err = l.parseMetadata(md); err != nil {
+		return
+	}
+
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
+
+	lc := net.ListenConfig{}
+	if l.md.mptcp {
+		lc.SetMultipathTCP(true)
+		l.logger.Debugf("mptcp enabled: %v", lc.MultipathTCP())
+	}
+	if l.md.reusePort {
+		lc.Control = xnet.ReusePortControl(lc.Control)
+	}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
+	if err != nil {
+		return
+	}
+
+	ln = proxyproto.WrapListener(l.options.ProxyProtocol, ln, 10*time.Second)
+	ln = metrics.WrapListener(l.options.Service, ln)
+	ln = stats.WrapListener(ln, l.options.Stats)
+	ln = admission.WrapListener(l.options.Admission, ln)
+	ln = limiter.WrapListener(l.options.TrafficLimiter, ln)
+	ln = climiter.WrapListener(l.options.ConnLimiter, ln)
+	l.ln = ln
+
+	l.cqueue = make(chan net.Conn, l.md.backlog)
+	l.errChan = make(chan error, 1)
+
+	go l.listenLoop()
+
+	return
+}
+
+func (l *demuxListener) Accept() (conn net.Conn, err error) {
+	var ok bool
+	select {
+	case conn = <-l.cqueue:
+	case err, ok = <-l.errChan:
+		if !ok {
+			err = listener.ErrClosed
+		}
+	}
+	return
+}
+
+func (l *demuxListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+func (l *demuxListener) Close() error {
+	return l.ln.Close()
+}
+
+func (l *demuxListener) listenLoop() {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			l.errChan <- err
+			close(l.errChan)
+			return
+		}
+		go l.dispatch(conn)
+	}
+}
+
+func (l *demuxListener) dispatch(conn net.Conn) {
+	br := bufio.NewReaderSize(conn, tls_util.ClientHelloBufferSize)
+
+	conn.SetReadDeadline(time.Now().Add(l.md.sniffingTimeout))
+	proto, alpn := sniff(br)
+	conn.SetReadDeadline(time.Time{})
+
+	conn = xnet.NewBufferReaderConn(conn, br)
+
+	target := l.route(proto, alpn)
+	if target == "" {
+		select {
+		case l.cqueue <- conn:
+		default:
+			conn.Close()
+			l.logger.Warnf("connection queue is full, client %s discarded", conn.RemoteAddr())
+		}
+		return
+	}
+
+	log := l.logger.WithFields(map[string]any{
+		"remote": conn.RemoteAddr().String(),
+		"local":  conn.LocalAddr().String(),
+	})
+	log.Debugf("demux: %s(%v) > %s", proto, alpn, target)
+
+	if err := l.forward(conn, target); err != nil {
+		log.Errorf("demux: %s: %v", target, err)
+	}
+}
+
+// route returns the target of the protocol, the ALPN protocols are matched in the order of the client preference,
+// the empty target means the connection is handled by the handler of the service.
+func (l *demuxListener) route(proto string, alpn []string) string {
+	if proto == "" {
+		return ""
+	}
+	for _, p := range alpn {
+		if target := l.md.routes[p]; target != "" {
+			return target
+		}
+	}
+	return l.md.routes[proto]
+}
+
+func (l *demuxListener) forward(conn net.Conn, target string) error {
+	defer conn.Close()
+
+	addr := target
+	// the target is the name of a service, the service listening on the unspecified address is dialed by loopback.
+	if svc := registry.ServiceRegistry().Get(target); svc != nil {
+		if svc.Addr() == nil {
+			return listener.ErrClosed
+		}
+		addr = svc.Addr().String()
+		if host, port, _ := net.SplitHostPort(addr); host == "" || net.ParseIP(host).IsUnspecified() {
+			addr = net.JoinHostPort("127.0.0.1", port)
+		}
+	}
+
+	cc, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	// the address of the client is passed to the target by the PROXY protocol header.
+	if v := l.md.proxyProtocol; v == 1 || v == 2 {
+		if _, err := pp.HeaderProxyFromAddrs(byte(v), conn.RemoteAddr(), conn.LocalAddr()).WriteTo(cc); err != nil {
+			return err
+		}
+	}
+
+	return xnet.Transport(conn, cc)
+}
@@ -0,0 +1,50 @@
+package demux
+
+import (
+	"strings"
+	"time"
+
+	md "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+const (
+	defaultBacklog         = 128
+	defaultSniffingTimeout = 2 * time.Second
+)
+
+type metadata struct {
+	// routes maps the protocol (tls, ssh, http or the ALPN protocol offered by the TLS client, e.g. h2)
+	// to the target, which is the name of a service or an address.
+	routes          map[string]string
+	sniffingTimeout time.Duration
+	proxyProtocol   int
+	backlog         int
+
+	mptcp     bool
+	reusePort bool
+}
+
+func (l *demuxListener) parseMetadata(md md.Metadata) (err error) {
+	l.md.routes = make(map[string]string)
+	for k, v := range mdutil.GetStringMapString(md, "demux.routes", "routes") {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" && v != "" {
+			l.md.routes[k] = strings.TrimSpace(v)
+		}
+	}
+
+	l.md.sniffingTimeout = mdutil.GetDuration(md, "demux.sniffingTimeout", "sniffingTimeout")
+	if l.md.sniffingTimeout <= 0 {
+		l.md.sniffingTimeout = defaultSniffingTimeout
+	}
+	l.md.proxyProtocol = mdutil.GetInt(md, "demux.proxyProtocol")
+
+	l.md.backlog = mdutil.GetInt(md, "demux.backlog", "backlog")
+	if l.md.backlog <= 0 {
+		l.md.backlog = defaultBacklog
+	}
+
+	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
+	return
+}
@@ -0,0 +1,55 @@
+package demux
+
+import (
+	"bufio"
+	"strings"
+
+	dissector "github.com/go-gost/tls-dissector"
+	tls_util "github.com/go-gost/x/internal/util/tls"
+)
+
+const (
+	protoTLS  = "tls"
+	protoSSH  = "ssh"
+	protoHTTP = "http"
+)
+
+var httpMethods = []string{
+	"GET ", "POST ", "PUT ", "DELETE ", "HEAD ", "OPTIONS ", "PATCH ", "CONNECT ", "TRACE ",
+}
+
+// sniff detects the protocol by the first bytes of the connection, the ALPN protocols offered by the client are returned for TLS.
+// The protocol is empty if it is unknown.
+func sniff(br *bufio.Reader) (proto string, alpn []string) {
+	b, err := br.Peek(1)
+	if err != nil {
+		return
+	}
+
+	switch b[0] {
+	case dissector.Handshake:
+		hello, err := tls_util.PeekClientHello(br)
+		if err == tls_util.ErrNotHandshake {
+			return
+		}
+		if err != nil {
+			return protoTLS, nil
+		}
+		return protoTLS, hello.ALPN
+	case 'S':
+		if b, err = br.Peek(4); err == nil && string(b) == "SSH-" {
+			return protoSSH, nil
+		}
+		return
+	}
+
+	// the HTTP request is detected by the method in the bytes already received, so that the short messages
+	// of the other protocols are not blocked.
+	b, _ = br.Peek(min(br.Buffered(), 8))
+	for _, method := range httpMethods {
+		if strings.HasPrefix(string(b), method) {
+			return protoHTTP, nil
+		}
+	}
+	return
+}
@@ -48,10 +48,7 @@ func (l *dtlsListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "udp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "udp4"
-	}
+	network := xnet.ListenNetwork("udp", l.options.Addr, md)
 	laddr, err := net.ResolveUDPAddr(network, l.options.Addr)
 	if err != nil {
 		return
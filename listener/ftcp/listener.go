@@ -44,10 +44,7 @@ func (l *ftcpListener) Init(md md.Metadata) (err error) {
 	}
 
 	var conn net.PacketConn
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 	conn, err = tcpraw.Listen(network, l.options.Addr)
 	if err != nil {
 		return
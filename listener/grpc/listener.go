@@ -12,8 +12,10 @@ import (
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/net/proxyproto"
 	pb "github.com/go-gost/x/internal/util/grpc/proto"
+	"github.com/go-gost/x/internal/util/systemd"
 	climiter "github.com/go-gost/x/limiter/conn/wrapper"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
+	mdx "github.com/go-gost/x/metadata"
 	metrics "github.com/go-gost/x/metrics/wrapper"
 	"github.com/go-gost/x/registry"
 	stats "github.com/go-gost/x/stats/wrapper"
@@ -24,6 +26,9 @@ import (
 
 func init() {
 	registry.ListenerRegistry().Register("grpc", NewListener)
+	mdx.RegisterDefaults(mdx.KindListener, "grpc",
+		mdx.Default{Keys: []string{"grpc.backlog", "backlog"}, Value: defaultBacklog},
+	)
 }
 
 type grpcListener struct {
@@ -52,16 +57,16 @@ func (l *grpcListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 	lc := net.ListenConfig{}
 	if l.md.mptcp {
 		lc.SetMultipathTCP(true)
 		l.logger.Debugf("mptcp enabled: %v", lc.MultipathTCP())
 	}
-	ln, err := lc.Listen(context.Background(), network, l.options.Addr)
+	if l.md.reusePort {
+		lc.Control = xnet.ReusePortControl(lc.Control)
+	}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
 	if err != nil {
 		return
 	}
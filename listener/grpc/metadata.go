@@ -22,6 +22,7 @@ type metadata struct {
 	keepalivePermitWithoutStream bool
 	keepaliveMaxConnectionIdle   time.Duration
 	mptcp                        bool
+	reusePort                    bool
 }
 
 func (l *grpcListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -51,6 +52,7 @@ func (l *grpcListener) parseMetadata(md mdata.Metadata) (err error) {
 		l.md.keepalivePermitWithoutStream = mdutil.GetBool(md, "grpc.keepalive.permitWithoutStream", "keepalive.permitWithoutStream")
 		l.md.keepaliveMaxConnectionIdle = mdutil.GetDuration(md, "grpc.keepalive.maxConnectionIdle", "keepalive.maxConnectionIdle")
 		l.md.mptcp = mdutil.GetBool(md, "mptcp")
+		l.md.reusePort = mdutil.GetBool(md, "reusePort")
 	}
 
 	return
@@ -15,6 +15,7 @@ import (
 	admission "github.com/go-gost/x/admission/wrapper"
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/net/proxyproto"
+	"github.com/go-gost/x/internal/util/systemd"
 	climiter "github.com/go-gost/x/limiter/conn/wrapper"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
 	metrics "github.com/go-gost/x/metrics/wrapper"
@@ -72,16 +73,16 @@ func (l *h2Listener) Init(md md.Metadata) (err error) {
 		Addr: l.options.Addr,
 	}
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 	lc := net.ListenConfig{}
 	if l.md.mptcp {
 		lc.SetMultipathTCP(true)
 		l.logger.Debugf("mptcp enabled: %v", lc.MultipathTCP())
 	}
-	ln, err := lc.Listen(context.Background(), network, l.options.Addr)
+	if l.md.reusePort {
+		lc.Control = xnet.ReusePortControl(lc.Control)
+	}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
 	if err != nil {
 		return err
 	}
@@ -134,6 +135,12 @@ func (l *h2Listener) Addr() net.Addr {
 	return l.addr
 }
 
+// Shutdown gracefully shuts down the server, the clients are notified by the GOAWAY frame
+// and the active streams are waited until ctx is done.
+func (l *h2Listener) Shutdown(ctx context.Context) error {
+	return l.server.Shutdown(ctx)
+}
+
 func (l *h2Listener) Close() (err error) {
 	select {
 	case <-l.errChan:
@@ -10,9 +10,10 @@ const (
 )
 
 type metadata struct {
-	path    string
-	backlog int
-	mptcp   bool
+	path      string
+	backlog   int
+	mptcp     bool
+	reusePort bool
 }
 
 func (l *h2Listener) parseMetadata(md mdata.Metadata) (err error) {
@@ -28,6 +29,7 @@ func (l *h2Listener) parseMetadata(md mdata.Metadata) (err error) {
 
 	l.md.path = mdutil.GetString(md, path)
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
 
 	return
 }
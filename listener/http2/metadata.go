@@ -10,8 +10,9 @@ const (
 )
 
 type metadata struct {
-	backlog int
-	mptcp   bool
+	backlog   int
+	mptcp     bool
+	reusePort bool
 }
 
 func (l *http2Listener) parseMetadata(md mdata.Metadata) (err error) {
@@ -24,6 +25,7 @@ func (l *http2Listener) parseMetadata(md mdata.Metadata) (err error) {
 		l.md.backlog = defaultBacklog
 	}
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
 
 	return
 }
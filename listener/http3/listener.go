@@ -46,10 +46,7 @@ func (l *http3Listener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "udp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "udp4"
-	}
+	network := xnet.ListenNetwork("udp", l.options.Addr, md)
 	l.addr, err = net.ResolveUDPAddr(network, l.options.Addr)
 	if err != nil {
 		return
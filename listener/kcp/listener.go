@@ -11,6 +11,7 @@ import (
 	xnet "github.com/go-gost/x/internal/net"
 	kcp_util "github.com/go-gost/x/internal/util/kcp"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
+	mdx "github.com/go-gost/x/metadata"
 	metrics "github.com/go-gost/x/metrics/wrapper"
 	"github.com/go-gost/x/registry"
 	stats "github.com/go-gost/x/stats/wrapper"
@@ -21,6 +22,9 @@ import (
 
 func init() {
 	registry.ListenerRegistry().Register("kcp", NewListener)
+	mdx.RegisterDefaults(mdx.KindListener, "kcp",
+		mdx.Default{Keys: []string{"backlog"}, Value: defaultBacklog},
+	)
 }
 
 type kcpListener struct {
@@ -54,16 +58,10 @@ func (l *kcpListener) Init(md md.Metadata) (err error) {
 
 	var conn net.PacketConn
 	if config.TCP {
-		network := "tcp"
-		if xnet.IsIPv4(l.options.Addr) {
-			network = "tcp4"
-		}
+		network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 		conn, err = tcpraw.Listen(network, l.options.Addr)
 	} else {
-		network := "udp"
-		if xnet.IsIPv4(l.options.Addr) {
-			network = "udp4"
-		}
+		network := xnet.ListenNetwork("udp", l.options.Addr, md)
 		var udpAddr *net.UDPAddr
 		udpAddr, err = net.ResolveUDPAddr(network, l.options.Addr)
 		if err != nil {
@@ -12,6 +12,7 @@ import (
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/net/proxyproto"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/systemd"
 	climiter "github.com/go-gost/x/limiter/conn/wrapper"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
 	metrics "github.com/go-gost/x/metrics/wrapper"
@@ -48,17 +49,17 @@ func (l *mtcpListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 
 	lc := net.ListenConfig{}
 	if l.md.mptcp {
 		lc.SetMultipathTCP(true)
 		l.logger.Debugf("mptcp enabled: %v", lc.MultipathTCP())
 	}
-	ln, err := lc.Listen(context.Background(), network, l.options.Addr)
+	if l.md.reusePort {
+		lc.Control = xnet.ReusePortControl(lc.Control)
+	}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
 	if err != nil {
 		return
 	}
@@ -13,6 +13,8 @@ import (
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/net/proxyproto"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/shaping"
+	"github.com/go-gost/x/internal/util/systemd"
 	climiter "github.com/go-gost/x/limiter/conn/wrapper"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
 	metrics "github.com/go-gost/x/metrics/wrapper"
@@ -49,17 +51,17 @@ func (l *mtlsListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 
 	lc := net.ListenConfig{}
 	if l.md.mptcp {
 		lc.SetMultipathTCP(true)
 		l.logger.Debugf("mptcp enabled: %v", lc.MultipathTCP())
 	}
-	ln, err := lc.Listen(context.Background(), network, l.options.Addr)
+	if l.md.reusePort {
+		lc.Control = xnet.ReusePortControl(lc.Control)
+	}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
 	if err != nil {
 		return
 	}
@@ -100,7 +102,7 @@ func (l *mtlsListener) listenLoop() {
 			close(l.errChan)
 			return
 		}
-		go l.mux(conn)
+		go l.mux(shaping.Conn(conn, l.md.shaping))
 	}
 }
 
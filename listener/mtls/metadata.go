@@ -4,6 +4,7 @@ import (
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/shaping"
 )
 
 const (
@@ -11,9 +12,11 @@ const (
 )
 
 type metadata struct {
-	muxCfg  *mux.Config
-	backlog int
-	mptcp   bool
+	muxCfg    *mux.Config
+	backlog   int
+	mptcp     bool
+	reusePort bool
+	shaping   *shaping.Profile
 }
 
 func (l *mtlsListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -32,6 +35,8 @@ func (l *mtlsListener) parseMetadata(md mdata.Metadata) (err error) {
 		MaxStreamBuffer:   mdutil.GetInt(md, "mux.maxStreamBuffer"),
 	}
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
 
+	l.md.shaping, err = shaping.ParseProfile(md)
 	return
 }
@@ -15,9 +15,12 @@ import (
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/net/proxyproto"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/shaping"
+	"github.com/go-gost/x/internal/util/systemd"
 	ws_util "github.com/go-gost/x/internal/util/ws"
 	climiter "github.com/go-gost/x/limiter/conn/wrapper"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
+	mdx "github.com/go-gost/x/metadata"
 	metrics "github.com/go-gost/x/metrics/wrapper"
 	"github.com/go-gost/x/registry"
 	stats "github.com/go-gost/x/stats/wrapper"
@@ -27,6 +30,12 @@ import (
 func init() {
 	registry.ListenerRegistry().Register("mws", NewListener)
 	registry.ListenerRegistry().Register("mwss", NewTLSListener)
+	for _, typ := range []string{"mws", "mwss"} {
+		mdx.RegisterDefaults(mdx.KindListener, typ,
+			mdx.Default{Keys: []string{"ws.path", "path"}, Value: defaultPath},
+			mdx.Default{Keys: []string{"ws.backlog", "backlog"}, Value: defaultBacklog},
+		)
+	}
 }
 
 type mwsListener struct {
@@ -92,17 +101,17 @@ func (l *mwsListener) Init(md md.Metadata) (err error) {
 	l.cqueue = make(chan net.Conn, l.md.backlog)
 	l.errChan = make(chan error, 1)
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 
 	lc := net.ListenConfig{}
 	if l.md.mptcp {
 		lc.SetMultipathTCP(true)
 		l.logger.Debugf("mptcp enabled: %v", lc.MultipathTCP())
 	}
-	ln, err := lc.Listen(context.Background(), network, l.options.Addr)
+	if l.md.reusePort {
+		lc.Control = xnet.ReusePortControl(lc.Control)
+	}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
 	if err != nil {
 		return
 	}
@@ -167,7 +176,7 @@ func (l *mwsListener) upgrade(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	l.mux(ws_util.Conn(conn), log)
+	l.mux(shaping.Conn(ws_util.Conn(conn), l.md.shaping), log)
 }
 
 func (l *mwsListener) mux(conn net.Conn, log logger.Logger) {
@@ -7,6 +7,7 @@ import (
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
 	"github.com/go-gost/x/internal/util/mux"
+	"github.com/go-gost/x/internal/util/shaping"
 )
 
 const (
@@ -27,7 +28,9 @@ type metadata struct {
 
 	muxCfg *mux.Config
 
-	mptcp bool
+	mptcp     bool
+	reusePort bool
+	shaping   *shaping.Profile
 }
 
 func (l *mwsListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -66,6 +69,8 @@ func (l *mwsListener) parseMetadata(md mdata.Metadata) (err error) {
 	}
 
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
 
+	l.md.shaping, err = shaping.ParseProfile(md)
 	return
 }
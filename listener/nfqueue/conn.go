@@ -0,0 +1,88 @@
+package nfqueue
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-gost/core/admission"
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/internal/util/nfqueue"
+)
+
+var (
+	errUnsupported = errors.New("nfqueue: use ReadPacket and SetVerdict")
+)
+
+type queueAddr struct {
+	queue uint16
+}
+
+func (a *queueAddr) Network() string {
+	return "nfqueue"
+}
+
+func (a *queueAddr) String() string {
+	return strconv.Itoa(int(a.queue))
+}
+
+// conn is the connection of the queue, it implements nfqueue.PacketConn.
+type conn struct {
+	*nfqueue.Queue
+	laddr     net.Addr
+	admission admission.Admission
+	logger    logger.Logger
+}
+
+// ReadPacket reads the next packet admitted by the admission,
+// the packets from the rejected sources are dropped.
+func (c *conn) ReadPacket() (*nfqueue.Packet, error) {
+	for {
+		p, err := c.Queue.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		if c.admission == nil {
+			return p, nil
+		}
+
+		hdr, err := nfqueue.ParseHeader(p.Payload)
+		if err != nil || c.admission.Admit(context.Background(), hdr.Src.String()) {
+			return p, nil
+		}
+		c.logger.Debugf("admission: %s is denied", hdr.Src)
+		if err := c.Queue.SetVerdict(p.ID, nfqueue.VerdictDrop); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *conn) Read(b []byte) (n int, err error) {
+	return 0, errUnsupported
+}
+
+func (c *conn) Write(b []byte) (n int, err error) {
+	return 0, errUnsupported
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return c.laddr
+}
+
+func (c *conn) SetDeadline(t time.Time) error {
+	return &net.OpError{Op: "set", Net: "nfqueue", Source: nil, Addr: nil, Err: errors.New("deadline not supported")}
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	return &net.OpError{Op: "set", Net: "nfqueue", Source: nil, Addr: nil, Err: errors.New("deadline not supported")}
+}
+
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	return &net.OpError{Op: "set", Net: "nfqueue", Source: nil, Addr: nil, Err: errors.New("deadline not supported")}
+}
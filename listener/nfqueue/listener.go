@@ -0,0 +1,102 @@
+package nfqueue
+
+import (
+	"net"
+
+	"github.com/go-gost/core/listener"
+	"github.com/go-gost/core/logger"
+	mdata "github.com/go-gost/core/metadata"
+	"github.com/go-gost/x/internal/util/nfqueue"
+	"github.com/go-gost/x/registry"
+)
+
+func init() {
+	registry.ListenerRegistry().Register("nfqueue", NewListener)
+}
+
+type nfqueueListener struct {
+	addr    net.Addr
+	queue   *nfqueue.Queue
+	cqueue  chan net.Conn
+	closed  chan struct{}
+	logger  logger.Logger
+	md      metadata
+	options listener.Options
+}
+
+// NewListener creates a listener of the netfilter queue,
+// it accepts only one connection delivering the queued packets to the handler.
+func NewListener(opts ...listener.Option) listener.Listener {
+	options := listener.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &nfqueueListener{
+		logger:  options.Logger,
+		options: options,
+	}
+}
+
+func (l *nfqueueListener) Init(md mdata.Metadata) (err error) {
+	if err = l.parseMetadata(md); err != nil {
+		return
+	}
+
+	q, err := nfqueue.Open(l.md.queue,
+		nfqueue.CopyRangeOption(l.md.copyRange),
+		nfqueue.MaxLenOption(l.md.maxLen),
+		nfqueue.FailOpenOption(l.md.failOpen),
+	)
+	if err != nil {
+		return
+	}
+	l.queue = q
+	l.addr = &queueAddr{queue: l.md.queue}
+	l.logger.Infof("queue: %d", l.md.queue)
+
+	l.cqueue = make(chan net.Conn, 1)
+	l.closed = make(chan struct{})
+	l.cqueue <- &conn{
+		Queue:     q,
+		laddr:     l.addr,
+		admission: l.options.Admission,
+		logger:    l.logger,
+	}
+
+	return
+}
+
+func (l *nfqueueListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.cqueue:
+		return conn, nil
+	case <-l.closed:
+	}
+
+	return nil, listener.ErrClosed
+}
+
+func (l *nfqueueListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *nfqueueListener) Close() error {
+	select {
+	case <-l.closed:
+		return net.ErrClosed
+	default:
+		close(l.closed)
+	}
+
+	// the connection is not accepted by the service yet.
+	select {
+	case conn := <-l.cqueue:
+		conn.Close()
+	default:
+	}
+
+	if l.queue != nil {
+		return l.queue.Close()
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package nfqueue
+
+import (
+	"net"
+	"strconv"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+type metadata struct {
+	queue     uint16
+	copyRange uint32
+	maxLen    uint32
+	failOpen  bool
+}
+
+func (l *nfqueueListener) parseMetadata(md mdata.Metadata) (err error) {
+	// the queue number defaults to the port of the service address, e.g. :100
+	if _, port, _ := net.SplitHostPort(l.options.Addr); port != "" {
+		n, _ := strconv.Atoi(port)
+		l.md.queue = uint16(n)
+	}
+	if v := mdutil.GetInt(md, "queue"); v > 0 {
+		l.md.queue = uint16(v)
+	}
+	l.md.copyRange = uint32(mdutil.GetInt(md, "copyRange"))
+	l.md.maxLen = uint32(mdutil.GetInt(md, "maxLen"))
+	l.md.failOpen = mdutil.GetBool(md, "failOpen")
+	return
+}
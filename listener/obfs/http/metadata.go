@@ -8,8 +8,9 @@ import (
 )
 
 type metadata struct {
-	header http.Header
-	mptcp  bool
+	header    http.Header
+	mptcp     bool
+	reusePort bool
 }
 
 func (l *obfsListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -26,5 +27,6 @@ func (l *obfsListener) parseMetadata(md mdata.Metadata) (err error) {
 	}
 
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
 	return
 }
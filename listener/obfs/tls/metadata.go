@@ -6,10 +6,12 @@ import (
 )
 
 type metadata struct {
-	mptcp bool
+	mptcp     bool
+	reusePort bool
 }
 
 func (l *obfsListener) parseMetadata(md md.Metadata) (err error) {
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
 	return
 }
@@ -59,10 +59,7 @@ func (l *phtListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 	l.addr, err = net.ResolveTCPAddr(network, l.options.Addr)
 	if err != nil {
 		return
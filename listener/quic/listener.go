@@ -11,6 +11,7 @@ import (
 	xnet "github.com/go-gost/x/internal/net"
 	quic_util "github.com/go-gost/x/internal/util/quic"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
+	mdx "github.com/go-gost/x/metadata"
 	metrics "github.com/go-gost/x/metrics/wrapper"
 	"github.com/go-gost/x/registry"
 	stats "github.com/go-gost/x/stats/wrapper"
@@ -19,6 +20,9 @@ import (
 
 func init() {
 	registry.ListenerRegistry().Register("quic", NewListener)
+	mdx.RegisterDefaults(mdx.KindListener, "quic",
+		mdx.Default{Keys: []string{"backlog"}, Value: defaultBacklog},
+	)
 }
 
 type quicListener struct {
@@ -51,10 +55,7 @@ func (l *quicListener) Init(md md.Metadata) (err error) {
 		addr = net.JoinHostPort(addr, "0")
 	}
 
-	network := "udp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "udp4"
-	}
+	network := xnet.ListenNetwork("udp", l.options.Addr, md)
 	var laddr *net.UDPAddr
 	laddr, err = net.ResolveUDPAddr(network, addr)
 	if err != nil {
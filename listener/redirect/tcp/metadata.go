@@ -6,8 +6,9 @@ import (
 )
 
 type metadata struct {
-	tproxy bool
-	mptcp  bool
+	tproxy    bool
+	mptcp     bool
+	reusePort bool
 }
 
 func (l *redirectListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -16,5 +17,6 @@ func (l *redirectListener) parseMetadata(md mdata.Metadata) (err error) {
 	)
 	l.md.tproxy = mdutil.GetBool(md, tproxy)
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
 	return
 }
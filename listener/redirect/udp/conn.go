@@ -2,35 +2,113 @@ package udp
 
 import (
 	"net"
+	"os"
 	"sync"
 	"time"
 
-	"github.com/go-gost/core/common/bufpool"
+	"github.com/go-gost/x/internal/bufpool"
 )
 
+const (
+	// the datagrams of a session queued before they are read by the handler.
+	sessionQueueSize = 128
+)
+
+// redirConn is the NAT session of a client to an original destination,
+// the session expires if no datagram is received in the TTL.
 type redirConn struct {
 	net.Conn
-	buf  []byte
-	ttl  time.Duration
-	once sync.Once
+	ttl            time.Duration
+	readBufferSize int
+	rc             chan []byte
+	done           chan struct{}
+	err            error
+	closed         chan struct{}
+	once           sync.Once
+	onClose        func()
 }
 
-func (c *redirConn) Read(b []byte) (n int, err error) {
-	if c.ttl > 0 {
-		c.SetReadDeadline(time.Now().Add(c.ttl))
-		defer c.SetReadDeadline(time.Time{})
+func newRedirConn(c net.Conn, b []byte, ttl time.Duration, readBufferSize int, onClose func()) *redirConn {
+	conn := &redirConn{
+		Conn:           c,
+		ttl:            ttl,
+		readBufferSize: readBufferSize,
+		rc:             make(chan []byte, sessionQueueSize),
+		done:           make(chan struct{}),
+		closed:         make(chan struct{}),
+		onClose:        onClose,
 	}
+	conn.rc <- b
+	go conn.readLoop()
+	return conn
+}
 
-	c.once.Do(func() {
-		n = copy(b, c.buf)
-		bufpool.Put(c.buf)
-	})
+// readLoop reads the datagrams received by the connected socket of the session.
+func (c *redirConn) readLoop() {
+	defer close(c.done)
+
+	for {
+		b := bufpool.Get(c.readBufferSize)
+		n, err := c.Conn.Read(b)
+		if err != nil {
+			bufpool.Put(b)
+			c.err = err
+			return
+		}
+		select {
+		case c.rc <- b[:n]:
+		case <-c.closed:
+			bufpool.Put(b)
+			return
+		}
+	}
+}
+
+// deliver queues the datagram b received by the listener socket before the connected socket of the session is ready,
+// false is returned if the queue is full or the session is closed.
+func (c *redirConn) deliver(b []byte) bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+	}
+
+	select {
+	case c.rc <- b:
+		return true
+	default:
+		return false
+	}
+}
 
-	if n == 0 {
-		n, err = c.Conn.Read(b)
+func (c *redirConn) Read(b []byte) (n int, err error) {
+	var timeout <-chan time.Time
+	if c.ttl > 0 {
+		t := time.NewTimer(c.ttl)
+		defer t.Stop()
+		timeout = t.C
 	}
 
-	return
+	select {
+	case bb := <-c.rc:
+		n = copy(b, bb)
+		bufpool.Put(bb)
+		return
+	case <-c.done:
+		// the queued datagrams are read before the error.
+		select {
+		case bb := <-c.rc:
+			n = copy(b, bb)
+			bufpool.Put(bb)
+			return
+		default:
+		}
+		return 0, c.err
+	case <-c.closed:
+		return 0, net.ErrClosed
+	case <-timeout:
+		return 0, os.ErrDeadlineExceeded
+	}
 }
 
 func (c *redirConn) Write(b []byte) (n int, err error) {
@@ -40,3 +118,13 @@ func (c *redirConn) Write(b []byte) (n int, err error) {
 	}
 	return c.Conn.Write(b)
 }
+
+func (c *redirConn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return c.Conn.Close()
+}
@@ -2,6 +2,7 @@ package udp
 
 import (
 	"net"
+	"sync"
 
 	"github.com/go-gost/core/listener"
 	"github.com/go-gost/core/logger"
@@ -18,10 +19,13 @@ func init() {
 }
 
 type redirectListener struct {
-	ln      *net.UDPConn
-	logger  logger.Logger
-	md      metadata
-	options listener.Options
+	ln     *net.UDPConn
+	logger logger.Logger
+	// the NAT sessions keyed by the client and original destination addresses.
+	sessions map[string]*redirConn
+	mu       sync.Mutex
+	md       metadata
+	options  listener.Options
 }
 
 func NewListener(opts ...listener.Option) listener.Listener {
@@ -30,8 +34,9 @@ func NewListener(opts ...listener.Option) listener.Listener {
 		opt(&options)
 	}
 	return &redirectListener{
-		logger:  options.Logger,
-		options: options,
+		logger:   options.Logger,
+		sessions: make(map[string]*redirConn),
+		options:  options,
 	}
 }
 
@@ -11,12 +11,17 @@ import (
 	"syscall"
 	"unsafe"
 
-	"github.com/go-gost/core/common/bufpool"
+	"github.com/go-gost/x/internal/bufpool"
 	xnet "github.com/go-gost/x/internal/net"
 	"golang.org/x/sys/unix"
 )
 
 func (l *redirectListener) listenUDP(addr string) (*net.UDPConn, error) {
+	network := "udp"
+	if xnet.IsIPv4(addr) {
+		network = "udp4"
+	}
+
 	lc := net.ListenConfig{
 		Control: func(network, address string, c syscall.RawConn) error {
 			return c.Control(func(fd uintptr) {
@@ -26,14 +31,20 @@ func (l *redirectListener) listenUDP(addr string) (*net.UDPConn, error) {
 				if err := unix.SetsockoptInt(int(fd), unix.SOL_IP, unix.IP_RECVORIGDSTADDR, 1); err != nil {
 					l.logger.Errorf("SetsockoptInt(SOL_IP, IP_RECVORIGDSTADDR, 1): %v", err)
 				}
+				if network == "udp4" {
+					return
+				}
+				// the IPv6 datagrams intercepted by ip6tables TPROXY rules.
+				if err := unix.SetsockoptInt(int(fd), unix.SOL_IPV6, unix.IPV6_TRANSPARENT, 1); err != nil {
+					l.logger.Errorf("SetsockoptInt(SOL_IPV6, IPV6_TRANSPARENT, 1): %v", err)
+				}
+				if err := unix.SetsockoptInt(int(fd), unix.SOL_IPV6, unix.IPV6_RECVORIGDSTADDR, 1); err != nil {
+					l.logger.Errorf("SetsockoptInt(SOL_IPV6, IPV6_RECVORIGDSTADDR, 1): %v", err)
+				}
 			})
 		},
 	}
 
-	network := "udp"
-	if xnet.IsIPv4(addr) {
-		network = "udp4"
-	}
 	pc, err := lc.ListenPacket(context.Background(), network, addr)
 	if err != nil {
 		return nil, err
@@ -43,32 +54,39 @@ func (l *redirectListener) listenUDP(addr string) (*net.UDPConn, error) {
 }
 
 func (l *redirectListener) accept() (conn net.Conn, err error) {
-	b := bufpool.Get(l.md.readBufferSize)
+	for {
+		b := bufpool.Get(l.md.readBufferSize)
 
-	n, raddr, dstAddr, err := readFromUDP(l.ln, b)
-	if err != nil {
-		l.logger.Error(err)
-		return
-	}
+		n, raddr, dstAddr, err := readFromUDP(l.ln, b)
+		if err != nil {
+			bufpool.Put(b)
+			l.logger.Error(err)
+			return nil, err
+		}
 
-	l.logger.Infof("%s >> %s", raddr.String(), dstAddr.String())
+		// the datagrams received before the connected socket of the session is ready
+		// are delivered to the existing session instead of creating a new one.
+		key := sessionKey(raddr, dstAddr)
+		if s := l.session(key); s != nil {
+			l.deliver(s, b[:n])
+			continue
+		}
 
-	network := "udp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "udp4"
-	}
-	c, err := dialUDP(network, dstAddr, raddr)
-	if err != nil {
-		l.logger.Error(err)
-		return
-	}
+		l.logger.Infof("%s >> %s", raddr.String(), dstAddr.String())
 
-	conn = &redirConn{
-		Conn: c,
-		buf:  b[:n],
-		ttl:  l.md.ttl,
+		network := "udp"
+		if xnet.IsIPv4(l.options.Addr) {
+			network = "udp4"
+		}
+		c, err := dialUDP(network, dstAddr, raddr)
+		if err != nil {
+			bufpool.Put(b)
+			l.logger.Error(err)
+			return nil, err
+		}
+
+		return l.newSession(key, c, b[:n]), nil
 	}
-	return
 }
 
 // ReadFromUDP reads a UDP packet from c, copying the payload into b.
@@ -93,32 +111,37 @@ func readFromUDP(conn *net.UDPConn, b []byte) (n int, remoteAddr *net.UDPAddr, d
 
 	for _, msg := range msgs {
 		if msg.Header.Level == unix.SOL_IP && msg.Header.Type == unix.IP_RECVORIGDSTADDR {
-			originalDstRaw := &unix.RawSockaddrInet4{}
-			if err = binary.Read(bytes.NewReader(msg.Data), binary.LittleEndian, originalDstRaw); err != nil {
+			pp := &unix.RawSockaddrInet4{}
+			if err = binary.Read(bytes.NewReader(msg.Data), binary.LittleEndian, pp); err != nil {
 				return 0, nil, nil, fmt.Errorf("reading original destination address: %s", err)
 			}
+			if pp.Family != unix.AF_INET {
+				return 0, nil, nil, fmt.Errorf("original destination is an unsupported network family")
+			}
+			p := (*[2]byte)(unsafe.Pointer(&pp.Port))
+			dstAddr = &net.UDPAddr{
+				IP:   net.IPv4(pp.Addr[0], pp.Addr[1], pp.Addr[2], pp.Addr[3]),
+				Port: int(p[0])<<8 + int(p[1]),
+			}
+			break
+		}
 
-			switch originalDstRaw.Family {
-			case unix.AF_INET:
-				pp := (*unix.RawSockaddrInet4)(unsafe.Pointer(originalDstRaw))
-				p := (*[2]byte)(unsafe.Pointer(&pp.Port))
-				dstAddr = &net.UDPAddr{
-					IP:   net.IPv4(pp.Addr[0], pp.Addr[1], pp.Addr[2], pp.Addr[3]),
-					Port: int(p[0])<<8 + int(p[1]),
-				}
-
-			case unix.AF_INET6:
-				pp := (*unix.RawSockaddrInet6)(unsafe.Pointer(originalDstRaw))
-				p := (*[2]byte)(unsafe.Pointer(&pp.Port))
-				dstAddr = &net.UDPAddr{
-					IP:   net.IP(pp.Addr[:]),
-					Port: int(p[0])<<8 + int(p[1]),
-					Zone: strconv.Itoa(int(pp.Scope_id)),
-				}
-
-			default:
+		if msg.Header.Level == unix.SOL_IPV6 && msg.Header.Type == unix.IPV6_RECVORIGDSTADDR {
+			pp := &unix.RawSockaddrInet6{}
+			if err = binary.Read(bytes.NewReader(msg.Data), binary.LittleEndian, pp); err != nil {
+				return 0, nil, nil, fmt.Errorf("reading original destination address: %s", err)
+			}
+			if pp.Family != unix.AF_INET6 {
 				return 0, nil, nil, fmt.Errorf("original destination is an unsupported network family")
 			}
+			p := (*[2]byte)(unsafe.Pointer(&pp.Port))
+			dstAddr = &net.UDPAddr{
+				IP:   net.IP(pp.Addr[:]),
+				Port: int(p[0])<<8 + int(p[1]),
+			}
+			if pp.Scope_id != 0 {
+				dstAddr.Zone = strconv.Itoa(int(pp.Scope_id))
+			}
 			break
 		}
 	}
@@ -144,14 +167,22 @@ func dialUDP(network string, laddr *net.UDPAddr, raddr *net.UDPAddr) (net.Conn,
 		return nil, &net.OpError{Op: "dial", Err: fmt.Errorf("build local socket address: %s", err)}
 	}
 
-	fileDescriptor, err := unix.Socket(udpAddrFamily(network, laddr, raddr), unix.SOCK_DGRAM, 0)
+	family := udpAddrFamily(network, laddr, raddr)
+	fileDescriptor, err := unix.Socket(family, unix.SOCK_DGRAM, 0)
 	if err != nil {
 		return nil, &net.OpError{Op: "dial", Err: fmt.Errorf("socket open: %s", err)}
 	}
 
-	if err = unix.SetsockoptInt(fileDescriptor, unix.SOL_IP, unix.IP_TRANSPARENT, 1); err != nil {
-		unix.Close(fileDescriptor)
-		return nil, &net.OpError{Op: "dial", Err: fmt.Errorf("set socket option: IP_TRANSPARENT: %s", err)}
+	if family == unix.AF_INET6 {
+		if err = unix.SetsockoptInt(fileDescriptor, unix.SOL_IPV6, unix.IPV6_TRANSPARENT, 1); err != nil {
+			unix.Close(fileDescriptor)
+			return nil, &net.OpError{Op: "dial", Err: fmt.Errorf("set socket option: IPV6_TRANSPARENT: %s", err)}
+		}
+	} else {
+		if err = unix.SetsockoptInt(fileDescriptor, unix.SOL_IP, unix.IP_TRANSPARENT, 1); err != nil {
+			unix.Close(fileDescriptor)
+			return nil, &net.OpError{Op: "dial", Err: fmt.Errorf("set socket option: IP_TRANSPARENT: %s", err)}
+		}
 	}
 
 	if err = unix.SetsockoptInt(fileDescriptor, unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); err != nil {
@@ -200,9 +231,18 @@ func udpAddrToSocketAddr(addr *net.UDPAddr) (unix.Sockaddr, error) {
 		ip := [16]byte{}
 		copy(ip[:], addr.IP.To16())
 
-		zoneID, err := strconv.ParseUint(addr.Zone, 10, 32)
-		if err != nil {
-			return nil, err
+		var zoneID uint64
+		if addr.Zone != "" {
+			id, err := strconv.ParseUint(addr.Zone, 10, 32)
+			if err != nil {
+				// the zone of the address received by the socket is the interface name.
+				ifce, ierr := net.InterfaceByName(addr.Zone)
+				if ierr != nil {
+					return nil, err
+				}
+				id = uint64(ifce.Index)
+			}
+			zoneID = id
 		}
 
 		return &unix.SockaddrInet6{Addr: ip, Port: addr.Port, ZoneId: uint32(zoneID)}, nil
@@ -221,7 +261,7 @@ func udpAddrFamily(net string, laddr, raddr *net.UDPAddr) int {
 	}
 
 	if (laddr == nil || laddr.IP.To4() != nil) &&
-		(raddr == nil || laddr.IP.To4() != nil) {
+		(raddr == nil || raddr.IP.To4() != nil) {
 		return unix.AF_INET
 	}
 	return unix.AF_INET6
@@ -0,0 +1,69 @@
+package udp
+
+import (
+	"net"
+
+	"github.com/go-gost/core/metrics"
+	"github.com/go-gost/x/internal/bufpool"
+	xudp "github.com/go-gost/x/internal/net/udp"
+	xmetrics "github.com/go-gost/x/metrics"
+)
+
+// session returns the NAT session of key, nil is returned if there is no such session.
+func (l *redirectListener) session(key string) *redirConn {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.sessions[key]
+}
+
+// newSession creates the NAT session of key on the connection c connected to the client,
+// the session is removed from the table when it is closed.
+func (l *redirectListener) newSession(key string, c net.Conn, b []byte) *redirConn {
+	var s *redirConn
+	s = newRedirConn(c, b, l.md.ttl, l.md.readBufferSize, func() {
+		l.mu.Lock()
+		if l.sessions[key] == s {
+			delete(l.sessions, key)
+			l.sessionsGauge(-1)
+		}
+		l.mu.Unlock()
+	})
+
+	l.mu.Lock()
+	l.sessions[key] = s
+	l.mu.Unlock()
+	l.sessionsGauge(1)
+
+	return s
+}
+
+func (l *redirectListener) sessionsGauge(delta float64) {
+	if v := xmetrics.GetGauge(xmetrics.MetricUDPSessionsGauge, metrics.Labels{
+		"service": l.options.Service,
+	}); v != nil {
+		v.Add(delta)
+	}
+}
+
+func (l *redirectListener) drop(reason string) {
+	if v := xmetrics.GetCounter(xmetrics.MetricUDPSessionDropsCounter, metrics.Labels{
+		"service": l.options.Service,
+		"reason":  reason,
+	}); v != nil {
+		v.Inc()
+	}
+}
+
+// deliver delivers the datagram b received by the listener socket to the session s,
+// the datagram is dropped if the queue of the session is full.
+func (l *redirectListener) deliver(s *redirConn, b []byte) {
+	if !s.deliver(b) {
+		bufpool.Put(b)
+		l.drop(xudp.DropReasonQueue)
+	}
+}
+
+func sessionKey(raddr, dstAddr net.Addr) string {
+	return raddr.String() + "/" + dstAddr.String()
+}
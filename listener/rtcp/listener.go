@@ -24,6 +24,7 @@ func init() {
 
 type rtcpListener struct {
 	laddr   net.Addr
+	baddr   net.Addr
 	ln      net.Listener
 	router  *chain.Router
 	logger  logger.Logger
@@ -49,10 +50,7 @@ func (l *rtcpListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 	if laddr, _ := net.ResolveTCPAddr(network, l.options.Addr); laddr != nil {
 		l.laddr = laddr
 	}
@@ -77,10 +75,7 @@ func (l *rtcpListener) Accept() (conn net.Conn, err error) {
 
 	ln := l.getListener()
 	if ln == nil {
-		ln, err = l.router.Bind(
-			context.Background(), "tcp", l.laddr.String(),
-			chain.MuxBindOption(true),
-		)
+		ln, err = l.bind()
 		if err != nil {
 			return nil, listener.NewAcceptError(err)
 		}
@@ -109,9 +104,56 @@ func (l *rtcpListener) Accept() (conn net.Conn, err error) {
 }
 
 func (l *rtcpListener) Addr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.baddr != nil {
+		return l.baddr
+	}
 	return l.laddr
 }
 
+// bind binds on the server, the port allocated by the server is requested again when rebinding.
+func (l *rtcpListener) bind() (net.Listener, error) {
+	bind := func(addr string) (net.Listener, error) {
+		return l.router.Bind(
+			context.Background(), "tcp", addr,
+			chain.MuxBindOption(true),
+		)
+	}
+
+	l.mu.Lock()
+	baddr := l.baddr
+	l.mu.Unlock()
+
+	var ln net.Listener
+	var err error
+	host, port, _ := net.SplitHostPort(l.laddr.String())
+	if port == "0" && baddr != nil {
+		if _, bport, _ := net.SplitHostPort(baddr.String()); bport != "" {
+			ln, err = bind(net.JoinHostPort(host, bport))
+			if err != nil {
+				l.logger.Warnf("rebind on port %s: %v", bport, err)
+			}
+		}
+	}
+	if ln == nil {
+		if ln, err = bind(l.laddr.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	if port == "0" {
+		l.logger.Infof("bind on %s/%s, allocated by the server", ln.Addr(), ln.Addr().Network())
+	}
+
+	l.mu.Lock()
+	l.baddr = ln.Addr()
+	l.mu.Unlock()
+
+	return ln, nil
+}
+
 func (l *rtcpListener) Close() error {
 	select {
 	case <-l.closed:
@@ -23,6 +23,7 @@ func init() {
 
 type rudpListener struct {
 	laddr   net.Addr
+	baddr   net.Addr
 	ln      net.Listener
 	router  *chain.Router
 	closed  chan struct{}
@@ -49,10 +50,7 @@ func (l *rudpListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "udp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "udp4"
-	}
+	network := xnet.ListenNetwork("udp", l.options.Addr, md)
 	if laddr, _ := net.ResolveUDPAddr(network, l.options.Addr); laddr != nil {
 		l.laddr = laddr
 	}
@@ -77,13 +75,7 @@ func (l *rudpListener) Accept() (conn net.Conn, err error) {
 
 	ln := l.getListener()
 	if ln == nil {
-		ln, err = l.router.Bind(
-			context.Background(), "udp", l.laddr.String(),
-			chain.BacklogBindOption(l.md.backlog),
-			chain.UDPConnTTLBindOption(l.md.ttl),
-			chain.UDPDataBufferSizeBindOption(l.md.readBufferSize),
-			chain.UDPDataQueueSizeBindOption(l.md.readQueueSize),
-		)
+		ln, err = l.bind()
 		if err != nil {
 			return nil, listener.NewAcceptError(err)
 		}
@@ -115,9 +107,59 @@ func (l *rudpListener) Accept() (conn net.Conn, err error) {
 }
 
 func (l *rudpListener) Addr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.baddr != nil {
+		return l.baddr
+	}
 	return l.laddr
 }
 
+// bind binds on the server, the port allocated by the server is requested again when rebinding.
+func (l *rudpListener) bind() (net.Listener, error) {
+	bind := func(addr string) (net.Listener, error) {
+		return l.router.Bind(
+			context.Background(), "udp", addr,
+			chain.BacklogBindOption(l.md.backlog),
+			chain.UDPConnTTLBindOption(l.md.ttl),
+			chain.UDPDataBufferSizeBindOption(l.md.readBufferSize),
+			chain.UDPDataQueueSizeBindOption(l.md.readQueueSize),
+		)
+	}
+
+	l.mu.Lock()
+	baddr := l.baddr
+	l.mu.Unlock()
+
+	var ln net.Listener
+	var err error
+	host, port, _ := net.SplitHostPort(l.laddr.String())
+	if port == "0" && baddr != nil {
+		if _, bport, _ := net.SplitHostPort(baddr.String()); bport != "" {
+			ln, err = bind(net.JoinHostPort(host, bport))
+			if err != nil {
+				l.logger.Warnf("rebind on port %s: %v", bport, err)
+			}
+		}
+	}
+	if ln == nil {
+		if ln, err = bind(l.laddr.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	if port == "0" {
+		l.logger.Infof("bind on %s/%s, allocated by the server", ln.Addr(), ln.Addr().Network())
+	}
+
+	l.mu.Lock()
+	l.baddr = ln.Addr()
+	l.mu.Unlock()
+
+	return ln, nil
+}
+
 func (l *rudpListener) Close() error {
 	select {
 	case <-l.closed:
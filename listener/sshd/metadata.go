@@ -18,6 +18,7 @@ type metadata struct {
 	authorizedKeys map[string]bool
 	backlog        int
 	mptcp          bool
+	reusePort      bool
 }
 
 func (l *sshdListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -66,5 +67,6 @@ func (l *sshdListener) parseMetadata(md mdata.Metadata) (err error) {
 	}
 
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
 	return
 }
@@ -0,0 +1,21 @@
+package tls
+
+import (
+	"crypto/tls"
+
+	mdata "github.com/go-gost/core/metadata"
+	mdx "github.com/go-gost/x/metadata"
+)
+
+type tlsConn struct {
+	*tls.Conn
+}
+
+// Metadata implements metadata.Metadatable interface,
+// the state of the TLS connection is available after the handshake.
+func (c *tlsConn) Metadata() mdata.Metadata {
+	state := c.ConnectionState()
+	return mdx.NewMetadata(map[string]any{
+		"tls": &state,
+	})
+}
@@ -0,0 +1,135 @@
+package tls
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-gost/core/listener"
+	"github.com/go-gost/core/logger"
+	xnet "github.com/go-gost/x/internal/net"
+	tls_util "github.com/go-gost/x/internal/util/tls"
+	pp "github.com/pires/go-proxyproto"
+)
+
+const (
+	fallbackDialTimeout = 10 * time.Second
+)
+
+// fallbackListener forwards the connections not for the proxy to the fallback upstream, e.g. a web server,
+// so that the proxy can share the port with a real website.
+// The connections are forwarded before the TLS handshake, so the upstream serves the TLS by itself.
+type fallbackListener struct {
+	net.Listener
+	cqueue  chan net.Conn
+	errChan chan error
+	md      metadata
+	logger  logger.Logger
+}
+
+func newFallbackListener(ln net.Listener, md metadata, log logger.Logger) net.Listener {
+	l := &fallbackListener{
+		Listener: ln,
+		cqueue:   make(chan net.Conn, md.backlog),
+		errChan:  make(chan error, 1),
+		md:       md,
+		logger:   log,
+	}
+	go l.listenLoop()
+	return l
+}
+
+func (l *fallbackListener) Accept() (conn net.Conn, err error) {
+	var ok bool
+	select {
+	case conn = <-l.cqueue:
+	case err, ok = <-l.errChan:
+		if !ok {
+			err = listener.ErrClosed
+		}
+	}
+	return
+}
+
+func (l *fallbackListener) listenLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.errChan <- err
+			close(l.errChan)
+			return
+		}
+		go l.dispatch(conn)
+	}
+}
+
+func (l *fallbackListener) dispatch(conn net.Conn) {
+	br := bufio.NewReaderSize(conn, tls_util.ClientHelloBufferSize)
+
+	conn.SetReadDeadline(time.Now().Add(l.md.fallbackTimeout))
+	hello, err := tls_util.PeekClientHello(br)
+	conn.SetReadDeadline(time.Time{})
+
+	conn = xnet.NewBufferReaderConn(conn, br)
+
+	if err == nil && l.match(hello.ServerName) {
+		select {
+		case l.cqueue <- conn:
+		default:
+			conn.Close()
+			l.logger.Warnf("connection queue is full, client %s discarded", conn.RemoteAddr())
+		}
+		return
+	}
+
+	log := l.logger.WithFields(map[string]any{
+		"remote": conn.RemoteAddr().String(),
+		"local":  conn.LocalAddr().String(),
+	})
+	if err != nil {
+		log.Debugf("fallback to %s: %v", l.md.fallback, err)
+	} else {
+		log.Debugf("fallback to %s: server name %q", l.md.fallback, hello.ServerName)
+	}
+	if err := l.forward(conn); err != nil {
+		log.Errorf("fallback to %s: %v", l.md.fallback, err)
+	}
+}
+
+// match checks whether the server name is served by the proxy,
+// all the server names are matched if no server name is specified.
+func (l *fallbackListener) match(serverName string) bool {
+	if len(l.md.serverNames) == 0 {
+		return true
+	}
+	serverName = strings.ToLower(strings.TrimSuffix(serverName, "."))
+	for _, name := range l.md.serverNames {
+		if name == serverName {
+			return true
+		}
+		if strings.HasPrefix(name, "*.") && strings.HasSuffix(serverName, name[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *fallbackListener) forward(conn net.Conn) error {
+	defer conn.Close()
+
+	cc, err := net.DialTimeout("tcp", l.md.fallback, fallbackDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	// the address of the client is passed to the upstream by the PROXY protocol header.
+	if v := l.md.fallbackProxyProtocol; v == 1 || v == 2 {
+		if _, err := pp.HeaderProxyFromAddrs(byte(v), conn.RemoteAddr(), conn.LocalAddr()).WriteTo(cc); err != nil {
+			return err
+		}
+	}
+
+	return xnet.Transport(conn, cc)
+}
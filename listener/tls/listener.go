@@ -12,6 +12,8 @@ import (
 	admission "github.com/go-gost/x/admission/wrapper"
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/net/proxyproto"
+	"github.com/go-gost/x/internal/util/shaping"
+	"github.com/go-gost/x/internal/util/systemd"
 	climiter "github.com/go-gost/x/limiter/conn/wrapper"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
 	metrics "github.com/go-gost/x/metrics/wrapper"
@@ -46,17 +48,17 @@ func (l *tlsListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 
 	lc := net.ListenConfig{}
 	if l.md.mptcp {
 		lc.SetMultipathTCP(true)
 		l.logger.Debugf("mptcp enabled: %v", lc.MultipathTCP())
 	}
-	ln, err := lc.Listen(context.Background(), network, l.options.Addr)
+	if l.md.reusePort {
+		lc.Control = xnet.ReusePortControl(lc.Control)
+	}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
 	if err != nil {
 		return
 	}
@@ -67,13 +69,23 @@ func (l *tlsListener) Init(md md.Metadata) (err error) {
 	ln = limiter.WrapListener(l.options.TrafficLimiter, ln)
 	ln = climiter.WrapListener(l.options.ConnLimiter, ln)
 
+	if l.md.fallback != "" {
+		ln = newFallbackListener(ln, l.md, l.logger)
+	}
+
 	l.ln = tls.NewListener(ln, l.options.TLSConfig)
 
 	return
 }
 
 func (l *tlsListener) Accept() (conn net.Conn, err error) {
-	return l.ln.Accept()
+	if conn, err = l.ln.Accept(); err != nil {
+		return
+	}
+	if tc, ok := conn.(*tls.Conn); ok {
+		conn = &tlsConn{Conn: tc}
+	}
+	return shaping.Conn(conn, l.md.shaping), nil
 }
 
 func (l *tlsListener) Addr() net.Addr {
@@ -1,15 +1,56 @@
 package tls
 
 import (
+	"strings"
+	"time"
+
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/shaping"
+)
+
+const (
+	defaultBacklog         = 128
+	defaultFallbackTimeout = 10 * time.Second
 )
 
 type metadata struct {
-	mptcp bool
+	mptcp     bool
+	reusePort bool
+	shaping   *shaping.Profile
+
+	// fallback is the address of the upstream, e.g. a web server,
+	// which serves the non-TLS connections and the TLS connections of the server names not in serverNames.
+	fallback              string
+	serverNames           []string
+	fallbackTimeout       time.Duration
+	fallbackProxyProtocol int
+	backlog               int
 }
 
 func (l *tlsListener) parseMetadata(md mdata.Metadata) (err error) {
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
+	l.md.shaping, err = shaping.ParseProfile(md)
+	if err != nil {
+		return
+	}
+
+	l.md.fallback = mdutil.GetString(md, "tls.fallback", "fallback")
+	for _, name := range mdutil.GetStrings(md, "tls.serverNames", "serverNames") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			l.md.serverNames = append(l.md.serverNames, name)
+		}
+	}
+	l.md.fallbackTimeout = mdutil.GetDuration(md, "tls.fallback.timeout", "fallback.timeout")
+	if l.md.fallbackTimeout <= 0 {
+		l.md.fallbackTimeout = defaultFallbackTimeout
+	}
+	l.md.fallbackProxyProtocol = mdutil.GetInt(md, "tls.fallback.proxyProtocol", "fallback.proxyProtocol")
+
+	l.md.backlog = mdutil.GetInt(md, "tls.backlog", "backlog")
+	if l.md.backlog <= 0 {
+		l.md.backlog = defaultBacklog
+	}
 	return
 }
@@ -2,6 +2,7 @@ package tun
 
 import (
 	"context"
+	"io"
 	"net"
 	"time"
 
@@ -19,6 +20,10 @@ import (
 
 func init() {
 	registry.ListenerRegistry().Register("tun", NewListener)
+	mdx.RegisterDefaults(mdx.KindListener, "tun",
+		mdx.Default{Keys: []string{"mtu"}, Value: defaultMTU},
+		mdx.Default{Keys: []string{"tun.rbuf", "rbuf", "readBufferSize"}, Value: defaultReadBufferSize},
+	)
 }
 
 type tunListener struct {
@@ -47,10 +52,7 @@ func (l *tunListener) Init(md mdata.Metadata) (err error) {
 		return
 	}
 
-	network := "udp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "udp4"
-	}
+	network := xnet.ListenNetwork("udp", l.options.Addr, md)
 	l.addr, err = net.ResolveUDPAddr(network, l.options.Addr)
 	if err != nil {
 		return
@@ -67,7 +69,17 @@ func (l *tunListener) listenLoop() {
 	for {
 		ctx, cancel := context.WithCancel(context.Background())
 		err := func() error {
-			ifce, name, ip, err := l.createTun()
+			fromFD := l.md.fd >= 0 || l.md.fdSocket != ""
+
+			var ifce io.ReadWriteCloser
+			var name string
+			var ip net.IP
+			var err error
+			if fromFD {
+				ifce, name, ip, err = l.createTunFromFD()
+			} else {
+				ifce, name, ip, err = l.createTun()
+			}
 			if err != nil {
 				if ifce != nil {
 					ifce.Close()
@@ -75,14 +87,18 @@ func (l *tunListener) listenLoop() {
 				return err
 			}
 
-			itf, err := net.InterfaceByName(name)
-			if err != nil {
-				return err
-			}
+			if fromFD {
+				l.logger.Infof("name: %s, net: %s, mtu: %d, fd", name, ip, l.md.config.MTU)
+			} else {
+				itf, err := net.InterfaceByName(name)
+				if err != nil {
+					return err
+				}
 
-			addrs, _ := itf.Addrs()
-			l.logger.Infof("name: %s, net: %s, mtu: %d, addrs: %s",
-				itf.Name, ip, itf.MTU, addrs)
+				addrs, _ := itf.Addrs()
+				l.logger.Infof("name: %s, net: %s, mtu: %d, addrs: %s",
+					itf.Name, ip, itf.MTU, addrs)
+			}
 
 			var c net.Conn
 			c = &conn{
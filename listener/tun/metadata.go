@@ -21,6 +21,11 @@ const (
 type metadata struct {
 	config         *tun_util.Config
 	readBufferSize int
+	// the file descriptor of an already opened tun device, or
+	// the unix socket path to receive the descriptor from, -1 if not set.
+	fd       int
+	fdSocket string
+	fdUsed   bool
 }
 
 func (l *tunListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -34,6 +39,12 @@ func (l *tunListener) parseMetadata(md mdata.Metadata) (err error) {
 		gateway = "gw"
 	)
 
+	l.md.fd = -1
+	if md.IsExists("fd") {
+		l.md.fd = mdutil.GetInt(md, "fd")
+	}
+	l.md.fdSocket = mdutil.GetString(md, "fd.socket")
+
 	l.md.readBufferSize = mdutil.GetInt(md, "tun.rbuf", "rbuf", "readBufferSize")
 	if l.md.readBufferSize <= 0 {
 		l.md.readBufferSize = defaultReadBufferSize
@@ -3,7 +3,7 @@ package tun
 import (
 	"io"
 
-	"github.com/go-gost/core/common/bufpool"
+	"github.com/go-gost/x/internal/bufpool"
 	"golang.zx2c4.com/wireguard/tun"
 )
 
@@ -0,0 +1,10 @@
+package tun
+
+import (
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+func createTunDeviceFromFD(fd int, mtu int) (tun.Device, string, error) {
+	// the netlink socket is not allowed on Android, the unmonitored device does not use it.
+	return tun.CreateUnmonitoredTUNFromFD(fd)
+}
@@ -0,0 +1,22 @@
+//go:build !linux && !windows
+
+package tun
+
+import (
+	"os"
+
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+func createTunDeviceFromFD(fd int, mtu int) (tun.Device, string, error) {
+	dev, err := tun.CreateTUNFromFile(os.NewFile(uintptr(fd), "/dev/tun"), mtu)
+	if err != nil {
+		return nil, "", err
+	}
+	name, err := dev.Name()
+	if err != nil {
+		dev.Close()
+		return nil, "", err
+	}
+	return dev, name, nil
+}
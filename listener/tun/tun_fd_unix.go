@@ -0,0 +1,77 @@
+//go:build !windows
+
+package tun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// createTunFromFD creates the tun device from the file descriptor opened by others,
+// e.g. the VpnService of Android. The device is configured by the owner of the descriptor,
+// so neither the addresses nor the routes are set.
+func (l *tunListener) createTunFromFD() (dev io.ReadWriteCloser, name string, ip net.IP, err error) {
+	fd := l.md.fd
+	if l.md.fdSocket != "" {
+		if fd, err = recvFD(l.md.fdSocket); err != nil {
+			return
+		}
+	} else if l.md.fdUsed {
+		// the descriptor is closed along with the device, it can not be used again.
+		err = fmt.Errorf("tun fd %d is closed", fd)
+		return
+	}
+	l.md.fdUsed = true
+
+	ifce, name, err := createTunDeviceFromFD(fd, l.md.config.MTU)
+	if err != nil {
+		unix.Close(fd)
+		return
+	}
+
+	dev = &tunDevice{
+		dev:            ifce,
+		readBufferSize: l.md.readBufferSize,
+	}
+	if len(l.md.config.Net) > 0 {
+		ip = l.md.config.Net[0].IP
+	}
+	return
+}
+
+// recvFD receives a file descriptor by SCM_RIGHTS from the unix socket path.
+func recvFD(path string) (int, error) {
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return -1, err
+	}
+	defer conn.Close()
+
+	b := make([]byte, 1)
+	oob := make([]byte, unix.CmsgSpace(4))
+	_, oobn, _, _, err := conn.ReadMsgUnix(b, oob)
+	if err != nil {
+		return -1, err
+	}
+
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return -1, err
+	}
+	for i := range msgs {
+		fds, err := unix.ParseUnixRights(&msgs[i])
+		if err != nil || len(fds) == 0 {
+			continue
+		}
+		// only the first descriptor is used, the extra ones are closed.
+		for _, fd := range fds[1:] {
+			unix.Close(fd)
+		}
+		return fds[0], nil
+	}
+	return -1, errors.New("no file descriptor received")
+}
@@ -0,0 +1,12 @@
+package tun
+
+import (
+	"errors"
+	"io"
+	"net"
+)
+
+func (l *tunListener) createTunFromFD() (dev io.ReadWriteCloser, name string, ip net.IP, err error) {
+	err = errors.New("tun fd is not supported on windows")
+	return
+}
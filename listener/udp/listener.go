@@ -3,13 +3,14 @@ package udp
 import (
 	"net"
 
-	"github.com/go-gost/core/common/net/udp"
 	"github.com/go-gost/core/listener"
 	"github.com/go-gost/core/logger"
 	md "github.com/go-gost/core/metadata"
 	admission "github.com/go-gost/x/admission/wrapper"
 	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/net/udp"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
+	mdx "github.com/go-gost/x/metadata"
 	metrics "github.com/go-gost/x/metrics/wrapper"
 	"github.com/go-gost/x/registry"
 	stats "github.com/go-gost/x/stats/wrapper"
@@ -17,6 +18,12 @@ import (
 
 func init() {
 	registry.ListenerRegistry().Register("udp", NewListener)
+	mdx.RegisterDefaults(mdx.KindListener, "udp",
+		mdx.Default{Keys: []string{"ttl"}, Value: defaultTTL},
+		mdx.Default{Keys: []string{"readBufferSize"}, Value: defaultReadBufferSize},
+		mdx.Default{Keys: []string{"readQueueSize"}, Value: defaultReadQueueSize},
+		mdx.Default{Keys: []string{"backlog"}, Value: defaultBacklog},
+	)
 }
 
 type udpListener struct {
@@ -42,10 +49,7 @@ func (l *udpListener) Init(md md.Metadata) (err error) {
 		return
 	}
 
-	network := "udp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "udp4"
-	}
+	network := xnet.ListenNetwork("udp", l.options.Addr, md)
 	laddr, err := net.ResolveUDPAddr(network, l.options.Addr)
 	if err != nil {
 		return
@@ -67,6 +71,8 @@ func (l *udpListener) Init(md md.Metadata) (err error) {
 		ReadBufferSize: l.md.readBufferSize,
 		KeepAlive:      l.md.keepalive,
 		TTL:            l.md.ttl,
+		MaxSessions:    l.md.maxSessions,
+		Service:        l.options.Service,
 		Logger:         l.logger,
 	})
 	return
@@ -20,6 +20,7 @@ type metadata struct {
 	backlog        int
 	keepalive      bool
 	ttl            time.Duration
+	maxSessions    int
 }
 
 func (l *udpListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -29,6 +30,7 @@ func (l *udpListener) parseMetadata(md mdata.Metadata) (err error) {
 		backlog        = "backlog"
 		keepalive      = "keepalive"
 		ttl            = "ttl"
+		maxSessions    = "maxSessions"
 	)
 
 	l.md.ttl = mdutil.GetDuration(md, ttl)
@@ -50,6 +52,7 @@ func (l *udpListener) parseMetadata(md mdata.Metadata) (err error) {
 		l.md.backlog = defaultBacklog
 	}
 	l.md.keepalive = mdutil.GetBool(md, keepalive)
+	l.md.maxSessions = mdutil.GetInt(md, maxSessions)
 
 	return
 }
@@ -0,0 +1,93 @@
+package uot
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/listener"
+	"github.com/go-gost/core/logger"
+	md "github.com/go-gost/core/metadata"
+	admission "github.com/go-gost/x/admission/wrapper"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/net/proxyproto"
+	"github.com/go-gost/x/internal/util/systemd"
+	"github.com/go-gost/x/internal/util/uot"
+	climiter "github.com/go-gost/x/limiter/conn/wrapper"
+	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
+	metrics "github.com/go-gost/x/metrics/wrapper"
+	"github.com/go-gost/x/registry"
+	stats "github.com/go-gost/x/stats/wrapper"
+)
+
+func init() {
+	registry.ListenerRegistry().Register("uot", NewListener)
+}
+
+// uotListener accepts the stream connections carrying the length-prefixed UDP datagrams,
+// each accepted connection reads and writes a datagram at a time.
+type uotListener struct {
+	ln      net.Listener
+	logger  logger.Logger
+	md      metadata
+	options listener.Options
+}
+
+func NewListener(opts ...listener.Option) listener.Listener {
+	options := listener.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &uotListener{
+		logger:  options.Logger,
+		options: options,
+	}
+}
+
+func (l *uotListener) Init(md md.Metadata) (err error) {
+	if err = l.parseMetadata(md); err != nil {
+		return
+	}
+
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
+
+	lc := net.ListenConfig{}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
+	if err != nil {
+		return
+	}
+
+	ln = proxyproto.WrapListener(l.options.ProxyProtocol, ln, 10*time.Second)
+	ln = metrics.WrapListener(l.options.Service, ln)
+	ln = stats.WrapListener(ln, l.options.Stats)
+	ln = admission.WrapListener(l.options.Admission, ln)
+	ln = limiter.WrapListener(l.options.TrafficLimiter, ln)
+	ln = climiter.WrapListener(l.options.ConnLimiter, ln)
+	l.ln = ln
+
+	return
+}
+
+func (l *uotListener) Accept() (conn net.Conn, err error) {
+	conn, err = l.ln.Accept()
+	if err != nil {
+		return
+	}
+
+	raddr := conn.RemoteAddr()
+	return uot.Conn(conn,
+		uot.TimestampOption(l.md.timestamp),
+		uot.MaxAgeOption(l.md.maxAge),
+		uot.DropCallbackOption(func() {
+			l.logger.Tracef("stale datagram from %s is dropped", raddr)
+		}),
+	), nil
+}
+
+func (l *uotListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+func (l *uotListener) Close() error {
+	return l.ln.Close()
+}
@@ -0,0 +1,24 @@
+package uot
+
+import (
+	"time"
+
+	md "github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+type metadata struct {
+	timestamp bool
+	maxAge    time.Duration
+}
+
+func (l *uotListener) parseMetadata(md md.Metadata) (err error) {
+	const (
+		timestamp = "timestamp"
+		maxAge    = "maxAge"
+	)
+
+	l.md.timestamp = mdutil.GetBool(md, timestamp)
+	l.md.maxAge = mdutil.GetDuration(md, maxAge)
+	return
+}
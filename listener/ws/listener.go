@@ -14,9 +14,12 @@ import (
 	admission "github.com/go-gost/x/admission/wrapper"
 	xnet "github.com/go-gost/x/internal/net"
 	"github.com/go-gost/x/internal/net/proxyproto"
+	"github.com/go-gost/x/internal/util/shaping"
+	"github.com/go-gost/x/internal/util/systemd"
 	ws_util "github.com/go-gost/x/internal/util/ws"
 	climiter "github.com/go-gost/x/limiter/conn/wrapper"
 	limiter "github.com/go-gost/x/limiter/traffic/wrapper"
+	mdx "github.com/go-gost/x/metadata"
 	metrics "github.com/go-gost/x/metrics/wrapper"
 	"github.com/go-gost/x/registry"
 	stats "github.com/go-gost/x/stats/wrapper"
@@ -26,6 +29,12 @@ import (
 func init() {
 	registry.ListenerRegistry().Register("ws", NewListener)
 	registry.ListenerRegistry().Register("wss", NewTLSListener)
+	for _, typ := range []string{"ws", "wss"} {
+		mdx.RegisterDefaults(mdx.KindListener, typ,
+			mdx.Default{Keys: []string{"ws.path", "path"}, Value: defaultPath},
+			mdx.Default{Keys: []string{"ws.backlog", "backlog"}, Value: defaultBacklog},
+		)
+	}
 }
 
 type wsListener struct {
@@ -78,6 +87,9 @@ func (l *wsListener) Init(md md.Metadata) (err error) {
 
 	mux := http.NewServeMux()
 	mux.Handle(l.md.path, http.HandlerFunc(l.upgrade))
+	if l.md.fallback != nil && l.md.path != "/" {
+		mux.Handle("/", l.md.fallback)
+	}
 	l.srv = &http.Server{
 		Addr:              l.options.Addr,
 		Handler:           mux,
@@ -87,17 +99,17 @@ func (l *wsListener) Init(md md.Metadata) (err error) {
 	l.cqueue = make(chan net.Conn, l.md.backlog)
 	l.errChan = make(chan error, 1)
 
-	network := "tcp"
-	if xnet.IsIPv4(l.options.Addr) {
-		network = "tcp4"
-	}
+	network := xnet.ListenNetwork("tcp", l.options.Addr, md)
 
 	lc := net.ListenConfig{}
 	if l.md.mptcp {
 		lc.SetMultipathTCP(true)
 		l.logger.Debugf("mptcp enabled: %v", lc.MultipathTCP())
 	}
-	ln, err := lc.Listen(context.Background(), network, l.options.Addr)
+	if l.md.reusePort {
+		lc.Control = xnet.ReusePortControl(lc.Control)
+	}
+	ln, err := systemd.Listen(context.Background(), &lc, network, l.options.Addr)
 	if err != nil {
 		return
 	}
@@ -155,6 +167,11 @@ func (l *wsListener) upgrade(w http.ResponseWriter, r *http.Request) {
 		log.Trace(string(dump))
 	}
 
+	if l.md.fallback != nil && !websocket.IsWebSocketUpgrade(r) {
+		l.md.fallback.ServeHTTP(w, r)
+		return
+	}
+
 	conn, err := l.upgrader.Upgrade(w, r, l.md.header)
 	if err != nil {
 		l.logger.Error(err)
@@ -163,7 +180,7 @@ func (l *wsListener) upgrade(w http.ResponseWriter, r *http.Request) {
 	}
 
 	select {
-	case l.cqueue <- ws_util.Conn(conn):
+	case l.cqueue <- shaping.Conn(ws_util.Conn(conn), l.md.shaping):
 	default:
 		conn.Close()
 		l.logger.Warnf("connection queue is full, client %s discarded", conn.RemoteAddr())
@@ -6,6 +6,8 @@ import (
 
 	mdata "github.com/go-gost/core/metadata"
 	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/x/internal/util/fallback"
+	"github.com/go-gost/x/internal/util/shaping"
 )
 
 const (
@@ -23,8 +25,12 @@ type metadata struct {
 	writeBufferSize   int
 	enableCompression bool
 	header            http.Header
+	// fallback serves the requests which are not the websocket handshake.
+	fallback *fallback.Fallback
 
-	mptcp bool
+	mptcp     bool
+	reusePort bool
+	shaping   *shaping.Profile
 }
 
 func (l *wsListener) parseMetadata(md mdata.Metadata) (err error) {
@@ -52,6 +58,10 @@ func (l *wsListener) parseMetadata(md mdata.Metadata) (err error) {
 		l.md.header = hd
 	}
 
+	l.md.fallback = fallback.Parse(mdutil.GetString(md, "ws.fallback", "fallback"))
+
 	l.md.mptcp = mdutil.GetBool(md, "mptcp")
+	l.md.reusePort = mdutil.GetBool(md, "reusePort")
+	l.md.shaping, err = shaping.ParseProfile(md)
 	return
 }
@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	mdutil "github.com/go-gost/core/metadata/util"
+)
+
+// The kinds of the components registering the metadata defaults.
+const (
+	KindListener  = "listener"
+	KindHandler   = "handler"
+	KindDialer    = "dialer"
+	KindConnector = "connector"
+)
+
+// Default is the default value of a metadata option of the component.
+type Default struct {
+	// the keys of the option in the order the component looks up, e.g. ws.path and path.
+	Keys []string
+	// the default value, the configured value is coerced to the type of it.
+	Value any
+}
+
+var (
+	defaults   = make(map[string][]Default)
+	defaultsMu sync.RWMutex
+)
+
+// RegisterDefaults registers the defaults of the metadata options of the component type,
+// the defaults are applied by the component itself, they are registered for the effective config.
+func RegisterDefaults(kind, typ string, v ...Default) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+
+	defaults[kind+"/"+typ] = append(defaults[kind+"/"+typ], v...)
+}
+
+// Defaults returns the registered defaults of the metadata options of the component type.
+func Defaults(kind, typ string) []Default {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+
+	return append([]Default(nil), defaults[kind+"/"+typ]...)
+}
+
+// Effective returns the metadata the component of the type actually runs with,
+// the registered defaults are applied and the configured values of the options are coerced to the types of the defaults.
+// The durations are formatted as strings, the unregistered options are returned as configured.
+func Effective(kind, typ string, m map[string]any) map[string]any {
+	md := NewMetadata(m)
+
+	effective := make(map[string]any)
+	for k, v := range m {
+		effective[k] = v
+	}
+
+	for _, d := range Defaults(kind, typ) {
+		if len(d.Keys) == 0 {
+			continue
+		}
+
+		key := d.Keys[0]
+		exists := false
+		for _, k := range d.Keys {
+			if md != nil && md.IsExists(k) {
+				key, exists = k, true
+				break
+			}
+		}
+		// the configured key may be in other cases.
+		for k := range m {
+			if strings.EqualFold(k, key) && k != key {
+				delete(effective, k)
+			}
+		}
+
+		if !exists {
+			effective[key] = formatValue(d.Value)
+			continue
+		}
+
+		var v any
+		switch d.Value.(type) {
+		case bool:
+			v = mdutil.GetBool(md, key)
+		case int:
+			v = mdutil.GetInt(md, key)
+		case float64:
+			v = mdutil.GetFloat(md, key)
+		case time.Duration:
+			v = mdutil.GetDuration(md, key)
+		case string:
+			v = mdutil.GetString(md, key)
+		case []string:
+			v = mdutil.GetStrings(md, key)
+		default:
+			v = md.Get(key)
+		}
+		// the components apply the defaults to the zero values, e.g. the backlog of 0.
+		if _, ok := v.(bool); !ok && (v == nil || reflect.ValueOf(v).IsZero()) {
+			v = d.Value
+		}
+		effective[key] = formatValue(v)
+	}
+
+	return effective
+}
+
+func formatValue(v any) any {
+	if d, ok := v.(time.Duration); ok {
+		return d.String()
+	}
+	return v
+}
@@ -0,0 +1,206 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mdutilPkg = "github.com/go-gost/core/metadata/util."
+)
+
+// StrictMetadata is the metadata tracking the accessed keys and the type coercions,
+// the unknown keys and the values failed to coerce are reported by Err after the metadata is parsed.
+type StrictMetadata struct {
+	md mapMetadata
+	// the original keys by the lower-cased keys.
+	keys     map[string]string
+	accessed map[string]bool
+	// the keys coerced successfully at least once.
+	coerced map[string]bool
+	// the first coercion errors of the keys.
+	errs map[string]error
+	mu   sync.Mutex
+}
+
+func NewStrictMetadata(m map[string]any) *StrictMetadata {
+	md := &StrictMetadata{
+		md:       make(mapMetadata),
+		keys:     make(map[string]string),
+		accessed: make(map[string]bool),
+		coerced:  make(map[string]bool),
+		errs:     make(map[string]error),
+	}
+	for k, v := range m {
+		md.md[strings.ToLower(k)] = v
+		md.keys[strings.ToLower(k)] = k
+	}
+	return md
+}
+
+func (m *StrictMetadata) IsExists(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key = strings.ToLower(key)
+	m.accessed[key] = true
+	return m.md.IsExists(key)
+}
+
+func (m *StrictMetadata) Set(key string, value any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.md.Set(key, value)
+	// the keys set by the component are not the unknown keys.
+	m.accessed[strings.ToLower(key)] = true
+}
+
+func (m *StrictMetadata) Get(key string) any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key = strings.ToLower(key)
+	v := m.md.Get(key)
+	m.accessed[key] = true
+
+	// the value is coerced by the caller, the key is valid if any of the coercions succeeds,
+	// e.g. the value is read by both GetStrings and GetString.
+	var pc [1]uintptr
+	if runtime.Callers(2, pc[:]) == 0 {
+		return v
+	}
+	frame, _ := runtime.CallersFrames(pc[:]).Next()
+	if !strings.HasPrefix(frame.Function, mdutilPkg) {
+		return v
+	}
+	if err := coerce(strings.TrimPrefix(frame.Function, mdutilPkg), v); err != nil {
+		if m.errs[key] == nil {
+			m.errs[key] = err
+		}
+	} else {
+		m.coerced[key] = true
+	}
+
+	return v
+}
+
+// Err returns the error of the unknown keys and the values failed to coerce, nil is returned if there is no error.
+func (m *StrictMetadata) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var unknown []string
+	for k := range m.md {
+		if !m.accessed[k] {
+			unknown = append(unknown, m.keys[k])
+		}
+	}
+	sort.Strings(unknown)
+
+	var errs []error
+	if len(unknown) > 0 {
+		errs = append(errs, fmt.Errorf("unknown metadata keys: %s", strings.Join(unknown, ", ")))
+	}
+
+	var invalid []string
+	for k := range m.errs {
+		if !m.coerced[k] {
+			invalid = append(invalid, k)
+		}
+	}
+	sort.Strings(invalid)
+	for _, k := range invalid {
+		name := m.keys[k]
+		if name == "" {
+			name = k
+		}
+		errs = append(errs, fmt.Errorf("metadata %s: %w", name, m.errs[k]))
+	}
+
+	return errors.Join(errs...)
+}
+
+// coerce checks whether the value can be coerced by the function of the metadata util package.
+func coerce(fn string, v any) error {
+	var typ string
+	switch fn {
+	case "GetBool":
+		typ = "bool"
+		switch vv := v.(type) {
+		case bool, int:
+			return nil
+		case string:
+			if _, err := strconv.ParseBool(vv); err == nil {
+				return nil
+			}
+		}
+	case "GetInt":
+		typ = "int"
+		switch vv := v.(type) {
+		case bool, int:
+			return nil
+		case string:
+			if _, err := strconv.Atoi(vv); err == nil {
+				return nil
+			}
+		}
+	case "GetFloat":
+		typ = "float"
+		switch vv := v.(type) {
+		case float64, int:
+			return nil
+		case string:
+			if _, err := strconv.ParseFloat(vv, 64); err == nil {
+				return nil
+			}
+		}
+	case "GetDuration":
+		typ = "duration"
+		switch vv := v.(type) {
+		case int:
+			return nil
+		case string:
+			if _, err := time.ParseDuration(vv); err == nil {
+				return nil
+			}
+			if _, err := strconv.Atoi(vv); err == nil {
+				return nil
+			}
+		}
+	case "GetString":
+		typ = "string"
+		switch v.(type) {
+		case string, int, int64, uint, uint64, bool, float32, float64:
+			return nil
+		}
+	case "GetStrings":
+		typ = "string list"
+		switch vv := v.(type) {
+		case []string:
+			return nil
+		case []any:
+			for _, s := range vv {
+				if _, ok := s.(string); !ok {
+					return fmt.Errorf("%v is not a valid %s", v, typ)
+				}
+			}
+			return nil
+		}
+	case "GetStringMap", "GetStringMapString":
+		typ = "map"
+		switch v.(type) {
+		case map[string]any, map[any]any:
+			return nil
+		}
+	default:
+		return nil
+	}
+	return fmt.Errorf("%v is not a valid %s", v, typ)
+}
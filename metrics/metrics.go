@@ -17,10 +17,44 @@ const (
 	MetricServiceTransferOutputBytesCounter metrics.MetricName = "gost_service_transfer_output_bytes_total"
 	// Chain node connect duration histogram. Labels: host, chain, node.
 	MetricNodeConnectDurationObserver metrics.MetricName = "gost_chain_node_connect_duration_seconds"
-	// Total service handler errors. Labels: host, service.
+	// Total service handler errors. Labels: host, service, client.
 	MetricServiceHandlerErrorsCounter metrics.MetricName = "gost_service_handler_errors_total"
+	// Total service handler errors by kind. Labels: host, service, error.
+	MetricServiceHandlerErrorKindsCounter metrics.MetricName = "gost_service_handler_error_kinds_total"
 	// Total chain connect errors. Labels: host, chain, node.
 	MetricChainErrorsCounter metrics.MetricName = "gost_chain_errors_total"
+	// Total routes through the chain. Labels: host, chain.
+	MetricChainRoutesCounter metrics.MetricName = "gost_chain_routes_total"
+	// Whether the service is draining. Labels: host, service.
+	MetricServiceDrainingGauge metrics.MetricName = "gost_service_draining"
+	// Total connections rejected by the connection limit of the service. Labels: host, service.
+	MetricServiceConnsRejectedCounter metrics.MetricName = "gost_service_conns_rejected_total"
+	// Number of connections waiting in the queue of the connection limit. Labels: host, service.
+	MetricServiceConnsQueuedGauge metrics.MetricName = "gost_service_conns_queued"
+	// Total relayed connections closed by the idle reaper. Labels: host, kind.
+	MetricIdleConnsReapedCounter metrics.MetricName = "gost_idle_conns_reaped_total"
+	// Number of buffers in use. Labels: host, size.
+	MetricBufferPoolBuffersInUseGauge metrics.MetricName = "gost_bufpool_buffers_in_use"
+	// Number of buffers allocated by the pool. Labels: host, size.
+	MetricBufferPoolBuffersAllocatedGauge metrics.MetricName = "gost_bufpool_buffers_allocated"
+	// Number of multiplexed sessions. Labels: host, kind, protocol.
+	MetricMuxSessionsGauge metrics.MetricName = "gost_mux_sessions"
+	// Number of streams of the multiplexed session. Labels: host, kind, protocol, session.
+	MetricMuxStreamsGauge metrics.MetricName = "gost_mux_streams"
+	// Number of active UDP sessions. Labels: host, service.
+	MetricUDPSessionsGauge metrics.MetricName = "gost_udp_sessions"
+	// Total UDP packets dropped by the session table. Labels: host, service, reason.
+	MetricUDPSessionDropsCounter metrics.MetricName = "gost_udp_session_drops_total"
+	// Resource consumption of the service. Labels: host, service, resource.
+	MetricServiceResourcesGauge metrics.MetricName = "gost_service_resources"
+	// Result of the last self-test of the service, 1 for pass and 0 for fail. Labels: host, service.
+	MetricServiceSelfTestGauge metrics.MetricName = "gost_service_selftest"
+	// Total flows relayed by the service by the classified protocol. Labels: host, service, protocol.
+	MetricServiceFlowProtocolsCounter metrics.MetricName = "gost_service_flow_protocols_total"
+	// Total BitTorrent handshakes, DHT and tracker messages blocked by the handler. Labels: host, service, network.
+	MetricServiceBitTorrentBlockedCounter metrics.MetricName = "gost_service_bittorrent_blocked_total"
+	// Total connections to the SMTP ports refused by the SMTP policy. Labels: host, service, action.
+	MetricServiceSMTPBlockedCounter metrics.MetricName = "gost_service_smtp_blocked_total"
 )
 
 var (
@@ -31,6 +31,60 @@ func NewMetrics() metrics.Metrics {
 					Help: "Current in-flight requests",
 				},
 				[]string{"host", "service", "client"}),
+			MetricServiceDrainingGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricServiceDrainingGauge),
+					Help: "Whether the service is draining the connections",
+				},
+				[]string{"host", "service"}),
+			MetricServiceConnsQueuedGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricServiceConnsQueuedGauge),
+					Help: "Current number of queued connections waiting for the connection limit",
+				},
+				[]string{"host", "service"}),
+			MetricBufferPoolBuffersInUseGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricBufferPoolBuffersInUseGauge),
+					Help: "Current number of buffers in use",
+				},
+				[]string{"host", "size"}),
+			MetricBufferPoolBuffersAllocatedGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricBufferPoolBuffersAllocatedGauge),
+					Help: "Number of buffers allocated by the buffer pool",
+				},
+				[]string{"host", "size"}),
+			MetricMuxSessionsGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricMuxSessionsGauge),
+					Help: "Current number of multiplexed sessions",
+				},
+				[]string{"host", "kind"}),
+			MetricMuxStreamsGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricMuxStreamsGauge),
+					Help: "Current number of streams of the multiplexed sessions",
+				},
+				[]string{"host", "kind"}),
+			MetricUDPSessionsGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricUDPSessionsGauge),
+					Help: "Current number of active UDP sessions",
+				},
+				[]string{"host", "service"}),
+			MetricServiceResourcesGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricServiceResourcesGauge),
+					Help: "Current resource consumption of the service: goroutines, fds, memory (bytes) and bandwidth (bytes per second)",
+				},
+				[]string{"host", "service", "resource"}),
+			MetricServiceSelfTestGauge: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: string(MetricServiceSelfTestGauge),
+					Help: "Result of the last self-test of the service, 1 for pass and 0 for fail",
+				},
+				[]string{"host", "service"}),
 		},
 		counters: map[metrics.MetricName]*prometheus.CounterVec{
 			MetricServiceRequestsCounter: prometheus.NewCounterVec(
@@ -57,12 +111,60 @@ func NewMetrics() metrics.Metrics {
 					Help: "Total service handler errors",
 				},
 				[]string{"host", "service", "client"}),
+			MetricServiceHandlerErrorKindsCounter: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: string(MetricServiceHandlerErrorKindsCounter),
+					Help: "Total service handler errors by kind",
+				},
+				[]string{"host", "service", "error"}),
+			MetricServiceConnsRejectedCounter: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: string(MetricServiceConnsRejectedCounter),
+					Help: "Total connections rejected by the connection limit",
+				},
+				[]string{"host", "service"}),
+			MetricIdleConnsReapedCounter: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: string(MetricIdleConnsReapedCounter),
+					Help: "Total relayed connections closed by the idle reaper",
+				},
+				[]string{"host", "kind"}),
 			MetricChainErrorsCounter: prometheus.NewCounterVec(
 				prometheus.CounterOpts{
 					Name: string(MetricChainErrorsCounter),
 					Help: "Total chain errors",
 				},
 				[]string{"host", "chain", "node"}),
+			MetricChainRoutesCounter: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: string(MetricChainRoutesCounter),
+					Help: "Total number of routes through the chain",
+				},
+				[]string{"host", "chain"}),
+			MetricUDPSessionDropsCounter: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: string(MetricUDPSessionDropsCounter),
+					Help: "Total number of UDP packets dropped by the session table",
+				},
+				[]string{"host", "service", "reason"}),
+			MetricServiceFlowProtocolsCounter: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: string(MetricServiceFlowProtocolsCounter),
+					Help: "Total flows relayed by the service by the classified protocol",
+				},
+				[]string{"host", "service", "protocol"}),
+			MetricServiceBitTorrentBlockedCounter: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: string(MetricServiceBitTorrentBlockedCounter),
+					Help: "Total BitTorrent handshakes, DHT and tracker messages blocked by the handler",
+				},
+				[]string{"host", "service", "network"}),
+			MetricServiceSMTPBlockedCounter: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: string(MetricServiceSMTPBlockedCounter),
+					Help: "Total connections to the SMTP ports refused by the SMTP policy",
+				},
+				[]string{"host", "service", "action"}),
 		},
 		histograms: map[metrics.MetricName]*prometheus.HistogramVec{
 			MetricServiceRequestsDurationObserver: prometheus.NewHistogramVec(
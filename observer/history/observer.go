@@ -0,0 +1,52 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-gost/core/observer"
+	"github.com/go-gost/x/stats"
+)
+
+type historyObserver struct {
+	next observer.Observer
+}
+
+// NewObserver creates an observer recording the stats events into the default store,
+// the events are passed to the next observer if it is not nil.
+func NewObserver(next observer.Observer) observer.Observer {
+	return &historyObserver{
+		next: next,
+	}
+}
+
+func (o *historyObserver) Observe(ctx context.Context, events []observer.Event, opts ...observer.Option) error {
+	store := DefaultStore()
+
+	now := time.Now()
+	for _, e := range events {
+		ev, ok := e.(stats.StatsEvent)
+		if !ok {
+			continue
+		}
+		var client string
+		if ev.Kind == "handler" {
+			// the anonymous clients are counted by the service.
+			if ev.Client == "" {
+				continue
+			}
+			client = ev.Client
+		}
+		store.Record(ev.Service, client, Counters{
+			InputBytes:  ev.InputBytes,
+			OutputBytes: ev.OutputBytes,
+			TotalConns:  ev.TotalConns,
+			TotalErrs:   ev.TotalErrs,
+		}, now)
+	}
+
+	if o.next != nil {
+		return o.next.Observe(ctx, events, opts...)
+	}
+	return nil
+}
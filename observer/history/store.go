@@ -0,0 +1,254 @@
+// Package history stores the traffic history of the services and the clients in memory,
+// the stats are aggregated in the ring buffers at the minute and hour resolutions.
+package history
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMinuteRetention = 24 * time.Hour
+	DefaultHourRetention   = 30 * 24 * time.Hour
+)
+
+// Resolution is the interval of the points of the history.
+type Resolution time.Duration
+
+const (
+	ResolutionMinute = Resolution(time.Minute)
+	ResolutionHour   = Resolution(time.Hour)
+)
+
+// Point is the traffic aggregated in the interval starting at Time.
+type Point struct {
+	// the start of the interval in unix seconds.
+	Time        int64  `json:"time"`
+	InputBytes  uint64 `json:"inputBytes"`
+	OutputBytes uint64 `json:"outputBytes"`
+	// the number of the new connections.
+	Conns uint64 `json:"conns"`
+	Errs  uint64 `json:"errs"`
+}
+
+// Counters is the cumulative counters of the stats.
+type Counters struct {
+	InputBytes  uint64
+	OutputBytes uint64
+	TotalConns  uint64
+	TotalErrs   uint64
+}
+
+type ring struct {
+	step   int64
+	points []Point
+}
+
+func newRing(step time.Duration, retention time.Duration) *ring {
+	n := int(retention / step)
+	if n < 1 {
+		n = 1
+	}
+	return &ring{
+		step:   int64(step / time.Second),
+		points: make([]Point, n),
+	}
+}
+
+func (r *ring) slot(t int64) *Point {
+	t -= t % r.step
+	p := &r.points[(t/r.step)%int64(len(r.points))]
+	if p.Time != t {
+		*p = Point{Time: t}
+	}
+	return p
+}
+
+func (r *ring) add(t int64, delta Counters) {
+	p := r.slot(t)
+	p.InputBytes += delta.InputBytes
+	p.OutputBytes += delta.OutputBytes
+	p.Conns += delta.TotalConns
+	p.Errs += delta.TotalErrs
+}
+
+// query returns the points in [from, to], the intervals without traffic are returned as the zero points.
+func (r *ring) query(from, to int64) []Point {
+	from -= from % r.step
+	if oldest := to - to%r.step - int64(len(r.points)-1)*r.step; from < oldest {
+		from = oldest
+	}
+
+	var points []Point
+	for t := from; t <= to; t += r.step {
+		p := r.points[(t/r.step)%int64(len(r.points))]
+		if p.Time != t {
+			p = Point{Time: t}
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+type series struct {
+	last    Counters
+	updated time.Time
+	minutes *ring
+	hours   *ring
+}
+
+type key struct {
+	service string
+	client  string
+}
+
+// Store is the traffic history of the services and the clients.
+type Store struct {
+	minuteRetention time.Duration
+	hourRetention   time.Duration
+	series          map[key]*series
+	swept           time.Time
+	mu              sync.RWMutex
+}
+
+// NewStore creates a store keeping the minute points for minuteRetention and the hour points for hourRetention,
+// the zero retentions are the defaults.
+func NewStore(minuteRetention, hourRetention time.Duration) *Store {
+	if minuteRetention <= 0 {
+		minuteRetention = DefaultMinuteRetention
+	}
+	if hourRetention <= 0 {
+		hourRetention = DefaultHourRetention
+	}
+	return &Store{
+		minuteRetention: minuteRetention,
+		hourRetention:   hourRetention,
+		series:          make(map[key]*series),
+	}
+}
+
+var (
+	defaultStore   = NewStore(0, 0)
+	defaultStoreMu sync.RWMutex
+)
+
+// DefaultStore returns the store of the history observers and the API.
+func DefaultStore() *Store {
+	defaultStoreMu.RLock()
+	defer defaultStoreMu.RUnlock()
+	return defaultStore
+}
+
+// SetRetention replaces the default store with the store of the retentions if they are changed.
+func SetRetention(minuteRetention, hourRetention time.Duration) {
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+
+	s := NewStore(minuteRetention, hourRetention)
+	if s.minuteRetention != defaultStore.minuteRetention || s.hourRetention != defaultStore.hourRetention {
+		defaultStore = s
+	}
+}
+
+// Retention returns the retention of the points at the resolution.
+func (s *Store) Retention(resolution Resolution) time.Duration {
+	if resolution == ResolutionHour {
+		return s.hourRetention
+	}
+	return s.minuteRetention
+}
+
+// Record records the cumulative counters of the client of the service, the client is empty for the service itself.
+// The difference from the last recorded counters is aggregated into the points of the time.
+func (s *Store) Record(service, client string, counters Counters, t time.Time) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{service: service, client: client}
+	ss := s.series[k]
+	if ss == nil {
+		ss = &series{
+			minutes: newRing(time.Minute, s.minuteRetention),
+			hours:   newRing(time.Hour, s.hourRetention),
+		}
+		s.series[k] = ss
+	}
+
+	// the counters are reset, e.g. the service is recreated.
+	delta := counters
+	if counters.InputBytes >= ss.last.InputBytes && counters.OutputBytes >= ss.last.OutputBytes &&
+		counters.TotalConns >= ss.last.TotalConns && counters.TotalErrs >= ss.last.TotalErrs {
+		delta = Counters{
+			InputBytes:  counters.InputBytes - ss.last.InputBytes,
+			OutputBytes: counters.OutputBytes - ss.last.OutputBytes,
+			TotalConns:  counters.TotalConns - ss.last.TotalConns,
+			TotalErrs:   counters.TotalErrs - ss.last.TotalErrs,
+		}
+	}
+	ss.last = counters
+	ss.updated = t
+
+	ts := t.Unix()
+	ss.minutes.add(ts, delta)
+	ss.hours.add(ts, delta)
+
+	s.sweep(t)
+}
+
+// sweep removes the series not updated in the retention.
+func (s *Store) sweep(t time.Time) {
+	if t.Sub(s.swept) < time.Hour {
+		return
+	}
+	s.swept = t
+
+	for k, ss := range s.series {
+		if t.Sub(ss.updated) > s.hourRetention {
+			delete(s.series, k)
+		}
+	}
+}
+
+// Query returns the points of the client of the service in the time range at the resolution,
+// nil is returned if there is no history.
+func (s *Store) Query(service, client string, resolution Resolution, from, to time.Time) []Point {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ss := s.series[key{service: service, client: client}]
+	if ss == nil {
+		return nil
+	}
+
+	r := ss.minutes
+	if resolution == ResolutionHour {
+		r = ss.hours
+	}
+	return r.query(from.Unix(), to.Unix())
+}
+
+// Clients returns the clients of the service with the history.
+func (s *Store) Clients(service string) []string {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var clients []string
+	for k := range s.series {
+		if k.service == service && k.client != "" {
+			clients = append(clients, k.client)
+		}
+	}
+	return clients
+}
@@ -3,4 +3,6 @@ package recorder
 const (
 	RecorderServiceHandlerSerial = "recorder.service.handler.serial"
 	RecorderServiceHandlerTunnel = "recorder.service.handler.tunnel"
+	// the protocols of the flows classified by the handler.
+	RecorderServiceHandlerProtocol = "recorder.service.handler.protocol"
 )
@@ -0,0 +1,194 @@
+package recorder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/go-gost/core/recorder"
+)
+
+const (
+	defaultRedactReplacement = "***"
+)
+
+type redactRecorderOptions struct {
+	headers     []string
+	cookies     bool
+	queries     []string
+	patterns    []string
+	replacement string
+}
+
+type RedactRecorderOption func(opts *redactRecorderOptions)
+
+// HeadersRedactRecorderOption redacts the values of the headers, in the form of the HTTP header (Name: value)
+// or the JSON field ("Name": "value").
+func HeadersRedactRecorderOption(headers ...string) RedactRecorderOption {
+	return func(opts *redactRecorderOptions) {
+		opts.headers = headers
+	}
+}
+
+// CookiesRedactRecorderOption redacts the values of the cookies in the Cookie and Set-Cookie headers, the names are kept.
+func CookiesRedactRecorderOption(cookies bool) RedactRecorderOption {
+	return func(opts *redactRecorderOptions) {
+		opts.cookies = cookies
+	}
+}
+
+// QueriesRedactRecorderOption redacts the values of the query parameters of the URLs, * for all the parameters.
+func QueriesRedactRecorderOption(queries ...string) RedactRecorderOption {
+	return func(opts *redactRecorderOptions) {
+		opts.queries = queries
+	}
+}
+
+// PatternsRedactRecorderOption redacts the data matching the regular expressions.
+func PatternsRedactRecorderOption(patterns ...string) RedactRecorderOption {
+	return func(opts *redactRecorderOptions) {
+		opts.patterns = patterns
+	}
+}
+
+// ReplacementRedactRecorderOption sets the replacement of the redacted data, default is ***.
+func ReplacementRedactRecorderOption(replacement string) RedactRecorderOption {
+	return func(opts *redactRecorderOptions) {
+		opts.replacement = replacement
+	}
+}
+
+type redactRule struct {
+	re   *regexp.Regexp
+	repl func(match []byte, sub [][]byte) []byte
+}
+
+type redactRecorder struct {
+	recorder    recorder.Recorder
+	rules       []redactRule
+	replacement []byte
+}
+
+// RedactRecorder redacts the sensitive data of the records before they are sent to the recorder r.
+func RedactRecorder(r recorder.Recorder, opts ...RedactRecorderOption) (recorder.Recorder, error) {
+	var options redactRecorderOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.replacement == "" {
+		options.replacement = defaultRedactReplacement
+	}
+
+	rr := &redactRecorder{
+		recorder:    r,
+		replacement: []byte(options.replacement),
+	}
+
+	var headers []string
+	for _, h := range options.headers {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, regexp.QuoteMeta(h))
+		}
+	}
+	if len(headers) > 0 {
+		names := strings.Join(headers, "|")
+		rr.rules = append(rr.rules,
+			// Name: value
+			rr.keepGroup(`(?im)^((?:`+names+`)[ \t]*:[ \t]*)[^\r\n]*`),
+			// "Name": "value" or "Name": ["value"]
+			rr.keepGroup(`(?i)("(?:`+names+`)"\s*:\s*\[?\s*")(?:[^"\\]|\\.)*`),
+		)
+	}
+
+	if options.cookies {
+		rr.rules = append(rr.rules,
+			redactRule{
+				re:   regexp.MustCompile(`(?im)^((?:set-)?cookie[ \t]*:[ \t]*)([^\r\n]*)`),
+				repl: rr.redactCookies,
+			},
+			redactRule{
+				re:   regexp.MustCompile(`(?i)("(?:set-)?cookie"\s*:\s*\[?\s*")((?:[^"\\]|\\.)*)`),
+				repl: rr.redactCookies,
+			},
+		)
+	}
+
+	var queries []string
+	for _, q := range options.queries {
+		if q = strings.TrimSpace(q); q == "*" {
+			queries = []string{`[^=&#\s"]+`}
+			break
+		} else if q != "" {
+			queries = append(queries, regexp.QuoteMeta(q))
+		}
+	}
+	if len(queries) > 0 {
+		rr.rules = append(rr.rules, rr.keepGroup(`([?&](?:`+strings.Join(queries, "|")+`)=)[^&#\s"]*`))
+	}
+
+	for _, pattern := range options.patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact: %w", err)
+		}
+		rr.rules = append(rr.rules, redactRule{
+			re: re,
+			repl: func(match []byte, sub [][]byte) []byte {
+				return rr.replacement
+			},
+		})
+	}
+
+	return rr, nil
+}
+
+// keepGroup returns the rule which keeps the first group of the match and redacts the rest.
+func (r *redactRecorder) keepGroup(expr string) redactRule {
+	return redactRule{
+		re: regexp.MustCompile(expr),
+		repl: func(match []byte, sub [][]byte) []byte {
+			return append(append([]byte{}, sub[1]...), r.replacement...)
+		},
+	}
+}
+
+// redactCookies redacts the values of the cookie pairs, the attributes of Set-Cookie are kept.
+func (r *redactRecorder) redactCookies(match []byte, sub [][]byte) []byte {
+	setCookie := strings.HasPrefix(strings.ToLower(strings.TrimLeft(string(sub[1]), `"`)), "set-")
+
+	var pairs []string
+	for i, pair := range strings.Split(string(sub[2]), ";") {
+		if setCookie && i > 0 {
+			pairs = append(pairs, pair)
+			continue
+		}
+		if name, _, ok := strings.Cut(pair, "="); ok {
+			pair = name + "=" + string(r.replacement)
+		}
+		pairs = append(pairs, pair)
+	}
+	return append(append([]byte{}, sub[1]...), strings.Join(pairs, ";")...)
+}
+
+func (r *redactRecorder) Record(ctx context.Context, b []byte, opts ...recorder.RecordOption) error {
+	return r.recorder.Record(ctx, r.redact(b), opts...)
+}
+
+func (r *redactRecorder) Close() error {
+	if closer, ok := r.recorder.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *redactRecorder) redact(b []byte) []byte {
+	for _, rule := range r.rules {
+		rule := rule
+		b = rule.re.ReplaceAllFunc(b, func(match []byte) []byte {
+			return rule.repl(match, rule.re.FindSubmatch(match))
+		})
+	}
+	return b
+}
@@ -0,0 +1,475 @@
+package recorder
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/recorder"
+	"github.com/rs/xid"
+)
+
+const (
+	defaultS3Region        = "us-east-1"
+	defaultS3BatchSize     = 4 * 1024 * 1024
+	defaultS3FlushInterval = 5 * time.Minute
+	defaultS3Timeout       = 30 * time.Second
+	// the suffix of the objects spilled to the spool directory.
+	s3SpoolSuffix = ".spool"
+	// the batches waiting for the upload, the others are spilled to the spool directory.
+	s3UploadQueueSize = 4
+)
+
+var (
+	errS3RecorderClosed = errors.New("s3: recorder is closed")
+)
+
+type s3RecorderOptions struct {
+	region        string
+	accessKey     string
+	secretKey     string
+	prefix        string
+	pathStyle     bool
+	sep           string
+	batchSize     int
+	flushInterval time.Duration
+	spoolDir      string
+	timeout       time.Duration
+	logger        logger.Logger
+}
+
+type S3RecorderOption func(opts *s3RecorderOptions)
+
+func RegionS3RecorderOption(region string) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.region = region
+	}
+}
+
+func CredentialsS3RecorderOption(accessKey, secretKey string) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.accessKey = accessKey
+		opts.secretKey = secretKey
+	}
+}
+
+// PrefixS3RecorderOption sets the prefix of the object keys.
+func PrefixS3RecorderOption(prefix string) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.prefix = prefix
+	}
+}
+
+// PathStyleS3RecorderOption addresses the bucket by the path (endpoint/bucket/key) instead of the host (bucket.endpoint/key).
+func PathStyleS3RecorderOption(pathStyle bool) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.pathStyle = pathStyle
+	}
+}
+
+// SepS3RecorderOption sets the separator of the records in the object, default is \n.
+func SepS3RecorderOption(sep string) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.sep = sep
+	}
+}
+
+// BatchSizeS3RecorderOption sets the size of the records before compression to upload an object.
+func BatchSizeS3RecorderOption(size int) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.batchSize = size
+	}
+}
+
+// FlushIntervalS3RecorderOption sets the max interval to upload the records.
+func FlushIntervalS3RecorderOption(interval time.Duration) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.flushInterval = interval
+	}
+}
+
+// SpoolDirS3RecorderOption sets the directory to keep the objects failed to upload, they are uploaded again later.
+func SpoolDirS3RecorderOption(dir string) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.spoolDir = dir
+	}
+}
+
+func TimeoutS3RecorderOption(timeout time.Duration) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.timeout = timeout
+	}
+}
+
+func LoggerS3RecorderOption(logger logger.Logger) S3RecorderOption {
+	return func(opts *s3RecorderOptions) {
+		opts.logger = logger
+	}
+}
+
+type s3Recorder struct {
+	endpoint   *url.URL
+	bucket     string
+	options    s3RecorderOptions
+	httpClient *http.Client
+
+	buf bytes.Buffer
+	gz  *gzip.Writer
+	// the size of the records before compression in buf.
+	n      int
+	closed bool
+	mu     sync.Mutex
+	// the batches are uploaded in order by a single goroutine.
+	uploads  chan s3Batch
+	uploaded chan struct{}
+
+	cancelFunc context.CancelFunc
+	done       chan struct{}
+}
+
+type s3Batch struct {
+	key  string
+	data []byte
+}
+
+// S3Recorder records data to the objects of the S3 compatible storage, such as AWS S3, MinIO and GCS (by HMAC keys).
+// The records are batched into the gzip compressed objects, which are uploaded when the batch size or the flush interval is reached.
+func S3Recorder(endpoint, bucket string, opts ...S3RecorderOption) (recorder.Recorder, error) {
+	var options s3RecorderOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.region == "" {
+		options.region = defaultS3Region
+	}
+	if options.sep == "" {
+		options.sep = "\n"
+	}
+	if options.batchSize <= 0 {
+		options.batchSize = defaultS3BatchSize
+	}
+	if options.flushInterval <= 0 {
+		options.flushInterval = defaultS3FlushInterval
+	}
+	if options.timeout <= 0 {
+		options.timeout = defaultS3Timeout
+	}
+	if options.logger == nil {
+		options.logger = logger.Default()
+	}
+
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("s3: bucket is required")
+	}
+
+	if options.spoolDir != "" {
+		if err := os.MkdirAll(options.spoolDir, 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &s3Recorder{
+		endpoint: u,
+		bucket:   bucket,
+		options:  options,
+		httpClient: &http.Client{
+			Timeout: options.timeout,
+		},
+		uploads:    make(chan s3Batch, s3UploadQueueSize),
+		uploaded:   make(chan struct{}),
+		cancelFunc: cancel,
+		done:       make(chan struct{}),
+	}
+	go r.uploadLoop()
+	go r.flushLoop(ctx)
+
+	return r, nil
+}
+
+func (r *s3Recorder) Record(ctx context.Context, b []byte, opts ...recorder.RecordOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errS3RecorderClosed
+	}
+	if r.gz == nil {
+		r.gz = gzip.NewWriter(&r.buf)
+	}
+	r.gz.Write(b)
+	r.gz.Write([]byte(r.options.sep))
+	r.n += len(b) + len(r.options.sep)
+
+	if r.n >= r.options.batchSize {
+		r.enqueue(r.cut())
+	}
+	return nil
+}
+
+// cut returns the compressed batch and starts a new one, it must be called with mu held.
+func (r *s3Recorder) cut() s3Batch {
+	if r.gz == nil {
+		return s3Batch{}
+	}
+	r.gz.Close()
+	b := s3Batch{
+		key:  r.options.prefix + time.Now().UTC().Format("2006/01/02/150405") + "-" + xid.New().String() + ".gz",
+		data: bytes.Clone(r.buf.Bytes()),
+	}
+	r.buf.Reset()
+	r.gz = nil
+	r.n = 0
+	return b
+}
+
+// enqueue queues the batch for the upload, the batch is spilled if the queue is full.
+// The empty batch retries the spilled objects. It must be called with mu held.
+func (r *s3Recorder) enqueue(b s3Batch) {
+	select {
+	case r.uploads <- b:
+	default:
+		if len(b.data) > 0 {
+			r.spill(b.key, b.data)
+		}
+	}
+}
+
+func (r *s3Recorder) uploadLoop() {
+	defer close(r.uploaded)
+
+	for b := range r.uploads {
+		r.upload(b)
+	}
+}
+
+func (r *s3Recorder) flushLoop(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.options.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.mu.Lock()
+			r.enqueue(r.cut())
+			r.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// upload uploads the batch, which is spilled to the spool directory on failure.
+// The spilled objects are uploaded again once the storage is available.
+func (r *s3Recorder) upload(b s3Batch) {
+	if len(b.data) > 0 {
+		if err := r.put(b.key, b.data); err != nil {
+			r.options.logger.Errorf("s3: %s: %v", b.key, err)
+			r.spill(b.key, b.data)
+			return
+		}
+		r.options.logger.Debugf("s3: %s uploaded, %d bytes", b.key, len(b.data))
+	}
+
+	r.unspool()
+}
+
+func (r *s3Recorder) spill(key string, data []byte) {
+	if r.options.spoolDir == "" {
+		r.options.logger.Warnf("s3: %s is dropped, %d bytes", key, len(data))
+		return
+	}
+
+	// the object is renamed after it is written, the partial files are not picked up by unspool.
+	name := filepath.Join(r.options.spoolDir, url.QueryEscape(key)+s3SpoolSuffix)
+	if err := os.WriteFile(name+".tmp", data, 0600); err != nil {
+		os.Remove(name + ".tmp")
+		r.options.logger.Errorf("s3: %s is dropped: %v", key, err)
+		return
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		os.Remove(name + ".tmp")
+		r.options.logger.Errorf("s3: %s is dropped: %v", key, err)
+		return
+	}
+	r.options.logger.Warnf("s3: %s is spilled to %s", key, name)
+}
+
+// unspool uploads the spilled objects in order until the first failure.
+func (r *s3Recorder) unspool() {
+	if r.options.spoolDir == "" {
+		return
+	}
+
+	entries, err := os.ReadDir(r.options.spoolDir)
+	if err != nil {
+		r.options.logger.Error(err)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), s3SpoolSuffix) {
+			continue
+		}
+		key, err := url.QueryUnescape(strings.TrimSuffix(entry.Name(), s3SpoolSuffix))
+		if err != nil {
+			continue
+		}
+		name := filepath.Join(r.options.spoolDir, entry.Name())
+		data, err := os.ReadFile(name)
+		if err != nil {
+			r.options.logger.Error(err)
+			continue
+		}
+		if err := r.put(key, data); err != nil {
+			r.options.logger.Debugf("s3: %s: %v", key, err)
+			return
+		}
+		os.Remove(name)
+		r.options.logger.Debugf("s3: spilled %s uploaded, %d bytes", key, len(data))
+	}
+}
+
+func (r *s3Recorder) put(key string, data []byte) error {
+	u := *r.endpoint
+	if r.options.pathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + r.bucket + "/" + key
+	} else {
+		u.Host = r.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	r.sign(req, data)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// sign signs the request by the AWS signature version 4, the request is anonymous without the credentials.
+func (r *s3Recorder) sign(req *http.Request, payload []byte) {
+	if r.options.accessKey == "" {
+		return
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hex.EncodeToString(sha256Sum(payload))
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"content-type:" + req.Header.Get("Content-Type") + "\n" +
+			"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + r.options.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sha256Sum([]byte(canonicalRequest)))
+
+	key := hmacSHA256([]byte("AWS4"+r.options.secretKey), date)
+	key = hmacSHA256(key, r.options.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		r.options.accessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+// Close uploads the pending records and waits for the queued uploads.
+func (r *s3Recorder) Close() error {
+	r.cancelFunc()
+	<-r.done
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	b := r.cut()
+	r.mu.Unlock()
+
+	if len(b.data) > 0 {
+		r.uploads <- b
+	}
+	close(r.uploads)
+	<-r.uploaded
+	return nil
+}
+
+// s3EscapePath escapes the path by the URI encoding of the AWS signature, the slashes are kept.
+func s3EscapePath(p string) string {
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
+
+func sha256Sum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	return h[:]
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -2,6 +2,7 @@ package registry
 
 import (
 	"context"
+	"io"
 
 	"github.com/go-gost/core/chain"
 	"github.com/go-gost/core/hop"
@@ -42,6 +43,17 @@ func (w *hopWrapper) Nodes() []*chain.Node {
 	return nil
 }
 
+// TrackConn tracks the connection to the node if the hop supports the draining of the connections.
+func (w *hopWrapper) TrackConn(node *chain.Node, c io.Closer) func() {
+	v := w.r.get(w.name)
+	if t, ok := v.(interface {
+		TrackConn(node *chain.Node, c io.Closer) func()
+	}); ok {
+		return t.TrackConn(node, c)
+	}
+	return func() {}
+}
+
 func (w *hopWrapper) Select(ctx context.Context, opts ...hop.SelectOption) *chain.Node {
 	v := w.r.get(w.name)
 	if v == nil {
@@ -2,8 +2,10 @@ package registry
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/go-gost/core/ingress"
+	xingress "github.com/go-gost/x/ingress"
 )
 
 type ingressRegistry struct {
@@ -46,3 +48,12 @@ func (w *ingressWrapper) SetRule(ctx context.Context, rule *ingress.Rule, opts .
 
 	return v.SetRule(ctx, rule, opts...)
 }
+
+func (w *ingressWrapper) MatchHTTP(ctx context.Context, req *http.Request) *xingress.Rule {
+	v, _ := w.r.get(w.name).(xingress.HTTPMatcher)
+	if v == nil {
+		return nil
+	}
+
+	return v.MatchHTTP(ctx, req)
+}
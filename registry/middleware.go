@@ -0,0 +1,19 @@
+package registry
+
+import (
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/x/handler/middleware"
+)
+
+type NewMiddleware func(opts ...middleware.Option) middleware.Middleware
+
+type middlewareRegistry struct {
+	registry[NewMiddleware]
+}
+
+func (r *middlewareRegistry) Register(name string, v NewMiddleware) error {
+	if err := r.registry.Register(name, v); err != nil {
+		logger.Default().Fatal(err)
+	}
+	return nil
+}
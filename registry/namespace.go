@@ -0,0 +1,33 @@
+package registry
+
+import (
+	"strings"
+)
+
+// NamespaceSeparator separates the namespace and the name of an object in the registries,
+// e.g. the service web of the namespace tenant1 is registered as tenant1/web.
+const NamespaceSeparator = "/"
+
+// QualifiedName returns the name of the object in the namespace,
+// the name is returned as is if the namespace is empty or the name is already in the namespace.
+func QualifiedName(namespace, name string) string {
+	if namespace == "" || name == "" || InNamespace(name, namespace) {
+		return name
+	}
+	return namespace + NamespaceSeparator + name
+}
+
+// SplitName splits the qualified name into the namespace and the name in the namespace,
+// the namespace is empty for the objects in the global namespace.
+func SplitName(name string) (namespace, local string) {
+	if ns, s, ok := strings.Cut(name, NamespaceSeparator); ok {
+		return ns, s
+	}
+	return "", name
+}
+
+// InNamespace reports whether the object of the qualified name is in the namespace.
+func InNamespace(name, namespace string) bool {
+	ns, _ := SplitName(name)
+	return ns == namespace
+}
@@ -54,6 +54,8 @@ var (
 	observerReg reg.Registry[observer.Observer] = new(observerRegistry)
 
 	loggerReg reg.Registry[logger.Logger] = new(loggerRegistry)
+
+	middlewareReg reg.Registry[NewMiddleware] = new(middlewareRegistry)
 )
 
 type registry[T any] struct {
@@ -113,6 +115,10 @@ func HandlerRegistry() reg.Registry[NewHandler] {
 	return handlerReg
 }
 
+func MiddlewareRegistry() reg.Registry[NewMiddleware] {
+	return middlewareReg
+}
+
 func DialerRegistry() reg.Registry[NewDialer] {
 	return dialerReg
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/hosts"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/resolver"
 	resolver_util "github.com/go-gost/x/internal/util/resolver"
@@ -28,8 +29,10 @@ type NameServer struct {
 }
 
 type options struct {
-	domain string
-	logger logger.Logger
+	domain   string
+	hosts    hosts.HostMapper
+	rewrites []*RewriteRule
+	logger   logger.Logger
 }
 
 type Option func(opts *options)
@@ -40,6 +43,21 @@ func DomainOption(domain string) Option {
 	}
 }
 
+// HostsOption sets the host mapper looked up before the nameservers,
+// it also maps the hostnames of the nameservers.
+func HostsOption(hosts hosts.HostMapper) Option {
+	return func(opts *options) {
+		opts.hosts = hosts
+	}
+}
+
+// RewritesOption sets the rules rewriting the answers, the first rule matching the hostname is applied.
+func RewritesOption(rules ...*RewriteRule) Option {
+	return func(opts *options) {
+		opts.rewrites = rules
+	}
+}
+
 func LoggerOption(logger logger.Logger) Option {
 	return func(opts *options) {
 		opts.logger = logger
@@ -58,6 +76,10 @@ func NewResolver(nameservers []NameServer, opts ...Option) (resolver.Resolver, e
 		opt(&options)
 	}
 
+	for _, rule := range options.rewrites {
+		rule.init()
+	}
+
 	var servers []NameServer
 	for _, server := range nameservers {
 		addr := strings.TrimSpace(server.Addr)
@@ -69,6 +91,7 @@ func NewResolver(nameservers []NameServer, opts ...Option) (resolver.Resolver, e
 			exchanger.RouterOption(
 				chain.NewRouter(
 					chain.ChainRouterOption(server.Chain),
+					chain.HostMapperRouterOption(options.hosts),
 					chain.LoggerRouterOption(options.logger),
 				),
 			),
@@ -113,6 +136,13 @@ func (r *localResolver) Resolve(ctx context.Context, network, host string, opts
 		host = host + "." + r.options.domain
 	}
 
+	if r.options.hosts != nil {
+		if ips, ok := r.options.hosts.Lookup(ctx, network, host); ok {
+			r.options.logger.Debugf("hit host mapper: %s -> %s", host, ips)
+			return ips, nil
+		}
+	}
+
 	for _, server := range r.servers {
 		if server.Async {
 			ips, err = r.resolveAsync(ctx, &server, host)
@@ -166,6 +196,7 @@ func (r *localResolver) resolveAsync(ctx context.Context, server *NameServer, ho
 }
 
 func (r *localResolver) lookupCache(ctx context.Context, server *NameServer, host string) (ips []net.IP, ttl time.Duration, ok bool) {
+	rule := r.rewriteRule(host)
 	lookup := func(t uint16, host string) (ips []net.IP, ttl time.Duration, ok bool) {
 		mq := dns.Msg{}
 		mq.SetQuestion(dns.Fqdn(host), t)
@@ -174,16 +205,9 @@ func (r *localResolver) lookupCache(ctx context.Context, server *NameServer, hos
 			return
 		}
 
-		ok = true
-
-		for _, ans := range mr.Answer {
-			if ar, _ := ans.(*dns.AAAA); ar != nil {
-				ips = append(ips, ar.AAAA)
-			}
-			if ar, _ := ans.(*dns.A); ar != nil {
-				ips = append(ips, ar.A)
-			}
-		}
+		ips, cname := rule.answers(mr)
+		// the CNAME to be flattened is resolved by the nameserver.
+		ok = cname == "" || rule == nil || !rule.Flatten
 		return
 	}
 
@@ -221,6 +245,31 @@ func (r *localResolver) resolveIPs(ctx context.Context, server *NameServer, mq *
 		r.options.logger.Trace(mq.String())
 	}
 
+	mr, err := r.query(ctx, server, mq)
+	if err != nil {
+		return
+	}
+
+	host := strings.TrimSuffix(mq.Question[0].Name, ".")
+	rule := r.rewriteRule(host)
+	ips, cname := rule.answers(mr)
+
+	// the upstream returns the CNAME without resolving the target.
+	for i := 0; cname != "" && rule != nil && rule.Flatten && i < maxCNAMEDepth; i++ {
+		r.options.logger.Debugf("flatten %s: CNAME %s", host, cname)
+
+		cq := dns.Msg{}
+		cq.SetQuestion(dns.Fqdn(cname), mq.Question[0].Qtype)
+		if mr, err = r.query(ctx, server, &cq); err != nil {
+			return
+		}
+		ips, cname = rule.answers(mr)
+	}
+
+	return
+}
+
+func (r *localResolver) query(ctx context.Context, server *NameServer, mq *dns.Msg) (mr *dns.Msg, err error) {
 	key := resolver_util.NewCacheKey(&mq.Question[0])
 	mr, ttl := r.cache.Load(key)
 	if ttl <= 0 {
@@ -235,17 +284,17 @@ func (r *localResolver) resolveIPs(ctx context.Context, server *NameServer, mq *
 			r.options.logger.Trace(mr.String())
 		}
 	}
+	return
+}
 
-	for _, ans := range mr.Answer {
-		if ar, _ := ans.(*dns.AAAA); ar != nil {
-			ips = append(ips, ar.AAAA)
-		}
-		if ar, _ := ans.(*dns.A); ar != nil {
-			ips = append(ips, ar.A)
+// rewriteRule returns the first rewrite rule matching the host, nil is returned if no rule is matched.
+func (r *localResolver) rewriteRule(host string) *RewriteRule {
+	for _, rule := range r.options.rewrites {
+		if rule.match(host) {
+			return rule
 		}
 	}
-
-	return
+	return nil
 }
 
 func (r *localResolver) exchange(ctx context.Context, ex exchanger.Exchanger, mq *dns.Msg) (mr *dns.Msg, err error) {
@@ -0,0 +1,178 @@
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-gost/x/internal/matcher"
+	"github.com/miekg/dns"
+)
+
+const (
+	// the maximum number of the CNAME records followed by flattening.
+	maxCNAMEDepth = 8
+)
+
+// IPRemap maps the IPs in the network From to the network To, the host part of the IP is kept.
+type IPRemap struct {
+	From *net.IPNet
+	To   *net.IPNet
+}
+
+// ParseIPRemap parses the remapping of the IP or CIDR from to the IP or CIDR to.
+func ParseIPRemap(from, to string) (IPRemap, error) {
+	fromNet, err := parseIPNet(from)
+	if err != nil {
+		return IPRemap{}, err
+	}
+	toNet, err := parseIPNet(to)
+	if err != nil {
+		return IPRemap{}, err
+	}
+	if (fromNet.IP.To4() == nil) != (toNet.IP.To4() == nil) {
+		return IPRemap{}, fmt.Errorf("remap %s to %s: address family mismatch", from, to)
+	}
+	return IPRemap{From: fromNet, To: toNet}, nil
+}
+
+func parseIPNet(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if _, inet, err := net.ParseCIDR(s); err == nil {
+		return inet, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP or CIDR %s", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func (m *IPRemap) remap(ip net.IP) (net.IP, bool) {
+	if !m.From.Contains(ip) {
+		return nil, false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	to := m.To.IP.Mask(m.To.Mask)
+	if len(to) != len(ip) {
+		return nil, false
+	}
+	v := make(net.IP, len(ip))
+	for i := range ip {
+		v[i] = to[i] | (ip[i] &^ m.From.Mask[i] &^ m.To.Mask[i])
+	}
+	return v, true
+}
+
+// RewriteRule rewrites the answers of the hostnames before they are returned by the resolver.
+type RewriteRule struct {
+	// Hosts is the patterns of the hostnames, the pattern can be a domain (example.com, .example.com)
+	// or wildcard (*.example.com), all the hostnames are matched if it is empty.
+	Hosts []string
+	// Remaps maps the IPs of the A/AAAA records, the first matched remapping is applied.
+	Remaps []IPRemap
+	// Block drops the answer records of the types (e.g. AAAA) or the IPs in the networks (IP or CIDR).
+	Block []string
+	// Flatten resolves the target of the CNAME record if the answer has no address record.
+	Flatten bool
+
+	domainMatcher   matcher.Matcher
+	wildcardMatcher matcher.Matcher
+	blockTypes      map[uint16]bool
+	blockNets       []*net.IPNet
+}
+
+func (rule *RewriteRule) init() {
+	var domains, wildcards []string
+	for _, host := range rule.Hosts {
+		host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+		if host == "" {
+			continue
+		}
+		if strings.ContainsAny(host, "*?") {
+			wildcards = append(wildcards, host)
+		} else {
+			domains = append(domains, host)
+		}
+	}
+	rule.domainMatcher = matcher.DomainMatcher(domains)
+	rule.wildcardMatcher = matcher.WildcardMatcher(wildcards)
+
+	rule.blockTypes = make(map[uint16]bool)
+	for _, s := range rule.Block {
+		if t, ok := dns.StringToType[strings.ToUpper(strings.TrimSpace(s))]; ok {
+			rule.blockTypes[t] = true
+			continue
+		}
+		if inet, err := parseIPNet(s); err == nil {
+			rule.blockNets = append(rule.blockNets, inet)
+		}
+	}
+}
+
+func (rule *RewriteRule) match(host string) bool {
+	if len(rule.Hosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	return rule.domainMatcher.Match(host) || rule.wildcardMatcher.Match(host)
+}
+
+// answers returns the IPs of the answer of the reply rewritten by the rule,
+// and the target of the CNAME record if no IP is found. The rule can be nil.
+func (rule *RewriteRule) answers(mr *dns.Msg) (ips []net.IP, cname string) {
+	for _, ans := range mr.Answer {
+		if rule != nil && rule.blockTypes[ans.Header().Rrtype] {
+			continue
+		}
+
+		var ip net.IP
+		switch ar := ans.(type) {
+		case *dns.A:
+			ip = ar.A
+		case *dns.AAAA:
+			ip = ar.AAAA
+		case *dns.CNAME:
+			cname = ar.Target
+			continue
+		default:
+			continue
+		}
+
+		if rule != nil {
+			if rule.blocked(ip) {
+				continue
+			}
+			ip = rule.remap(ip)
+		}
+		ips = append(ips, ip)
+	}
+
+	if len(ips) > 0 {
+		cname = ""
+	}
+	return
+}
+
+func (rule *RewriteRule) blocked(ip net.IP) bool {
+	for _, inet := range rule.blockNets {
+		if inet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *RewriteRule) remap(ip net.IP) net.IP {
+	for i := range rule.Remaps {
+		if v, ok := rule.Remaps[i].remap(ip); ok {
+			return v
+		}
+	}
+	return ip
+}
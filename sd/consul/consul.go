@@ -0,0 +1,257 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/sd"
+)
+
+const (
+	defaultTTL = 30 * time.Second
+
+	metaNode    = "node"
+	metaNetwork = "network"
+	metaAddress = "address"
+)
+
+type options struct {
+	token     string
+	ttl       time.Duration
+	timeout   time.Duration
+	tlsConfig *tls.Config
+}
+
+type Option func(opts *options)
+
+// TokenOption sets the ACL token of consul.
+func TokenOption(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// TTLOption sets the TTL of the health check of the registered services,
+// a service which is not renewed within the TTL becomes critical.
+func TTLOption(ttl time.Duration) Option {
+	return func(opts *options) {
+		opts.ttl = ttl
+	}
+}
+
+func TimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+func TLSConfigOption(cfg *tls.Config) Option {
+	return func(opts *options) {
+		opts.tlsConfig = cfg
+	}
+}
+
+type consulService struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Address string            `json:"Address,omitempty"`
+	Port    int               `json:"Port,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Check   *consulCheck      `json:"Check,omitempty"`
+}
+
+type consulCheck struct {
+	CheckID                        string `json:"CheckID"`
+	TTL                            string `json:"TTL"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter,omitempty"`
+}
+
+type consulHealthEntry struct {
+	Service struct {
+		ID      string            `json:"ID"`
+		Service string            `json:"Service"`
+		Address string            `json:"Address"`
+		Port    int               `json:"Port"`
+		Meta    map[string]string `json:"Meta"`
+	} `json:"Service"`
+}
+
+type consulSD struct {
+	url     *url.URL
+	client  *http.Client
+	options options
+	log     logger.Logger
+}
+
+// NewSD creates a service discovery based on the HTTP API of consul agent.
+// The services are registered with a TTL health check, and only the healthy services are discovered.
+func NewSD(name string, addr string, opts ...Option) (sd.SD, error) {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.ttl <= 0 {
+		options.ttl = defaultTTL
+	}
+
+	if !strings.Contains(addr, "://") {
+		if options.tlsConfig != nil {
+			addr = "https://" + addr
+		} else {
+			addr = "http://" + addr
+		}
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &consulSD{
+		url: u,
+		client: &http.Client{
+			Timeout: options.timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: options.tlsConfig,
+			},
+		},
+		options: options,
+		log: logger.Default().WithFields(map[string]any{
+			"kind": "sd",
+			"sd":   name,
+		}),
+	}, nil
+}
+
+func (p *consulSD) Register(ctx context.Context, service *sd.Service, opts ...sd.Option) error {
+	if service == nil {
+		return nil
+	}
+
+	cs := &consulService{
+		ID:      service.ID,
+		Name:    service.Name,
+		Address: service.Address,
+		Meta: map[string]string{
+			metaNode:    service.Node,
+			metaNetwork: service.Network,
+			metaAddress: service.Address,
+		},
+		Check: &consulCheck{
+			CheckID:                        checkID(service),
+			TTL:                            p.options.ttl.String(),
+			DeregisterCriticalServiceAfter: (10 * p.options.ttl).String(),
+		},
+	}
+	if host, port, _ := net.SplitHostPort(service.Address); port != "" {
+		cs.Address = host
+		cs.Port, _ = strconv.Atoi(port)
+	}
+
+	if err := p.do(ctx, http.MethodPut, "/v1/agent/service/register", cs, nil); err != nil {
+		return err
+	}
+	p.log.Debugf("register service %s/%s: %s", service.Name, service.ID, service.Address)
+
+	return p.Renew(ctx, service)
+}
+
+func (p *consulSD) Deregister(ctx context.Context, service *sd.Service) error {
+	if service == nil {
+		return nil
+	}
+	return p.do(ctx, http.MethodPut, "/v1/agent/service/deregister/"+url.PathEscape(service.ID), nil, nil)
+}
+
+func (p *consulSD) Renew(ctx context.Context, service *sd.Service) error {
+	if service == nil {
+		return nil
+	}
+	return p.do(ctx, http.MethodPut, "/v1/agent/check/pass/"+url.PathEscape(checkID(service)), nil, nil)
+}
+
+func (p *consulSD) Get(ctx context.Context, name string) (services []*sd.Service, err error) {
+	var entries []*consulHealthEntry
+	if err = p.do(ctx, http.MethodGet, "/v1/health/service/"+url.PathEscape(name)+"?passing=true", nil, &entries); err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		s := entry.Service
+		addr := s.Meta[metaAddress]
+		if addr == "" {
+			addr = s.Address
+			if s.Port > 0 {
+				addr = net.JoinHostPort(s.Address, strconv.Itoa(s.Port))
+			}
+		}
+		services = append(services, &sd.Service{
+			ID:      s.ID,
+			Name:    s.Service,
+			Node:    s.Meta[metaNode],
+			Network: s.Meta[metaNetwork],
+			Address: addr,
+		})
+	}
+	return
+}
+
+func (p *consulSD) do(ctx context.Context, method string, path string, in any, out any) error {
+	var body io.Reader
+	if in != nil {
+		v, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(v)
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.url.ResolveReference(ref).String(), body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.options.token != "" {
+		req.Header.Set("X-Consul-Token", p.options.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func checkID(service *sd.Service) string {
+	return "service:" + service.ID
+}
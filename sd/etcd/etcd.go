@@ -0,0 +1,371 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/sd"
+)
+
+const (
+	defaultTTL    = 30 * time.Second
+	defaultPrefix = "/gost/sd"
+)
+
+var (
+	ErrLeaseNotFound = errors.New("lease not found")
+)
+
+type options struct {
+	username  string
+	password  string
+	prefix    string
+	ttl       time.Duration
+	timeout   time.Duration
+	tlsConfig *tls.Config
+}
+
+type Option func(opts *options)
+
+// AuthOption sets the user credentials of etcd.
+func AuthOption(username, password string) Option {
+	return func(opts *options) {
+		opts.username = username
+		opts.password = password
+	}
+}
+
+// PrefixOption sets the key prefix of the registered services.
+func PrefixOption(prefix string) Option {
+	return func(opts *options) {
+		opts.prefix = prefix
+	}
+}
+
+// TTLOption sets the TTL of the lease attached to the registered services,
+// a service which is not renewed within the TTL is removed.
+func TTLOption(ttl time.Duration) Option {
+	return func(opts *options) {
+		opts.ttl = ttl
+	}
+}
+
+func TimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+func TLSConfigOption(cfg *tls.Config) Option {
+	return func(opts *options) {
+		opts.tlsConfig = cfg
+	}
+}
+
+type etcdService struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Node    string `json:"node"`
+	Network string `json:"network"`
+	Address string `json:"address"`
+}
+
+type etcdSD struct {
+	url     *url.URL
+	client  *http.Client
+	token   string
+	leases  map[string]string
+	mu      sync.Mutex
+	options options
+	log     logger.Logger
+}
+
+// NewSD creates a service discovery based on the gRPC gateway (JSON API v3) of etcd.
+// Each service is stored as a key under prefix/name/id, attached to a lease with the TTL.
+func NewSD(name string, addr string, opts ...Option) (sd.SD, error) {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.ttl <= 0 {
+		options.ttl = defaultTTL
+	}
+	if options.prefix == "" {
+		options.prefix = defaultPrefix
+	}
+
+	if !strings.Contains(addr, "://") {
+		if options.tlsConfig != nil {
+			addr = "https://" + addr
+		} else {
+			addr = "http://" + addr
+		}
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &etcdSD{
+		url: u,
+		client: &http.Client{
+			Timeout: options.timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: options.tlsConfig,
+			},
+		},
+		leases:  make(map[string]string),
+		options: options,
+		log: logger.Default().WithFields(map[string]any{
+			"kind": "sd",
+			"sd":   name,
+		}),
+	}, nil
+}
+
+func (p *etcdSD) Register(ctx context.Context, service *sd.Service, opts ...sd.Option) error {
+	if service == nil {
+		return nil
+	}
+
+	var lease struct {
+		ID string `json:"ID"`
+	}
+	if err := p.do(ctx, "/v3/lease/grant", map[string]any{
+		"TTL": int64(p.options.ttl.Seconds()),
+	}, &lease); err != nil {
+		return err
+	}
+
+	v, err := json.Marshal(etcdService{
+		ID:      service.ID,
+		Name:    service.Name,
+		Node:    service.Node,
+		Network: service.Network,
+		Address: service.Address,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := p.do(ctx, "/v3/kv/put", map[string]any{
+		"key":   encode(p.key(service.Name, service.ID)),
+		"value": base64.StdEncoding.EncodeToString(v),
+		"lease": lease.ID,
+	}, nil); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.leases[service.ID] = lease.ID
+	p.mu.Unlock()
+
+	p.log.Debugf("register service %s/%s: %s", service.Name, service.ID, service.Address)
+	return nil
+}
+
+func (p *etcdSD) Deregister(ctx context.Context, service *sd.Service) error {
+	if service == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	lease := p.leases[service.ID]
+	delete(p.leases, service.ID)
+	p.mu.Unlock()
+
+	if err := p.do(ctx, "/v3/kv/deleterange", map[string]any{
+		"key": encode(p.key(service.Name, service.ID)),
+	}, nil); err != nil {
+		return err
+	}
+	if lease != "" {
+		return p.do(ctx, "/v3/lease/revoke", map[string]any{
+			"ID": lease,
+		}, nil)
+	}
+	return nil
+}
+
+func (p *etcdSD) Renew(ctx context.Context, service *sd.Service) error {
+	if service == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	lease := p.leases[service.ID]
+	p.mu.Unlock()
+
+	if lease == "" {
+		return ErrLeaseNotFound
+	}
+
+	var resp struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	if err := p.do(ctx, "/v3/lease/keepalive", map[string]any{
+		"ID": lease,
+	}, &resp); err != nil {
+		return err
+	}
+	// the lease is expired
+	if resp.Result.TTL == "" || resp.Result.TTL == "0" {
+		p.mu.Lock()
+		delete(p.leases, service.ID)
+		p.mu.Unlock()
+		return ErrLeaseNotFound
+	}
+	return nil
+}
+
+func (p *etcdSD) Get(ctx context.Context, name string) (services []*sd.Service, err error) {
+	prefix := p.key(name, "")
+	var resp struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err = p.do(ctx, "/v3/kv/range", map[string]any{
+		"key":       encode(prefix),
+		"range_end": encode(prefixEnd(prefix)),
+	}, &resp); err != nil {
+		return
+	}
+
+	for _, kv := range resp.Kvs {
+		b, er := base64.StdEncoding.DecodeString(kv.Value)
+		if er != nil {
+			continue
+		}
+		var s etcdService
+		if er := json.Unmarshal(b, &s); er != nil {
+			continue
+		}
+		services = append(services, &sd.Service{
+			ID:      s.ID,
+			Name:    s.Name,
+			Node:    s.Node,
+			Network: s.Network,
+			Address: s.Address,
+		})
+	}
+	return
+}
+
+func (p *etcdSD) key(name, id string) string {
+	return path.Join(p.options.prefix, name) + "/" + id
+}
+
+func (p *etcdSD) authenticate(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	token := p.token
+	p.mu.Unlock()
+
+	if token != "" || p.options.username == "" {
+		return token, nil
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := p.post(ctx, "", "/v3/auth/authenticate", map[string]any{
+		"name":     p.options.username,
+		"password": p.options.password,
+	}, &resp); err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	p.token = resp.Token
+	p.mu.Unlock()
+
+	return resp.Token, nil
+}
+
+func (p *etcdSD) do(ctx context.Context, path string, in any, out any) error {
+	token, err := p.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = p.post(ctx, token, path, in, out)
+	if err != nil && token != "" {
+		// the token may be expired, authenticate again on the next request.
+		p.mu.Lock()
+		p.token = ""
+		p.mu.Unlock()
+	}
+	return err
+}
+
+func (p *etcdSD) post(ctx context.Context, token string, path string, in any, out any) error {
+	var body io.Reader
+	if in != nil {
+		v, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(v)
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url.ResolveReference(ref).String(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", path, resp.Status)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// prefixEnd returns the range end of the keys with the prefix.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00"
+}
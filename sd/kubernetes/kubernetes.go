@@ -0,0 +1,262 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/sd"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	defaultNamespace  = "default"
+	labelServiceName  = "kubernetes.io/service-name"
+)
+
+var (
+	ErrReadOnly = errors.New("kubernetes: service registration is not supported")
+)
+
+type options struct {
+	namespace string
+	port      string
+	token     string
+	timeout   time.Duration
+	tlsConfig *tls.Config
+}
+
+type Option func(opts *options)
+
+// NamespaceOption sets the default namespace of the services.
+func NamespaceOption(namespace string) Option {
+	return func(opts *options) {
+		opts.namespace = namespace
+	}
+}
+
+// PortOption sets the name of the service port used as the endpoint port,
+// the first port of the endpoints is used if it is not set.
+func PortOption(port string) Option {
+	return func(opts *options) {
+		opts.port = port
+	}
+}
+
+// TokenOption sets the bearer token used to access the API server.
+func TokenOption(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+func TimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+func TLSConfigOption(cfg *tls.Config) Option {
+	return func(opts *options) {
+		opts.tlsConfig = cfg
+	}
+}
+
+type endpointSliceList struct {
+	Items []struct {
+		Endpoints []struct {
+			Addresses  []string `json:"addresses"`
+			Conditions struct {
+				Ready *bool `json:"ready"`
+			} `json:"conditions"`
+			NodeName  string `json:"nodeName"`
+			TargetRef *struct {
+				Name string `json:"name"`
+			} `json:"targetRef"`
+		} `json:"endpoints"`
+		Ports []struct {
+			Name     *string `json:"name"`
+			Protocol *string `json:"protocol"`
+			Port     *int32  `json:"port"`
+		} `json:"ports"`
+	} `json:"items"`
+}
+
+type kubernetesSD struct {
+	url     *url.URL
+	client  *http.Client
+	options options
+	log     logger.Logger
+}
+
+// NewSD creates a read-only service discovery which resolves the ready endpoints of
+// a Kubernetes Service from its EndpointSlices.
+// If addr is empty, the API server, token, CA and namespace of the in-cluster service account are used.
+// The service name can be NAME or NAMESPACE/NAME.
+func NewSD(name string, addr string, opts ...Option) (sd.SD, error) {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	if addr == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("kubernetes: not running in a cluster, the API server address is required")
+		}
+		addr = "https://" + net.JoinHostPort(host, port)
+
+		if options.token == "" {
+			b, err := os.ReadFile(serviceAccountDir + "/token")
+			if err != nil {
+				return nil, err
+			}
+			options.token = strings.TrimSpace(string(b))
+		}
+		if options.namespace == "" {
+			if b, err := os.ReadFile(serviceAccountDir + "/namespace"); err == nil {
+				options.namespace = strings.TrimSpace(string(b))
+			}
+		}
+		if options.tlsConfig == nil {
+			if b, err := os.ReadFile(serviceAccountDir + "/ca.crt"); err == nil {
+				pool := x509.NewCertPool()
+				pool.AppendCertsFromPEM(b)
+				options.tlsConfig = &tls.Config{RootCAs: pool}
+			}
+		}
+	}
+	if options.namespace == "" {
+		options.namespace = defaultNamespace
+	}
+
+	if !strings.Contains(addr, "://") {
+		addr = "https://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kubernetesSD{
+		url: u,
+		client: &http.Client{
+			Timeout: options.timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: options.tlsConfig,
+			},
+		},
+		options: options,
+		log: logger.Default().WithFields(map[string]any{
+			"kind": "sd",
+			"sd":   name,
+		}),
+	}, nil
+}
+
+func (p *kubernetesSD) Register(ctx context.Context, service *sd.Service, opts ...sd.Option) error {
+	return ErrReadOnly
+}
+
+func (p *kubernetesSD) Deregister(ctx context.Context, service *sd.Service) error {
+	return ErrReadOnly
+}
+
+func (p *kubernetesSD) Renew(ctx context.Context, service *sd.Service) error {
+	return ErrReadOnly
+}
+
+func (p *kubernetesSD) Get(ctx context.Context, name string) (services []*sd.Service, err error) {
+	namespace := p.options.namespace
+	if ns, n, ok := strings.Cut(name, "/"); ok {
+		namespace, name = ns, n
+	}
+
+	u := *p.url
+	u.Path = fmt.Sprintf("/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices", url.PathEscape(namespace))
+	u.RawQuery = url.Values{
+		"labelSelector": []string{labelServiceName + "=" + name},
+	}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.options.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.options.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kubernetes: %s", resp.Status)
+	}
+
+	var list endpointSliceList
+	if err = json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return
+	}
+
+	for _, item := range list.Items {
+		var port int32
+		network := "tcp"
+		for _, v := range item.Ports {
+			if v.Port == nil {
+				continue
+			}
+			if p.options.port != "" && (v.Name == nil || *v.Name != p.options.port) {
+				continue
+			}
+			port = *v.Port
+			if v.Protocol != nil {
+				network = strings.ToLower(*v.Protocol)
+			}
+			break
+		}
+		if port == 0 {
+			continue
+		}
+
+		for _, ep := range item.Endpoints {
+			// a nil ready condition means ready.
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			for _, addr := range ep.Addresses {
+				address := net.JoinHostPort(addr, strconv.Itoa(int(port)))
+				id := address
+				if ep.TargetRef != nil && ep.TargetRef.Name != "" {
+					id = ep.TargetRef.Name
+				}
+				services = append(services, &sd.Service{
+					ID:      id,
+					Name:    name,
+					Node:    ep.NodeName,
+					Network: network,
+					Address: address,
+				})
+			}
+		}
+	}
+
+	p.log.Debugf("service %s/%s: %d endpoints", namespace, name, len(services))
+	return
+}
@@ -0,0 +1,192 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/sd"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultTTL    = 30 * time.Second
+	defaultPrefix = "gost:sd"
+)
+
+type options struct {
+	db       int
+	password string
+	prefix   string
+	ttl      time.Duration
+}
+
+type Option func(opts *options)
+
+func DBOption(db int) Option {
+	return func(opts *options) {
+		opts.db = db
+	}
+}
+
+func PasswordOption(password string) Option {
+	return func(opts *options) {
+		opts.password = password
+	}
+}
+
+// PrefixOption sets the key prefix of the services, the services of name NAME are stored in the hash PREFIX:NAME.
+func PrefixOption(prefix string) Option {
+	return func(opts *options) {
+		opts.prefix = prefix
+	}
+}
+
+// TTLOption sets the TTL of the registered services,
+// a service which is not renewed within the TTL is discarded.
+func TTLOption(ttl time.Duration) Option {
+	return func(opts *options) {
+		opts.ttl = ttl
+	}
+}
+
+type redisService struct {
+	Node    string `json:"node,omitempty"`
+	Network string `json:"network,omitempty"`
+	Address string `json:"address,omitempty"`
+	Expire  int64  `json:"expire"`
+}
+
+type redisSD struct {
+	client  *redis.Client
+	options options
+	log     logger.Logger
+}
+
+// NewSD creates a service discovery based on redis,
+// the instances sharing the same redis see the services registered by each other.
+func NewSD(name string, addr string, opts ...Option) (sd.SD, error) {
+	var options options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.ttl <= 0 {
+		options.ttl = defaultTTL
+	}
+	if options.prefix == "" {
+		options.prefix = defaultPrefix
+	}
+
+	return &redisSD{
+		client: redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: options.password,
+			DB:       options.db,
+		}),
+		options: options,
+		log: logger.Default().WithFields(map[string]any{
+			"kind": "sd",
+			"sd":   name,
+		}),
+	}, nil
+}
+
+func (p *redisSD) Register(ctx context.Context, service *sd.Service, opts ...sd.Option) error {
+	if service == nil {
+		return nil
+	}
+
+	if err := p.set(ctx, service); err != nil {
+		return err
+	}
+	p.log.Debugf("register service %s/%s: %s", service.Name, service.ID, service.Address)
+
+	return nil
+}
+
+func (p *redisSD) Deregister(ctx context.Context, service *sd.Service) error {
+	if service == nil {
+		return nil
+	}
+	return p.client.HDel(ctx, p.key(service.Name), service.ID).Err()
+}
+
+func (p *redisSD) Renew(ctx context.Context, service *sd.Service) error {
+	if service == nil {
+		return nil
+	}
+
+	v, err := p.client.HGet(ctx, p.key(service.Name), service.ID).Bytes()
+	if err != nil {
+		return err
+	}
+	var rs redisService
+	if err := json.Unmarshal(v, &rs); err != nil {
+		return err
+	}
+
+	return p.set(ctx, &sd.Service{
+		ID:      service.ID,
+		Name:    service.Name,
+		Node:    rs.Node,
+		Network: rs.Network,
+		Address: rs.Address,
+	})
+}
+
+func (p *redisSD) Get(ctx context.Context, name string) (services []*sd.Service, err error) {
+	m, err := p.client.HGetAll(ctx, p.key(name)).Result()
+	if err != nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	var expired []string
+	for id, v := range m {
+		var rs redisService
+		if err := json.Unmarshal([]byte(v), &rs); err != nil || rs.Expire < now {
+			expired = append(expired, id)
+			continue
+		}
+		services = append(services, &sd.Service{
+			ID:      id,
+			Name:    name,
+			Node:    rs.Node,
+			Network: rs.Network,
+			Address: rs.Address,
+		})
+	}
+	if len(expired) > 0 {
+		if err := p.client.HDel(ctx, p.key(name), expired...).Err(); err != nil {
+			p.log.Warn(err)
+		}
+	}
+	return services, nil
+}
+
+func (p *redisSD) set(ctx context.Context, service *sd.Service) error {
+	v, err := json.Marshal(&redisService{
+		Node:    service.Node,
+		Network: service.Network,
+		Address: service.Address,
+		Expire:  time.Now().Add(p.options.ttl).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	key := p.key(service.Name)
+	pipe := p.client.TxPipeline()
+	pipe.HSet(ctx, key, service.ID, v)
+	// the hash is removed if none of the services is renewed for a while.
+	pipe.Expire(ctx, key, 10*p.options.ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (p *redisSD) key(name string) string {
+	return p.options.prefix + ":" + name
+}
@@ -0,0 +1,62 @@
+package selector
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-gost/core/selector"
+	ctxvalue "github.com/go-gost/x/ctx"
+	"github.com/patrickmn/go-cache"
+)
+
+// DefaultAffinityTTL is the default idle time after which a sticky binding expires.
+const DefaultAffinityTTL = 10 * time.Minute
+
+type affinityStrategy[T any] struct {
+	strategy selector.Strategy[T]
+	ttl      time.Duration
+	cache    *cache.Cache
+}
+
+// AffinityStrategy wraps a strategy with session affinity.
+// The affinity key is taken from the hash source of the context (client IP by default,
+// or the destination host when the handler's hash option is set to host).
+// A key sticks to the object previously selected for it as long as that object
+// survives the filters (it is healthy) and the binding is used within the TTL.
+func AffinityStrategy[T any](strategy selector.Strategy[T], ttl time.Duration) selector.Strategy[T] {
+	if strategy == nil {
+		strategy = RoundRobinStrategy[T]()
+	}
+	if ttl <= 0 {
+		ttl = DefaultAffinityTTL
+	}
+	return &affinityStrategy[T]{
+		strategy: strategy,
+		ttl:      ttl,
+		cache:    cache.New(ttl, 2*ttl),
+	}
+}
+
+func (s *affinityStrategy[T]) Apply(ctx context.Context, vs ...T) (v T) {
+	if len(vs) == 0 {
+		return
+	}
+
+	h := ctxvalue.HashFromContext(ctx)
+	if h == nil || h.Source == "" {
+		return s.strategy.Apply(ctx, vs...)
+	}
+
+	if cv, ok := s.cache.Get(h.Source); ok {
+		for i := range vs {
+			if any(vs[i]) == cv {
+				s.cache.Set(h.Source, cv, s.ttl)
+				return vs[i]
+			}
+		}
+	}
+
+	v = s.strategy.Apply(ctx, vs...)
+	s.cache.Set(h.Source, any(v), s.ttl)
+	return
+}
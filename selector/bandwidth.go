@@ -0,0 +1,157 @@
+package selector
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/metadata"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/core/selector"
+	"github.com/go-gost/x/stats"
+)
+
+const (
+	labelBandwidth = "bandwidth"
+)
+
+const (
+	// the minimum interval between two samples of the live stats.
+	bandwidthSampleInterval = time.Second
+	// the smoothing factor of the exponentially weighted moving average.
+	bandwidthSmoothing = 0.5
+	// the stats of an idle object are discarded after this period.
+	bandwidthStatsTTL = 10 * time.Minute
+)
+
+type liveStats struct {
+	stats     *stats.Stats
+	rate      float64
+	loss      float64
+	bytes     uint64
+	conns     uint64
+	errs      uint64
+	atime     time.Time
+	sampledAt time.Time
+}
+
+// sample returns the smoothed throughput (bytes per second) and connection loss ratio.
+func (s *liveStats) sample(now time.Time) (rate, loss float64) {
+	d := now.Sub(s.sampledAt)
+	if d < bandwidthSampleInterval {
+		return s.rate, s.loss
+	}
+
+	bytes := s.stats.Get(stats.KindInputBytes) + s.stats.Get(stats.KindOutputBytes)
+	conns := s.stats.Get(stats.KindTotalConns)
+	errs := s.stats.Get(stats.KindTotalErrs)
+
+	s.rate = bandwidthSmoothing*float64(bytes-s.bytes)/d.Seconds() + (1-bandwidthSmoothing)*s.rate
+	if n := conns - s.conns; n > 0 {
+		s.loss = bandwidthSmoothing*float64(errs-s.errs)/float64(n) + (1-bandwidthSmoothing)*s.loss
+	}
+
+	s.bytes, s.conns, s.errs = bytes, conns, errs
+	s.sampledAt = now
+	return s.rate, s.loss
+}
+
+type liveStatsMap struct {
+	m     map[any]*liveStats
+	prune time.Time
+	mu    sync.Mutex
+}
+
+var liveStatsTable = liveStatsMap{
+	m: make(map[any]*liveStats),
+}
+
+// LiveStats returns the live traffic stats of the object v (e.g. a node),
+// the stats are fed by the routes and consumed by BandwidthStrategy.
+// Nil is returned if v is not selected by BandwidthStrategy, so that the traffic of the other objects is not tracked.
+func LiveStats(v any) *stats.Stats {
+	t := &liveStatsTable
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s := t.m[v]; s != nil {
+		s.atime = time.Now()
+		return s.stats
+	}
+	return nil
+}
+
+func sampleLiveStats(v any, now time.Time) (rate, loss float64) {
+	t := &liveStatsTable
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.get(v, now).sample(now)
+}
+
+func (t *liveStatsMap) get(v any, now time.Time) *liveStats {
+	if now.Sub(t.prune) > bandwidthStatsTTL {
+		for k, s := range t.m {
+			if now.Sub(s.atime) > bandwidthStatsTTL && s.stats.Get(stats.KindCurrentConns) == 0 {
+				delete(t.m, k)
+			}
+		}
+		t.prune = now
+	}
+
+	s := t.m[v]
+	if s == nil {
+		s = &liveStats{
+			stats:     &stats.Stats{},
+			sampledAt: now,
+		}
+		t.m[v] = s
+	}
+	s.atime = now
+	return s
+}
+
+type bandwidthStrategy[T any] struct {
+	counter uint64
+}
+
+// BandwidthStrategy is a strategy for node selector.
+// The node with the most headroom will be selected. The headroom is the
+// remaining bandwidth (the bandwidth label in bytes per second minus the live throughput)
+// discounted by the connection loss ratio, a node without bandwidth label
+// is considered to have more headroom the less traffic it carries.
+func BandwidthStrategy[T any]() selector.Strategy[T] {
+	return &bandwidthStrategy[T]{}
+}
+
+func (s *bandwidthStrategy[T]) Apply(ctx context.Context, vs ...T) (v T) {
+	if len(vs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	// rotate the start index to share the load between the nodes with equal headroom.
+	start := int((atomic.AddUint64(&s.counter, 1) - 1) % uint64(len(vs)))
+
+	var max float64
+	for i := range vs {
+		idx := (start + i) % len(vs)
+		rate, loss := sampleLiveStats(any(vs[idx]), now)
+
+		var bandwidth float64
+		if md, _ := any(vs[idx]).(metadata.Metadatable); md != nil {
+			bandwidth = mdutil.GetFloat(md.Metadata(), labelBandwidth)
+		}
+		headroom := (bandwidth - rate) * (1 - loss)
+		if bandwidth-rate < 0 {
+			headroom = (bandwidth - rate) * (1 + loss)
+		}
+
+		if i == 0 || headroom > max {
+			max = headroom
+			v = vs[idx]
+		}
+	}
+	return
+}
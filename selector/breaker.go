@@ -0,0 +1,118 @@
+package selector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	"github.com/go-gost/core/selector"
+)
+
+// default options for CircuitBreakerFilter
+const (
+	DefaultBreakerThreshold      = 5
+	DefaultBreakerOpenTimeout    = 30 * time.Second
+	DefaultBreakerHalfOpenProbes = 1
+)
+
+type breakerState struct {
+	// the failure time which opened the circuit.
+	failTime time.Time
+	// the start time of the current half-open window.
+	probeTime time.Time
+	probes    int
+}
+
+type circuitBreakerFilter[T any] struct {
+	threshold      int
+	openTimeout    time.Duration
+	halfOpenProbes int
+	states         map[selector.Marker]*breakerState
+	mu             sync.Mutex
+}
+
+// CircuitBreakerFilter filters objects with an open circuit.
+// The circuit of an object is opened if it fails threshold times consecutively,
+// no traffic goes to it during openTimeout, then the circuit turns half-open
+// and at most halfOpenProbes connections are let through as probes.
+// A successful probe closes the circuit, a failed one opens it again.
+func CircuitBreakerFilter[T any](threshold int, openTimeout time.Duration, halfOpenProbes int) selector.Filter[T] {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = DefaultBreakerOpenTimeout
+	}
+	if halfOpenProbes <= 0 {
+		halfOpenProbes = DefaultBreakerHalfOpenProbes
+	}
+	return &circuitBreakerFilter[T]{
+		threshold:      threshold,
+		openTimeout:    openTimeout,
+		halfOpenProbes: halfOpenProbes,
+		states:         make(map[selector.Marker]*breakerState),
+	}
+}
+
+// Filter filters objects with open circuit.
+func (f *circuitBreakerFilter[T]) Filter(ctx context.Context, vs ...T) []T {
+	if len(vs) <= 1 {
+		return vs
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var l []T
+	for _, v := range vs {
+		mi, _ := any(v).(selector.Markable)
+		if mi == nil {
+			l = append(l, v)
+			continue
+		}
+		marker := mi.Marker()
+		if marker == nil {
+			l = append(l, v)
+			continue
+		}
+		if f.allow(marker) {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+func (f *circuitBreakerFilter[T]) allow(marker selector.Marker) bool {
+	// closed
+	if marker.Count() < int64(f.threshold) {
+		delete(f.states, marker)
+		return true
+	}
+
+	st := f.states[marker]
+	if st == nil || !st.failTime.Equal(marker.Time()) {
+		// the circuit is opened by a new failure.
+		if st == nil {
+			logger.Default().Debugf("circuit breaker: open after %d failures", marker.Count())
+		}
+		st = &breakerState{failTime: marker.Time()}
+		f.states[marker] = st
+	}
+
+	// open
+	if time.Since(st.failTime) < f.openTimeout {
+		return false
+	}
+
+	// half-open, renew the probe quota if the previous probes got no result.
+	if st.probeTime.IsZero() || time.Since(st.probeTime) >= f.openTimeout {
+		st.probeTime = time.Now()
+		st.probes = 0
+	}
+	if st.probes < f.halfOpenProbes {
+		st.probes++
+		return true
+	}
+	return false
+}
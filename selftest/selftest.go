@@ -0,0 +1,514 @@
+// Package selftest verifies the configured services by connecting to them locally as a client,
+// e.g. the HTTP CONNECT, SOCKS and shadowsocks handshakes, to catch the broken auth and TLS configs early.
+package selftest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/logger"
+	mdutil "github.com/go-gost/core/metadata/util"
+	"github.com/go-gost/core/metrics"
+	xchain "github.com/go-gost/x/chain"
+	"github.com/go-gost/x/config"
+	"github.com/go-gost/x/config/parsing"
+	node_parser "github.com/go-gost/x/config/parsing/node"
+	xhop "github.com/go-gost/x/hop"
+	mdx "github.com/go-gost/x/metadata"
+	xmetrics "github.com/go-gost/x/metrics"
+	"github.com/go-gost/x/registry"
+	ss "github.com/shadowsocks/shadowsocks-go/shadowsocks"
+)
+
+const (
+	// MDKeySelfTest disables the self-test of the service if it is false.
+	MDKeySelfTest = "selftest"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+)
+
+var (
+	// the connectors used to test the services by the handler types.
+	connectors = map[string]string{
+		"auto":    "http",
+		"http":    "http",
+		"http2":   "http2",
+		"socks":   "socks5",
+		"socks4":  "socks4",
+		"socks4a": "socks4a",
+		"socks5":  "socks5",
+		"ss":      "ss",
+		"relay":   "relay",
+	}
+
+	// the listeners can not be dialed as a client.
+	undialable = map[string]bool{
+		"udp":  true,
+		"rtcp": true,
+		"rudp": true,
+	}
+)
+
+const (
+	StatePass = "pass"
+	StateFail = "fail"
+	// the service is not testable, e.g. the unknown protocols or the forwarding handlers.
+	StateSkip = "skip"
+)
+
+// Result is the result of the self-test of a service.
+type Result struct {
+	Service string
+	Addr    string
+	// the protocol of the client, e.g. socks5+tls.
+	Protocol string
+	State    string
+	// the reason of the failed or skipped test.
+	Error    string
+	Duration time.Duration
+	Time     time.Time
+}
+
+type options struct {
+	timeout time.Duration
+	target  string
+	logger  logger.Logger
+}
+
+type Option func(opts *options)
+
+// TimeoutOption sets the timeout of the test of each service.
+func TimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+// TargetOption sets the target address connected through the services,
+// a local target is used if it is empty.
+func TargetOption(target string) Option {
+	return func(opts *options) {
+		opts.target = target
+	}
+}
+
+func LoggerOption(logger logger.Logger) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
+}
+
+// Tester tests the services of the global config.
+type Tester struct {
+	mu      sync.RWMutex
+	results map[string]*Result
+	running sync.Mutex
+	cancel  context.CancelFunc
+	options options
+}
+
+func NewTester(opts ...Option) *Tester {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.timeout <= 0 {
+		options.timeout = defaultTimeout
+	}
+	if options.logger == nil {
+		options.logger = logger.Default().WithFields(map[string]any{
+			"kind": "selftest",
+		})
+	}
+
+	return &Tester{
+		results: make(map[string]*Result),
+		options: options,
+	}
+}
+
+var (
+	defaultTester *Tester
+	defaultMu     sync.Mutex
+)
+
+// DefaultTester returns the tester used by the API.
+func DefaultTester() *Tester {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultTester == nil {
+		defaultTester = NewTester()
+	}
+	return defaultTester
+}
+
+// SetDefaultTester replaces the default tester, the scheduled tests of the previous tester are stopped.
+func SetDefaultTester(t *Tester) {
+	if t == nil {
+		return
+	}
+	defaultMu.Lock()
+	old := defaultTester
+	defaultTester = t
+	defaultMu.Unlock()
+
+	if old != nil && old != t {
+		old.Stop()
+	}
+}
+
+// Start runs the tests of all services periodically with the interval.
+func (t *Tester) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.mu.Lock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+	t.cancel = cancel
+	t.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			t.Run(ctx)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduled tests.
+func (t *Tester) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cancel != nil {
+		t.cancel()
+		t.cancel = nil
+	}
+}
+
+// Results returns the last results of the services ordered by the service names.
+func (t *Tester) Results() []*Result {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var results []*Result
+	for _, r := range t.results {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Service < results[j].Service
+	})
+	return results
+}
+
+// Run tests the services of the names, or all services if no name is specified.
+func (t *Tester) Run(ctx context.Context, names ...string) []*Result {
+	t.running.Lock()
+	defer t.running.Unlock()
+
+	cfg := config.Global()
+
+	filter := make(map[string]bool)
+	for _, name := range names {
+		filter[name] = true
+	}
+
+	var services []*config.ServiceConfig
+	for _, svc := range cfg.Services {
+		if svc == nil || (len(filter) > 0 && !filter[svc.Name]) {
+			continue
+		}
+		services = append(services, svc)
+	}
+
+	target, stop, err := t.listenTarget()
+	if err != nil {
+		t.options.logger.Error(err)
+		return nil
+	}
+	defer stop()
+
+	var results []*Result
+	for _, svc := range services {
+		r := t.test(ctx, svc, target)
+		results = append(results, r)
+
+		t.mu.Lock()
+		t.results[r.Service] = r
+		t.mu.Unlock()
+
+		if g := xmetrics.GetGauge(xmetrics.MetricServiceSelfTestGauge,
+			metrics.Labels{"service": r.Service}); g != nil && r.State != StateSkip {
+			v := 0.0
+			if r.State == StatePass {
+				v = 1
+			}
+			g.Set(v)
+		}
+	}
+
+	t.mu.Lock()
+	for name := range t.results {
+		if registry.ServiceRegistry().Get(name) == nil {
+			delete(t.results, name)
+		}
+	}
+	t.mu.Unlock()
+
+	return results
+}
+
+// target is the address connected through the services,
+// the probe is echoed by the local target to verify the data path.
+type target struct {
+	addr  string
+	probe string
+}
+
+// listenTarget starts the local target if the target is not specified.
+func (t *Tester) listenTarget() (*target, func(), error) {
+	if t.options.target != "" {
+		return &target{addr: t.options.target}, func() {}, nil
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	tg := &target{
+		addr:  ln.Addr().String(),
+		probe: hex.EncodeToString(b),
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(t.options.timeout))
+				b := make([]byte, len(tg.probe))
+				if _, err := io.ReadFull(conn, b); err != nil {
+					return
+				}
+				conn.Write(b)
+			}()
+		}
+	}()
+
+	return tg, func() { ln.Close() }, nil
+}
+
+func (t *Tester) test(ctx context.Context, cfg *config.ServiceConfig, tg *target) (r *Result) {
+	start := time.Now()
+	r = &Result{
+		Service: cfg.Name,
+		Time:    start,
+	}
+	defer func() {
+		r.Duration = time.Since(start)
+	}()
+
+	log := t.options.logger.WithFields(map[string]any{
+		"service": cfg.Name,
+	})
+
+	node, err := t.clientNode(cfg)
+	if node != nil {
+		r.Addr = node.Addr
+		r.Protocol = node.Connector.Type
+		if node.Dialer.Type != "tcp" {
+			r.Protocol += "+" + node.Dialer.Type
+		}
+	}
+	if err != nil {
+		r.State, r.Error = StateSkip, err.Error()
+		log.Debugf("skip: %v", err)
+		return
+	}
+
+	if err = t.connect(ctx, node, tg, log); err != nil {
+		r.State, r.Error = StateFail, err.Error()
+		log.Warnf("self-test %s %s failed: %v", r.Protocol, r.Addr, err)
+		return
+	}
+
+	r.State = StatePass
+	log.Debugf("self-test %s %s passed in %s", r.Protocol, r.Addr, time.Since(start))
+	return
+}
+
+// clientNode builds the config of the client node connecting to the service.
+func (t *Tester) clientNode(cfg *config.ServiceConfig) (*config.NodeConfig, error) {
+	if cfg.Metadata != nil {
+		md := mdx.NewMetadata(cfg.Metadata)
+		if md.IsExists(MDKeySelfTest) && !mdutil.GetBool(md, MDKeySelfTest) {
+			return nil, errors.New("disabled")
+		}
+	}
+	if cfg.Handler == nil || cfg.Listener == nil {
+		return nil, errors.New("no handler or listener")
+	}
+	if cfg.Forwarder != nil {
+		return nil, errors.New("forwarding service")
+	}
+
+	connector := connectors[cfg.Handler.Type]
+	if connector == "" || !registry.ConnectorRegistry().IsRegistered(connector) {
+		return nil, fmt.Errorf("handler %s is not supported", cfg.Handler.Type)
+	}
+	dialer := cfg.Listener.Type
+	if dialer == "" {
+		dialer = "tcp"
+	}
+	if undialable[dialer] || !registry.DialerRegistry().IsRegistered(dialer) {
+		return nil, fmt.Errorf("listener %s is not supported", dialer)
+	}
+
+	svc := registry.ServiceRegistry().Get(cfg.Name)
+	if svc == nil {
+		return nil, errors.New("service is not running")
+	}
+	addr, err := localAddr(svc.Addr())
+	if err != nil {
+		return nil, err
+	}
+
+	auth := cfg.Handler.Auth
+	if auth == nil {
+		auth = autherAuth(cfg.Handler.Auther)
+	}
+	if connector == "ss" && auth != nil {
+		// the AEAD ciphers share the salt replay filter in the process,
+		// the salt of the local client is always rejected by the service.
+		if _, err := ss.NewCipher(auth.Username, auth.Password); err != nil {
+			return nil, fmt.Errorf("ss cipher %s is not supported", auth.Username)
+		}
+	}
+
+	node := &config.NodeConfig{
+		Name: cfg.Name,
+		Addr: addr,
+		// the metadata of the handler and listener are not strictly the metadata of the connector and dialer.
+		Metadata: map[string]any{
+			parsing.MDKeyStrictMetadata: false,
+		},
+		Connector: &config.ConnectorConfig{
+			Type:     connector,
+			Auth:     auth,
+			Metadata: cfg.Handler.Metadata,
+		},
+		Dialer: &config.DialerConfig{
+			Type:     dialer,
+			Metadata: cfg.Listener.Metadata,
+		},
+	}
+	if tls := cfg.Listener.TLS; tls != nil {
+		node.Dialer.TLS = &config.TLSConfig{
+			ServerName: tls.ServerName,
+		}
+	}
+	return node, nil
+}
+
+// autherAuth returns the first credential of the auther in the global config.
+func autherAuth(name string) *config.AuthConfig {
+	if name == "" {
+		return nil
+	}
+	for _, v := range config.Global().Authers {
+		if v != nil && v.Name == name && len(v.Auths) > 0 {
+			return v.Auths[0]
+		}
+	}
+	return nil
+}
+
+func (t *Tester) connect(ctx context.Context, cfg *config.NodeConfig, tg *target, log logger.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, t.options.timeout)
+	defer cancel()
+
+	node, err := node_parser.ParseNode("selftest", cfg, log)
+	if err != nil {
+		return err
+	}
+	ch := xchain.NewChain("selftest", xchain.LoggerChainOption(log))
+	ch.AddHop(xhop.NewHop(
+		xhop.NameOption("selftest"),
+		xhop.NodeOption(node),
+		xhop.LoggerOption(log),
+	))
+
+	conn, err := ch.Route(ctx, "tcp", tg.addr).Dial(ctx, "tcp", tg.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if tg.probe == "" {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// the client writes first, some connectors (e.g. ss) send the request header with the first write.
+	if _, err := conn.Write([]byte(tg.probe)); err != nil {
+		return fmt.Errorf("write probe: %w", err)
+	}
+	b := make([]byte, len(tg.probe))
+	if _, err := io.ReadFull(conn, b); err != nil {
+		return fmt.Errorf("read probe: %w", err)
+	}
+	if string(b) != tg.probe {
+		return errors.New("probe mismatch")
+	}
+	return nil
+}
+
+// localAddr returns the loopback address of the listening address.
+func localAddr(addr net.Addr) (string, error) {
+	if addr == nil {
+		return "", errors.New("no listening address")
+	}
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+		if ip != nil && ip.To4() == nil {
+			host = "::1"
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStartupTimeout = 30 * time.Second
+	dependsCheckInterval  = 500 * time.Millisecond
+)
+
+// Dependency is a service or chain the service depends on.
+type Dependency interface {
+	Name() string
+	// Ready reports whether the dependency is ready.
+	Ready() bool
+}
+
+// waitDependencies waits until all the dependencies are ready.
+func (s *defaultService) waitDependencies() error {
+	opts := s.options.depends
+	if len(opts.deps) == 0 {
+		return nil
+	}
+
+	timeout := opts.timeout
+	if timeout <= 0 {
+		timeout = defaultStartupTimeout
+	}
+
+	s.setState(StateWaiting)
+
+	ticker := time.NewTicker(dependsCheckInterval)
+	defer ticker.Stop()
+
+	for attempt := 0; opts.retries < 0 || attempt <= opts.retries; attempt++ {
+		deadline := time.Now().Add(timeout)
+		for {
+			pending := pendingDependencies(opts.deps)
+			if len(pending) == 0 {
+				s.options.logger.Debugf("dependencies are ready")
+				return nil
+			}
+			if time.Now().After(deadline) {
+				s.options.logger.Warnf("dependencies %s are not ready in %s (attempt %d)",
+					strings.Join(pending, ","), timeout, attempt+1)
+				break
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.done:
+				return fmt.Errorf("service %s is closed", s.name)
+			}
+		}
+	}
+
+	return fmt.Errorf("service %s: dependencies %s are not ready",
+		s.name, strings.Join(pendingDependencies(opts.deps), ","))
+}
+
+func pendingDependencies(deps []Dependency) (pending []string) {
+	for _, dep := range deps {
+		if !dep.Ready() {
+			pending = append(pending, dep.Name())
+		}
+	}
+	return
+}
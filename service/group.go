@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/service"
+)
+
+// groupService is the service listening on multiple addresses, e.g. the ports of a port range.
+type groupService struct {
+	services []service.Service
+}
+
+// NewGroupService combines the services to a service, it is closed if any of the services fails.
+func NewGroupService(services ...service.Service) service.Service {
+	return &groupService{
+		services: services,
+	}
+}
+
+func (s *groupService) Addr() net.Addr {
+	if len(s.services) == 0 {
+		return &net.TCPAddr{}
+	}
+	return s.services[0].Addr()
+}
+
+func (s *groupService) Serve() error {
+	if len(s.services) == 0 {
+		return errors.New("service: no available service")
+	}
+
+	errc := make(chan error, len(s.services))
+	for _, svc := range s.services {
+		go func(svc service.Service) {
+			errc <- svc.Serve()
+		}(svc)
+	}
+
+	err := <-errc
+	s.Close()
+	for i := 1; i < len(s.services); i++ {
+		<-errc
+	}
+	return err
+}
+
+func (s *groupService) Close() error {
+	var errs []error
+	for _, svc := range s.services {
+		if err := svc.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Status returns the status of the first service.
+func (s *groupService) Status() *Status {
+	if len(s.services) > 0 {
+		if v, ok := s.services[0].(interface{ Status() *Status }); ok {
+			return v.Status()
+		}
+	}
+	return nil
+}
+
+// SetMaintenance puts all the services into maintenance mode, nil brings them back.
+func (s *groupService) SetMaintenance(m *Maintenance) {
+	for _, svc := range s.services {
+		if v, ok := svc.(interface{ SetMaintenance(m *Maintenance) }); ok {
+			// each service keeps its own copy as the response is defaulted in place.
+			var mc *Maintenance
+			if m != nil {
+				c := *m
+				mc = &c
+			}
+			v.SetMaintenance(mc)
+		}
+	}
+}
+
+// SwapChain swaps the chain of all the services.
+func (s *groupService) SwapChain(name string, c chain.Chainer, grace time.Duration) error {
+	for _, svc := range s.services {
+		v, ok := svc.(interface {
+			SwapChain(name string, c chain.Chainer, grace time.Duration) error
+		})
+		if !ok {
+			return ErrChainNotSwappable
+		}
+		if err := v.SwapChain(name, c, grace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetScheduleOverride overrides the schedule of all the services.
+func (s *groupService) SetScheduleOverride(override string) error {
+	for _, svc := range s.services {
+		v, ok := svc.(interface{ SetScheduleOverride(override string) error })
+		if !ok {
+			return ErrNoSchedule
+		}
+		if err := v.SetScheduleOverride(override); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// overflow policies of the connection limiter.
+const (
+	// the connections exceeding the limit and the queue are closed.
+	OverflowReject = "reject"
+	// the service stops accepting until a connection finishes,
+	// the pending connections are left to the backlog of the listener.
+	OverflowBlock = "block"
+)
+
+type ticket int
+
+const (
+	ticketRejected ticket = iota
+	ticketAcquired
+	ticketQueued
+)
+
+// connLimiter limits the number of the connections handled concurrently by a service.
+type connLimiter struct {
+	slots        chan struct{}
+	queue        chan struct{}
+	queueTimeout time.Duration
+	block        bool
+}
+
+func newConnLimiter(limit int, queueSize int, queueTimeout time.Duration, overflow string) *connLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	l := &connLimiter{
+		slots:        make(chan struct{}, limit),
+		queueTimeout: queueTimeout,
+		block:        overflow == OverflowBlock,
+	}
+	if queueSize > 0 {
+		l.queue = make(chan struct{}, queueSize)
+	}
+	return l
+}
+
+// admit is called by the accept loop for each accepted connection,
+// a queued connection must wait for its slot before being handled.
+func (l *connLimiter) admit(done <-chan struct{}) ticket {
+	if l == nil {
+		return ticketAcquired
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return ticketAcquired
+	default:
+	}
+
+	if l.queue != nil {
+		select {
+		case l.queue <- struct{}{}:
+			return ticketQueued
+		default:
+		}
+	}
+
+	if !l.block {
+		return ticketRejected
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return ticketAcquired
+	case <-done:
+		return ticketRejected
+	}
+}
+
+// wait waits for the slot of a queued connection, it reports false if
+// the connection waits longer than the queue timeout.
+func (l *connLimiter) wait(ctx context.Context) bool {
+	defer func() { <-l.queue }()
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timeout:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+func (l *connLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// cancel gives back the ticket of the connection which is not handled.
+func (l *connLimiter) cancel(t ticket) {
+	switch t {
+	case ticketAcquired:
+		l.release()
+	case ticketQueued:
+		<-l.queue
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gost/gosocks4"
+	"github.com/go-gost/gosocks5"
+)
+
+const (
+	// MaintenanceAuto responds by the protocol sniffed from the first byte of the connection.
+	MaintenanceAuto = "auto"
+	// MaintenanceHTTP responds with HTTP 503.
+	MaintenanceHTTP = "http"
+	// MaintenanceSOCKS responds with the SOCKS4 or SOCKS5 failure reply.
+	MaintenanceSOCKS = "socks"
+	// MaintenanceReset resets the connection.
+	MaintenanceReset = "reset"
+
+	maintenanceTimeout = 5 * time.Second
+)
+
+// Maintenance is the response to the new connections of the service in maintenance mode,
+// the connections accepted before keep running until they finish.
+type Maintenance struct {
+	// the type of the response, one of auto, http, socks or reset, default is auto.
+	Response string
+	// the body of the HTTP response.
+	Message string
+	// the value of the Retry-After header of the HTTP response.
+	RetryAfter time.Duration
+}
+
+// SetMaintenance puts the service into maintenance mode, nil brings the service back.
+func (s *defaultService) SetMaintenance(m *Maintenance) {
+	if m != nil && m.Response == "" {
+		m.Response = MaintenanceAuto
+	}
+
+	old := s.maintenance.Swap(m)
+	if (m == nil) == (old == nil) {
+		return
+	}
+	// the state of the service not serving is kept.
+	if state := s.status.State(); state == StateReady || state == StateMaintenance {
+		s.setState(s.readyState())
+	}
+}
+
+// readyState returns the state of the serving service.
+func (s *defaultService) readyState() State {
+	if s.scheduler != nil && s.scheduler.isDisabled() {
+		return StateDisabled
+	}
+	if s.maintenance.Load() != nil {
+		return StateMaintenance
+	}
+	return StateReady
+}
+
+// Maintenance returns the maintenance of the service, nil if the service is not in maintenance mode.
+func (s *defaultService) Maintenance() *Maintenance {
+	return s.maintenance.Load()
+}
+
+// refuse responds to the connection accepted in maintenance mode and closes it.
+func (s *defaultService) refuse(conn net.Conn, m *Maintenance) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(maintenanceTimeout))
+	br := bufio.NewReader(conn)
+
+	response := m.Response
+	if response == MaintenanceAuto {
+		response = MaintenanceReset
+		if b, err := br.Peek(1); err == nil {
+			switch {
+			case b[0] == gosocks4.Ver4 || b[0] == gosocks5.Ver5:
+				response = MaintenanceSOCKS
+			case 'A' <= b[0] && b[0] <= 'Z':
+				response = MaintenanceHTTP
+			}
+		}
+	}
+
+	switch response {
+	case MaintenanceHTTP:
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		req.Body.Close()
+
+		msg := m.Message
+		if msg == "" {
+			msg = http.StatusText(http.StatusServiceUnavailable)
+		}
+		resp := &http.Response{
+			StatusCode:    http.StatusServiceUnavailable,
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          io.NopCloser(strings.NewReader(msg)),
+			ContentLength: int64(len(msg)),
+			Close:         true,
+		}
+		resp.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		if m.RetryAfter > 0 {
+			resp.Header.Set("Retry-After", strconv.Itoa(int(m.RetryAfter.Seconds())))
+		}
+		resp.Write(conn)
+
+	case MaintenanceSOCKS:
+		b, err := br.Peek(1)
+		if err != nil {
+			return
+		}
+		if b[0] == gosocks4.Ver4 {
+			if _, err := gosocks4.ReadRequest(br); err != nil {
+				return
+			}
+			gosocks4.NewReply(gosocks4.Failed, nil).Write(conn)
+			return
+		}
+
+		methods, err := gosocks5.ReadMethods(br)
+		if err != nil {
+			return
+		}
+		method := uint8(gosocks5.MethodNoAcceptable)
+		for _, v := range methods {
+			if v == gosocks5.MethodNoAuth {
+				method = v
+				break
+			}
+		}
+		// the client requiring the authentication is refused by the method selection.
+		if err := gosocks5.WriteMethod(method, conn); err != nil || method == gosocks5.MethodNoAcceptable {
+			return
+		}
+		if _, err := gosocks5.ReadRequest(br); err != nil {
+			return
+		}
+		gosocks5.NewReply(gosocks5.Failure, nil).Write(conn)
+
+	default:
+		reset(conn)
+	}
+
+	s.options.logger.Debugf("maintenance: connection from %s is refused by %s", conn.RemoteAddr(), response)
+}
+
+// reset closes the TCP connection with RST instead of FIN.
+func reset(conn net.Conn) {
+	if c, ok := conn.(interface{ SetLinger(sec int) error }); ok {
+		c.SetLinger(0)
+	}
+}
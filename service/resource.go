@@ -0,0 +1,350 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/core/chain"
+	"github.com/go-gost/core/metrics"
+	"github.com/go-gost/core/observer"
+	xmetrics "github.com/go-gost/x/metrics"
+	"github.com/go-gost/x/stats"
+)
+
+const (
+	// the pprof label of the goroutines of the service.
+	serviceLabel = "gost.service"
+
+	defaultResourceInterval = 5 * time.Second
+	// the size of the buffers copying the relayed data.
+	relayBufferSize = 64 * 1024
+)
+
+var (
+	rsys = &resourceSystem{
+		accounts: make(map[*resourceAccount]struct{}),
+	}
+)
+
+// Resources is the resource consumption of a service,
+// the zero fields of the caps are unlimited.
+type Resources struct {
+	// the number of the goroutines started for the connections of the service.
+	Goroutines int64
+	// the number of the open client and upstream connections.
+	FDs int64
+	// the estimated memory in bytes of the goroutine stacks and the relay buffers.
+	Memory int64
+	// the bandwidth of the service in bytes per second.
+	Bandwidth int64
+}
+
+type resourceOptions struct {
+	enabled bool
+	caps    Resources
+}
+
+// ResourcesOption enables the resource accounting of the service, the new connections are rejected
+// while any of the consumption exceeds its cap.
+func ResourcesOption(enabled bool, caps Resources) Option {
+	return func(opts *options) {
+		opts.resources = resourceOptions{
+			enabled: enabled || caps != Resources{},
+			caps:    caps,
+		}
+	}
+}
+
+// resourceAccount accounts the resources consumed by the service.
+type resourceAccount struct {
+	s         *defaultService
+	caps      Resources
+	conns     atomic.Int64
+	upstreams atomic.Int64
+	usage     atomic.Pointer[Resources]
+	// exceeded is the resource exceeding its cap, it is empty if all are within the caps.
+	exceeded  atomic.Value
+	lastBytes uint64
+	lastTime  time.Time
+}
+
+func newResourceAccount(s *defaultService, caps Resources) *resourceAccount {
+	a := &resourceAccount{
+		s:    s,
+		caps: caps,
+	}
+	a.usage.Store(&Resources{})
+	a.exceeded.Store("")
+	return a
+}
+
+// Usage returns the resource consumption of the last accounting.
+func (a *resourceAccount) Usage() Resources {
+	return *a.usage.Load()
+}
+
+// Exceeded returns the resource exceeding its cap, it is empty if all are within the caps.
+func (a *resourceAccount) Exceeded() string {
+	v, _ := a.exceeded.Load().(string)
+	return v
+}
+
+func (a *resourceAccount) update(goroutines int64, stackSize int64, now time.Time) {
+	usage := &Resources{
+		Goroutines: goroutines,
+		FDs:        a.conns.Load() + a.upstreams.Load(),
+	}
+	// each relay has a buffer for both directions.
+	usage.Memory = goroutines*stackSize + a.upstreams.Load()*2*relayBufferSize
+
+	if st := a.s.status.Stats(); st != nil {
+		n := st.Get(stats.KindInputBytes) + st.Get(stats.KindOutputBytes)
+		if !a.lastTime.IsZero() && n >= a.lastBytes {
+			if d := now.Sub(a.lastTime); d > 0 {
+				usage.Bandwidth = int64(float64(n-a.lastBytes) / d.Seconds())
+			}
+		}
+		a.lastBytes, a.lastTime = n, now
+	}
+	a.usage.Store(usage)
+
+	for k, v := range map[string]int64{
+		"goroutines": usage.Goroutines,
+		"fds":        usage.FDs,
+		"memory":     usage.Memory,
+		"bandwidth":  usage.Bandwidth,
+	} {
+		if g := xmetrics.GetGauge(xmetrics.MetricServiceResourcesGauge,
+			metrics.Labels{"service": a.s.name, "resource": k}); g != nil {
+			g.Set(float64(v))
+		}
+	}
+
+	exceeded, usageValue, capValue := a.check(usage)
+	if last := a.Exceeded(); exceeded != last {
+		a.exceeded.Store(exceeded)
+		a.alert(exceeded, last, usageValue, capValue)
+	}
+}
+
+// check returns the first resource exceeding its cap.
+func (a *resourceAccount) check(usage *Resources) (string, int64, int64) {
+	switch {
+	case a.caps.Goroutines > 0 && usage.Goroutines > a.caps.Goroutines:
+		return "goroutines", usage.Goroutines, a.caps.Goroutines
+	case a.caps.FDs > 0 && usage.FDs > a.caps.FDs:
+		return "fds", usage.FDs, a.caps.FDs
+	case a.caps.Memory > 0 && usage.Memory > a.caps.Memory:
+		return "memory", usage.Memory, a.caps.Memory
+	case a.caps.Bandwidth > 0 && usage.Bandwidth > a.caps.Bandwidth:
+		return "bandwidth", usage.Bandwidth, a.caps.Bandwidth
+	}
+	return "", 0, 0
+}
+
+func (a *resourceAccount) alert(exceeded, last string, usage, cap int64) {
+	s := a.s
+
+	var msg string
+	if exceeded != "" {
+		msg = fmt.Sprintf("service %s exceeds the %s cap (%d > %d), new connections are rejected", s.name, exceeded, usage, cap)
+		s.options.logger.Warn(msg)
+	} else {
+		msg = fmt.Sprintf("service %s is within the %s cap, new connections are accepted", s.name, last)
+		s.options.logger.Info(msg)
+	}
+	s.status.addEvent(Event{
+		Time:    time.Now(),
+		Message: msg,
+	})
+
+	if obs := s.options.observer; obs != nil {
+		obs.Observe(context.Background(), []observer.Event{ResourceEvent{
+			Kind:     "resource",
+			Service:  s.name,
+			Resource: exceeded,
+			Usage:    usage,
+			Cap:      cap,
+			Msg:      msg,
+		}})
+	}
+}
+
+// ResourceEvent is the alert of the service exceeding (or returning within) the resource cap,
+// Resource is empty if the service is within all the caps.
+type ResourceEvent struct {
+	Kind     string
+	Service  string
+	Resource string
+	Usage    int64
+	Cap      int64
+	Msg      string
+}
+
+func (ResourceEvent) Type() observer.EventType {
+	return observer.EventStatus
+}
+
+// resourceSystem accounts the goroutines of all the services periodically by the pprof labels.
+type resourceSystem struct {
+	mu       sync.Mutex
+	accounts map[*resourceAccount]struct{}
+	running  bool
+}
+
+func (r *resourceSystem) add(a *resourceAccount) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.accounts[a] = struct{}{}
+	if !r.running {
+		r.running = true
+		go r.run()
+	}
+}
+
+func (r *resourceSystem) remove(a *resourceAccount) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.accounts, a)
+}
+
+func (r *resourceSystem) run() {
+	ticker := time.NewTicker(defaultResourceInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.mu.Lock()
+		if len(r.accounts) == 0 {
+			r.running = false
+			r.mu.Unlock()
+			return
+		}
+		accounts := make([]*resourceAccount, 0, len(r.accounts))
+		for a := range r.accounts {
+			accounts = append(accounts, a)
+		}
+		r.mu.Unlock()
+
+		goroutines := countGoroutines()
+
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		var stackSize int64
+		if n := runtime.NumGoroutine(); n > 0 {
+			stackSize = int64(ms.StackInuse) / int64(n)
+		}
+
+		now := time.Now()
+		for _, a := range accounts {
+			a.update(goroutines[a.s.name], stackSize, now)
+		}
+	}
+}
+
+// countGoroutines counts the goroutines of the services by the label of the goroutine profile.
+func countGoroutines() map[string]int64 {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+
+	m := make(map[string]int64)
+	var n int64
+	key := strconv.Quote(serviceLabel) + ":"
+	sc := bufio.NewScanner(&buf)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		// the record header: N @ 0x... 0x...
+		if s, _, ok := strings.Cut(line, " @ "); ok {
+			n, _ = strconv.ParseInt(s, 10, 64)
+			continue
+		}
+		// # labels: {"gost.service":"name", ...}
+		if !strings.HasPrefix(line, "# labels: ") {
+			continue
+		}
+		_, s, ok := strings.Cut(line, key)
+		if !ok {
+			continue
+		}
+		if s, err := strconv.QuotedPrefix(s); err == nil {
+			if name, err := strconv.Unquote(s); err == nil {
+				m[name] += n
+			}
+		}
+	}
+	return m
+}
+
+// handle runs the handler of the conn with the service label, the goroutines started by the handler inherit the label.
+func (a *resourceAccount) handle(ctx context.Context, fn func(ctx context.Context)) {
+	a.conns.Add(1)
+	defer a.conns.Add(-1)
+
+	// the upstream connections are released with the client connection.
+	h := &resourceHandle{a: a}
+	defer func() {
+		a.upstreams.Add(-h.upstreams.Load())
+	}()
+
+	ctx = context.WithValue(ctx, resourceAccountKey{}, h)
+	pprof.Do(ctx, pprof.Labels(serviceLabel, a.s.name), fn)
+}
+
+// resourceHandle accounts the upstream connections of a client connection.
+type resourceHandle struct {
+	a         *resourceAccount
+	upstreams atomic.Int64
+}
+
+type resourceAccountKey struct{}
+
+type resourceChainer struct {
+	chain.Chainer
+}
+
+// ResourceChainer wraps the chain to account the upstream connections of the service,
+// the direct connections without chain are accounted as well.
+func ResourceChainer(c chain.Chainer) chain.Chainer {
+	return &resourceChainer{Chainer: c}
+}
+
+func (c *resourceChainer) Route(ctx context.Context, network, address string, opts ...chain.RouteOption) chain.Route {
+	var route chain.Route
+	if c.Chainer != nil {
+		route = c.Chainer.Route(ctx, network, address, opts...)
+	}
+	if route == nil {
+		route = chain.DefaultRoute
+	}
+	return &resourceRoute{Route: route}
+}
+
+type resourceRoute struct {
+	chain.Route
+}
+
+func (r *resourceRoute) Dial(ctx context.Context, network, address string, opts ...chain.DialOption) (net.Conn, error) {
+	conn, err := r.Route.Dial(ctx, network, address, opts...)
+	if err != nil {
+		return conn, err
+	}
+	if h, _ := ctx.Value(resourceAccountKey{}).(*resourceHandle); h != nil {
+		h.upstreams.Add(1)
+		h.a.upstreams.Add(1)
+	}
+	return conn, err
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/listener"
+	"github.com/go-gost/core/metadata"
+	"github.com/go-gost/x/internal/util/schedule"
+)
+
+const (
+	// ScheduleAuto enables the service by the schedule.
+	ScheduleAuto = "auto"
+	// ScheduleEnable enables the service regardless of the schedule.
+	ScheduleEnable = "enable"
+	// ScheduleDisable disables the service regardless of the schedule.
+	ScheduleDisable = "disable"
+
+	// the interval to retry listening if it fails when the service is enabled.
+	scheduleRetryInterval = time.Minute
+)
+
+var (
+	ErrNoSchedule = errors.New("the service has no schedule")
+)
+
+type scheduleOptions struct {
+	schedule    *schedule.Schedule
+	newListener func() (listener.Listener, error)
+}
+
+// ScheduleOption enables the service only in the windows of the schedule, the listener is closed out of the windows
+// and created again by newListener when the next window begins.
+func ScheduleOption(schedule *schedule.Schedule, newListener func() (listener.Listener, error)) Option {
+	return func(opts *options) {
+		opts.schedule = scheduleOptions{
+			schedule:    schedule,
+			newListener: newListener,
+		}
+	}
+}
+
+// scheduler enables and disables the service by the schedule or the override.
+type scheduler struct {
+	schedule *schedule.Schedule
+	listener *scheduledListener
+	override string
+	disabled bool
+	// overridec notifies the scheduler of the changed override.
+	overridec chan struct{}
+	mu        sync.Mutex
+}
+
+func newScheduler(sched *schedule.Schedule, ln *scheduledListener) *scheduler {
+	return &scheduler{
+		schedule:  sched,
+		listener:  ln,
+		override:  ScheduleAuto,
+		overridec: make(chan struct{}, 1),
+	}
+}
+
+// enabled reports whether the service should be enabled at t.
+func (s *scheduler) enabled(t time.Time) bool {
+	s.mu.Lock()
+	override := s.override
+	s.mu.Unlock()
+
+	switch override {
+	case ScheduleEnable:
+		return true
+	case ScheduleDisable:
+		return false
+	default:
+		return s.schedule.Active(t)
+	}
+}
+
+func (s *scheduler) isDisabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.disabled
+}
+
+// SetScheduleOverride overrides the schedule of the service, one of auto, enable or disable.
+func (s *defaultService) SetScheduleOverride(override string) error {
+	sc := s.scheduler
+	if sc == nil {
+		return ErrNoSchedule
+	}
+
+	sc.mu.Lock()
+	sc.override = override
+	sc.mu.Unlock()
+
+	select {
+	case sc.overridec <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// runSchedule applies the schedule on each change of it until ctx is done.
+func (s *defaultService) runSchedule(ctx context.Context) {
+	sc := s.scheduler
+
+	for {
+		wait := scheduleRetryInterval
+		if s.applySchedule() {
+			wait = 0
+			if next := sc.schedule.Next(time.Now()); !next.IsZero() {
+				wait = time.Until(next)
+			}
+		}
+
+		var timer *time.Timer
+		var timerc <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timerc = timer.C
+		}
+
+		select {
+		case <-timerc:
+		case <-sc.overridec:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// applySchedule enables or disables the listener, it returns false if the listener fails to be created.
+func (s *defaultService) applySchedule() bool {
+	sc := s.scheduler
+	enabled := sc.enabled(time.Now())
+
+	if enabled {
+		if err := sc.listener.enable(); err != nil {
+			s.options.logger.Errorf("schedule: %v, retrying in %v", err, scheduleRetryInterval)
+			return false
+		}
+	} else {
+		sc.listener.disable()
+	}
+
+	sc.mu.Lock()
+	changed := sc.disabled == enabled
+	sc.disabled = !enabled
+	sc.mu.Unlock()
+
+	if changed {
+		if enabled {
+			s.options.logger.Infof("schedule: listening on %s", sc.listener.Addr())
+		} else {
+			s.options.logger.Infof("schedule: stop listening on %s", sc.listener.Addr())
+		}
+		if state := s.status.State(); state == StateReady || state == StateMaintenance || state == StateDisabled {
+			s.setState(s.readyState())
+		}
+	}
+	return true
+}
+
+// scheduledListener is the listener which can be closed and created again,
+// Accept blocks while the listener is closed.
+type scheduledListener struct {
+	newListener func() (listener.Listener, error)
+	ln          listener.Listener
+	addr        net.Addr
+	closed      bool
+	// changedc is closed when the listener is changed.
+	changedc chan struct{}
+	mu       sync.Mutex
+}
+
+func newScheduledListener(ln listener.Listener, newListener func() (listener.Listener, error)) *scheduledListener {
+	return &scheduledListener{
+		newListener: newListener,
+		ln:          ln,
+		addr:        ln.Addr(),
+		changedc:    make(chan struct{}),
+	}
+}
+
+func (l *scheduledListener) Init(md metadata.Metadata) error {
+	return nil
+}
+
+func (l *scheduledListener) Accept() (net.Conn, error) {
+	for {
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			return nil, net.ErrClosed
+		}
+		ln, changedc := l.ln, l.changedc
+		l.mu.Unlock()
+
+		if ln == nil {
+			<-changedc
+			continue
+		}
+
+		conn, err := ln.Accept()
+		if err == nil {
+			return conn, nil
+		}
+
+		l.mu.Lock()
+		changed := !l.closed && l.ln != ln
+		l.mu.Unlock()
+		// the listener is closed by the schedule.
+		if changed {
+			continue
+		}
+		return nil, err
+	}
+}
+
+func (l *scheduledListener) enable() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed || l.ln != nil {
+		return nil
+	}
+	ln, err := l.newListener()
+	if err != nil {
+		return err
+	}
+	l.ln = ln
+	l.addr = ln.Addr()
+	l.notify()
+	return nil
+}
+
+func (l *scheduledListener) disable() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.ln == nil {
+		return
+	}
+	l.ln.Close()
+	l.ln = nil
+	l.notify()
+}
+
+func (l *scheduledListener) notify() {
+	close(l.changedc)
+	l.changedc = make(chan struct{})
+}
+
+func (l *scheduledListener) Addr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.addr
+}
+
+func (l *scheduledListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	l.notify()
+	if l.ln != nil {
+		return l.ln.Close()
+	}
+	return nil
+}
@@ -5,24 +5,39 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-gost/core/admission"
 	"github.com/go-gost/core/handler"
+	"github.com/go-gost/core/ingress"
 	"github.com/go-gost/core/listener"
 	"github.com/go-gost/core/logger"
 	"github.com/go-gost/core/metrics"
 	"github.com/go-gost/core/observer"
 	"github.com/go-gost/core/recorder"
+	"github.com/go-gost/core/sd"
 	"github.com/go-gost/core/service"
 	ctxvalue "github.com/go-gost/x/ctx"
+	xerrors "github.com/go-gost/x/errors"
+	"github.com/go-gost/x/hook"
+	xnet "github.com/go-gost/x/internal/net"
+	"github.com/go-gost/x/internal/util/nat"
+	"github.com/go-gost/x/internal/util/systemd"
 	xmetrics "github.com/go-gost/x/metrics"
 	"github.com/go-gost/x/stats"
+	stats_wrapper "github.com/go-gost/x/stats/wrapper"
 	"github.com/rs/xid"
 )
 
+const (
+	defaultSDRenewInterval = 10 * time.Second
+)
+
 type options struct {
 	admission admission.Admission
 	recorders []recorder.RecorderObject
@@ -32,7 +47,43 @@ type options struct {
 	postDown  []string
 	stats     *stats.Stats
 	observer  observer.Observer
-	logger    logger.Logger
+	sd        sd.SD
+	sdOptions sdOptions
+	sockOpts  *xnet.SockOpts
+	// the period for the active connections to finish when the service is closed.
+	drainTimeout time.Duration
+	connLimit    connLimitOptions
+	nat          natOptions
+	depends      dependsOptions
+	resources    resourceOptions
+	chainSwapper ChainSwapper
+	schedule     scheduleOptions
+	logger       logger.Logger
+}
+
+type natOptions struct {
+	traversal   *nat.Traversal
+	ingress     ingress.Ingress
+	ingressHost string
+}
+
+type connLimitOptions struct {
+	limit        int
+	queueSize    int
+	queueTimeout time.Duration
+	overflow     string
+}
+
+type dependsOptions struct {
+	deps    []Dependency
+	timeout time.Duration
+	retries int
+}
+
+type sdOptions struct {
+	service       string
+	addr          string
+	renewInterval time.Duration
 }
 
 type Option func(opts *options)
@@ -85,18 +136,121 @@ func ObserverOption(observer observer.Observer) Option {
 	}
 }
 
+// SDOption registers the service with the address addr as the name service to the service discovery,
+// the registration is renewed every renewInterval while the service is running.
+func SDOption(sd sd.SD, service string, addr string, renewInterval time.Duration) Option {
+	return func(opts *options) {
+		opts.sd = sd
+		opts.sdOptions = sdOptions{
+			service:       service,
+			addr:          addr,
+			renewInterval: renewInterval,
+		}
+	}
+}
+
+// SockOptsOption sets the socket options of the accepted connections.
+func SockOptsOption(sockOpts *xnet.SockOpts) Option {
+	return func(opts *options) {
+		opts.sockOpts = sockOpts
+	}
+}
+
+// DrainTimeoutOption enables the graceful shutdown, the closed service stops accepting
+// and the active connections are closed in the background if they last longer than timeout.
+func DrainTimeoutOption(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.drainTimeout = timeout
+	}
+}
+
+// ConnLimitOption limits the number of the connections handled concurrently to limit.
+// At most queueSize connections exceeding the limit wait up to queueTimeout for a free slot,
+// the others are handled by the overflow policy, OverflowReject or OverflowBlock.
+func ConnLimitOption(limit int, queueSize int, queueTimeout time.Duration, overflow string) Option {
+	return func(opts *options) {
+		opts.connLimit = connLimitOptions{
+			limit:        limit,
+			queueSize:    queueSize,
+			queueTimeout: queueTimeout,
+			overflow:     overflow,
+		}
+	}
+}
+
+// NATOption keeps the public address of the listener up to date by the NAT traversal,
+// the address is registered to the service discovery instead of the listen address,
+// and published to the ingress as the endpoint of the rule for ingressHost.
+func NATOption(traversal *nat.Traversal, ingress ingress.Ingress, ingressHost string) Option {
+	return func(opts *options) {
+		opts.nat = natOptions{
+			traversal:   traversal,
+			ingress:     ingress,
+			ingressHost: ingressHost,
+		}
+	}
+}
+
+// DependsOnOption sets the dependencies of the service, the service waits for them to be ready
+// in timeout before serving, and retries for retries times (-1 for unlimited).
+func DependsOnOption(deps []Dependency, timeout time.Duration, retries int) Option {
+	return func(opts *options) {
+		opts.depends = dependsOptions{
+			deps:    deps,
+			timeout: timeout,
+			retries: retries,
+		}
+	}
+}
+
+// ChainSwapperOption sets the chain of the handler which can be swapped at runtime.
+func ChainSwapperOption(swapper ChainSwapper) Option {
+	return func(opts *options) {
+		opts.chainSwapper = swapper
+	}
+}
+
 func LoggerOption(logger logger.Logger) Option {
 	return func(opts *options) {
 		opts.logger = logger
 	}
 }
 
+// shutdowner is implemented by the listeners which can notify the clients to stop
+// sending new requests before closing, e.g. by the GOAWAY frame of HTTP/2.
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
 type defaultService struct {
 	name     string
 	listener listener.Listener
 	handler  handler.Handler
 	status   *Status
 	options  options
+
+	// ctx is the context of the handlers, it is canceled after draining.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	conns   map[net.Conn]struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	closing atomic.Bool
+	// drained is set before waiting for the active connections, no connection is added after it.
+	drained bool
+
+	limiter *connLimiter
+	// done is closed when the service is closed, it releases the blocked accept loop.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// the public address discovered by the NAT traversal.
+	publicAddr atomic.Value
+	// publicc notifies the registration of the changed public address.
+	publicc chan struct{}
+
+	maintenance atomic.Pointer[Maintenance]
+	scheduler   *scheduler
 }
 
 func NewService(name string, ln listener.Listener, h handler.Handler, opts ...Option) service.Service {
@@ -114,7 +268,23 @@ func NewService(name string, ln listener.Listener, h handler.Handler, opts ...Op
 			events:     make([]Event, 0, MaxEventSize),
 			stats:      options.stats,
 		},
+		conns: make(map[net.Conn]struct{}),
+		limiter: newConnLimiter(options.connLimit.limit, options.connLimit.queueSize,
+			options.connLimit.queueTimeout, options.connLimit.overflow),
+		done:    make(chan struct{}),
+		publicc: make(chan struct{}, 1),
+	}
+	if options.resources.enabled {
+		s.status.resources = newResourceAccount(s, options.resources.caps)
 	}
+	if options.schedule.schedule != nil && options.schedule.newListener != nil {
+		sl := newScheduledListener(ln, options.schedule.newListener)
+		s.listener = sl
+		s.scheduler = newScheduler(options.schedule.schedule, sl)
+		// the port is not exposed out of the windows before serving.
+		s.applySchedule()
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 	s.setState(StateRunning)
 
 	s.execCmds("pre-up", s.options.preUp)
@@ -127,16 +297,47 @@ func (s *defaultService) Addr() net.Addr {
 }
 
 func (s *defaultService) Serve() error {
+	if err := s.waitDependencies(); err != nil {
+		s.setState(StateFailed)
+		s.options.logger.Error(err)
+		return err
+	}
+
 	s.execCmds("post-up", s.options.postUp)
-	s.setState(StateReady)
+	s.setState(s.readyState())
+	// the listeners are created before serving, so the process is ready once any service is serving.
+	systemd.Ready()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	defer func() {
+		// the handlers keep running while the service is draining.
+		if !s.closing.Load() {
+			s.cancel()
+		}
+	}()
 
 	if s.status.Stats() != nil {
 		go s.observeStats(ctx)
 	}
 
+	if s.options.nat.traversal != nil {
+		go s.traverse(ctx)
+	}
+
+	if s.scheduler != nil {
+		go s.runSchedule(ctx)
+	}
+
+	if s.options.sd != nil {
+		go s.register(ctx)
+	}
+
+	if a := s.status.resources; a != nil {
+		rsys.add(a)
+		defer rsys.remove(a)
+	}
+
 	if v := xmetrics.GetGauge(
 		xmetrics.MetricServicesGauge,
 		metrics.Labels{}); v != nil {
@@ -165,7 +366,9 @@ func (s *defaultService) Serve() error {
 				time.Sleep(tempDelay)
 				continue
 			}
-			s.setState(StateClosed)
+			if !s.closing.Load() {
+				s.setState(StateClosed)
+			}
 			s.options.logger.Errorf("accept: %v", e)
 
 			return e
@@ -173,7 +376,7 @@ func (s *defaultService) Serve() error {
 
 		if tempDelay > 0 {
 			tempDelay = 0
-			s.setState(StateReady)
+			s.setState(s.readyState())
 		}
 
 		s.status.stats.Add(stats.KindTotalConns, 1)
@@ -181,10 +384,15 @@ func (s *defaultService) Serve() error {
 		clientAddr := conn.RemoteAddr().String()
 		clientIP := clientAddr
 		if h, _, _ := net.SplitHostPort(clientAddr); h != "" {
-			clientIP = h
+			clientIP = xnet.NormalizeHost(h)
 		}
 
-		ctx := ctxvalue.ContextWithSid(ctx, ctxvalue.Sid(xid.New().String()))
+		sid := xid.New().String()
+		values := ctxvalue.NewValues()
+		values.SetTraceID(sid)
+
+		ctx := ctxvalue.ContextWithSid(s.ctx, ctxvalue.Sid(sid))
+		ctx = ctxvalue.ContextWithValues(ctx, values)
 		ctx = ctxvalue.ContextWithClientAddr(ctx, ctxvalue.ClientAddr(clientAddr))
 		ctx = ctxvalue.ContextWithHash(ctx, &ctxvalue.Hash{Source: clientIP})
 
@@ -203,7 +411,71 @@ func (s *defaultService) Serve() error {
 			continue
 		}
 
+		if err := s.options.sockOpts.Apply(conn); err != nil {
+			s.options.logger.Warnf("sockopts: %v", err)
+		}
+
+		if m := s.maintenance.Load(); m != nil {
+			go s.refuse(conn, m)
+			continue
+		}
+
+		if a := s.status.resources; a != nil {
+			if res := a.Exceeded(); res != "" {
+				conn.Close()
+				s.options.logger.Debugf("resources: connection from %s is rejected, %s exceeds the cap", clientAddr, res)
+				s.rejectConn()
+				continue
+			}
+		}
+
+		t := s.limiter.admit(s.done)
+		if t == ticketRejected {
+			conn.Close()
+			s.options.logger.Debugf("limit: connection from %s is rejected", clientAddr)
+			s.rejectConn()
+			continue
+		}
+
+		var hstats *stats.Stats
+		if hook.Enabled() {
+			if _, ok := conn.(net.PacketConn); !ok {
+				hstats = &stats.Stats{}
+				conn = stats_wrapper.WrapConn(conn, hstats)
+			}
+			ctx = hook.NewContext(ctx, hook.Event{
+				Service:    s.name,
+				Sid:        string(ctxvalue.SidFromContext(ctx)),
+				RemoteAddr: clientAddr,
+				LocalAddr:  conn.LocalAddr().String(),
+				Stats:      hstats,
+			})
+			if err := hook.Accepted(ctx); err != nil {
+				conn.Close()
+				s.limiter.cancel(t)
+				s.options.logger.Debugf("hook: connection from %s is rejected: %v", clientAddr, err)
+				continue
+			}
+		}
+
+		if !s.addConn(conn) {
+			conn.Close()
+			s.limiter.cancel(t)
+			continue
+		}
 		go func() {
+			defer s.removeConn(conn)
+
+			if t == ticketQueued {
+				if !s.waitConn(ctx) {
+					conn.Close()
+					s.options.logger.Debugf("limit: connection from %s is rejected after queueing", clientAddr)
+					s.rejectConn()
+					return
+				}
+			}
+			defer s.limiter.release()
+
 			s.status.stats.Add(stats.KindCurrentConns, 1)
 			defer s.status.stats.Add(stats.KindCurrentConns, -1)
 
@@ -226,13 +498,30 @@ func (s *defaultService) Serve() error {
 				}()
 			}
 
-			if err := s.handler.Handle(ctx, conn); err != nil {
-				s.options.logger.Error(err)
+			var err error
+			if a := s.status.resources; a != nil {
+				a.handle(ctx, func(ctx context.Context) {
+					err = s.handler.Handle(ctx, conn)
+				})
+			} else {
+				err = s.handler.Handle(ctx, conn)
+			}
+			hook.Closed(ctx, time.Since(start), err)
+			if err != nil {
+				kind := xerrors.KindOf(err)
+				s.options.logger.WithFields(map[string]any{
+					"error": kind,
+				}).Error(err)
 				if v := xmetrics.GetCounter(xmetrics.MetricServiceHandlerErrorsCounter,
 					metrics.Labels{"service": s.name, "client": clientIP}); v != nil {
 					v.Inc()
 				}
+				if v := xmetrics.GetCounter(xmetrics.MetricServiceHandlerErrorKindsCounter,
+					metrics.Labels{"service": s.name, "error": string(kind)}); v != nil {
+					v.Inc()
+				}
 				s.status.stats.Add(stats.KindTotalErrs, 1)
+				s.status.addError(kind)
 			}
 		}()
 	}
@@ -244,14 +533,219 @@ func (s *defaultService) Status() *Status {
 
 func (s *defaultService) Close() error {
 	s.execCmds("pre-down", s.options.preDown)
-	defer s.execCmds("post-down", s.options.postDown)
 
+	s.closeOnce.Do(func() { close(s.done) })
+
+	if s.options.drainTimeout > 0 && !s.closing.Swap(true) {
+		s.setState(StateDraining)
+
+		// the listener stops accepting now, the active connections are drained in the background.
+		var err error
+		if _, ok := s.listener.(shutdowner); !ok {
+			err = s.listener.Close()
+		}
+		go func() {
+			s.drain(s.options.drainTimeout)
+			if closer, ok := s.handler.(io.Closer); ok {
+				closer.Close()
+			}
+			s.execCmds("post-down", s.options.postDown)
+		}()
+		return err
+	}
+
+	defer s.execCmds("post-down", s.options.postDown)
 	if closer, ok := s.handler.(io.Closer); ok {
 		closer.Close()
 	}
 	return s.listener.Close()
 }
 
+// drain waits for the active connections to finish
+// until timeout expires, then closes the remaining ones.
+func (s *defaultService) drain(timeout time.Duration) {
+	if v := xmetrics.GetGauge(xmetrics.MetricServiceDrainingGauge,
+		metrics.Labels{"service": s.name}); v != nil {
+		v.Inc()
+		defer v.Dec()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if sd, ok := s.listener.(shutdowner); ok {
+		if err := sd.Shutdown(ctx); err != nil {
+			s.options.logger.Warnf("shutdown: %v", err)
+		}
+		s.listener.Close()
+	}
+
+	s.mu.Lock()
+	s.drained = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.options.logger.Infof("service %s drained", s.name)
+	case <-ctx.Done():
+		s.mu.Lock()
+		n := len(s.conns)
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		s.options.logger.Warnf("drain timeout, %d connections are closed", n)
+	}
+	s.cancel()
+	s.setState(StateClosed)
+}
+
+// addConn tracks the connection to be handled, false is returned if the service is drained.
+func (s *defaultService) addConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.drained {
+		return false
+	}
+	s.wg.Add(1)
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *defaultService) removeConn(conn net.Conn) {
+	defer s.wg.Done()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// waitConn waits for the slot of the queued connection.
+func (s *defaultService) waitConn(ctx context.Context) bool {
+	if v := xmetrics.GetGauge(xmetrics.MetricServiceConnsQueuedGauge,
+		metrics.Labels{"service": s.name}); v != nil {
+		v.Inc()
+		defer v.Dec()
+	}
+	return s.limiter.wait(ctx)
+}
+
+func (s *defaultService) rejectConn() {
+	s.status.stats.Add(stats.KindTotalErrs, 1)
+	if v := xmetrics.GetCounter(xmetrics.MetricServiceConnsRejectedCounter,
+		metrics.Labels{"service": s.name}); v != nil {
+		v.Inc()
+	}
+}
+
+func (s *defaultService) traverse(ctx context.Context) {
+	var network string
+	switch addr := s.listener.Addr(); addr.(type) {
+	case *net.TCPAddr:
+		network = "tcp"
+	case *net.UDPAddr:
+		network = "udp"
+	default:
+		s.options.logger.Warnf("nat: %s/%s is not supported", addr, addr.Network())
+		return
+	}
+
+	s.options.nat.traversal.Run(ctx, network, s.listener.Addr(), func(addr string) {
+		s.publicAddr.Store(addr)
+		select {
+		case s.publicc <- struct{}{}:
+		default:
+		}
+
+		if ing := s.options.nat.ingress; ing != nil && s.options.nat.ingressHost != "" {
+			ing.SetRule(ctx, &ingress.Rule{
+				Hostname: s.options.nat.ingressHost,
+				Endpoint: addr,
+			})
+		}
+	})
+}
+
+// sdAddr returns the address registered to the service discovery.
+func (s *defaultService) sdAddr() string {
+	if addr := s.options.sdOptions.addr; addr != "" {
+		return addr
+	}
+	if addr, _ := s.publicAddr.Load().(string); addr != "" {
+		return addr
+	}
+	return s.listener.Addr().String()
+}
+
+func (s *defaultService) register(ctx context.Context) {
+	service := &sd.Service{
+		ID:      xid.New().String(),
+		Name:    s.options.sdOptions.service,
+		Network: s.listener.Addr().Network(),
+		Address: s.sdAddr(),
+	}
+	if service.Name == "" {
+		service.Name = s.name
+	}
+	service.Node, _ = os.Hostname()
+
+	interval := s.options.sdOptions.renewInterval
+	if interval <= 0 {
+		interval = defaultSDRenewInterval
+	}
+
+	registered := false
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if addr := s.sdAddr(); addr != service.Address {
+			if registered {
+				if err := s.options.sd.Deregister(ctx, service); err != nil {
+					s.options.logger.Warnf("sd deregister: %v", err)
+				}
+				registered = false
+			}
+			service.Address = addr
+		}
+
+		if registered {
+			if err := s.options.sd.Renew(ctx, service); err != nil {
+				s.options.logger.Warnf("sd renew: %v", err)
+				registered = false
+			}
+		}
+		if !registered {
+			if err := s.options.sd.Register(ctx, service); err != nil {
+				s.options.logger.Errorf("sd register: %v", err)
+			} else {
+				registered = true
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.publicc:
+		case <-ctx.Done():
+			if registered {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := s.options.sd.Deregister(ctx, service); err != nil {
+					s.options.logger.Warnf("sd deregister: %v", err)
+				}
+			}
+			return
+		}
+	}
+}
+
 func (s *defaultService) execCmds(phase string, cmds []string) {
 	for _, cmd := range cmds {
 		cmd := strings.TrimSpace(cmd)
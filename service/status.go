@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	xerrors "github.com/go-gost/x/errors"
 	"github.com/go-gost/x/stats"
 )
 
@@ -14,10 +15,17 @@ const (
 type State string
 
 const (
-	StateRunning State = "running"
-	StateReady   State = "ready"
-	StateFailed  State = "failed"
-	StateClosed  State = "closed"
+	StateRunning  State = "running"
+	StateWaiting  State = "waiting"
+	StateReady    State = "ready"
+	StateFailed   State = "failed"
+	StateDraining State = "draining"
+	StateClosed   State = "closed"
+
+	// the service is in maintenance mode, the new connections are refused.
+	StateMaintenance State = "maintenance"
+	// the service is out of the windows of its schedule, the listener is closed.
+	StateDisabled State = "disabled"
 )
 
 type Event struct {
@@ -30,6 +38,8 @@ type Status struct {
 	state      State
 	events     []Event
 	stats      *stats.Stats
+	resources  *resourceAccount
+	errors     map[xerrors.Kind]uint64
 	mu         sync.RWMutex
 }
 
@@ -74,3 +84,34 @@ func (p *Status) addEvent(event Event) {
 func (p *Status) Stats() *stats.Stats {
 	return p.stats
 }
+
+// Errors returns the number of the handler errors by the kinds.
+func (p *Status) Errors() map[xerrors.Kind]uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	errors := make(map[xerrors.Kind]uint64, len(p.errors))
+	for k, v := range p.errors {
+		errors[k] = v
+	}
+	return errors
+}
+
+func (p *Status) addError(kind xerrors.Kind) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.errors == nil {
+		p.errors = make(map[xerrors.Kind]uint64)
+	}
+	p.errors[kind]++
+}
+
+// Resources returns the resource consumption of the service, nil is returned if the accounting is disabled.
+func (p *Status) Resources() *Resources {
+	if p.resources == nil {
+		return nil
+	}
+	usage := p.resources.Usage()
+	return &usage
+}
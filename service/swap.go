@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-gost/core/chain"
+)
+
+var (
+	ErrChainNotSwappable = errors.New("the chain of the service is not swappable")
+)
+
+// ChainSwapper is implemented by the chain of the handler which can be swapped at runtime.
+type ChainSwapper interface {
+	// Swap replaces the chain by c, the connections dialed by the old chain are closed after grace.
+	Swap(c chain.Chainer, grace time.Duration) int
+}
+
+// SwapChain replaces the chain of the handler by c, the active connections through the old chain
+// are drained in grace, while the new connections go through c immediately.
+func (s *defaultService) SwapChain(name string, c chain.Chainer, grace time.Duration) error {
+	if s.options.chainSwapper == nil {
+		return ErrChainNotSwappable
+	}
+
+	n := s.options.chainSwapper.Swap(c, grace)
+
+	msg := fmt.Sprintf("chain of service %s is swapped to %s, %d connections are draining", s.name, name, n)
+	s.status.addEvent(Event{
+		Time:    time.Now(),
+		Message: msg,
+	})
+	s.options.logger.Info(msg)
+	return nil
+}